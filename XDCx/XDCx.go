@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/big"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
@@ -15,6 +16,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/core/state"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/event"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/node"
 	"github.com/XinFinOrg/XDPoSChain/p2p"
@@ -29,6 +31,8 @@ const (
 	overflowIdx        // Indicator of message queue overflow
 	defaultCacheLimit  = 1024
 	MaximumTxMatchSize = 1000
+	// MaximumStopOrderActivations caps the stop orders activated in a block
+	MaximumStopOrderActivations = 100
 )
 
 var (
@@ -44,6 +48,12 @@ type Config struct {
 	ReplicaSetName string `toml:",omitempty"`
 }
 
+// Default connection urls of the add-on databases of SDK nodes.
+const (
+	DefaultPostgresConnectionUrl = "postgres://localhost:5432/XDCdex?sslmode=disable"
+	DefaultMongoConnectionUrl    = "localhost:27017"
+)
+
 // DefaultConfig represents (shocker!) the default configuration.
 var DefaultConfig = Config{
 	DataDir: "",
@@ -52,7 +62,7 @@ var DefaultConfig = Config{
 type XDCX struct {
 	// Order related
 	db         XDCxDAO.XDCXDAO
-	mongodb    XDCxDAO.XDCXDAO
+	sdkdb      XDCxDAO.XDCXDAO                  // add-on database of SDK nodes, mongodb or postgres
 	Triegc     *prque.Prque[int64, common.Hash] // Priority queue mapping block numbers to tries to gc
 	StateCache tradingstate.Database            // State database to reuse between imports (contains state cache)    *XDCx_state.TradingStateDB
 
@@ -62,6 +72,11 @@ type XDCX struct {
 	settings          syncmap.Map // holds configuration settings that can be dynamically changed
 	tokenDecimalCache *lru.Cache[common.Address, *big.Int]
 	orderCache        *lru.Cache[common.Hash, map[common.Hash]tradingstate.OrderHistoryItem]
+
+	tradeFeed       event.Feed
+	orderBookFeed   event.Feed
+	orderStatusFeed event.Feed
+	scope           event.SubscriptionScope
 }
 
 func (XDCx *XDCX) Protocols() []p2p.Protocol {
@@ -73,6 +88,7 @@ func (XDCx *XDCX) Start() error {
 }
 
 func (XDCx *XDCX) Stop() error {
+	XDCx.scope.Close()
 	return nil
 }
 
@@ -92,6 +108,14 @@ func NewMongoDBEngine(cfg *Config) *XDCxDAO.MongoDatabase {
 	return mongoDB
 }
 
+func NewPostgresEngine(cfg *Config) *XDCxDAO.PostgresDatabase {
+	postgresDB, err := XDCxDAO.NewPostgresDatabase(cfg.ConnectionUrl, 0)
+	if err != nil {
+		log.Crit("Failed to init postgres engine", "err", err)
+	}
+	return postgresDB
+}
+
 func New(stack *node.Node, cfg *Config) *XDCX {
 	XDCX := &XDCX{
 		orderNonce:        make(map[common.Address]*big.Int),
@@ -104,8 +128,13 @@ func New(stack *node.Node, cfg *Config) *XDCX {
 	XDCX.db = NewLDBEngine(cfg)
 	XDCX.sdkNode = false
 
-	if cfg.DBEngine == "mongodb" { // this is an add-on DBEngine for SDK nodes
-		XDCX.mongodb = NewMongoDBEngine(cfg)
+	// add-on DBEngines for SDK nodes
+	switch cfg.DBEngine {
+	case "mongodb":
+		XDCX.sdkdb = NewMongoDBEngine(cfg)
+		XDCX.sdkNode = true
+	case "postgres":
+		XDCX.sdkdb = NewPostgresEngine(cfg)
 		XDCX.sdkNode = true
 	}
 
@@ -135,8 +164,19 @@ func (XDCx *XDCX) GetLevelDB() XDCxDAO.XDCXDAO {
 	return XDCx.db
 }
 
-func (XDCx *XDCX) GetMongoDB() XDCxDAO.XDCXDAO {
-	return XDCx.mongodb
+// GetSDKDB returns the add-on database of SDK nodes, either mongodb or
+// postgres depending on the configured DBEngine.
+func (XDCx *XDCX) GetSDKDB() XDCxDAO.XDCXDAO {
+	return XDCx.sdkdb
+}
+
+// GetHistoryDB returns the database keeping orders, trades and epoch prices:
+// the add-on database on SDK nodes, the XDCx leveldb on the others.
+func (XDCx *XDCX) GetHistoryDB() XDCxDAO.XDCXDAO {
+	if XDCx.sdkNode {
+		return XDCx.sdkdb
+	}
+	return XDCx.db
 }
 
 // APIs returns the RPC descriptors the XDCX implementation offers
@@ -146,6 +186,11 @@ func (XDCx *XDCX) APIs() []rpc.API {
 			Namespace: ProtocolName,
 			Service:   NewPublicXDCXAPI(XDCx),
 		},
+		{
+			// streams are reachable through xdcx_subscribe
+			Namespace: strings.ToLower(ProtocolName),
+			Service:   NewPublicXDCXStreamAPI(XDCx),
+		},
 	}
 }
 
@@ -192,6 +237,7 @@ func (XDCx *XDCX) ProcessOrderPending(header *types.Header, coinbase common.Addr
 			Type:            tx.Type(),
 			Hash:            tx.OrderHash(),
 			OrderID:         tx.OrderID(),
+			TriggerPrice:    tx.TriggerPrice(),
 			Signature: &tradingstate.Signature{
 				V: byte(n),
 				R: common.BigToHash(R),
@@ -301,7 +347,7 @@ func (XDCx *XDCX) ConvertXDCToToken(chain consensus.ChainContext, statedb *state
 	return tokenQuantity, tokenPriceInXDC, nil
 }
 
-// there are 3 tasks need to complete to update data in SDK nodes after matching
+// there are 3 tasks need to complete to update the history database after matching
 //  1. txMatchData.Order: order has been processed. This order should be put to `orders` collection with status sdktypes.OrderStatusOpen
 //  2. txMatchData.Trades: includes information of matched orders.
 //     a. PutObject them to `trades` collection
@@ -315,8 +361,9 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 		makerDirtyHashes                    []string
 		makerDirtyFilledAmount              map[string]*big.Int
 		err                                 error
+		events                              = newMatchingEvents(txHash, false)
 	)
-	db := XDCx.GetMongoDB()
+	db := XDCx.GetHistoryDB()
 	db.InitBulk()
 	if takerOrderInTx.Status == tradingstate.OrderStatusCancelled && len(rejectedOrders) > 0 {
 		// cancel order is rejected -> nothing change
@@ -335,6 +382,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 			UpdatedAt:    originTakerOrder.UpdatedAt,
 		}
 	}
+	events.touch(takerOrderInTx.Hash, originTakerOrder)
 	if originTakerOrder != nil {
 		updatedTakerOrder = originTakerOrder
 	} else {
@@ -364,11 +412,24 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 	// 2. put trades to db and update status to FILLED
 	log.Debug("Got trades", "number", len(trades), "txhash", txHash.Hex())
 	makerDirtyFilledAmount = make(map[string]*big.Int)
+	var (
+		// trades of the stop orders activated by this order, synced for each of them afterwards
+		activatedHashes []common.Hash
+		activatedTrades = make(map[common.Hash][]map[string]string)
+	)
 	for _, trade := range trades {
 		// 2.a. put to trades
 		if trade == nil {
 			continue
 		}
+		if takerHash, ok := trade[tradingstate.TradeTakerOrderHash]; ok && common.HexToHash(takerHash) != updatedTakerOrder.Hash {
+			hash := common.HexToHash(takerHash)
+			if _, exist := activatedTrades[hash]; !exist {
+				activatedHashes = append(activatedHashes, hash)
+			}
+			activatedTrades[hash] = append(activatedTrades[hash], trade)
+			continue
+		}
 		tradeRecord := &tradingstate.Trade{}
 		quantity := tradingstate.ToBigInt(trade[tradingstate.TradeQuantity])
 		price := tradingstate.ToBigInt(trade[tradingstate.TradePrice])
@@ -408,6 +469,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 		if err := db.PutObject(tradeRecord.Hash, tradeRecord); err != nil {
 			return fmt.Errorf("SDKNode: failed to store tradeRecord %s", err.Error())
 		}
+		events.addTrade(tradeRecord)
 
 		// 2.b. update status and filledAmount
 		filledAmount := quantity
@@ -423,7 +485,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 		//updatedTakerOrder = XDCx.updateMatchedOrder(updatedTakerOrder, filledAmount, txMatchTime, txHash)
 		//  update filledAmount, status of takerOrder
 		updatedTakerOrder.FilledAmount = new(big.Int).Add(updatedTakerOrder.FilledAmount, filledAmount)
-		if updatedTakerOrder.FilledAmount.Cmp(updatedTakerOrder.Quantity) < 0 && updatedTakerOrder.Type == tradingstate.Limit {
+		if updatedTakerOrder.FilledAmount.Cmp(updatedTakerOrder.Quantity) < 0 && (updatedTakerOrder.Type == tradingstate.Limit || updatedTakerOrder.Type == tradingstate.StopLimit) {
 			updatedTakerOrder.Status = tradingstate.OrderStatusPartialFilled
 		} else {
 			updatedTakerOrder.Status = tradingstate.OrderStatusFilled
@@ -433,13 +495,24 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 	// for Market orders
 	// filledAmount > 0 : FILLED
 	// otherwise: REJECTED
-	if updatedTakerOrder.Type == tradingstate.Market {
+	// for stop market orders once activated
+	if updatedTakerOrder.Type == tradingstate.Market || (updatedTakerOrder.Type == tradingstate.StopMarket && len(trades) > 0) {
 		if updatedTakerOrder.FilledAmount.Sign() > 0 {
 			updatedTakerOrder.Status = tradingstate.OrderStatusFilled
 		} else {
 			updatedTakerOrder.Status = tradingstate.OrderStatusRejected
 		}
 	}
+	// for IOC and FOK orders, never added to the orderbook
+	// filledAmount = quantity : FILLED
+	// otherwise: CANCELLED, along with the unmatched part
+	if updatedTakerOrder.Type == tradingstate.ImmediateOrCancel || updatedTakerOrder.Type == tradingstate.FillOrKill {
+		if updatedTakerOrder.FilledAmount.Cmp(updatedTakerOrder.Quantity) < 0 {
+			updatedTakerOrder.Status = tradingstate.OrderStatusCancelled
+		} else {
+			updatedTakerOrder.Status = tradingstate.OrderStatusFilled
+		}
+	}
 	log.Debug("PutObject processed takerOrder",
 		"userAddr", updatedTakerOrder.UserAddress.Hex(), "side", updatedTakerOrder.Side,
 		"price", updatedTakerOrder.Price, "quantity", updatedTakerOrder.Quantity, "filledAmount", updatedTakerOrder.FilledAmount, "status", updatedTakerOrder.Status,
@@ -447,6 +520,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 	if err := db.PutObject(updatedTakerOrder.Hash, updatedTakerOrder); err != nil {
 		return fmt.Errorf("SDKNode: failed to put processed takerOrder. Hash: %s Error: %s", updatedTakerOrder.Hash.Hex(), err.Error())
 	}
+	events.update(updatedTakerOrder)
 	items := db.GetListItemByHashes(makerDirtyHashes, &tradingstate.OrderItem{})
 	if items != nil {
 		makerOrders := items.([]*tradingstate.OrderItem)
@@ -463,6 +537,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 				UpdatedAt:    o.UpdatedAt,
 			}
 			XDCx.UpdateOrderCache(o.BaseToken, o.QuoteToken, o.Hash, txHash, lastState)
+			events.touch(o.Hash, o)
 			o.TxHash = txHash
 			o.UpdatedAt = txMatchTime
 			o.FilledAmount = new(big.Int).Add(o.FilledAmount, makerDirtyFilledAmount[o.Hash.Hex()])
@@ -478,6 +553,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 			if err := db.PutObject(o.Hash, o); err != nil {
 				return fmt.Errorf("SDKNode: failed to put processed makerOrder. Hash: %s Error: %s", o.Hash.Hex(), err.Error())
 			}
+			events.update(o)
 		}
 	}
 
@@ -510,6 +586,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 				if err := db.PutObject(updatedTakerOrder.Hash, updatedTakerOrder); err != nil {
 					return fmt.Errorf("SDKNode: failed to reject takerOrder. Hash: %s Error: %s", updatedTakerOrder.Hash.Hex(), err.Error())
 				}
+				events.update(updatedTakerOrder)
 			}
 		}
 		items := db.GetListItemByHashes(rejectedHashes, &tradingstate.OrderItem{})
@@ -528,6 +605,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 					UpdatedAt:    order.UpdatedAt,
 				}
 				XDCx.UpdateOrderCache(order.BaseToken, order.QuoteToken, order.Hash, txHash, orderHistoryRecord)
+				events.touch(order.Hash, order)
 				dirtyFilledAmount, ok := makerDirtyFilledAmount[order.Hash.Hex()]
 				if ok && dirtyFilledAmount != nil {
 					order.FilledAmount = new(big.Int).Add(order.FilledAmount, dirtyFilledAmount)
@@ -544,6 +622,7 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 				if err = db.PutObject(order.Hash, order); err != nil {
 					return fmt.Errorf("SDKNode: failed to update rejectedOder to sdkNode %s", err.Error())
 				}
+				events.update(order)
 			}
 		}
 	}
@@ -551,6 +630,19 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 	if err := db.CommitBulk(); err != nil {
 		return fmt.Errorf("SDKNode fail to commit bulk update orders, trades at txhash %s . Error: %s", txHash.Hex(), err.Error())
 	}
+	XDCx.postMatchingEvents(events)
+
+	// 4. stop orders activated by this order are the takers of their own trades
+	for _, hash := range activatedHashes {
+		val, err := db.GetObject(hash, &tradingstate.OrderItem{})
+		if err != nil || val == nil {
+			log.Warn("SDKNode: activated stop order not found", "hash", hash.Hex(), "txHash", txHash.Hex(), "err", err)
+			continue
+		}
+		if err := XDCx.SyncDataToSDKNode(val.(*tradingstate.OrderItem), txHash, txMatchTime, statedb, activatedTrades[hash], nil, dirtyOrderCount); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -611,13 +703,17 @@ func (XDCx *XDCX) UpdateOrderCache(baseToken, quoteToken common.Address, orderHa
 	XDCx.orderCache.Add(txhash, orderCacheAtTxHash)
 }
 
+// RollbackReorgTxMatch reverts the orders and trades written for a matching
+// transaction dropped by a reorg, and posts the matching rollback events.
 func (XDCx *XDCX) RollbackReorgTxMatch(txhash common.Hash) error {
-	db := XDCx.GetMongoDB()
+	db := XDCx.GetHistoryDB()
 	db.InitBulk()
 
+	events := newMatchingEvents(txhash, true)
 	items := db.GetListItemByTxHash(txhash, &tradingstate.OrderItem{})
 	if items != nil {
 		for _, order := range items.([]*tradingstate.OrderItem) {
+			events.touch(order.Hash, order)
 			orderCacheAtTxHash, ok := XDCx.orderCache.Get(txhash)
 			log.Debug("XDCx reorg: rollback order", "txhash", txhash.Hex(), "order", tradingstate.ToJSON(order), "orderHistoryItem", orderCacheAtTxHash)
 			if !ok || orderCacheAtTxHash == nil {
@@ -625,6 +721,7 @@ func (XDCx *XDCX) RollbackReorgTxMatch(txhash common.Hash) error {
 				if err := db.DeleteObject(order.Hash, &tradingstate.OrderItem{}); err != nil {
 					log.Crit("SDKNode: failed to remove reorg order", "err", err.Error(), "order", tradingstate.ToJSON(order))
 				}
+				events.drop(order)
 				continue
 			}
 			orderHistoryItem := orderCacheAtTxHash[tradingstate.GetOrderHistoryKey(order.BaseToken, order.QuoteToken, order.Hash)]
@@ -633,6 +730,7 @@ func (XDCx *XDCX) RollbackReorgTxMatch(txhash common.Hash) error {
 				if err := db.DeleteObject(order.Hash, &tradingstate.OrderItem{}); err != nil {
 					log.Crit("SDKNode: failed to remove reorg order", "err", err.Error(), "order", tradingstate.ToJSON(order))
 				}
+				events.drop(order)
 				continue
 			}
 			order.TxHash = orderHistoryItem.TxHash
@@ -643,6 +741,12 @@ func (XDCx *XDCX) RollbackReorgTxMatch(txhash common.Hash) error {
 			if err := db.PutObject(order.Hash, order); err != nil {
 				log.Crit("SDKNode: failed to update reorg order", "err", err.Error(), "order", tradingstate.ToJSON(order))
 			}
+			events.update(order)
+		}
+	}
+	if trades := db.GetListItemByTxHash(txhash, &tradingstate.Trade{}); trades != nil {
+		for _, trade := range trades.([]*tradingstate.Trade) {
+			events.addTrade(trade)
 		}
 	}
 	log.Debug("XDCx reorg: DeleteTradeByTxHash", "txhash", txhash.Hex())
@@ -650,5 +754,6 @@ func (XDCx *XDCX) RollbackReorgTxMatch(txhash common.Hash) error {
 	if err := db.CommitBulk(); err != nil {
 		return fmt.Errorf("failed to RollbackTradingData. %v", err)
 	}
+	XDCx.postMatchingEvents(events)
 	return nil
 }
@@ -3,12 +3,22 @@ package XDCx
 import (
 	"context"
 	"errors"
+	"math/big"
 	"sync"
 	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/XDCxDAO"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
 )
 
 const (
 	LimitThresholdOrderNonceInQueue = 100
+
+	defaultHistoryPageSize = 100  // Number of items returned by history queries without a limit
+	maxHistoryPageSize     = 1000 // Maximum number of items returned by a single history query
 )
 
 // List of errors
@@ -16,6 +26,9 @@ var (
 	ErrNoTopics          = errors.New("missing topic(s)")
 	ErrOrderNonceTooLow  = errors.New("OrderNonce too low")
 	ErrOrderNonceTooHigh = errors.New("OrderNonce too high")
+	ErrHistoryNotFound   = errors.New("XDCx history database is not available")
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrInvalidRange      = errors.New("invalid range")
 )
 
 // PublicXDCXAPI provides the XDCX RPC service that can be
@@ -40,3 +53,281 @@ func NewPublicXDCXAPI(t *XDCX) *PublicXDCXAPI {
 func (api *PublicXDCXAPI) Version(ctx context.Context) string {
 	return ProtocolVersionStr
 }
+
+// OrderStatusHistory is the current state of an order together with all the
+// states it went through.
+type OrderStatusHistory struct {
+	Order   *tradingstate.OrderItem          `json:"order"`
+	History []*tradingstate.OrderHistoryItem `json:"history"`
+}
+
+// Candle is the OHLCV summary of an orderbook over one epoch. The close price
+// is the average price of the epoch recorded in the trading state, the open
+// price is the close of the previous epoch. The high and low prices also cover
+// every trade of the epoch.
+type Candle struct {
+	Epoch  hexutil.Uint64 `json:"epoch"`
+	Open   *big.Int       `json:"open"`
+	High   *big.Int       `json:"high"`
+	Low    *big.Int       `json:"low"`
+	Close  *big.Int       `json:"close"`
+	Volume *big.Int       `json:"volume"`
+}
+
+func (api *PublicXDCXAPI) historyDB() (XDCxDAO.XDCXHistoryDAO, error) {
+	db, ok := api.t.GetHistoryDB().(XDCxDAO.XDCXHistoryDAO)
+	if !ok || db == nil {
+		return nil, ErrHistoryNotFound
+	}
+	return db, nil
+}
+
+func pageSize(limit *int) int {
+	if limit == nil || *limit <= 0 {
+		return defaultHistoryPageSize
+	}
+	if *limit > maxHistoryPageSize {
+		return maxHistoryPageSize
+	}
+	return *limit
+}
+
+// GetOrderByHash returns the latest known state of an order.
+func (api *PublicXDCXAPI) GetOrderByHash(ctx context.Context, orderHash common.Hash) (*tradingstate.OrderItem, error) {
+	val, err := api.t.GetHistoryDB().GetObject(orderHash, &tradingstate.OrderItem{})
+	if err != nil || val == nil {
+		return nil, ErrOrderNotFound
+	}
+	return val.(*tradingstate.OrderItem), nil
+}
+
+// GetOrdersByUser returns the orders placed by a user, newest first.
+func (api *PublicXDCXAPI) GetOrdersByUser(ctx context.Context, user common.Address, offset *int, limit *int) ([]*tradingstate.OrderItem, error) {
+	db, err := api.historyDB()
+	if err != nil {
+		return nil, err
+	}
+	start := 0
+	if offset != nil && *offset > 0 {
+		start = *offset
+	}
+	return db.GetOrdersByUser(user, start, pageSize(limit))
+}
+
+// GetTradesByPair returns the trades of a pair created within the [from, to)
+// range of unix timestamps, oldest first.
+func (api *PublicXDCXAPI) GetTradesByPair(ctx context.Context, baseToken, quoteToken common.Address, from, to hexutil.Uint64, limit *int) ([]*tradingstate.Trade, error) {
+	if from > to {
+		return nil, ErrInvalidRange
+	}
+	db, err := api.historyDB()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetTradesByPair(baseToken, quoteToken, time.Unix(int64(from), 0).UTC(), time.Unix(int64(to), 0).UTC(), pageSize(limit))
+}
+
+// GetOrderHistory returns an order and every status it went through.
+func (api *PublicXDCXAPI) GetOrderHistory(ctx context.Context, orderHash common.Hash) (*OrderStatusHistory, error) {
+	db, err := api.historyDB()
+	if err != nil {
+		return nil, err
+	}
+	order, err := api.GetOrderByHash(ctx, orderHash)
+	if err != nil {
+		return nil, err
+	}
+	history, err := db.GetOrderHistory(orderHash)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderStatusHistory{Order: order, History: history}, nil
+}
+
+// GetCandles returns one candle per epoch of a pair within [fromEpoch, toEpoch].
+// Epochs without a recorded price are skipped.
+func (api *PublicXDCXAPI) GetCandles(ctx context.Context, baseToken, quoteToken common.Address, fromEpoch, toEpoch hexutil.Uint64) ([]*Candle, error) {
+	if fromEpoch > toEpoch || uint64(toEpoch-fromEpoch) >= maxHistoryPageSize {
+		return nil, ErrInvalidRange
+	}
+	db, err := api.historyDB()
+	if err != nil {
+		return nil, err
+	}
+	start := uint64(fromEpoch)
+	if start > 0 {
+		start-- // the previous epoch opens the first candle
+	}
+	items, err := db.GetEpochPrices(tradingstate.GetTradingOrderBookHash(baseToken, quoteToken), start, uint64(toEpoch))
+	if err != nil {
+		return nil, err
+	}
+	priceRange := func(from, to uint64) (*big.Int, *big.Int, error) {
+		return db.GetTradePriceRange(baseToken, quoteToken, time.Unix(int64(from), 0).UTC(), time.Unix(int64(to), 0).UTC())
+	}
+	return buildCandles(items, uint64(fromEpoch), priceRange)
+}
+
+// buildCandles turns consecutive epoch prices into candles, starting from
+// the given epoch. priceRange returns the highest and lowest trade prices
+// within a range of unix timestamps, the span of an epoch being the close
+// times of the previous epoch and of its own.
+func buildCandles(items []*tradingstate.EpochPriceItem, fromEpoch uint64, priceRange func(from, to uint64) (*big.Int, *big.Int, error)) ([]*Candle, error) {
+	var (
+		candles   = []*Candle{}
+		lastClose *big.Int
+		lastTime  uint64
+	)
+	for _, item := range items {
+		if item.Price == nil || item.Price.Sign() <= 0 {
+			continue
+		}
+		open, openTime := lastClose, lastTime
+		lastClose, lastTime = item.Price, item.Time
+		if item.Epoch < fromEpoch {
+			continue
+		}
+		if open == nil {
+			open = item.Price
+		}
+		candle := &Candle{
+			Epoch:  hexutil.Uint64(item.Epoch),
+			Open:   new(big.Int).Set(open),
+			High:   new(big.Int).Set(open),
+			Low:    new(big.Int).Set(open),
+			Close:  new(big.Int).Set(item.Price),
+			Volume: new(big.Int),
+		}
+		candle.widen(item.Price)
+		// Records written before the close times were kept have no span
+		if openTime > 0 && item.Time > openTime {
+			high, low, err := priceRange(openTime, item.Time)
+			if err != nil {
+				return nil, err
+			}
+			candle.widen(high)
+			candle.widen(low)
+		}
+		if item.Volume != nil {
+			candle.Volume.Set(item.Volume)
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// widen extends the high and low of the candle to a traded price.
+func (c *Candle) widen(price *big.Int) {
+	if price == nil {
+		return
+	}
+	if price.Cmp(c.High) > 0 {
+		c.High.Set(price)
+	}
+	if price.Cmp(c.Low) < 0 {
+		c.Low.Set(price)
+	}
+}
+
+// PublicXDCXStreamAPI offers websocket subscriptions to the events of the
+// XDCx matching engine.
+type PublicXDCXStreamAPI struct {
+	t *XDCX
+}
+
+// NewPublicXDCXStreamAPI creates a new XDCx stream API.
+func NewPublicXDCXStreamAPI(t *XDCX) *PublicXDCXStreamAPI {
+	return &PublicXDCXStreamAPI{t: t}
+}
+
+// Trades streams the trades of a pair, or of every pair if no pair is given.
+func (api *PublicXDCXStreamAPI) Trades(ctx context.Context, baseToken, quoteToken *common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan TradeEvent, 128)
+		sub := api.t.SubscribeTradeEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if baseToken != nil && ev.Trade.BaseToken != *baseToken {
+					continue
+				}
+				if quoteToken != nil && ev.Trade.QuoteToken != *quoteToken {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, ev)
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// OrderBookDelta streams the changes of the volume resting at the price levels
+// of a pair.
+func (api *PublicXDCXStreamAPI) OrderBookDelta(ctx context.Context, baseToken, quoteToken common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan OrderBookDeltaEvent, 128)
+		sub := api.t.SubscribeOrderBookDeltaEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if ev.BaseToken == baseToken && ev.QuoteToken == quoteToken {
+					notifier.Notify(rpcSub.ID, ev)
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// OrderStatus streams the status changes of the orders of a user.
+func (api *PublicXDCXStreamAPI) OrderStatus(ctx context.Context, user common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan OrderStatusEvent, 128)
+		sub := api.t.SubscribeOrderStatusEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if ev.Order.UserAddress == user {
+					notifier.Notify(rpcSub.ID, ev)
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,193 @@
+package XDCx
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/node"
+)
+
+func TestBuildCandles(t *testing.T) {
+	items := []*tradingstate.EpochPriceItem{
+		{Epoch: 4, Price: big.NewInt(100), Volume: big.NewInt(1), Time: 1000},
+		{Epoch: 5, Price: big.NewInt(120), Volume: big.NewInt(2), Time: 2000},
+		{Epoch: 7, Price: big.NewInt(90), Volume: big.NewInt(3), Time: 4000},
+	}
+	// Trades of epoch 5 spiked above its close, those of epoch 7 dipped below
+	trades := map[[2]uint64][2]int64{
+		{1000, 2000}: {150, 95},
+		{2000, 4000}: {110, 80},
+	}
+	priceRange := func(from, to uint64) (*big.Int, *big.Int, error) {
+		r, ok := trades[[2]uint64{from, to}]
+		if !ok {
+			return nil, nil, nil
+		}
+		return big.NewInt(r[0]), big.NewInt(r[1]), nil
+	}
+	candles, err := buildCandles(items, 5, priceRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 2 {
+		t.Fatalf("candle count mismatch: have %d, want %d", len(candles), 2)
+	}
+	tests := []struct {
+		epoch                          uint64
+		open, high, low, close, volume int64
+	}{
+		{5, 100, 150, 95, 120, 2},
+		{7, 120, 120, 80, 90, 3},
+	}
+	for i, tt := range tests {
+		c := candles[i]
+		if uint64(c.Epoch) != tt.epoch || c.Open.Int64() != tt.open || c.High.Int64() != tt.high ||
+			c.Low.Int64() != tt.low || c.Close.Int64() != tt.close || c.Volume.Int64() != tt.volume {
+			t.Errorf("candle %d mismatch: have %+v, want %+v", i, c, tt)
+		}
+	}
+	// The first recorded epoch opens at its own price and has no known span
+	candles, err = buildCandles(items, 4, priceRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := candles[0]; c.Open.Int64() != 100 || c.High.Int64() != 100 || c.Low.Int64() != 100 {
+		t.Errorf("first candle mismatch: have %+v", c)
+	}
+	// Without trades the candle spans its open and close
+	candles, err = buildCandles(items, 5, func(from, to uint64) (*big.Int, *big.Int, error) { return nil, nil, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := candles[1]; c.High.Int64() != 120 || c.Low.Int64() != 90 {
+		t.Errorf("candle without trades mismatch: have %+v", c)
+	}
+}
+
+func TestMatchingEvents(t *testing.T) {
+	stack, err := node.New(&node.DefaultConfig)
+	if err != nil {
+		t.Fatalf("could not create new node: %v", err)
+	}
+	defer stack.Close()
+	// A node without SDK database records the history into its leveldb and
+	// posts the events all the same
+	XDCx := New(stack, &Config{DataDir: t.TempDir()})
+	defer XDCx.GetLevelDB().Close()
+
+	var (
+		base  = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		quote = common.HexToAddress("0x1100000000000000000000000000000000000003")
+		maker = &tradingstate.OrderItem{
+			Quantity:     big.NewInt(10),
+			Price:        big.NewInt(5),
+			UserAddress:  common.HexToAddress("0xaa"),
+			BaseToken:    base,
+			QuoteToken:   quote,
+			Status:       tradingstate.OrderStatusOpen,
+			Side:         tradingstate.Ask,
+			Type:         tradingstate.Limit,
+			Hash:         common.HexToHash("0x01"),
+			TxHash:       common.HexToHash("0xa1"),
+			FilledAmount: new(big.Int),
+			Nonce:        big.NewInt(1),
+			CreatedAt:    time.Unix(1000, 0).UTC(),
+			UpdatedAt:    time.Unix(1000, 0).UTC(),
+		}
+		taker = &tradingstate.OrderItem{
+			Quantity:    big.NewInt(4),
+			Price:       big.NewInt(5),
+			UserAddress: common.HexToAddress("0xbb"),
+			BaseToken:   base,
+			QuoteToken:  quote,
+			Status:      tradingstate.OrderStatusNew,
+			Side:        tradingstate.Bid,
+			Type:        tradingstate.Limit,
+			Hash:        common.HexToHash("0x02"),
+			Nonce:       big.NewInt(1),
+		}
+		txHash = common.HexToHash("0xa2")
+		trades = []map[string]string{{
+			tradingstate.TradeQuantity:       "4",
+			tradingstate.TradePrice:          "5",
+			tradingstate.TradeMaker:          maker.UserAddress.Hex(),
+			tradingstate.TradeMakerOrderHash: maker.Hash.Hex(),
+			tradingstate.TradeMakerExchange:  common.Address{}.Hex(),
+			tradingstate.MakerFee:            "0",
+			tradingstate.TakerFee:            "0",
+			tradingstate.MakerOrderType:      tradingstate.Limit,
+		}}
+	)
+	if err := XDCx.GetLevelDB().PutObject(maker.Hash, maker); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		tradeCh  = make(chan TradeEvent, 10)
+		deltaCh  = make(chan OrderBookDeltaEvent, 10)
+		statusCh = make(chan OrderStatusEvent, 10)
+	)
+	defer XDCx.SubscribeTradeEvent(tradeCh).Unsubscribe()
+	defer XDCx.SubscribeOrderBookDeltaEvent(deltaCh).Unsubscribe()
+	defer XDCx.SubscribeOrderStatusEvent(statusCh).Unsubscribe()
+
+	dirty := uint64(0)
+	if err := XDCx.SyncDataToSDKNode(taker, txHash, time.Unix(1100, 0).UTC(), nil, trades, nil, &dirty); err != nil {
+		t.Fatalf("failed to sync matching data: %v", err)
+	}
+	if ev := <-tradeCh; ev.Removed || ev.Trade.Amount.Int64() != 4 || ev.Trade.MakerOrderHash != maker.Hash {
+		t.Fatalf("trade event mismatch: %+v", ev)
+	}
+	if ev := <-deltaCh; ev.Removed || ev.Side != tradingstate.Ask || ev.Delta.Int64() != -4 {
+		t.Fatalf("orderbook delta mismatch: %+v", ev)
+	}
+	statuses := map[common.Hash]string{}
+	for i := 0; i < 2; i++ {
+		ev := <-statusCh
+		statuses[ev.Order.Hash] = ev.Order.Status
+	}
+	if statuses[taker.Hash] != tradingstate.OrderStatusFilled || statuses[maker.Hash] != tradingstate.OrderStatusPartialFilled {
+		t.Fatalf("order status events mismatch: %v", statuses)
+	}
+
+	// Rolling the transaction back gives the liquidity back to the maker
+	if err := XDCx.RollbackReorgTxMatch(txHash); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-tradeCh; !ev.Removed {
+		t.Fatalf("trade rollback event mismatch: %+v", ev)
+	}
+	if ev := <-deltaCh; !ev.Removed || ev.Delta.Int64() != 4 {
+		t.Fatalf("orderbook rollback delta mismatch: %+v", ev)
+	}
+	for i := 0; i < 2; i++ {
+		if ev := <-statusCh; !ev.Removed {
+			t.Fatalf("order status rollback event mismatch: %+v", ev)
+		}
+	}
+	if order, _ := XDCx.GetLevelDB().GetObject(maker.Hash, &tradingstate.OrderItem{}); order.(*tradingstate.OrderItem).Status != tradingstate.OrderStatusOpen {
+		t.Fatalf("maker order not restored: %v", order)
+	}
+}
+
+func TestStreamAPINamespace(t *testing.T) {
+	stack, err := node.New(&node.DefaultConfig)
+	if err != nil {
+		t.Fatalf("could not create new node: %v", err)
+	}
+	defer stack.Close()
+	XDCx := New(stack, &Config{DataDir: t.TempDir()})
+	defer XDCx.GetLevelDB().Close()
+
+	var namespaces []string
+	for _, api := range XDCx.APIs() {
+		if _, ok := api.Service.(*PublicXDCXStreamAPI); ok {
+			namespaces = append(namespaces, api.Namespace)
+		}
+	}
+	if len(namespaces) != 1 || namespaces[0] != "xdcx" {
+		t.Fatalf("stream API namespaces mismatch: have %v, want [xdcx]", namespaces)
+	}
+}
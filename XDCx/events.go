@@ -0,0 +1,153 @@
+package XDCx
+
+import (
+	"math/big"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/event"
+)
+
+// The events below are posted on every node, SDK or not, as the results of the
+// matching engine for the blocks of the canonical chain are recorded into the
+// history database, see SyncDataToSDKNode and RollbackReorgTxMatch.
+
+// TradeEvent is posted when a trade of the matching engine is written to the
+// history database. Removed is set when the trade is rolled back by a reorg.
+type TradeEvent struct {
+	Trade   *tradingstate.Trade `json:"trade"`
+	Removed bool                `json:"removed"`
+}
+
+// OrderBookDeltaEvent is posted when the volume resting at a price level of an
+// orderbook changes. Delta is signed: negative when liquidity is taken or
+// cancelled, positive when an order rests on the book.
+type OrderBookDeltaEvent struct {
+	BaseToken  common.Address `json:"baseToken"`
+	QuoteToken common.Address `json:"quoteToken"`
+	Side       string         `json:"side"`
+	Price      *big.Int       `json:"price"`
+	Delta      *big.Int       `json:"delta"`
+	TxHash     common.Hash    `json:"txHash"`
+	Removed    bool           `json:"removed"`
+}
+
+// OrderStatusEvent is posted with the latest state of an order whenever a
+// matching transaction changes it. When Removed is set the change made by
+// TxHash was rolled back by a reorg and Order holds the restored state, or the
+// dropped order if it didn't exist before.
+type OrderStatusEvent struct {
+	Order   *tradingstate.OrderItem `json:"order"`
+	TxHash  common.Hash             `json:"txHash"`
+	Removed bool                    `json:"removed"`
+}
+
+// SubscribeTradeEvent registers a subscription of TradeEvent.
+func (XDCx *XDCX) SubscribeTradeEvent(ch chan<- TradeEvent) event.Subscription {
+	return XDCx.scope.Track(XDCx.tradeFeed.Subscribe(ch))
+}
+
+// SubscribeOrderBookDeltaEvent registers a subscription of OrderBookDeltaEvent.
+func (XDCx *XDCX) SubscribeOrderBookDeltaEvent(ch chan<- OrderBookDeltaEvent) event.Subscription {
+	return XDCx.scope.Track(XDCx.orderBookFeed.Subscribe(ch))
+}
+
+// SubscribeOrderStatusEvent registers a subscription of OrderStatusEvent.
+func (XDCx *XDCX) SubscribeOrderStatusEvent(ch chan<- OrderStatusEvent) event.Subscription {
+	return XDCx.scope.Track(XDCx.orderStatusFeed.Subscribe(ch))
+}
+
+// restingQuantity returns the quantity an order keeps on the orderbook.
+func restingQuantity(order *tradingstate.OrderItem) *big.Int {
+	if order == nil || order.Type == tradingstate.Market || order.Type == tradingstate.StopMarket || order.Quantity == nil {
+		return new(big.Int)
+	}
+	if order.Status != tradingstate.OrderStatusOpen && order.Status != tradingstate.OrderStatusPartialFilled {
+		return new(big.Int)
+	}
+	remaining := new(big.Int).Set(order.Quantity)
+	if order.FilledAmount != nil {
+		remaining.Sub(remaining, order.FilledAmount)
+	}
+	if remaining.Sign() < 0 {
+		return new(big.Int)
+	}
+	return remaining
+}
+
+// matchingEvents collects the events of a single matching transaction, so
+// that they are only posted once its data is committed.
+type matchingEvents struct {
+	txHash  common.Hash
+	removed bool
+	trades  []*tradingstate.Trade
+	before  map[common.Hash]*big.Int               // resting quantity of the touched orders before the transaction
+	after   map[common.Hash]*big.Int               // resting quantity of the touched orders after the transaction
+	orders  map[common.Hash]tradingstate.OrderItem // latest state of the touched orders
+	touched []common.Hash                          // touched orders in order of appearance
+}
+
+func newMatchingEvents(txHash common.Hash, removed bool) *matchingEvents {
+	return &matchingEvents{
+		txHash:  txHash,
+		removed: removed,
+		before:  make(map[common.Hash]*big.Int),
+		after:   make(map[common.Hash]*big.Int),
+		orders:  make(map[common.Hash]tradingstate.OrderItem),
+	}
+}
+
+// touch records the resting quantity of an order before it gets modified.
+// Only the first call for an order has an effect, a nil order is a new one.
+func (m *matchingEvents) touch(hash common.Hash, order *tradingstate.OrderItem) {
+	if _, ok := m.before[hash]; ok {
+		return
+	}
+	m.before[hash] = restingQuantity(order)
+	m.touched = append(m.touched, hash)
+}
+
+// update records the state of an order as it is written to the database.
+// Orders are modified in place and may be cached by the database, so a
+// snapshot is kept.
+func (m *matchingEvents) update(order *tradingstate.OrderItem) {
+	m.touch(order.Hash, nil)
+	m.orders[order.Hash] = *order
+	m.after[order.Hash] = restingQuantity(order)
+}
+
+// drop records an order removed from the database.
+func (m *matchingEvents) drop(order *tradingstate.OrderItem) {
+	m.touch(order.Hash, order)
+	m.orders[order.Hash] = *order
+	m.after[order.Hash] = new(big.Int)
+}
+
+func (m *matchingEvents) addTrade(trade *tradingstate.Trade) {
+	m.trades = append(m.trades, trade)
+}
+
+// postMatchingEvents sends the collected events to the subscribers.
+func (XDCx *XDCX) postMatchingEvents(m *matchingEvents) {
+	for _, trade := range m.trades {
+		XDCx.tradeFeed.Send(TradeEvent{Trade: trade, Removed: m.removed})
+	}
+	for _, hash := range m.touched {
+		order, ok := m.orders[hash]
+		if !ok {
+			continue
+		}
+		if delta := new(big.Int).Sub(m.after[hash], m.before[hash]); delta.Sign() != 0 {
+			XDCx.orderBookFeed.Send(OrderBookDeltaEvent{
+				BaseToken:  order.BaseToken,
+				QuoteToken: order.QuoteToken,
+				Side:       order.Side,
+				Price:      order.Price,
+				Delta:      delta,
+				TxHash:     m.txHash,
+				Removed:    m.removed,
+			})
+		}
+		XDCx.orderStatusFeed.Send(OrderStatusEvent{Order: &order, TxHash: m.txHash, Removed: m.removed})
+	}
+}
@@ -3,6 +3,7 @@ package XDCx
 import (
 	"encoding/json"
 	"math/big"
+	"slices"
 	"strconv"
 	"time"
 
@@ -69,7 +70,17 @@ func (XDCx *XDCX) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		}
 		return trades, rejects, nil
 	}
-	if order.Type != tradingstate.Market {
+	if tradingstate.TimeInForceOrderType[order.Type] && !chain.Config().IsTIPXDCXOrderTypes(header.Number) {
+		log.Debug("Reject order type not activated yet", "type", order.Type)
+		rejects = append(rejects, order)
+		return trades, rejects, nil
+	}
+	if tradingstate.StopOrderType[order.Type] && !chain.Config().IsTIPXDCXStopOrders(header.Number) {
+		log.Debug("Reject order type not activated yet", "type", order.Type)
+		rejects = append(rejects, order)
+		return trades, rejects, nil
+	}
+	if order.Type != tradingstate.Market && order.Type != tradingstate.StopMarket {
 		if order.Price.Sign() == 0 || common.BigToHash(order.Price).Big().Cmp(order.Price) != 0 {
 			log.Debug("Reject order price invalid", "price", order.Price)
 			rejects = append(rejects, order)
@@ -82,6 +93,13 @@ func (XDCx *XDCX) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		return trades, rejects, nil
 	}
 	orderType := order.Type
+	if tradingstate.StopOrderType[orderType] {
+		log.Debug("Process stop order", "side", order.Side, "quantity", order.Quantity, "triggerPrice", order.TriggerPrice)
+		if !XDCx.processStopOrder(tradingStateDB, orderBook, order) {
+			rejects = append(rejects, order)
+		}
+		return trades, rejects, nil
+	}
 	// if we do not use auto-increment orderid, we must set price slot to avoid conflict
 	if orderType == tradingstate.Market {
 		log.Debug("Process maket order", "side", order.Side, "quantity", order.Quantity, "price", order.Price)
@@ -104,6 +122,151 @@ func (XDCx *XDCX) ApplyOrder(header *types.Header, coinbase common.Address, chai
 	return trades, rejects, nil
 }
 
+// processStopOrder : add the stop order to the trigger tree of the orderbook.
+// The trigger price must be out of the range the price has moved over since the
+// stop orders were last activated, the order is activated once the last price
+// reaches it.
+func (XDCx *XDCX) processStopOrder(tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) bool {
+	if order.TriggerPrice == nil || common.BigToHash(order.TriggerPrice).Big().Cmp(order.TriggerPrice) != 0 {
+		log.Debug("Reject stop order trigger price invalid", "triggerPrice", order.TriggerPrice)
+		return false
+	}
+	lastPrice := tradingStateDB.GetLastPrice(orderBook)
+	if lastPrice == nil || lastPrice.Sign() == 0 {
+		log.Debug("Reject stop order without last price", "triggerPrice", order.TriggerPrice)
+		return false
+	}
+	stopOrderPrice := tradingStateDB.GetStopOrderPrice(orderBook)
+	if stopOrderPrice == nil || stopOrderPrice.Sign() == 0 {
+		stopOrderPrice = lastPrice
+		tradingStateDB.SetStopOrderPrice(orderBook, tradingstate.CloneBigInt(lastPrice))
+	}
+	low, high := stopOrderPrice, lastPrice
+	if low.Cmp(high) > 0 {
+		low, high = high, low
+	}
+	if order.TriggerPrice.Cmp(low) >= 0 && order.TriggerPrice.Cmp(high) <= 0 {
+		log.Debug("Reject stop order trigger price already crossed", "triggerPrice", order.TriggerPrice, "lastPrice", lastPrice, "stopOrderPrice", stopOrderPrice)
+		return false
+	}
+	orderId := tradingStateDB.GetNonce(orderBook)
+	order.OrderID = orderId + 1
+	tradingStateDB.SetNonce(orderBook, orderId+1)
+	orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
+	tradingStateDB.InsertStopOrder(orderBook, orderIdHash, *order)
+	log.Debug("Stop order is added to trigger tree", "side", order.Side, "triggerPrice", order.TriggerPrice, "orderId", order.OrderID)
+	return true
+}
+
+// ActivateStopOrders : match the stop orders of the orderbooks whose last price
+// moved past their trigger prices, at most MaximumStopOrderActivations per call.
+// It runs once per block, after the orders and the liquidation auctions of the
+// block, the orderbooks left over by the cap stay pending for the next block.
+// The trades and rejects of all the activated orders are returned together, the
+// takers of the trades are the activated orders.
+func (XDCx *XDCX) ActivateStopOrders(header *types.Header, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB) tradingstate.MatchingResult {
+	var result tradingstate.MatchingResult
+	if !chain.Config().IsTIPXDCXStopOrders(header.Number) {
+		return result
+	}
+	budget := MaximumStopOrderActivations
+	for _, orderBook := range tradingStateDB.GetPendingStopOrderBooks() {
+		if budget == 0 {
+			break
+		}
+		trades, rejects, activated, done := XDCx.activateStopOrders(coinbase, chain, statedb, tradingStateDB, orderBook, budget)
+		budget -= activated
+		if done {
+			tradingStateDB.RemovePendingStopOrderBook(orderBook)
+		}
+		result.Trades = append(result.Trades, trades...)
+		result.Rejects = append(result.Rejects, rejects...)
+	}
+	return result
+}
+
+// activateStopOrders : match the stop orders whose trigger price has been crossed
+// since the stop orders of the orderbook were last activated, up to budget orders.
+// Orders are activated one after the other, nearest trigger price first, and the
+// price moves they cause can activate more orders. The reference price only moves
+// past a trigger price once all its orders are activated, the rest wait for the
+// next block. It reports whether no triggered order is left.
+func (XDCx *XDCX) activateStopOrders(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, budget int) ([]map[string]string, []*tradingstate.OrderItem, int, bool) {
+	var (
+		trades    []map[string]string
+		rejects   []*tradingstate.OrderItem
+		activated int
+		done      bool
+	)
+	stopOrderPrice := tradingStateDB.GetStopOrderPrice(orderBook)
+	if stopOrderPrice == nil || stopOrderPrice.Sign() == 0 {
+		return trades, rejects, activated, true
+	}
+	from := stopOrderPrice
+	for activated < budget {
+		lastPrice := tradingStateDB.GetLastPrice(orderBook)
+		triggered := tradingStateDB.GetTriggeredStopOrders(orderBook, from, lastPrice, 1)
+		if len(triggered) == 0 {
+			from, done = lastPrice, true
+			break
+		}
+		complete := true
+		for i := range triggered {
+			if activated == budget {
+				complete = false
+				break
+			}
+			activated++
+			order := &triggered[i]
+			orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
+			if err := tradingStateDB.RemoveStopOrder(orderBook, orderIdHash); err != nil {
+				log.Warn("activateStopOrders RemoveStopOrder", "err", err, "orderBook", orderBook, "orderId", order.OrderID)
+				continue
+			}
+			newTrades, newRejects, err := XDCx.activateStopOrder(coinbase, chain, statedb, tradingStateDB, orderBook, order)
+			if err != nil {
+				log.Debug("Reject stop order", "err", err, "order", tradingstate.ToJSON(order))
+				rejects = append(rejects, order)
+				continue
+			}
+			trades = append(trades, newTrades...)
+			rejects = append(rejects, newRejects...)
+		}
+		if !complete {
+			break
+		}
+		from = triggered[0].TriggerPrice
+	}
+	if from != nil && from.Cmp(stopOrderPrice) != 0 {
+		tradingStateDB.SetStopOrderPrice(orderBook, tradingstate.CloneBigInt(from))
+	}
+	return trades, rejects, activated, done
+}
+
+// activateStopOrder : match a triggered stop order as a market or limit order,
+// the changes are reverted if it fails
+func (XDCx *XDCX) activateStopOrder(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
+	var (
+		trades  []map[string]string
+		rejects []*tradingstate.OrderItem
+		err     error
+	)
+	XDCxSnap := tradingStateDB.Snapshot()
+	dbSnap := statedb.Snapshot()
+	log.Debug("Activate stop order", "orderId", order.OrderID, "side", order.Side, "triggerPrice", order.TriggerPrice, "lastPrice", tradingStateDB.GetLastPrice(orderBook))
+	if order.Type == tradingstate.StopMarket {
+		trades, rejects, err = XDCx.processMarketOrder(coinbase, chain, statedb, tradingStateDB, orderBook, order)
+	} else {
+		trades, rejects, err = XDCx.processLimitOrder(coinbase, chain, statedb, tradingStateDB, orderBook, order)
+	}
+	if err != nil {
+		tradingStateDB.RevertToSnapshot(XDCxSnap)
+		statedb.RevertToSnapshot(dbSnap)
+		return nil, nil, err
+	}
+	return trades, rejects, nil
+}
+
 // processMarketOrder : process the market order
 func (XDCx *XDCX) processMarketOrder(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
 	var (
@@ -149,6 +312,10 @@ func (XDCx *XDCX) processMarketOrder(coinbase common.Address, chain consensus.Ch
 
 // processLimitOrder : process the limit order, can change the quote
 // If not care for performance, we should make a copy of quote to prevent further reference problem
+// Orders with a time in force are matched the same way, then:
+//   - IOC: the unmatched part is cancelled instead of added to the orderbook
+//   - FOK: the order is cancelled along with all its trades unless fully matched
+//   - PO: the order is rejected by processOrderList if it would match
 func (XDCx *XDCX) processLimitOrder(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
 	var (
 		trades     []map[string]string
@@ -164,6 +331,12 @@ func (XDCx *XDCX) processLimitOrder(coinbase common.Address, chain consensus.Cha
 	// speedup the comparison, do not assign because it is pointer
 	zero := tradingstate.Zero
 
+	// a fill or kill order is matched over a snapshot to undo a partial fill
+	var XDCxSnap, dbSnap int
+	if order.Type == tradingstate.FillOrKill {
+		XDCxSnap, dbSnap = tradingStateDB.Snapshot(), statedb.Snapshot()
+	}
+
 	if side == tradingstate.Bid {
 		minPrice, volume := tradingStateDB.GetBestAskPrice(orderBook)
 		log.Debug("processLimitOrder ", "side", side, "minPrice", minPrice, "orderPrice", price, "volume", volume)
@@ -195,11 +368,25 @@ func (XDCx *XDCX) processLimitOrder(coinbase common.Address, chain consensus.Cha
 			log.Debug("processLimitOrder ", "side", side, "maxPrice", maxPrice, "orderPrice", price, "volume", volume)
 		}
 	}
-	if quantityToTrade.Cmp(zero) > 0 {
-		orderId := tradingStateDB.GetNonce(orderBook)
-		order.OrderID = orderId + 1
+	switch {
+	case order.Type == tradingstate.FillOrKill && (quantityToTrade.Cmp(zero) > 0 || slices.Contains(rejects, order)):
+		log.Debug("Kill fill or kill order", "quantity", order.Quantity, "unmatched", quantityToTrade, "trades", len(trades))
+		tradingStateDB.RevertToSnapshot(XDCxSnap)
+		statedb.RevertToSnapshot(dbSnap)
+		if slices.Contains(rejects, order) {
+			return nil, []*tradingstate.OrderItem{order}, nil
+		}
+		return nil, nil, nil
+	case order.Type == tradingstate.ImmediateOrCancel && quantityToTrade.Cmp(zero) > 0:
+		log.Debug("Cancel unmatched part of immediate or cancel order", "quantity", order.Quantity, "unmatched", quantityToTrade)
+	case quantityToTrade.Cmp(zero) > 0:
+		// an activated stop limit order keeps the id it got in the trigger tree
+		if order.Type != tradingstate.StopLimit {
+			orderId := tradingStateDB.GetNonce(orderBook)
+			order.OrderID = orderId + 1
+			tradingStateDB.SetNonce(orderBook, orderId+1)
+		}
 		order.Quantity = quantityToTrade
-		tradingStateDB.SetNonce(orderBook, orderId+1)
 		orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
 		tradingStateDB.InsertOrderItem(orderBook, orderIdHash, *order)
 		log.Debug("After matching, order (unmatched part) is now added to tree", "side", order.Side, "order", order)
@@ -209,6 +396,11 @@ func (XDCx *XDCX) processLimitOrder(coinbase common.Address, chain consensus.Cha
 
 // processOrderList : process the order list
 func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, side string, orderBook common.Hash, price *big.Int, quantityStillToTrade *big.Int, order *tradingstate.OrderItem) (*big.Int, []map[string]string, []*tradingstate.OrderItem, error) {
+	// a post only order must not take liquidity
+	if order.Type == tradingstate.PostOnly {
+		log.Debug("Reject post only order matching the orderbook", "price", order.Price, "side", order.Side)
+		return new(big.Int), nil, []*tradingstate.OrderItem{order}, nil
+	}
 	quantityToTrade := tradingstate.CloneBigInt(quantityStillToTrade)
 	log.Debug("Process matching between order and orderlist", "quantityToTrade", quantityToTrade)
 	var (
@@ -260,7 +452,7 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 			if tradedQuantity.Cmp(maxTradedQuantity) == 0 {
 				if quantityToTrade.Cmp(amount) == 0 { // reject Taker & maker
 					rejects = append(rejects, order)
-					quantityToTrade = tradingstate.Zero
+					quantityToTrade = new(big.Int)
 					rejects = append(rejects, &oldestOrder)
 					err = tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
@@ -269,7 +461,7 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 					break
 				} else if quantityToTrade.Cmp(amount) < 0 { // reject Taker
 					rejects = append(rejects, order)
-					quantityToTrade = tradingstate.Zero
+					quantityToTrade = new(big.Int)
 					break
 				} else { // reject maker
 					rejects = append(rejects, &oldestOrder)
@@ -289,7 +481,7 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 					continue
 				} else { // reject Taker
 					rejects = append(rejects, order)
-					quantityToTrade = tradingstate.Zero
+					quantityToTrade = new(big.Int)
 					break
 				}
 			}
@@ -299,7 +491,7 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 		if tradedQuantity.Sign() == 0 && !rejectMaker {
 			log.Debug("Reject order Taker ", "tradedQuantity", tradedQuantity, "rejectMaker", rejectMaker)
 			rejects = append(rejects, order)
-			quantityToTrade = tradingstate.Zero
+			quantityToTrade = new(big.Int)
 			break
 		}
 		if tradedQuantity.Sign() > 0 {
@@ -309,6 +501,7 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 				log.Warn("processOrderList SubAmountOrderItem", "err", err, "orderBook", orderBook, "orderId", orderId, "price", *price, "tradedQuantity", *tradedQuantity, "side", side)
 			}
 			tradingStateDB.SetLastPrice(orderBook, price)
+			tradingStateDB.AddPendingStopOrderBook(orderBook)
 			log.Debug("Update quantity for orderId", "orderId", orderId.Hex())
 			log.Debug("TRADE", "orderBook", orderBook, "Taker price", price, "maker price", order.Price, "Amount", tradedQuantity, "orderId", orderId, "side", side)
 
@@ -762,7 +955,7 @@ func (XDCx *XDCX) getCancelFee(chain consensus.ChainContext, statedb *state.Stat
 	return cancelFee, tokenPriceInXDC
 }
 
-func (XDCx *XDCX) UpdateMediumPriceBeforeEpoch(epochNumber uint64, tradingStateDB *tradingstate.TradingStateDB, statedb *state.StateDB) error {
+func (XDCx *XDCX) UpdateMediumPriceBeforeEpoch(header *types.Header, epochNumber uint64, tradingStateDB *tradingstate.TradingStateDB, statedb *state.StateDB) error {
 	mapPairs, err := tradingstate.GetAllTradingPairs(statedb)
 	log.Debug("UpdateMediumPriceBeforeEpoch", "len(mapPairs)", len(mapPairs))
 
@@ -770,9 +963,11 @@ func (XDCx *XDCX) UpdateMediumPriceBeforeEpoch(epochNumber uint64, tradingStateD
 		return err
 	}
 	epochPriceResult := map[common.Hash]*big.Int{}
+	epochVolumeResult := map[common.Hash]*big.Int{}
 	for orderbook := range mapPairs {
 		oldMediumPriceBeforeEpoch := tradingStateDB.GetMediumPriceBeforeEpoch(orderbook)
-		mediumPriceCurrent, _ := tradingStateDB.GetMediumPriceAndTotalAmount(orderbook)
+		mediumPriceCurrent, totalAmount := tradingStateDB.GetMediumPriceAndTotalAmount(orderbook)
+		epochVolumeResult[orderbook] = totalAmount
 
 		// if there is no trade in this epoch, use average price of last epoch
 		epochPriceResult[orderbook] = oldMediumPriceBeforeEpoch
@@ -783,29 +978,35 @@ func (XDCx *XDCX) UpdateMediumPriceBeforeEpoch(epochNumber uint64, tradingStateD
 		}
 		tradingStateDB.SetMediumPrice(orderbook, tradingstate.Zero, tradingstate.Zero)
 	}
-	if XDCx.IsSDKNode() {
-		if err := XDCx.LogEpochPrice(epochNumber, epochPriceResult); err != nil {
-			log.Error("failed to update epochPrice", "err", err)
-		}
+	if err := XDCx.LogEpochPrice(epochNumber, header.Time, epochPriceResult, epochVolumeResult); err != nil {
+		log.Error("failed to update epochPrice", "err", err)
 	}
 	return nil
 }
 
-// put average price of epoch to mongodb for tracking liquidation trades
+// put average price of epoch to the history database for tracking liquidation trades and building candles
 // epochPriceResult: a map of epoch average price, key is orderbook hash , value is epoch average price
+// epochVolumeResult: a map of traded quantity within the epoch, key is orderbook hash
+// epochTime: unix time of the block closing the epoch, bounding the trades of its candle
 // orderbook hash genereted from baseToken, quoteToken at XDPoSChain/XDCx/tradingstate/common.go:214
-func (XDCx *XDCX) LogEpochPrice(epochNumber uint64, epochPriceResult map[common.Hash]*big.Int) error {
-	db := XDCx.GetMongoDB()
+func (XDCx *XDCX) LogEpochPrice(epochNumber uint64, epochTime uint64, epochPriceResult map[common.Hash]*big.Int, epochVolumeResult map[common.Hash]*big.Int) error {
+	db := XDCx.GetHistoryDB()
 	db.InitBulk()
 
 	for orderbook, price := range epochPriceResult {
 		if price.Sign() <= 0 {
 			continue
 		}
+		volume := epochVolumeResult[orderbook]
+		if volume == nil {
+			volume = new(big.Int)
+		}
 		epochPriceItem := &tradingstate.EpochPriceItem{
 			Epoch:     epochNumber,
 			Orderbook: orderbook,
 			Price:     price,
+			Volume:    new(big.Int).Set(volume),
+			Time:      epochTime,
 		}
 		epochPriceItem.Hash = epochPriceItem.ComputeHash()
 		if err := db.PutObject(epochPriceItem.Hash, epochPriceItem); err != nil {
@@ -7,9 +7,12 @@ import (
 
 	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
 	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/node"
+	"github.com/XinFinOrg/XDPoSChain/params"
 )
 
 func Test_getCancelFeeV1(t *testing.T) {
@@ -580,3 +583,278 @@ func TestGetTradeQuantity(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeInForceOrders(t *testing.T) {
+	stack, err := node.New(&node.DefaultConfig)
+	if err != nil {
+		t.Fatalf("could not create new node: %v", err)
+	}
+	XDCx := New(stack, &DefaultConfig)
+	defer stack.Close()
+
+	var (
+		relayer   = common.HexToAddress("0x0000000000000000000000000000000000000010")
+		maker     = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		taker     = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		baseToken = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		orderBook = tradingstate.GetTradingOrderBookHash(baseToken, common.XDCNativeAddressBinary)
+		amount    = func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), common.BasePrice) }
+	)
+	XDCx.SetTokenDecimal(baseToken, common.BasePrice)
+
+	// An orderbook holding a single ask of 100 tokens at price 1
+	newBook := func() (*state.StateDB, *tradingstate.TradingStateDB) {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+		tradingStateDb, _ := tradingstate.New(types.EmptyRootHash, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+		tradingstate.SetSubRelayerFee(relayer, amount(30000), common.Big0, statedb)
+		owner := new(big.Int).Add(tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"]), tradingstate.RelayerStructMappingSlot["_owner"])
+		statedb.SetState(common.RelayerRegistrationSMC, common.BigToHash(owner), common.BytesToHash(relayer.Bytes()))
+		statedb.SetNonce(baseToken, 1)
+		tradingstate.SetTokenBalance(maker, amount(1000), baseToken, statedb)
+		statedb.SetBalance(taker, amount(1000))
+
+		ask := tradingstate.OrderItem{
+			Quantity:        amount(100),
+			Price:           common.BasePrice,
+			ExchangeAddress: relayer,
+			UserAddress:     maker,
+			BaseToken:       baseToken,
+			QuoteToken:      common.XDCNativeAddressBinary,
+			Status:          tradingstate.OrderStatusOpen,
+			Side:            tradingstate.Ask,
+			Type:            tradingstate.Limit,
+			Hash:            common.HexToHash("0x01"),
+			OrderID:         1,
+		}
+		tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(1)), ask)
+		tradingStateDb.SetNonce(orderBook, 1)
+		return statedb, tradingStateDb
+	}
+	newBid := func(orderType string, price, quantity *big.Int) *tradingstate.OrderItem {
+		return &tradingstate.OrderItem{
+			Quantity:        quantity,
+			Price:           price,
+			ExchangeAddress: relayer,
+			UserAddress:     taker,
+			BaseToken:       baseToken,
+			QuoteToken:      common.XDCNativeAddressBinary,
+			Status:          tradingstate.OrderStatusNew,
+			Side:            tradingstate.Bid,
+			Type:            orderType,
+			Hash:            common.HexToHash("0x02"),
+		}
+	}
+	tests := []struct {
+		name     string
+		order    *tradingstate.OrderItem
+		trades   int
+		rejected bool
+		ask      *big.Int // volume left at the best ask
+		bid      *big.Int // best bid price after processing
+	}{
+		{"LO: unmatched part added to the orderbook", newBid(tradingstate.Limit, common.BasePrice, amount(150)), 1, false, common.Big0, common.BasePrice},
+		{"IOC: unmatched part cancelled", newBid(tradingstate.ImmediateOrCancel, common.BasePrice, amount(150)), 1, false, common.Big0, common.Big0},
+		{"IOC: nothing to match", newBid(tradingstate.ImmediateOrCancel, big.NewInt(1), amount(50)), 0, false, amount(100), common.Big0},
+		{"FOK: fully matched", newBid(tradingstate.FillOrKill, common.BasePrice, amount(100)), 1, false, common.Big0, common.Big0},
+		{"FOK: partially matched, killed", newBid(tradingstate.FillOrKill, common.BasePrice, amount(150)), 0, false, amount(100), common.Big0},
+		{"PO: would match, rejected", newBid(tradingstate.PostOnly, common.BasePrice, amount(50)), 0, true, amount(100), common.Big0},
+		{"PO: added to the orderbook", newBid(tradingstate.PostOnly, big.NewInt(1), amount(50)), 0, false, amount(100), big.NewInt(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statedb, tradingStateDb := newBook()
+			trades, rejects, err := XDCx.processLimitOrder(common.Address{}, nil, statedb, tradingStateDb, orderBook, tt.order)
+			if err != nil {
+				t.Fatalf("processLimitOrder() error = %v", err)
+			}
+			if len(trades) != tt.trades {
+				t.Errorf("trades = %d, want %d", len(trades), tt.trades)
+			}
+			if rejected := len(rejects) == 1 && rejects[0] == tt.order; rejected != tt.rejected || (!rejected && len(rejects) > 0) {
+				t.Errorf("rejects = %v, want taker rejected %v", rejects, tt.rejected)
+			}
+			if _, volume := tradingStateDb.GetBestAskPrice(orderBook); volume.Cmp(tt.ask) != 0 {
+				t.Errorf("ask volume = %v, want %v", volume, tt.ask)
+			}
+			if price, _ := tradingStateDb.GetBestBidPrice(orderBook); price.Cmp(tt.bid) != 0 {
+				t.Errorf("best bid = %v, want %v", price, tt.bid)
+			}
+		})
+	}
+}
+
+// stopOrderChain is the chain context of the stop order tests.
+type stopOrderChain struct {
+	consensus.ChainContext
+}
+
+func (stopOrderChain) Config() *params.ChainConfig { return params.TestXDPoSMockChainConfig }
+
+func TestStopOrders(t *testing.T) {
+	stack, err := node.New(&node.DefaultConfig)
+	if err != nil {
+		t.Fatalf("could not create new node: %v", err)
+	}
+	XDCx := New(stack, &DefaultConfig)
+	defer stack.Close()
+
+	var (
+		relayer   = common.HexToAddress("0x0000000000000000000000000000000000000010")
+		maker     = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		taker     = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		baseToken = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		orderBook = tradingstate.GetTradingOrderBookHash(baseToken, common.XDCNativeAddressBinary)
+		amount    = func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), common.BasePrice) }
+		half      = new(big.Int).Div(common.BasePrice, common.Big2)
+	)
+	XDCx.SetTokenDecimal(baseToken, common.BasePrice)
+
+	backup := common.TIPXDCXStopOrders
+	common.TIPXDCXStopOrders = big.NewInt(0)
+	defer func() { common.TIPXDCXStopOrders = backup }()
+	var (
+		header   = &types.Header{Number: big.NewInt(1)}
+		coinbase common.Address
+		chain    = stopOrderChain{}
+	)
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	tradingStateDb, _ := tradingstate.New(types.EmptyRootHash, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+	tradingstate.SetSubRelayerFee(relayer, amount(30000), common.Big0, statedb)
+	owner := new(big.Int).Add(tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"]), tradingstate.RelayerStructMappingSlot["_owner"])
+	statedb.SetState(common.RelayerRegistrationSMC, common.BigToHash(owner), common.BytesToHash(relayer.Bytes()))
+	statedb.SetNonce(baseToken, 1)
+	tradingstate.SetTokenBalance(maker, amount(1000), baseToken, statedb)
+	statedb.SetBalance(taker, amount(1000))
+
+	// An ask of 100 tokens at price 1, the pair last traded at 0.5
+	tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(1)), tradingstate.OrderItem{
+		Quantity:        amount(100),
+		Price:           common.BasePrice,
+		ExchangeAddress: relayer,
+		UserAddress:     maker,
+		BaseToken:       baseToken,
+		QuoteToken:      common.XDCNativeAddressBinary,
+		Status:          tradingstate.OrderStatusOpen,
+		Side:            tradingstate.Ask,
+		Type:            tradingstate.Limit,
+		Hash:            common.HexToHash("0x01"),
+		OrderID:         1,
+	})
+	tradingStateDb.SetNonce(orderBook, 1)
+	tradingStateDb.SetLastPrice(orderBook, half)
+
+	newOrder := func(user common.Address, side, orderType string, price, triggerPrice, quantity *big.Int, hash byte) *tradingstate.OrderItem {
+		return &tradingstate.OrderItem{
+			Quantity:        quantity,
+			Price:           price,
+			TriggerPrice:    triggerPrice,
+			ExchangeAddress: relayer,
+			UserAddress:     user,
+			BaseToken:       baseToken,
+			QuoteToken:      common.XDCNativeAddressBinary,
+			Status:          tradingstate.OrderStatusNew,
+			Side:            side,
+			Type:            orderType,
+			Hash:            common.BytesToHash([]byte{hash}),
+		}
+	}
+	stopBuy := newOrder(taker, tradingstate.Bid, tradingstate.StopMarket, nil, common.BasePrice, amount(50), 2)
+	takeProfit := newOrder(taker, tradingstate.Bid, tradingstate.StopLimit, amount(3), amount(3), amount(10), 3)
+	stopLoss := newOrder(maker, tradingstate.Ask, tradingstate.StopMarket, nil, big.NewInt(1), amount(10), 4)
+	for _, order := range []*tradingstate.OrderItem{stopBuy, takeProfit, stopLoss} {
+		if !XDCx.processStopOrder(tradingStateDb, orderBook, order) {
+			t.Fatalf("stop order %s rejected", order.Hash.Hex())
+		}
+	}
+	if XDCx.processStopOrder(tradingStateDb, orderBook, newOrder(taker, tradingstate.Bid, tradingstate.StopMarket, nil, half, amount(1), 5)) {
+		t.Errorf("stop order triggered at the last price should be rejected")
+	}
+	if _, volume := tradingStateDb.GetBestAskPrice(orderBook); volume.Cmp(amount(100)) != 0 {
+		t.Errorf("ask volume = %v, want %v", volume, amount(100))
+	}
+
+	// A trade at 1 activates the stop buy order, which is matched against the same ask
+	trades, rejects, err := XDCx.processLimitOrder(common.Address{}, nil, statedb, tradingStateDb, orderBook, newOrder(taker, tradingstate.Bid, tradingstate.Limit, common.BasePrice, nil, amount(10), 6))
+	if err != nil || len(trades) != 1 || len(rejects) != 0 {
+		t.Fatalf("processLimitOrder() trades = %d, rejects = %d, err = %v", len(trades), len(rejects), err)
+	}
+	if pending := tradingStateDb.GetPendingStopOrderBooks(); len(pending) != 1 || pending[0] != orderBook {
+		t.Fatalf("pending stop order books = %v, want the traded orderbook", pending)
+	}
+	result := XDCx.ActivateStopOrders(header, coinbase, chain, statedb, tradingStateDb)
+	trades = result.Trades
+	if len(trades) != 1 || len(result.Rejects) != 0 {
+		t.Fatalf("ActivateStopOrders() trades = %d, rejects = %d", len(trades), len(result.Rejects))
+	}
+	if pending := tradingStateDb.GetPendingStopOrderBooks(); len(pending) != 0 {
+		t.Errorf("pending stop order books after activation = %v, want none", pending)
+	}
+	if price := tradingStateDb.GetStopOrderPrice(orderBook); price.Cmp(common.BasePrice) != 0 {
+		t.Errorf("stop order price = %v, want %v", price, common.BasePrice)
+	}
+	if trades[0][tradingstate.TradeTakerOrderHash] != stopBuy.Hash.Hex() {
+		t.Errorf("taker of the activated trade = %s, want %s", trades[0][tradingstate.TradeTakerOrderHash], stopBuy.Hash.Hex())
+	}
+	if _, volume := tradingStateDb.GetBestAskPrice(orderBook); volume.Cmp(amount(40)) != 0 {
+		t.Errorf("ask volume = %v, want %v", volume, amount(40))
+	}
+	if triggered := tradingStateDb.GetTriggeredStopOrders(orderBook, common.BasePrice, big.NewInt(1), MaximumStopOrderActivations); len(triggered) != 1 || triggered[0].Hash != stopLoss.Hash {
+		t.Errorf("pending stop orders below the last price = %v, want the stop loss", triggered)
+	}
+
+	// Pending stop orders are cancelled out of the trigger tree
+	snap := tradingStateDb.Snapshot()
+	if err := tradingStateDb.CancelOrder(orderBook, takeProfit); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	if triggered := tradingStateDb.GetTriggeredStopOrders(orderBook, common.BasePrice, amount(5), MaximumStopOrderActivations); len(triggered) != 0 {
+		t.Errorf("cancelled stop order still pending: %v", triggered)
+	}
+	if err := tradingStateDb.CancelOrder(orderBook, stopBuy); err == nil {
+		t.Errorf("activated stop market order cancelled")
+	}
+	tradingStateDb.RevertToSnapshot(snap)
+	if triggered := tradingStateDb.GetTriggeredStopOrders(orderBook, common.BasePrice, amount(5), MaximumStopOrderActivations); len(triggered) != 1 || triggered[0].Hash != takeProfit.Hash {
+		t.Errorf("reverted cancel: pending stop orders = %v, want the take profit", triggered)
+	}
+
+	// The trigger tree is read one trigger price at a time, nearest first
+	nearer := newOrder(taker, tradingstate.Bid, tradingstate.StopMarket, nil, amount(2), amount(1), 7)
+	if !XDCx.processStopOrder(tradingStateDb, orderBook, nearer) {
+		t.Fatalf("stop order %s rejected", nearer.Hash.Hex())
+	}
+	if triggered := tradingStateDb.GetTriggeredStopOrders(orderBook, common.BasePrice, amount(5), 1); len(triggered) != 1 || triggered[0].Hash != nearer.Hash {
+		t.Errorf("nearest pending stop orders = %v, want the nearer stop buy", triggered)
+	}
+	if triggered := tradingStateDb.GetTriggeredStopOrders(orderBook, common.BasePrice, amount(5), 2); len(triggered) != 2 || triggered[1].Hash != takeProfit.Hash {
+		t.Errorf("pending stop orders = %v, want the nearer stop buy and the take profit", triggered)
+	}
+
+	// A trigger price crossed since the last activation is rejected
+	tradingStateDb.SetLastPrice(orderBook, amount(4))
+	tradingStateDb.AddPendingStopOrderBook(orderBook)
+	if XDCx.processStopOrder(tradingStateDb, orderBook, newOrder(taker, tradingstate.Bid, tradingstate.StopMarket, nil, new(big.Int).Add(common.BasePrice, half), amount(1), 8)) {
+		t.Errorf("stop order triggered within the crossed range should be rejected")
+	}
+
+	// Orders over the budget wait for the next block
+	if _, _, activated, done := XDCx.activateStopOrders(common.Address{}, chain, statedb, tradingStateDb, orderBook, 1); activated != 1 || done {
+		t.Errorf("activated = %d, done = %v, want 1 and false", activated, done)
+	}
+	if price := tradingStateDb.GetStopOrderPrice(orderBook); price.Cmp(amount(2)) != 0 {
+		t.Errorf("stop order price = %v, want %v", price, amount(2))
+	}
+	if triggered := tradingStateDb.GetTriggeredStopOrders(orderBook, amount(2), amount(4), MaximumStopOrderActivations); len(triggered) != 1 || triggered[0].Hash != takeProfit.Hash {
+		t.Errorf("pending stop orders = %v, want the take profit", triggered)
+	}
+	if pending := tradingStateDb.GetPendingStopOrderBooks(); len(pending) != 1 {
+		t.Fatalf("pending stop order books = %v, want the orderbook left over by the budget", pending)
+	}
+	if result := XDCx.ActivateStopOrders(header, coinbase, chain, statedb, tradingStateDb); len(result.Trades) != 1 || result.Trades[0][tradingstate.TradeTakerOrderHash] != takeProfit.Hash.Hex() {
+		t.Errorf("next block trades = %v, want the take profit activated", result.Trades)
+	}
+	if pending := tradingStateDb.GetPendingStopOrderBooks(); len(pending) != 0 {
+		t.Errorf("pending stop order books after the next block = %v, want none", pending)
+	}
+}
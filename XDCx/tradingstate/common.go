@@ -23,10 +23,23 @@ var (
 	Limit     = "LO"
 	Cancel    = "CANCELLED"
 	OrderNew  = "NEW"
+
+	// Limit orders with a time in force, accepted from TIPXDCXOrderTypes
+	ImmediateOrCancel = "IOC" // matched at once, the rest is cancelled
+	FillOrKill        = "FOK" // matched at once in full, or cancelled
+	PostOnly          = "PO"  // added to the orderbook, rejected if it would match
+
+	// Orders waiting for the last price to cross a trigger price, accepted from TIPXDCXStopOrders
+	StopMarket = "SMO" // activated as a market order
+	StopLimit  = "SLO" // activated as a limit order
 )
 
 var EmptyHash = common.Hash{}
 var Zero = big.NewInt(0)
+
+// PendingStopOrderBooksHash is the key of the exchange object listing the
+// orderbooks whose stop orders wait to be activated.
+var PendingStopOrderBooksHash = crypto.Keccak256Hash([]byte("XDCx.pendingStopOrderBooks"))
 var One = big.NewInt(1)
 
 var EmptyOrderList = orderList{
@@ -50,11 +63,30 @@ var (
 	ErrInvalidOrderType = errors.New("verify order: unsupported order type")
 	ErrInvalidOrderSide = errors.New("verify order: invalid order side")
 	ErrInvalidStatus    = errors.New("verify order: invalid status")
+	ErrInvalidTrigger   = errors.New("verify order: invalid trigger price")
 
 	// supported order types
 	MatchingOrderType = map[string]bool{
-		Market: true,
-		Limit:  true,
+		Market:            true,
+		Limit:             true,
+		ImmediateOrCancel: true,
+		FillOrKill:        true,
+		PostOnly:          true,
+		StopMarket:        true,
+		StopLimit:         true,
+	}
+
+	// order types introduced at TIPXDCXOrderTypes
+	TimeInForceOrderType = map[string]bool{
+		ImmediateOrCancel: true,
+		FillOrKill:        true,
+		PostOnly:          true,
+	}
+
+	// order types introduced at TIPXDCXStopOrders
+	StopOrderType = map[string]bool{
+		StopMarket: true,
+		StopLimit:  true,
 	}
 )
 
@@ -78,6 +110,9 @@ type tradingExchangeObject struct {
 	BidRoot                common.Hash // merkle root of the storage trie
 	OrderRoot              common.Hash
 	LiquidationPriceRoot   common.Hash
+	StopOrderRoot          common.Hash   `rlp:"optional"` // zero until the first stop order, keeps older encodings
+	StopOrderPrice         *big.Int      `rlp:"optional"` // last price the stop orders have been activated from
+	PendingStopOrderBooks  []common.Hash `rlp:"optional"` // only on PendingStopOrderBooksHash, see GetPendingStopOrderBooks
 }
 
 var (
@@ -151,10 +186,10 @@ func (tx TxDataMatch) DecodeOrder() (*OrderItem, error) {
 }
 
 type OrderHistoryItem struct {
-	TxHash       common.Hash
-	FilledAmount *big.Int
-	Status       string
-	UpdatedAt    time.Time
+	TxHash       common.Hash `json:"txHash"`
+	FilledAmount *big.Int    `json:"filledAmount"`
+	Status       string      `json:"status"`
+	UpdatedAt    time.Time   `json:"updatedAt"`
 }
 
 // ToJSON : log json string
@@ -383,3 +383,169 @@ func (t *TradingStateDB) DumpLiquidationPriceTrie(orderBook common.Hash) (map[*b
 	}
 	return mapResult, nil
 }
+
+// DumpOrderBook is the content of a single orderbook of the trading state.
+type DumpOrderBook struct {
+	Info              *DumpOrderBookInfo
+	Asks              map[*big.Int]DumpOrderList
+	Bids              map[*big.Int]DumpOrderList
+	StopOrders        map[*big.Int]DumpOrderList
+	Orders            map[*big.Int]OrderItem
+	LiquidationPrices map[*big.Int]DumpLendingBook
+}
+
+// DumpState is the content of a whole trading state: every orderbook and the
+// order nonce of every user.
+type DumpState struct {
+	OrderBooks            map[common.Hash]*DumpOrderBook
+	Nonces                map[common.Address]uint64
+	PendingStopOrderBooks []common.Hash
+}
+
+// isUserNonce reports whether an object of the trading state trie holds the
+// order nonce of a user rather than an orderbook. Both share the same key
+// space, but a user object never carries prices or trees.
+func (te *tradingExchanges) isUserNonce() bool {
+	for _, value := range []*big.Int{te.data.LastPrice, te.data.MediumPrice, te.data.MediumPriceBeforeEpoch, te.data.TotalQuantity, te.data.LendingCount, te.data.StopOrderPrice} {
+		if value != nil && value.Sign() != 0 {
+			return false
+		}
+	}
+	for _, root := range []common.Hash{te.data.AskRoot, te.data.BidRoot, te.data.OrderRoot, te.data.LiquidationPriceRoot, te.data.StopOrderRoot} {
+		if root != (common.Hash{}) && root != EmptyRoot {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *TradingStateDB) DumpStopOrderTrie(orderBook common.Hash) (map[*big.Int]DumpOrderList, error) {
+	exhangeObject := t.getStateExchangeObject(orderBook)
+	if exhangeObject == nil {
+		return nil, fmt.Errorf("not found orderBook: %v", orderBook.Hex())
+	}
+	mapResult := map[*big.Int]DumpOrderList{}
+	it := trie.NewIterator(exhangeObject.getStopOrderTrie(t.db).NodeIterator(nil))
+	for it.Next() {
+		priceHash := common.BytesToHash(it.Key)
+		if priceHash.IsZero() {
+			continue
+		}
+		if _, exist := exhangeObject.stopOrderStates[priceHash]; exist {
+			continue
+		}
+		price := new(big.Int).SetBytes(priceHash.Bytes())
+		var data orderList
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return nil, fmt.Errorf("fail when decode stop order list orderBook: %v , trigger price : %v", orderBook.Hex(), price)
+		}
+		stateOrderList := newStateOrderList(t, StopMarket, orderBook, priceHash, data, nil)
+		mapResult[price] = stateOrderList.DumpOrderList(t.db)
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	for priceHash, stateOrderList := range exhangeObject.stopOrderStates {
+		if stateOrderList.Volume().Sign() > 0 {
+			mapResult[new(big.Int).SetBytes(priceHash.Bytes())] = stateOrderList.DumpOrderList(t.db)
+		}
+	}
+	return mapResult, nil
+}
+
+func (t *TradingStateDB) DumpOrderTrie(orderBook common.Hash) (map[*big.Int]OrderItem, error) {
+	exhangeObject := t.getStateExchangeObject(orderBook)
+	if exhangeObject == nil {
+		return nil, fmt.Errorf("not found orderBook: %v", orderBook.Hex())
+	}
+	mapResult := map[*big.Int]OrderItem{}
+	it := trie.NewIterator(exhangeObject.getOrdersTrie(t.db).NodeIterator(nil))
+	for it.Next() {
+		orderIdHash := common.BytesToHash(it.Key)
+		if orderIdHash.IsZero() {
+			continue
+		}
+		if _, exist := exhangeObject.stateOrderObjects[orderIdHash]; exist {
+			continue
+		}
+		orderId := new(big.Int).SetBytes(orderIdHash.Bytes())
+		var data OrderItem
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return nil, fmt.Errorf("fail when decode order orderBook: %v , orderId : %v", orderBook.Hex(), orderId)
+		}
+		mapResult[orderId] = data
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	for orderIdHash, stateOrderItem := range exhangeObject.stateOrderObjects {
+		mapResult[new(big.Int).SetBytes(orderIdHash.Bytes())] = stateOrderItem.data
+	}
+	return mapResult, nil
+}
+
+// DumpOrderBook returns every tree of the given orderbook.
+func (t *TradingStateDB) DumpOrderBook(orderBook common.Hash) (*DumpOrderBook, error) {
+	var (
+		result = new(DumpOrderBook)
+		err    error
+	)
+	if result.Info, err = t.DumpOrderBookInfo(orderBook); err != nil {
+		return nil, err
+	}
+	if result.Asks, err = t.DumpAskTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.Bids, err = t.DumpBidTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.StopOrders, err = t.DumpStopOrderTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.Orders, err = t.DumpOrderTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.LiquidationPrices, err = t.DumpLiquidationPriceTrie(orderBook); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Dump returns every orderbook and user nonce of the trading state.
+func (t *TradingStateDB) Dump() (*DumpState, error) {
+	result := &DumpState{
+		OrderBooks: map[common.Hash]*DumpOrderBook{},
+		Nonces:     map[common.Address]uint64{},
+	}
+	keys := map[common.Hash]struct{}{}
+	it := trie.NewIterator(t.trie.NodeIterator(nil))
+	for it.Next() {
+		keys[common.BytesToHash(it.Key)] = struct{}{}
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	for key := range t.stateExhangeObjects {
+		keys[key] = struct{}{}
+	}
+	for key := range keys {
+		exhangeObject := t.getStateExchangeObject(key)
+		if exhangeObject == nil {
+			continue
+		}
+		if key == PendingStopOrderBooksHash {
+			result.PendingStopOrderBooks = t.GetPendingStopOrderBooks()
+			continue
+		}
+		if exhangeObject.isUserNonce() {
+			result.Nonces[common.BytesToAddress(key[:])] = exhangeObject.Nonce()
+			continue
+		}
+		orderBook, err := t.DumpOrderBook(key)
+		if err != nil {
+			return nil, err
+		}
+		result.OrderBooks[key] = orderBook
+	}
+	return result, t.Error()
+}
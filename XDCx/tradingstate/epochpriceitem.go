@@ -15,6 +15,8 @@ type EpochPriceItem struct {
 	Orderbook common.Hash `bson:"orderbook" json:"orderbook"`
 	Hash      common.Hash `bson:"hash" json:"hash"`
 	Price     *big.Int    `bson:"price" json:"price"`
+	Volume    *big.Int    `bson:"volume" json:"volume"`
+	Time      uint64      `bson:"time" json:"time"` // Unix time of the block closing the epoch
 }
 
 type EpochPriceItemBSON struct {
@@ -22,15 +24,22 @@ type EpochPriceItemBSON struct {
 	Orderbook string `bson:"orderbook" json:"orderbook"`
 	Hash      string `bson:"hash" json:"hash"` // Keccak256Hash of Epoch and orderbook, used as an index of this collection
 	Price     string `bson:"price" json:"price"`
+	Volume    string `bson:"volume" json:"volume"`
+	Time      string `bson:"time" json:"time"`
 }
 
 func (item *EpochPriceItem) GetBSON() (interface{}, error) {
-	return EpochPriceItemBSON{
+	decoded := EpochPriceItemBSON{
 		Epoch:     strconv.FormatUint(item.Epoch, 10),
 		Orderbook: item.Orderbook.Hex(),
 		Price:     item.Price.String(),
 		Hash:      item.Hash.Hex(),
-	}, nil
+		Time:      strconv.FormatUint(item.Time, 10),
+	}
+	if item.Volume != nil {
+		decoded.Volume = item.Volume.String()
+	}
+	return decoded, nil
 }
 
 func (item *EpochPriceItem) SetBSON(raw bson.Raw) error {
@@ -50,6 +59,14 @@ func (item *EpochPriceItem) SetBSON(raw bson.Raw) error {
 	if decoded.Price != "" {
 		item.Price = ToBigInt(decoded.Price)
 	}
+	if decoded.Volume != "" {
+		item.Volume = ToBigInt(decoded.Volume)
+	}
+	if decoded.Time != "" {
+		if item.Time, err = strconv.ParseUint(decoded.Time, 10, 64); err != nil {
+			return fmt.Errorf("failed to parse EpochPriceItem.Time. Err: %v", err)
+		}
+	}
 	return nil
 }
 
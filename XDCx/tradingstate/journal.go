@@ -55,6 +55,13 @@ type (
 		hash common.Hash
 		prev *big.Int
 	}
+	stopOrderPriceChange struct {
+		hash common.Hash
+		prev *big.Int
+	}
+	pendingStopOrderBooksChange struct {
+		prev []common.Hash
+	}
 	mediumPriceChange struct {
 		hash         common.Hash
 		prevPrice    *big.Int
@@ -76,6 +83,15 @@ type (
 		lendingBook common.Hash
 		tradeId     uint64
 	}
+	insertStopOrder struct {
+		orderBook common.Hash
+		orderId   common.Hash
+	}
+	removeStopOrder struct {
+		orderBook common.Hash
+		orderId   common.Hash
+		order     OrderItem
+	}
 )
 
 func (ch insertOrder) undo(s *TradingStateDB) {
@@ -96,6 +112,15 @@ func (ch insertLiquidationPrice) undo(s *TradingStateDB) {
 func (ch removeLiquidationPrice) undo(s *TradingStateDB) {
 	s.InsertLiquidationPrice(ch.orderBook, ch.price, ch.lendingBook, ch.tradeId)
 }
+func (ch insertStopOrder) undo(s *TradingStateDB) {
+	err := s.RemoveStopOrder(ch.orderBook, ch.orderId)
+	if err != nil {
+		log.Warn("undo RemoveStopOrder", "err", err, "ch.orderBook", ch.orderBook, "ch.orderId", ch.orderId)
+	}
+}
+func (ch removeStopOrder) undo(s *TradingStateDB) {
+	s.InsertStopOrder(ch.orderBook, ch.orderId, ch.order)
+}
 func (ch subAmountOrder) undo(s *TradingStateDB) {
 	priceHash := common.BigToHash(ch.order.Price)
 	stateOrderBook := s.getStateExchangeObject(ch.orderBook)
@@ -111,8 +136,13 @@ func (ch subAmountOrder) undo(s *TradingStateDB) {
 	stateOrderItem := stateOrderBook.getStateOrderObject(s.db, ch.orderId)
 	newAmount := new(big.Int).Add(stateOrderItem.Quantity(), ch.amount)
 	stateOrderItem.setVolume(newAmount)
+	// the order list was removed from the orderbook if the trade emptied it
+	emptied := stateOrderList.empty()
 	stateOrderList.insertOrderItem(s.db, ch.orderId, common.BigToHash(newAmount))
 	stateOrderList.AddVolume(ch.amount)
+	if emptied {
+		stateOrderBook.restoreStateOrderListObject(s.db, ch.order.Side, stateOrderList)
+	}
 }
 func (ch nonceChange) undo(s *TradingStateDB) {
 	s.SetNonce(ch.hash, ch.prev)
@@ -120,6 +150,12 @@ func (ch nonceChange) undo(s *TradingStateDB) {
 func (ch lastPriceChange) undo(s *TradingStateDB) {
 	s.SetLastPrice(ch.hash, ch.prev)
 }
+func (ch stopOrderPriceChange) undo(s *TradingStateDB) {
+	s.SetStopOrderPrice(ch.hash, ch.prev)
+}
+func (ch pendingStopOrderBooksChange) undo(s *TradingStateDB) {
+	s.GetOrNewStateExchangeObject(PendingStopOrderBooksHash).setPendingStopOrderBooks(ch.prev)
+}
 func (ch mediumPriceChange) undo(s *TradingStateDB) {
 	s.SetMediumPrice(ch.hash, ch.prevPrice, ch.prevQuantity)
 }
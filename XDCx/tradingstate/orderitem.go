@@ -43,6 +43,7 @@ type OrderItem struct {
 	UpdatedAt       time.Time      `json:"updatedAt,omitempty"`
 	OrderID         uint64         `json:"orderID,omitempty"`
 	ExtraData       string         `json:"extraData,omitempty"`
+	TriggerPrice    *big.Int       `json:"triggerPrice,omitempty" rlp:"optional"`
 }
 
 // Signature struct
@@ -77,6 +78,7 @@ type OrderItemBSON struct {
 	UpdatedAt       time.Time        `json:"updatedAt,omitempty" bson:"updatedAt"`
 	OrderID         string           `json:"orderID,omitempty" bson:"orderID"`
 	ExtraData       string           `json:"extraData,omitempty" bson:"extraData"`
+	TriggerPrice    string           `json:"triggerPrice,omitempty" bson:"triggerPrice,omitempty"`
 }
 
 func (o *OrderItem) GetBSON() (interface{}, error) {
@@ -103,6 +105,10 @@ func (o *OrderItem) GetBSON() (interface{}, error) {
 		or.FilledAmount = o.FilledAmount.String()
 	}
 
+	if o.TriggerPrice != nil {
+		or.TriggerPrice = o.TriggerPrice.String()
+	}
+
 	if o.Signature != nil {
 		or.Signature = &SignatureRecord{
 			V: o.Signature.V,
@@ -137,6 +143,7 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 		UpdatedAt       time.Time        `json:"updatedAt" bson:"updatedAt"`
 		OrderID         string           `json:"orderID" bson:"orderID"`
 		ExtraData       string           `json:"extraData,omitempty" bson:"extraData"`
+		TriggerPrice    string           `json:"triggerPrice,omitempty" bson:"triggerPrice"`
 	})
 
 	err := raw.Unmarshal(decoded)
@@ -168,6 +175,10 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 		o.Price = ToBigInt(decoded.Price)
 	}
 
+	if decoded.TriggerPrice != "" {
+		o.TriggerPrice = ToBigInt(decoded.TriggerPrice)
+	}
+
 	if decoded.Signature != nil {
 		o.Signature = &Signature{
 			V: byte(decoded.Signature.V),
@@ -207,11 +218,16 @@ func (o *OrderItem) VerifyOrder(state *state.StateDB) error {
 func (o *OrderItem) VerifyBasicOrderInfo() error {
 
 	if o.Status == OrderNew {
-		if o.Type == Limit {
+		if o.Type == Limit || o.Type == StopLimit || TimeInForceOrderType[o.Type] {
 			if err := o.verifyPrice(); err != nil {
 				return err
 			}
 		}
+		if StopOrderType[o.Type] {
+			if err := o.verifyTriggerPrice(); err != nil {
+				return err
+			}
+		}
 		if err := o.verifyQuantity(); err != nil {
 			return err
 		}
@@ -252,6 +268,7 @@ func (o *OrderItem) verifySignature() error {
 
 	tx := types.NewOrderTransaction(uint64(n), o.Quantity, o.Price, o.ExchangeAddress, o.UserAddress,
 		o.BaseToken, o.QuoteToken, o.Status, o.Side, o.Type, o.Hash, o.OrderID)
+	tx.SetTriggerPrice(o.TriggerPrice)
 	tx.ImportSignature(V, R, S)
 	from, _ := types.OrderSender(types.OrderTxSigner{}, tx)
 	if from != tx.UserAddress() {
@@ -295,6 +312,15 @@ func (o *OrderItem) verifyPrice() error {
 	return nil
 }
 
+// verifyTriggerPrice make sure a stop order has a positive trigger price
+func (o *OrderItem) verifyTriggerPrice() error {
+	if o.TriggerPrice == nil || o.TriggerPrice.Sign() <= 0 {
+		log.Debug("Invalid trigger price", "triggerPrice", o.TriggerPrice)
+		return ErrInvalidTrigger
+	}
+	return nil
+}
+
 // verifyQuantity make sure quantity is a positive number
 func (o *OrderItem) verifyQuantity() error {
 	if o.Quantity == nil || o.Quantity.Sign() <= 0 {
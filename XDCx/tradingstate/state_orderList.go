@@ -21,10 +21,12 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"slices"
 
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
 )
 
 // stateObject represents an Ethereum orderId which is being modified.
@@ -124,6 +126,28 @@ func (s *stateOrderList) GetOrderAmount(db Database, orderId common.Hash) common
 	return amount
 }
 
+// getAllOrderIds returns the ids of the orders in the list, in ascending order
+func (s *stateOrderList) getAllOrderIds(db Database) []common.Hash {
+	orderIds := []common.Hash{}
+	for orderId, amount := range s.cachedStorage {
+		if !amount.IsZero() {
+			orderIds = append(orderIds, orderId)
+		}
+	}
+	orderListIt := trie.NewIterator(s.getTrie(db).NodeIterator(nil))
+	for orderListIt.Next() {
+		orderId := common.BytesToHash(orderListIt.Key)
+		if _, exist := s.cachedStorage[orderId]; exist {
+			continue
+		}
+		orderIds = append(orderIds, orderId)
+	}
+	slices.SortFunc(orderIds, func(a, b common.Hash) int {
+		return a.Cmp(b)
+	})
+	return orderIds
+}
+
 // SetState updates a value in orderId storage.
 func (s *stateOrderList) insertOrderItem(db Database, orderId common.Hash, amount common.Hash) {
 	s.setOrderItem(orderId, amount)
@@ -25,6 +25,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
 )
 
 // stateObject represents an Ethereum orderId which is being modified.
@@ -50,6 +51,7 @@ type tradingExchanges struct {
 	bidsTrie             Trie // storage trie, which becomes non-nil on first access
 	ordersTrie           Trie // storage trie, which becomes non-nil on first access
 	liquidationPriceTrie Trie
+	stopOrderTrie        Trie // stop orders keyed by trigger price
 
 	stateAskObjects      map[common.Hash]*stateOrderList
 	stateAskObjectsDirty map[common.Hash]struct{}
@@ -63,6 +65,9 @@ type tradingExchanges struct {
 	liquidationPriceStates      map[common.Hash]*liquidationPriceState
 	liquidationPriceStatesDirty map[common.Hash]struct{}
 
+	stopOrderStates      map[common.Hash]*stateOrderList
+	stopOrderStatesDirty map[common.Hash]struct{}
+
 	onDirty func(hash common.Hash) // Callback method to mark a state object newly dirty
 }
 
@@ -98,6 +103,15 @@ func (te *tradingExchanges) empty() bool {
 	if !te.data.LiquidationPriceRoot.IsZero() {
 		return false
 	}
+	if !te.data.StopOrderRoot.IsZero() {
+		return false
+	}
+	if te.data.StopOrderPrice != nil && te.data.StopOrderPrice.Sign() > 0 {
+		return false
+	}
+	if len(te.data.PendingStopOrderBooks) > 0 {
+		return false
+	}
 	return true
 }
 
@@ -115,6 +129,8 @@ func newStateExchanges(db *TradingStateDB, hash common.Hash, data tradingExchang
 		stateBidObjectsDirty:        make(map[common.Hash]struct{}),
 		stateOrderObjectsDirty:      make(map[common.Hash]struct{}),
 		liquidationPriceStatesDirty: make(map[common.Hash]struct{}),
+		stopOrderStates:             make(map[common.Hash]*stateOrderList),
+		stopOrderStatesDirty:        make(map[common.Hash]struct{}),
 		onDirty:                     onDirty,
 	}
 }
@@ -358,6 +374,15 @@ func (te *tradingExchanges) deepCopy(db *TradingStateDB, onDirty func(hash commo
 	for price := range te.liquidationPriceStatesDirty {
 		stateExchanges.liquidationPriceStatesDirty[price] = struct{}{}
 	}
+	if te.stopOrderTrie != nil {
+		stateExchanges.stopOrderTrie = db.db.CopyTrie(te.stopOrderTrie)
+	}
+	for price, stopOrderList := range te.stopOrderStates {
+		stateExchanges.stopOrderStates[price] = stopOrderList.deepCopy(db, te.MarkStateStopOrderListDirty)
+	}
+	for price := range te.stopOrderStatesDirty {
+		stateExchanges.stopOrderStatesDirty[price] = struct{}{}
+	}
 	return stateExchanges
 }
 
@@ -390,6 +415,22 @@ func (te *tradingExchanges) setLastPrice(price *big.Int) {
 	}
 }
 
+func (te *tradingExchanges) setStopOrderPrice(price *big.Int) {
+	te.data.StopOrderPrice = price
+	if te.onDirty != nil {
+		te.onDirty(te.Hash())
+		te.onDirty = nil
+	}
+}
+
+func (te *tradingExchanges) setPendingStopOrderBooks(orderBooks []common.Hash) {
+	te.data.PendingStopOrderBooks = orderBooks
+	if te.onDirty != nil {
+		te.onDirty(te.Hash())
+		te.onDirty = nil
+	}
+}
+
 func (te *tradingExchanges) setMediumPriceBeforeEpoch(price *big.Int) {
 	te.data.MediumPriceBeforeEpoch = price
 	if te.onDirty != nil {
@@ -417,6 +458,21 @@ func (te *tradingExchanges) removeStateOrderListBidObject(db Database, stateOrde
 	te.setError(te.bidsTrie.TryDelete(stateOrderList.price[:]))
 }
 
+// restoreStateOrderListObject writes back an order list removed from the trie
+// of its side, when reverting the trade that emptied it.
+func (te *tradingExchanges) restoreStateOrderListObject(db Database, side string, stateOrderList *stateOrderList) {
+	data, err := rlp.EncodeToBytes(stateOrderList)
+	if err != nil {
+		panic(fmt.Errorf("can't encode order list object at %x: %v", stateOrderList.price[:], err))
+	}
+	switch side {
+	case Ask:
+		te.setError(te.getAsksTrie(db).TryUpdate(stateOrderList.price[:], data))
+	case Bid:
+		te.setError(te.getBidsTrie(db).TryUpdate(stateOrderList.price[:], data))
+	}
+}
+
 // Retrieve a state object given by the address. Returns nil if not found.
 func (te *tradingExchanges) getStateOrderListAskObject(db Database, price common.Hash) (stateOrderList *stateOrderList) {
 	// Prefer 'live' objects.
@@ -799,6 +855,155 @@ func (t *tradingExchanges) CommitLiquidationPriceTrie(db Database) error {
 	return err
 }
 
+func (t *tradingExchanges) MarkStateStopOrderListDirty(price common.Hash) {
+	t.stopOrderStatesDirty[price] = struct{}{}
+	if t.onDirty != nil {
+		t.onDirty(t.Hash())
+		t.onDirty = nil
+	}
+}
+
+func (t *tradingExchanges) createStateStopOrderList(db Database, triggerPrice common.Hash) (newobj *stateOrderList) {
+	newobj = newStateOrderList(t.db, StopMarket, t.orderBookHash, triggerPrice, orderList{Volume: Zero}, t.MarkStateStopOrderListDirty)
+	t.stopOrderStates[triggerPrice] = newobj
+	t.stopOrderStatesDirty[triggerPrice] = struct{}{}
+	data, err := rlp.EncodeToBytes(newobj)
+	if err != nil {
+		panic(fmt.Errorf("can't encode stop order list object at %x: %v", triggerPrice[:], err))
+	}
+	t.setError(t.getStopOrderTrie(db).TryUpdate(triggerPrice[:], data))
+	if t.onDirty != nil {
+		t.onDirty(t.Hash())
+		t.onDirty = nil
+	}
+	return newobj
+}
+
+func (t *tradingExchanges) getStopOrderTrie(db Database) Trie {
+	if t.stopOrderTrie == nil {
+		var err error
+		t.stopOrderTrie, err = db.OpenStorageTrie(t.orderBookHash, t.data.StopOrderRoot)
+		if err != nil {
+			t.stopOrderTrie, _ = db.OpenStorageTrie(t.orderBookHash, types.EmptyRootHash)
+			t.setError(fmt.Errorf("can't create stop order trie: %v", err))
+		}
+	}
+	return t.stopOrderTrie
+}
+
+func (t *tradingExchanges) getStateStopOrderList(db Database, triggerPrice common.Hash) (stateObject *stateOrderList) {
+	// Prefer 'live' objects.
+	if obj := t.stopOrderStates[triggerPrice]; obj != nil {
+		return obj
+	}
+
+	// Load the object from the database.
+	enc, err := t.getStopOrderTrie(db).TryGet(triggerPrice[:])
+	if len(enc) == 0 {
+		t.setError(err)
+		return nil
+	}
+	var data orderList
+	if err := rlp.DecodeBytes(enc, &data); err != nil {
+		log.Error("Failed to decode state stop order list", "triggerPrice", triggerPrice, "err", err)
+		return nil
+	}
+	// Insert into the live set.
+	obj := newStateOrderList(t.db, StopMarket, t.orderBookHash, triggerPrice, data, t.MarkStateStopOrderListDirty)
+	t.stopOrderStates[triggerPrice] = obj
+	return obj
+}
+
+// getStopOrderListsBetween returns the non empty stop order lists with a trigger
+// price from low to high included, in ascending order. The trigger tree is only
+// read within these bounds.
+func (t *tradingExchanges) getStopOrderListsBetween(db Database, low common.Hash, high common.Hash) []*stateOrderList {
+	result := []*stateOrderList{}
+	it := trie.NewIterator(t.getStopOrderTrie(db).NodeIterator(low[:]))
+	for it.Next() {
+		price := common.BytesToHash(it.Key)
+		if price.Cmp(high) > 0 {
+			break
+		}
+		obj := t.stopOrderStates[price]
+		if obj == nil {
+			var data orderList
+			if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+				log.Error("Failed to decode state stop order list", "price", price, "err", err)
+				return result
+			}
+			obj = newStateOrderList(t.db, StopMarket, t.orderBookHash, price, data, t.MarkStateStopOrderListDirty)
+			t.stopOrderStates[price] = obj
+		}
+		if obj.empty() {
+			continue
+		}
+		result = append(result, obj)
+	}
+	t.setError(it.Err)
+	return result
+}
+
+func (t *tradingExchanges) removeStateStopOrderList(db Database, stopOrderList *stateOrderList) {
+	t.setError(t.getStopOrderTrie(db).TryDelete(stopOrderList.price[:]))
+}
+
+func (t *tradingExchanges) updateStopOrderTrie(db Database) Trie {
+	tr := t.getStopOrderTrie(db)
+	for price, stateObject := range t.stopOrderStates {
+		if _, isDirty := t.stopOrderStatesDirty[price]; isDirty {
+			delete(t.stopOrderStatesDirty, price)
+			if stateObject.empty() {
+				t.setError(tr.TryDelete(price[:]))
+				continue
+			}
+			err := stateObject.updateRoot(db)
+			if err != nil {
+				log.Warn("updateStopOrderTrie updateRoot", "err", err, "price", price, "stateObject", *stateObject)
+			}
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ := rlp.EncodeToBytes(stateObject)
+			t.setError(tr.TryUpdate(price[:], v))
+		}
+	}
+	return tr
+}
+
+// stopOrderRoot returns the root stored for the stop order trie, an orderbook
+// without stop orders keeps an empty root so that its encoding is unchanged
+func stopOrderRoot(root common.Hash) common.Hash {
+	if root == EmptyRoot {
+		return EmptyHash
+	}
+	return root
+}
+
+func (t *tradingExchanges) updateStopOrderRoot(db Database) {
+	t.updateStopOrderTrie(db)
+	t.data.StopOrderRoot = stopOrderRoot(t.stopOrderTrie.Hash())
+}
+
+func (t *tradingExchanges) CommitStopOrderTrie(db Database) error {
+	t.updateStopOrderTrie(db)
+	if t.dbErr != nil {
+		return t.dbErr
+	}
+	root, err := t.stopOrderTrie.Commit(func(_ [][]byte, _ []byte, leaf []byte, parent common.Hash, _ []byte) error {
+		var orderList orderList
+		if err := rlp.DecodeBytes(leaf, &orderList); err != nil {
+			return nil
+		}
+		if orderList.Root != EmptyRoot {
+			db.TrieDB().Reference(orderList.Root, parent)
+		}
+		return nil
+	})
+	if err == nil {
+		t.data.StopOrderRoot = stopOrderRoot(root)
+	}
+	return err
+}
+
 func (t *tradingExchanges) addLendingCount(amount *big.Int) {
 	t.setLendingCount(new(big.Int).Add(t.data.LendingCount, amount))
 }
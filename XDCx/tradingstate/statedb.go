@@ -20,6 +20,7 @@ package tradingstate
 import (
 	"fmt"
 	"math/big"
+	"slices"
 	"sort"
 	"sync"
 
@@ -160,6 +161,65 @@ func (t *TradingStateDB) SetLastPrice(addr common.Hash, price *big.Int) {
 	}
 }
 
+// GetStopOrderPrice returns the last price the stop orders of the orderbook
+// have been activated from, nil until the first stop order.
+func (t *TradingStateDB) GetStopOrderPrice(addr common.Hash) *big.Int {
+	stateObject := t.getStateExchangeObject(addr)
+	if stateObject != nil {
+		return stateObject.data.StopOrderPrice
+	}
+	return nil
+}
+
+func (t *TradingStateDB) SetStopOrderPrice(addr common.Hash, price *big.Int) {
+	stateObject := t.GetOrNewStateExchangeObject(addr)
+	if stateObject != nil {
+		t.journal = append(t.journal, stopOrderPriceChange{
+			hash: addr,
+			prev: stateObject.data.StopOrderPrice,
+		})
+		stateObject.setStopOrderPrice(price)
+	}
+}
+
+// GetPendingStopOrderBooks returns the orderbooks whose last price moved since
+// their stop orders were last activated, in ascending order.
+func (t *TradingStateDB) GetPendingStopOrderBooks() []common.Hash {
+	stateObject := t.getStateExchangeObject(PendingStopOrderBooksHash)
+	if stateObject != nil {
+		return slices.Clone(stateObject.data.PendingStopOrderBooks)
+	}
+	return nil
+}
+
+// AddPendingStopOrderBook marks the stop orders of an orderbook for activation
+// once its last price moved. Orderbooks which never had stop orders are left
+// out.
+func (t *TradingStateDB) AddPendingStopOrderBook(orderBook common.Hash) {
+	if price := t.GetStopOrderPrice(orderBook); price == nil || price.Sign() == 0 {
+		return
+	}
+	prev := t.GetPendingStopOrderBooks()
+	i, found := slices.BinarySearchFunc(prev, orderBook, func(a, b common.Hash) int { return a.Cmp(b) })
+	if found {
+		return
+	}
+	t.journal = append(t.journal, pendingStopOrderBooksChange{prev: prev})
+	t.GetOrNewStateExchangeObject(PendingStopOrderBooksHash).setPendingStopOrderBooks(slices.Insert(slices.Clone(prev), i, orderBook))
+}
+
+// RemovePendingStopOrderBook drops an orderbook whose triggered stop orders
+// have all been activated.
+func (t *TradingStateDB) RemovePendingStopOrderBook(orderBook common.Hash) {
+	prev := t.GetPendingStopOrderBooks()
+	i, found := slices.BinarySearchFunc(prev, orderBook, func(a, b common.Hash) int { return a.Cmp(b) })
+	if !found {
+		return
+	}
+	t.journal = append(t.journal, pendingStopOrderBooksChange{prev: prev})
+	t.GetOrNewStateExchangeObject(PendingStopOrderBooksHash).setPendingStopOrderBooks(slices.Delete(slices.Clone(prev), i, i+1))
+}
+
 func (t *TradingStateDB) SetMediumPrice(addr common.Hash, price *big.Int, quantity *big.Int) {
 	stateObject := t.GetOrNewStateExchangeObject(addr)
 	if stateObject != nil {
@@ -289,6 +349,12 @@ func (t *TradingStateDB) CancelOrder(orderBook common.Hash, order *OrderItem) er
 	if stateOrderItem == nil || stateOrderItem.empty() {
 		return fmt.Errorf("empty OrderItem: order book: %s , order id : %s", orderBook, orderIdHash.Hex())
 	}
+	if t.isPendingStopOrder(stateObject, orderIdHash, stateOrderItem.data) {
+		if stateOrderItem.data.UserAddress != order.UserAddress || stateOrderItem.data.Hash != order.Hash || stateOrderItem.data.ExchangeAddress != order.ExchangeAddress {
+			return fmt.Errorf("stop order mismatch when cancel: order book : %s , order id : %s", orderBook, orderIdHash.Hex())
+		}
+		return t.RemoveStopOrder(orderBook, orderIdHash)
+	}
 	priceHash := common.BigToHash(stateOrderItem.data.Price)
 	var stateOrderList *stateOrderList
 	switch stateOrderItem.data.Side {
@@ -417,6 +483,11 @@ func (t *TradingStateDB) GetBestOrderIdAndAmount(orderBook common.Hash, price *b
 // updateStateExchangeObject writes the given object to the trie.
 func (t *TradingStateDB) updateStateExchangeObject(stateObject *tradingExchanges) {
 	addr := stateObject.Hash()
+	if addr == PendingStopOrderBooksHash && len(stateObject.data.PendingStopOrderBooks) == 0 {
+		// no stop order waits for activation, the list leaves no trace in the state
+		t.setError(t.trie.TryDelete(addr[:]))
+		return
+	}
 	data, err := rlp.EncodeToBytes(stateObject)
 	if err != nil {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
@@ -549,6 +620,7 @@ func (t *TradingStateDB) Finalise() {
 			stateObject.updateBidsRoot(t.db)
 			stateObject.updateOrdersRoot(t.db)
 			stateObject.updateLiquidationPriceRoot(t.db)
+			stateObject.updateStopOrderRoot(t.db)
 			// Update the object in the main orderId trie.
 			t.updateStateExchangeObject(stateObject)
 			//delete(s.stateExhangeObjectsDirty, addr)
@@ -584,6 +656,9 @@ func (t *TradingStateDB) Commit() (root common.Hash, err error) {
 			if err := stateObject.CommitLiquidationPriceTrie(t.db); err != nil {
 				return EmptyHash, err
 			}
+			if err := stateObject.CommitStopOrderTrie(t.db); err != nil {
+				return EmptyHash, err
+			}
 			// Update the object in the main orderId trie.
 			t.updateStateExchangeObject(stateObject)
 			delete(t.stateExhangeObjectsDirty, addr)
@@ -607,6 +682,9 @@ func (t *TradingStateDB) Commit() (root common.Hash, err error) {
 		if exchange.LiquidationPriceRoot != EmptyRoot {
 			t.db.TrieDB().Reference(exchange.LiquidationPriceRoot, parent)
 		}
+		if exchange.StopOrderRoot != EmptyHash {
+			t.db.TrieDB().Reference(exchange.StopOrderRoot, parent)
+		}
 		return nil
 	})
 	log.Debug("Trading State Trie cache stats after commit", "root", root.Hex())
@@ -733,3 +811,98 @@ func (t *TradingStateDB) RemoveLiquidationPrice(orderBook common.Hash, price *bi
 	})
 	return nil
 }
+
+// InsertStopOrder stores a stop order and adds it to the trigger tree of the orderbook
+func (t *TradingStateDB) InsertStopOrder(orderBook common.Hash, orderId common.Hash, order OrderItem) {
+	triggerPriceHash := common.BigToHash(order.TriggerPrice)
+	orderBookState := t.getStateExchangeObject(orderBook)
+	if orderBookState == nil {
+		orderBookState = t.createExchangeObject(orderBook)
+	}
+	stopOrderList := orderBookState.getStateStopOrderList(t.db, triggerPriceHash)
+	if stopOrderList == nil || stopOrderList.empty() {
+		// an emptied list is removed from the trigger tree
+		stopOrderList = orderBookState.createStateStopOrderList(t.db, triggerPriceHash)
+	}
+	t.journal = append(t.journal, insertStopOrder{
+		orderBook: orderBook,
+		orderId:   orderId,
+	})
+	orderBookState.createStateOrderObject(t.db, orderId, order)
+	stopOrderList.insertOrderItem(t.db, orderId, common.BigToHash(order.Quantity))
+	stopOrderList.AddVolume(order.Quantity)
+}
+
+// RemoveStopOrder takes a stop order out of the trigger tree, the order item
+// is left with no quantity until it is inserted in the orderbook
+func (t *TradingStateDB) RemoveStopOrder(orderBook common.Hash, orderId common.Hash) error {
+	orderBookState := t.getStateExchangeObject(orderBook)
+	if orderBookState == nil {
+		return fmt.Errorf("not found order book: %s", orderBook.Hex())
+	}
+	stateOrderItem := orderBookState.getStateOrderObject(t.db, orderId)
+	if stateOrderItem == nil || stateOrderItem.empty() {
+		return fmt.Errorf("empty stop order: order book: %s , order id : %s", orderBook.Hex(), orderId.Hex())
+	}
+	if !t.isPendingStopOrder(orderBookState, orderId, stateOrderItem.data) {
+		return fmt.Errorf("not found stop order: order book: %s , order id : %s", orderBook.Hex(), orderId.Hex())
+	}
+	stopOrderList := orderBookState.getStateStopOrderList(t.db, common.BigToHash(stateOrderItem.data.TriggerPrice))
+	t.journal = append(t.journal, removeStopOrder{
+		orderBook: orderBook,
+		orderId:   orderId,
+		order:     stateOrderItem.data,
+	})
+	currentAmount := new(big.Int).SetBytes(stopOrderList.GetOrderAmount(t.db, orderId).Bytes())
+	stateOrderItem.setVolume(big.NewInt(0))
+	stopOrderList.subVolume(currentAmount)
+	stopOrderList.removeOrderItem(t.db, orderId)
+	if stopOrderList.empty() {
+		orderBookState.removeStateStopOrderList(t.db, stopOrderList)
+	}
+	return nil
+}
+
+// isPendingStopOrder reports whether the order is still waiting in the trigger tree
+func (t *TradingStateDB) isPendingStopOrder(orderBookState *tradingExchanges, orderId common.Hash, order OrderItem) bool {
+	if !StopOrderType[order.Type] || order.TriggerPrice == nil || order.TriggerPrice.Sign() <= 0 {
+		return false
+	}
+	stopOrderList := orderBookState.getStateStopOrderList(t.db, common.BigToHash(order.TriggerPrice))
+	if stopOrderList == nil || stopOrderList.empty() {
+		return false
+	}
+	return !stopOrderList.GetOrderAmount(t.db, orderId).IsZero()
+}
+
+// GetTriggeredStopOrders returns the stop orders crossed by a move of the last
+// price from one price to another, the bound it moves from is excluded. Orders
+// are sorted by trigger price in the direction of the move, then by order id.
+// Whole trigger prices are returned, nearest first, until at least limit orders.
+// The trigger tree is only read between the two prices.
+func (t *TradingStateDB) GetTriggeredStopOrders(orderBook common.Hash, from *big.Int, to *big.Int, limit int) []OrderItem {
+	result := []OrderItem{}
+	orderBookState := t.getStateExchangeObject(orderBook)
+	if orderBookState == nil || from == nil || to == nil || from.Cmp(to) == 0 || limit <= 0 {
+		return result
+	}
+	rising := to.Cmp(from) > 0
+	var stopOrderLists []*stateOrderList
+	if rising {
+		stopOrderLists = orderBookState.getStopOrderListsBetween(t.db, common.BigToHash(new(big.Int).Add(from, One)), common.BigToHash(to))
+	} else {
+		stopOrderLists = orderBookState.getStopOrderListsBetween(t.db, common.BigToHash(to), common.BigToHash(new(big.Int).Sub(from, One)))
+		slices.Reverse(stopOrderLists)
+	}
+	for _, stopOrderList := range stopOrderLists {
+		if len(result) >= limit {
+			break
+		}
+		for _, orderId := range stopOrderList.getAllOrderIds(t.db) {
+			if order := t.GetOrder(orderBook, orderId); order.Quantity != nil && order.Quantity.Sign() > 0 {
+				result = append(result, order)
+			}
+		}
+	}
+	return result
+}
@@ -306,3 +306,129 @@ func TestDumpState(t *testing.T) {
 	db.Close()
 }
 */
+
+func TestVerifyState(t *testing.T) {
+	orderBook := GetTradingOrderBookHash(common.HexToAddress("0x0000000000000000000000000000000000000022"), common.XDCNativeAddressBinary)
+	user := common.HexToAddress("0x0000000000000000000000000000000000000011")
+	signature := &Signature{V: 1, R: common.HexToHash("111111"), S: common.HexToHash("222222222222")}
+
+	db := rawdb.NewMemoryDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(types.EmptyRootHash, stateCache)
+	statedb.SetNonce(user.Hash(), 3)
+	statedb.SetNonce(orderBook, 5)
+	statedb.SetLastPrice(orderBook, big.NewInt(10))
+	for i := 1; i <= 2; i++ {
+		statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(int64(i))), OrderItem{OrderID: uint64(i), Quantity: big.NewInt(int64(i)), Price: big.NewInt(int64(10 + i)), Side: Ask, Signature: signature})
+		statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(int64(i+2))), OrderItem{OrderID: uint64(i + 2), Quantity: big.NewInt(int64(i)), Price: big.NewInt(int64(10 - i)), Side: Bid, Signature: signature})
+	}
+	statedb.InsertStopOrder(orderBook, common.BigToHash(big.NewInt(5)), OrderItem{OrderID: 5, Quantity: big.NewInt(1), Price: big.NewInt(12), TriggerPrice: big.NewInt(12), Side: Bid, Type: StopLimit, Signature: signature})
+	statedb.InsertLiquidationPrice(orderBook, big.NewInt(8), common.StringToHash("XDC/60"), 1)
+	root := statedb.IntermediateRoot()
+	if _, err := statedb.Commit(); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie database: %v", err)
+	}
+
+	statedb, err := New(root, stateCache)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	dump, err := statedb.Dump()
+	if err != nil {
+		t.Fatalf("failed to dump state: %v", err)
+	}
+	if len(dump.Nonces) != 1 || dump.Nonces[user] != 3 {
+		t.Errorf("nonces mismatch: have %v, want %v: 3", dump.Nonces, user)
+	}
+	book := dump.OrderBooks[orderBook]
+	if len(dump.OrderBooks) != 1 || book == nil {
+		t.Fatalf("orderbooks mismatch: have %v, want %x", dump.OrderBooks, orderBook)
+	}
+	if book.Info.Nonce != 5 || book.Info.LastPrice.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("orderbook info mismatch: have nonce %d last price %v, want 5 and 10", book.Info.Nonce, book.Info.LastPrice)
+	}
+	if len(book.Asks) != 2 || len(book.Bids) != 2 || len(book.StopOrders) != 1 || len(book.Orders) != 5 || len(book.LiquidationPrices) != 1 {
+		t.Errorf("orderbook trees mismatch: have %d asks, %d bids, %d stop orders, %d orders, %d liquidation prices, want 2, 2, 1, 5, 1",
+			len(book.Asks), len(book.Bids), len(book.StopOrders), len(book.Orders), len(book.LiquidationPrices))
+	}
+
+	stats, err := VerifyState(stateCache, root)
+	if err != nil {
+		t.Fatalf("failed to verify state: %v", err)
+	}
+	// main trie, orders, the asks, bids and stop orders trees with one order
+	// list per price, the liquidation price tree with its lending book tree and
+	// the trades of that lending book
+	if want := 1 + 1 + (1 + 2) + (1 + 2) + (1 + 1) + 3; stats.Tries != want {
+		t.Errorf("verified tries mismatch: have %d, want %d", stats.Tries, want)
+	}
+
+	// Dropping any node below the root must be reported.
+	it := db.NewIterator(nil, nil)
+	for it.Next() {
+		if len(it.Key()) == common.HashLength && common.BytesToHash(it.Key()) != root {
+			db.Delete(it.Key())
+			break
+		}
+	}
+	it.Release()
+	if _, err := VerifyState(NewDatabase(db), root); err == nil {
+		t.Fatal("verified a state with a missing node")
+	}
+}
+
+func TestPendingStopOrderBooks(t *testing.T) {
+	var (
+		stateCache = NewDatabase(rawdb.NewMemoryDatabase())
+		statedb, _ = New(types.EmptyRootHash, stateCache)
+		withStops  = common.StringToHash("BTC/XDC")
+		other      = common.StringToHash("ETH/XDC")
+		noStops    = common.StringToHash("XRP/XDC")
+	)
+	statedb.SetStopOrderPrice(withStops, big.NewInt(10))
+	statedb.SetStopOrderPrice(other, big.NewInt(10))
+	noPendingRoot := statedb.IntermediateRoot()
+
+	statedb.AddPendingStopOrderBook(noStops)
+	if pending := statedb.GetPendingStopOrderBooks(); len(pending) != 0 {
+		t.Fatalf("pending = %v, want none for an orderbook without stop orders", pending)
+	}
+	statedb.AddPendingStopOrderBook(withStops)
+	statedb.AddPendingStopOrderBook(other)
+	statedb.AddPendingStopOrderBook(withStops)
+	if pending := statedb.GetPendingStopOrderBooks(); len(pending) != 2 || pending[0].Cmp(pending[1]) >= 0 {
+		t.Fatalf("pending = %v, want both orderbooks once, sorted", pending)
+	}
+
+	// the set is reverted with the state
+	snap := statedb.Snapshot()
+	statedb.RemovePendingStopOrderBook(other)
+	if pending := statedb.GetPendingStopOrderBooks(); len(pending) != 1 || pending[0] != withStops {
+		t.Fatalf("pending = %v, want %x", pending, withStops)
+	}
+	statedb.RevertToSnapshot(snap)
+	if pending := statedb.GetPendingStopOrderBooks(); len(pending) != 2 {
+		t.Fatalf("reverted pending = %v, want both orderbooks", pending)
+	}
+
+	// and stored in the trie
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	statedb, err = New(root, stateCache)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if pending := statedb.GetPendingStopOrderBooks(); len(pending) != 2 {
+		t.Fatalf("committed pending = %v, want both orderbooks", pending)
+	}
+	statedb.RemovePendingStopOrderBook(withStops)
+	statedb.RemovePendingStopOrderBook(other)
+	if root := statedb.IntermediateRoot(); root != noPendingRoot {
+		t.Errorf("root once drained = %x, want %x", root, noPendingRoot)
+	}
+}
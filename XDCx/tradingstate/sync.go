@@ -0,0 +1,75 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package tradingstate
+
+import (
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+)
+
+// SyncTrieKind identifies the layout of a trie of the trading state, so that
+// range based state sync can find the tries nested in its leaves.
+type SyncTrieKind uint8
+
+const (
+	SyncExchangeTrie         SyncTrieKind = iota // Root trie, orderbook hash -> exchange object
+	SyncOrderBookTrie                            // Asks or bids, price -> order list
+	SyncLiquidationPriceTrie                     // Liquidation price -> lending books
+	SyncLendingBookTrie                          // Lending book -> trade ids
+	SyncLeafTrie                                 // Orders, orders at a price and trade ids, no nested tries
+)
+
+// SyncTrie is a trie of the trading state referenced from a leaf of its parent.
+type SyncTrie struct {
+	Root common.Hash
+	Kind SyncTrieKind
+}
+
+// SyncChildren decodes a leaf of a trading state trie of the given kind and
+// returns the non-empty tries it references.
+func SyncChildren(kind SyncTrieKind, leaf []byte) ([]SyncTrie, error) {
+	var children []SyncTrie
+	add := func(root common.Hash, kind SyncTrieKind) {
+		if root != EmptyHash && root != EmptyRoot {
+			children = append(children, SyncTrie{Root: root, Kind: kind})
+		}
+	}
+	switch kind {
+	case SyncExchangeTrie:
+		var data tradingExchangeObject
+		if err := rlp.DecodeBytes(leaf, &data); err != nil {
+			return nil, err
+		}
+		add(data.AskRoot, SyncOrderBookTrie)
+		add(data.BidRoot, SyncOrderBookTrie)
+		add(data.OrderRoot, SyncLeafTrie)
+		add(data.LiquidationPriceRoot, SyncLiquidationPriceTrie)
+		add(data.StopOrderRoot, SyncOrderBookTrie)
+
+	case SyncOrderBookTrie, SyncLiquidationPriceTrie, SyncLendingBookTrie:
+		var data orderList
+		if err := rlp.DecodeBytes(leaf, &data); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case SyncLiquidationPriceTrie:
+			add(data.Root, SyncLendingBookTrie)
+		default:
+			add(data.Root, SyncLeafTrie)
+		}
+	}
+	return children, nil
+}
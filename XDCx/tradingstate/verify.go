@@ -0,0 +1,129 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package tradingstate
+
+import (
+	"fmt"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/ethdb/memorydb"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// VerifyStats counts what VerifyState went through.
+type VerifyStats struct {
+	Tries  int // non empty tries whose root was recomputed
+	Leaves int // leaves re-inserted into the recomputed tries
+}
+
+// VerifyState walks the trading state at root and every trie referenced from
+// it, rebuilding each trie from its leaves and checking that the rebuilt root
+// matches the stored one. It fails on the first missing node, undecodable
+// leaf or root mismatch.
+func VerifyState(db Database, root common.Hash) (*VerifyStats, error) {
+	stats := new(VerifyStats)
+	err := verifyTrie(db, stats, root, func(key, value []byte) error {
+		var data tradingExchangeObject
+		if err := rlp.DecodeBytes(value, &data); err != nil {
+			return fmt.Errorf("failed to decode exchange object %x: %v", key, err)
+		}
+		return verifyExchangeObject(db, stats, common.BytesToHash(key), data)
+	})
+	return stats, err
+}
+
+func verifyExchangeObject(db Database, stats *VerifyStats, orderBook common.Hash, data tradingExchangeObject) error {
+	if err := verifyTrie(db, stats, data.AskRoot, verifyOrderList(db, stats)); err != nil {
+		return fmt.Errorf("orderbook %x asks: %v", orderBook, err)
+	}
+	if err := verifyTrie(db, stats, data.BidRoot, verifyOrderList(db, stats)); err != nil {
+		return fmt.Errorf("orderbook %x bids: %v", orderBook, err)
+	}
+	if err := verifyTrie(db, stats, data.StopOrderRoot, verifyOrderList(db, stats)); err != nil {
+		return fmt.Errorf("orderbook %x stop orders: %v", orderBook, err)
+	}
+	if err := verifyTrie(db, stats, data.OrderRoot, func(key, value []byte) error {
+		var order OrderItem
+		if err := rlp.DecodeBytes(value, &order); err != nil {
+			return fmt.Errorf("failed to decode order %x: %v", key, err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("orderbook %x orders: %v", orderBook, err)
+	}
+	// Every liquidation price holds a trie of lending books, each of them an
+	// order list of lending trades.
+	if err := verifyTrie(db, stats, data.LiquidationPriceRoot, func(key, value []byte) error {
+		var data orderList
+		if err := rlp.DecodeBytes(value, &data); err != nil {
+			return fmt.Errorf("failed to decode liquidation price %x: %v", key, err)
+		}
+		return verifyTrie(db, stats, data.Root, verifyOrderList(db, stats))
+	}); err != nil {
+		return fmt.Errorf("orderbook %x liquidation prices: %v", orderBook, err)
+	}
+	return nil
+}
+
+// verifyOrderList returns a leaf callback checking the trie of an order list
+// stored as a leaf value.
+func verifyOrderList(db Database, stats *VerifyStats) func(key, value []byte) error {
+	return func(key, value []byte) error {
+		var data orderList
+		if err := rlp.DecodeBytes(value, &data); err != nil {
+			return fmt.Errorf("failed to decode order list %x: %v", key, err)
+		}
+		if err := verifyTrie(db, stats, data.Root, nil); err != nil {
+			return fmt.Errorf("order list %x: %v", key, err)
+		}
+		return nil
+	}
+}
+
+// verifyTrie rebuilds the trie at root from its leaves in a scratch database
+// and compares the roots, calling onLeaf for every leaf along the way. Zero
+// and empty roots are not backed by any node and are skipped.
+func verifyTrie(db Database, stats *VerifyStats, root common.Hash, onLeaf func(key, value []byte) error) error {
+	if root == (common.Hash{}) || root == EmptyRoot {
+		return nil
+	}
+	tr, err := db.OpenStorageTrie(common.Hash{}, root)
+	if err != nil {
+		return err
+	}
+	rebuilt := trie.NewEmpty(trie.NewDatabase(memorydb.New()))
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		if err := rebuilt.TryUpdate(it.Key, it.Value); err != nil {
+			return err
+		}
+		stats.Leaves++
+		if onLeaf != nil {
+			if err := onLeaf(it.Key, it.Value); err != nil {
+				return err
+			}
+		}
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+	if hash := rebuilt.Hash(); hash != root {
+		return fmt.Errorf("root mismatch: have %x, want %x", hash, root)
+	}
+	stats.Tries++
+	return nil
+}
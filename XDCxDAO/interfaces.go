@@ -5,6 +5,10 @@
 package XDCxDAO
 
 import (
+	"math/big"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/ethdb"
 )
@@ -53,6 +57,22 @@ type XDCXDAO interface {
 	Compact(start []byte, limit []byte) error
 }
 
+// XDCXHistoryDAO is implemented by the databases able to serve historical
+// orders, trades and epoch prices to the XDCx RPC API.
+type XDCXHistoryDAO interface {
+	// GetOrdersByUser returns the orders placed by user, newest first.
+	GetOrdersByUser(user common.Address, offset, limit int) ([]*tradingstate.OrderItem, error)
+	// GetTradesByPair returns the trades of a pair created within [from, to), oldest first.
+	GetTradesByPair(baseToken, quoteToken common.Address, from, to time.Time, limit int) ([]*tradingstate.Trade, error)
+	// GetTradePriceRange returns the highest and lowest price of the trades of a
+	// pair created within [from, to), or nils if there is none.
+	GetTradePriceRange(baseToken, quoteToken common.Address, from, to time.Time) (*big.Int, *big.Int, error)
+	// GetOrderHistory returns every recorded status change of an order, oldest first.
+	GetOrderHistory(orderHash common.Hash) ([]*tradingstate.OrderHistoryItem, error)
+	// GetEpochPrices returns the recorded prices of an orderbook within [fromEpoch, toEpoch].
+	GetEpochPrices(orderbook common.Hash, fromEpoch, toEpoch uint64) ([]*tradingstate.EpochPriceItem, error)
+}
+
 // use alloc to prevent reference manipulation
 func EmptyKey() []byte {
 	key := make([]byte, common.HashLength)
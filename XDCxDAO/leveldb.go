@@ -2,16 +2,35 @@ package XDCxDAO
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"math/big"
 	"sync"
+	"time"
 
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
 	"github.com/XinFinOrg/XDPoSChain/ethdb"
 	"github.com/XinFinOrg/XDPoSChain/log"
 )
 
+// The masternode keeps its own copy of the XDCx history next to the trading
+// tries, so that the RPC API doesn't depend on an SDK node. Orders and trades
+// are stored as JSON and indexed by the following keys.
+var (
+	orderPrefix        = []byte("XDCx-o") // orderPrefix + orderHash -> order
+	orderUserPrefix    = []byte("XDCx-u") // orderUserPrefix + user + createdAt + orderHash -> nil
+	orderTxPrefix      = []byte("XDCx-x") // orderTxPrefix + txHash + orderHash -> nil
+	orderHistoryPrefix = []byte("XDCx-h") // orderHistoryPrefix + orderHash + updatedAt + txHash -> history item
+	tradePrefix        = []byte("XDCx-t") // tradePrefix + tradeHash -> trade
+	tradePairPrefix    = []byte("XDCx-p") // tradePairPrefix + baseToken + quoteToken + createdAt + tradeHash -> nil
+	tradeTxPrefix      = []byte("XDCx-y") // tradeTxPrefix + txHash + tradeHash -> nil
+	epochPricePrefix   = []byte("XDCx-e") // epochPricePrefix + orderbook + epoch -> epoch price
+)
+
 type BatchItem struct {
 	Value interface{}
 }
@@ -60,22 +79,66 @@ func (db *BatchDatabase) getCacheKey(key []byte) string {
 }
 
 func (db *BatchDatabase) HasObject(hash common.Hash, val interface{}) (bool, error) {
-	// for mongodb only
+	switch val.(type) {
+	case *tradingstate.OrderItem:
+		return db.db.Has(orderKey(hash))
+	case *tradingstate.Trade:
+		return db.db.Has(tradeKey(hash))
+	}
 	return false, nil
 }
 
 func (db *BatchDatabase) GetObject(hash common.Hash, val interface{}) (interface{}, error) {
-	// for mongodb only
+	if db.IsEmptyKey(hash.Bytes()) {
+		return nil, nil
+	}
+	// Avoid wrapping nil pointers, callers compare the result against nil
+	switch val.(type) {
+	case *tradingstate.OrderItem:
+		if order, err := db.getOrder(hash); order != nil || err != nil {
+			return order, err
+		}
+	case *tradingstate.Trade:
+		if trade, err := db.getTrade(hash); trade != nil || err != nil {
+			return trade, err
+		}
+	}
 	return nil, nil
 }
 
 func (db *BatchDatabase) PutObject(hash common.Hash, val interface{}) error {
-	// for mongodb only
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	switch item := val.(type) {
+	case *tradingstate.OrderItem:
+		return db.putOrder(item)
+	case *tradingstate.Trade:
+		return db.putTrade(item)
+	case *tradingstate.EpochPriceItem:
+		return db.putJSON(epochPriceKey(item.Orderbook, item.Epoch), item)
+	}
 	return nil
 }
 
 func (db *BatchDatabase) DeleteObject(hash common.Hash, val interface{}) error {
-	// for mongodb only
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	switch val.(type) {
+	case *tradingstate.OrderItem:
+		order, err := db.getOrder(hash)
+		if err != nil || order == nil {
+			return nil
+		}
+		return db.deleteOrder(order)
+	case *tradingstate.Trade:
+		trade, err := db.getTrade(hash)
+		if err != nil || trade == nil {
+			return nil
+		}
+		return db.deleteTrade(trade)
+	}
 	return nil
 }
 
@@ -108,13 +171,54 @@ func (db *BatchDatabase) NewBatchWithSize(size int) ethdb.Batch {
 }
 
 func (db *BatchDatabase) DeleteItemByTxHash(txhash common.Hash, val interface{}) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	switch val.(type) {
+	case *tradingstate.OrderItem:
+		for _, order := range db.ordersByTxHash(txhash) {
+			if err := db.deleteOrder(order); err != nil {
+				log.Error("DeleteItemByTxHash: failed to delete order", "txhash", txhash, "err", err)
+			}
+		}
+	case *tradingstate.Trade:
+		for _, trade := range db.tradesByTxHash(txhash) {
+			if err := db.deleteTrade(trade); err != nil {
+				log.Error("DeleteItemByTxHash: failed to delete trade", "txhash", txhash, "err", err)
+			}
+		}
+	}
 }
 
 func (db *BatchDatabase) GetListItemByTxHash(txhash common.Hash, val interface{}) interface{} {
+	switch val.(type) {
+	case *tradingstate.OrderItem:
+		return db.ordersByTxHash(txhash)
+	case *tradingstate.Trade:
+		return db.tradesByTxHash(txhash)
+	}
 	return []interface{}{}
 }
 
 func (db *BatchDatabase) GetListItemByHashes(hashes []string, val interface{}) interface{} {
+	switch val.(type) {
+	case *tradingstate.OrderItem:
+		result := []*tradingstate.OrderItem{}
+		for _, hash := range hashes {
+			if order, err := db.getOrder(common.HexToHash(hash)); err == nil && order != nil {
+				result = append(result, order)
+			}
+		}
+		return result
+	case *tradingstate.Trade:
+		result := []*tradingstate.Trade{}
+		for _, hash := range hashes {
+			if trade, err := db.getTrade(common.HexToHash(hash)); err == nil && trade != nil {
+				result = append(result, trade)
+			}
+		}
+		return result
+	}
 	return []interface{}{}
 }
 
@@ -170,7 +274,7 @@ func (db *BatchDatabase) Sync() error {
 }
 
 func (db *BatchDatabase) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
-	panic("NewIterator from XDCxDAO leveldb is not supported")
+	return db.db.NewIterator(prefix, start)
 }
 
 func (db *BatchDatabase) Stat(property string) (string, error) {
@@ -180,3 +284,312 @@ func (db *BatchDatabase) Stat(property string) (string, error) {
 func (db *BatchDatabase) Compact(start []byte, limit []byte) error {
 	return errNotSupported
 }
+
+func encodeTime(t time.Time) []byte {
+	enc := make([]byte, 8)
+	if unix := t.Unix(); unix > 0 {
+		binary.BigEndian.PutUint64(enc, uint64(unix))
+	}
+	return enc
+}
+
+func encodeUint64(n uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, n)
+	return enc
+}
+
+func joinKey(parts ...[]byte) []byte {
+	var key []byte
+	for _, part := range parts {
+		key = append(key, part...)
+	}
+	return key
+}
+
+func orderKey(hash common.Hash) []byte {
+	return joinKey(orderPrefix, hash.Bytes())
+}
+
+func orderUserKey(order *tradingstate.OrderItem) []byte {
+	return joinKey(orderUserPrefix, order.UserAddress.Bytes(), encodeTime(order.CreatedAt), order.Hash.Bytes())
+}
+
+func orderTxKey(txHash, orderHash common.Hash) []byte {
+	return joinKey(orderTxPrefix, txHash.Bytes(), orderHash.Bytes())
+}
+
+func orderHistoryKey(orderHash common.Hash, updatedAt time.Time, txHash common.Hash) []byte {
+	return joinKey(orderHistoryPrefix, orderHash.Bytes(), encodeTime(updatedAt), txHash.Bytes())
+}
+
+func tradeKey(hash common.Hash) []byte {
+	return joinKey(tradePrefix, hash.Bytes())
+}
+
+func tradePairKey(trade *tradingstate.Trade) []byte {
+	return joinKey(tradePairPrefix, trade.BaseToken.Bytes(), trade.QuoteToken.Bytes(), encodeTime(trade.CreatedAt), trade.Hash.Bytes())
+}
+
+func tradeTxKey(txHash, tradeHash common.Hash) []byte {
+	return joinKey(tradeTxPrefix, txHash.Bytes(), tradeHash.Bytes())
+}
+
+func epochPriceKey(orderbook common.Hash, epoch uint64) []byte {
+	return joinKey(epochPricePrefix, orderbook.Bytes(), encodeUint64(epoch))
+}
+
+func (db *BatchDatabase) putJSON(key []byte, val interface{}) error {
+	enc, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return db.db.Put(key, enc)
+}
+
+func (db *BatchDatabase) getJSON(key []byte, val interface{}) (bool, error) {
+	enc, err := db.db.Get(key)
+	if err != nil || len(enc) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(enc, val); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *BatchDatabase) getOrder(hash common.Hash) (*tradingstate.OrderItem, error) {
+	order := new(tradingstate.OrderItem)
+	if ok, err := db.getJSON(orderKey(hash), order); !ok {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (db *BatchDatabase) getTrade(hash common.Hash) (*tradingstate.Trade, error) {
+	trade := new(tradingstate.Trade)
+	if ok, err := db.getJSON(tradeKey(hash), trade); !ok {
+		return nil, err
+	}
+	return trade, nil
+}
+
+// putOrder stores the order together with its indexes and appends the new
+// state to the order history. Storing an order whose update time is older
+// than the recorded history means the newer states were rolled back by a
+// reorg, so they are dropped from the history.
+func (db *BatchDatabase) putOrder(order *tradingstate.OrderItem) error {
+	batch := db.db.NewBatch()
+	if prev, _ := db.getOrder(order.Hash); prev != nil && !prev.CreatedAt.Equal(order.CreatedAt) {
+		batch.Delete(orderUserKey(prev))
+	}
+	enc, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	batch.Put(orderKey(order.Hash), enc)
+	batch.Put(orderUserKey(order), nil)
+	batch.Put(orderTxKey(order.TxHash, order.Hash), nil)
+
+	it := db.db.NewIterator(joinKey(orderHistoryPrefix, order.Hash.Bytes()), encodeTime(order.UpdatedAt.Add(time.Second)))
+	for it.Next() {
+		batch.Delete(common.CopyBytes(it.Key()))
+	}
+	it.Release()
+
+	history, err := json.Marshal(&tradingstate.OrderHistoryItem{
+		TxHash:       order.TxHash,
+		FilledAmount: order.FilledAmount,
+		Status:       order.Status,
+		UpdatedAt:    order.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	batch.Put(orderHistoryKey(order.Hash, order.UpdatedAt, order.TxHash), history)
+	return batch.Write()
+}
+
+func (db *BatchDatabase) deleteOrder(order *tradingstate.OrderItem) error {
+	batch := db.db.NewBatch()
+	batch.Delete(orderKey(order.Hash))
+	batch.Delete(orderUserKey(order))
+	batch.Delete(orderTxKey(order.TxHash, order.Hash))
+
+	it := db.db.NewIterator(joinKey(orderHistoryPrefix, order.Hash.Bytes()), nil)
+	for it.Next() {
+		batch.Delete(common.CopyBytes(it.Key()))
+	}
+	it.Release()
+	return batch.Write()
+}
+
+func (db *BatchDatabase) putTrade(trade *tradingstate.Trade) error {
+	enc, err := json.Marshal(trade)
+	if err != nil {
+		return err
+	}
+	batch := db.db.NewBatch()
+	batch.Put(tradeKey(trade.Hash), enc)
+	batch.Put(tradePairKey(trade), nil)
+	batch.Put(tradeTxKey(trade.TxHash, trade.Hash), nil)
+	return batch.Write()
+}
+
+func (db *BatchDatabase) deleteTrade(trade *tradingstate.Trade) error {
+	batch := db.db.NewBatch()
+	batch.Delete(tradeKey(trade.Hash))
+	batch.Delete(tradePairKey(trade))
+	batch.Delete(tradeTxKey(trade.TxHash, trade.Hash))
+	return batch.Write()
+}
+
+// ordersByTxHash returns the orders whose latest state was set by txhash.
+// Index entries left behind by later updates of an order are skipped.
+func (db *BatchDatabase) ordersByTxHash(txhash common.Hash) []*tradingstate.OrderItem {
+	prefix := joinKey(orderTxPrefix, txhash.Bytes())
+	it := db.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	result := []*tradingstate.OrderItem{}
+	for it.Next() {
+		order, err := db.getOrder(common.BytesToHash(it.Key()[len(prefix):]))
+		if err != nil || order == nil || order.TxHash != txhash {
+			continue
+		}
+		result = append(result, order)
+	}
+	return result
+}
+
+func (db *BatchDatabase) tradesByTxHash(txhash common.Hash) []*tradingstate.Trade {
+	prefix := joinKey(tradeTxPrefix, txhash.Bytes())
+	it := db.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	result := []*tradingstate.Trade{}
+	for it.Next() {
+		trade, err := db.getTrade(common.BytesToHash(it.Key()[len(prefix):]))
+		if err != nil || trade == nil {
+			continue
+		}
+		result = append(result, trade)
+	}
+	return result
+}
+
+// GetOrdersByUser implements XDCXHistoryDAO, walking the user index backwards
+// in time.
+func (db *BatchDatabase) GetOrdersByUser(user common.Address, offset, limit int) ([]*tradingstate.OrderItem, error) {
+	prefix := joinKey(orderUserPrefix, user.Bytes())
+	it := db.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var hashes []common.Hash
+	for it.Next() {
+		hashes = append(hashes, common.BytesToHash(it.Key()[len(prefix)+8:]))
+	}
+	result := []*tradingstate.OrderItem{}
+	for i := len(hashes) - 1 - offset; i >= 0 && len(result) < limit; i-- {
+		order, err := db.getOrder(hashes[i])
+		if err != nil {
+			return nil, err
+		}
+		if order != nil {
+			result = append(result, order)
+		}
+	}
+	return result, nil
+}
+
+// GetTradesByPair implements XDCXHistoryDAO.
+func (db *BatchDatabase) GetTradesByPair(baseToken, quoteToken common.Address, from, to time.Time, limit int) ([]*tradingstate.Trade, error) {
+	prefix := joinKey(tradePairPrefix, baseToken.Bytes(), quoteToken.Bytes())
+	it := db.db.NewIterator(prefix, encodeTime(from))
+	defer it.Release()
+
+	end := encodeTime(to)
+	result := []*tradingstate.Trade{}
+	for it.Next() && len(result) < limit {
+		key := it.Key()[len(prefix):]
+		if bytes.Compare(key[:8], end) >= 0 {
+			break
+		}
+		trade, err := db.getTrade(common.BytesToHash(key[8:]))
+		if err != nil {
+			return nil, err
+		}
+		if trade != nil {
+			result = append(result, trade)
+		}
+	}
+	return result, nil
+}
+
+// GetTradePriceRange implements XDCXHistoryDAO.
+func (db *BatchDatabase) GetTradePriceRange(baseToken, quoteToken common.Address, from, to time.Time) (*big.Int, *big.Int, error) {
+	prefix := joinKey(tradePairPrefix, baseToken.Bytes(), quoteToken.Bytes())
+	it := db.db.NewIterator(prefix, encodeTime(from))
+	defer it.Release()
+
+	var (
+		end       = encodeTime(to)
+		high, low *big.Int
+	)
+	for it.Next() {
+		key := it.Key()[len(prefix):]
+		if bytes.Compare(key[:8], end) >= 0 {
+			break
+		}
+		trade, err := db.getTrade(common.BytesToHash(key[8:]))
+		if err != nil {
+			return nil, nil, err
+		}
+		if trade == nil || trade.PricePoint == nil {
+			continue
+		}
+		if high == nil || trade.PricePoint.Cmp(high) > 0 {
+			high = trade.PricePoint
+		}
+		if low == nil || trade.PricePoint.Cmp(low) < 0 {
+			low = trade.PricePoint
+		}
+	}
+	return high, low, it.Error()
+}
+
+// GetOrderHistory implements XDCXHistoryDAO.
+func (db *BatchDatabase) GetOrderHistory(orderHash common.Hash) ([]*tradingstate.OrderHistoryItem, error) {
+	it := db.db.NewIterator(joinKey(orderHistoryPrefix, orderHash.Bytes()), nil)
+	defer it.Release()
+
+	result := []*tradingstate.OrderHistoryItem{}
+	for it.Next() {
+		item := new(tradingstate.OrderHistoryItem)
+		if err := json.Unmarshal(it.Value(), item); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// GetEpochPrices implements XDCXHistoryDAO.
+func (db *BatchDatabase) GetEpochPrices(orderbook common.Hash, fromEpoch, toEpoch uint64) ([]*tradingstate.EpochPriceItem, error) {
+	prefix := joinKey(epochPricePrefix, orderbook.Bytes())
+	it := db.db.NewIterator(prefix, encodeUint64(fromEpoch))
+	defer it.Release()
+
+	result := []*tradingstate.EpochPriceItem{}
+	for it.Next() {
+		if binary.BigEndian.Uint64(it.Key()[len(prefix):]) > toEpoch {
+			break
+		}
+		item := new(tradingstate.EpochPriceItem)
+		if err := json.Unmarshal(it.Value(), item); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
@@ -0,0 +1,162 @@
+package XDCxDAO
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+)
+
+func newTestOrder(user common.Address, hash common.Hash, txHash common.Hash, at time.Time) *tradingstate.OrderItem {
+	return &tradingstate.OrderItem{
+		Quantity:     big.NewInt(100),
+		Price:        big.NewInt(5),
+		UserAddress:  user,
+		BaseToken:    common.HexToAddress("0x01"),
+		QuoteToken:   common.HexToAddress("0x02"),
+		Status:       tradingstate.OrderStatusOpen,
+		Side:         tradingstate.Bid,
+		Type:         tradingstate.Limit,
+		Hash:         hash,
+		TxHash:       txHash,
+		FilledAmount: new(big.Int),
+		Nonce:        big.NewInt(1),
+		CreatedAt:    at,
+		UpdatedAt:    at,
+	}
+}
+
+func TestBatchDatabaseOrderHistory(t *testing.T) {
+	db := NewBatchDatabase(t.TempDir(), 0)
+	defer db.Close()
+
+	var (
+		user   = common.HexToAddress("0xaa")
+		start  = time.Unix(1000, 0).UTC()
+		order1 = newTestOrder(user, common.HexToHash("0x11"), common.HexToHash("0xa1"), start)
+		order2 = newTestOrder(user, common.HexToHash("0x12"), common.HexToHash("0xa2"), start.Add(time.Minute))
+	)
+	for _, order := range []*tradingstate.OrderItem{order1, order2} {
+		if err := db.PutObject(order.Hash, order); err != nil {
+			t.Fatalf("failed to put order: %v", err)
+		}
+	}
+	orders, err := db.GetOrdersByUser(user, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].Hash != order2.Hash || orders[1].Hash != order1.Hash {
+		t.Fatalf("orders by user mismatch: %v", orders)
+	}
+	if orders, _ := db.GetOrdersByUser(user, 1, 10); len(orders) != 1 || orders[0].Hash != order1.Hash {
+		t.Fatalf("paged orders by user mismatch: %v", orders)
+	}
+
+	// Fill the first order in a later transaction, then roll it back
+	filled := *order1
+	filled.TxHash = common.HexToHash("0xa3")
+	filled.FilledAmount = big.NewInt(100)
+	filled.Status = tradingstate.OrderStatusFilled
+	filled.UpdatedAt = start.Add(2 * time.Minute)
+	if err := db.PutObject(filled.Hash, &filled); err != nil {
+		t.Fatal(err)
+	}
+	history, _ := db.GetOrderHistory(order1.Hash)
+	if len(history) != 2 || history[1].Status != tradingstate.OrderStatusFilled {
+		t.Fatalf("order history mismatch: %v", history)
+	}
+	items := db.GetListItemByTxHash(filled.TxHash, &tradingstate.OrderItem{}).([]*tradingstate.OrderItem)
+	if len(items) != 1 || items[0].Hash != order1.Hash {
+		t.Fatalf("orders by tx hash mismatch: %v", items)
+	}
+	if items := db.GetListItemByTxHash(order1.TxHash, &tradingstate.OrderItem{}).([]*tradingstate.OrderItem); len(items) != 0 {
+		t.Fatalf("stale order returned by tx hash: %v", items)
+	}
+	if err := db.PutObject(order1.Hash, order1); err != nil {
+		t.Fatal(err)
+	}
+	history, _ = db.GetOrderHistory(order1.Hash)
+	if len(history) != 1 || history[0].Status != tradingstate.OrderStatusOpen {
+		t.Fatalf("rolled back order history mismatch: %v", history)
+	}
+
+	if err := db.DeleteObject(order2.Hash, &tradingstate.OrderItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if orders, _ := db.GetOrdersByUser(user, 0, 10); len(orders) != 1 {
+		t.Fatalf("deleted order still indexed: %v", orders)
+	}
+	if history, _ := db.GetOrderHistory(order2.Hash); len(history) != 0 {
+		t.Fatalf("deleted order history left: %v", history)
+	}
+}
+
+func TestBatchDatabaseTrades(t *testing.T) {
+	db := NewBatchDatabase(t.TempDir(), 0)
+	defer db.Close()
+
+	var (
+		base   = common.HexToAddress("0x01")
+		quote  = common.HexToAddress("0x02")
+		txHash = common.HexToHash("0xa1")
+		start  = time.Unix(1000, 0).UTC()
+	)
+	for i := 0; i < 5; i++ {
+		trade := &tradingstate.Trade{
+			BaseToken:      base,
+			QuoteToken:     quote,
+			MakerOrderHash: common.BigToHash(big.NewInt(int64(i))),
+			TxHash:         txHash,
+			PricePoint:     big.NewInt(int64(10 + i)),
+			Amount:         big.NewInt(1),
+			CreatedAt:      start.Add(time.Duration(i) * time.Minute),
+		}
+		trade.Hash = trade.ComputeHash()
+		if err := db.PutObject(trade.Hash, trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trades, err := db.GetTradesByPair(base, quote, start.Add(time.Minute), start.Add(3*time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || trades[0].PricePoint.Int64() != 11 || trades[1].PricePoint.Int64() != 12 {
+		t.Fatalf("trades by pair mismatch: %v", trades)
+	}
+	if trades, _ := db.GetTradesByPair(quote, base, start, start.Add(time.Hour), 10); len(trades) != 0 {
+		t.Fatalf("trades returned for the reverse pair: %v", trades)
+	}
+	if high, low, err := db.GetTradePriceRange(base, quote, start.Add(time.Minute), start.Add(4*time.Minute)); err != nil || high.Int64() != 13 || low.Int64() != 11 {
+		t.Fatalf("trade price range mismatch: high %v, low %v, err %v", high, low, err)
+	}
+	if high, low, _ := db.GetTradePriceRange(base, quote, start.Add(time.Hour), start.Add(2*time.Hour)); high != nil || low != nil {
+		t.Fatalf("trade price range outside of the trades: high %v, low %v", high, low)
+	}
+	db.DeleteItemByTxHash(txHash, &tradingstate.Trade{})
+	if trades, _ := db.GetTradesByPair(base, quote, start, start.Add(time.Hour), 10); len(trades) != 0 {
+		t.Fatalf("trades left after deletion: %v", trades)
+	}
+}
+
+func TestBatchDatabaseEpochPrices(t *testing.T) {
+	db := NewBatchDatabase(t.TempDir(), 0)
+	defer db.Close()
+
+	orderbook := common.HexToHash("0x0b")
+	for epoch := uint64(1); epoch <= 5; epoch++ {
+		item := &tradingstate.EpochPriceItem{Epoch: epoch, Orderbook: orderbook, Price: big.NewInt(int64(epoch)), Volume: big.NewInt(1), Time: 1000 * epoch}
+		item.Hash = item.ComputeHash()
+		if err := db.PutObject(item.Hash, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := db.GetEpochPrices(orderbook, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].Epoch != 2 || items[2].Epoch != 4 || items[2].Time != 4000 {
+		t.Fatalf("epoch prices mismatch: %v", items)
+	}
+}
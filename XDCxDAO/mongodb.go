@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,6 +28,7 @@ const (
 	lendingRepayCollection  = "lending_repays"
 	lendingRecallCollection = "lending_recalls"
 	epochPriceCollection    = "epoch_prices"
+	orderHistoryCollection  = "order_histories"
 )
 
 type MongoDatabase struct {
@@ -36,6 +39,7 @@ type MongoDatabase struct {
 	orderBulk        *mgo.Bulk
 	tradeBulk        *mgo.Bulk
 	epochPriceBulk   *mgo.Bulk
+	orderHistoryBulk *mgo.Bulk
 	lendingItemBulk  *mgo.Bulk
 	topUpBulk        *mgo.Bulk
 	recallBulk       *mgo.Bulk
@@ -239,6 +243,7 @@ func (db *MongoDatabase) PutObject(hash common.Hash, val interface{}) error {
 			query := bson.M{"hash": item.Hash.Hex()}
 			db.orderBulk.Upsert(query, item)
 		}
+		db.putOrderHistory(item)
 		return nil
 	case *tradingstate.EpochPriceItem:
 		query := bson.M{"hash": item.Hash.Hex()}
@@ -312,6 +317,10 @@ func (db *MongoDatabase) DeleteObject(hash common.Hash, val interface{}) error {
 			if err != nil && err != mgo.ErrNotFound {
 				return fmt.Errorf("failed to delete orderItem. Err: %v", err)
 			}
+			_, err = sc.DB(db.dbName).C(orderHistoryCollection).RemoveAll(bson.M{"orderHash": hash.Hex()})
+			if err != nil && err != mgo.ErrNotFound {
+				return fmt.Errorf("failed to delete order history. Err: %v", err)
+			}
 		case *tradingstate.Trade:
 			err = sc.DB(db.dbName).C(tradesCollection).Remove(query)
 			if err != nil && err != mgo.ErrNotFound {
@@ -348,6 +357,7 @@ func (db *MongoDatabase) InitBulk() {
 	db.orderBulk = sc.DB(db.dbName).C(ordersCollection).Bulk()
 	db.tradeBulk = sc.DB(db.dbName).C(tradesCollection).Bulk()
 	db.epochPriceBulk = sc.DB(db.dbName).C(epochPriceCollection).Bulk()
+	db.orderHistoryBulk = sc.DB(db.dbName).C(orderHistoryCollection).Bulk()
 }
 
 func (db *MongoDatabase) InitLendingBulk() {
@@ -369,6 +379,9 @@ func (db *MongoDatabase) CommitBulk() error {
 	if _, err := db.epochPriceBulk.Run(); err != nil && !mgo.IsDup(err) {
 		return err
 	}
+	if _, err := db.orderHistoryBulk.Run(); err != nil && !mgo.IsDup(err) {
+		return err
+	}
 	return nil
 }
 
@@ -701,6 +714,24 @@ func (db *MongoDatabase) EnsureIndexes() error {
 		Name:       "index_lending_topup_unique",
 	}
 
+	orderUserIndex := mgo.Index{
+		Key:        []string{"userAddress", "-createdAt"},
+		Background: true,
+		Sparse:     true,
+		Name:       "index_order_user",
+	}
+	tradePairIndex := mgo.Index{
+		Key:        []string{"baseToken", "quoteToken", "createdAt"},
+		Background: true,
+		Sparse:     true,
+		Name:       "index_trade_pair",
+	}
+	orderHistoryIndex := mgo.Index{
+		Key:        []string{"orderHash", "updatedAt"},
+		Background: true,
+		Sparse:     true,
+		Name:       "index_order_history",
+	}
 	epochPriceIndex := mgo.Index{
 		Key:        []string{"hash"},
 		Unique:     true,
@@ -724,6 +755,18 @@ func (db *MongoDatabase) EnsureIndexes() error {
 			return fmt.Errorf("failed to create index %s . Err: %v", orderTxHashIndex.Name, err)
 		}
 	}
+	if !existingIndex(orderUserIndex.Name, indexes) {
+		if err := sc.DB(db.dbName).C(ordersCollection).EnsureIndex(orderUserIndex); err != nil {
+			return fmt.Errorf("failed to create index %s . Err: %v", orderUserIndex.Name, err)
+		}
+	}
+
+	indexes, _ = sc.DB(db.dbName).C(orderHistoryCollection).Indexes()
+	if !existingIndex(orderHistoryIndex.Name, indexes) {
+		if err := sc.DB(db.dbName).C(orderHistoryCollection).EnsureIndex(orderHistoryIndex); err != nil {
+			return fmt.Errorf("failed to create index %s . Err: %v", orderHistoryIndex.Name, err)
+		}
+	}
 
 	indexes, _ = sc.DB(db.dbName).C(tradesCollection).Indexes()
 	if !existingIndex(tradeHashIndex.Name, indexes) {
@@ -736,6 +779,11 @@ func (db *MongoDatabase) EnsureIndexes() error {
 			return fmt.Errorf("failed to create index %s . Err: %v", tradeTxHashIndex.Name, err)
 		}
 	}
+	if !existingIndex(tradePairIndex.Name, indexes) {
+		if err := sc.DB(db.dbName).C(tradesCollection).EnsureIndex(tradePairIndex); err != nil {
+			return fmt.Errorf("failed to create index %s . Err: %v", tradePairIndex.Name, err)
+		}
+	}
 
 	indexes, _ = sc.DB(db.dbName).C(lendingItemsCollection).Indexes()
 	if !existingIndex(lendingItemHashIndex.Name, indexes) {
@@ -930,3 +978,136 @@ func existingIndex(indexName string, indexes []mgo.Index) bool {
 	}
 	return false
 }
+
+// orderHistoryRecord is a document of the order_histories collection, one per
+// status change of an order.
+type orderHistoryRecord struct {
+	OrderHash    string    `bson:"orderHash"`
+	TxHash       string    `bson:"txHash"`
+	FilledAmount string    `bson:"filledAmount"`
+	Status       string    `bson:"status"`
+	UpdatedAt    time.Time `bson:"updatedAt"`
+}
+
+// putOrderHistory queues the current state of an order into the history
+// bulk. States newer than the order itself were rolled back by a reorg and
+// are removed first.
+func (db *MongoDatabase) putOrderHistory(item *tradingstate.OrderItem) {
+	record := orderHistoryRecord{
+		OrderHash: item.Hash.Hex(),
+		TxHash:    item.TxHash.Hex(),
+		Status:    item.Status,
+		UpdatedAt: item.UpdatedAt,
+	}
+	if item.FilledAmount != nil {
+		record.FilledAmount = item.FilledAmount.String()
+	}
+	db.orderHistoryBulk.RemoveAll(bson.M{"orderHash": record.OrderHash, "updatedAt": bson.M{"$gt": item.UpdatedAt}})
+	db.orderHistoryBulk.Upsert(bson.M{"orderHash": record.OrderHash, "txHash": record.TxHash}, record)
+}
+
+// GetOrdersByUser implements XDCXHistoryDAO.
+func (db *MongoDatabase) GetOrdersByUser(user common.Address, offset, limit int) ([]*tradingstate.OrderItem, error) {
+	sc := db.Session.Copy()
+	defer sc.Close()
+
+	result := []*tradingstate.OrderItem{}
+	query := bson.M{"userAddress": user.Hex()}
+	if err := sc.DB(db.dbName).C(ordersCollection).Find(query).Sort("-createdAt").Skip(offset).Limit(limit).All(&result); err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTradesByPair implements XDCXHistoryDAO.
+func (db *MongoDatabase) GetTradesByPair(baseToken, quoteToken common.Address, from, to time.Time, limit int) ([]*tradingstate.Trade, error) {
+	sc := db.Session.Copy()
+	defer sc.Close()
+
+	result := []*tradingstate.Trade{}
+	query := bson.M{
+		"baseToken":  baseToken.Hex(),
+		"quoteToken": quoteToken.Hex(),
+		"createdAt":  bson.M{"$gte": from, "$lt": to},
+	}
+	if err := sc.DB(db.dbName).C(tradesCollection).Find(query).Sort("createdAt").Limit(limit).All(&result); err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTradePriceRange implements XDCXHistoryDAO. Prices are stored as strings,
+// so they are compared after decoding rather than by the query.
+func (db *MongoDatabase) GetTradePriceRange(baseToken, quoteToken common.Address, from, to time.Time) (*big.Int, *big.Int, error) {
+	sc := db.Session.Copy()
+	defer sc.Close()
+
+	query := bson.M{
+		"baseToken":  baseToken.Hex(),
+		"quoteToken": quoteToken.Hex(),
+		"createdAt":  bson.M{"$gte": from, "$lt": to},
+	}
+	var (
+		iter   = sc.DB(db.dbName).C(tradesCollection).Find(query).Select(bson.M{"pricepoint": 1}).Iter()
+		record struct {
+			PricePoint string `bson:"pricepoint"`
+		}
+		high, low *big.Int
+	)
+	for iter.Next(&record) {
+		price, ok := new(big.Int).SetString(record.PricePoint, 10)
+		if !ok {
+			continue
+		}
+		if high == nil || price.Cmp(high) > 0 {
+			high = price
+		}
+		if low == nil || price.Cmp(low) < 0 {
+			low = price
+		}
+	}
+	if err := iter.Close(); err != nil && err != mgo.ErrNotFound {
+		return nil, nil, err
+	}
+	return high, low, nil
+}
+
+// GetOrderHistory implements XDCXHistoryDAO.
+func (db *MongoDatabase) GetOrderHistory(orderHash common.Hash) ([]*tradingstate.OrderHistoryItem, error) {
+	sc := db.Session.Copy()
+	defer sc.Close()
+
+	var records []orderHistoryRecord
+	if err := sc.DB(db.dbName).C(orderHistoryCollection).Find(bson.M{"orderHash": orderHash.Hex()}).Sort("updatedAt").All(&records); err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+	result := make([]*tradingstate.OrderHistoryItem, 0, len(records))
+	for _, record := range records {
+		result = append(result, &tradingstate.OrderHistoryItem{
+			TxHash:       common.HexToHash(record.TxHash),
+			FilledAmount: tradingstate.ToBigInt(record.FilledAmount),
+			Status:       record.Status,
+			UpdatedAt:    record.UpdatedAt,
+		})
+	}
+	return result, nil
+}
+
+// GetEpochPrices implements XDCXHistoryDAO. Epochs are stored as strings, so
+// the items are looked up by their hashes instead of an epoch range.
+func (db *MongoDatabase) GetEpochPrices(orderbook common.Hash, fromEpoch, toEpoch uint64) ([]*tradingstate.EpochPriceItem, error) {
+	sc := db.Session.Copy()
+	defer sc.Close()
+
+	var hashes []string
+	for epoch := fromEpoch; epoch <= toEpoch; epoch++ {
+		item := tradingstate.EpochPriceItem{Epoch: epoch, Orderbook: orderbook}
+		hashes = append(hashes, item.ComputeHash().Hex())
+	}
+	result := []*tradingstate.EpochPriceItem{}
+	if err := sc.DB(db.dbName).C(epochPriceCollection).Find(bson.M{"hash": bson.M{"$in": hashes}}).All(&result); err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Epoch < result[j].Epoch })
+	return result, nil
+}
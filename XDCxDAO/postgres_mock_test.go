@@ -0,0 +1,288 @@
+package XDCxDAO
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+)
+
+// mockStatement is a statement the mocked server expects to receive next.
+type mockStatement struct {
+	query   string         // Expected query prefix
+	args    []driver.Value // Expected arguments, not checked if nil
+	columns []string       // Columns of the returned rows
+	rows    [][]driver.Value
+	err     error // Error to fail the statement with
+}
+
+// mockPostgres is a database/sql driver standing in for a PostgreSQL server:
+// it checks the statements it receives against a scripted sequence and
+// answers them with canned rows.
+type mockPostgres struct {
+	t         *testing.T
+	lock      sync.Mutex
+	pending   []*mockStatement
+	commits   int
+	rollbacks int
+}
+
+// newMockPostgres opens a database backed by a mocked server expecting the
+// schema to be at the given version, it fails the test if any scripted
+// statement is left unused when the test ends.
+func newMockPostgres(t *testing.T, version int) (*PostgresDatabase, *mockPostgres) {
+	mock := &mockPostgres{t: t}
+	mock.expectMigrations(version)
+
+	db, err := newPostgresDatabase(sql.OpenDB(mock), 0)
+	if err != nil {
+		t.Fatalf("failed to open mocked database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		mock.lock.Lock()
+		defer mock.lock.Unlock()
+		if len(mock.pending) > 0 {
+			t.Errorf("%d statements not executed, next: %s", len(mock.pending), mock.pending[0].query)
+		}
+	})
+	return db, mock
+}
+
+// expect scripts the next statements the server receives.
+func (m *mockPostgres) expect(stmts ...*mockStatement) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pending = append(m.pending, stmts...)
+}
+
+// expectMigrations scripts the statements migrating a schema at the given
+// version to the latest one.
+func (m *mockPostgres) expectMigrations(version int) {
+	m.expect(
+		&mockStatement{query: "CREATE TABLE IF NOT EXISTS schema_migrations"},
+		&mockStatement{query: "SELECT COALESCE(MAX(version), 0) FROM schema_migrations", columns: []string{"version"}, rows: [][]driver.Value{{int64(version)}}},
+	)
+	for ; version < len(postgresMigrations); version++ {
+		m.expect(
+			&mockStatement{query: postgresMigrations[version]},
+			&mockStatement{query: "INSERT INTO schema_migrations", args: []driver.Value{int64(version + 1)}},
+		)
+	}
+}
+
+// next pops the scripted statement matching a received one.
+func (m *mockPostgres) next(query string, args []driver.NamedValue) (*mockStatement, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(m.pending) == 0 {
+		m.t.Errorf("unexpected statement: %s", query)
+		return nil, errors.New("unexpected statement")
+	}
+	stmt := m.pending[0]
+	m.pending = m.pending[1:]
+
+	if !strings.HasPrefix(query, stmt.query) {
+		m.t.Errorf("statement mismatch:\nhave %s\nwant %s", query, stmt.query)
+		return nil, errors.New("statement mismatch")
+	}
+	if stmt.args != nil {
+		values := make([]driver.Value, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		if !reflect.DeepEqual(values, stmt.args) {
+			m.t.Errorf("arguments mismatch for %s:\nhave %v\nwant %v", stmt.query, values, stmt.args)
+			return nil, errors.New("arguments mismatch")
+		}
+	}
+	return stmt, stmt.err
+}
+
+func (m *mockPostgres) Connect(context.Context) (driver.Conn, error) { return &mockConn{m}, nil }
+func (m *mockPostgres) Driver() driver.Driver                        { return nil }
+
+type mockConn struct{ mock *mockPostgres }
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements not supported: %s", query)
+}
+func (c *mockConn) Close() error              { return nil }
+func (c *mockConn) Begin() (driver.Tx, error) { return &mockTx{c.mock}, nil }
+
+func (c *mockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := c.mock.next(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *mockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	stmt, err := c.mock.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &mockRows{columns: stmt.columns, rows: stmt.rows}, nil
+}
+
+type mockTx struct{ mock *mockPostgres }
+
+func (tx *mockTx) Commit() error {
+	tx.mock.lock.Lock()
+	defer tx.mock.lock.Unlock()
+	tx.mock.commits++
+	return nil
+}
+
+func (tx *mockTx) Rollback() error {
+	tx.mock.lock.Lock()
+	defer tx.mock.lock.Unlock()
+	tx.mock.rollbacks++
+	return nil
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *mockRows) Columns() []string { return r.columns }
+func (r *mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// Tests that only the missing schema migrations are applied, and that a schema
+// newer than the supported one is refused.
+func TestPostgresMockMigrations(t *testing.T) {
+	_, mock := newMockPostgres(t, 0)
+	if mock.commits != len(postgresMigrations) {
+		t.Fatalf("migration commits mismatch: have %d, want %d", mock.commits, len(postgresMigrations))
+	}
+	_, mock = newMockPostgres(t, len(postgresMigrations)-1)
+	if mock.commits != 1 {
+		t.Fatalf("migration commits mismatch: have %d, want 1", mock.commits)
+	}
+	_, mock = newMockPostgres(t, len(postgresMigrations))
+	if mock.commits != 0 {
+		t.Fatalf("migration applied twice")
+	}
+
+	mock = &mockPostgres{t: t}
+	mock.expect(
+		&mockStatement{query: "CREATE TABLE IF NOT EXISTS schema_migrations"},
+		&mockStatement{query: "SELECT COALESCE(MAX(version), 0) FROM schema_migrations", columns: []string{"version"}, rows: [][]driver.Value{{int64(len(postgresMigrations) + 1)}}},
+	)
+	if _, err := newPostgresDatabase(sql.OpenDB(mock), 0); err == nil {
+		t.Fatalf("newer schema accepted")
+	}
+}
+
+// Tests that a bulk is written in a single transaction, rolled back as a whole
+// if any of its statements fails.
+func TestPostgresMockCommitBulk(t *testing.T) {
+	db, mock := newMockPostgres(t, len(postgresMigrations))
+	mock.commits = 0
+
+	order := newTestOrder(common.HexToAddress("0xaa"), common.HexToHash("0x11"), common.HexToHash("0xa1"), time.Unix(1000, 0).UTC())
+	item := &tradingstate.EpochPriceItem{Epoch: 2, Orderbook: common.HexToHash("0x0b"), Price: big.NewInt(1), Time: 2000}
+	item.Hash = item.ComputeHash()
+
+	db.InitBulk()
+	db.PutObject(order.Hash, order)
+	db.PutObject(item.Hash, item)
+	mock.expect(
+		&mockStatement{query: upsertOrderQuery},
+		&mockStatement{query: "DELETE FROM " + orderHistoryCollection, args: []driver.Value{order.Hash.Hex(), order.UpdatedAt}},
+		&mockStatement{query: upsertOrderHistoryQuery},
+		&mockStatement{query: upsertEpochPriceQuery, args: []driver.Value{item.Hash.Hex(), item.Orderbook.Hex(), int64(2), "1", nil, int64(2000)}},
+	)
+	if err := db.CommitBulk(); err != nil {
+		t.Fatalf("failed to commit bulk: %v", err)
+	}
+	if mock.commits != 1 || mock.rollbacks != 0 {
+		t.Fatalf("transaction mismatch: commits %d, rollbacks %d", mock.commits, mock.rollbacks)
+	}
+	// The committed bulk is reset, a failing one is rolled back
+	if err := db.CommitBulk(); err != nil {
+		t.Fatalf("failed to commit empty bulk: %v", err)
+	}
+	db.PutObject(item.Hash, item)
+	mock.expect(&mockStatement{query: upsertEpochPriceQuery, err: errors.New("connection reset")})
+	if err := db.CommitBulk(); err == nil {
+		t.Fatalf("failed bulk committed")
+	}
+	if mock.commits != 1 || mock.rollbacks != 1 {
+		t.Fatalf("transaction mismatch: commits %d, rollbacks %d", mock.commits, mock.rollbacks)
+	}
+}
+
+// Tests that the history queries decode the returned rows.
+func TestPostgresMockHistoryQueries(t *testing.T) {
+	db, mock := newMockPostgres(t, len(postgresMigrations))
+
+	var (
+		base, quote = common.HexToAddress("0x01"), common.HexToAddress("0x02")
+		from, to    = time.Unix(1000, 0).UTC(), time.Unix(2000, 0).UTC()
+		orderbook   = common.HexToHash("0x0b")
+	)
+	mock.expect(&mockStatement{
+		query:   "SELECT MAX(price_point), MIN(price_point) FROM " + tradesCollection,
+		args:    []driver.Value{base.Hex(), quote.Hex(), from, to},
+		columns: []string{"max", "min"},
+		rows:    [][]driver.Value{{[]byte("120000000000000000000"), []byte("11")}},
+	})
+	high, low, err := db.GetTradePriceRange(base, quote, from, to)
+	if err != nil || high.String() != "120000000000000000000" || low.Int64() != 11 {
+		t.Fatalf("trade price range mismatch: high %v, low %v, err %v", high, low, err)
+	}
+	mock.expect(&mockStatement{
+		query:   "SELECT MAX(price_point), MIN(price_point) FROM " + tradesCollection,
+		columns: []string{"max", "min"},
+		rows:    [][]driver.Value{{nil, nil}},
+	})
+	if high, low, err := db.GetTradePriceRange(base, quote, from, to); err != nil || high != nil || low != nil {
+		t.Fatalf("empty trade price range mismatch: high %v, low %v, err %v", high, low, err)
+	}
+
+	mock.expect(&mockStatement{
+		query:   selectQuery(epochPriceCollection, epochPriceColumns, "WHERE orderbook = $1"),
+		args:    []driver.Value{orderbook.Hex(), int64(2), int64(5)},
+		columns: epochPriceColumns,
+		rows: [][]driver.Value{
+			{common.HexToHash("0x02").Hex(), orderbook.Hex(), int64(2), "2", nil, int64(2000)},
+			{common.HexToHash("0x03").Hex(), orderbook.Hex(), int64(3), "3", "30", int64(3000)},
+		},
+	})
+	items, err := db.GetEpochPrices(orderbook, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Epoch != 2 || items[0].Volume != nil || items[0].Time != 2000 || items[1].Volume.Int64() != 30 || items[1].Orderbook != orderbook {
+		t.Fatalf("epoch prices mismatch: %v", items)
+	}
+
+	// A failed listing is reported as an empty one
+	mock.expect(&mockStatement{query: selectQuery(tradesCollection, tradeColumns, "WHERE tx_hash = $1"), err: errors.New("connection reset")})
+	if trades := db.GetListItemByTxHash(common.HexToHash("0xa1"), &tradingstate.Trade{}).([]*tradingstate.Trade); len(trades) != 0 {
+		t.Fatalf("trades returned by a failed query: %v", trades)
+	}
+}
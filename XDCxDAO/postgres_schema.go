@@ -0,0 +1,447 @@
+package XDCxDAO
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/XDCxlending/lendingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+)
+
+// postgresMigrations are the schema changes of the postgres database, applied
+// in order and recorded in the schema_migrations table. Released migrations
+// must never be modified, append a new one instead.
+var postgresMigrations = []string{
+	// 1: XDCx orders, trades, order history and epoch prices
+	`CREATE TABLE orders (
+		hash             TEXT PRIMARY KEY,
+		tx_hash          TEXT NOT NULL,
+		user_address     TEXT NOT NULL,
+		exchange_address TEXT NOT NULL,
+		base_token       TEXT NOT NULL,
+		quote_token      TEXT NOT NULL,
+		status           TEXT NOT NULL,
+		side             TEXT NOT NULL,
+		type             TEXT NOT NULL,
+		quantity         NUMERIC(78, 0),
+		price            NUMERIC(78, 0),
+		filled_amount    NUMERIC(78, 0),
+		nonce            NUMERIC(78, 0),
+		signature_v      SMALLINT,
+		signature_r      TEXT,
+		signature_s      TEXT,
+		order_id         BIGINT NOT NULL,
+		extra_data       TEXT NOT NULL,
+		created_at       TIMESTAMPTZ NOT NULL,
+		updated_at       TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX orders_tx_hash_idx ON orders (tx_hash);
+	CREATE INDEX orders_user_idx ON orders (user_address, created_at DESC);
+
+	CREATE TABLE order_histories (
+		order_hash    TEXT NOT NULL,
+		tx_hash       TEXT NOT NULL,
+		filled_amount NUMERIC(78, 0),
+		status        TEXT NOT NULL,
+		updated_at    TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (order_hash, tx_hash)
+	);
+	CREATE INDEX order_histories_updated_idx ON order_histories (order_hash, updated_at);
+
+	CREATE TABLE trades (
+		hash             TEXT PRIMARY KEY,
+		tx_hash          TEXT NOT NULL,
+		taker            TEXT NOT NULL,
+		maker            TEXT NOT NULL,
+		base_token       TEXT NOT NULL,
+		quote_token      TEXT NOT NULL,
+		maker_order_hash TEXT NOT NULL,
+		taker_order_hash TEXT NOT NULL,
+		maker_exchange   TEXT NOT NULL,
+		taker_exchange   TEXT NOT NULL,
+		price_point      NUMERIC(78, 0),
+		amount           NUMERIC(78, 0),
+		make_fee         NUMERIC(78, 0),
+		take_fee         NUMERIC(78, 0),
+		status           TEXT NOT NULL,
+		taker_order_side TEXT NOT NULL,
+		taker_order_type TEXT NOT NULL,
+		maker_order_type TEXT NOT NULL,
+		created_at       TIMESTAMPTZ NOT NULL,
+		updated_at       TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX trades_tx_hash_idx ON trades (tx_hash);
+	CREATE INDEX trades_pair_idx ON trades (base_token, quote_token, created_at);
+
+	CREATE TABLE epoch_prices (
+		hash      TEXT PRIMARY KEY,
+		orderbook TEXT NOT NULL,
+		epoch     BIGINT NOT NULL,
+		price     NUMERIC(78, 0),
+		volume    NUMERIC(78, 0)
+	);
+	CREATE INDEX epoch_prices_orderbook_idx ON epoch_prices (orderbook, epoch);`,
+
+	// 2: XDCx lending items, repays, top ups, recalls and trades
+	lendingItemTableSchema(lendingItemsCollection, "PRIMARY KEY (hash)") +
+		lendingItemTableSchema(lendingRepayCollection, "UNIQUE (tx_hash, hash)") +
+		lendingItemTableSchema(lendingTopUpCollection, "UNIQUE (tx_hash, hash)") +
+		lendingItemTableSchema(lendingRecallCollection, "UNIQUE (tx_hash, hash)") +
+		`CREATE TABLE lending_trades (
+		hash                     TEXT PRIMARY KEY,
+		tx_hash                  TEXT NOT NULL,
+		borrower                 TEXT NOT NULL,
+		investor                 TEXT NOT NULL,
+		lending_token            TEXT NOT NULL,
+		collateral_token         TEXT NOT NULL,
+		borrowing_order_hash     TEXT NOT NULL,
+		investing_order_hash     TEXT NOT NULL,
+		borrowing_relayer        TEXT NOT NULL,
+		investing_relayer        TEXT NOT NULL,
+		term                     BIGINT NOT NULL,
+		interest                 BIGINT NOT NULL,
+		collateral_price         NUMERIC(78, 0),
+		liquidation_price        NUMERIC(78, 0),
+		collateral_locked_amount NUMERIC(78, 0),
+		auto_top_up              BOOLEAN NOT NULL,
+		liquidation_time         BIGINT NOT NULL,
+		deposit_rate             NUMERIC(78, 0),
+		liquidation_rate         NUMERIC(78, 0),
+		recall_rate              NUMERIC(78, 0),
+		amount                   NUMERIC(78, 0),
+		borrowing_fee            NUMERIC(78, 0),
+		investing_fee            NUMERIC(78, 0),
+		status                   TEXT NOT NULL,
+		taker_order_side         TEXT NOT NULL,
+		taker_order_type         TEXT NOT NULL,
+		maker_order_type         TEXT NOT NULL,
+		trade_id                 BIGINT NOT NULL,
+		extra_data               TEXT NOT NULL,
+		created_at               TIMESTAMPTZ NOT NULL,
+		updated_at               TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX lending_trades_tx_hash_idx ON lending_trades (tx_hash);`,
+
+	// 3: close time of the epochs, bounding the trades of their candles
+	`ALTER TABLE epoch_prices ADD COLUMN time BIGINT NOT NULL DEFAULT 0;`,
+}
+
+// lendingItemTableSchema returns the schema of a table of lending items. The
+// lending items, repays, top ups and recalls share the same layout.
+func lendingItemTableSchema(table string, key string) string {
+	return fmt.Sprintf(`CREATE TABLE %[1]s (
+		hash             TEXT NOT NULL,
+		tx_hash          TEXT NOT NULL,
+		user_address     TEXT NOT NULL,
+		relayer          TEXT NOT NULL,
+		lending_token    TEXT NOT NULL,
+		collateral_token TEXT NOT NULL,
+		side             TEXT NOT NULL,
+		type             TEXT NOT NULL,
+		status           TEXT NOT NULL,
+		quantity         NUMERIC(78, 0),
+		interest         NUMERIC(78, 0),
+		filled_amount    NUMERIC(78, 0),
+		nonce            NUMERIC(78, 0),
+		term             BIGINT NOT NULL,
+		auto_top_up      BOOLEAN NOT NULL,
+		signature_v      SMALLINT,
+		signature_r      TEXT,
+		signature_s      TEXT,
+		lending_id       BIGINT NOT NULL,
+		lending_trade_id BIGINT NOT NULL,
+		extra_data       TEXT NOT NULL,
+		created_at       TIMESTAMPTZ NOT NULL,
+		updated_at       TIMESTAMPTZ NOT NULL,
+		%[2]s
+	);
+	CREATE INDEX %[1]s_tx_hash_idx ON %[1]s (tx_hash);
+	`, table, key)
+}
+
+var (
+	orderColumns = []string{
+		"hash", "tx_hash", "user_address", "exchange_address", "base_token", "quote_token", "status", "side", "type",
+		"quantity", "price", "filled_amount", "nonce", "signature_v", "signature_r", "signature_s",
+		"order_id", "extra_data", "created_at", "updated_at",
+	}
+	orderHistoryColumns = []string{"order_hash", "tx_hash", "filled_amount", "status", "updated_at"}
+	tradeColumns        = []string{
+		"hash", "tx_hash", "taker", "maker", "base_token", "quote_token", "maker_order_hash", "taker_order_hash",
+		"maker_exchange", "taker_exchange", "price_point", "amount", "make_fee", "take_fee", "status",
+		"taker_order_side", "taker_order_type", "maker_order_type", "created_at", "updated_at",
+	}
+	epochPriceColumns  = []string{"hash", "orderbook", "epoch", "price", "volume", "time"}
+	lendingItemColumns = []string{
+		"hash", "tx_hash", "user_address", "relayer", "lending_token", "collateral_token", "side", "type", "status",
+		"quantity", "interest", "filled_amount", "nonce", "term", "auto_top_up", "signature_v", "signature_r",
+		"signature_s", "lending_id", "lending_trade_id", "extra_data", "created_at", "updated_at",
+	}
+	lendingTradeColumns = []string{
+		"hash", "tx_hash", "borrower", "investor", "lending_token", "collateral_token", "borrowing_order_hash",
+		"investing_order_hash", "borrowing_relayer", "investing_relayer", "term", "interest", "collateral_price",
+		"liquidation_price", "collateral_locked_amount", "auto_top_up", "liquidation_time", "deposit_rate",
+		"liquidation_rate", "recall_rate", "amount", "borrowing_fee", "investing_fee", "status", "taker_order_side",
+		"taker_order_type", "maker_order_type", "trade_id", "extra_data", "created_at", "updated_at",
+	}
+)
+
+// upsertQuery returns an insert statement of the given columns replacing the
+// row conflicting on key, or leaving it untouched if replace isn't set.
+func upsertQuery(table string, columns []string, key string, replace bool) string {
+	placeholders := make([]string, len(columns))
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		updates[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) ", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), key)
+	if !replace {
+		return query + "DO NOTHING"
+	}
+	return query + "DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// selectQuery returns a select statement of the given columns, completed by
+// the given clauses.
+func selectQuery(table string, columns []string, clauses string) string {
+	return fmt.Sprintf("SELECT %s FROM %s %s", strings.Join(columns, ", "), table, clauses)
+}
+
+// rowScanner is implemented by both sql.Row and sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// numericValue encodes a big integer as a NUMERIC value.
+func numericValue(n *big.Int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.String()
+}
+
+// sqlNumeric decodes a nullable NUMERIC column into a big integer.
+type sqlNumeric struct{ v **big.Int }
+
+func (s sqlNumeric) Scan(src interface{}) error {
+	var text string
+	switch src := src.(type) {
+	case nil:
+		*s.v = nil
+		return nil
+	case int64:
+		*s.v = big.NewInt(src)
+		return nil
+	case []byte:
+		text = string(src)
+	case string:
+		text = src
+	default:
+		return fmt.Errorf("unsupported NUMERIC source %T", src)
+	}
+	n, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return fmt.Errorf("invalid NUMERIC value %q", text)
+	}
+	*s.v = n
+	return nil
+}
+
+// sqlHex decodes a TEXT column holding a hex encoded address or hash.
+type sqlHex struct{ v interface{} }
+
+func (s sqlHex) Scan(src interface{}) error {
+	var text string
+	switch src := src.(type) {
+	case []byte:
+		text = string(src)
+	case string:
+		text = src
+	default:
+		return fmt.Errorf("unsupported TEXT source %T", src)
+	}
+	switch v := s.v.(type) {
+	case *common.Address:
+		*v = common.HexToAddress(text)
+	case *common.Hash:
+		*v = common.HexToHash(text)
+	default:
+		return fmt.Errorf("unsupported hex destination %T", v)
+	}
+	return nil
+}
+
+// signatureValues encodes a signature into its V, R and S columns.
+func signatureValues(v *byte, r, s *common.Hash) (interface{}, interface{}, interface{}) {
+	if v == nil {
+		return nil, nil, nil
+	}
+	return int64(*v), r.Hex(), s.Hex()
+}
+
+// sqlSignature collects the V, R and S columns of a signature.
+type sqlSignature struct {
+	v    sql.NullInt64
+	r, s sql.NullString
+}
+
+func (s *sqlSignature) dest() []interface{} {
+	return []interface{}{&s.v, &s.r, &s.s}
+}
+
+func (s *sqlSignature) valid() bool {
+	return s.v.Valid && s.r.Valid && s.s.Valid
+}
+
+func orderValues(o *tradingstate.OrderItem) []interface{} {
+	var sigV, sigR, sigS interface{}
+	if o.Signature != nil {
+		sigV, sigR, sigS = signatureValues(&o.Signature.V, &o.Signature.R, &o.Signature.S)
+	}
+	return []interface{}{
+		o.Hash.Hex(), o.TxHash.Hex(), o.UserAddress.Hex(), o.ExchangeAddress.Hex(), o.BaseToken.Hex(), o.QuoteToken.Hex(), o.Status, o.Side, o.Type,
+		numericValue(o.Quantity), numericValue(o.Price), numericValue(o.FilledAmount), numericValue(o.Nonce), sigV, sigR, sigS,
+		int64(o.OrderID), o.ExtraData, o.CreatedAt, o.UpdatedAt,
+	}
+}
+
+func scanOrder(row rowScanner) (*tradingstate.OrderItem, error) {
+	var (
+		o       = new(tradingstate.OrderItem)
+		sig     sqlSignature
+		orderID int64
+	)
+	dest := []interface{}{
+		sqlHex{&o.Hash}, sqlHex{&o.TxHash}, sqlHex{&o.UserAddress}, sqlHex{&o.ExchangeAddress}, sqlHex{&o.BaseToken}, sqlHex{&o.QuoteToken}, &o.Status, &o.Side, &o.Type,
+		sqlNumeric{&o.Quantity}, sqlNumeric{&o.Price}, sqlNumeric{&o.FilledAmount}, sqlNumeric{&o.Nonce},
+	}
+	dest = append(dest, sig.dest()...)
+	dest = append(dest, &orderID, &o.ExtraData, &o.CreatedAt, &o.UpdatedAt)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if sig.valid() {
+		o.Signature = &tradingstate.Signature{V: byte(sig.v.Int64), R: common.HexToHash(sig.r.String), S: common.HexToHash(sig.s.String)}
+	}
+	o.OrderID = uint64(orderID)
+	return o, nil
+}
+
+func orderHistoryValues(o *tradingstate.OrderItem) []interface{} {
+	return []interface{}{o.Hash.Hex(), o.TxHash.Hex(), numericValue(o.FilledAmount), o.Status, o.UpdatedAt}
+}
+
+func scanOrderHistory(row rowScanner) (*tradingstate.OrderHistoryItem, error) {
+	var (
+		h         = new(tradingstate.OrderHistoryItem)
+		orderHash common.Hash
+	)
+	if err := row.Scan(sqlHex{&orderHash}, sqlHex{&h.TxHash}, sqlNumeric{&h.FilledAmount}, &h.Status, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func tradeValues(t *tradingstate.Trade) []interface{} {
+	return []interface{}{
+		t.Hash.Hex(), t.TxHash.Hex(), t.Taker.Hex(), t.Maker.Hex(), t.BaseToken.Hex(), t.QuoteToken.Hex(), t.MakerOrderHash.Hex(), t.TakerOrderHash.Hex(),
+		t.MakerExchange.Hex(), t.TakerExchange.Hex(), numericValue(t.PricePoint), numericValue(t.Amount), numericValue(t.MakeFee), numericValue(t.TakeFee), t.Status,
+		t.TakerOrderSide, t.TakerOrderType, t.MakerOrderType, t.CreatedAt, t.UpdatedAt,
+	}
+}
+
+func scanTrade(row rowScanner) (*tradingstate.Trade, error) {
+	t := new(tradingstate.Trade)
+	err := row.Scan(
+		sqlHex{&t.Hash}, sqlHex{&t.TxHash}, sqlHex{&t.Taker}, sqlHex{&t.Maker}, sqlHex{&t.BaseToken}, sqlHex{&t.QuoteToken}, sqlHex{&t.MakerOrderHash}, sqlHex{&t.TakerOrderHash},
+		sqlHex{&t.MakerExchange}, sqlHex{&t.TakerExchange}, sqlNumeric{&t.PricePoint}, sqlNumeric{&t.Amount}, sqlNumeric{&t.MakeFee}, sqlNumeric{&t.TakeFee}, &t.Status,
+		&t.TakerOrderSide, &t.TakerOrderType, &t.MakerOrderType, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func epochPriceValues(e *tradingstate.EpochPriceItem) []interface{} {
+	return []interface{}{e.Hash.Hex(), e.Orderbook.Hex(), int64(e.Epoch), numericValue(e.Price), numericValue(e.Volume), int64(e.Time)}
+}
+
+func scanEpochPrice(row rowScanner) (*tradingstate.EpochPriceItem, error) {
+	var (
+		e           = new(tradingstate.EpochPriceItem)
+		epoch, time int64
+	)
+	if err := row.Scan(sqlHex{&e.Hash}, sqlHex{&e.Orderbook}, &epoch, sqlNumeric{&e.Price}, sqlNumeric{&e.Volume}, &time); err != nil {
+		return nil, err
+	}
+	e.Epoch, e.Time = uint64(epoch), uint64(time)
+	return e, nil
+}
+
+func lendingItemValues(l *lendingstate.LendingItem) []interface{} {
+	var sigV, sigR, sigS interface{}
+	if l.Signature != nil {
+		sigV, sigR, sigS = signatureValues(&l.Signature.V, &l.Signature.R, &l.Signature.S)
+	}
+	return []interface{}{
+		l.Hash.Hex(), l.TxHash.Hex(), l.UserAddress.Hex(), l.Relayer.Hex(), l.LendingToken.Hex(), l.CollateralToken.Hex(), l.Side, l.Type, l.Status,
+		numericValue(l.Quantity), numericValue(l.Interest), numericValue(l.FilledAmount), numericValue(l.Nonce), int64(l.Term), l.AutoTopUp, sigV, sigR,
+		sigS, int64(l.LendingId), int64(l.LendingTradeId), l.ExtraData, l.CreatedAt, l.UpdatedAt,
+	}
+}
+
+func scanLendingItem(row rowScanner) (*lendingstate.LendingItem, error) {
+	var (
+		l                             = new(lendingstate.LendingItem)
+		sig                           sqlSignature
+		term, lendingID, lendingTrade int64
+	)
+	dest := []interface{}{
+		sqlHex{&l.Hash}, sqlHex{&l.TxHash}, sqlHex{&l.UserAddress}, sqlHex{&l.Relayer}, sqlHex{&l.LendingToken}, sqlHex{&l.CollateralToken}, &l.Side, &l.Type, &l.Status,
+		sqlNumeric{&l.Quantity}, sqlNumeric{&l.Interest}, sqlNumeric{&l.FilledAmount}, sqlNumeric{&l.Nonce}, &term, &l.AutoTopUp,
+	}
+	dest = append(dest, sig.dest()...)
+	dest = append(dest, &lendingID, &lendingTrade, &l.ExtraData, &l.CreatedAt, &l.UpdatedAt)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if sig.valid() {
+		l.Signature = &lendingstate.Signature{V: byte(sig.v.Int64), R: common.HexToHash(sig.r.String), S: common.HexToHash(sig.s.String)}
+	}
+	l.Term, l.LendingId, l.LendingTradeId = uint64(term), uint64(lendingID), uint64(lendingTrade)
+	return l, nil
+}
+
+func lendingTradeValues(t *lendingstate.LendingTrade) []interface{} {
+	return []interface{}{
+		t.Hash.Hex(), t.TxHash.Hex(), t.Borrower.Hex(), t.Investor.Hex(), t.LendingToken.Hex(), t.CollateralToken.Hex(), t.BorrowingOrderHash.Hex(),
+		t.InvestingOrderHash.Hex(), t.BorrowingRelayer.Hex(), t.InvestingRelayer.Hex(), int64(t.Term), int64(t.Interest), numericValue(t.CollateralPrice),
+		numericValue(t.LiquidationPrice), numericValue(t.CollateralLockedAmount), t.AutoTopUp, int64(t.LiquidationTime), numericValue(t.DepositRate),
+		numericValue(t.LiquidationRate), numericValue(t.RecallRate), numericValue(t.Amount), numericValue(t.BorrowingFee), numericValue(t.InvestingFee), t.Status, t.TakerOrderSide,
+		t.TakerOrderType, t.MakerOrderType, int64(t.TradeId), t.ExtraData, t.CreatedAt, t.UpdatedAt,
+	}
+}
+
+func scanLendingTrade(row rowScanner) (*lendingstate.LendingTrade, error) {
+	var (
+		t                                        = new(lendingstate.LendingTrade)
+		term, interest, liquidationTime, tradeID int64
+	)
+	err := row.Scan(
+		sqlHex{&t.Hash}, sqlHex{&t.TxHash}, sqlHex{&t.Borrower}, sqlHex{&t.Investor}, sqlHex{&t.LendingToken}, sqlHex{&t.CollateralToken}, sqlHex{&t.BorrowingOrderHash},
+		sqlHex{&t.InvestingOrderHash}, sqlHex{&t.BorrowingRelayer}, sqlHex{&t.InvestingRelayer}, &term, &interest, sqlNumeric{&t.CollateralPrice},
+		sqlNumeric{&t.LiquidationPrice}, sqlNumeric{&t.CollateralLockedAmount}, &t.AutoTopUp, &liquidationTime, sqlNumeric{&t.DepositRate},
+		sqlNumeric{&t.LiquidationRate}, sqlNumeric{&t.RecallRate}, sqlNumeric{&t.Amount}, sqlNumeric{&t.BorrowingFee}, sqlNumeric{&t.InvestingFee}, &t.Status, &t.TakerOrderSide,
+		&t.TakerOrderType, &t.MakerOrderType, &tradeID, &t.ExtraData, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	t.Term, t.Interest, t.LiquidationTime, t.TradeId = uint64(term), uint64(interest), uint64(liquidationTime), uint64(tradeID)
+	return t, nil
+}
@@ -0,0 +1,263 @@
+package XDCxDAO
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/XDCxlending/lendingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+)
+
+// newTestPostgres connects to the server given by XDCX_POSTGRES_URL, e.g.
+// postgres://postgres@localhost:5432/postgres?sslmode=disable, and returns a
+// database living in a fresh schema, dropped when the test ends.
+func newTestPostgres(t *testing.T) (*PostgresDatabase, string) {
+	url := os.Getenv("XDCX_POSTGRES_URL")
+	if url == "" {
+		t.Skip("XDCX_POSTGRES_URL not set")
+	}
+	admin, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("xdcx_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	url += sep + "search_path=" + schema
+
+	db, err := NewPostgresDatabase(url, 0)
+	if err != nil {
+		t.Fatalf("failed to open postgres database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, url
+}
+
+func TestUpsertQuery(t *testing.T) {
+	have := upsertQuery("trades", []string{"hash", "amount"}, "hash", true)
+	want := "INSERT INTO trades (hash, amount) VALUES ($1, $2) ON CONFLICT (hash) DO UPDATE SET hash = EXCLUDED.hash, amount = EXCLUDED.amount"
+	if have != want {
+		t.Errorf("upsert query mismatch:\nhave %s\nwant %s", have, want)
+	}
+	have = upsertQuery("lending_repays", []string{"hash", "tx_hash"}, "tx_hash, hash", false)
+	want = "INSERT INTO lending_repays (hash, tx_hash) VALUES ($1, $2) ON CONFLICT (tx_hash, hash) DO NOTHING"
+	if have != want {
+		t.Errorf("insert query mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
+
+func TestSQLScanners(t *testing.T) {
+	var n *big.Int
+	if err := (sqlNumeric{&n}).Scan([]byte("123456789012345678901234567890")); err != nil || n.String() != "123456789012345678901234567890" {
+		t.Fatalf("numeric mismatch: %v, %v", n, err)
+	}
+	if err := (sqlNumeric{&n}).Scan(nil); err != nil || n != nil {
+		t.Fatalf("null numeric mismatch: %v, %v", n, err)
+	}
+	if err := (sqlNumeric{&n}).Scan("1.5"); err == nil {
+		t.Fatalf("fractional numeric accepted")
+	}
+	var (
+		addr common.Address
+		hash common.Hash
+	)
+	if err := (sqlHex{&addr}).Scan("0x00000000000000000000000000000000000000aa"); err != nil || addr != common.HexToAddress("0xaa") {
+		t.Fatalf("address mismatch: %v, %v", addr, err)
+	}
+	if err := (sqlHex{&hash}).Scan([]byte("0x01")); err != nil || hash != common.HexToHash("0x01") {
+		t.Fatalf("hash mismatch: %v, %v", hash, err)
+	}
+}
+
+func TestPostgresDatabaseOrders(t *testing.T) {
+	db, url := newTestPostgres(t)
+
+	var (
+		user  = common.HexToAddress("0xaa")
+		start = time.Unix(1000, 0).UTC()
+		order = newTestOrder(user, common.HexToHash("0x11"), common.HexToHash("0xa1"), start)
+	)
+	order.Signature = &tradingstate.Signature{V: 27, R: common.HexToHash("0x04"), S: common.HexToHash("0x05")}
+	db.InitBulk()
+	if err := db.PutObject(order.Hash, order); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CommitBulk(); err != nil {
+		t.Fatalf("failed to commit bulk: %v", err)
+	}
+	// Reopening the database skips the applied migrations and starts with an
+	// empty cache
+	db.Close()
+	db, err := NewPostgresDatabase(url, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	val, err := db.GetObject(order.Hash, &tradingstate.OrderItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := val.(*tradingstate.OrderItem)
+	if stored.Quantity.Cmp(order.Quantity) != 0 || stored.UserAddress != user || !stored.CreatedAt.Equal(start) || *stored.Signature != *order.Signature {
+		t.Fatalf("stored order mismatch: have %+v, want %+v", stored, order)
+	}
+	if has, _ := db.HasObject(common.HexToHash("0x12"), &tradingstate.OrderItem{}); has {
+		t.Fatalf("unknown order found")
+	}
+
+	// Fill the order in a later transaction, then roll it back
+	filled := *order
+	filled.TxHash = common.HexToHash("0xa2")
+	filled.FilledAmount = big.NewInt(100)
+	filled.Status = tradingstate.OrderStatusFilled
+	filled.UpdatedAt = start.Add(time.Minute)
+	db.InitBulk()
+	db.PutObject(filled.Hash, &filled)
+	if err := db.CommitBulk(); err != nil {
+		t.Fatal(err)
+	}
+	if items := db.GetListItemByTxHash(filled.TxHash, &tradingstate.OrderItem{}).([]*tradingstate.OrderItem); len(items) != 1 || items[0].Status != tradingstate.OrderStatusFilled {
+		t.Fatalf("orders by tx hash mismatch: %v", items)
+	}
+	if history, _ := db.GetOrderHistory(order.Hash); len(history) != 2 {
+		t.Fatalf("order history mismatch: %v", history)
+	}
+	db.InitBulk()
+	db.PutObject(order.Hash, order)
+	if err := db.CommitBulk(); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := db.GetOrderHistory(order.Hash); len(history) != 1 || history[0].Status != tradingstate.OrderStatusOpen {
+		t.Fatalf("rolled back order history mismatch: %v", history)
+	}
+	if orders, _ := db.GetOrdersByUser(user, 0, 10); len(orders) != 1 || orders[0].Status != tradingstate.OrderStatusOpen {
+		t.Fatalf("orders by user mismatch: %v", orders)
+	}
+	if err := db.DeleteObject(order.Hash, &tradingstate.OrderItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := db.HasObject(order.Hash, &tradingstate.OrderItem{}); has {
+		t.Fatalf("deleted order found")
+	}
+}
+
+func TestPostgresDatabaseTrades(t *testing.T) {
+	db, _ := newTestPostgres(t)
+
+	var (
+		base   = common.HexToAddress("0x01")
+		quote  = common.HexToAddress("0x02")
+		txHash = common.HexToHash("0xa1")
+		start  = time.Unix(1000, 0).UTC()
+		hashes []string
+	)
+	db.InitBulk()
+	for i := 0; i < 5; i++ {
+		trade := &tradingstate.Trade{
+			BaseToken:      base,
+			QuoteToken:     quote,
+			MakerOrderHash: common.BigToHash(big.NewInt(int64(i))),
+			TxHash:         txHash,
+			PricePoint:     big.NewInt(int64(10 + i)),
+			Amount:         big.NewInt(1),
+			CreatedAt:      start.Add(time.Duration(i) * time.Minute),
+		}
+		trade.Hash = trade.ComputeHash()
+		hashes = append(hashes, trade.Hash.Hex())
+		db.PutObject(trade.Hash, trade)
+	}
+	for epoch := uint64(1); epoch <= 3; epoch++ {
+		item := &tradingstate.EpochPriceItem{Epoch: epoch, Orderbook: common.HexToHash("0x0b"), Price: big.NewInt(int64(epoch)), Time: 1000 * epoch}
+		item.Hash = item.ComputeHash()
+		db.PutObject(item.Hash, item)
+	}
+	if err := db.CommitBulk(); err != nil {
+		t.Fatal(err)
+	}
+	trades, err := db.GetTradesByPair(base, quote, start.Add(time.Minute), start.Add(3*time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || trades[0].PricePoint.Int64() != 11 || trades[1].PricePoint.Int64() != 12 || trades[0].MakeFee != nil {
+		t.Fatalf("trades by pair mismatch: %v", trades)
+	}
+	if items := db.GetListItemByHashes(hashes[:2], &tradingstate.Trade{}).([]*tradingstate.Trade); len(items) != 2 {
+		t.Fatalf("trades by hashes mismatch: %v", items)
+	}
+	if high, low, err := db.GetTradePriceRange(base, quote, start.Add(time.Minute), start.Add(3*time.Minute)); err != nil || high.Int64() != 12 || low.Int64() != 11 {
+		t.Fatalf("trade price range mismatch: high %v, low %v, err %v", high, low, err)
+	}
+	if items, _ := db.GetEpochPrices(common.HexToHash("0x0b"), 2, 5); len(items) != 2 || items[0].Epoch != 2 || items[0].Volume != nil || items[0].Time != 2000 {
+		t.Fatalf("epoch prices mismatch: %v", items)
+	}
+	db.DeleteItemByTxHash(txHash, &tradingstate.Trade{})
+	if items := db.GetListItemByTxHash(txHash, &tradingstate.Trade{}).([]*tradingstate.Trade); len(items) != 0 {
+		t.Fatalf("trades left after deletion: %v", items)
+	}
+}
+
+func TestPostgresDatabaseLending(t *testing.T) {
+	db, _ := newTestPostgres(t)
+
+	var (
+		txHash = common.HexToHash("0xa1")
+		hash   = common.HexToHash("0x11")
+	)
+	newItem := func(typ string, status string) *lendingstate.LendingItem {
+		return &lendingstate.LendingItem{
+			Quantity:     big.NewInt(100),
+			Interest:     big.NewInt(5),
+			Side:         lendingstate.Investing,
+			Type:         typ,
+			FilledAmount: new(big.Int),
+			Status:       status,
+			Term:         86400,
+			Hash:         hash,
+			TxHash:       txHash,
+			LendingId:    7,
+			CreatedAt:    time.Unix(1000, 0).UTC(),
+			UpdatedAt:    time.Unix(1000, 0).UTC(),
+		}
+	}
+	db.InitLendingBulk()
+	db.PutObject(hash, newItem(lendingstate.Limit, lendingstate.LendingStatusOpen))
+	// A repay is kept apart from the lending items, only once per transaction
+	db.PutObject(hash, newItem(lendingstate.Repay, lendingstate.LendingStatusOpen))
+	db.PutObject(hash, newItem(lendingstate.Repay, lendingstate.LendingStatusOpen))
+	db.PutObject(hash, &lendingstate.LendingTrade{Hash: common.HexToHash("0x21"), TxHash: txHash, Term: 86400, Amount: big.NewInt(100), TradeId: 3})
+	if err := db.CommitLendingBulk(); err != nil {
+		t.Fatalf("failed to commit lending bulk: %v", err)
+	}
+	items := db.GetListItemByTxHash(txHash, &lendingstate.LendingItem{}).([]*lendingstate.LendingItem)
+	if len(items) != 1 || items[0].Term != 86400 || items[0].LendingId != 7 || items[0].Status != lendingstate.LendingStatusOpen {
+		t.Fatalf("lending items mismatch: %v", items)
+	}
+	repays := db.GetListItemByTxHash(txHash, &lendingstate.LendingItem{Type: lendingstate.Repay}).([]*lendingstate.LendingItem)
+	if len(repays) != 1 || repays[0].Status != lendingstate.Repay {
+		t.Fatalf("repays mismatch: %v", repays)
+	}
+	trades := db.GetListItemByTxHash(txHash, &lendingstate.LendingTrade{}).([]*lendingstate.LendingTrade)
+	if len(trades) != 1 || trades[0].TradeId != 3 || trades[0].Amount.Int64() != 100 || trades[0].CollateralPrice != nil {
+		t.Fatalf("lending trades mismatch: %v", trades)
+	}
+	db.DeleteItemByTxHash(txHash, &lendingstate.LendingItem{Type: lendingstate.Repay})
+	if repays := db.GetListItemByTxHash(txHash, &lendingstate.LendingItem{Type: lendingstate.Repay}).([]*lendingstate.LendingItem); len(repays) != 0 {
+		t.Fatalf("repays left after deletion: %v", repays)
+	}
+}
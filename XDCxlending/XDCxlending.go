@@ -81,8 +81,8 @@ func (l *Lending) GetLevelDB() XDCxDAO.XDCXDAO {
 	return l.XDCx.GetLevelDB()
 }
 
-func (l *Lending) GetMongoDB() XDCxDAO.XDCXDAO {
-	return l.XDCx.GetMongoDB()
+func (l *Lending) GetSDKDB() XDCxDAO.XDCXDAO {
+	return l.XDCx.GetSDKDB()
 }
 
 // APIs returns the RPC descriptors the Lending implementation offers
@@ -204,7 +204,7 @@ func (l *Lending) SyncDataToSDKNode(chain consensus.ChainContext, statedb *state
 		makerDirtyFilledAmount                          map[string]*big.Int
 		err                                             error
 	)
-	db := l.GetMongoDB()
+	db := l.GetSDKDB()
 	db.InitLendingBulk()
 	if takerLendingItem.Status == lendingstate.LendingStatusCancelled && len(rejectedItems) > 0 {
 		// cancel order is rejected -> nothing change
@@ -469,7 +469,7 @@ func (l *Lending) SyncDataToSDKNode(chain consensus.ChainContext, statedb *state
 }
 
 func (l *Lending) UpdateLiquidatedTrade(blockTime uint64, result lendingstate.FinalizedResult, trades map[common.Hash]*lendingstate.LendingTrade) error {
-	db := l.GetMongoDB()
+	db := l.GetSDKDB()
 	db.InitLendingBulk()
 
 	txhash := result.TxHash
@@ -615,7 +615,7 @@ func (l *Lending) UpdateLiquidatedTrade(blockTime uint64, result lendingstate.Fi
 }
 
 func (l *Lending) UpdateLendingTrade(trades map[common.Hash]*lendingstate.LendingTrade, txhash common.Hash, txTime time.Time) error {
-	db := l.GetMongoDB()
+	db := l.GetSDKDB()
 	hashQuery := []string{}
 	if len(trades) == 0 {
 		return nil
@@ -731,7 +731,7 @@ func (l *Lending) UpdateLendingTradeCache(hash common.Hash, txhash common.Hash,
 }
 
 func (l *Lending) RollbackLendingData(txhash common.Hash) error {
-	db := l.GetMongoDB()
+	db := l.GetSDKDB()
 	db.InitLendingBulk()
 
 	// rollback lendingItem
@@ -843,7 +843,7 @@ func (l *Lending) ProcessLiquidationData(header *types.Header, chain consensus.C
 				}
 				if trade != nil && trade.Hash != (common.Hash{}) {
 					updatedTrades[trade.Hash] = trade
-					if trade.Status == lendingstate.TradeStatusLiquidated {
+					if trade.Status == lendingstate.TradeStatusLiquidated || trade.Status == lendingstate.TradeStatusLiquidationAuction {
 						liquidatedTrades = append(liquidatedTrades, trade)
 					} else if trade.Status == lendingstate.TradeStatusClosed {
 						autoRepayTrades = append(autoRepayTrades, trade)
@@ -877,6 +877,17 @@ func (l *Lending) ProcessLiquidationData(header *types.Header, chain consensus.C
 							continue
 						}
 					}
+					if chain.Config().IsTIPXDCXLendingAuction(header.Number) {
+						log.Debug("StartLiquidationAuction", "highestLiquidatePrice", highestLiquidatePrice, "lendingBook", lendingBook.Hex(), "tradingIdHash", tradingIdHash.Hex())
+						newTrade, err := l.StartLiquidationAuction(header, lendingState, tradingState, lendingBook, tradingIdHash.Big().Uint64(), collateralPrice, lendingstate.LiquidatedByPrice)
+						if err != nil {
+							log.Error("Fail when start liquidation auction", "time", time, "lendingBook", lendingBook.Hex(), "tradingIdHash", tradingIdHash.Hex(), "error", err)
+							return updatedTrades, liquidatedTrades, autoRepayTrades, autoTopUpTrades, autoRecallTrades, err
+						}
+						liquidatedTrades = append(liquidatedTrades, newTrade)
+						updatedTrades[newTrade.Hash] = newTrade
+						continue
+					}
 					log.Debug("LiquidationTrade", "highestLiquidatePrice", highestLiquidatePrice, "lendingBook", lendingBook.Hex(), "tradingIdHash", tradingIdHash.Hex())
 					newTrade, err := l.LiquidationTrade(lendingState, statedb, tradingState, lendingBook, tradingIdHash.Big().Uint64())
 					if err != nil {
@@ -935,3 +946,69 @@ func (l *Lending) ProcessLiquidationData(header *types.Header, chain consensus.C
 	log.Debug("ProcessLiquidationData", "updatedTrades", len(updatedTrades), "liquidated", len(liquidatedTrades), "autoRepay", len(autoRepayTrades), "autoTopUp", len(autoTopUpTrades), "autoRecall", len(autoRecallTrades))
 	return updatedTrades, liquidatedTrades, autoRepayTrades, autoTopUpTrades, autoRecallTrades, nil
 }
+
+// ProcessLiquidationAuctions runs every liquidation auction for one block:
+// the collateral is sold to the bids crossing the current auction price, and
+// auctions that repaid their debt, sold all their collateral or expired are
+// settled. Only the lending books indexed as running auctions are visited. It returns the trades of the settled auctions and every sale of
+// collateral made in the block.
+func (l *Lending) ProcessLiquidationAuctions(header *types.Header, chain consensus.ChainContext, statedb *state.StateDB, tradingState *tradingstate.TradingStateDB, lendingState *lendingstate.LendingStateDB) (updatedTrades map[common.Hash]*lendingstate.LendingTrade, liquidatedTrades []*lendingstate.LendingTrade, fills []*lendingstate.AuctionFill, err error) {
+	updatedTrades = map[common.Hash]*lendingstate.LendingTrade{}
+	liquidatedTrades = []*lendingstate.LendingTrade{}
+	fills = []*lendingstate.AuctionFill{}
+	if !chain.Config().IsTIPXDCXLendingAuction(header.Number) {
+		return updatedTrades, liquidatedTrades, fills, nil
+	}
+	// the lending books are visited in ascending order, fills of one auction
+	// change the order book seen by the next one
+	number := header.Number.Uint64()
+	for _, lendingBook := range lendingState.GetAuctionLendingBooks() {
+		for _, auction := range lendingState.GetLiquidationAuctions(lendingBook) {
+			collateralTokenDecimal, err := l.XDCx.GetTokenDecimal(chain, statedb, auction.CollateralToken)
+			if err != nil || collateralTokenDecimal == nil || collateralTokenDecimal.Sign() == 0 {
+				log.Error("ProcessLiquidationAuctions: cannot get collateral token decimal", "collateralToken", auction.CollateralToken.Hex(), "err", err)
+				continue
+			}
+			auctionFills, err := fillLiquidationAuction(statedb, tradingState, &auction, auction.Price(number), collateralTokenDecimal)
+			if err != nil {
+				log.Error("Fail when fill liquidation auction", "lendingBook", lendingBook.Hex(), "tradeId", auction.TradeId, "error", err)
+				return updatedTrades, liquidatedTrades, fills, err
+			}
+			fills = append(fills, auctionFills...)
+			if auction.Collateral.Sign() > 0 && !auction.Covered() && !auction.Expired(number) {
+				// only auctions that sold collateral are written back
+				if len(auctionFills) > 0 {
+					lendingState.InsertLiquidationAuction(lendingBook, auction)
+				}
+				continue
+			}
+			surplus, err := settleLiquidationAuction(statedb, &auction)
+			if err != nil {
+				log.Error("Fail when settle liquidation auction", "lendingBook", lendingBook.Hex(), "tradeId", auction.TradeId, "error", err)
+				return updatedTrades, liquidatedTrades, fills, err
+			}
+			liquidationData := lendingstate.LiquidationData{
+				RecallAmount:      auction.Collateral,
+				LiquidationAmount: auction.SoldAmount,
+				CollateralPrice:   auction.CollateralPrice,
+				Reason:            auction.Reason,
+				AuctionSoldAmount: auction.SoldAmount,
+				AuctionProceeds:   auction.Proceeds,
+				AuctionSurplus:    surplus,
+			}
+			if !auction.Covered() {
+				// the investor took the unsold collateral
+				liquidationData.RecallAmount = common.Big0
+				liquidationData.LiquidationAmount = lendingstate.Add(auction.SoldAmount, auction.Collateral)
+			}
+			if err := lendingState.RemoveLiquidationAuction(lendingBook, auction.TradeId); err != nil {
+				return updatedTrades, liquidatedTrades, fills, err
+			}
+			log.Debug("Settled liquidation auction", "lendingBook", lendingBook.Hex(), "tradeId", auction.TradeId, "sold", auction.SoldAmount, "proceeds", auction.Proceeds, "debt", auction.Debt, "surplus", surplus)
+			trade := auction.LendingTrade(lendingstate.TradeStatusLiquidated, liquidationData)
+			liquidatedTrades = append(liquidatedTrades, trade)
+			updatedTrades[trade.Hash] = trade
+		}
+	}
+	return updatedTrades, liquidatedTrades, fills, nil
+}
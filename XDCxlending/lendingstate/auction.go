@@ -0,0 +1,117 @@
+package lendingstate
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+)
+
+// LiquidationAuction holds the collateral seized from a liquidated lending
+// trade while it is sold through the XDCx order book. The asking price falls
+// linearly from StartPrice at StartBlock to FloorPrice at EndBlock.
+type LiquidationAuction struct {
+	TradeId          uint64         `json:"tradeId"`
+	TradeHash        common.Hash    `json:"tradeHash"`
+	Investor         common.Address `json:"investor"`
+	Borrower         common.Address `json:"borrower"`
+	LendingToken     common.Address `json:"lendingToken"`
+	CollateralToken  common.Address `json:"collateralToken"`
+	Collateral       *big.Int       `json:"collateral"`       // collateral left to sell
+	SoldAmount       *big.Int       `json:"soldAmount"`       // collateral sold so far
+	Debt             *big.Int       `json:"debt"`             // lending token owed to the investor
+	Proceeds         *big.Int       `json:"proceeds"`         // lending token raised so far
+	CollateralPrice  *big.Int       `json:"collateralPrice"`  // collateral/lending price when the auction started
+	LiquidationPrice *big.Int       `json:"liquidationPrice"` // liquidation price of the trade
+	StartPrice       *big.Int       `json:"startPrice"`
+	FloorPrice       *big.Int       `json:"floorPrice"`
+	StartBlock       uint64         `json:"startBlock"`
+	EndBlock         uint64         `json:"endBlock"`
+	Reason           uint64         `json:"reason"` // LiquidatedByTime or LiquidatedByPrice
+}
+
+// AuctionFill is a sale of auctioned collateral to a bid of the XDCx order book.
+type AuctionFill struct {
+	TradeId   uint64         `json:"tradeId"`
+	Maker     common.Address `json:"maker"`
+	OrderHash common.Hash    `json:"orderHash"`
+	Price     *big.Int       `json:"price"`
+	Quantity  *big.Int       `json:"quantity"` // collateral sold
+	Cost      *big.Int       `json:"cost"`     // lending token paid by the maker
+}
+
+var EmptyLiquidationAuction = LiquidationAuction{
+	Collateral: big.NewInt(0),
+}
+
+// NewLiquidationAuction opens an auction for the collateral locked by trade,
+// starting at the given block and priced around collateralPrice.
+func NewLiquidationAuction(trade *LendingTrade, debt, collateralPrice *big.Int, startBlock uint64, reason uint64) LiquidationAuction {
+	startPrice := new(big.Int).Mul(collateralPrice, common.AuctionStartPremium)
+	startPrice = new(big.Int).Div(startPrice, common.BaseAuction)
+	floorPrice := new(big.Int).Mul(collateralPrice, common.AuctionFloorRate)
+	floorPrice = new(big.Int).Div(floorPrice, common.BaseAuction)
+	return LiquidationAuction{
+		TradeId:          trade.TradeId,
+		TradeHash:        trade.Hash,
+		Investor:         trade.Investor,
+		Borrower:         trade.Borrower,
+		LendingToken:     trade.LendingToken,
+		CollateralToken:  trade.CollateralToken,
+		Collateral:       CloneBigInt(trade.CollateralLockedAmount),
+		SoldAmount:       big.NewInt(0),
+		Debt:             CloneBigInt(debt),
+		Proceeds:         big.NewInt(0),
+		CollateralPrice:  CloneBigInt(collateralPrice),
+		LiquidationPrice: CloneBigInt(trade.LiquidationPrice),
+		StartPrice:       startPrice,
+		FloorPrice:       floorPrice,
+		StartBlock:       startBlock,
+		EndBlock:         startBlock + common.LiquidationAuctionBlocks,
+		Reason:           reason,
+	}
+}
+
+// Price returns the lowest price, in lending token per collateral token,
+// the auction accepts at the given block.
+func (a *LiquidationAuction) Price(number uint64) *big.Int {
+	if number <= a.StartBlock {
+		return CloneBigInt(a.StartPrice)
+	}
+	if number >= a.EndBlock || a.StartPrice.Cmp(a.FloorPrice) <= 0 {
+		return CloneBigInt(a.FloorPrice)
+	}
+	// price = StartPrice - (StartPrice - FloorPrice) * elapsed / duration
+	decay := new(big.Int).Sub(a.StartPrice, a.FloorPrice)
+	decay = new(big.Int).Mul(decay, new(big.Int).SetUint64(number-a.StartBlock))
+	decay = new(big.Int).Div(decay, new(big.Int).SetUint64(a.EndBlock-a.StartBlock))
+	return new(big.Int).Sub(a.StartPrice, decay)
+}
+
+// Expired reports whether the auction has run for its full duration.
+func (a *LiquidationAuction) Expired(number uint64) bool {
+	return number >= a.EndBlock
+}
+
+// Covered reports whether the proceeds repay the debt in full.
+func (a *LiquidationAuction) Covered() bool {
+	return a.Proceeds.Cmp(a.Debt) >= 0
+}
+
+// LendingTrade returns the record of the auctioned trade as the SDK keeps it,
+// with the given status and liquidation data.
+func (a *LiquidationAuction) LendingTrade(status string, data LiquidationData) *LendingTrade {
+	extraData, _ := json.Marshal(data)
+	return &LendingTrade{
+		Borrower:               a.Borrower,
+		Investor:               a.Investor,
+		LendingToken:           a.LendingToken,
+		CollateralToken:        a.CollateralToken,
+		LiquidationPrice:       a.LiquidationPrice,
+		CollateralLockedAmount: Add(a.Collateral, a.SoldAmount),
+		TradeId:                a.TradeId,
+		Hash:                   a.TradeHash,
+		Status:                 status,
+		ExtraData:              string(extraData),
+	}
+}
@@ -0,0 +1,33 @@
+package lendingstate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+)
+
+func TestLiquidationAuctionPrice(t *testing.T) {
+	trade := &LendingTrade{TradeId: 1, CollateralLockedAmount: big.NewInt(100), LiquidationPrice: big.NewInt(70)}
+	auction := NewLiquidationAuction(trade, big.NewInt(60), big.NewInt(1000), 100, LiquidatedByTime)
+	end := 100 + common.LiquidationAuctionBlocks
+	tests := []struct {
+		number uint64
+		want   int64
+	}{
+		{0, 1100},
+		{100, 1100},
+		{100 + common.LiquidationAuctionBlocks/2, 800},
+		{end - 1, 502},
+		{end, 500},
+		{end + 100, 500},
+	}
+	for _, tt := range tests {
+		if got := auction.Price(tt.number); got.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("Price(%d) = %v, want %d", tt.number, got, tt.want)
+		}
+	}
+	if auction.Expired(end-1) || !auction.Expired(end) {
+		t.Errorf("auction should expire at block %d", end)
+	}
+}
@@ -15,6 +15,10 @@ var (
 
 var EmptyHash = common.Hash{}
 var Zero = big.NewInt(0)
+
+// AuctionLendingBooksHash is the key of the lending exchange object listing the
+// lending books running liquidation auctions.
+var AuctionLendingBooksHash = crypto.Keccak256Hash([]byte("XDCxlending.auctionLendingBooks"))
 var One = big.NewInt(1)
 var EmptyLendingOrder = LendingItem{
 	Quantity: Zero,
@@ -37,6 +41,9 @@ type lendingObject struct {
 	LiquidationTimeRoot common.Hash
 	LendingItemRoot     common.Hash
 	LendingTradeRoot    common.Hash
+
+	LiquidationAuctionRoot common.Hash   `rlp:"optional"`
+	AuctionLendingBooks    []common.Hash `rlp:"optional"` // only on AuctionLendingBooksHash, see GetAuctionLendingBooks
 }
 
 // liquidation reasons
@@ -50,6 +57,11 @@ type LiquidationData struct {
 	LiquidationAmount *big.Int
 	CollateralPrice   *big.Int
 	Reason            uint64
+
+	// auction settlement, only set for trades liquidated through an auction
+	AuctionSoldAmount *big.Int `json:",omitempty"`
+	AuctionProceeds   *big.Int `json:",omitempty"`
+	AuctionSurplus    *big.Int `json:",omitempty"`
 }
 
 var (
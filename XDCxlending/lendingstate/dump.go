@@ -416,3 +416,118 @@ func (ls *LendingStateDB) DumpLendingTradeTrie(orderBook common.Hash) (map[*big.
 	}
 	return result, nil
 }
+
+func (ls *LendingStateDB) DumpLiquidationAuctionTrie(orderBook common.Hash) (map[*big.Int]LiquidationAuction, error) {
+	exhangeObject := ls.getLendingExchange(orderBook)
+	if exhangeObject == nil {
+		return nil, fmt.Errorf("not found orderBook: %v", orderBook.Hex())
+	}
+	result := map[*big.Int]LiquidationAuction{}
+	for _, auction := range ls.GetLiquidationAuctions(orderBook) {
+		result[new(big.Int).SetUint64(auction.TradeId)] = auction
+	}
+	return result, ls.Error()
+}
+
+// DumpLendingBook is the content of a single lending book of the lending state.
+type DumpLendingBook struct {
+	Info             *DumpOrderBookInfo
+	Investings       map[*big.Int]DumpOrderList
+	Borrowings       map[*big.Int]DumpOrderList
+	LiquidationTimes map[*big.Int]DumpOrderList
+	LendingItems     map[*big.Int]LendingItem
+	LendingTrades    map[*big.Int]LendingTrade
+	Auctions         map[*big.Int]LiquidationAuction
+}
+
+// DumpState is the content of a whole lending state: every lending book and
+// the lending nonce of every user.
+type DumpState struct {
+	LendingBooks        map[common.Hash]*DumpLendingBook
+	Nonces              map[common.Address]uint64
+	AuctionLendingBooks []common.Hash
+}
+
+// isUserNonce reports whether an object of the lending state trie holds the
+// lending nonce of a user rather than a lending book. Both share the same key
+// space, but a user object never carries trades or trees.
+func (le *lendingExchangeState) isUserNonce() bool {
+	if le.data.TradeNonce != 0 {
+		return false
+	}
+	for _, root := range []common.Hash{le.data.InvestingRoot, le.data.BorrowingRoot, le.data.LiquidationTimeRoot, le.data.LendingItemRoot, le.data.LendingTradeRoot, le.data.LiquidationAuctionRoot} {
+		if root != (common.Hash{}) && root != EmptyRoot {
+			return false
+		}
+	}
+	return true
+}
+
+// DumpLendingBook returns every tree of the given lending book.
+func (ls *LendingStateDB) DumpLendingBook(orderBook common.Hash) (*DumpLendingBook, error) {
+	var (
+		result = new(DumpLendingBook)
+		err    error
+	)
+	if result.Info, err = ls.DumpOrderBookInfo(orderBook); err != nil {
+		return nil, err
+	}
+	if result.Investings, err = ls.DumpInvestingTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.Borrowings, err = ls.DumpBorrowingTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.LiquidationTimes, err = ls.DumpLiquidationTimeTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.LendingItems, err = ls.DumpLendingOrderTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.LendingTrades, err = ls.DumpLendingTradeTrie(orderBook); err != nil {
+		return nil, err
+	}
+	if result.Auctions, err = ls.DumpLiquidationAuctionTrie(orderBook); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Dump returns every lending book and user nonce of the lending state.
+func (ls *LendingStateDB) Dump() (*DumpState, error) {
+	result := &DumpState{
+		LendingBooks: map[common.Hash]*DumpLendingBook{},
+		Nonces:       map[common.Address]uint64{},
+	}
+	keys := map[common.Hash]struct{}{}
+	it := trie.NewIterator(ls.trie.NodeIterator(nil))
+	for it.Next() {
+		keys[common.BytesToHash(it.Key)] = struct{}{}
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	for key := range ls.lendingExchangeStates {
+		keys[key] = struct{}{}
+	}
+	for key := range keys {
+		exhangeObject := ls.getLendingExchange(key)
+		if exhangeObject == nil {
+			continue
+		}
+		if key == AuctionLendingBooksHash {
+			result.AuctionLendingBooks = ls.GetAuctionLendingBooks()
+			continue
+		}
+		if exhangeObject.isUserNonce() {
+			result.Nonces[common.BytesToAddress(key[:])] = exhangeObject.Nonce()
+			continue
+		}
+		lendingBook, err := ls.DumpLendingBook(key)
+		if err != nil {
+			return nil, err
+		}
+		result.LendingBooks[key] = lendingBook
+	}
+	return result, ls.Error()
+}
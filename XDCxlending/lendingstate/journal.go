@@ -75,6 +75,14 @@ type (
 		tradeId   common.Hash
 		prev      *big.Int
 	}
+	liquidationAuctionChange struct {
+		lendingBook common.Hash
+		tradeId     uint64
+		prev        LiquidationAuction
+	}
+	auctionLendingBooksChange struct {
+		prev []common.Hash
+	}
 )
 
 func (ch insertOrder) undo(s *LendingStateDB) {
@@ -141,3 +149,15 @@ func (ch collateralLockedAmount) undo(s *LendingStateDB) {
 	}
 	stateLendingTrade.SetCollateralLockedAmount(ch.prev)
 }
+
+func (ch liquidationAuctionChange) undo(s *LendingStateDB) {
+	stateOrderBook := s.getLendingExchange(ch.lendingBook)
+	if stateOrderBook == nil {
+		return
+	}
+	stateOrderBook.setLiquidationAuction(s.db, common.Uint64ToHash(ch.tradeId), ch.prev)
+}
+
+func (ch auctionLendingBooksChange) undo(s *LendingStateDB) {
+	s.GetOrNewLendingExchangeObject(AuctionLendingBooksHash).setAuctionLendingBooks(ch.prev)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
 )
 
 type lendingExchangeState struct {
@@ -44,6 +45,7 @@ type lendingExchangeState struct {
 	lendingItemTrie     Trie
 	lendingTradeTrie    Trie
 	liquidationTimeTrie Trie
+	auctionTrie         Trie
 
 	liquidationTimeStates      map[common.Hash]*liquidationTimeState
 	liquidationTimestatesDirty map[common.Hash]struct{}
@@ -60,6 +62,9 @@ type lendingExchangeState struct {
 	lendingTradeStates      map[common.Hash]*lendingTradeState
 	lendingTradeStatesDirty map[common.Hash]struct{}
 
+	liquidationAuctionStates      map[common.Hash]*liquidationAuctionState
+	liquidationAuctionStatesDirty map[common.Hash]struct{}
+
 	onDirty func(hash common.Hash) // Callback method to mark a state object newly dirty
 }
 
@@ -86,6 +91,12 @@ func (s *lendingExchangeState) empty() bool {
 	if !s.data.LiquidationTimeRoot.IsZero() {
 		return false
 	}
+	if !s.data.LiquidationAuctionRoot.IsZero() {
+		return false
+	}
+	if len(s.data.AuctionLendingBooks) > 0 {
+		return false
+	}
 	return true
 }
 
@@ -104,7 +115,10 @@ func newStateExchanges(db *LendingStateDB, hash common.Hash, data lendingObject,
 		lendingItemStatesDirty:     make(map[common.Hash]struct{}),
 		lendingTradeStatesDirty:    make(map[common.Hash]struct{}),
 		liquidationTimestatesDirty: make(map[common.Hash]struct{}),
-		onDirty:                    onDirty,
+
+		liquidationAuctionStates:      make(map[common.Hash]*liquidationAuctionState),
+		liquidationAuctionStatesDirty: make(map[common.Hash]struct{}),
+		onDirty:                       onDirty,
 	}
 }
 
@@ -148,6 +162,18 @@ func (le *lendingExchangeState) getLendingTradeTrie(db Database) Trie {
 	return le.lendingTradeTrie
 }
 
+func (le *lendingExchangeState) getLiquidationAuctionTrie(db Database) Trie {
+	if le.auctionTrie == nil {
+		var err error
+		le.auctionTrie, err = db.OpenStorageTrie(le.lendingBook, le.data.LiquidationAuctionRoot)
+		if err != nil {
+			le.auctionTrie, _ = db.OpenStorageTrie(le.lendingBook, types.EmptyRootHash)
+			le.setError(fmt.Errorf("can't create liquidation auction trie: %v", err))
+		}
+	}
+	return le.auctionTrie
+}
+
 func (le *lendingExchangeState) getInvestingTrie(db Database) Trie {
 	if le.investingTrie == nil {
 		var err error
@@ -304,6 +330,58 @@ func (le *lendingExchangeState) getLendingTrade(db Database, tradeId common.Hash
 	return obj
 }
 
+func (le *lendingExchangeState) getLiquidationAuction(db Database, tradeId common.Hash) (stateObject *liquidationAuctionState) {
+	// Prefer 'live' objects.
+	if obj := le.liquidationAuctionStates[tradeId]; obj != nil {
+		return obj
+	}
+	if le.data.LiquidationAuctionRoot.IsZero() {
+		return nil
+	}
+	// Load the object from the database.
+	enc, err := le.getLiquidationAuctionTrie(db).TryGet(tradeId[:])
+	if len(enc) == 0 {
+		le.setError(err)
+		return nil
+	}
+	var data LiquidationAuction
+	if err := rlp.DecodeBytes(enc, &data); err != nil {
+		log.Error("Failed to decode state liquidation auction", "tradeId", tradeId, "err", err)
+		return nil
+	}
+	// Insert into the live set.
+	obj := newLiquidationAuctionState(le.lendingBook, tradeId, data, le.MarkLiquidationAuctionDirty)
+	le.liquidationAuctionStates[tradeId] = obj
+	return obj
+}
+
+// getAllLiquidationAuctions returns the running auctions of this lending book,
+// both committed and live.
+func (le *lendingExchangeState) getAllLiquidationAuctions(db Database) []LiquidationAuction {
+	auctions := []LiquidationAuction{}
+	for _, obj := range le.liquidationAuctionStates {
+		if !obj.empty() {
+			auctions = append(auctions, obj.data)
+		}
+	}
+	if le.data.LiquidationAuctionRoot.IsZero() {
+		return auctions
+	}
+	it := trie.NewIterator(le.getLiquidationAuctionTrie(db).NodeIterator(nil))
+	for it.Next() {
+		if _, exist := le.liquidationAuctionStates[common.BytesToHash(it.Key)]; exist {
+			continue
+		}
+		var data LiquidationAuction
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			log.Error("Failed to decode state liquidation auction", "tradeId", common.BytesToHash(it.Key), "err", err)
+			continue
+		}
+		auctions = append(auctions, data)
+	}
+	return auctions
+}
+
 /*
 *
 
@@ -343,6 +421,23 @@ func (le *lendingExchangeState) updateLendingTradeTrie(db Database) Trie {
 	return tr
 }
 
+func (le *lendingExchangeState) updateLiquidationAuctionTrie(db Database) Trie {
+	tr := le.getLiquidationAuctionTrie(db)
+	for tradeId, auction := range le.liquidationAuctionStates {
+		if _, isDirty := le.liquidationAuctionStatesDirty[tradeId]; isDirty {
+			delete(le.liquidationAuctionStatesDirty, tradeId)
+			if auction.empty() {
+				le.setError(tr.TryDelete(tradeId[:]))
+				continue
+			}
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ := rlp.EncodeToBytes(auction)
+			le.setError(tr.TryUpdate(tradeId[:], v))
+		}
+	}
+	return tr
+}
+
 func (le *lendingExchangeState) updateBorrowingTrie(db Database) Trie {
 	tr := le.getBorrowingTrie(db)
 	for rate, orderList := range le.borrowingStates {
@@ -434,6 +529,23 @@ func (le *lendingExchangeState) updateLiquidationTimeRoot(db Database) {
 	le.data.LiquidationTimeRoot = le.liquidationTimeTrie.Hash()
 }
 
+// updateLiquidationAuctionRoot only touches the root once an auction has been
+// opened in this lending book, so the encoding of books that never ran one is
+// unchanged. An emptied auction trie is stored as the zero hash for the same reason.
+func (le *lendingExchangeState) updateLiquidationAuctionRoot(db Database) {
+	if le.auctionTrie == nil && len(le.liquidationAuctionStatesDirty) == 0 {
+		return
+	}
+	le.data.LiquidationAuctionRoot = auctionRoot(le.updateLiquidationAuctionTrie(db).Hash())
+}
+
+func auctionRoot(root common.Hash) common.Hash {
+	if root == EmptyRoot {
+		return common.Hash{}
+	}
+	return root
+}
+
 func (le *lendingExchangeState) updateLendingTradeRoot(db Database) {
 	le.updateLendingTradeTrie(db)
 	le.data.LendingTradeRoot = le.lendingTradeTrie.Hash()
@@ -467,6 +579,21 @@ func (le *lendingExchangeState) CommitLendingTradeTrie(db Database) error {
 	return err
 }
 
+func (le *lendingExchangeState) CommitLiquidationAuctionTrie(db Database) error {
+	if le.auctionTrie == nil && len(le.liquidationAuctionStatesDirty) == 0 {
+		return nil
+	}
+	le.updateLiquidationAuctionTrie(db)
+	if le.dbErr != nil {
+		return le.dbErr
+	}
+	root, err := le.auctionTrie.Commit(nil)
+	if err == nil {
+		le.data.LiquidationAuctionRoot = auctionRoot(root)
+	}
+	return err
+}
+
 func (le *lendingExchangeState) CommitInvestingTrie(db Database) error {
 	le.updateInvestingTrie(db)
 	if le.dbErr != nil {
@@ -654,6 +781,15 @@ func (le *lendingExchangeState) deepCopy(db *LendingStateDB, onDirty func(hash c
 	for time := range le.liquidationTimestatesDirty {
 		stateExchanges.liquidationTimestatesDirty[time] = struct{}{}
 	}
+	if le.auctionTrie != nil {
+		stateExchanges.auctionTrie = db.db.CopyTrie(le.auctionTrie)
+	}
+	for key, value := range le.liquidationAuctionStates {
+		stateExchanges.liquidationAuctionStates[key] = value.deepCopy(stateExchanges.MarkLiquidationAuctionDirty)
+	}
+	for tradeId := range le.liquidationAuctionStatesDirty {
+		stateExchanges.liquidationAuctionStatesDirty[tradeId] = struct{}{}
+	}
 	return stateExchanges
 }
 
@@ -670,6 +806,14 @@ func (le *lendingExchangeState) setNonce(nonce uint64) {
 	}
 }
 
+func (le *lendingExchangeState) setAuctionLendingBooks(lendingBooks []common.Hash) {
+	le.data.AuctionLendingBooks = lendingBooks
+	if le.onDirty != nil {
+		le.onDirty(le.Hash())
+		le.onDirty = nil
+	}
+}
+
 func (le *lendingExchangeState) Nonce() uint64 {
 	return le.data.Nonce
 }
@@ -742,6 +886,14 @@ func (le *lendingExchangeState) MarkLendingTradeDirty(tradeId common.Hash) {
 	}
 }
 
+func (le *lendingExchangeState) MarkLiquidationAuctionDirty(tradeId common.Hash) {
+	le.liquidationAuctionStatesDirty[tradeId] = struct{}{}
+	if le.onDirty != nil {
+		le.onDirty(le.Hash())
+		le.onDirty = nil
+	}
+}
+
 func (le *lendingExchangeState) MarkLiquidationTimeDirty(orderId common.Hash) {
 	le.liquidationTimestatesDirty[orderId] = struct{}{}
 	if le.onDirty != nil {
@@ -804,3 +956,13 @@ func (le *lendingExchangeState) insertLendingTrade(tradeId common.Hash, order Le
 	}
 	return newobj
 }
+
+func (le *lendingExchangeState) setLiquidationAuction(db Database, tradeId common.Hash, auction LiquidationAuction) {
+	if obj := le.getLiquidationAuction(db, tradeId); obj != nil {
+		obj.setData(auction)
+		return
+	}
+	newobj := newLiquidationAuctionState(le.lendingBook, tradeId, auction, le.MarkLiquidationAuctionDirty)
+	le.liquidationAuctionStates[tradeId] = newobj
+	le.MarkLiquidationAuctionDirty(tradeId)
+}
@@ -0,0 +1,61 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package lendingstate
+
+import (
+	"io"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+)
+
+type liquidationAuctionState struct {
+	lendingBook common.Hash
+	tradeId     common.Hash
+	data        LiquidationAuction
+	onDirty     func(tradeId common.Hash) // Callback method to mark a state object newly dirty
+}
+
+func (la *liquidationAuctionState) empty() bool {
+	return la.data.Collateral == nil || la.data.Collateral.Sign() == 0
+}
+
+func newLiquidationAuctionState(lendingBook common.Hash, tradeId common.Hash, data LiquidationAuction, onDirty func(tradeId common.Hash)) *liquidationAuctionState {
+	return &liquidationAuctionState{
+		lendingBook: lendingBook,
+		tradeId:     tradeId,
+		data:        data,
+		onDirty:     onDirty,
+	}
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (la *liquidationAuctionState) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, la.data)
+}
+
+func (la *liquidationAuctionState) deepCopy(onDirty func(tradeId common.Hash)) *liquidationAuctionState {
+	return newLiquidationAuctionState(la.lendingBook, la.tradeId, la.data, onDirty)
+}
+
+func (la *liquidationAuctionState) setData(data LiquidationAuction) {
+	la.data = data
+	// auctions are updated on every block they run, keep the callback armed
+	if la.onDirty != nil {
+		la.onDirty(la.tradeId)
+	}
+}
@@ -20,6 +20,7 @@ package lendingstate
 import (
 	"fmt"
 	"math/big"
+	"slices"
 	"sort"
 	"sync"
 
@@ -402,6 +403,11 @@ func (ls *LendingStateDB) GetBestLendingIdAndAmount(orderBook common.Hash, price
 // updateLendingExchange writes the given object to the trie.
 func (ls *LendingStateDB) updateLendingExchange(stateObject *lendingExchangeState) {
 	addr := stateObject.Hash()
+	if addr == AuctionLendingBooksHash && len(stateObject.data.AuctionLendingBooks) == 0 {
+		// no auction is running, the index leaves no trace in the state
+		ls.setError(ls.trie.TryDelete(addr[:]))
+		return
+	}
 	data, err := rlp.EncodeToBytes(stateObject)
 	if err != nil {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
@@ -535,6 +541,7 @@ func (ls *LendingStateDB) Finalise() {
 			stateObject.updateOrderRoot(ls.db)
 			stateObject.updateLendingTradeRoot(ls.db)
 			stateObject.updateLiquidationTimeRoot(ls.db)
+			stateObject.updateLiquidationAuctionRoot(ls.db)
 			// Update the object in the main tradeId trie.
 			ls.updateLendingExchange(stateObject)
 			//delete(s.investingStatesDirty, addr)
@@ -573,6 +580,9 @@ func (ls *LendingStateDB) Commit() (root common.Hash, err error) {
 			if err := stateObject.CommitLiquidationTimeTrie(ls.db); err != nil {
 				return EmptyHash, err
 			}
+			if err := stateObject.CommitLiquidationAuctionTrie(ls.db); err != nil {
+				return EmptyHash, err
+			}
 			// Update the object in the main tradeId trie.
 			ls.updateLendingExchange(stateObject)
 			delete(ls.lendingExchangeStatesDirty, addr)
@@ -599,6 +609,9 @@ func (ls *LendingStateDB) Commit() (root common.Hash, err error) {
 		if exchange.LiquidationTimeRoot != EmptyRoot {
 			ls.db.TrieDB().Reference(exchange.LiquidationTimeRoot, parent)
 		}
+		if !exchange.LiquidationAuctionRoot.IsZero() {
+			ls.db.TrieDB().Reference(exchange.LiquidationAuctionRoot, parent)
+		}
 		return nil
 	})
 	log.Debug("Lending State Trie cache stats after commit", "root", root.Hex())
@@ -675,3 +688,92 @@ func (ls *LendingStateDB) CancelLendingTrade(orderBook common.Hash, tradeId uint
 	lendingTrade.SetAmount(Zero)
 	return nil
 }
+
+// InsertLiquidationAuction stores auction under its trade id, replacing the
+// auction already running for that trade if any. The lending book is indexed
+// among the books running auctions.
+func (ls *LendingStateDB) InsertLiquidationAuction(lendingBook common.Hash, auction LiquidationAuction) {
+	tradeIdHash := common.Uint64ToHash(auction.TradeId)
+	stateExchange := ls.getLendingExchange(lendingBook)
+	if stateExchange == nil {
+		stateExchange = ls.createLendingExchangeObject(lendingBook)
+	}
+	prev := ls.GetLiquidationAuction(lendingBook, auction.TradeId)
+	ls.journal = append(ls.journal, liquidationAuctionChange{
+		lendingBook: lendingBook,
+		tradeId:     auction.TradeId,
+		prev:        prev,
+	})
+	stateExchange.setLiquidationAuction(ls.db, tradeIdHash, auction)
+	if auction.Collateral != nil && auction.Collateral.Sign() > 0 {
+		ls.addAuctionLendingBook(lendingBook)
+	}
+}
+
+func (ls *LendingStateDB) GetLiquidationAuction(lendingBook common.Hash, tradeId uint64) LiquidationAuction {
+	stateExchange := ls.getLendingExchange(lendingBook)
+	if stateExchange == nil {
+		return EmptyLiquidationAuction
+	}
+	stateAuction := stateExchange.getLiquidationAuction(ls.db, common.Uint64ToHash(tradeId))
+	if stateAuction == nil || stateAuction.empty() {
+		return EmptyLiquidationAuction
+	}
+	return stateAuction.data
+}
+
+// GetLiquidationAuctions returns the auctions running in a lending book, ordered by trade id.
+func (ls *LendingStateDB) GetLiquidationAuctions(lendingBook common.Hash) []LiquidationAuction {
+	stateExchange := ls.getLendingExchange(lendingBook)
+	if stateExchange == nil {
+		return []LiquidationAuction{}
+	}
+	auctions := stateExchange.getAllLiquidationAuctions(ls.db)
+	sort.Slice(auctions, func(i, j int) bool {
+		return auctions[i].TradeId < auctions[j].TradeId
+	})
+	return auctions
+}
+
+func (ls *LendingStateDB) RemoveLiquidationAuction(lendingBook common.Hash, tradeId uint64) error {
+	auction := ls.GetLiquidationAuction(lendingBook, tradeId)
+	if auction.TradeId != tradeId || auction.Collateral.Sign() == 0 {
+		return fmt.Errorf("not found liquidation auction: lending book: %s , trade id: %d", lendingBook.Hex(), tradeId)
+	}
+	auction.Collateral = big.NewInt(0)
+	ls.InsertLiquidationAuction(lendingBook, auction)
+	if stateExchange := ls.getLendingExchange(lendingBook); stateExchange == nil || len(stateExchange.getAllLiquidationAuctions(ls.db)) == 0 {
+		ls.removeAuctionLendingBook(lendingBook)
+	}
+	return nil
+}
+
+// GetAuctionLendingBooks returns the lending books running liquidation auctions,
+// in ascending order.
+func (ls *LendingStateDB) GetAuctionLendingBooks() []common.Hash {
+	stateObject := ls.getLendingExchange(AuctionLendingBooksHash)
+	if stateObject != nil {
+		return slices.Clone(stateObject.data.AuctionLendingBooks)
+	}
+	return nil
+}
+
+func (ls *LendingStateDB) addAuctionLendingBook(lendingBook common.Hash) {
+	prev := ls.GetAuctionLendingBooks()
+	i, found := slices.BinarySearchFunc(prev, lendingBook, func(a, b common.Hash) int { return a.Cmp(b) })
+	if found {
+		return
+	}
+	ls.journal = append(ls.journal, auctionLendingBooksChange{prev: prev})
+	ls.GetOrNewLendingExchangeObject(AuctionLendingBooksHash).setAuctionLendingBooks(slices.Insert(slices.Clone(prev), i, lendingBook))
+}
+
+func (ls *LendingStateDB) removeAuctionLendingBook(lendingBook common.Hash) {
+	prev := ls.GetAuctionLendingBooks()
+	i, found := slices.BinarySearchFunc(prev, lendingBook, func(a, b common.Hash) int { return a.Cmp(b) })
+	if !found {
+		return
+	}
+	ls.journal = append(ls.journal, auctionLendingBooksChange{prev: prev})
+	ls.GetOrNewLendingExchangeObject(AuctionLendingBooksHash).setAuctionLendingBooks(slices.Delete(slices.Clone(prev), i, i+1))
+}
@@ -262,3 +262,155 @@ func TestDumpStates(t *testing.T) {
 	db.Close()
 }
 */
+
+func TestVerifyState(t *testing.T) {
+	lendingBook := GetLendingOrderBookHash(common.HexToAddress("0x0000000000000000000000000000000000000022"), 86400)
+	user := common.HexToAddress("0x0000000000000000000000000000000000000011")
+	signature := &Signature{V: 1, R: common.HexToHash("111111"), S: common.HexToHash("222222222222")}
+
+	db := rawdb.NewMemoryDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(types.EmptyRootHash, stateCache)
+	statedb.SetNonce(user.Hash(), 3)
+	statedb.SetNonce(lendingBook, 4)
+	for i := 1; i <= 2; i++ {
+		statedb.InsertLendingItem(lendingBook, common.BigToHash(big.NewInt(int64(i))), LendingItem{LendingId: uint64(i), Quantity: big.NewInt(int64(i)), Interest: big.NewInt(int64(i)), Side: Investing, Signature: signature})
+	}
+	statedb.InsertLendingItem(lendingBook, common.BigToHash(big.NewInt(3)), LendingItem{LendingId: 3, Quantity: big.NewInt(1), Interest: big.NewInt(1), Side: Borrowing, Signature: signature})
+	statedb.InsertTradingItem(lendingBook, 1, LendingTrade{TradeId: 1, Amount: big.NewInt(2)})
+	statedb.InsertLiquidationTime(lendingBook, big.NewInt(100), 1)
+	root := statedb.IntermediateRoot()
+	if _, err := statedb.Commit(); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie database: %v", err)
+	}
+
+	statedb, err := New(root, stateCache)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	dump, err := statedb.Dump()
+	if err != nil {
+		t.Fatalf("failed to dump state: %v", err)
+	}
+	if len(dump.Nonces) != 1 || dump.Nonces[user] != 3 {
+		t.Errorf("nonces mismatch: have %v, want %v: 3", dump.Nonces, user)
+	}
+	book := dump.LendingBooks[lendingBook]
+	if len(dump.LendingBooks) != 1 || book == nil {
+		t.Fatalf("lending books mismatch: have %v, want %x", dump.LendingBooks, lendingBook)
+	}
+	if book.Info.Nonce != 4 {
+		t.Errorf("lending book nonce mismatch: have %d, want 4", book.Info.Nonce)
+	}
+	if len(book.Investings) != 2 || len(book.Borrowings) != 1 || len(book.LiquidationTimes) != 1 || len(book.LendingItems) != 3 || len(book.LendingTrades) != 1 {
+		t.Errorf("lending book trees mismatch: have %d investings, %d borrowings, %d liquidation times, %d items, %d trades, want 2, 1, 1, 3, 1",
+			len(book.Investings), len(book.Borrowings), len(book.LiquidationTimes), len(book.LendingItems), len(book.LendingTrades))
+	}
+
+	stats, err := VerifyState(stateCache, root)
+	if err != nil {
+		t.Fatalf("failed to verify state: %v", err)
+	}
+	// main trie, items, trades, the investing, borrowing and liquidation time
+	// trees with one item list per rate or time
+	if want := 1 + 1 + 1 + (1 + 2) + (1 + 1) + (1 + 1); stats.Tries != want {
+		t.Errorf("verified tries mismatch: have %d, want %d", stats.Tries, want)
+	}
+
+	// Dropping any node below the root must be reported.
+	it := db.NewIterator(nil, nil)
+	for it.Next() {
+		if len(it.Key()) == common.HashLength && common.BytesToHash(it.Key()) != root {
+			db.Delete(it.Key())
+			break
+		}
+	}
+	it.Release()
+	if _, err := VerifyState(NewDatabase(db), root); err == nil {
+		t.Fatal("verified a state with a missing node")
+	}
+}
+
+func TestLiquidationAuctionStates(t *testing.T) {
+	lendingBook := GetLendingOrderBookHash(common.HexToAddress("0x0000000000000000000000000000000000000022"), 86400)
+	newAuction := func(tradeId uint64) LiquidationAuction {
+		trade := &LendingTrade{TradeId: tradeId, Hash: common.BigToHash(new(big.Int).SetUint64(tradeId)), CollateralLockedAmount: big.NewInt(100), LiquidationPrice: big.NewInt(70)}
+		return NewLiquidationAuction(trade, big.NewInt(60), big.NewInt(100), 1000, LiquidatedByPrice)
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(types.EmptyRootHash, stateCache)
+	statedb.InsertTradingItem(lendingBook, 1, LendingTrade{TradeId: 1, Amount: big.NewInt(2)})
+	noAuctionRoot := statedb.IntermediateRoot()
+
+	// Auctions are listed by trade id and reverted with the rest of the state
+	statedb.InsertLiquidationAuction(lendingBook, newAuction(3))
+	snap := statedb.Snapshot()
+	statedb.InsertLiquidationAuction(lendingBook, newAuction(2))
+	if auctions := statedb.GetLiquidationAuctions(lendingBook); len(auctions) != 2 || auctions[0].TradeId != 2 || auctions[1].TradeId != 3 {
+		t.Fatalf("auctions mismatch: have %v, want trades 2 and 3", auctions)
+	}
+	statedb.RevertToSnapshot(snap)
+	if auctions := statedb.GetLiquidationAuctions(lendingBook); len(auctions) != 1 || auctions[0].TradeId != 3 {
+		t.Fatalf("auctions after revert mismatch: have %v, want trade 3", auctions)
+	}
+	if books := statedb.GetAuctionLendingBooks(); len(books) != 1 || books[0] != lendingBook {
+		t.Fatalf("auction lending books mismatch: have %v, want %x", books, lendingBook)
+	}
+
+	root := statedb.IntermediateRoot()
+	if root == noAuctionRoot {
+		t.Fatal("running auction does not change the state root")
+	}
+	if _, err := statedb.Commit(); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie database: %v", err)
+	}
+	statedb, err := New(root, stateCache)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	auction := statedb.GetLiquidationAuction(lendingBook, 3)
+	if auction.TradeId != 3 || auction.Collateral.Cmp(big.NewInt(100)) != 0 || auction.StartPrice.Cmp(big.NewInt(110)) != 0 || auction.FloorPrice.Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("stored auction mismatch: have %+v", auction)
+	}
+	if stats, err := VerifyState(stateCache, root); err != nil || stats.Tries != 3 {
+		t.Fatalf("failed to verify state: %v, tries %v", err, stats)
+	}
+	dump, err := statedb.Dump()
+	if err != nil {
+		t.Fatalf("failed to dump state: %v", err)
+	}
+	if book := dump.LendingBooks[lendingBook]; book == nil || len(book.Auctions) != 1 {
+		t.Fatalf("dumped auctions mismatch: have %v", book)
+	}
+	if len(dump.AuctionLendingBooks) != 1 || dump.LendingBooks[AuctionLendingBooksHash] != nil {
+		t.Fatalf("dumped auction lending books mismatch: have %v", dump.AuctionLendingBooks)
+	}
+
+	// Progress is kept, and a settled auction leaves the lending book as it was
+	auction.Collateral = big.NewInt(40)
+	auction.SoldAmount = big.NewInt(60)
+	statedb.InsertLiquidationAuction(lendingBook, auction)
+	if have := statedb.GetLiquidationAuction(lendingBook, 3); have.SoldAmount.Cmp(big.NewInt(60)) != 0 {
+		t.Errorf("sold amount mismatch: have %v, want 60", have.SoldAmount)
+	}
+	if err := statedb.RemoveLiquidationAuction(lendingBook, 3); err != nil {
+		t.Fatalf("failed to remove auction: %v", err)
+	}
+	if err := statedb.RemoveLiquidationAuction(lendingBook, 3); err == nil {
+		t.Error("removed a settled auction twice")
+	}
+	if books := statedb.GetAuctionLendingBooks(); len(books) != 0 {
+		t.Errorf("settled lending book still indexed: %v", books)
+	}
+	if root := statedb.IntermediateRoot(); root != noAuctionRoot {
+		t.Errorf("root after settlement mismatch: have %x, want %x", root, noAuctionRoot)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package lendingstate
+
+import (
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+)
+
+// SyncTrieKind identifies the layout of a trie of the lending state, so that
+// range based state sync can find the tries nested in its leaves.
+type SyncTrieKind uint8
+
+const (
+	SyncLendingBookTrie SyncTrieKind = iota // Root trie, lending book hash -> lending object
+	SyncItemListTrie                        // Investing, borrowing or liquidation time -> item list
+	SyncLeafTrie                            // Lending items, trades and item ids, no nested tries
+)
+
+// SyncTrie is a trie of the lending state referenced from a leaf of its parent.
+type SyncTrie struct {
+	Root common.Hash
+	Kind SyncTrieKind
+}
+
+// SyncChildren decodes a leaf of a lending state trie of the given kind and
+// returns the non-empty tries it references.
+func SyncChildren(kind SyncTrieKind, leaf []byte) ([]SyncTrie, error) {
+	var children []SyncTrie
+	add := func(root common.Hash, kind SyncTrieKind) {
+		if root != EmptyHash && root != EmptyRoot {
+			children = append(children, SyncTrie{Root: root, Kind: kind})
+		}
+	}
+	switch kind {
+	case SyncLendingBookTrie:
+		var data lendingObject
+		if err := rlp.DecodeBytes(leaf, &data); err != nil {
+			return nil, err
+		}
+		add(data.InvestingRoot, SyncItemListTrie)
+		add(data.BorrowingRoot, SyncItemListTrie)
+		add(data.LiquidationTimeRoot, SyncItemListTrie)
+		add(data.LendingItemRoot, SyncLeafTrie)
+		add(data.LendingTradeRoot, SyncLeafTrie)
+
+	case SyncItemListTrie:
+		var data itemList
+		if err := rlp.DecodeBytes(leaf, &data); err != nil {
+			return nil, err
+		}
+		add(data.Root, SyncLeafTrie)
+	}
+	return children, nil
+}
@@ -16,6 +16,10 @@ const (
 	TradeStatusOpen       = "OPEN"
 	TradeStatusClosed     = "CLOSED"
 	TradeStatusLiquidated = "LIQUIDATED"
+
+	// TradeStatusLiquidationAuction marks a trade whose collateral is being
+	// sold through a liquidation auction.
+	TradeStatusLiquidationAuction = "LIQUIDATION_AUCTION"
 )
 
 type LendingTrade struct {
@@ -0,0 +1,136 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package lendingstate
+
+import (
+	"fmt"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/ethdb/memorydb"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// VerifyStats counts what VerifyState went through.
+type VerifyStats struct {
+	Tries  int // non empty tries whose root was recomputed
+	Leaves int // leaves re-inserted into the recomputed tries
+}
+
+// VerifyState walks the lending state at root and every trie referenced from
+// it, rebuilding each trie from its leaves and checking that the rebuilt root
+// matches the stored one. It fails on the first missing node, undecodable
+// leaf or root mismatch.
+func VerifyState(db Database, root common.Hash) (*VerifyStats, error) {
+	stats := new(VerifyStats)
+	err := verifyTrie(db, stats, root, func(key, value []byte) error {
+		var data lendingObject
+		if err := rlp.DecodeBytes(value, &data); err != nil {
+			return fmt.Errorf("failed to decode lending object %x: %v", key, err)
+		}
+		return verifyLendingObject(db, stats, common.BytesToHash(key), data)
+	})
+	return stats, err
+}
+
+func verifyLendingObject(db Database, stats *VerifyStats, lendingBook common.Hash, data lendingObject) error {
+	if err := verifyTrie(db, stats, data.InvestingRoot, verifyItemList(db, stats)); err != nil {
+		return fmt.Errorf("lending book %x investings: %v", lendingBook, err)
+	}
+	if err := verifyTrie(db, stats, data.BorrowingRoot, verifyItemList(db, stats)); err != nil {
+		return fmt.Errorf("lending book %x borrowings: %v", lendingBook, err)
+	}
+	if err := verifyTrie(db, stats, data.LiquidationTimeRoot, verifyItemList(db, stats)); err != nil {
+		return fmt.Errorf("lending book %x liquidation times: %v", lendingBook, err)
+	}
+	if err := verifyTrie(db, stats, data.LendingItemRoot, func(key, value []byte) error {
+		var item LendingItem
+		if err := rlp.DecodeBytes(value, &item); err != nil {
+			return fmt.Errorf("failed to decode lending item %x: %v", key, err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("lending book %x lending items: %v", lendingBook, err)
+	}
+	if err := verifyTrie(db, stats, data.LendingTradeRoot, func(key, value []byte) error {
+		var trade LendingTrade
+		if err := rlp.DecodeBytes(value, &trade); err != nil {
+			return fmt.Errorf("failed to decode lending trade %x: %v", key, err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("lending book %x lending trades: %v", lendingBook, err)
+	}
+	if err := verifyTrie(db, stats, data.LiquidationAuctionRoot, func(key, value []byte) error {
+		var auction LiquidationAuction
+		if err := rlp.DecodeBytes(value, &auction); err != nil {
+			return fmt.Errorf("failed to decode liquidation auction %x: %v", key, err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("lending book %x liquidation auctions: %v", lendingBook, err)
+	}
+	return nil
+}
+
+// verifyItemList returns a leaf callback checking the trie of an item list
+// stored as a leaf value.
+func verifyItemList(db Database, stats *VerifyStats) func(key, value []byte) error {
+	return func(key, value []byte) error {
+		var data itemList
+		if err := rlp.DecodeBytes(value, &data); err != nil {
+			return fmt.Errorf("failed to decode item list %x: %v", key, err)
+		}
+		if err := verifyTrie(db, stats, data.Root, nil); err != nil {
+			return fmt.Errorf("item list %x: %v", key, err)
+		}
+		return nil
+	}
+}
+
+// verifyTrie rebuilds the trie at root from its leaves in a scratch database
+// and compares the roots, calling onLeaf for every leaf along the way. Zero
+// and empty roots are not backed by any node and are skipped.
+func verifyTrie(db Database, stats *VerifyStats, root common.Hash, onLeaf func(key, value []byte) error) error {
+	if root == (common.Hash{}) || root == EmptyRoot {
+		return nil
+	}
+	tr, err := db.OpenStorageTrie(common.Hash{}, root)
+	if err != nil {
+		return err
+	}
+	rebuilt := trie.NewEmpty(trie.NewDatabase(memorydb.New()))
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		if err := rebuilt.TryUpdate(it.Key, it.Value); err != nil {
+			return err
+		}
+		stats.Leaves++
+		if onLeaf != nil {
+			if err := onLeaf(it.Key, it.Value); err != nil {
+				return err
+			}
+		}
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+	if hash := rebuilt.Hash(); hash != root {
+		return fmt.Errorf("root mismatch: have %x, want %x", hash, root)
+	}
+	stats.Tries++
+	return nil
+}
@@ -925,6 +925,163 @@ func (l *Lending) LiquidationTrade(lendingStateDB *lendingstate.LendingStateDB,
 	return &lendingTrade, nil
 }
 
+// StartLiquidationAuction liquidates a lending trade by putting its collateral
+// up for auction instead of handing it to the investor. The collateral stays
+// locked until ProcessLiquidationAuctions settles the auction.
+func (l *Lending) StartLiquidationAuction(header *types.Header, lendingStateDB *lendingstate.LendingStateDB, tradingstateDB *tradingstate.TradingStateDB, lendingBook common.Hash, lendingTradeId uint64, collateralPrice *big.Int, reason uint64) (*lendingstate.LendingTrade, error) {
+	lendingTradeIdHash := common.Uint64ToHash(lendingTradeId)
+	lendingTrade := lendingStateDB.GetLendingTrade(lendingBook, lendingTradeIdHash)
+	if lendingTrade.TradeId != lendingTradeId {
+		return nil, fmt.Errorf("Lending Trade Id not found : %d ", lendingTradeId)
+	}
+	debt := lendingstate.CalculateTotalRepayValue(header.Time, lendingTrade.LiquidationTime, lendingTrade.Term, lendingTrade.Interest, lendingTrade.Amount)
+	auction := lendingstate.NewLiquidationAuction(&lendingTrade, debt, collateralPrice, header.Number.Uint64(), reason)
+
+	err := lendingStateDB.RemoveLiquidationTime(lendingBook, lendingTradeId, lendingTrade.LiquidationTime)
+	if err != nil {
+		log.Debug("StartLiquidationAuction RemoveLiquidationTime", "err", err)
+		return nil, err
+	}
+	err = tradingstateDB.RemoveLiquidationPrice(tradingstate.GetTradingOrderBookHash(lendingTrade.CollateralToken, lendingTrade.LendingToken), lendingTrade.LiquidationPrice, lendingBook, lendingTradeId)
+	if err != nil {
+		log.Debug("StartLiquidationAuction RemoveLiquidationPrice", "err", err)
+		return nil, err
+	}
+	err = lendingStateDB.CancelLendingTrade(lendingBook, lendingTradeId)
+	if err != nil {
+		log.Debug("StartLiquidationAuction CancelLendingTrade", "err", err)
+		return nil, err
+	}
+	lendingStateDB.InsertLiquidationAuction(lendingBook, auction)
+	log.Debug("StartLiquidationAuction", "lendingBook", lendingBook.Hex(), "tradeId", lendingTradeId, "collateral", auction.Collateral, "debt", debt, "startPrice", auction.StartPrice, "floorPrice", auction.FloorPrice)
+
+	// update liquidationData mongodb
+	liquidationData := lendingstate.LiquidationData{
+		RecallAmount:      common.Big0,
+		LiquidationAmount: lendingTrade.CollateralLockedAmount,
+		CollateralPrice:   collateralPrice,
+		Reason:            reason,
+	}
+	extraData, _ := json.Marshal(liquidationData)
+	lendingTrade.ExtraData = string(extraData)
+	lendingTrade.Status = lendingstate.TradeStatusLiquidationAuction
+	return &lendingTrade, nil
+}
+
+// fillLiquidationAuction sells the collateral of an auction to the bids of the
+// collateral/lending order book priced at or above the current auction price,
+// best price first, until the debt is covered or the collateral runs out.
+// Makers pay at their own price and no relayer fee is charged. The proceeds are
+// held by the lending lock address until the auction settles. Makers that
+// cannot pay for their fill are cancelled, as the matching engine does.
+func fillLiquidationAuction(statedb *state.StateDB, tradingstateDB *tradingstate.TradingStateDB, auction *lendingstate.LiquidationAuction, price *big.Int, collateralTokenDecimal *big.Int) ([]*lendingstate.AuctionFill, error) {
+	fills := []*lendingstate.AuctionFill{}
+	orderBook := tradingstate.GetTradingOrderBookHash(auction.CollateralToken, auction.LendingToken)
+	for auction.Collateral.Sign() > 0 && !auction.Covered() {
+		bestPrice, _ := tradingstateDB.GetBestBidPrice(orderBook)
+		if bestPrice.Sign() == 0 || bestPrice.Cmp(price) < 0 {
+			return fills, nil
+		}
+		orderId, amount, err := tradingstateDB.GetBestOrderIdAndAmount(orderBook, bestPrice, tradingstate.Bid)
+		if err != nil {
+			return fills, err
+		}
+		order := tradingstateDB.GetOrder(orderBook, orderId)
+		if amount.Sign() == 0 || order.Quantity == nil {
+			return fills, nil
+		}
+		// only sell the collateral needed to repay the remaining debt, rounded up
+		needed := new(big.Int).Mul(new(big.Int).Sub(auction.Debt, auction.Proceeds), collateralTokenDecimal)
+		needed = new(big.Int).Add(needed, new(big.Int).Sub(bestPrice, common.Big1))
+		needed = new(big.Int).Div(needed, bestPrice)
+		quantity := tradingstate.CloneBigInt(amount)
+		for _, limit := range []*big.Int{auction.Collateral, needed} {
+			if quantity.Cmp(limit) > 0 {
+				quantity = tradingstate.CloneBigInt(limit)
+			}
+		}
+		cost := new(big.Int).Mul(quantity, bestPrice)
+		cost = new(big.Int).Div(cost, collateralTokenDecimal)
+		if cost.Sign() == 0 {
+			// the remaining collateral is worth nothing at this price
+			return fills, nil
+		}
+		if lendingstate.GetTokenBalance(order.UserAddress, auction.LendingToken, statedb).Cmp(cost) < 0 {
+			log.Debug("fillLiquidationAuction: cancel unfunded maker", "orderId", orderId.Hex(), "maker", order.UserAddress.Hex(), "cost", cost)
+			if err := tradingstateDB.CancelOrder(orderBook, &order); err != nil {
+				return fills, err
+			}
+			continue
+		}
+		if err := tradingstateDB.SubAmountOrderItem(orderBook, orderId, bestPrice, quantity, tradingstate.Bid); err != nil {
+			return fills, err
+		}
+		// a fill trades like a market sell, the stop orders it crosses are activated
+		// at the end of the block
+		tradingstateDB.SetLastPrice(orderBook, bestPrice)
+		tradingstateDB.AddPendingStopOrderBook(orderBook)
+		if err := lendingstate.SubTokenBalance(order.UserAddress, cost, auction.LendingToken, statedb); err != nil {
+			return fills, err
+		}
+		if err := lendingstate.AddTokenBalance(common.LendingLockAddressBinary, cost, auction.LendingToken, statedb); err != nil {
+			return fills, err
+		}
+		if err := lendingstate.SubTokenBalance(common.LendingLockAddressBinary, quantity, auction.CollateralToken, statedb); err != nil {
+			return fills, err
+		}
+		if err := lendingstate.AddTokenBalance(order.UserAddress, quantity, auction.CollateralToken, statedb); err != nil {
+			return fills, err
+		}
+		auction.Collateral = new(big.Int).Sub(auction.Collateral, quantity)
+		auction.SoldAmount = new(big.Int).Add(auction.SoldAmount, quantity)
+		auction.Proceeds = new(big.Int).Add(auction.Proceeds, cost)
+		fills = append(fills, &lendingstate.AuctionFill{
+			TradeId:   auction.TradeId,
+			Maker:     order.UserAddress,
+			OrderHash: order.Hash,
+			Price:     bestPrice,
+			Quantity:  quantity,
+			Cost:      cost,
+		})
+		log.Debug("fillLiquidationAuction", "tradeId", auction.TradeId, "maker", order.UserAddress.Hex(), "price", bestPrice, "quantity", quantity, "cost", cost)
+	}
+	return fills, nil
+}
+
+// settleLiquidationAuction pays the proceeds of an auction out of the lending
+// lock address: the investor is repaid up to the debt and the borrower gets
+// the surplus. Unsold collateral goes back to the borrower when the debt is
+// covered and to the investor otherwise. It returns the surplus.
+func settleLiquidationAuction(statedb *state.StateDB, auction *lendingstate.LiquidationAuction) (*big.Int, error) {
+	repaid := auction.Proceeds
+	if repaid.Cmp(auction.Debt) > 0 {
+		repaid = auction.Debt
+	}
+	surplus := new(big.Int).Sub(auction.Proceeds, repaid)
+	if err := lendingstate.SubTokenBalance(common.LendingLockAddressBinary, auction.Proceeds, auction.LendingToken, statedb); err != nil {
+		return nil, err
+	}
+	if err := lendingstate.AddTokenBalance(auction.Investor, repaid, auction.LendingToken, statedb); err != nil {
+		return nil, err
+	}
+	if err := lendingstate.AddTokenBalance(auction.Borrower, surplus, auction.LendingToken, statedb); err != nil {
+		return nil, err
+	}
+	if auction.Collateral.Sign() > 0 {
+		receiver := auction.Investor
+		if auction.Covered() {
+			receiver = auction.Borrower
+		}
+		if err := lendingstate.SubTokenBalance(common.LendingLockAddressBinary, auction.Collateral, auction.CollateralToken, statedb); err != nil {
+			return nil, err
+		}
+		if err := lendingstate.AddTokenBalance(receiver, auction.Collateral, auction.CollateralToken, statedb); err != nil {
+			return nil, err
+		}
+	}
+	return surplus, nil
+}
+
 // cancellation fee = 1/10 borrowing fee
 // deprecated after hardfork at TIPXDCXCancellationFee
 func getCancelFeeV1(collateralTokenDecimal *big.Int, collateralPrice, borrowFee *big.Int, order *lendingstate.LendingItem) *big.Int {
@@ -1168,6 +1325,14 @@ func (l *Lending) ProcessRepayLendingTrade(header *types.Header, chain consensus
 		if lendingTrade.LiquidationTime > time {
 			return nil, fmt.Errorf("not enough balance need : %s , have : %s", paymentBalance, tokenBalance)
 		}
+		if chain.Config().IsTIPXDCXLendingAuction(header.Number) {
+			_, collateralPrice, err := l.GetCollateralPrices(header, chain, statedb, tradingstateDB, lendingTrade.CollateralToken, lendingTrade.LendingToken)
+			if err == nil && collateralPrice != nil && collateralPrice.Sign() > 0 {
+				return l.StartLiquidationAuction(header, lendingStateDB, tradingstateDB, lendingBook, lendingTradeId, collateralPrice, lendingstate.LiquidatedByTime)
+			}
+			// without a price the collateral cannot be auctioned, seize it as before
+			log.Error("ProcessRepayLendingTrade: cannot get collateralPrice for liquidation auction", "err", err)
+		}
 		newLendingTrade := &lendingstate.LendingTrade{}
 		var err error
 		if chain.Config().IsTIPXDCXLending(header.Number) {
@@ -10,6 +10,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/XDCxlending/lendingstate"
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/node"
 )
@@ -547,3 +548,132 @@ func TestGetLendQuantity(t *testing.T) {
 		})
 	}
 }
+
+func TestLiquidationAuction(t *testing.T) {
+	var (
+		investor        = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		borrower        = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		collateralToken = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		orderBook       = tradingstate.GetTradingOrderBookHash(collateralToken, common.XDCNativeAddressBinary)
+		amount          = func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), common.BasePrice) }
+		price           = func(percent int64) *big.Int { return new(big.Int).Div(amount(percent), big.NewInt(100)) }
+	)
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	tradingStateDb, _ := tradingstate.New(types.EmptyRootHash, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetNonce(collateralToken, 1)
+	tradingstate.SetTokenBalance(common.LendingLockAddressBinary, amount(100), collateralToken, statedb)
+
+	// bids of 20 tokens at 1, 100 tokens at 0.9 from an unfunded maker and 100 tokens at 0.8
+	bids := []struct {
+		maker    common.Address
+		balance  *big.Int
+		price    *big.Int
+		quantity *big.Int
+	}{
+		{common.HexToAddress("0x21"), amount(100), price(100), amount(20)},
+		{common.HexToAddress("0x22"), common.Big0, price(90), amount(100)},
+		{common.HexToAddress("0x23"), amount(100), price(80), amount(100)},
+	}
+	for i, bid := range bids {
+		statedb.SetBalance(bid.maker, bid.balance)
+		tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(int64(i+1))), tradingstate.OrderItem{
+			Quantity:    bid.quantity,
+			Price:       bid.price,
+			UserAddress: bid.maker,
+			BaseToken:   collateralToken,
+			QuoteToken:  common.XDCNativeAddressBinary,
+			Status:      tradingstate.OrderStatusOpen,
+			Side:        tradingstate.Bid,
+			Type:        tradingstate.Limit,
+			Hash:        common.BigToHash(big.NewInt(int64(i + 1))),
+			OrderID:     uint64(i + 1),
+		})
+	}
+
+	// the pair has stop orders waiting for the price to move
+	tradingStateDb.SetStopOrderPrice(orderBook, price(100))
+
+	trade := &lendingstate.LendingTrade{
+		TradeId:                1,
+		Investor:               investor,
+		Borrower:               borrower,
+		LendingToken:           common.XDCNativeAddressBinary,
+		CollateralToken:        collateralToken,
+		CollateralLockedAmount: amount(100),
+		LiquidationPrice:       price(70),
+	}
+	auction := lendingstate.NewLiquidationAuction(trade, amount(60), common.BasePrice, 1000, lendingstate.LiquidatedByPrice)
+	if auction.EndBlock != 1000+common.LiquidationAuctionBlocks {
+		t.Fatalf("auction ends at %d, want %d", auction.EndBlock, 1000+common.LiquidationAuctionBlocks)
+	}
+	steps := []struct {
+		number   uint64
+		fills    int
+		sold     *big.Int
+		proceeds *big.Int
+	}{
+		{1000, 0, common.Big0, common.Big0}, // opens at 1.1, above every bid
+		{1050, 1, amount(20), amount(20)},   // 1.0 takes the first bid
+		{1100, 0, amount(20), amount(20)},   // 0.9 cancels the unfunded maker
+		{1150, 1, amount(70), amount(60)},   // 0.8 sells only what repays the debt
+		{1200, 0, amount(70), amount(60)},   // covered, nothing left to do
+	}
+	for _, step := range steps {
+		fills, err := fillLiquidationAuction(statedb, tradingStateDb, &auction, auction.Price(step.number), common.BasePrice)
+		if err != nil {
+			t.Fatalf("block %d: fillLiquidationAuction() error = %v", step.number, err)
+		}
+		if len(fills) != step.fills || auction.SoldAmount.Cmp(step.sold) != 0 || auction.Proceeds.Cmp(step.proceeds) != 0 {
+			t.Fatalf("block %d: fills = %d, sold = %v, proceeds = %v, want %d, %v, %v", step.number, len(fills), auction.SoldAmount, auction.Proceeds, step.fills, step.sold, step.proceeds)
+		}
+	}
+	if order := tradingStateDb.GetOrder(orderBook, common.BigToHash(big.NewInt(2))); order.Quantity.Sign() != 0 {
+		t.Errorf("unfunded bid left with %v", order.Quantity)
+	}
+	if _, volume := tradingStateDb.GetBestBidPrice(orderBook); volume.Cmp(amount(50)) != 0 {
+		t.Errorf("remaining bid volume = %v, want %v", volume, amount(50))
+	}
+	if last := tradingStateDb.GetLastPrice(orderBook); last.Cmp(price(80)) != 0 {
+		t.Errorf("last price = %v, want %v", last, price(80))
+	}
+	if pending := tradingStateDb.GetPendingStopOrderBooks(); len(pending) != 1 || pending[0] != orderBook {
+		t.Errorf("pending stop order books = %v, want the auctioned pair", pending)
+	}
+	if !auction.Covered() {
+		t.Fatalf("auction should cover its debt")
+	}
+
+	surplus, err := settleLiquidationAuction(statedb, &auction)
+	if err != nil {
+		t.Fatalf("settleLiquidationAuction() error = %v", err)
+	}
+	if surplus.Sign() != 0 {
+		t.Errorf("surplus = %v, want 0", surplus)
+	}
+	if balance := statedb.GetBalance(investor); balance.Cmp(amount(60)) != 0 {
+		t.Errorf("investor got %v, want %v", balance, amount(60))
+	}
+	if balance := tradingstate.GetTokenBalance(borrower, collateralToken, statedb); balance.Cmp(amount(30)) != 0 {
+		t.Errorf("borrower got back %v collateral, want %v", balance, amount(30))
+	}
+	if balance := tradingstate.GetTokenBalance(common.LendingLockAddressBinary, collateralToken, statedb); balance.Sign() != 0 {
+		t.Errorf("lending lock address keeps %v collateral", balance)
+	}
+	if balance := tradingstate.GetTokenBalance(bids[2].maker, collateralToken, statedb); balance.Cmp(amount(50)) != 0 {
+		t.Errorf("last maker bought %v collateral, want %v", balance, amount(50))
+	}
+
+	// An auction floored above the remaining bids expires, leaving the investor the unsold collateral
+	tradingstate.SetTokenBalance(common.LendingLockAddressBinary, amount(10), collateralToken, statedb)
+	trade.CollateralLockedAmount = amount(10)
+	expired := lendingstate.NewLiquidationAuction(trade, amount(60), amount(2), 2000, lendingstate.LiquidatedByTime)
+	if fills, _ := fillLiquidationAuction(statedb, tradingStateDb, &expired, expired.Price(expired.EndBlock), common.BasePrice); len(fills) != 0 {
+		t.Fatalf("expired auction filled %d bids below its floor", len(fills))
+	}
+	if _, err := settleLiquidationAuction(statedb, &expired); err != nil {
+		t.Fatalf("settleLiquidationAuction() error = %v", err)
+	}
+	if balance := tradingstate.GetTokenBalance(investor, collateralToken, statedb); balance.Cmp(amount(10)) != 0 {
+		t.Errorf("investor got %v collateral, want %v", balance, amount(10))
+	}
+}
@@ -40,6 +40,13 @@ const (
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
 	MimetypeTextPlain         = "text/plain"
+
+	// XDPoS consensus messages. The data is the RLP encoding whose keccak256
+	// hash the engine signs, so that a signer can apply policy to the content.
+	MimetypeXDPoSV1Header = "application/x-xdpos-v1-header"
+	MimetypeXDPoSHeader   = "application/x-xdpos-header"
+	MimetypeXDPoSVote     = "application/x-xdpos-vote"
+	MimetypeXDPoSTimeout  = "application/x-xdpos-timeout"
 )
 
 // Wallet represents a software or hardware wallet that might contain one or more
@@ -0,0 +1,171 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/accounts"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+)
+
+// testSigner mimics the account namespace of clef, signing with a single key
+// and refusing every message type it has no rule for.
+type testSigner struct {
+	key     *ecdsa.PrivateKey
+	allowed map[string]bool
+}
+
+func (s *testSigner) Version() string {
+	return "6.1.0"
+}
+
+func (s *testSigner) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *testSigner) SignData(contentType string, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if !s.allowed[contentType] {
+		return nil, errors.New("request denied")
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(data), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27 // clef returns signatures in Ethereum-legacy form
+	return sig, nil
+}
+
+func (s *testSigner) SignTransaction(args SendTxArgs) (*signTransactionResult, error) {
+	tx := types.NewTransaction(uint64(args.Nonce), *args.To, args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), *args.Data)
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signTransactionResult{Raw: raw, Tx: signed}, nil
+}
+
+func newTestBackend(t *testing.T, allowed ...string) (*ExternalBackend, *ecdsa.PrivateKey) {
+	key, _ := crypto.GenerateKey()
+	signer := &testSigner{key: key, allowed: make(map[string]bool)}
+	for _, mimeType := range allowed {
+		signer.allowed[mimeType] = true
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+	httpsrv := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpsrv.Close()
+		server.Stop()
+	})
+	backend, err := NewExternalBackend(httpsrv.URL)
+	if err != nil {
+		t.Fatalf("failed to connect to signer: %v", err)
+	}
+	return backend, key
+}
+
+func TestExternalSignerAccounts(t *testing.T) {
+	backend, key := newTestBackend(t)
+	wallets := backend.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	wallet := wallets[0]
+	if wallet.URL().Scheme != Scheme {
+		t.Errorf("wallet scheme mismatch: have %s, want %s", wallet.URL().Scheme, Scheme)
+	}
+	if status, _ := wallet.Status(); status != "ok [version=6.1.0]" {
+		t.Errorf("wallet status mismatch: have %q", status)
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	if !wallet.Contains(accounts.Account{Address: addr}) {
+		t.Errorf("wallet does not contain %x", addr)
+	}
+	if wallet.Contains(accounts.Account{Address: common.HexToAddress("0x01")}) {
+		t.Errorf("wallet contains unknown account")
+	}
+	if _, err := wallet.SignHash(accounts.Account{Address: addr}, make([]byte, 32)); err == nil {
+		t.Errorf("external signer signed a bare hash")
+	}
+}
+
+func TestExternalSignerSignData(t *testing.T) {
+	backend, key := newTestBackend(t, accounts.MimetypeXDPoSVote)
+	wallet := backend.Wallets()[0]
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+
+	data := []byte("vote preimage")
+	sig, err := wallet.SignData(account, accounts.MimetypeXDPoSVote, data)
+	if err != nil {
+		t.Fatalf("failed to sign vote: %v", err)
+	}
+	if sig[64] != 0 && sig[64] != 1 {
+		t.Errorf("recovery id not in 0/1 form: %d", sig[64])
+	}
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(data), sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if addr := crypto.PubkeyToAddress(*pubkey); addr != account.Address {
+		t.Errorf("recovered signer mismatch: have %x, want %x", addr, account.Address)
+	}
+	// The signer's policy has no rule for timeouts, so it must refuse them
+	if _, err := wallet.SignData(account, accounts.MimetypeXDPoSTimeout, data); err == nil {
+		t.Errorf("signer policy bypassed for timeout")
+	}
+}
+
+func TestExternalSignerSignTx(t *testing.T) {
+	backend, key := newTestBackend(t)
+	wallet := backend.Wallets()[0]
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+
+	chainID := big.NewInt(51)
+	tx := types.NewTransaction(3, common.HexToAddress("0x89"), big.NewInt(1), 21000, big.NewInt(250000000), []byte{0x01})
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if signed.Hash() == tx.Hash() {
+		t.Fatalf("transaction left unsigned")
+	}
+	from, err := types.Sender(types.NewEIP155Signer(chainID), signed)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if from != account.Address {
+		t.Errorf("sender mismatch: have %x, want %x", from, account.Address)
+	}
+	if signed.Nonce() != tx.Nonce() || *signed.To() != *tx.To() || signed.Value().Cmp(tx.Value()) != 0 {
+		t.Errorf("signed transaction differs from request")
+	}
+}
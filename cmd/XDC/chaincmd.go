@@ -190,7 +190,7 @@ func initGenesis(ctx *cli.Context) error {
 	defer stack.Close()
 
 	name := "chaindata"
-	chaindb, err := stack.OpenDatabase(name, 0, 0, "", false)
+	chaindb, err := stack.OpenDatabaseWithFreezer(name, 0, 0, ctx.String(utils.AncientFlag.Name), "", false)
 	if err != nil {
 		utils.Fatalf("Failed to open database: %v", err)
 	}
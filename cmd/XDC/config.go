@@ -31,6 +31,7 @@ import (
 
 	"github.com/XinFinOrg/XDPoSChain/XDCx"
 	"github.com/XinFinOrg/XDPoSChain/accounts"
+	"github.com/XinFinOrg/XDPoSChain/accounts/external"
 	"github.com/XinFinOrg/XDPoSChain/accounts/keystore"
 	"github.com/XinFinOrg/XDPoSChain/accounts/scwallet"
 	"github.com/XinFinOrg/XDPoSChain/accounts/usbwallet"
@@ -346,6 +347,15 @@ func setAccountManagerBackends(conf *node.Config, am *accounts.Manager, keydir s
 	// If/when we implement some form of lockfile for USB and keystore wallets,
 	// we can have both, but it's very confusing for the user to see the same
 	// accounts in both externally and locally, plus very racey.
+	if len(conf.ExternalSigner) > 0 {
+		log.Info("Using external signer", "url", conf.ExternalSigner)
+		extBackend, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return fmt.Errorf("error connecting to external signer: %v", err)
+		}
+		am.AddBackend(extBackend)
+		return nil
+	}
 	am.AddBackend(keystore.NewKeyStore(keydir, scryptN, scryptP))
 	if conf.USB {
 		// Start a USB hub for Ledger hardware wallets
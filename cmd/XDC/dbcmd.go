@@ -17,15 +17,20 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/XinFinOrg/XDPoSChain/cmd/utils"
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
 	"github.com/XinFinOrg/XDPoSChain/console"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
 	"github.com/XinFinOrg/XDPoSChain/ethdb"
@@ -54,6 +59,8 @@ Remove blockchain and state databases`,
 			dbGetCmd,
 			dbDeleteCmd,
 			dbPutCmd,
+			dbImportRewardsCmd,
+			dbVerifyForensicsCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -119,6 +126,30 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		Description: `This command sets a given database key to the given value.
 WARNING: This is a low-level operation which may cause database corruption!`,
 	}
+	dbImportRewardsCmd = &cli.Command{
+		Action:    dbImportRewards,
+		Name:      "import-rewards",
+		Usage:     "Import epoch reward files into the database",
+		ArgsUsage: "[<rewards folder>]",
+		Flags: slices.Concat([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command imports the <number>.<hash> reward files written by older
+versions running with --store-reward. The folder defaults to <datadir>/XDC/rewards.
+Rewards already in the database are skipped, the files are left untouched.`,
+	}
+	dbVerifyForensicsCmd = &cli.Command{
+		Action:    dbVerifyForensics,
+		Name:      "verify-forensics",
+		Usage:     "Verify the forensic proofs stored in the database",
+		ArgsUsage: "[<proof id>]",
+		Flags: slices.Concat([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command re-checks the signatures of the stored forensic proofs, or of
+the given one only, against the masternodes of the epoch switch headers of the
+signed blocks. It fails if any proof doesn't hold.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -328,3 +359,121 @@ func dbPut(ctx *cli.Context) error {
 	}
 	return db.Put(key, value)
 }
+
+// dbImportRewards imports the epoch reward files of older versions into the
+// database.
+func dbImportRewards(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("max 1 argument: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	folder := filepath.Join(stack.DataDir(), "XDC", "rewards")
+	if ctx.NArg() == 1 {
+		folder = ctx.Args().Get(0)
+	}
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		return err
+	}
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	var (
+		batch    = db.NewBatch()
+		start    = time.Now()
+		logged   = time.Now()
+		imported int
+		skipped  int
+	)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		prefix, suffix, _ := strings.Cut(file.Name(), ".")
+		number, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			log.Warn("Skipping unknown file in rewards folder", "name", file.Name())
+			skipped++
+			continue
+		}
+		enc, err := hexutil.Decode(suffix)
+		if err != nil || len(enc) != common.HashLength {
+			log.Warn("Skipping unknown file in rewards folder", "name", file.Name())
+			skipped++
+			continue
+		}
+		hash := common.BytesToHash(enc)
+		if rawdb.HasRewards(db, number, hash) {
+			skipped++
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(folder, file.Name()))
+		if err != nil {
+			return err
+		}
+		if err := rawdb.WriteRewards(batch, number, hash, data); err != nil {
+			log.Warn("Skipping invalid reward file", "name", file.Name(), "err", err)
+			skipped++
+			continue
+		}
+		imported++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing rewards", "imported", imported, "skipped", skipped, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Imported rewards", "imported", imported, "skipped", skipped, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func dbVerifyForensics(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("max 1 argument: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, true)
+	defer db.Close()
+
+	engine, ok := chain.Engine().(*XDPoS.XDPoS)
+	if !ok {
+		return errors.New("forensics are only supported by the XDPoS engine")
+	}
+	var (
+		id       = ctx.Args().First()
+		verified int
+		failed   int
+	)
+	for _, report := range rawdb.ReadForensicsReports(db, 0, math.MaxUint64, 0, 0) {
+		if id != "" && report.Proof.Id != id {
+			continue
+		}
+		if err := engine.EngineV2.VerifyForensicsReport(chain, report); err != nil {
+			log.Error("Invalid forensic proof", "round", report.Round, "id", report.Proof.Id, "type", report.Proof.ForensicsType, "err", err)
+			failed++
+			continue
+		}
+		log.Info("Verified forensic proof", "round", report.Round, "id", report.Proof.Id, "type", report.Proof.ForensicsType, "signers", report.Signers)
+		verified++
+	}
+	if id != "" && verified+failed == 0 {
+		return fmt.Errorf("forensic proof %s not found", id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d forensic proofs failed verification", failed, verified+failed)
+	}
+	log.Info("Verified forensic proofs", "count", verified)
+	return nil
+}
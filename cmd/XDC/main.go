@@ -36,6 +36,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/internal/debug"
 	"github.com/XinFinOrg/XDPoSChain/internal/ethapi"
 	"github.com/XinFinOrg/XDPoSChain/internal/flags"
+	"github.com/XinFinOrg/XDPoSChain/light"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/metrics"
 	"github.com/XinFinOrg/XDPoSChain/node"
@@ -71,6 +72,7 @@ var (
 		utils.NoUSBFlag, // deprecated
 		utils.USBFlag,
 		utils.SmartCardDaemonPathFlag,
+		utils.ExternalSignerFlag,
 		utils.EnablePersonal,
 		utils.XDCXEnabledFlag,
 		utils.XDCXDBEngineFlag,
@@ -87,8 +89,22 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolSpecialAccountSlotsFlag,
+		utils.TxPoolSpecialSlotsFlag,
+		utils.OrderPoolAccountSlotsFlag,
+		utils.OrderPoolGlobalSlotsFlag,
+		utils.OrderPoolAccountQueueFlag,
+		utils.OrderPoolGlobalQueueFlag,
+		utils.OrderPoolLifetimeFlag,
+		utils.LendingPoolAccountSlotsFlag,
+		utils.LendingPoolGlobalSlotsFlag,
+		utils.LendingPoolAccountQueueFlag,
+		utils.LendingPoolGlobalQueueFlag,
+		utils.LendingPoolLifetimeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.LightServerFlag,
+		utils.LightCheckpointFlag,
 		// utils.LightServFlag,  // deprecated
 		// utils.LightPeersFlag, // deprecated
 		//utils.LightKDFFlag,
@@ -96,6 +112,8 @@ var (
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
 		utils.CacheGCFlag,
+		utils.CacheSnapshotFlag,
+		utils.SnapshotFlag,
 		utils.CachePrefetchFlag,
 		//utils.TrieCacheGenFlag,
 		utils.CachePreimagesFlag,
@@ -147,6 +165,7 @@ var (
 	rpcFlags = []cli.Flag{
 		utils.HTTPEnabledFlag,
 		utils.RPCGlobalGasCapFlag,
+		utils.RPCGlobalEVMTimeoutFlag,
 		utils.HTTPListenAddrFlag,
 		utils.HTTPPortFlag,
 		utils.HTTPReadTimeoutFlag,
@@ -214,6 +233,10 @@ func init() {
 		dumpConfigCommand,
 		// see dbcmd.go
 		dbCommand,
+		// See validatorcmd.go
+		validatorCommand,
+		// See xdcxcmd.go
+		xdcxCommand,
 		// See cmd/utils/flags_legacy.go
 		utils.ShowDeprecated,
 	}
@@ -266,34 +289,42 @@ func XDC(ctx *cli.Context) error {
 	return nil
 }
 
-// startNode boots up the system node and all registered protocols, after which
-// it unlocks any requested accounts, and starts the RPC/IPC interfaces and the
-// miner.
-func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, cfg XDCConfig) {
-	// Start up the node itself
-	utils.StartNode(stack)
-
-	// Unlock any account specifically requested
-	backends := stack.AccountManager().Backends(keystore.KeyStoreType)
-	if len(backends) == 0 {
-		log.Warn("Failed to unlock accounts, keystore is not available")
-		return
-	}
-	ks := backends[0].(*keystore.KeyStore)
-
+// unlockAccounts unlocks any keystore account requested on the command line or
+// in the config file. Nothing is unlocked when an external signer is in use.
+func unlockAccounts(ctx *cli.Context, stack *node.Node, cfg *XDCConfig) {
 	if ctx.IsSet(utils.UnlockedAccountFlag.Name) {
 		cfg.Account.Unlocks = strings.Split(ctx.String(utils.UnlockedAccountFlag.Name), ",")
 	}
-
 	if ctx.IsSet(utils.PasswordFileFlag.Name) {
 		cfg.Account.Passwords = utils.MakePasswordList(ctx)
 	}
+	if len(cfg.Account.Unlocks) == 0 {
+		return
+	}
+	backends := stack.AccountManager().Backends(keystore.KeyStoreType)
+	if len(backends) == 0 {
+		log.Warn("Failed to unlock accounts, keystore is not available")
+		return
+	}
+	ks := backends[0].(*keystore.KeyStore)
 
 	for i, account := range cfg.Account.Unlocks {
 		if trimmed := strings.TrimSpace(account); trimmed != "" {
 			unlockAccount(ctx, ks, trimmed, i, cfg.Account.Passwords)
 		}
 	}
+}
+
+// startNode boots up the system node and all registered protocols, after which
+// it unlocks any requested accounts, and starts the RPC/IPC interfaces and the
+// miner.
+func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, cfg XDCConfig) {
+	// Start up the node itself
+	utils.StartNode(stack)
+
+	// Unlock any account specifically requested
+	unlockAccounts(ctx, stack, &cfg)
+
 	// Register wallet event handlers to open and auto-derive wallets
 	events := make(chan accounts.WalletEvent, 16)
 	stack.AccountManager().Subscribe(events)
@@ -334,6 +365,10 @@ func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, cfg X
 			}
 		}
 	}()
+	// Light clients follow a full node and run no auxiliary services
+	if _, ok := backend.(*light.LightAPIBackend); ok {
+		return
+	}
 	// Start auxiliary services if enabled
 
 	ethBackend, ok := backend.(*eth.EthAPIBackend)
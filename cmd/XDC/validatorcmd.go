@@ -0,0 +1,530 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/XinFinOrg/XDPoSChain/accounts/abi/bind"
+	"github.com/XinFinOrg/XDPoSChain/accounts/keystore"
+	"github.com/XinFinOrg/XDPoSChain/cmd/utils"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/contracts/validator/contract"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/ethclient"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	validatorEndpointFlag = &cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "RPC endpoint of the node to query and send transactions to",
+		Value: "http://127.0.0.1:8545",
+	}
+	validatorContractFlag = &cli.StringFlag{
+		Name:  "contract",
+		Usage: "Address of the validator contract",
+		Value: common.MasternodeVotingSMC,
+	}
+	validatorFromFlag = &cli.StringFlag{
+		Name:  "from",
+		Usage: "Keystore account (address or index) signing the transaction",
+	}
+	validatorOfflineFlag = &cli.BoolFlag{
+		Name:  "offline",
+		Usage: "Sign the transaction without a node and print it instead of sending it (requires --nonce, --gas, --gasprice and --chainid)",
+	}
+	validatorNonceFlag = &cli.Uint64Flag{
+		Name:  "nonce",
+		Usage: "Nonce of the transaction (default: pending nonce of the account)",
+	}
+	validatorGasFlag = &cli.Uint64Flag{
+		Name:  "gas",
+		Usage: "Gas limit of the transaction (default: estimated)",
+	}
+	validatorGasPriceFlag = &cli.StringFlag{
+		Name:  "gasprice",
+		Usage: "Gas price of the transaction in wei (default: suggested by the node)",
+	}
+	validatorChainIdFlag = &cli.Uint64Flag{
+		Name:  "chainid",
+		Usage: "Chain id the transaction is signed for (default: chain id of the node)",
+	}
+
+	validatorQueryFlags = []cli.Flag{
+		validatorEndpointFlag,
+		validatorContractFlag,
+	}
+	validatorTxFlags = []cli.Flag{
+		utils.DataDirFlag,
+		utils.KeyStoreDirFlag,
+		utils.PasswordFileFlag,
+		validatorEndpointFlag,
+		validatorContractFlag,
+		validatorFromFlag,
+		validatorOfflineFlag,
+		validatorNonceFlag,
+		validatorGasFlag,
+		validatorGasPriceFlag,
+		validatorChainIdFlag,
+	}
+
+	validatorCommand = &cli.Command{
+		Name:  "validator",
+		Usage: "Manage masternode candidates and votes",
+		Description: `
+Operate on the validator contract (XDCValidator) to become, resign or vote for
+a masternode candidate, and to withdraw the refunded stakes.
+
+Transactions are signed with an account of the keystore, given with --from, and
+sent to the node at --endpoint once their gas is estimated. With --offline,
+they are signed without any node and printed as raw transactions, which can be
+broadcast later with eth.sendRawTransaction. Amounts are given in XDC.`,
+		Subcommands: []*cli.Command{
+			{
+				Name:      "propose",
+				Usage:     "Propose a new masternode candidate",
+				ArgsUsage: "<candidate> <amount>",
+				Action:    validatorPropose,
+				Flags:     validatorTxFlags,
+				Description: `
+    XDC validator propose --from <owner> <candidate> <amount>
+
+Proposes the candidate with the given stake, which must be at least the minimum
+candidate cap. The owner must have uploaded its KYC beforehand.`,
+			},
+			{
+				Name:      "resign",
+				Usage:     "Resign a masternode candidate",
+				ArgsUsage: "<candidate>",
+				Action:    validatorResign,
+				Flags:     validatorTxFlags,
+				Description: `
+    XDC validator resign --from <owner> <candidate>
+
+Resigns the candidate. The stake of the owner can be withdrawn once the
+candidate withdraw delay has passed.`,
+			},
+			{
+				Name:      "vote",
+				Usage:     "Vote for a masternode candidate",
+				ArgsUsage: "<candidate> <amount>",
+				Action:    validatorVote,
+				Flags:     validatorTxFlags,
+				Description: `
+    XDC validator vote --from <voter> <candidate> <amount>
+
+Stakes the given amount on the candidate, which must be at least the minimum
+voter cap.`,
+			},
+			{
+				Name:      "unvote",
+				Usage:     "Take back votes from a masternode candidate",
+				ArgsUsage: "<candidate> <amount>",
+				Action:    validatorUnvote,
+				Flags:     validatorTxFlags,
+				Description: `
+    XDC validator unvote --from <voter> <candidate> <amount>
+
+Takes back the given amount staked on the candidate. It can be withdrawn once
+the voter withdraw delay has passed.`,
+			},
+			{
+				Name:      "withdraw",
+				Usage:     "Withdraw an unlocked stake",
+				ArgsUsage: "<blockNumber> [index]",
+				Action:    validatorWithdraw,
+				Flags:     validatorTxFlags,
+				Description: `
+    XDC validator withdraw --from <account> <blockNumber> [index]
+
+Withdraws the stake unlocked at the given block, as listed by 'XDC validator
+status'. The index of the withdrawal is looked up on the node unless given,
+which is required with --offline.`,
+			},
+			{
+				Name:      "status",
+				Usage:     "Print the candidate and the pending withdrawals of an address",
+				ArgsUsage: "<address>",
+				Action:    validatorStatus,
+				Flags:     validatorQueryFlags,
+				Description: `
+    XDC validator status <address>
+
+Prints the candidate state of the address, if any, and the stakes it can
+withdraw along with the block they unlock at.`,
+			},
+			{
+				Name:      "list-candidates",
+				Usage:     "Print the masternode candidates",
+				ArgsUsage: " ",
+				Action:    validatorListCandidates,
+				Flags:     validatorQueryFlags,
+				Description: `
+    XDC validator list-candidates
+
+Prints the candidates with their owner and stake, by decreasing stake.`,
+			},
+		},
+	}
+)
+
+// validatorContract binds the validator contract, over the node at the given
+// endpoint unless offline.
+func validatorContract(ctx *cli.Context, offline bool) (*contract.XDCValidator, *ethclient.Client) {
+	addr := ctx.String(validatorContractFlag.Name)
+	if !common.IsHexAddress(addr) {
+		utils.Fatalf("Invalid validator contract address: %s", addr)
+	}
+	var (
+		client  *ethclient.Client
+		backend bind.ContractBackend
+	)
+	if !offline {
+		var err error
+		if client, err = ethclient.Dial(ctx.String(validatorEndpointFlag.Name)); err != nil {
+			utils.Fatalf("Failed to connect to the node: %v", err)
+		}
+		backend = client
+	}
+	validator, err := contract.NewXDCValidator(common.HexToAddress(addr), backend)
+	if err != nil {
+		utils.Fatalf("Failed to bind the validator contract: %v", err)
+	}
+	return validator, client
+}
+
+// validatorTransact signs the transaction built by the given contract call with
+// the account of the keystore given by --from. The transaction is sent to the
+// node, or printed with --offline.
+func validatorTransact(ctx *cli.Context, value *big.Int, call func(*contract.XDCValidator, *bind.TransactOpts) (*types.Transaction, error)) error {
+	if !ctx.IsSet(validatorFromFlag.Name) {
+		utils.Fatalf("No account given with --from")
+	}
+	offline := ctx.Bool(validatorOfflineFlag.Name)
+	if offline {
+		for _, flag := range []string{validatorNonceFlag.Name, validatorGasFlag.Name, validatorGasPriceFlag.Name, validatorChainIdFlag.Name} {
+			if !ctx.IsSet(flag) {
+				utils.Fatalf("--%s is required with --offline", flag)
+			}
+		}
+	}
+	validator, client := validatorContract(ctx, offline)
+	if client != nil {
+		defer client.Close()
+	}
+
+	opts, err := validatorTransactOpts(ctx, client)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	opts.Value = value
+	tx, err := call(validator, opts)
+	if err != nil {
+		utils.Fatalf("Failed to create the transaction: %v", err)
+	}
+	fmt.Printf("From:      %s\n", opts.From.Hex())
+	fmt.Printf("Nonce:     %d\n", tx.Nonce())
+	fmt.Printf("Gas:       %d\n", tx.Gas())
+	fmt.Printf("Gas price: %s wei\n", tx.GasPrice())
+	fmt.Printf("Value:     %s XDC\n", formatXDC(tx.Value()))
+	fmt.Printf("Max fee:   %s XDC\n", formatXDC(tx.Cost()))
+
+	if offline {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			utils.Fatalf("Failed to encode the transaction: %v", err)
+		}
+		fmt.Printf("Raw transaction: %s\n", hexutil.Encode(raw))
+		return nil
+	}
+	if err := client.SendTransaction(context.Background(), tx); err != nil {
+		utils.Fatalf("Failed to send the transaction: %v", err)
+	}
+	fmt.Printf("Transaction sent: %s\n", tx.Hash().Hex())
+	return nil
+}
+
+// validatorTransactOpts unlocks the account given by --from and returns the
+// options signing with it. They are filled in from the flags and, unless
+// offline, completed by the contract binding from the node.
+func validatorTransactOpts(ctx *cli.Context, client *ethclient.Client) (*bind.TransactOpts, error) {
+	am := makeAccountManager(ctx)
+	backends := am.Backends(keystore.KeyStoreType)
+	if len(backends) == 0 {
+		return nil, errors.New("keystore is not available")
+	}
+	ks := backends[0].(*keystore.KeyStore)
+	account, _ := unlockAccount(ctx, ks, ctx.String(validatorFromFlag.Name), 0, utils.MakePasswordList(ctx))
+
+	var chainID *big.Int
+	if ctx.IsSet(validatorChainIdFlag.Name) {
+		chainID = new(big.Int).SetUint64(ctx.Uint64(validatorChainIdFlag.Name))
+	} else {
+		id, err := client.ChainID(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the chain id: %v", err)
+		}
+		chainID = id
+	}
+	opts, err := bind.NewKeyStoreTransactorWithChainID(ks, account, chainID)
+	if err != nil {
+		return nil, err
+	}
+	// The transaction is sent by the command once printed
+	opts.NoSend = true
+	if ctx.IsSet(validatorNonceFlag.Name) {
+		opts.Nonce = new(big.Int).SetUint64(ctx.Uint64(validatorNonceFlag.Name))
+	}
+	if ctx.IsSet(validatorGasFlag.Name) {
+		opts.GasLimit = ctx.Uint64(validatorGasFlag.Name)
+	}
+	if ctx.IsSet(validatorGasPriceFlag.Name) {
+		price, ok := new(big.Int).SetString(ctx.String(validatorGasPriceFlag.Name), 10)
+		if !ok || price.Sign() < 0 {
+			return nil, fmt.Errorf("invalid gas price: %s", ctx.String(validatorGasPriceFlag.Name))
+		}
+		opts.GasPrice = price
+	}
+	return opts, nil
+}
+
+func validatorPropose(ctx *cli.Context) error {
+	candidate, amount := candidateAndAmountArgs(ctx)
+	return validatorTransact(ctx, amount, func(validator *contract.XDCValidator, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return validator.Propose(opts, candidate)
+	})
+}
+
+func validatorResign(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("This command requires the candidate as the only argument")
+	}
+	candidate := addressArg(ctx.Args().First())
+	return validatorTransact(ctx, nil, func(validator *contract.XDCValidator, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return validator.Resign(opts, candidate)
+	})
+}
+
+func validatorVote(ctx *cli.Context) error {
+	candidate, amount := candidateAndAmountArgs(ctx)
+	return validatorTransact(ctx, amount, func(validator *contract.XDCValidator, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return validator.Vote(opts, candidate)
+	})
+}
+
+func validatorUnvote(ctx *cli.Context) error {
+	candidate, amount := candidateAndAmountArgs(ctx)
+	return validatorTransact(ctx, nil, func(validator *contract.XDCValidator, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return validator.Unvote(opts, candidate, amount)
+	})
+}
+
+func validatorWithdraw(ctx *cli.Context) error {
+	if ctx.Args().Len() < 1 || ctx.Args().Len() > 2 {
+		utils.Fatalf("This command requires the unlock block number and optionally the index of the withdrawal")
+	}
+	number, ok := new(big.Int).SetString(ctx.Args().Get(0), 10)
+	if !ok || number.Sign() <= 0 {
+		utils.Fatalf("Invalid block number: %s", ctx.Args().Get(0))
+	}
+	var index *big.Int
+	if ctx.Args().Len() == 2 {
+		if index, ok = new(big.Int).SetString(ctx.Args().Get(1), 10); !ok || index.Sign() < 0 {
+			utils.Fatalf("Invalid withdrawal index: %s", ctx.Args().Get(1))
+		}
+	} else if ctx.Bool(validatorOfflineFlag.Name) {
+		utils.Fatalf("The index of the withdrawal is required with --offline")
+	}
+	return validatorTransact(ctx, nil, func(validator *contract.XDCValidator, opts *bind.TransactOpts) (*types.Transaction, error) {
+		if index == nil {
+			numbers, err := validator.GetWithdrawBlockNumbers(&bind.CallOpts{From: opts.From})
+			if err != nil {
+				return nil, err
+			}
+			i := slices.IndexFunc(numbers, func(n *big.Int) bool { return n.Cmp(number) == 0 })
+			if i < 0 {
+				return nil, fmt.Errorf("no withdrawal of %s unlocking at block %v", opts.From.Hex(), number)
+			}
+			index = big.NewInt(int64(i))
+		}
+		return validator.Withdraw(opts, number, index)
+	})
+}
+
+func validatorStatus(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("This command requires the address as the only argument")
+	}
+	addr := addressArg(ctx.Args().First())
+	validator, client := validatorContract(ctx, false)
+	defer client.Close()
+
+	head, err := client.BlockNumber(context.Background())
+	if err != nil {
+		utils.Fatalf("Failed to retrieve the current block: %v", err)
+	}
+	opts := &bind.CallOpts{From: addr}
+	isCandidate, err := validator.IsCandidate(opts, addr)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve the candidate state: %v", err)
+	}
+	fmt.Printf("Address:   %s\n", addr.Hex())
+	fmt.Printf("Candidate: %t\n", isCandidate)
+	if isCandidate {
+		owner, err := validator.GetCandidateOwner(opts, addr)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the candidate owner: %v", err)
+		}
+		stake, err := validator.GetCandidateCap(opts, addr)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the candidate stake: %v", err)
+		}
+		voters, err := validator.GetVoters(opts, addr)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the candidate voters: %v", err)
+		}
+		fmt.Printf("Owner:     %s\n", owner.Hex())
+		fmt.Printf("Stake:     %s XDC\n", formatXDC(stake))
+		fmt.Printf("Voters:    %d\n", len(voters))
+	}
+
+	// Withdrawals are recorded by sender, deleted ones are zeroed
+	numbers, err := validator.GetWithdrawBlockNumbers(opts)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve the withdrawals: %v", err)
+	}
+	fmt.Printf("Pending withdrawals at block %d:\n", head)
+	var pending int
+	for i, number := range numbers {
+		if number.Sign() == 0 {
+			continue
+		}
+		stake, err := validator.GetWithdrawCap(opts, number)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the withdrawal at block %v: %v", number, err)
+		}
+		state := "locked"
+		if number.Uint64() <= head {
+			state = "unlocked"
+		}
+		fmt.Printf("  #%d: %s XDC, unlock block %v (%s)\n", i, formatXDC(stake), number, state)
+		pending++
+	}
+	if pending == 0 {
+		fmt.Println("  none")
+	}
+	return nil
+}
+
+func validatorListCandidates(ctx *cli.Context) error {
+	validator, client := validatorContract(ctx, false)
+	defer client.Close()
+
+	opts := new(bind.CallOpts)
+	candidates, err := validator.GetCandidates(opts)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve the candidates: %v", err)
+	}
+	type candidate struct {
+		address, owner common.Address
+		stake          *big.Int
+	}
+	var list []candidate
+	for _, addr := range candidates {
+		// Resigned candidates are zeroed
+		if addr == (common.Address{}) {
+			continue
+		}
+		isCandidate, err := validator.IsCandidate(opts, addr)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the state of %s: %v", addr.Hex(), err)
+		}
+		if !isCandidate {
+			continue
+		}
+		owner, err := validator.GetCandidateOwner(opts, addr)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the owner of %s: %v", addr.Hex(), err)
+		}
+		stake, err := validator.GetCandidateCap(opts, addr)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the stake of %s: %v", addr.Hex(), err)
+		}
+		list = append(list, candidate{addr, owner, stake})
+	}
+	slices.SortStableFunc(list, func(a, b candidate) int { return b.stake.Cmp(a.stake) })
+	for i, c := range list {
+		fmt.Printf("#%d: %s owner %s stake %s XDC\n", i, c.address.Hex(), c.owner.Hex(), formatXDC(c.stake))
+	}
+	fmt.Printf("%d candidates\n", len(list))
+	return nil
+}
+
+// candidateAndAmountArgs parses the candidate and amount arguments.
+func candidateAndAmountArgs(ctx *cli.Context) (common.Address, *big.Int) {
+	if ctx.Args().Len() != 2 {
+		utils.Fatalf("This command requires the candidate and the amount as arguments")
+	}
+	amount, err := parseXDC(ctx.Args().Get(1))
+	if err != nil {
+		utils.Fatalf("Invalid amount %s: %v", ctx.Args().Get(1), err)
+	}
+	return addressArg(ctx.Args().Get(0)), amount
+}
+
+func addressArg(s string) common.Address {
+	if !common.IsHexAddress(s) {
+		utils.Fatalf("Invalid address: %s", s)
+	}
+	return common.HexToAddress(s)
+}
+
+// parseXDC converts a positive decimal amount of XDC to wei.
+func parseXDC(s string) (*big.Int, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > 18 {
+		return nil, errors.New("more than 18 decimals")
+	}
+	digits := whole + frac + strings.Repeat("0", 18-len(frac))
+	if strings.ContainsAny(digits, "+-") {
+		return nil, errors.New("invalid number")
+	}
+	wei, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, errors.New("invalid number")
+	}
+	if wei.Sign() == 0 {
+		return nil, errors.New("zero amount")
+	}
+	return wei, nil
+}
+
+// formatXDC converts an amount of wei to a decimal amount of XDC.
+func formatXDC(wei *big.Int) string {
+	whole, frac := new(big.Int).QuoRem(wei, big.NewInt(params.Ether), new(big.Int))
+	if frac.Sign() == 0 {
+		return whole.String()
+	}
+	return strings.TrimRight(fmt.Sprintf("%s.%018d", whole, frac.Abs(frac)), "0")
+}
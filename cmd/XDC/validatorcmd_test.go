@@ -0,0 +1,82 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseXDC(t *testing.T) {
+	tests := []struct {
+		in  string
+		wei string
+	}{
+		{"1", "1000000000000000000"},
+		{"10000000", "10000000000000000000000000"},
+		{"0.5", "500000000000000000"},
+		{"1.000000000000000001", "1000000000000000001"},
+		{".25", "250000000000000000"},
+	}
+	for _, test := range tests {
+		wei, err := parseXDC(test.in)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.in, err)
+			continue
+		}
+		if wei.String() != test.wei {
+			t.Errorf("%s: got %v, want %s", test.in, wei, test.wei)
+		}
+		if back := formatXDC(wei); back != test.in && "0"+test.in != back {
+			t.Errorf("%s: formatted back to %s", test.in, back)
+		}
+	}
+	for _, in := range []string{"", "0", "-1", "1.0000000000000000001", "1e18", "1.-5", "0x10"} {
+		if _, err := parseXDC(in); err == nil {
+			t.Errorf("%s: expected an error", in)
+		}
+	}
+}
+
+func TestFormatXDC(t *testing.T) {
+	if s := formatXDC(new(big.Int)); s != "0" {
+		t.Errorf("got %s, want 0", s)
+	}
+	if s := formatXDC(big.NewInt(1)); s != "0.000000000000000001" {
+		t.Errorf("got %s, want 0.000000000000000001", s)
+	}
+}
+
+func TestValidatorOfflineVote(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	XDC := runXDC(t, "validator", "vote", "--datadir", datadir, "--offline",
+		"--from", "7ef5a6135f1fd6a02593eedc869c6d41d934aef8",
+		"--nonce", "3", "--gas", "300000", "--gasprice", "250000000", "--chainid", "51",
+		"xdcf466859ead1932d743d622cb74fc058882e8648a", "25000")
+	defer XDC.ExpectExit()
+	XDC.Expect(`
+Unlocking account 7ef5a6135f1fd6a02593eedc869c6d41d934aef8 | Attempt 1/3
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+From:      xdc7EF5A6135f1FD6a02593eEdC869c6D41D934aef8
+Nonce:     3
+Gas:       300000
+Gas price: 250000000 wei
+Value:     25000 XDC
+Max fee:   25000.000075 XDC
+`)
+	XDC.ExpectRegexp(`Raw transaction: 0x[0-9a-f]+\n`)
+}
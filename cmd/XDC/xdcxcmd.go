@@ -0,0 +1,420 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/XDCxlending/lendingstate"
+	"github.com/XinFinOrg/XDPoSChain/cmd/utils"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	xdcxRelayerFlag = &cli.StringFlag{
+		Name:  "relayer",
+		Usage: "Address that committed the trading and lending roots of the block (default: the block signer)",
+	}
+	xdcxOrderBookFlag = &cli.StringFlag{
+		Name:  "orderbook",
+		Usage: "Only dump the orderbook or lending book with this hash",
+	}
+	xdcxFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format of the dump (json or csv)",
+		Value: "json",
+	}
+	xdcxOutputFlag = &cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write the dump to (default: standard output)",
+	}
+
+	xdcxCommand = &cli.Command{
+		Name:      "xdcx",
+		Usage:     "Inspect the XDCx trading and lending states",
+		ArgsUsage: "",
+		Subcommands: []*cli.Command{
+			xdcxDumpCmd,
+			xdcxVerifyCmd,
+		},
+	}
+	xdcxDumpCmd = &cli.Command{
+		Action:    xdcxDump,
+		Name:      "dump",
+		Usage:     "Export the trading and lending states of a block",
+		ArgsUsage: "[? <blockHash> | <blockNum>]",
+		Flags: slices.Concat([]cli.Flag{
+			xdcxRelayerFlag,
+			xdcxOrderBookFlag,
+			xdcxFormatFlag,
+			xdcxOutputFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+The dump command opens the databases read-only, resolves the trading and lending
+state roots committed in the given block (default: the head block) and exports
+the order books, lending books, liquidation trees and user nonces as JSON or CSV.`,
+	}
+	xdcxVerifyCmd = &cli.Command{
+		Action:    xdcxVerify,
+		Name:      "verify",
+		Usage:     "Recompute the trading and lending state roots of a block",
+		ArgsUsage: "[? <blockHash> | <blockNum>]",
+		Flags: slices.Concat([]cli.Flag{
+			xdcxRelayerFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+The verify command opens the databases read-only, resolves the trading and lending
+state roots committed in the given block (default: the head block) and rebuilds
+every trie below them from its leaves, failing if a node is missing or a
+recomputed root differs from the stored one.`,
+	}
+)
+
+// xdcxState is the trading and lending state of a block, as committed by its
+// relayer.
+type xdcxState struct {
+	Number      uint64
+	Hash        common.Hash
+	Relayer     common.Address
+	TradingRoot common.Hash
+	LendingRoot common.Hash
+
+	db ethdb.Database
+}
+
+// openXDCxState resolves the trading and lending roots of the block selected
+// by the command arguments and opens the XDCx database read-only.
+func openXDCxState(ctx *cli.Context) (*xdcxState, error) {
+	if ctx.NArg() > 1 {
+		return nil, fmt.Errorf("expected 1 argument (number or hash), got %d", ctx.NArg())
+	}
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	block, err := readXDCxBlock(db, ctx.Args().First())
+	if err != nil {
+		return nil, err
+	}
+	var relayer common.Address
+	if ctx.IsSet(xdcxRelayerFlag.Name) {
+		if !common.IsHexAddress(ctx.String(xdcxRelayerFlag.Name)) {
+			return nil, fmt.Errorf("invalid relayer address %q", ctx.String(xdcxRelayerFlag.Name))
+		}
+		relayer = common.HexToAddress(ctx.String(xdcxRelayerFlag.Name))
+	} else {
+		config, err := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+		if err != nil {
+			return nil, err
+		}
+		if config.XDPoS == nil {
+			return nil, errors.New("XDCx states are only supported by the XDPoS engine")
+		}
+		if relayer, err = XDPoS.New(config, db).Author(block.Header()); err != nil {
+			return nil, fmt.Errorf("failed to recover the signer of block %d: %v", block.NumberU64(), err)
+		}
+	}
+	state := &xdcxState{
+		Number:      block.NumberU64(),
+		Hash:        block.Hash(),
+		Relayer:     relayer,
+		TradingRoot: tradingstate.EmptyRoot,
+		LendingRoot: lendingstate.EmptyRoot,
+	}
+	// The relayer commits both roots in a single transaction to the trading
+	// state address, the trading root first.
+	for _, tx := range block.Transactions() {
+		to, from := tx.To(), tx.From()
+		if to == nil || *to != common.TradingStateAddrBinary || from == nil || *from != relayer {
+			continue
+		}
+		if data := tx.Data(); len(data) >= 32 {
+			state.TradingRoot = common.BytesToHash(data[:32])
+			if len(data) >= 64 {
+				state.LendingRoot = common.BytesToHash(data[32:64])
+			}
+			break
+		}
+	}
+	log.Info("Resolved XDCx state roots", "number", state.Number, "hash", state.Hash, "relayer", relayer, "trading", state.TradingRoot, "lending", state.LendingRoot)
+
+	if state.db, err = rawdb.NewLevelDBDatabase(cfg.XDCX.DataDir, 128, 1024, "", true); err != nil {
+		return nil, fmt.Errorf("failed to open XDCx database %s: %v", cfg.XDCX.DataDir, err)
+	}
+	return state, nil
+}
+
+// readXDCxBlock reads the block with the given number or hash, or the head
+// block if arg is empty.
+func readXDCxBlock(db ethdb.Database, arg string) (*types.Block, error) {
+	var hash common.Hash
+	switch {
+	case arg == "":
+		hash = rawdb.ReadHeadBlockHash(db)
+	case hashish(arg):
+		hash = common.HexToHash(arg)
+	default:
+		number, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if hash = rawdb.ReadCanonicalHash(db, number); hash == (common.Hash{}) {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+	}
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	block := rawdb.ReadBlock(db, hash, *number)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	return block, nil
+}
+
+// xdcxStateDump is the exported content of the XDCx states of a block.
+type xdcxStateDump struct {
+	*xdcxState
+	Trading *tradingstate.DumpState
+	Lending *lendingstate.DumpState
+}
+
+func xdcxDump(ctx *cli.Context) error {
+	format := ctx.String(xdcxFormatFlag.Name)
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("unknown format %q, want json or csv", format)
+	}
+	state, err := openXDCxState(ctx)
+	if err != nil {
+		return err
+	}
+	defer state.db.Close()
+
+	trading, err := tradingstate.New(state.TradingRoot, tradingstate.NewDatabase(state.db))
+	if err != nil {
+		return fmt.Errorf("trading state %x not found: %v", state.TradingRoot, err)
+	}
+	lending, err := lendingstate.New(state.LendingRoot, lendingstate.NewDatabase(state.db))
+	if err != nil {
+		return fmt.Errorf("lending state %x not found: %v", state.LendingRoot, err)
+	}
+	dump := &xdcxStateDump{xdcxState: state}
+	if ctx.IsSet(xdcxOrderBookFlag.Name) {
+		// A single book is requested, nonces belong to users and are left out.
+		book := common.HexToHash(ctx.String(xdcxOrderBookFlag.Name))
+		dump.Trading = &tradingstate.DumpState{OrderBooks: map[common.Hash]*tradingstate.DumpOrderBook{}}
+		dump.Lending = &lendingstate.DumpState{LendingBooks: map[common.Hash]*lendingstate.DumpLendingBook{}}
+		if trading.Exist(book) {
+			if dump.Trading.OrderBooks[book], err = trading.DumpOrderBook(book); err != nil {
+				return err
+			}
+		}
+		if lending.Exist(book) {
+			if dump.Lending.LendingBooks[book], err = lending.DumpLendingBook(book); err != nil {
+				return err
+			}
+		}
+		if len(dump.Trading.OrderBooks) == 0 && len(dump.Lending.LendingBooks) == 0 {
+			return fmt.Errorf("orderbook %x not found", book)
+		}
+	} else {
+		if dump.Trading, err = trading.Dump(); err != nil {
+			return err
+		}
+		if dump.Lending, err = lending.Dump(); err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if path := ctx.String(xdcxOutputFlag.Name); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	if format == "csv" {
+		return writeXDCxCSV(out, dump)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
+
+// writeXDCxCSV writes the dump as one row per entry of the tries. The key and
+// id columns hold the trie key (price, rate, time or order id) and the entry
+// of the order list below it, when there is one.
+func writeXDCxCSV(out io.Writer, dump *xdcxStateDump) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"state", "book", "tree", "key", "id", "value"})
+
+	writeLists := func(state string, book common.Hash, tree string, lists map[*big.Int]tradingstate.DumpOrderList) {
+		for _, key := range sortedBigKeys(lists) {
+			for _, id := range sortedBigKeys(lists[key].Orders) {
+				w.Write([]string{state, book.Hex(), tree, key.String(), id.String(), lists[key].Orders[id].String()})
+			}
+		}
+	}
+	writeItem := func(state string, book common.Hash, tree string, key *big.Int, item interface{}) error {
+		enc, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		return w.Write([]string{state, book.Hex(), tree, key.String(), "", string(enc)})
+	}
+	writeNonces := func(state string, nonces map[common.Address]uint64) {
+		addrs := make([]common.Address, 0, len(nonces))
+		for addr := range nonces {
+			addrs = append(addrs, addr)
+		}
+		slices.SortFunc(addrs, func(a, b common.Address) int { return bytes.Compare(a[:], b[:]) })
+		for _, addr := range addrs {
+			w.Write([]string{state, "", "nonce", addr.Hex(), "", strconv.FormatUint(nonces[addr], 10)})
+		}
+	}
+
+	writeNonces("trading", dump.Trading.Nonces)
+	for _, hash := range sortedHashKeys(dump.Trading.OrderBooks) {
+		book := dump.Trading.OrderBooks[hash]
+		info := book.Info
+		for _, field := range [][2]string{
+			{"nonce", strconv.FormatUint(info.Nonce, 10)},
+			{"lastPrice", info.LastPrice.String()},
+			{"mediumPrice", info.MediumPrice.String()},
+			{"mediumPriceBeforeEpoch", info.MediumPriceBeforeEpoch.String()},
+			{"totalQuantity", info.TotalQuantity.String()},
+			{"lendingCount", info.LendingCount.String()},
+		} {
+			w.Write([]string{"trading", hash.Hex(), "info", field[0], "", field[1]})
+		}
+		writeLists("trading", hash, "ask", book.Asks)
+		writeLists("trading", hash, "bid", book.Bids)
+		writeLists("trading", hash, "stop", book.StopOrders)
+		for _, price := range sortedBigKeys(book.LiquidationPrices) {
+			lendingBooks := book.LiquidationPrices[price].LendingBooks
+			for _, lendingBook := range sortedHashKeys(lendingBooks) {
+				trades := lendingBooks[lendingBook].Orders
+				for _, id := range sortedBigKeys(trades) {
+					w.Write([]string{"trading", hash.Hex(), "liquidation", price.String() + "/" + lendingBook.Hex(), id.String(), trades[id].String()})
+				}
+			}
+		}
+		for _, id := range sortedBigKeys(book.Orders) {
+			if err := writeItem("trading", hash, "order", id, book.Orders[id]); err != nil {
+				return err
+			}
+		}
+	}
+
+	writeNonces("lending", dump.Lending.Nonces)
+	for _, hash := range sortedHashKeys(dump.Lending.LendingBooks) {
+		book := dump.Lending.LendingBooks[hash]
+		w.Write([]string{"lending", hash.Hex(), "info", "nonce", "", strconv.FormatUint(book.Info.Nonce, 10)})
+		w.Write([]string{"lending", hash.Hex(), "info", "tradeNonce", "", strconv.FormatUint(book.Info.TradeNonce, 10)})
+		for _, tree := range []struct {
+			name  string
+			lists map[*big.Int]lendingstate.DumpOrderList
+		}{
+			{"investing", book.Investings},
+			{"borrowing", book.Borrowings},
+			{"liquidation", book.LiquidationTimes},
+		} {
+			// Both packages share the order list layout.
+			converted := make(map[*big.Int]tradingstate.DumpOrderList, len(tree.lists))
+			for key, list := range tree.lists {
+				converted[key] = tradingstate.DumpOrderList(list)
+			}
+			writeLists("lending", hash, tree.name, converted)
+		}
+		for _, id := range sortedBigKeys(book.LendingItems) {
+			if err := writeItem("lending", hash, "item", id, book.LendingItems[id]); err != nil {
+				return err
+			}
+		}
+		for _, id := range sortedBigKeys(book.LendingTrades) {
+			if err := writeItem("lending", hash, "trade", id, book.LendingTrades[id]); err != nil {
+				return err
+			}
+		}
+		for _, id := range sortedBigKeys(book.Auctions) {
+			if err := writeItem("lending", hash, "auction", id, book.Auctions[id]); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func sortedBigKeys[V any](m map[*big.Int]V) []*big.Int {
+	keys := make([]*big.Int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b *big.Int) int { return a.Cmp(b) })
+	return keys
+}
+
+func sortedHashKeys[V any](m map[common.Hash]V) []common.Hash {
+	keys := make([]common.Hash, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b common.Hash) int { return a.Cmp(b) })
+	return keys
+}
+
+func xdcxVerify(ctx *cli.Context) error {
+	state, err := openXDCxState(ctx)
+	if err != nil {
+		return err
+	}
+	defer state.db.Close()
+
+	tradingStats, err := tradingstate.VerifyState(tradingstate.NewDatabase(state.db), state.TradingRoot)
+	if err != nil {
+		return fmt.Errorf("trading state %x is corrupted: %v", state.TradingRoot, err)
+	}
+	log.Info("Verified trading state", "root", state.TradingRoot, "tries", tradingStats.Tries, "leaves", tradingStats.Leaves)
+
+	lendingStats, err := lendingstate.VerifyState(lendingstate.NewDatabase(state.db), state.LendingRoot)
+	if err != nil {
+		return fmt.Errorf("lending state %x is corrupted: %v", state.LendingRoot, err)
+	}
+	log.Info("Verified lending state", "root", state.LendingRoot, "tries", lendingStats.Tries, "leaves", lendingStats.Leaves)
+	return nil
+}
@@ -12,7 +12,7 @@ var (
 
 	RollbackNumber = uint64(0)
 
-	StoreRewardFolder string
+	StoreReward bool
 
 	TRC21GasPriceBefore = big.NewInt(2500)
 	TRC21GasPrice       = big.NewInt(250000000)
@@ -35,6 +35,9 @@ var (
 	BaseLendingInterest     = big.NewInt(100000000)         // 1e8
 	RelayerLendingFee       = big.NewInt(10000000000000000) // 0.01
 	RelayerLendingCancelFee = big.NewInt(1000000000000000)  // 0.001
+	AuctionStartPremium     = big.NewInt(110)               // auction opens at 110% of the collateral price
+	AuctionFloorRate        = big.NewInt(50)                // auction never sells below 50% of the collateral price
+	BaseAuction             = big.NewInt(100)
 )
 
 type constant struct {
@@ -62,6 +65,11 @@ type constant struct {
 	tipUpgradeReward       *big.Int
 	tipUpgradePenalty      *big.Int
 	tipEpochHalving        *big.Int
+	tipSlashing            *big.Int // Masternodes can be slashed with forensic proofs
+	tipXDCXOrderTypes      *big.Int // XDCx accepts IOC, FOK and post-only orders
+	tipXDCXStopOrders      *big.Int // XDCx accepts stop-market and stop-limit orders
+	tipXDCXLendingAuction  *big.Int // XDCx lending liquidates collateral through a Dutch auction
+	tipEpochRoles          *big.Int // XDPoS v2 epoch switch blocks record the protector and observer sets
 	eip1559Block           *big.Int
 	cancunBlock            *big.Int
 
@@ -101,6 +109,11 @@ var (
 	TIPUpgradeReward       = MaintnetConstant.tipUpgradeReward
 	TipUpgradePenalty      = MaintnetConstant.tipUpgradePenalty
 	TIPEpochHalving        = MaintnetConstant.tipEpochHalving
+	TIPSlashing            = MaintnetConstant.tipSlashing
+	TIPXDCXOrderTypes      = MaintnetConstant.tipXDCXOrderTypes
+	TIPXDCXStopOrders      = MaintnetConstant.tipXDCXStopOrders
+	TIPXDCXLendingAuction  = MaintnetConstant.tipXDCXLendingAuction
+	TIPEpochRoles          = MaintnetConstant.tipEpochRoles
 
 	TRC21IssuerSMC         = MaintnetConstant.trc21IssuerSMC
 	XDCXListingSMC         = MaintnetConstant.xdcxListingSMC
@@ -162,6 +175,11 @@ func CopyConstants(chainID uint64) {
 	TIPUpgradeReward = c.tipUpgradeReward
 	TipUpgradePenalty = c.tipUpgradePenalty
 	TIPEpochHalving = c.tipEpochHalving
+	TIPSlashing = c.tipSlashing
+	TIPXDCXOrderTypes = c.tipXDCXOrderTypes
+	TIPXDCXStopOrders = c.tipXDCXStopOrders
+	TIPXDCXLendingAuction = c.tipXDCXLendingAuction
+	TIPEpochRoles = c.tipEpochRoles
 
 	TRC21IssuerSMC = c.trc21IssuerSMC
 	XDCXListingSMC = c.xdcxListingSMC
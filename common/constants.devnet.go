@@ -31,6 +31,11 @@ var DevnetConstant = constant{
 	tipUpgradeReward:       big.NewInt(9999999999),
 	tipUpgradePenalty:      big.NewInt(9999999999),
 	tipEpochHalving:        big.NewInt(9999999999),
+	tipSlashing:            big.NewInt(9999999999),
+	tipXDCXOrderTypes:      big.NewInt(9999999999),
+	tipXDCXStopOrders:      big.NewInt(9999999999),
+	tipXDCXLendingAuction:  big.NewInt(9999999999),
+	tipEpochRoles:          big.NewInt(9999999999),
 
 	trc21IssuerSMC:         HexToAddress("0x8c0faeb5C6bEd2129b8674F262Fd45c4e9468bee"),
 	xdcxListingSMC:         HexToAddress("0xDE34dD0f536170993E8CFF639DdFfCF1A85D3E53"),
@@ -26,7 +26,8 @@ const (
 	BlocksPerYear              = uint64(15768000)
 	OneYear                    = uint64(365 * 86400)
 	LiquidateLendingTradeBlock = uint64(100)
-	LimitTimeFinality          = uint64(30) // limit in 30 block
+	LiquidationAuctionBlocks   = uint64(300) // blocks a liquidation auction runs before it settles
+	LimitTimeFinality          = uint64(30)  // limit in 30 block
 
 	HexSignMethod = "e341eaa4"
 	HexSetSecret  = "34d38600"
@@ -31,6 +31,11 @@ var localConstant = constant{
 	tipUpgradeReward:       big.NewInt(0),
 	tipUpgradePenalty:      big.NewInt(0),
 	tipEpochHalving:        big.NewInt(0),
+	tipSlashing:            big.NewInt(0),
+	tipXDCXOrderTypes:      big.NewInt(0),
+	tipXDCXStopOrders:      big.NewInt(0),
+	tipXDCXLendingAuction:  big.NewInt(0),
+	tipEpochRoles:          big.NewInt(0),
 
 	trc21IssuerSMC:         HexToAddress("0x8c0faeb5C6bEd2129b8674F262Fd45c4e9468bee"),
 	xdcxListingSMC:         HexToAddress("0xDE34dD0f536170993E8CFF639DdFfCF1A85D3E53"),
@@ -31,6 +31,11 @@ var TestnetConstant = constant{
 	tipUpgradeReward:       big.NewInt(9999999999),
 	tipUpgradePenalty:      big.NewInt(9999999999),
 	tipEpochHalving:        big.NewInt(9999999999),
+	tipSlashing:            big.NewInt(9999999999),
+	tipXDCXOrderTypes:      big.NewInt(9999999999),
+	tipXDCXStopOrders:      big.NewInt(9999999999),
+	tipXDCXLendingAuction:  big.NewInt(9999999999),
+	tipEpochRoles:          big.NewInt(9999999999),
 
 	trc21IssuerSMC:         HexToAddress("0x0E2C88753131CE01c7551B726b28BFD04e44003F"),
 	xdcxListingSMC:         HexToAddress("0x14B2Bf043b9c31827A472CE4F94294fE9a6277e0"),
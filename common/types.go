@@ -42,6 +42,7 @@ const (
 	TradingStateAddr                 = "xdc0000000000000000000000000000000000000092"
 	XDCXLendingAddress               = "xdc0000000000000000000000000000000000000093"
 	XDCXLendingFinalizedTradeAddress = "xdc0000000000000000000000000000000000000094"
+	SlashingAddr                     = "xdc0000000000000000000000000000000000000095"
 	XDCNativeAddress                 = "xdc0000000000000000000000000000000000000001"
 	LendingLockAddress               = "xdc0000000000000000000000000000000000000011"
 	VoteMethod                       = "0x6dd7d8ea"
@@ -69,6 +70,7 @@ var (
 	TradingStateAddrBinary                 = HexToAddress("0x0000000000000000000000000000000000000092")
 	XDCXLendingAddressBinary               = HexToAddress("0x0000000000000000000000000000000000000093")
 	XDCXLendingFinalizedTradeAddressBinary = HexToAddress("0x0000000000000000000000000000000000000094")
+	SlashingAddrBinary                     = HexToAddress("0x0000000000000000000000000000000000000095")
 	XDCNativeAddressBinary                 = HexToAddress("0x0000000000000000000000000000000000000001")
 	LendingLockAddressBinary               = HexToAddress("0x0000000000000000000000000000000000000011")
 	MintedRecordAddressBinary              = HexToAddress("0x000000000000000000000000000000000000009a")
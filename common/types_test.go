@@ -349,6 +349,7 @@ var testCases = []struct {
 	{TradingStateAddrBinary, TradingStateAddr},
 	{XDCXLendingAddressBinary, XDCXLendingAddress},
 	{XDCXLendingFinalizedTradeAddressBinary, XDCXLendingFinalizedTradeAddress},
+	{SlashingAddrBinary, SlashingAddr},
 	{XDCNativeAddressBinary, XDCNativeAddress},
 	{LendingLockAddressBinary, LendingLockAddress},
 }
@@ -273,6 +273,18 @@ func (x *XDPoS) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	}
 }
 
+// ApplyRewards credits the rewards due at the given block to the state, the
+// same way Finalize does, and returns them. It returns nil if the block does
+// not distribute rewards.
+func (x *XDPoS) ApplyRewards(chain consensus.ChainReader, header *types.Header, state *state.StateDB, parentState *state.StateDB) (map[string]interface{}, error) {
+	switch x.config.BlockConsensusVersion(header.Number, header.Extra, ExtraFieldCheck) {
+	case params.ConsensusEngineVersion2:
+		return x.EngineV2.ApplyRewards(chain, header, state, parentState)
+	default: // Default "v1"
+		return x.EngineV1.ApplyRewards(chain, header, state, parentState)
+	}
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (x *XDPoS) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
@@ -317,6 +329,13 @@ func (x *XDPoS) Authorize(signer common.Address, signFn clique.SignerFn) {
 	x.EngineV2.Authorize(signer, signFn)
 }
 
+// AuthorizeData injects a signer that signs the preimages of consensus hashes,
+// such as an external signer, into the consensus engine.
+func (x *XDPoS) AuthorizeData(signer common.Address, signDataFn utils.SignDataFn) {
+	x.EngineV1.AuthorizeData(signer, signDataFn)
+	x.EngineV2.AuthorizeData(signer, signDataFn)
+}
+
 func (x *XDPoS) GetPeriod() uint64 {
 	return x.config.Period
 }
@@ -16,20 +16,21 @@
 package XDPoS
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
-	"os"
-	"path/filepath"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
 
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/params"
@@ -82,6 +83,10 @@ type MasternodesStatus struct {
 	Penalty         []common.Address
 	StandbynodesLen int
 	Standbynodes    []common.Address
+	ProtectorsLen   int
+	Protectors      []common.Address
+	ObserversLen    int
+	Observers       []common.Address
 	Error           error
 }
 
@@ -108,10 +113,22 @@ type AccountRewardResponse struct {
 
 type AccountRewardStatus string
 
-type rewardFileName struct {
-	epochBlockNum  int
-	epochBlockHash common.Hash
-}
+const (
+	defaultRewardPageSize = 100  // Number of epochs returned by reward history queries without a limit
+	maxRewardPageSize     = 1000 // Maximum number of epochs returned by a single reward history query
+)
+
+const (
+	defaultForensicsPageSize = 100  // Number of reports returned by forensics queries without a limit
+	maxForensicsPageSize     = 1000 // Maximum number of reports returned by a single forensics query
+)
+
+var errRewardsNotFound = errors.New("rewards not found, the node must run with --store-reward")
+
+const (
+	maxFinalityProofEpochs  = 32  // Maximum number of epoch switch headers in a finality proof, each proven from about Gap headers
+	maxFinalityProofHeaders = 128 // Maximum number of headers searched for the committing QCs
+)
 
 const (
 	statusMasternode    AccountRewardStatus = "MasterNode"
@@ -121,6 +138,16 @@ const (
 
 type MessageStatus map[string]map[string]interface{}
 
+// ForensicsReportsArgs filters and pages the forensics reports returned by
+// GetForensicsReports.
+type ForensicsReportsArgs struct {
+	FromRound *types.Round    `json:"fromRound"`
+	ToRound   *types.Round    `json:"toRound"`
+	Signer    *common.Address `json:"signer"`
+	Offset    *int            `json:"offset"`
+	Limit     *int            `json:"limit"`
+}
+
 type SyncInfoTypes struct {
 	Hash      common.Hash `json:"hash"`
 	QCSigners int         `json:"qcSigners"`
@@ -206,6 +233,8 @@ func (api *API) GetMasternodesByNumber(number *rpc.BlockNumber) MasternodesStatu
 	masterNodes := api.XDPoS.EngineV2.GetMasternodes(api.chain, header)
 	penalties := api.XDPoS.EngineV2.GetPenalties(api.chain, header)
 	standbynodes := api.XDPoS.EngineV2.GetStandbynodes(api.chain, header)
+	protectors := api.XDPoS.EngineV2.GetProtectors(api.chain, header)
+	observers := api.XDPoS.EngineV2.GetObservers(api.chain, header)
 
 	info := MasternodesStatus{
 		Epoch:           epochNum,
@@ -217,6 +246,10 @@ func (api *API) GetMasternodesByNumber(number *rpc.BlockNumber) MasternodesStatu
 		Penalty:         penalties,
 		StandbynodesLen: len(standbynodes),
 		Standbynodes:    standbynodes,
+		ProtectorsLen:   len(protectors),
+		Protectors:      protectors,
+		ObserversLen:    len(observers),
+		Observers:       observers,
 	}
 	return info
 }
@@ -336,6 +369,145 @@ func (api *API) GetV2BlockByHash(blockHash common.Hash) *V2BlockInfo {
 	return api.GetV2BlockByHeader(header, uncle)
 }
 
+// GetFinalityProof returns a proof that a block is committed, which can be
+// checked offline with utils.VerifyFinalityProof. The proof links the block
+// to the trusted epoch switch header checkpoint, or to the epoch switch header
+// of its own epoch if no checkpoint is given. Every epoch switch is proven
+// from the state of its gap block, which must not be pruned.
+func (api *API) GetFinalityProof(blockHash common.Hash, checkpoint *common.Hash) (*utils.FinalityProof, error) {
+	config := api.XDPoS.config
+	if config.V2 == nil || config.V2.SwitchBlock == nil {
+		return nil, errors.New("finality proofs require the v2 consensus")
+	}
+	header := api.chain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+	if header.Number.Cmp(config.V2.SwitchBlock) < 0 {
+		return nil, utils.ErrNotV2Header
+	}
+	if canonical := api.chain.GetHeaderByNumber(header.Number.Uint64()); canonical == nil || canonical.Hash() != blockHash {
+		return nil, errors.New("block is not in the canonical chain")
+	}
+	proof := &utils.FinalityProof{Version: utils.FinalityProofVersion}
+
+	// Collect the descendants of the block up to the QC committing it
+	rounds := make([]types.Round, 0, 4)
+	for h := header; ; {
+		round, err := utils.HeaderRound(config, h)
+		if err != nil {
+			return nil, err
+		}
+		proof.Headers, rounds = append(proof.Headers, h), append(rounds, round)
+		if n := len(rounds); n >= 4 && rounds[n-2] == rounds[n-3]+1 && rounds[n-3] == rounds[n-4]+1 {
+			break
+		}
+		if len(proof.Headers) >= maxFinalityProofHeaders {
+			return nil, fmt.Errorf("no committing QCs within %d blocks", maxFinalityProofHeaders)
+		}
+		if h = api.chain.GetHeaderByNumber(h.Number.Uint64() + 1); h == nil {
+			return nil, errors.New("block is not committed yet")
+		}
+	}
+	// Link the epoch of the block to the checkpoint
+	if checkpoint == nil {
+		number, _, err := api.XDPoS.EngineV2.GetCurrentEpochSwitchBlock(api.chain, header.Number)
+		if err != nil {
+			return nil, err
+		}
+		proof.Checkpoint = api.chain.GetHeaderByNumber(number)
+	} else {
+		proof.Checkpoint = api.chain.GetHeaderByHash(*checkpoint)
+	}
+	if proof.Checkpoint == nil {
+		return nil, errors.New("checkpoint not found")
+	}
+	if proof.Checkpoint.Hash() == blockHash {
+		return proof, api.proveCommittees(proof, proof.Headers[1:])
+	}
+	if proof.Checkpoint.Number.Cmp(header.Number) > 0 {
+		return nil, errors.New("checkpoint is after the block")
+	}
+	infos, err := api.XDPoS.EngineV2.GetEpochSwitchInfoBetween(api.chain, proof.Checkpoint, header)
+	if err != nil {
+		return nil, err
+	}
+	// The search stops at the first v2 epoch switch, leaving out its parent, the last v1 block
+	if len(infos) > 0 && infos[0].EpochSwitchBlockInfo.Hash == proof.Checkpoint.Hash() {
+		infos = infos[1:]
+	} else if proof.Checkpoint.Number.Cmp(config.V2.SwitchBlock) != 0 || len(infos) == 0 || infos[0].EpochSwitchParentBlockInfo == nil || infos[0].EpochSwitchParentBlockInfo.Hash != proof.Checkpoint.Hash() {
+		return nil, errors.New("checkpoint is not an epoch switch block of the chain of the block")
+	}
+	if len(infos) > maxFinalityProofEpochs {
+		return nil, fmt.Errorf("checkpoint is more than %d epochs before the block", maxFinalityProofEpochs)
+	}
+	for _, info := range infos {
+		epochSwitch := api.chain.GetHeaderByHash(info.EpochSwitchBlockInfo.Hash)
+		if epochSwitch == nil {
+			return nil, fmt.Errorf("epoch switch block %v not found", info.EpochSwitchBlockInfo.Number)
+		}
+		proof.EpochSwitches = append(proof.EpochSwitches, epochSwitch)
+	}
+	return proof, api.proveCommittees(proof, slices.Concat(proof.EpochSwitches, proof.Headers[1:]))
+}
+
+// proveCommittees appends to the proof the committee proofs of the epoch
+// switch headers among the given ones, in order.
+func (api *API) proveCommittees(proof *utils.FinalityProof, headers []*types.Header) error {
+	for _, header := range headers {
+		switched, err := utils.IsEpochSwitchHeader(api.XDPoS.config, header)
+		if err != nil {
+			return err
+		}
+		if !switched {
+			continue
+		}
+		committee, err := api.committeeProof(header)
+		if err != nil {
+			return fmt.Errorf("epoch switch block %v: %w", header.Number, err)
+		}
+		proof.Committees = append(proof.Committees, committee)
+	}
+	return nil
+}
+
+// committeeProof proves the masternodes of an epoch switch header from the
+// state of the gap block of its epoch, which must still be available.
+func (api *API) committeeProof(header *types.Header) (*utils.CommitteeProof, error) {
+	config := api.XDPoS.config
+	proof := new(utils.CommitteeProof)
+	if header.Number.Uint64() == config.V2.SwitchBlock.Uint64()+1 {
+		return proof, nil
+	}
+	committee, err := utils.NewCommittee(config, header)
+	if err != nil {
+		return nil, err
+	}
+	gapNumber := committee.GapNumber(config)
+	for h := header; h.Number.Uint64() > gapNumber; {
+		if h = api.chain.GetHeader(h.ParentHash, h.Number.Uint64()-1); h == nil {
+			return nil, fmt.Errorf("gap block %d not found", gapNumber)
+		}
+		proof.GapHeaders = append(proof.GapHeaders, h)
+	}
+	slices.Reverse(proof.GapHeaders)
+
+	chain, ok := api.chain.(interface {
+		StateAt(root common.Hash) (*state.StateDB, error)
+	})
+	if !ok {
+		return nil, errors.New("state not available")
+	}
+	statedb, err := chain.StateAt(proof.GapHeaders[0].Root)
+	if err != nil {
+		return nil, err
+	}
+	if proof.State, err = utils.ProveCandidates(statedb); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
 func (api *API) NetworkInformation() NetworkInformation {
 	info := NetworkInformation{}
 	info.NetworkId = api.chain.Config().ChainId
@@ -394,24 +566,32 @@ func calculateSigners(message map[string]SignerTypes, pool map[string]map[common
 	}
 }
 
+// GetRewardByAccount returns the rewards an account received as a masternode,
+// protector or observer within [begin, end], with their total. The epochs are
+// looked up in the account reward index of the chain database: epochs that did
+// not reward the account are no longer listed with an empty reward, and only
+// canonical epoch switch blocks are returned, rewards of reorged blocks are
+// kept in the database but skipped.
 func (api *API) GetRewardByAccount(account common.Address, begin rpc.BlockNumber, end rpc.BlockNumber) (AccountRewardResponse, error) {
-	rewardFileNames, err := api.getRewardFileNamesInRange(&begin, &end)
-	if err != nil {
-		return AccountRewardResponse{}, err
+	beginHeader := api.getHeaderFromApiBlockNum(&begin)
+	if beginHeader == nil {
+		return AccountRewardResponse{}, errors.New("illegal begin block number")
+	}
+	endHeader := api.getHeaderFromApiBlockNum(&end)
+	if endHeader == nil {
+		return AccountRewardResponse{}, errors.New("illegal end block number")
+	}
+	if beginHeader.Number.Cmp(endHeader.Number) > 0 {
+		return AccountRewardResponse{}, errors.New("illegal begin and end block number, begin > end")
+	}
+	diff := new(big.Int).Sub(endHeader.Number, beginHeader.Number).Int64()
+	if diff > 1_500_000 {
+		return AccountRewardResponse{}, errors.New("block range over limit of 1,500,000 blocks")
 	}
 
 	epochRewards := []AccountEpochReward{}
-	for _, fileName := range rewardFileNames {
-		header := api.chain.GetHeaderByHash(fileName.epochBlockHash)
-		if header == nil {
-			// this is the case when there is chain rollback but the reward files of the old chain still remain, skip the reward of unknown blockhash
-			continue
-		}
-		if int(header.Number.Int64()) != fileName.epochBlockNum {
-			log.Error("[GetRewardByAccount] block number mismatch in reward filename", "reward file blocknum", fileName.epochBlockNum, "header blocknum", int(header.Number.Int64()), "blockhash", header.Hash())
-			return AccountRewardResponse{}, errors.New("reward file block number mismatch")
-		}
-		epochReward, err := getEpochReward(account, header)
+	for _, header := range api.accountRewardHeaders(account, beginHeader.Number.Uint64(), endHeader.Number.Uint64()) {
+		epochReward, err := api.getEpochReward(account, header)
 		if err != nil {
 			return AccountRewardResponse{}, err
 		}
@@ -447,85 +627,130 @@ func (api *API) GetRewardByAccount(account common.Address, begin rpc.BlockNumber
 	return response, nil
 }
 
-func (api *API) getRewardFileNamesInRange(begin, end *rpc.BlockNumber) ([]rewardFileName, error) {
-	beginHeader := api.getHeaderFromApiBlockNum(begin)
-	if beginHeader == nil {
-		return nil, errors.New("illegal begin block number")
-	}
-	endHeader := api.getHeaderFromApiBlockNum(end)
-	if endHeader == nil {
-		return nil, errors.New("illegal end block number")
-	}
-	if beginHeader.Number.Cmp(endHeader.Number) > 0 {
-		return nil, errors.New("illegal begin and end block number, begin > end")
-	}
-	diff := new(big.Int).Sub(endHeader.Number, beginHeader.Number).Int64()
-	if diff < 0 {
-		return nil, errors.New("illegal begin and end block number, begin > end")
-	}
-	if diff > 1_500_000 {
-		return nil, errors.New("block range over limit of 1,500,000 blocks")
-	}
-	files, err := os.ReadDir(common.StoreRewardFolder)
+// GetRewardHistory returns the rewards an account received as a masternode,
+// protector or observer, oldest epoch first. At most limit epochs are returned
+// after skipping the first offset ones.
+func (api *API) GetRewardHistory(account common.Address, offset *int, limit *int) ([]AccountEpochReward, error) {
+	start, size := 0, defaultRewardPageSize
+	if offset != nil && *offset > 0 {
+		start = *offset
+	}
+	if limit != nil && *limit > 0 {
+		size = min(*limit, maxRewardPageSize)
+	}
+	head := api.chain.CurrentHeader()
+	if head == nil {
+		return nil, errors.New("current header not found")
+	}
+	// Walk the account index from genesis without loading it, stepping over
+	// the first start canonical epochs and stopping once the page is full
+	epochRewards := make([]AccountEpochReward, 0, min(size, 64))
+	var err error
+	api.iterateRewardHeaders(account, 0, head.Number.Uint64(), func(header *types.Header) bool {
+		if start > 0 {
+			start--
+			return true
+		}
+		var epochReward AccountEpochReward
+		if epochReward, err = api.getEpochReward(account, header); err != nil {
+			return false
+		}
+		epochRewards = append(epochRewards, epochReward)
+		return len(epochRewards) < size
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	var rewardFileNames = []rewardFileName{}
-	for _, file := range files {
-		if !file.IsDir() {
-			filePrefix, fileSuffix, found := strings.Cut(file.Name(), ".")
-			if found {
-				filePrefixInt, err := strconv.Atoi(filePrefix)
-				if err != nil {
-					log.Warn("[getEpochNumbersFromRewardFiles] found unknown filename format in rewards folder")
-					return nil, err
-				}
-				fileSuffixHash := common.HexToHash(fileSuffix)
-				rewardName := rewardFileName{
-					epochBlockNum:  filePrefixInt,
-					epochBlockHash: fileSuffixHash,
-				}
-				rewardFileNames = append(rewardFileNames, rewardName)
-			}
+	return epochRewards, nil
+}
+
+// GetForensicsReports returns the forensic proofs persisted by the node within
+// the requested rounds, lowest round first, optionally only those blaming a
+// signer. At most limit reports are returned after skipping the first offset
+// ones.
+func (api *API) GetForensicsReports(args *ForensicsReportsArgs) ([]*types.ForensicsReport, error) {
+	var (
+		from, to    = uint64(0), uint64(math.MaxUint64)
+		skip, limit = 0, defaultForensicsPageSize
+	)
+	if args != nil {
+		if args.FromRound != nil {
+			from = uint64(*args.FromRound)
+		}
+		if args.ToRound != nil {
+			to = uint64(*args.ToRound)
+		}
+		if args.Offset != nil && *args.Offset > 0 {
+			skip = *args.Offset
 		}
+		if args.Limit != nil && *args.Limit > 0 {
+			limit = min(*args.Limit, maxForensicsPageSize)
+		}
+	}
+	if from > to {
+		return nil, errors.New("fromRound must not be greater than toRound")
+	}
+	var reports []*types.ForensicsReport
+	if args != nil && args.Signer != nil {
+		reports = rawdb.ReadSignerForensicsReports(api.XDPoS.db, *args.Signer, from, to, skip, limit)
+	} else {
+		reports = rawdb.ReadForensicsReports(api.XDPoS.db, from, to, skip, limit)
 	}
+	if reports == nil {
+		reports = []*types.ForensicsReport{}
+	}
+	return reports, nil
+}
 
-	sort.Slice(rewardFileNames, func(i, j int) bool {
-		return rewardFileNames[i].epochBlockNum < rewardFileNames[j].epochBlockNum
+// accountRewardHeaders returns the headers of the canonical epoch switch blocks
+// within [from, to] that rewarded an account.
+func (api *API) accountRewardHeaders(account common.Address, from, to uint64) []*types.Header {
+	var headers []*types.Header
+	api.iterateRewardHeaders(account, from, to, func(header *types.Header) bool {
+		headers = append(headers, header)
+		return true
 	})
+	return headers
+}
 
-	epochNumbers := make([]int, len(rewardFileNames))
-	for i, obj := range rewardFileNames {
-		epochNumbers[i] = obj.epochBlockNum
-	}
-
-	startIndex := sort.SearchInts(epochNumbers, int(beginHeader.Number.Int64()))
-	endIndex := sort.SearchInts(epochNumbers, int(endHeader.Number.Int64()))
-	if endIndex == len(epochNumbers) {
-		endIndex-- //this is to prevent endIndex out of bounds when endInput is higher than last reward(epoch) block but lower than latest block
-	}
+// iterateRewardHeaders calls fn with the header of every canonical epoch switch
+// block within [from, to] that rewarded an account, in ascending order, until
+// fn returns false.
+func (api *API) iterateRewardHeaders(account common.Address, from, to uint64, fn func(*types.Header) bool) {
+	var last *types.Header
+	rawdb.IterateAccountRewardEntries(api.XDPoS.db, account, from, to, func(entry rawdb.RewardEntry) bool {
+		if last != nil && last.Number.Uint64() == entry.Number {
+			return true
+		}
+		header := api.chain.GetHeaderByNumber(entry.Number)
+		if header == nil {
+			return true
+		}
+		// Rewards of a block sealed locally are stored under its hash without
+		// the validator signature, rewards of reorged blocks are skipped
+		if header.Hash() != entry.Hash && header.HashNoValidator() != entry.Hash {
+			return true
+		}
+		last = header
+		return fn(header)
+	})
+}
 
-	var rewardfileNamesInRange []rewardFileName
-	for i := startIndex; i <= endIndex; i++ {
-		rewardfileNamesInRange = append(rewardfileNamesInRange, rewardFileNames[i])
+// readRewards retrieves the json encoded rewards of an epoch switch block.
+func (api *API) readRewards(header *types.Header) []byte {
+	if data := rawdb.ReadRewards(api.XDPoS.db, header.Number.Uint64(), header.Hash()); len(data) > 0 {
+		return data
 	}
-	return rewardfileNamesInRange, nil
+	return rawdb.ReadRewards(api.XDPoS.db, header.Number.Uint64(), header.HashNoValidator())
 }
 
-func getEpochReward(account common.Address, header *types.Header) (AccountEpochReward, error) {
-	path := filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.Hash().Hex())
-	file, err := os.Open(path)
-	if err != nil {
-		alternatePath := filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.HashNoValidator().Hex())
-		file, err = os.Open(alternatePath)
-		if err != nil {
-			log.Warn("[getEpochReward] rewards file not found", "path", path, "alternatePath", alternatePath)
-			return AccountEpochReward{}, err
-		}
+func (api *API) getEpochReward(account common.Address, header *types.Header) (AccountEpochReward, error) {
+	rewards := api.readRewards(header)
+	if len(rewards) == 0 {
+		log.Warn("[getEpochReward] rewards not found", "number", header.Number, "hash", header.Hash())
+		return AccountEpochReward{}, errRewardsNotFound
 	}
-	defer file.Close()
-	decoder := json.NewDecoder(file)
+	decoder := json.NewDecoder(bytes.NewReader(rewards))
 	decoder.UseNumber()
 
 	var data map[string]interface{}
@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -19,12 +17,14 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDPoSChain/consensus/clique"
 	"github.com/XinFinOrg/XDPoSChain/consensus/misc/eip1559"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
 	"github.com/XinFinOrg/XDPoSChain/core/state"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/crypto"
 	"github.com/XinFinOrg/XDPoSChain/ethdb"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
 	"github.com/XinFinOrg/XDPoSChain/trie"
 )
 
@@ -49,9 +49,10 @@ type XDPoS_v1 struct {
 	verifiedHeaders     *lru.Cache[common.Hash, struct{}]
 	proposals           map[common.Address]bool // Current list of proposals we are pushing
 
-	signer common.Address  // Ethereum address of the signing key
-	signFn clique.SignerFn // Signer function to authorize hashes with
-	lock   sync.RWMutex    // Protects the signer fields
+	signer     common.Address   // Ethereum address of the signing key
+	signFn     clique.SignerFn  // Signer function to authorize hashes with
+	signDataFn utils.SignDataFn // Signer function to authorize hash preimages with, used over signFn if set
+	lock       sync.RWMutex     // Protects the signer fields
 
 	HookReward            func(chain consensus.ChainReader, state *state.StateDB, parentState *state.StateDB, header *types.Header) (map[string]interface{}, error)
 	HookPenalty           func(chain consensus.ChainReader, blockNumberEpoc uint64) ([]common.Address, error)
@@ -823,36 +824,44 @@ func (x *XDPoS_v1) UpdateMasternodes(chain consensus.ChainReader, header *types.
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given, and returns the final block.
 func (x *XDPoS_v1) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, parentState *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
-	// set block reward
-	number := header.Number.Uint64()
-	rCheckpoint := chain.Config().XDPoS.RewardCheckpoint
-
 	// _ = c.CacheData(header, txs, receipts)
 
-	if x.HookReward != nil && number%rCheckpoint == 0 {
-		rewards, err := x.HookReward(chain, state, parentState, header)
-		if err != nil {
-			return nil, err
-		}
-		if len(common.StoreRewardFolder) > 0 {
-			data, err := json.Marshal(rewards)
-			if err == nil {
-				err = os.WriteFile(filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.Hash().Hex()), data, 0644)
-			}
-			if err != nil {
-				log.Error("Error when save reward info ", "number", header.Number, "hash", header.Hash().Hex(), "err", err)
-			}
-		}
+	// set block reward
+	rewards, err := x.ApplyRewards(chain, header, state, parentState)
+	if err != nil {
+		return nil, err
 	}
 
 	// the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 
+	// Store the rewards once the header is complete, so the block can be found
+	// by its hash without validator signature
+	if common.StoreReward && rewards != nil {
+		data, err := json.Marshal(rewards)
+		if err == nil {
+			err = rawdb.WriteRewards(x.db, header.Number.Uint64(), header.Hash(), data)
+		}
+		if err != nil {
+			log.Error("Error when save reward info ", "number", header.Number, "hash", header.Hash().Hex(), "err", err)
+		}
+	}
+
 	// Assemble and return the final block for sealing
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
 }
 
+// ApplyRewards credits the checkpoint rewards to the state if the header is a
+// reward checkpoint, returning the rewards as recorded by the reward hook.
+func (x *XDPoS_v1) ApplyRewards(chain consensus.ChainReader, header *types.Header, state *state.StateDB, parentState *state.StateDB) (map[string]interface{}, error) {
+	rCheckpoint := chain.Config().XDPoS.RewardCheckpoint
+	if x.HookReward == nil || header.Number.Uint64()%rCheckpoint != 0 {
+		return nil, nil
+	}
+	return x.HookReward(chain, state, parentState, header)
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 func (x *XDPoS_v1) Authorize(signer common.Address, signFn clique.SignerFn) {
@@ -861,6 +870,18 @@ func (x *XDPoS_v1) Authorize(signer common.Address, signFn clique.SignerFn) {
 
 	x.signer = signer
 	x.signFn = signFn
+	x.signDataFn = nil
+}
+
+// AuthorizeData injects a signer that is handed the RLP preimage of the header
+// hash instead of the hash itself, as external signers require.
+func (x *XDPoS_v1) AuthorizeData(signer common.Address, signDataFn utils.SignDataFn) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	x.signer = signer
+	x.signFn = nil
+	x.signDataFn = signDataFn
 }
 
 // Seal implements consensus.Engine, attempting to create a sealed block using
@@ -880,7 +901,7 @@ func (x *XDPoS_v1) Seal(chain consensus.ChainReader, block *types.Block, stop <-
 	}
 	// Don't hold the signer fields for the entire sealing procedure
 	x.lock.RLock()
-	signer, signFn := x.signer, x.signFn
+	signer, signFn, signDataFn := x.signer, x.signFn, x.signDataFn
 	x.lock.RUnlock()
 
 	// Bail out if we're unauthorized to sign a block
@@ -925,9 +946,21 @@ func (x *XDPoS_v1) Seal(chain consensus.ChainReader, block *types.Block, stop <-
 	default:
 	}
 	// Sign all the things!
-	sighash, err := signFn(accounts.Account{Address: signer}, x.SigHash(header).Bytes())
-	if err != nil {
-		return nil, err
+	var sighash []byte
+	if signDataFn != nil {
+		enc, err := rlp.EncodeToBytes(sigHashFields(header))
+		if err != nil {
+			return nil, err
+		}
+		sighash, err = signDataFn(accounts.Account{Address: signer}, accounts.MimetypeXDPoSV1Header, enc)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		sighash, err = signFn(accounts.Account{Address: signer}, x.SigHash(header).Bytes())
+		if err != nil {
+			return nil, err
+		}
 	}
 	copy(header.Extra[len(header.Extra)-utils.ExtraSeal:], sighash)
 	m2, err := x.GetValidator(signer, chain, header)
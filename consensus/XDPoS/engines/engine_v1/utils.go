@@ -59,7 +59,13 @@ func getM1M2(masternodes []common.Address, validators []int64, currentHeader *ty
 
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
+	rlp.Encode(hasher, sigHashFields(header))
+	hasher.Sum(hash[:0])
+	return hash
+}
 
+// sigHashFields returns the header fields whose RLP encoding is hashed by sigHash.
+func sigHashFields(header *types.Header) []interface{} {
 	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
@@ -80,9 +86,7 @@ func sigHash(header *types.Header) (hash common.Hash) {
 	if header.BaseFee != nil {
 		enc = append(enc, header.BaseFee)
 	}
-	rlp.Encode(hasher, enc)
-	hasher.Sum(hash[:0])
-	return hash
+	return enc
 }
 
 // ecrecover extracts the Ethereum account address from a signed header.
@@ -5,8 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -17,6 +15,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDPoSChain/consensus/clique"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
 	"github.com/XinFinOrg/XDPoSChain/core/state"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/ethdb"
@@ -42,12 +41,14 @@ type XDPoS_v2 struct {
 	// input: round, output: infos of epoch switch block and next epoch switch block info
 	round2epochBlockInfo *lru.Cache[types.Round, *types.BlockInfo]
 
-	signer   common.Address  // Ethereum address of the signing key
-	signFn   clique.SignerFn // Signer function to authorize hashes with
-	lock     sync.RWMutex    // Protects the signer fields
-	signLock sync.RWMutex    // Protects the signer fields
+	signer     common.Address   // Ethereum address of the signing key
+	signFn     clique.SignerFn  // Signer function to authorize hashes with
+	signDataFn utils.SignDataFn // Signer function to authorize hash preimages with, used over signFn if set
+	lock       sync.RWMutex     // Protects the signer fields
+	signLock   sync.RWMutex     // Protects the signer fields
 
 	BroadcastCh  chan interface{}
+	broadcastFn  func(msg interface{}) // Replaces BroadcastCh for outgoing messages, test only
 	minePeriodCh chan int
 	newRoundCh   chan types.Round
 
@@ -72,6 +73,8 @@ type XDPoS_v2 struct {
 	ForensicsProcessor *Forensics
 
 	votePoolCollectionTime time.Time
+	roundStartTime         time.Time   // Time the current round started, for the round duration metric
+	highestMissedRound     types.Round // Highest round accounted in the missed rounds metrics
 }
 
 func New(chainConfig *params.ChainConfig, db ethdb.Database, minePeriodCh chan int, newRoundCh chan types.Round) *XDPoS_v2 {
@@ -126,7 +129,7 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database, minePeriodCh chan i
 		},
 		highestVotedRound:  types.Round(0),
 		highestCommitBlock: nil,
-		ForensicsProcessor: NewForensics(),
+		ForensicsProcessor: NewForensics(db),
 	}
 	// Add callback to the timer
 	timeoutTimer.OnTimeoutFn = engine.OnCountdownTimeout
@@ -366,6 +369,16 @@ func (x *XDPoS_v2) Prepare(chain consensus.ChainReader, header *types.Header) er
 		for _, v := range penalties {
 			header.Penalties = append(header.Penalties, v[:]...)
 		}
+		// Record protectors and observers of the epoch alongside the round and QC
+		extra.Roles, err = x.calcEpochRoles(chain, header.Number, masterNodes, penalties, currentRound)
+		if err != nil {
+			return err
+		}
+		if extra.Roles != nil {
+			if header.Extra, err = extra.EncodeToBytes(); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Mix digest is reserved for now, set to empty
@@ -391,36 +404,45 @@ func (x *XDPoS_v2) Prepare(chain consensus.ChainReader, header *types.Header) er
 // rewards given, and returns the final block.
 func (x *XDPoS_v2) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, parentState *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
 	// set block reward
-
-	isEpochSwitch, _, err := x.IsEpochSwitch(header)
+	rewards, err := x.ApplyRewards(chain, header, state, parentState)
 	if err != nil {
-		log.Error("[Finalize] IsEpochSwitch bug!", "err", err)
 		return nil, err
 	}
-	if x.HookReward != nil && isEpochSwitch {
-		rewards, err := x.HookReward(chain, state, parentState, header)
-		if err != nil {
-			return nil, err
-		}
-		if len(common.StoreRewardFolder) > 0 {
-			data, err := json.Marshal(rewards)
-			if err == nil {
-				err = os.WriteFile(filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.Hash().Hex()), data, 0644)
-			}
-			if err != nil {
-				log.Error("Error when save reward info ", "number", header.Number, "hash", header.Hash().Hex(), "err", err)
-			}
-		}
-	}
 
 	// the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 
+	// Store the rewards once the header is complete, so the block can be found
+	// by its hash without validator signature
+	if common.StoreReward && rewards != nil {
+		data, err := json.Marshal(rewards)
+		if err == nil {
+			err = rawdb.WriteRewards(x.db, header.Number.Uint64(), header.Hash(), data)
+		}
+		if err != nil {
+			log.Error("Error when save reward info ", "number", header.Number, "hash", header.Hash().Hex(), "err", err)
+		}
+	}
+
 	// Assemble and return the final block for sealing
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
 }
 
+// ApplyRewards credits the epoch rewards to the state if the header is an epoch
+// switch block, returning the rewards as recorded by the reward hook.
+func (x *XDPoS_v2) ApplyRewards(chain consensus.ChainReader, header *types.Header, state *state.StateDB, parentState *state.StateDB) (map[string]interface{}, error) {
+	isEpochSwitch, _, err := x.IsEpochSwitch(header)
+	if err != nil {
+		log.Error("[Finalize] IsEpochSwitch bug!", "err", err)
+		return nil, err
+	}
+	if x.HookReward == nil || !isEpochSwitch {
+		return nil, nil
+	}
+	return x.HookReward(chain, state, parentState, header)
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks with.
 func (x *XDPoS_v2) Authorize(signer common.Address, signFn clique.SignerFn) {
 	x.signLock.Lock()
@@ -428,6 +450,18 @@ func (x *XDPoS_v2) Authorize(signer common.Address, signFn clique.SignerFn) {
 
 	x.signer = signer
 	x.signFn = signFn
+	x.signDataFn = nil
+}
+
+// AuthorizeData injects a signer that is handed the RLP preimage of every block,
+// vote and timeout hash instead of the hash itself, as external signers require.
+func (x *XDPoS_v2) AuthorizeData(signer common.Address, signDataFn utils.SignDataFn) {
+	x.signLock.Lock()
+	defer x.signLock.Unlock()
+
+	x.signer = signer
+	x.signFn = nil
+	x.signDataFn = signDataFn
 }
 
 func (x *XDPoS_v2) Author(header *types.Header) (common.Address, error) {
@@ -445,11 +479,6 @@ func (x *XDPoS_v2) Seal(chain consensus.ChainReader, block *types.Block, stop <-
 		return nil, utils.ErrUnknownBlock
 	}
 
-	// Don't hold the signer fields for the entire sealing procedure
-	x.signLock.RLock()
-	signer, signFn := x.signer, x.signFn
-	x.signLock.RUnlock()
-
 	select {
 	case <-stop:
 		return nil, nil
@@ -457,7 +486,7 @@ func (x *XDPoS_v2) Seal(chain consensus.ChainReader, block *types.Block, stop <-
 	}
 
 	// Sign all the things!
-	signature, err := signFn(accounts.Account{Address: signer}, sigHash(header).Bytes())
+	signature, err := x.signPayload(accounts.MimetypeXDPoSHeader, sigHashFields(header))
 	if err != nil {
 		return nil, err
 	}
@@ -593,6 +622,7 @@ func (x *XDPoS_v2) ProposedBlockHandler(chain consensus.ChainReader, blockHeader
 		log.Error("[ProposedBlockHandler] Fail to processQC", "QC proposed blockInfo round number", quorumCert.ProposedBlockInfo.Round, "QC proposed blockInfo hash", quorumCert.ProposedBlockInfo.Hash)
 		return err
 	}
+	x.updateMissedRounds(chain, blockHeader, quorumCert.ProposedBlockInfo.Round, round)
 
 	allow := x.allowedToSend(chain, blockHeader, "vote")
 	if !allow {
@@ -740,6 +770,7 @@ func (x *XDPoS_v2) processQC(blockChainReader consensus.ChainReader, incomingQuo
 	if incomingQuorumCert.ProposedBlockInfo.Round > x.highestQuorumCert.ProposedBlockInfo.Round {
 		log.Debug("[processQC] update x.highestQuorumCert", "blockNum", incomingQuorumCert.ProposedBlockInfo.Number, "round", incomingQuorumCert.ProposedBlockInfo.Round, "hash", incomingQuorumCert.ProposedBlockInfo.Hash)
 		x.highestQuorumCert = incomingQuorumCert
+		qcRoundGauge.Update(int64(incomingQuorumCert.ProposedBlockInfo.Round))
 	}
 	// 2. Get QC from header and update lockQuorumCert(lockQuorumCert is the parent of highestQC)
 	proposedBlockHeader := blockChainReader.GetHeaderByHash(incomingQuorumCert.ProposedBlockInfo.Hash)
@@ -783,6 +814,11 @@ func (x *XDPoS_v2) setNewRound(blockChainReader consensus.ChainReader, round typ
 	log.Info("[setNewRound] new round and reset pools and workers", "round", round)
 	x.currentRound = round
 	x.timeoutCount = 0
+	if !x.roundStartTime.IsZero() {
+		roundTimer.UpdateSince(x.roundStartTime)
+	}
+	x.roundStartTime = time.Now()
+	roundGauge.Update(int64(round))
 	x.timeoutWorker.Reset(blockChainReader, x.currentRound, x.highestQuorumCert.ProposedBlockInfo.Round)
 	// don't need to clean pool, we have other process to clean and it's not good to clean here, some edge case may break
 	// for example round gets bump during collecting vote, so we have to keep vote.
@@ -795,6 +831,10 @@ func (x *XDPoS_v2) setNewRound(blockChainReader consensus.ChainReader, round typ
 }
 
 func (x *XDPoS_v2) broadcastToBftChannel(msg interface{}) {
+	if x.broadcastFn != nil {
+		x.broadcastFn(msg)
+		return
+	}
 	go func() {
 		x.BroadcastCh <- msg
 	}()
@@ -849,6 +889,12 @@ func (x *XDPoS_v2) commitBlocks(blockChainReader consensus.ChainReader, proposed
 		Round:  round,
 	}
 	log.Info("Successfully commit and confirm block from continuous 3 blocks", "num", x.highestCommitBlock.Number, "round", x.highestCommitBlock.Round, "hash", x.highestCommitBlock.Hash)
+	committedNumberGauge.Update(x.highestCommitBlock.Number.Int64())
+	committedRoundGauge.Update(int64(x.highestCommitBlock.Round))
+	// Committed blocks can no longer be reorged, let the chain freezer know
+	if x.db != nil {
+		rawdb.WriteFinalizedBlockHash(x.db, x.highestCommitBlock.Hash)
+	}
 	// Perform forensics related operation
 	headerQcToBeCommitted := []types.Header{*parentBlock, *proposedBlockHeader}
 	go x.ForensicsProcessor.ForensicsMonitoring(blockChainReader, x, headerQcToBeCommitted, *incomingQc)
@@ -869,6 +915,19 @@ func (x *XDPoS_v2) GetMasternodesFromEpochSwitchHeader(epochSwitchHeader *types.
 	return masternodes
 }
 
+// Get protectors and observers over extra data of epoch switch block, nil if the block does not record them.
+func (x *XDPoS_v2) GetEpochRolesFromEpochSwitchHeader(epochSwitchHeader *types.Header) *types.EpochRoles {
+	if epochSwitchHeader == nil || epochSwitchHeader.Number.Cmp(x.config.V2.SwitchBlock) <= 0 {
+		return nil
+	}
+	var decodedExtraField types.ExtraFields_v2
+	if err := utils.DecodeBytesExtraFields(epochSwitchHeader.Extra, &decodedExtraField); err != nil {
+		log.Error("[GetEpochRolesFromEpochSwitchHeader] error on decode extra fields", "err", err, "number", epochSwitchHeader.Number)
+		return nil
+	}
+	return decodedExtraField.Roles
+}
+
 // Given header, get master node from the epoch switch block of that epoch
 func (x *XDPoS_v2) GetMasternodes(chain consensus.ChainReader, header *types.Header) []common.Address {
 	epochSwitchInfo, err := x.getEpochSwitchInfo(chain, header, header.Hash())
@@ -898,6 +957,26 @@ func (x *XDPoS_v2) GetStandbynodes(chain consensus.ChainReader, header *types.He
 	return epochSwitchInfo.Standbynodes
 }
 
+// Given header, get protectors from the epoch switch block of that epoch
+func (x *XDPoS_v2) GetProtectors(chain consensus.ChainReader, header *types.Header) []common.Address {
+	epochSwitchInfo, err := x.getEpochSwitchInfo(chain, header, header.Hash())
+	if err != nil {
+		log.Error("[GetProtectors] Adaptor v2 getEpochSwitchInfo has error", "err", err)
+		return []common.Address{}
+	}
+	return epochSwitchInfo.Protectors
+}
+
+// Given header, get observers from the epoch switch block of that epoch
+func (x *XDPoS_v2) GetObservers(chain consensus.ChainReader, header *types.Header) []common.Address {
+	epochSwitchInfo, err := x.getEpochSwitchInfo(chain, header, header.Hash())
+	if err != nil {
+		log.Error("[GetObservers] Adaptor v2 getEpochSwitchInfo has error", "err", err)
+		return []common.Address{}
+	}
+	return epochSwitchInfo.Observers
+}
+
 // Calculate masternodes for a block number and parent hash. In V2, truncating candidates[:MaxMasternodes] is done in this function.
 func (x *XDPoS_v2) calcMasternodes(chain consensus.ChainReader, blockNum *big.Int, parentHash common.Hash, round types.Round) ([]common.Address, []common.Address, error) {
 	// using new max masterndoes
@@ -930,14 +1009,26 @@ func (x *XDPoS_v2) calcMasternodes(chain consensus.ChainReader, blockNum *big.In
 		log.Error("[calcMasternodes] Adaptor v2 HookPenalty has error", "err", err)
 		return nil, nil, err
 	}
-	masternodes := common.RemoveItemFromArray(candidates, penalties)
-	if len(masternodes) > maxMasternodes {
-		masternodes = masternodes[:maxMasternodes]
-	}
+	masternodes := utils.SelectMasternodes(candidates, penalties, maxMasternodes)
 
 	return masternodes, penalties, nil
 }
 
+// Calculate protectors and observers for an epoch switch block from the
+// candidates of its snapshot. Returns nil before TIPEpochRoles.
+func (x *XDPoS_v2) calcEpochRoles(chain consensus.ChainReader, blockNum *big.Int, masternodes, penalties []common.Address, round types.Round) (*types.EpochRoles, error) {
+	if !chain.Config().IsTIPEpochRoles(blockNum) || blockNum.Uint64() == x.config.V2.SwitchBlock.Uint64()+1 {
+		return nil, nil
+	}
+	config := x.config.V2.Config(uint64(round))
+	snap, err := x.getSnapshot(chain, blockNum.Uint64(), false)
+	if err != nil {
+		log.Error("[calcEpochRoles] Adaptor v2 getSnapshot has error", "err", err)
+		return nil, err
+	}
+	return utils.SelectEpochRoles(snap.NextEpochCandidates, masternodes, penalties, config.MaxProtectorNodes, config.MaxObverserNodes), nil
+}
+
 // Given hash, get master node from the epoch switch block of the epoch
 func (x *XDPoS_v2) GetMasternodesByHash(chain consensus.ChainReader, hash common.Hash) []common.Address {
 	epochSwitchInfo, err := x.getEpochSwitchInfo(chain, nil, hash)
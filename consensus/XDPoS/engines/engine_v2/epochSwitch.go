@@ -60,6 +60,8 @@ func (x *XDPoS_v2) getEpochSwitchInfo(chain consensus.ChainReader, header *types
 				Standbynodes:   standbynodes,
 				Masternodes:    masternodes,
 				MasternodesLen: len(masternodes),
+				Protectors:     []common.Address{},
+				Observers:      []common.Address{},
 				EpochSwitchBlockInfo: &types.BlockInfo{
 					Hash:   hash,
 					Number: h.Number,
@@ -88,11 +90,20 @@ func (x *XDPoS_v2) getEpochSwitchInfo(chain consensus.ChainReader, header *types
 			standbynodes = common.RemoveItemFromArray(standbynodes, penalties)
 		}
 
+		protectors := []common.Address{}
+		observers := []common.Address{}
+		if roles := x.GetEpochRolesFromEpochSwitchHeader(h); roles != nil {
+			protectors = append(protectors, roles.Protectors...)
+			observers = append(observers, roles.Observers...)
+		}
+
 		epochSwitchInfo := &types.EpochSwitchInfo{
 			Penalties:      penalties,
 			Standbynodes:   standbynodes,
 			Masternodes:    masternodes,
 			MasternodesLen: len(masternodes),
+			Protectors:     protectors,
+			Observers:      observers,
 			EpochSwitchBlockInfo: &types.BlockInfo{
 				Hash:   hash,
 				Number: h.Number,
@@ -6,32 +6,39 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
 	"github.com/XinFinOrg/XDPoSChain/event"
 	"github.com/XinFinOrg/XDPoSChain/log"
 )
 
 const (
 	NUM_OF_FORENSICS_QC = 3
+
+	forensicsTypeQC   = "QC"
+	forensicsTypeVote = "Vote"
 )
 
 // Forensics instance. Placeholder for future properties to be added
 type Forensics struct {
 	HighestCommittedQCs []types.QuorumCert
+	db                  ethdb.KeyValueWriter // Database the proofs are persisted into, nil to only send them out
 	forensicsFeed       event.Feed
 	scope               event.SubscriptionScope
 }
 
 // Initiate a forensics process
-func NewForensics() *Forensics {
-	return &Forensics{}
+func NewForensics(db ethdb.KeyValueWriter) *Forensics {
+	return &Forensics{db: db}
 }
 
 // SubscribeForensicsEvent registers a subscription of ForensicsEvent and
@@ -114,21 +121,23 @@ func (f *Forensics) ProcessForensics(chain consensus.ChainReader, engine *XDPoS_
 	foundSameRoundQC, sameRoundHCQC, sameRoundQC := f.findQCsInSameRound(highestCommittedQCs, incomingQuorunCerts)
 
 	if foundSameRoundQC {
-		f.SendForensicProof(chain, engine, sameRoundHCQC, sameRoundQC)
+		f.SendForensicProof(chain, engine, sameRoundHCQC, sameRoundQC, highestCommittedQCs)
 	} else {
 		// Not found, need a more complex approach to find the two QC
 		ancestorQC, lowerRoundQCs, _, err := f.findAncestorQcThroughRound(chain, highestCommittedQCs, incomingQuorunCerts)
 		if err != nil {
 			log.Error("[ProcessForensics] Error while trying to find ancestor QC through round number", "err", err)
 		}
-		f.SendForensicProof(chain, engine, ancestorQC, lowerRoundQCs[NUM_OF_FORENSICS_QC-1])
+		f.SendForensicProof(chain, engine, ancestorQC, lowerRoundQCs[NUM_OF_FORENSICS_QC-1], highestCommittedQCs)
 	}
 
 	return nil
 }
 
 // Last step of forensics which sends out detailed proof to report service.
-func (f *Forensics) SendForensicProof(chain consensus.ChainReader, engine *XDPoS_v2, firstQc types.QuorumCert, secondQc types.QuorumCert) error {
+// committedQCs are the QCs of three consecutive rounds committing the fork one
+// of the two QCs is on, they are the evidence needed to blame signers.
+func (f *Forensics) SendForensicProof(chain consensus.ChainReader, engine *XDPoS_v2, firstQc types.QuorumCert, secondQc types.QuorumCert, committedQCs []types.QuorumCert) error {
 	// Re-order the QC by its round number to make the function cleaner.
 	lowerRoundQC := firstQc
 	higherRoundQC := secondQc
@@ -182,6 +191,7 @@ func (f *Forensics) SendForensicProof(chain consensus.ChainReader, engine *XDPoS
 			QuorumCert:      higherRoundQC,
 			SignerAddresses: f.getQcSignerAddresses(higherRoundQC),
 		},
+		CommittedQCs: committedQCs,
 	})
 
 	if err != nil {
@@ -191,14 +201,96 @@ func (f *Forensics) SendForensicProof(chain consensus.ChainReader, engine *XDPoS
 
 	forensicsProof := &types.ForensicProof{
 		Id:            generateForensicsId(ancestorHash.Hex(), &lowerRoundQC, &higherRoundQC),
-		ForensicsType: "QC",
+		ForensicsType: forensicsTypeQC,
 		Content:       string(content),
 	}
+	// Only the signers the committed QCs prove at fault are blamed
+	var blamed []common.Address
+	conflicting := higherRoundQC
+	if isCommittedQC(committedQCs, higherRoundQC) {
+		conflicting = lowerRoundQC
+	}
+	if isCommittedQC(committedQCs, lowerRoundQC) || isCommittedQC(committedQCs, higherRoundQC) {
+		blamed, err = f.blameConflictingQC(chain, committedQCs, conflicting)
+		if err != nil {
+			log.Warn("[SendForensicProof] No signer can be blamed", "err", err)
+		}
+	}
+	f.storeProof(lowerRoundQC.ProposedBlockInfo.Round, blamed, forensicsProof)
+	forensicsQCCounter.Inc(1)
+
 	log.Info("Forensics proof report generated, sending to the stats server", "forensicsProof", forensicsProof)
 	go f.forensicsFeed.Send(types.ForensicsEvent{ForensicsProof: forensicsProof})
 	return nil
 }
 
+// storeProof persists a forensic proof, indexed by the lowest round involved
+// and by the signers it blames.
+func (f *Forensics) storeProof(round types.Round, signers []common.Address, proof *types.ForensicProof) {
+	if f.db == nil {
+		return
+	}
+	rawdb.WriteForensicsReport(f.db, &types.ForensicsReport{
+		Round:   round,
+		Signers: signers,
+		Proof:   proof,
+	})
+}
+
+// isCommittedQC reports whether the QC is one of the committed QCs.
+func isCommittedQC(committedQCs []types.QuorumCert, qc types.QuorumCert) bool {
+	for _, committedQC := range committedQCs {
+		if committedQC.ProposedBlockInfo.Hash == qc.ProposedBlockInfo.Hash && committedQC.ProposedBlockInfo.Round == qc.ProposedBlockInfo.Round {
+			return true
+		}
+	}
+	return false
+}
+
+// blameConflictingQC returns the signers of a QC on a fork conflicting with the
+// block committed by the QCs of rounds r, r+1 and r+2. A QC of one of these
+// rounds blames the masternodes having signed both QCs of the round. A QC of a
+// later round blames the signers of the QC of round r+2, locked on the QC of
+// round r, if its block extends a QC older than r.
+func (f *Forensics) blameConflictingQC(chain consensus.ChainReader, committedQCs []types.QuorumCert, qc types.QuorumCert) ([]common.Address, error) {
+	if len(committedQCs) != NUM_OF_FORENSICS_QC {
+		return nil, errors.New("QC forensics content misses the committed QCs")
+	}
+	committedRound := committedQCs[0].ProposedBlockInfo.Round
+	round := qc.ProposedBlockInfo.Round
+	if round < committedRound {
+		return nil, fmt.Errorf("QC of round %d is older than the committed round %d", round, committedRound)
+	}
+	var blamedBy types.QuorumCert
+	if round < committedRound+NUM_OF_FORENSICS_QC {
+		blamedBy = committedQCs[round-committedRound]
+	} else {
+		header := chain.GetHeaderByHash(qc.ProposedBlockInfo.Hash)
+		if header == nil {
+			return nil, fmt.Errorf("could not find the block %v of the QC of round %d", qc.ProposedBlockInfo.Hash.Hex(), round)
+		}
+		var extra types.ExtraFields_v2
+		if err := utils.DecodeBytesExtraFields(header.Extra, &extra); err != nil {
+			return nil, err
+		}
+		if extra.QuorumCert.ProposedBlockInfo.Round >= committedRound {
+			return nil, fmt.Errorf("block of the QC of round %d extends a QC not older than the committed round %d", round, committedRound)
+		}
+		blamedBy = committedQCs[NUM_OF_FORENSICS_QC-1]
+	}
+	signed := make(map[string]bool)
+	for _, signer := range f.getQcSignerAddresses(blamedBy) {
+		signed[signer] = true
+	}
+	var blamed []common.Address
+	for _, signer := range f.getQcSignerAddresses(qc) {
+		if signed[signer] {
+			blamed = append(blamed, common.HexToAddress(signer))
+		}
+	}
+	return blamed, nil
+}
+
 // Utils function to help find the n-th previous QC. It returns an array of QC in ascending order including the currentQc as the last item in the array
 func (f *Forensics) findAncestorQCs(chain consensus.ChainReader, currentQc types.QuorumCert, distanceFromCurrrentQc int) ([]types.QuorumCert, error) {
 	var quorumCerts []types.QuorumCert
@@ -418,7 +510,7 @@ func (f *Forensics) ProcessVoteEquivocation(chain consensus.ChainReader, engine
 		return nil
 	}
 	// Trigger the safety Alarm if failed
-	isVoteBlamed, parentQC, err := f.isVoteBlamed(chain, highestCommittedQCs, incomingVote)
+	isVoteBlamed, parentQC, err := f.isVoteBlamed(chain, highestCommittedQCs[NUM_OF_FORENSICS_QC-1].ProposedBlockInfo.Round, incomingVote)
 	if err != nil {
 		log.Error("[ProcessVoteEquivocation] Error while trying to call isVoteBlamed", "error", err)
 		return err
@@ -470,8 +562,14 @@ func (f *Forensics) isExtendingFromAncestor(blockChainReader consensus.ChainRead
 	return false, nil
 }
 
-func (f *Forensics) isVoteBlamed(chain consensus.ChainReader, highestCommittedQCs []types.QuorumCert, incomingVote *types.Vote) (bool, *types.QuorumCert, error) {
+// isVoteBlamed reports whether a vote breaks the lock of a masternode having
+// voted for a QC of lockedRound: the QC the voted block extends must not be of
+// a lower round. The QC of the voted block is returned as well.
+func (f *Forensics) isVoteBlamed(chain consensus.ChainReader, lockedRound types.Round, incomingVote *types.Vote) (bool, *types.QuorumCert, error) {
 	proposedBlock := chain.GetHeaderByHash(incomingVote.ProposedBlockInfo.Hash)
+	if proposedBlock == nil {
+		return false, nil, fmt.Errorf("could not find the voted block %v", incomingVote.ProposedBlockInfo.Hash)
+	}
 	var decodedExtraField types.ExtraFields_v2
 	err := utils.DecodeBytesExtraFields(proposedBlock.Extra, &decodedExtraField)
 	if err != nil {
@@ -479,12 +577,38 @@ func (f *Forensics) isVoteBlamed(chain consensus.ChainReader, highestCommittedQC
 		return false, nil, err
 	}
 	// Found the parent QC, if its round < hcqc3's round, return true
-	if decodedExtraField.QuorumCert.ProposedBlockInfo.Round < highestCommittedQCs[NUM_OF_FORENSICS_QC-1].ProposedBlockInfo.Round {
+	if decodedExtraField.QuorumCert.ProposedBlockInfo.Round < lockedRound {
 		return true, decodedExtraField.QuorumCert, nil
 	}
 	return false, decodedExtraField.QuorumCert, nil
 }
 
+// lockedRound returns the round of the QC a masternode voting for the block is
+// locked on, the QC carried by the parent block the voted block certifies.
+func (x *XDPoS_v2) lockedRound(chain consensus.ChainReader, blockInfo *types.BlockInfo) (types.Round, error) {
+	header := chain.GetHeaderByHash(blockInfo.Hash)
+	if header == nil {
+		return 0, fmt.Errorf("could not find the voted block %v", blockInfo.Hash.Hex())
+	}
+	var extra types.ExtraFields_v2
+	if err := utils.DecodeBytesExtraFields(header.Extra, &extra); err != nil {
+		return 0, err
+	}
+	parent := extra.QuorumCert.ProposedBlockInfo
+	// The switch block carries no QC, nothing is locked before it
+	if parent.Number.Cmp(x.config.V2.SwitchBlock) <= 0 {
+		return 0, nil
+	}
+	parentHeader := chain.GetHeaderByHash(parent.Hash)
+	if parentHeader == nil {
+		return 0, fmt.Errorf("could not find the certified block %v", parent.Hash.Hex())
+	}
+	if err := utils.DecodeBytesExtraFields(parentHeader.Extra, &extra); err != nil {
+		return 0, err
+	}
+	return extra.QuorumCert.ProposedBlockInfo.Round, nil
+}
+
 func (f *Forensics) DetectEquivocationInVotePool(vote *types.Vote, votePool *utils.Pool) {
 	return
 	poolKey := vote.PoolKey()
@@ -542,9 +666,12 @@ func (f *Forensics) SendVoteEquivocationProof(vote1, vote2 *types.Vote, signer c
 	}
 	forensicsProof := &types.ForensicProof{
 		Id:            generateVoteEquivocationId(signer, smallerRoundVote.ProposedBlockInfo.Round, largerRoundVote.ProposedBlockInfo.Round),
-		ForensicsType: "Vote",
+		ForensicsType: forensicsTypeVote,
 		Content:       string(content),
 	}
+	f.storeProof(smallerRoundVote.ProposedBlockInfo.Round, []common.Address{signer}, forensicsProof)
+	forensicsVoteCounter.Inc(1)
+
 	log.Info("Forensics proof report generated, sending to the stats server", "forensicsProof", forensicsProof)
 	go f.forensicsFeed.Send(types.ForensicsEvent{ForensicsProof: forensicsProof})
 	return nil
@@ -564,3 +691,249 @@ func GetVoteSignerAddresses(vote *types.Vote) (common.Address, error) {
 	copy(signerAddress[:], crypto.Keccak256(pubkey[1:])[12:])
 	return signerAddress, nil
 }
+
+// VerifyForensicsReport re-checks a forensics report: every signature in its
+// proof must come from a masternode of the epoch the signed block belongs to,
+// the signed messages must actually conflict and the report must be indexed by
+// the lowest round involved. A QC report must carry the QCs committing one of
+// the forks and the hash paths from the block the forks diverge at, a vote
+// report must show the later vote breaks the lock of the earlier one.
+func (x *XDPoS_v2) VerifyForensicsReport(chain consensus.ChainReader, report *types.ForensicsReport) error {
+	if report.Proof == nil {
+		return errors.New("forensics report without proof")
+	}
+	switch report.Proof.ForensicsType {
+	case forensicsTypeQC:
+		var content types.ForensicsContent
+		if err := json.Unmarshal([]byte(report.Proof.Content), &content); err != nil {
+			return fmt.Errorf("invalid QC forensics content: %v", err)
+		}
+		if content.SmallerRoundInfo == nil || content.LargerRoundInfo == nil {
+			return errors.New("QC forensics content misses a quorum cert")
+		}
+		if err := x.verifyCommittedQCs(chain, content.CommittedQCs); err != nil {
+			return err
+		}
+		for _, info := range []*types.ForensicsInfo{content.SmallerRoundInfo, content.LargerRoundInfo} {
+			qc := info.QuorumCert
+			if qc.ProposedBlockInfo == nil {
+				return errors.New("QC forensics content misses a proposed block")
+			}
+			signers, err := x.verifyForensicsQC(chain, &qc)
+			if err != nil {
+				return err
+			}
+			for _, signer := range info.SignerAddresses {
+				if !signers[common.HexToAddress(signer)] {
+					return fmt.Errorf("listed signer %s has no signature in the QC of round %d", signer, qc.ProposedBlockInfo.Round)
+				}
+			}
+		}
+		lowerQC, higherQC := content.SmallerRoundInfo.QuorumCert, content.LargerRoundInfo.QuorumCert
+		lower, higher := lowerQC.ProposedBlockInfo, higherQC.ProposedBlockInfo
+		if lower.Round > higher.Round || report.Round != lower.Round {
+			return fmt.Errorf("report round %d doesn't match the QC rounds %d and %d", report.Round, lower.Round, higher.Round)
+		}
+		var conflicting types.QuorumCert
+		switch {
+		case isCommittedQC(content.CommittedQCs, lowerQC):
+			conflicting = higherQC
+		case isCommittedQC(content.CommittedQCs, higherQC):
+			conflicting = lowerQC
+		default:
+			return errors.New("neither QC is one of the committed QCs")
+		}
+		if err := x.verifyForensicsHashPaths(chain, &content); err != nil {
+			return err
+		}
+		blamed, err := x.ForensicsProcessor.blameConflictingQC(chain, content.CommittedQCs, conflicting)
+		if err != nil {
+			return err
+		}
+		for _, signer := range report.Signers {
+			if !slices.Contains(blamed, signer) {
+				return fmt.Errorf("blamed signer %s is not proven at fault by the committed QCs", signer.Hex())
+			}
+		}
+		return nil
+
+	case forensicsTypeVote:
+		var content types.VoteEquivocationContent
+		if err := json.Unmarshal([]byte(report.Proof.Content), &content); err != nil {
+			return fmt.Errorf("invalid vote forensics content: %v", err)
+		}
+		if content.SmallerRoundVote == nil || content.LargerRoundVote == nil {
+			return errors.New("vote forensics content misses a vote")
+		}
+		for _, vote := range []*types.Vote{content.SmallerRoundVote, content.LargerRoundVote} {
+			if vote.ProposedBlockInfo == nil {
+				return errors.New("vote forensics content misses a proposed block")
+			}
+			signers, err := x.verifyForensicsSignatures(chain, vote.ProposedBlockInfo, vote.GapNumber, []types.Signature{vote.Signature})
+			if err != nil {
+				return err
+			}
+			if signers[0] != content.Signer {
+				return fmt.Errorf("vote of round %d signed by %s instead of %s", vote.ProposedBlockInfo.Round, signers[0].Hex(), content.Signer.Hex())
+			}
+		}
+		lower, higher := content.SmallerRoundVote.ProposedBlockInfo, content.LargerRoundVote.ProposedBlockInfo
+		if lower.Hash == higher.Hash {
+			return errors.New("votes are for the same block")
+		}
+		if lower.Round > higher.Round || report.Round != lower.Round {
+			return fmt.Errorf("report round %d doesn't match the vote rounds %d and %d", report.Round, lower.Round, higher.Round)
+		}
+		if err := x.verifyConflictingBlocks(chain, lower, higher); err != nil {
+			return err
+		}
+		// Voting in a later round on another fork breaks the lock taken when
+		// voting for the earlier block only if it extends an older QC
+		if lower.Round != higher.Round {
+			lockedRound, err := x.lockedRound(chain, lower)
+			if err != nil {
+				return err
+			}
+			blamed, _, err := x.ForensicsProcessor.isVoteBlamed(chain, lockedRound, content.LargerRoundVote)
+			if err != nil {
+				return err
+			}
+			if !blamed {
+				return fmt.Errorf("vote of round %d extends a QC not older than the round %d lock of the vote of round %d", higher.Round, lockedRound, lower.Round)
+			}
+		}
+		if len(report.Signers) != 1 || report.Signers[0] != content.Signer {
+			return fmt.Errorf("blamed signers %v don't match the vote signer %s", report.Signers, content.Signer.Hex())
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown forensics type %q", report.Proof.ForensicsType)
+}
+
+// verifyConflictingBlocks checks that two blocks signed by the same masternodes
+// are on conflicting forks: distinct blocks of the same round, or a block of a
+// higher round not extending the one of the lower round.
+func (x *XDPoS_v2) verifyConflictingBlocks(chain consensus.ChainReader, lower, higher *types.BlockInfo) error {
+	if lower.Round == higher.Round {
+		return nil
+	}
+	extending, err := x.ForensicsProcessor.isExtendingFromAncestor(chain, higher, lower)
+	if err != nil {
+		return err
+	}
+	if extending {
+		return fmt.Errorf("block %v of round %d extends block %v of round %d", higher.Hash.Hex(), higher.Round, lower.Hash.Hex(), lower.Round)
+	}
+	return nil
+}
+
+// verifyCommittedQCs checks that the QCs of a QC forensics report commit a
+// block: QCs of three consecutive rounds, each certifying a child of the block
+// certified by the previous one.
+func (x *XDPoS_v2) verifyCommittedQCs(chain consensus.ChainReader, committedQCs []types.QuorumCert) error {
+	if len(committedQCs) != NUM_OF_FORENSICS_QC {
+		return errors.New("QC forensics content misses the committed QCs")
+	}
+	for i := range committedQCs {
+		qc := &committedQCs[i]
+		if qc.ProposedBlockInfo == nil {
+			return errors.New("committed QC misses a proposed block")
+		}
+		if _, err := x.verifyForensicsQC(chain, qc); err != nil {
+			return err
+		}
+		if i == 0 {
+			continue
+		}
+		parent := committedQCs[i-1].ProposedBlockInfo
+		if qc.ProposedBlockInfo.Round != parent.Round+1 {
+			return fmt.Errorf("committed QC of round %d doesn't follow the one of round %d", qc.ProposedBlockInfo.Round, parent.Round)
+		}
+		header := chain.GetHeaderByHash(qc.ProposedBlockInfo.Hash)
+		if header == nil || header.ParentHash != parent.Hash {
+			return fmt.Errorf("block %v of committed QC of round %d doesn't extend block %v", qc.ProposedBlockInfo.Hash.Hex(), qc.ProposedBlockInfo.Round, parent.Hash.Hex())
+		}
+	}
+	return nil
+}
+
+// verifyForensicsHashPaths checks that the hash paths of a QC forensics report
+// lead from the diverging block to the blocks of its two QCs through distinct
+// children, the diverging block being an ancestor of the committed block.
+func (x *XDPoS_v2) verifyForensicsHashPaths(chain consensus.ChainReader, content *types.ForensicsContent) error {
+	diverging := chain.GetHeaderByHash(common.HexToHash(content.DivergingBlockHash))
+	if diverging == nil || diverging.Number.Uint64() != content.DivergingBlockNumber {
+		return fmt.Errorf("unknown diverging block %s", content.DivergingBlockHash)
+	}
+	if diverging.Number.Cmp(content.CommittedQCs[0].ProposedBlockInfo.Number) >= 0 {
+		return fmt.Errorf("diverging block %d is not an ancestor of the committed block %d", diverging.Number, content.CommittedQCs[0].ProposedBlockInfo.Number)
+	}
+	var children [2]string
+	for i, info := range []*types.ForensicsInfo{content.SmallerRoundInfo, content.LargerRoundInfo} {
+		path := info.HashPath
+		if len(path) < 2 || path[0] != content.DivergingBlockHash || common.HexToHash(path[len(path)-1]) != info.QuorumCert.ProposedBlockInfo.Hash {
+			return fmt.Errorf("hash path of the QC of round %d doesn't lead from the diverging block to its block", info.QuorumCert.ProposedBlockInfo.Round)
+		}
+		for j := 1; j < len(path); j++ {
+			header := chain.GetHeaderByHash(common.HexToHash(path[j]))
+			if header == nil || header.ParentHash != common.HexToHash(path[j-1]) {
+				return fmt.Errorf("hash path of the QC of round %d is broken at %s", info.QuorumCert.ProposedBlockInfo.Round, path[j])
+			}
+		}
+		children[i] = path[1]
+	}
+	if children[0] == children[1] {
+		return errors.New("hash paths of the QCs don't diverge")
+	}
+	return nil
+}
+
+// verifyForensicsQC checks the signatures of a QC and that they reach the
+// certificate threshold, it returns the signers.
+func (x *XDPoS_v2) verifyForensicsQC(chain consensus.ChainReader, qc *types.QuorumCert) (map[common.Address]bool, error) {
+	signers, err := x.verifyForensicsSignatures(chain, qc.ProposedBlockInfo, qc.GapNumber, qc.Signatures)
+	if err != nil {
+		return nil, err
+	}
+	signed := make(map[common.Address]bool, len(signers))
+	for _, signer := range signers {
+		signed[signer] = true
+	}
+	epochInfo, err := x.getEpochSwitchInfo(chain, nil, qc.ProposedBlockInfo.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the epoch switch info of block %v: %v", qc.ProposedBlockInfo.Hash.Hex(), err)
+	}
+	certThreshold := x.config.V2.Config(uint64(qc.ProposedBlockInfo.Round)).CertThreshold
+	if float64(len(signed)) < float64(epochInfo.MasternodesLen)*certThreshold {
+		return nil, fmt.Errorf("QC of round %d has %d signers, less than the threshold", qc.ProposedBlockInfo.Round, len(signed))
+	}
+	return signed, nil
+}
+
+// verifyForensicsSignatures recovers the signers of the votes for a block and
+// checks them against the masternodes of its epoch switch header.
+func (x *XDPoS_v2) verifyForensicsSignatures(chain consensus.ChainReader, blockInfo *types.BlockInfo, gapNumber uint64, signatures []types.Signature) ([]common.Address, error) {
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("no signature for block %v of round %d", blockInfo.Hash.Hex(), blockInfo.Round)
+	}
+	epochInfo, err := x.getEpochSwitchInfo(chain, nil, blockInfo.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the epoch switch info of block %v: %v", blockInfo.Hash.Hex(), err)
+	}
+	signHash := types.VoteSigHash(&types.VoteForSign{
+		ProposedBlockInfo: blockInfo,
+		GapNumber:         gapNumber,
+	})
+	signers := make([]common.Address, 0, len(signatures))
+	for _, signature := range signatures {
+		valid, signer, err := x.verifyMsgSignature(signHash, signature, epochInfo.Masternodes)
+		if err != nil {
+			return nil, err
+		}
+		if !valid {
+			return nil, fmt.Errorf("signer %s is not a masternode of the epoch of block %v", signer.Hex(), blockInfo.Hash.Hex())
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
@@ -0,0 +1,64 @@
+package engine_v2
+
+import (
+	"strings"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/metrics"
+)
+
+// Consensus health metrics of the v2 engine.
+var (
+	roundGauge = metrics.NewRegisteredGauge("xdpos/v2/round", nil)
+	roundTimer = metrics.NewRegisteredTimer("xdpos/v2/round/duration", nil)
+
+	qcRoundGauge    = metrics.NewRegisteredGauge("xdpos/v2/qc/round", nil)
+	qcFormedCounter = metrics.NewRegisteredCounter("xdpos/v2/qc/formed", nil)
+	qcLatencyTimer  = metrics.NewRegisteredTimer("xdpos/v2/qc/latency", nil)
+
+	tcRoundGauge    = metrics.NewRegisteredGauge("xdpos/v2/tc/round", nil)
+	tcFormedCounter = metrics.NewRegisteredCounter("xdpos/v2/tc/formed", nil)
+
+	committedNumberGauge = metrics.NewRegisteredGauge("xdpos/v2/committed/number", nil)
+	committedRoundGauge  = metrics.NewRegisteredGauge("xdpos/v2/committed/round", nil)
+
+	votePoolGauge    = metrics.NewRegisteredGauge("xdpos/v2/pool/votes", nil)
+	timeoutPoolGauge = metrics.NewRegisteredGauge("xdpos/v2/pool/timeouts", nil)
+
+	timeoutSentCounter  = metrics.NewRegisteredCounter("xdpos/v2/timeout/sent", nil)
+	missedRoundsCounter = metrics.NewRegisteredCounter("xdpos/v2/missedrounds", nil)
+
+	forensicsQCCounter   = metrics.NewRegisteredCounter("xdpos/v2/forensics/qc", nil)
+	forensicsVoteCounter = metrics.NewRegisteredCounter("xdpos/v2/forensics/vote", nil)
+)
+
+// updateMissedRounds accounts the rounds skipped between the parent of a
+// block and the block to the masternodes that were leaders of these rounds.
+// Every round is accounted once, however often its block is processed.
+func (x *XDPoS_v2) updateMissedRounds(chain consensus.ChainReader, header *types.Header, parentRound, round types.Round) {
+	if !metrics.Enabled() || round <= x.highestMissedRound {
+		return
+	}
+	from := max(parentRound, x.highestMissedRound) + 1
+	x.highestMissedRound = round
+	if from >= round {
+		return
+	}
+	masternodes := x.GetMasternodes(chain, header)
+	if len(masternodes) == 0 {
+		return
+	}
+	for r := from; r < round; r++ {
+		leader := masternodes[uint64(r)%x.config.Epoch%uint64(len(masternodes))]
+		missedRoundsCounterOf(leader).Inc(1)
+		missedRoundsCounter.Inc(1)
+	}
+}
+
+// missedRoundsCounterOf returns the counter of the rounds a masternode missed
+// as leader.
+func missedRoundsCounterOf(leader common.Address) *metrics.Counter {
+	return metrics.GetOrRegisterCounter("xdpos/v2/missedrounds/"+strings.ToLower(leader.Hex()), nil)
+}
@@ -1,6 +1,8 @@
 package engine_v2
 
 import (
+	"time"
+
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
@@ -86,3 +88,15 @@ func (x *XDPoS_v2) AuthorizeFaker(signer common.Address) {
 func (x *XDPoS_v2) GetForensicsFaker() *Forensics {
 	return x.ForensicsProcessor
 }
+
+// Hand the outgoing vote, timeout and syncInfo messages synchronously to fn
+// instead of BroadcastCh, so a test can route them deterministically
+func (x *XDPoS_v2) SetBroadcastFaker(fn func(msg interface{})) {
+	x.broadcastFn = fn
+}
+
+// Silence the countdown timer, the test drives OnCountdownTimeout on its own clock.
+// Must be called before the engine is initialised
+func (x *XDPoS_v2) DisableCountdownFaker() {
+	x.timeoutWorker.OnTimeoutFn = func(time.Time, interface{}) error { return nil }
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/XinFinOrg/XDPoSChain/accounts"
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
@@ -64,6 +65,7 @@ func (x *XDPoS_v2) timeoutHandler(blockChainReader consensus.ChainReader, timeou
 	}
 	// Collect timeout, generate TC
 	numberOfTimeoutsInPool, pooledTimeouts := x.timeoutPool.Add(timeout)
+	timeoutPoolGauge.Update(int64(x.timeoutPool.Len()))
 	log.Debug("[timeoutHandler] collect timeout", "number", numberOfTimeoutsInPool)
 
 	epochInfo, err := x.getEpochSwitchInfo(blockChainReader, blockChainReader.CurrentHeader(), blockChainReader.CurrentHeader().Hash())
@@ -104,6 +106,9 @@ func (x *XDPoS_v2) onTimeoutPoolThresholdReached(blockChainReader consensus.Chai
 		GapNumber:  gapNumber,
 	}
 	// Process TC
+	if timeoutCert.Round > x.highestTimeoutCert.Round {
+		tcFormedCounter.Inc(1)
+	}
 	err := x.processTC(blockChainReader, timeoutCert)
 	if err != nil {
 		log.Error("[onTimeoutPoolThresholdReached] Error while processing TC in the Timeout handler after reaching pool threshold", "TcRound", timeoutCert.Round, "NumberOfTcSig", len(timeoutCert.Signatures), "GapNumber", gapNumber, "Error", err)
@@ -230,6 +235,7 @@ func (x *XDPoS_v2) verifyTC(chain consensus.ChainReader, timeoutCert *types.Time
 func (x *XDPoS_v2) processTC(blockChainReader consensus.ChainReader, timeoutCert *types.TimeoutCert) error {
 	if x.highestTimeoutCert.Round < timeoutCert.Round {
 		x.highestTimeoutCert = timeoutCert
+		tcRoundGauge.Update(int64(timeoutCert.Round))
 	}
 	if timeoutCert.Round >= x.currentRound {
 		x.setNewRound(blockChainReader, timeoutCert.Round+1)
@@ -276,10 +282,10 @@ func (x *XDPoS_v2) sendTimeout(chain consensus.ChainReader) error {
 		log.Debug("[sendTimeout] non-epoch-switch block found its epoch block and calculated the gapNumber", "epochSwitchInfo.EpochSwitchBlockInfo.Number", epochSwitchInfo.EpochSwitchBlockInfo.Number.Uint64(), "gapNumber", gapNumber)
 	}
 
-	signedHash, err := x.signSignature(types.TimeoutSigHash(&types.TimeoutForSign{
+	signedHash, err := x.signSignature(accounts.MimetypeXDPoSTimeout, &types.TimeoutForSign{
 		Round:     x.currentRound,
 		GapNumber: gapNumber,
-	}))
+	})
 	if err != nil {
 		log.Error("[sendTimeout] signSignature when sending out TC", "Error", err, "round", x.currentRound, "gap", gapNumber)
 		return err
@@ -321,6 +327,7 @@ func (x *XDPoS_v2) OnCountdownTimeout(time time.Time, chain interface{}) error {
 		log.Error("Error while sending out timeout message at time: ", "time", time, "err", err)
 		return err
 	}
+	timeoutSentCounter.Inc(1)
 
 	x.timeoutCount++
 	if x.timeoutCount%x.config.V2.CurrentConfig.TimeoutSyncThreshold == 0 {
@@ -351,6 +358,7 @@ func (x *XDPoS_v2) hygieneTimeoutPool() {
 			x.timeoutPool.ClearByPoolKey(k)
 		}
 	}
+	timeoutPoolGauge.Update(int64(x.timeoutPool.Len()))
 }
 
 func (x *XDPoS_v2) ReceivedTimeouts() map[string]map[common.Hash]utils.PoolObj {
@@ -18,7 +18,13 @@ import (
 
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
+	rlp.Encode(hasher, sigHashFields(header))
+	hasher.Sum(hash[:0])
+	return hash
+}
 
+// sigHashFields returns the header fields whose RLP encoding is hashed by sigHash.
+func sigHashFields(header *types.Header) []interface{} {
 	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
@@ -41,9 +47,7 @@ func sigHash(header *types.Header) (hash common.Hash) {
 	if header.BaseFee != nil {
 		enc = append(enc, header.BaseFee)
 	}
-	rlp.Encode(hasher, enc)
-	hasher.Sum(hash[:0])
-	return hash
+	return enc
 }
 
 func ecrecover(header *types.Header, sigcache *utils.SigLRU) (common.Address, error) {
@@ -91,17 +95,35 @@ func UniqueSignatures(signatureSlice []types.Signature) ([]types.Signature, []ty
 	return list, duplicates
 }
 
-func (x *XDPoS_v2) signSignature(signingHash common.Hash) (types.Signature, error) {
+func (x *XDPoS_v2) signSignature(mimeType string, payload interface{}) (types.Signature, error) {
+	signedHash, err := x.signPayload(mimeType, payload)
+	if err != nil {
+		return nil, fmt.Errorf("error %v while signing hash", err)
+	}
+	return signedHash, nil
+}
+
+// signPayload signs the keccak256 hash of the RLP encoding of payload. A data
+// signer is handed the encoding itself, tagged with mimeType, so that it can
+// decode the message and apply its signing policy before signing.
+func (x *XDPoS_v2) signPayload(mimeType string, payload interface{}) ([]byte, error) {
 	// Don't hold the signFn for the whole signing operation
 	x.signLock.RLock()
-	signer, signFn := x.signer, x.signFn
+	signer, signFn, signDataFn := x.signer, x.signFn, x.signDataFn
 	x.signLock.RUnlock()
 
-	signedHash, err := signFn(accounts.Account{Address: signer}, signingHash.Bytes())
+	data, err := rlp.EncodeToBytes(payload)
 	if err != nil {
-		return nil, fmt.Errorf("error %v while signing hash", err)
+		return nil, err
 	}
-	return signedHash, nil
+	account := accounts.Account{Address: signer}
+	if signDataFn != nil {
+		return signDataFn(account, mimeType, data)
+	}
+	if signFn == nil {
+		return nil, utils.ErrUnauthorized
+	}
+	return signFn(account, crypto.Keccak256(data))
 }
 
 func (x *XDPoS_v2) verifyMsgSignature(signedHashToBeVerified common.Hash, signature types.Signature, masternodes []common.Address) (bool, common.Address, error) {
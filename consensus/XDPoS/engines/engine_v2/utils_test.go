@@ -0,0 +1,77 @@
+package engine_v2
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/accounts"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+)
+
+func TestSignWithDataSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	signHash := func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	}
+	var mimeTypes []string
+	signData := func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+		if account.Address != signer {
+			t.Fatalf("signing account mismatch: have %x, want %x", account.Address, signer)
+		}
+		mimeTypes = append(mimeTypes, mimeType)
+		return crypto.Sign(crypto.Keccak256(data), key)
+	}
+
+	vote := &types.VoteForSign{
+		ProposedBlockInfo: &types.BlockInfo{Hash: common.HexToHash("0x01"), Round: 10, Number: big.NewInt(900)},
+		GapNumber:         450,
+	}
+	timeout := &types.TimeoutForSign{Round: 11, GapNumber: 450}
+	header := &types.Header{Number: big.NewInt(901), Difficulty: big.NewInt(1), Extra: []byte{0x02}}
+
+	tests := []struct {
+		mimeType string
+		payload  interface{}
+		hash     common.Hash
+	}{
+		{accounts.MimetypeXDPoSVote, vote, types.VoteSigHash(vote)},
+		{accounts.MimetypeXDPoSTimeout, timeout, types.TimeoutSigHash(timeout)},
+		{accounts.MimetypeXDPoSHeader, sigHashFields(header), sigHash(header)},
+	}
+	x := &XDPoS_v2{}
+	for _, tt := range tests {
+		x.Authorize(signer, signHash)
+		want, err := x.signSignature(tt.mimeType, tt.payload)
+		if err != nil {
+			t.Fatalf("%s: failed to sign with hash signer: %v", tt.mimeType, err)
+		}
+		x.AuthorizeData(signer, signData)
+		have, err := x.signSignature(tt.mimeType, tt.payload)
+		if err != nil {
+			t.Fatalf("%s: failed to sign with data signer: %v", tt.mimeType, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("%s: signature mismatch: have %x, want %x", tt.mimeType, have, want)
+		}
+		pubkey, err := crypto.SigToPub(tt.hash.Bytes(), have)
+		if err != nil {
+			t.Fatalf("%s: failed to recover signer: %v", tt.mimeType, err)
+		}
+		if addr := crypto.PubkeyToAddress(*pubkey); addr != signer {
+			t.Errorf("%s: recovered signer mismatch: have %x, want %x", tt.mimeType, addr, signer)
+		}
+	}
+	if len(mimeTypes) != len(tests) {
+		t.Fatalf("data signer calls mismatch: have %d, want %d", len(mimeTypes), len(tests))
+	}
+	for i, tt := range tests {
+		if mimeTypes[i] != tt.mimeType {
+			t.Errorf("call %d: mime type mismatch: have %s, want %s", i, mimeTypes[i], tt.mimeType)
+		}
+	}
+}
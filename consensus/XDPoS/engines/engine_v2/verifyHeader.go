@@ -149,6 +149,18 @@ func (x *XDPoS_v2) verifyHeader(chain consensus.ChainReader, header *types.Heade
 			return utils.ErrPenaltiesNotLegit
 		}
 
+		localRoles, err := x.calcEpochRoles(chain, header.Number, localMasterNodes, localPenalties, round)
+		if err != nil {
+			log.Error("[verifyHeader] Fail to calculate protectors and observers", "Number", header.Number, "Hash", header.Hash())
+			return err
+		}
+		roles := x.GetEpochRolesFromEpochSwitchHeader(header)
+		if (localRoles == nil) != (roles == nil) {
+			return utils.ErrEpochRolesNotLegit
+		}
+		if localRoles != nil && (!utils.CompareSignersLists(localRoles.Protectors, roles.Protectors) || !utils.CompareSignersLists(localRoles.Observers, roles.Observers)) {
+			return utils.ErrEpochRolesNotLegit
+		}
 	} else {
 		if len(header.Validators) != 0 {
 			log.Warn("[verifyHeader] Validators shall not have values in non-epochSwitch block", "Hash", header.Hash(), "Number", header.Number, "header.Validators", header.Validators)
@@ -158,6 +170,10 @@ func (x *XDPoS_v2) verifyHeader(chain consensus.ChainReader, header *types.Heade
 			log.Warn("[verifyHeader] Penalties shall not have values in non-epochSwitch block", "Hash", header.Hash(), "Number", header.Number, "header.Penalties", header.Penalties)
 			return utils.ErrInvalidFieldInNonEpochSwitch
 		}
+		if x.GetEpochRolesFromEpochSwitchHeader(header) != nil {
+			log.Warn("[verifyHeader] Protectors and observers shall not have values in non-epochSwitch block", "Hash", header.Hash(), "Number", header.Number)
+			return utils.ErrInvalidFieldInNonEpochSwitch
+		}
 		masterNodes = x.GetMasternodes(chain, header)
 	}
 
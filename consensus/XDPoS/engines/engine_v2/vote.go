@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/XinFinOrg/XDPoSChain/accounts"
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/consensus"
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
@@ -68,10 +69,10 @@ func (x *XDPoS_v2) sendVote(chainReader consensus.ChainReader, blockInfo *types.
 	if epochSwitchNumber-epochSwitchNumber%x.config.Epoch < x.config.Gap {
 		gapNumber = 0
 	}
-	signedHash, err := x.signSignature(types.VoteSigHash(&types.VoteForSign{
+	signedHash, err := x.signSignature(accounts.MimetypeXDPoSVote, &types.VoteForSign{
 		ProposedBlockInfo: blockInfo,
 		GapNumber:         gapNumber,
-	}))
+	})
 	if err != nil {
 		log.Error("signSignature when sending out Vote", "BlockInfoHash", blockInfo.Hash, "Error", err)
 		return err
@@ -110,6 +111,7 @@ func (x *XDPoS_v2) voteHandler(chain consensus.ChainReader, voteMsg *types.Vote)
 
 	// Collect vote
 	numberOfVotesInPool, pooledVotes := x.votePool.Add(voteMsg)
+	votePoolGauge.Update(int64(x.votePool.Len()))
 	log.Debug("[voteHandler] collect votes", "number", numberOfVotesInPool)
 	go x.ForensicsProcessor.DetectEquivocationInVotePool(voteMsg, x.votePool)
 	go x.ForensicsProcessor.ProcessVoteEquivocation(chain, x, voteMsg)
@@ -148,6 +150,7 @@ func (x *XDPoS_v2) voteHandler(chain consensus.ChainReader, voteMsg *types.Vote)
 			return err
 		}
 		elapsed := time.Since(x.votePoolCollectionTime)
+		qcLatencyTimer.Update(elapsed)
 		log.Info("[voteHandler] time cost from receive first vote under QC create", "elapsed", elapsed)
 		x.votePoolCollectionTime = time.Time{}
 	}
@@ -235,11 +238,15 @@ func (x *XDPoS_v2) onVotePoolThresholdReached(chain consensus.ChainReader, poole
 		Signatures:        validSignatures,
 		GapNumber:         currentVoteMsg.(*types.Vote).GapNumber,
 	}
+	formed := quorumCert.ProposedBlockInfo.Round > x.highestQuorumCert.ProposedBlockInfo.Round
 	err = x.processQC(chain, quorumCert)
 	if err != nil {
 		log.Error("Error while processing QC in the Vote handler after reaching pool threshold, ", err)
 		return err
 	}
+	if formed {
+		qcFormedCounter.Inc(1)
+	}
 	log.Info("Successfully processed the vote and produced QC!", "QcRound", quorumCert.ProposedBlockInfo.Round, "QcNumOfSig", len(quorumCert.Signatures), "QcHash", quorumCert.ProposedBlockInfo.Hash, "QcNumber", quorumCert.ProposedBlockInfo.Number.Uint64())
 	return nil
 }
@@ -323,6 +330,7 @@ func (x *XDPoS_v2) hygieneVotePool() {
 			x.votePool.ClearByPoolKey(k)
 		}
 	}
+	votePoolGauge.Update(int64(x.votePool.Len()))
 }
 
 func (x *XDPoS_v2) ReceivedVotes() map[string]map[common.Hash]utils.PoolObj {
@@ -0,0 +1,167 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+var (
+	ErrNotV2Header     = errors.New("header is not an XDPoS v2 header")
+	ErrEmptyCommittee  = errors.New("epoch switch header without masternodes")
+	ErrQCBlockMismatch = errors.New("QC does not certify the header")
+	ErrUnknownQCSigner = errors.New("QC signed by a non masternode")
+)
+
+// Committee is the set of masternodes certifying the blocks of an epoch, as
+// recorded in the epoch switch header opening it.
+type Committee struct {
+	Number      uint64 // Number of the epoch switch header
+	Hash        common.Hash
+	Round       types.Round
+	Masternodes []common.Address
+}
+
+// NewCommittee reads the committee opened by an epoch switch header. The last
+// v1 block records its masternodes in the extra data, v2 epoch switch headers
+// in the validators field.
+func NewCommittee(config *params.XDPoSConfig, header *types.Header) (*Committee, error) {
+	var (
+		masternodes []common.Address
+		round       types.Round
+	)
+	if header.Number.Cmp(config.V2.SwitchBlock) == 0 {
+		if len(header.Extra) < ExtraVanity+ExtraSeal {
+			return nil, ErrEmptyCommittee
+		}
+		masternodes = common.ExtractAddressFromBytes(header.Extra[ExtraVanity : len(header.Extra)-ExtraSeal])
+	} else {
+		_, r, err := DecodeHeaderExtra(config, header)
+		if err != nil {
+			return nil, err
+		}
+		masternodes, round = common.ExtractAddressFromBytes(header.Validators), r
+	}
+	if len(masternodes) == 0 {
+		return nil, ErrEmptyCommittee
+	}
+	return &Committee{
+		Number:      header.Number.Uint64(),
+		Hash:        header.Hash(),
+		Round:       round,
+		Masternodes: masternodes,
+	}, nil
+}
+
+// GapNumber returns the gap block number the votes of the committee refer to.
+func (c *Committee) GapNumber(config *params.XDPoSConfig) uint64 {
+	start := c.Number - c.Number%config.Epoch
+	// prevent overflow
+	if start < config.Gap {
+		return 0
+	}
+	return start - config.Gap
+}
+
+// InEpoch reports whether a round belongs to the epoch of the committee.
+func (c *Committee) InEpoch(config *params.XDPoSConfig, round types.Round) bool {
+	start := c.Round - c.Round%types.Round(config.Epoch)
+	return round >= start && round < start+types.Round(config.Epoch)
+}
+
+// VerifyQC checks a quorum certificate against the committee, the same way
+// the v2 engine does: enough distinct masternode signatures over the vote of
+// the certified block and the gap number of the epoch.
+func (c *Committee) VerifyQC(config *params.XDPoSConfig, qc *types.QuorumCert) error {
+	if qc == nil || qc.ProposedBlockInfo == nil {
+		return ErrInvalidQC
+	}
+	signHash := types.VoteSigHash(&types.VoteForSign{
+		ProposedBlockInfo: qc.ProposedBlockInfo,
+		GapNumber:         qc.GapNumber,
+	})
+	signers := make(map[common.Address]struct{}, len(qc.Signatures))
+	for _, sig := range qc.Signatures {
+		pubkey, err := crypto.Ecrecover(signHash.Bytes(), sig)
+		if err != nil {
+			return fmt.Errorf("invalid QC signature: %w", err)
+		}
+		var signer common.Address
+		copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+		if !slices.Contains(c.Masternodes, signer) {
+			return fmt.Errorf("%w: %v", ErrUnknownQCSigner, signer)
+		}
+		signers[signer] = struct{}{}
+	}
+	// The QC of the last v1 block carried by the first v2 block has no signatures
+	round := qc.ProposedBlockInfo.Round
+	threshold := config.V2.Config(uint64(round)).CertThreshold
+	if round > 0 && float64(len(signers)) < float64(len(c.Masternodes))*threshold {
+		return ErrInvalidQCSignatures
+	}
+	if gap := c.GapNumber(config); qc.GapNumber != gap {
+		return fmt.Errorf("gap number mismatch QC Gap %d, shouldBe %d", qc.GapNumber, gap)
+	}
+	return nil
+}
+
+// DecodeHeaderExtra returns the quorum certificate and the round of a v2
+// header.
+func DecodeHeaderExtra(config *params.XDPoSConfig, header *types.Header) (*types.QuorumCert, types.Round, error) {
+	if header.Number.Cmp(config.V2.SwitchBlock) <= 0 {
+		return nil, 0, ErrNotV2Header
+	}
+	var extra types.ExtraFields_v2
+	if err := DecodeBytesExtraFields(header.Extra, &extra); err != nil {
+		return nil, 0, err
+	}
+	if extra.QuorumCert == nil || extra.QuorumCert.ProposedBlockInfo == nil || extra.QuorumCert.ProposedBlockInfo.Number == nil {
+		return nil, 0, ErrInvalidV2Extra
+	}
+	return extra.QuorumCert, extra.Round, nil
+}
+
+// HeaderRound returns the round of a header, zero for the last v1 block.
+func HeaderRound(config *params.XDPoSConfig, header *types.Header) (types.Round, error) {
+	if header.Number.Cmp(config.V2.SwitchBlock) == 0 {
+		return 0, nil
+	}
+	_, round, err := DecodeHeaderExtra(config, header)
+	return round, err
+}
+
+// IsEpochSwitchHeader reports whether the header opens a new epoch, following
+// the rule of the v2 engine without access to the chain.
+func IsEpochSwitchHeader(config *params.XDPoSConfig, header *types.Header) (bool, error) {
+	if header.Number.Cmp(config.V2.SwitchBlock) == 0 {
+		return true, nil
+	}
+	qc, round, err := DecodeHeaderExtra(config, header)
+	if err != nil {
+		return false, err
+	}
+	if qc.ProposedBlockInfo.Number.Cmp(config.V2.SwitchBlock) == 0 {
+		return true, nil
+	}
+	return qc.ProposedBlockInfo.Round < round-round%types.Round(config.Epoch), nil
+}
@@ -51,6 +51,7 @@ var (
 
 	ErrValidatorsNotLegit = errors.New("validators does not match what's stored in snapshot minus its penalty")
 	ErrPenaltiesNotLegit  = errors.New("penalties does not match")
+	ErrEpochRolesNotLegit = errors.New("protectors or observers does not match")
 
 	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
 	ErrInvalidMixDigest = errors.New("non-zero mix digest")
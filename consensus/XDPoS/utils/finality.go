@@ -0,0 +1,363 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/ethdb/memorydb"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// FinalityProofVersion is the version of the finality proof format.
+const FinalityProofVersion = 1
+
+var (
+	ErrProofVersion        = errors.New("unsupported finality proof version")
+	ErrProofCheckpoint     = errors.New("finality proof does not start from the trusted checkpoint")
+	ErrProofEpochSwitch    = errors.New("invalid epoch switch header in finality proof")
+	ErrProofNonContiguous  = errors.New("non contiguous headers in finality proof")
+	ErrProofNotCommitted   = errors.New("finality proof has no committing chain of three QCs")
+	ErrProofOutsideOfEpoch = errors.New("header outside of the epoch of its committee")
+	ErrProofCommittee      = errors.New("committee does not match the candidates of its gap block")
+	ErrProofGap            = errors.New("gap headers do not link the gap block to the epoch switch")
+	ErrProofState          = errors.New("invalid state proof of the candidates")
+)
+
+// FinalityProof proves that a block is committed by the XDPoS v2 consensus,
+// starting from a trusted epoch switch header.
+//
+// EpochSwitches links the checkpoint to the epoch of the block: each epoch
+// switch header carries the QC of its parent, signed by the committee of the
+// previous one. Headers starts with the block and holds its descendants up to
+// the header carrying the last QC of a chain of three QCs for consecutive
+// rounds, which commits the block. Committees proves the committee of every
+// epoch switch header met, first in EpochSwitches then in Headers.
+type FinalityProof struct {
+	Version       uint64            `json:"version"`
+	Checkpoint    *types.Header     `json:"checkpoint"`
+	EpochSwitches []*types.Header   `json:"epochSwitches"`
+	Headers       []*types.Header   `json:"headers"`
+	Committees    []*CommitteeProof `json:"committees"`
+}
+
+// CommitteeProof proves the masternodes recorded by an epoch switch header
+// from the state of the gap block of its epoch. GapHeaders links the gap block
+// to the parent of the epoch switch header, and State holds the trie nodes of
+// the candidates of the validator contract and their stakes at the gap block.
+// The first v2 epoch keeps the masternodes of the last v1 block, so its proof
+// is empty.
+type CommitteeProof struct {
+	GapHeaders []*types.Header `json:"gapHeaders"`
+	State      []hexutil.Bytes `json:"state"`
+}
+
+// EncodeToBytes encodes the proof with RLP.
+func (p *FinalityProof) EncodeToBytes() ([]byte, error) {
+	return rlp.EncodeToBytes(p)
+}
+
+// DecodeFinalityProof decodes an RLP encoded proof of a supported version.
+func DecodeFinalityProof(b []byte) (*FinalityProof, error) {
+	proof := new(FinalityProof)
+	if err := rlp.DecodeBytes(b, proof); err != nil {
+		return nil, err
+	}
+	if proof.Version != FinalityProofVersion {
+		return nil, ErrProofVersion
+	}
+	return proof, nil
+}
+
+// VerifyFinalityProof checks offline that the proof commits its block, given
+// the hash of a trusted epoch switch header. It returns the committed block
+// header.
+//
+// The committee of every epoch is read from its epoch switch header, which is
+// accepted once it extends a block certified by the previous committee and its
+// masternodes match the candidates proven in the state of its gap block, an
+// ancestor of that certified block. The proof is thus as trustworthy as the
+// checkpoint and the honest majority of every committee in between. The V2
+// config index must be built, as done by the engine.
+func VerifyFinalityProof(config *params.XDPoSConfig, checkpoint common.Hash, proof *FinalityProof) (*types.Header, error) {
+	if config == nil || config.V2 == nil || config.V2.SwitchBlock == nil {
+		return nil, ErrNotV2Header
+	}
+	if proof.Version != FinalityProofVersion {
+		return nil, ErrProofVersion
+	}
+	if proof.Checkpoint == nil || proof.Checkpoint.Hash() != checkpoint {
+		return nil, ErrProofCheckpoint
+	}
+	if ok, err := IsEpochSwitchHeader(config, proof.Checkpoint); err != nil || !ok {
+		return nil, ErrProofCheckpoint
+	}
+	committee, err := NewCommittee(config, proof.Checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	// Hand over the committee at every epoch switch
+	committees := proof.Committees
+	for _, header := range proof.EpochSwitches {
+		if len(committees) == 0 {
+			return nil, fmt.Errorf("epoch switch %v: %w", header.Number, ErrProofCommittee)
+		}
+		if committee, err = nextCommittee(config, committee, header, committees[0]); err != nil {
+			return nil, fmt.Errorf("epoch switch %v: %w", header.Number, err)
+		}
+		committees = committees[1:]
+	}
+	if len(proof.Headers) == 0 {
+		return nil, ErrProofNotCommitted
+	}
+	block := proof.Headers[0]
+	if block.Hash() != committee.Hash {
+		if block.Number.Uint64() <= committee.Number {
+			return nil, ErrProofOutsideOfEpoch
+		}
+		switched, err := IsEpochSwitchHeader(config, block)
+		if err != nil {
+			return nil, err
+		}
+		round, _ := HeaderRound(config, block)
+		if switched || !committee.InEpoch(config, round) {
+			return nil, ErrProofOutsideOfEpoch
+		}
+	}
+	// Verify the QC certifying every header but the last one
+	rounds := make([]types.Round, len(proof.Headers))
+	for i, header := range proof.Headers {
+		if rounds[i], err = HeaderRound(config, header); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			continue
+		}
+		parent := proof.Headers[i-1]
+		if header.ParentHash != parent.Hash() || header.Number.Uint64() != parent.Number.Uint64()+1 {
+			return nil, ErrProofNonContiguous
+		}
+		qc, _, err := DecodeHeaderExtra(config, header)
+		if err != nil {
+			return nil, err
+		}
+		info := qc.ProposedBlockInfo
+		if info.Hash != parent.Hash() || info.Number.Cmp(parent.Number) != 0 || info.Round != rounds[i-1] || rounds[i] <= info.Round {
+			return nil, ErrQCBlockMismatch
+		}
+		if err := committee.VerifyQC(config, qc); err != nil {
+			return nil, fmt.Errorf("header %v: %w", parent.Number, err)
+		}
+		switched, err := IsEpochSwitchHeader(config, header)
+		if err != nil {
+			return nil, err
+		}
+		if switched {
+			if len(committees) == 0 {
+				return nil, fmt.Errorf("epoch switch %v: %w", header.Number, ErrProofCommittee)
+			}
+			if committee, err = verifyCommittee(config, committee, header, committees[0]); err != nil {
+				return nil, fmt.Errorf("epoch switch %v: %w", header.Number, err)
+			}
+			committees = committees[1:]
+		}
+		// Three certified headers of consecutive rounds commit the first one
+		if i >= 3 && rounds[i-1] == rounds[i-2]+1 && rounds[i-2] == rounds[i-3]+1 {
+			return block, nil
+		}
+	}
+	return nil, ErrProofNotCommitted
+}
+
+// nextCommittee checks that an epoch switch header extends a block certified
+// by the current committee and returns the committee it opens.
+func nextCommittee(config *params.XDPoSConfig, current *Committee, header *types.Header, proof *CommitteeProof) (*Committee, error) {
+	if header.Number.Uint64() <= current.Number {
+		return nil, ErrProofEpochSwitch
+	}
+	if ok, err := IsEpochSwitchHeader(config, header); err != nil || !ok {
+		return nil, ErrProofEpochSwitch
+	}
+	qc, round, err := DecodeHeaderExtra(config, header)
+	if err != nil {
+		return nil, err
+	}
+	info := qc.ProposedBlockInfo
+	if info.Hash != header.ParentHash || info.Number.Uint64()+1 != header.Number.Uint64() || info.Number.Uint64() < current.Number {
+		return nil, ErrQCBlockMismatch
+	}
+	if round <= info.Round || !current.InEpoch(config, info.Round) {
+		return nil, ErrProofOutsideOfEpoch
+	}
+	if err := current.VerifyQC(config, qc); err != nil {
+		return nil, err
+	}
+	return verifyCommittee(config, current, header, proof)
+}
+
+// verifyCommittee returns the committee opened by an epoch switch header whose
+// parent is certified by the current committee, once its masternodes are
+// recomputed the same way as the engine does: the candidates of the gap block
+// in descending stake order, without the penalties recorded in the header,
+// capped at the maximum number of masternodes of its round.
+func verifyCommittee(config *params.XDPoSConfig, current *Committee, header *types.Header, proof *CommitteeProof) (*Committee, error) {
+	if proof == nil {
+		return nil, ErrProofCommittee
+	}
+	committee, err := NewCommittee(config, header)
+	if err != nil {
+		return nil, err
+	}
+	maxMasternodes := config.V2.Config(uint64(committee.Round)).MaxMasternodes
+
+	var expected []common.Address
+	if header.Number.Uint64() == config.V2.SwitchBlock.Uint64()+1 {
+		expected = SelectMasternodes(current.Masternodes, nil, maxMasternodes)
+	} else {
+		gap, err := proof.gapHeader(header, committee.GapNumber(config))
+		if err != nil {
+			return nil, err
+		}
+		candidates, err := proof.candidates(gap.Root)
+		if err != nil {
+			return nil, err
+		}
+		expected = SelectMasternodes(candidates, common.ExtractAddressFromBytes(header.Penalties), maxMasternodes)
+	}
+	if !CompareSignersLists(expected, committee.Masternodes) {
+		return nil, ErrProofCommittee
+	}
+	return committee, nil
+}
+
+// gapHeader checks that the gap headers link the gap block of the given number
+// to the parent of the epoch switch header, and returns the gap block header.
+func (p *CommitteeProof) gapHeader(header *types.Header, number uint64) (*types.Header, error) {
+	if len(p.GapHeaders) == 0 || p.GapHeaders[0].Number.Uint64() != number {
+		return nil, ErrProofGap
+	}
+	for i := 1; i < len(p.GapHeaders); i++ {
+		parent := p.GapHeaders[i-1]
+		if p.GapHeaders[i].ParentHash != parent.Hash() || p.GapHeaders[i].Number.Uint64() != parent.Number.Uint64()+1 {
+			return nil, ErrProofGap
+		}
+	}
+	if last := p.GapHeaders[len(p.GapHeaders)-1]; last.Hash() != header.ParentHash {
+		return nil, ErrProofGap
+	}
+	return p.GapHeaders[0], nil
+}
+
+// candidates reads the candidates of the validator contract in descending
+// stake order from the state proof, checked against the given state root.
+func (p *CommitteeProof) candidates(root common.Hash) ([]common.Address, error) {
+	db := memorydb.New()
+	for _, node := range p.State {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	blob, err := trie.VerifyProof(root, crypto.Keccak256(common.MasternodeVotingSMCBinary.Bytes()), db)
+	if err != nil || len(blob) == 0 {
+		return nil, ErrProofState
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, ErrProofState
+	}
+	read := func(key common.Hash) (common.Hash, error) {
+		enc, err := trie.VerifyProof(account.Root, crypto.Keccak256(key.Bytes()), db)
+		if err != nil {
+			return common.Hash{}, ErrProofState
+		}
+		if len(enc) == 0 {
+			return common.Hash{}, nil
+		}
+		_, content, _, err := rlp.Split(enc)
+		if err != nil {
+			return common.Hash{}, ErrProofState
+		}
+		return common.BytesToHash(content), nil
+	}
+	length, err := read(state.GetCandidatesLengthLoc())
+	if err != nil {
+		return nil, err
+	}
+	var ms []Masternode
+	for i := uint64(0); i < length.Big().Uint64(); i++ {
+		value, err := read(state.GetCandidateLoc(i))
+		if err != nil {
+			return nil, err
+		}
+		candidate := common.BytesToAddress(value[:])
+		// Same as the engine, skipping the removed candidates
+		if candidate.IsZero() {
+			continue
+		}
+		stake, err := read(state.GetCandidateCapLoc(candidate))
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, Masternode{Address: candidate, Stake: stake.Big()})
+	}
+	return SortCandidates(ms), nil
+}
+
+// ProveCandidates collects the trie nodes proving the candidates of the
+// validator contract and their stakes in the given state, for a CommitteeProof.
+func ProveCandidates(statedb *state.StateDB) ([]hexutil.Bytes, error) {
+	var (
+		nodes []hexutil.Bytes
+		seen  = make(map[common.Hash]struct{})
+	)
+	add := func(proof [][]byte, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, node := range proof {
+			hash := crypto.Keccak256Hash(node)
+			if _, ok := seen[hash]; !ok {
+				seen[hash] = struct{}{}
+				nodes = append(nodes, node)
+			}
+		}
+		return nil
+	}
+	contract := common.MasternodeVotingSMCBinary
+	if err := add(statedb.GetProof(contract)); err != nil {
+		return nil, err
+	}
+	keys := []common.Hash{state.GetCandidatesLengthLoc()}
+	count := statedb.GetState(contract, state.GetCandidatesLengthLoc()).Big().Uint64()
+	for i := uint64(0); i < count; i++ {
+		keys = append(keys, state.GetCandidateLoc(i))
+	}
+	for _, candidate := range state.GetCandidates(statedb) {
+		keys = append(keys, state.GetCandidateCapLoc(candidate))
+	}
+	for _, key := range keys {
+		if err := add(statedb.GetStorageProof(contract, key)); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
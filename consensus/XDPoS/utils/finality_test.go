@@ -0,0 +1,298 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFinalityTestConfig() *params.XDPoSConfig {
+	v2 := &params.V2{
+		SwitchBlock: big.NewInt(10),
+		AllConfigs: map[uint64]*params.V2Config{
+			0: {CertThreshold: 0.667, MaxMasternodes: 18},
+		},
+	}
+	v2.BuildConfigIndex()
+	return &params.XDPoSConfig{Epoch: 10, Gap: 5, V2: v2}
+}
+
+// finalityTestChain is a v2 chain starting with the last v1 block, along with
+// the states of its headers.
+type finalityTestChain struct {
+	config  *params.XDPoSConfig
+	headers []*types.Header
+	states  state.Database
+}
+
+// newFinalityTestChain generates a v2 chain starting with the last v1 block,
+// where round i is skipped when skip returns true. Every epoch after the first
+// v2 one is certified by a new set of masternodes, recorded as the candidates
+// of the state of the previous epoch along with a penalized one.
+func newFinalityTestChain(t *testing.T, config *params.XDPoSConfig, length int, skip func(types.Round) bool) *finalityTestChain {
+	tc := &finalityTestChain{config: config, states: state.NewDatabase(rawdb.NewMemoryDatabase())}
+	newKeys := func() []*ecdsa.PrivateKey {
+		keys := make([]*ecdsa.PrivateKey, 4)
+		for i := range keys {
+			keys[i], _ = crypto.GenerateKey()
+		}
+		return keys
+	}
+	addresses := func(keys []*ecdsa.PrivateKey) []byte {
+		var b []byte
+		for _, key := range keys {
+			b = append(b, crypto.PubkeyToAddress(key.PublicKey).Bytes()...)
+		}
+		return b
+	}
+	penalized := common.Address{0xff}
+	candidatesRoot := func(keys []*ecdsa.PrivateKey) common.Hash {
+		candidates := append([]common.Address{penalized}, common.ExtractAddressFromBytes(addresses(keys))...)
+		statedb, err := state.New(types.EmptyRootHash, tc.states)
+		assert.Nil(t, err)
+		contract := common.MasternodeVotingSMCBinary
+		statedb.SetState(contract, state.GetCandidatesLengthLoc(), common.BigToHash(big.NewInt(int64(len(candidates)))))
+		for i, candidate := range candidates {
+			statedb.SetState(contract, state.GetCandidateLoc(uint64(i)), common.BytesToHash(candidate[:]))
+			statedb.SetState(contract, state.GetCandidateCapLoc(candidate), common.BigToHash(big.NewInt(int64(len(candidates)-i))))
+		}
+		root, err := statedb.Commit(false)
+		assert.Nil(t, err)
+		assert.Nil(t, tc.states.TrieDB().Commit(root, false))
+		return root
+	}
+	keys, next := newKeys(), newKeys()
+	extra := append(make([]byte, ExtraVanity), addresses(keys)...)
+	tc.headers = []*types.Header{{Number: new(big.Int).Set(config.V2.SwitchBlock), Extra: append(extra, make([]byte, ExtraSeal)...)}}
+
+	var (
+		parentRound types.Round
+		committee   = config.V2.SwitchBlock.Uint64()
+	)
+	for round := types.Round(1); len(tc.headers) < length; round++ {
+		if skip != nil && skip(round) {
+			continue
+		}
+		parent := tc.headers[len(tc.headers)-1]
+		qc := &types.QuorumCert{
+			ProposedBlockInfo: &types.BlockInfo{Hash: parent.Hash(), Round: parentRound, Number: parent.Number},
+			GapNumber:         committee - committee%config.Epoch - config.Gap,
+		}
+		signHash := types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: qc.ProposedBlockInfo, GapNumber: qc.GapNumber})
+		for _, key := range keys {
+			sig, _ := crypto.Sign(signHash.Bytes(), key)
+			qc.Signatures = append(qc.Signatures, sig)
+		}
+		extra, err := (&types.ExtraFields_v2{Round: round, QuorumCert: qc}).EncodeToBytes()
+		assert.Nil(t, err)
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Extra:      extra,
+		}
+		if len(tc.headers) == 1 {
+			// The first v2 epoch keeps the masternodes of the last v1 block
+			header.Validators = addresses(keys)
+			committee = header.Number.Uint64()
+		} else if parentRound < round-round%types.Round(config.Epoch) {
+			keys, next = next, newKeys()
+			header.Validators = addresses(keys)
+			header.Penalties = penalized.Bytes()
+			committee = header.Number.Uint64()
+		}
+		header.Root = candidatesRoot(next)
+		tc.headers = append(tc.headers, header)
+		parentRound = round
+	}
+	return tc
+}
+
+// committeeProof proves the committee of the epoch switch header at index i.
+func (tc *finalityTestChain) committeeProof(t *testing.T, i int) *CommitteeProof {
+	header := tc.headers[i]
+	if header.Number.Uint64() == tc.config.V2.SwitchBlock.Uint64()+1 {
+		return &CommitteeProof{}
+	}
+	committee, err := NewCommittee(tc.config, header)
+	assert.Nil(t, err)
+	gap := int(committee.GapNumber(tc.config) - tc.headers[0].Number.Uint64())
+	statedb, err := state.New(tc.headers[gap].Root, tc.states)
+	assert.Nil(t, err)
+	nodes, err := ProveCandidates(statedb)
+	assert.Nil(t, err)
+	return &CommitteeProof{GapHeaders: tc.headers[gap:i], State: nodes}
+}
+
+func (tc *finalityTestChain) proof(t *testing.T, checkpoint int, switches []int, from, to int) *FinalityProof {
+	proof := &FinalityProof{
+		Version:    FinalityProofVersion,
+		Checkpoint: tc.headers[checkpoint],
+		Headers:    tc.headers[from : to+1],
+	}
+	for _, i := range switches {
+		proof.EpochSwitches = append(proof.EpochSwitches, tc.headers[i])
+		proof.Committees = append(proof.Committees, tc.committeeProof(t, i))
+	}
+	for i := from + 1; i <= to; i++ {
+		if ok, _ := IsEpochSwitchHeader(tc.config, tc.headers[i]); ok {
+			proof.Committees = append(proof.Committees, tc.committeeProof(t, i))
+		}
+	}
+	return proof
+}
+
+func TestVerifyFinalityProof(t *testing.T) {
+	config := newFinalityTestConfig()
+	tc := newFinalityTestChain(t, config, 30, nil)
+	headers := tc.headers
+
+	// Epoch switches at the first v2 block (round 1), rounds 10 and 20
+	proof := tc.proof(t, 0, []int{1, 10, 20}, 25, 28)
+	block, err := VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[25].Hash(), block.Hash())
+
+	// Through RLP
+	enc, err := proof.EncodeToBytes()
+	assert.Nil(t, err)
+	decoded, err := DecodeFinalityProof(enc)
+	assert.Nil(t, err)
+	block, err = VerifyFinalityProof(config, headers[0].Hash(), decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[25].Hash(), block.Hash())
+
+	// From a later checkpoint, across an epoch switch in the committing headers
+	proof = tc.proof(t, 10, nil, 18, 21)
+	assert.Equal(t, 1, len(proof.Committees))
+	block, err = VerifyFinalityProof(config, headers[10].Hash(), proof)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[18].Hash(), block.Hash())
+
+	// Of an epoch switch block
+	proof = tc.proof(t, 1, []int{10}, 10, 13)
+	_, err = VerifyFinalityProof(config, headers[1].Hash(), proof)
+	assert.Nil(t, err)
+}
+
+func TestVerifyFinalityProofSkippedRound(t *testing.T) {
+	config := newFinalityTestConfig()
+	tc := newFinalityTestChain(t, config, 10, func(round types.Round) bool { return round == 4 })
+
+	// Rounds 2, 3 and 5 do not commit block 2
+	_, err := VerifyFinalityProof(config, tc.headers[1].Hash(), tc.proof(t, 1, nil, 2, 5))
+	assert.Equal(t, ErrProofNotCommitted, err)
+
+	// Rounds 5, 6 and 7 do, along with their ancestors
+	block, err := VerifyFinalityProof(config, tc.headers[1].Hash(), tc.proof(t, 1, nil, 2, 7))
+	assert.Nil(t, err)
+	assert.Equal(t, tc.headers[2].Hash(), block.Hash())
+}
+
+func TestVerifyFinalityProofInvalid(t *testing.T) {
+	config := newFinalityTestConfig()
+	tc := newFinalityTestChain(t, config, 30, nil)
+	other := newFinalityTestChain(t, config, 30, nil)
+	headers := tc.headers
+
+	// Untrusted checkpoint
+	_, err := VerifyFinalityProof(config, other.headers[0].Hash(), tc.proof(t, 0, []int{1, 10, 20}, 25, 28))
+	assert.Equal(t, ErrProofCheckpoint, err)
+
+	// Missing epoch switch
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), tc.proof(t, 0, []int{1, 20}, 25, 28))
+	assert.True(t, errors.Is(err, ErrProofOutsideOfEpoch))
+
+	// Epoch switch of another chain
+	proof := tc.proof(t, 0, []int{1, 10}, 25, 28)
+	proof.EpochSwitches = append(proof.EpochSwitches, other.headers[20])
+	proof.Committees = append(proof.Committees, other.committeeProof(t, 20))
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.True(t, errors.Is(err, ErrUnknownQCSigner))
+
+	// Block outside of the epoch of the last committee
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), tc.proof(t, 0, []int{1, 10}, 25, 28))
+	assert.Equal(t, ErrProofOutsideOfEpoch, err)
+
+	// Too few committing headers
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), tc.proof(t, 0, []int{1, 10, 20}, 25, 27))
+	assert.Equal(t, ErrProofNotCommitted, err)
+
+	// Non contiguous headers
+	proof = tc.proof(t, 0, []int{1, 10, 20}, 25, 28)
+	proof.Headers = append([]*types.Header{headers[24]}, proof.Headers[1:]...)
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.Equal(t, ErrProofNonContiguous, err)
+
+	// Unsupported version
+	proof = tc.proof(t, 0, []int{1, 10, 20}, 25, 28)
+	proof.Version = FinalityProofVersion + 1
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.Equal(t, ErrProofVersion, err)
+}
+
+func TestVerifyFinalityProofCommittee(t *testing.T) {
+	config := newFinalityTestConfig()
+	tc := newFinalityTestChain(t, config, 30, nil)
+	other := newFinalityTestChain(t, config, 30, nil)
+	headers := tc.headers
+
+	// Committee proofs are required for every epoch switch
+	proof := tc.proof(t, 0, []int{1, 10, 20}, 25, 28)
+	proof.Committees = proof.Committees[:2]
+	_, err := VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.True(t, errors.Is(err, ErrProofCommittee))
+
+	proof = tc.proof(t, 10, nil, 18, 21)
+	proof.Committees = nil
+	_, err = VerifyFinalityProof(config, headers[10].Hash(), proof)
+	assert.True(t, errors.Is(err, ErrProofCommittee))
+
+	// Gap headers not linked to the epoch switch
+	proof = tc.proof(t, 0, []int{1, 10, 20}, 25, 28)
+	proof.Committees[2] = &CommitteeProof{GapHeaders: headers[15:19], State: proof.Committees[2].State}
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.True(t, errors.Is(err, ErrProofGap))
+
+	// State proof of another gap block
+	proof = tc.proof(t, 0, []int{1, 10, 20}, 25, 28)
+	proof.Committees[2].State = other.committeeProof(t, 20).State
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.True(t, errors.Is(err, ErrProofState))
+
+	// Incomplete state proof
+	proof = tc.proof(t, 0, []int{1, 10, 20}, 25, 28)
+	proof.Committees[2].State = proof.Committees[2].State[:len(proof.Committees[2].State)-1]
+	_, err = VerifyFinalityProof(config, headers[0].Hash(), proof)
+	assert.True(t, errors.Is(err, ErrProofState))
+
+	// Masternodes other than the candidates of the gap block
+	committee, err := NewCommittee(config, headers[10])
+	assert.Nil(t, err)
+	forged := types.CopyHeader(headers[20])
+	forged.Validators = forged.Validators[common.AddressLength:]
+	_, err = verifyCommittee(config, committee, forged, tc.committeeProof(t, 20))
+	assert.Equal(t, ErrProofCommittee, err)
+
+	// Including the penalized candidate
+	forged = types.CopyHeader(headers[20])
+	forged.Penalties = nil
+	_, err = verifyCommittee(config, committee, forged, tc.committeeProof(t, 20))
+	assert.Equal(t, ErrProofCommittee, err)
+
+	// The first v2 epoch keeps the masternodes of the last v1 block
+	committee, err = NewCommittee(config, headers[0])
+	assert.Nil(t, err)
+	forged = types.CopyHeader(headers[1])
+	forged.Validators = other.headers[1].Validators
+	_, err = verifyCommittee(config, committee, forged, &CommitteeProof{})
+	assert.Equal(t, ErrProofCommittee, err)
+}
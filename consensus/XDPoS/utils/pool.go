@@ -48,6 +48,18 @@ func (p *Pool) Size(obj PoolObj) int {
 	return len(objListKeyed)
 }
 
+// Len returns the number of objects in the pool under all keys.
+func (p *Pool) Len() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var n int
+	for _, objListKeyed := range p.objList {
+		n += len(objListKeyed)
+	}
+	return n
+}
+
 func (p *Pool) PoolObjKeysList() []string {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
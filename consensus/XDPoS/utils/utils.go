@@ -9,6 +9,7 @@ import (
 	"strconv"
 
 	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
 	"golang.org/x/crypto/sha3"
@@ -77,6 +78,49 @@ func CompareSignersLists(list1 []common.Address, list2 []common.Address) bool {
 	return reflect.DeepEqual(l1, l2)
 }
 
+// Sort candidates by descending stake, the same way as when the candidates of
+// an epoch are recorded at its gap block, and return their addresses.
+func SortCandidates(ms []Masternode) []common.Address {
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Stake.Cmp(ms[j].Stake) >= 0
+	})
+	candidates := make([]common.Address, len(ms))
+	for i, m := range ms {
+		candidates[i] = m.Address
+	}
+	return candidates
+}
+
+// Select the masternodes of an epoch among its candidates, given in descending
+// stake order: the candidates which are not penalized, capped at maxMasternodes.
+func SelectMasternodes(candidates, penalties []common.Address, maxMasternodes int) []common.Address {
+	masternodes := common.RemoveItemFromArray(candidates, penalties)
+	if len(masternodes) > maxMasternodes {
+		masternodes = masternodes[:maxMasternodes]
+	}
+	return masternodes
+}
+
+// Select protectors and observers of an epoch among its candidates, given in
+// descending stake order. They are the top candidates which are neither
+// masternodes nor penalized, filled up to maxProtectors and then maxObservers.
+func SelectEpochRoles(candidates, masternodes, penalties []common.Address, maxProtectors, maxObservers int) *types.EpochRoles {
+	candidates = common.RemoveItemFromArray(candidates, masternodes)
+	candidates = common.RemoveItemFromArray(candidates, penalties)
+
+	roles := &types.EpochRoles{Protectors: []common.Address{}, Observers: []common.Address{}}
+	for _, candidate := range candidates {
+		if len(roles.Protectors) < maxProtectors {
+			roles.Protectors = append(roles.Protectors, candidate)
+		} else if len(roles.Observers) < maxObservers {
+			roles.Observers = append(roles.Observers, candidate)
+		} else {
+			break
+		}
+	}
+	return roles
+}
+
 // Decode extra fields for consensus version >= 2 (XDPoS 2.0 and future versions)
 func DecodeBytesExtraFields(b []byte, val interface{}) error {
 	if len(b) == 0 {
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/XinFinOrg/XDPoSChain/common"
@@ -40,3 +42,42 @@ func TestCompareSignersLists(t *testing.T) {
 		t.Error("Failed with list has only one signer")
 	}
 }
+
+func TestSelectEpochRoles(t *testing.T) {
+	candidates := make([]common.Address, 8)
+	for i := range candidates {
+		candidates[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+	masternodes := candidates[:2]
+	penalties := []common.Address{candidates[3]}
+
+	roles := SelectEpochRoles(candidates, masternodes, penalties, 2, 2)
+	if !reflect.DeepEqual(roles.Protectors, []common.Address{candidates[2], candidates[4]}) {
+		t.Errorf("protectors mismatch: have %v", roles.Protectors)
+	}
+	if !reflect.DeepEqual(roles.Observers, []common.Address{candidates[5], candidates[6]}) {
+		t.Errorf("observers mismatch: have %v", roles.Observers)
+	}
+	// Without enough candidates the observers are left empty
+	roles = SelectEpochRoles(candidates[:4], masternodes, penalties, 2, 2)
+	if len(roles.Protectors) != 1 || len(roles.Observers) != 0 {
+		t.Errorf("roles mismatch: have %d protectors and %d observers, want 1 and 0", len(roles.Protectors), len(roles.Observers))
+	}
+}
+
+func TestSelectMasternodes(t *testing.T) {
+	candidates := make([]common.Address, 5)
+	for i := range candidates {
+		candidates[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+	penalties := []common.Address{candidates[1]}
+
+	masternodes := SelectMasternodes(candidates, penalties, 3)
+	if !reflect.DeepEqual(masternodes, []common.Address{candidates[0], candidates[2], candidates[3]}) {
+		t.Errorf("masternodes mismatch: have %v", masternodes)
+	}
+	// The candidates are left untouched
+	if candidates[1] != common.BigToAddress(big.NewInt(2)) {
+		t.Errorf("candidates modified: have %v", candidates)
+	}
+}
@@ -1,11 +1,13 @@
 package engine_v2_tests
 
 import (
+	"errors"
 	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/params"
 	"github.com/XinFinOrg/XDPoSChain/rpc"
@@ -231,3 +233,55 @@ func TestGetBlockByEpochNumber(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Nil(t, info)
 }
+
+func TestGetFinalityProof(t *testing.T) {
+	blockchain, _, _, _, _, _ := PrepareXDCTestBlockChainForV2Engine(t, 1805, params.TestXDPoSMockChainConfig, nil)
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	api := engine.APIs(blockchain)[0].Service.(*XDPoS.API)
+	config := blockchain.Config().XDPoS
+
+	// From the last v1 block, across the first v2 epoch switch at 901
+	checkpoint := blockchain.GetHeaderByNumber(900).Hash()
+	block := blockchain.GetHeaderByNumber(1000)
+	proof, err := api.GetFinalityProof(block.Hash(), &checkpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(proof.EpochSwitches))
+	assert.Equal(t, 1, len(proof.Committees))
+
+	committed, err := utils.VerifyFinalityProof(config, checkpoint, proof)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Hash(), committed.Hash())
+
+	// Across the epoch switches at 901 and 1800, proven from the gap block 1350
+	block = blockchain.GetHeaderByNumber(1801)
+	proof, err = api.GetFinalityProof(block.Hash(), &checkpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(proof.EpochSwitches))
+	assert.Equal(t, 4, len(proof.Headers))
+	assert.Equal(t, 2, len(proof.Committees))
+	assert.Equal(t, uint64(1350), proof.Committees[1].GapHeaders[0].Number.Uint64())
+	assert.Equal(t, 450, len(proof.Committees[1].GapHeaders))
+	assert.NotEmpty(t, proof.Committees[1].State)
+
+	// The mock chain records masternodes other than the candidates of its state
+	_, err = utils.VerifyFinalityProof(config, checkpoint, proof)
+	assert.True(t, errors.Is(err, utils.ErrProofCommittee))
+
+	// From the epoch switch block of the block by default
+	proof, err = api.GetFinalityProof(block.Hash(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1800), proof.Checkpoint.Number.Uint64())
+	assert.Equal(t, 0, len(proof.EpochSwitches))
+
+	_, err = utils.VerifyFinalityProof(config, proof.Checkpoint.Hash(), proof)
+	assert.Nil(t, err)
+
+	// Not committed yet
+	_, err = api.GetFinalityProof(blockchain.GetHeaderByNumber(1803).Hash(), nil)
+	assert.EqualError(t, err, "block is not committed yet")
+
+	// Checkpoint after the block
+	checkpoint = blockchain.GetHeaderByNumber(1800).Hash()
+	_, err = api.GetFinalityProof(blockchain.GetHeaderByNumber(1000).Hash(), &checkpoint)
+	assert.EqualError(t, err, "checkpoint is after the block")
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/XinFinOrg/XDPoSChain/accounts"
 	"github.com/XinFinOrg/XDPoSChain/accounts/abi/bind/backends"
+	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
 	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
@@ -459,3 +460,179 @@ func TestVoteEquivocationDifferentRound(t *testing.T) {
 		}
 	}
 }
+
+func TestForensicsReportPersistedAndVerified(t *testing.T) {
+	var numOfForks = new(int)
+	*numOfForks = 1
+	blockchain, _, currentBlock, signer, signFn, currentForkBlock := PrepareXDCTestBlockChainForV2Engine(t, 901, params.TestXDPoSMockChainConfig, &ForkedBlockOptions{numOfForkedBlocks: numOfForks})
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	forensics := engine.EngineV2.GetForensicsFaker()
+
+	signVote := func(block *types.Block, round types.Round) *types.Vote {
+		blockInfo := &types.BlockInfo{Hash: block.Hash(), Round: round, Number: block.Number()}
+		signedHash, err := signFn(accounts.Account{Address: signer}, types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: blockInfo, GapNumber: 450}).Bytes())
+		assert.Nil(t, err)
+		return &types.Vote{ProposedBlockInfo: blockInfo, Signature: signedHash, GapNumber: 450}
+	}
+	err := forensics.SendVoteEquivocationProof(signVote(currentBlock, 1), signVote(currentForkBlock, 1), signer)
+	assert.Nil(t, err)
+
+	api := engine.APIs(blockchain)[0].Service.(*XDPoS.API)
+	reports, err := api.GetForensicsReports(&XDPoS.ForensicsReportsArgs{Signer: &signer})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, types.Round(1), reports[0].Round)
+	assert.Equal(t, "Vote", reports[0].Proof.ForensicsType)
+
+	from := types.Round(2)
+	reports, err = api.GetForensicsReports(&XDPoS.ForensicsReportsArgs{FromRound: &from})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(reports))
+
+	reports, err = api.GetForensicsReports(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, engine.EngineV2.VerifyForensicsReport(blockchain, reports[0]))
+
+	// Blaming another node than the vote signer must be rejected
+	reports[0].Signers = []common.Address{acc1Addr}
+	assert.NotNil(t, engine.EngineV2.VerifyForensicsReport(blockchain, reports[0]))
+}
+
+func TestForensicsReportRejectsNonConflictingVotes(t *testing.T) {
+	var numOfForks = new(int)
+	*numOfForks = 1
+	blockchain, _, currentBlock, signer, signFn, currentForkBlock := PrepareXDCTestBlockChainForV2Engine(t, 901, params.TestXDPoSMockChainConfig, &ForkedBlockOptions{numOfForkedBlocks: numOfForks})
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+
+	signVote := func(block *types.Block, round types.Round) *types.Vote {
+		blockInfo := &types.BlockInfo{Hash: block.Hash(), Round: round, Number: block.Number()}
+		signedHash, err := signFn(accounts.Account{Address: signer}, types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: blockInfo, GapNumber: 450}).Bytes())
+		assert.Nil(t, err)
+		return &types.Vote{ProposedBlockInfo: blockInfo, Signature: signedHash, GapNumber: 450}
+	}
+	report := func(lower, higher *types.Vote) *types.ForensicsReport {
+		content, err := json.Marshal(&types.VoteEquivocationContent{SmallerRoundVote: lower, LargerRoundVote: higher, Signer: signer})
+		assert.Nil(t, err)
+		return &types.ForensicsReport{
+			Round:   lower.ProposedBlockInfo.Round,
+			Signers: []common.Address{signer},
+			Proof:   &types.ForensicProof{Id: "equivocation", ForensicsType: "Vote", Content: string(content)},
+		}
+	}
+	parentBlock := blockchain.GetBlockByHash(currentBlock.ParentHash())
+
+	// Two votes for distinct blocks of the same round always conflict
+	assert.Nil(t, engine.EngineV2.VerifyForensicsReport(blockchain, report(signVote(currentBlock, 1), signVote(currentForkBlock, 1))))
+
+	// Voting for a block and later for one of its descendants is honest
+	err := engine.EngineV2.VerifyForensicsReport(blockchain, report(signVote(parentBlock, 1), signVote(currentBlock, 2)))
+	assert.ErrorContains(t, err, "extends block")
+
+	// Voting on another fork is honest once unlocked by a higher round QC
+	err = engine.EngineV2.VerifyForensicsReport(blockchain, report(signVote(currentBlock, 0), signVote(currentForkBlock, 2)))
+	assert.ErrorContains(t, err, "extends a QC not older")
+}
+
+func TestForensicsReportRejectsHonestSiblingVote(t *testing.T) {
+	report := func(t *testing.T, signer common.Address, signFn func(accounts.Account, []byte) ([]byte, error), lower, higher *types.Block, lowerRound, higherRound types.Round) *types.ForensicsReport {
+		signVote := func(block *types.Block, round types.Round) *types.Vote {
+			blockInfo := &types.BlockInfo{Hash: block.Hash(), Round: round, Number: block.Number()}
+			signedHash, err := signFn(accounts.Account{Address: signer}, types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: blockInfo, GapNumber: 450}).Bytes())
+			assert.Nil(t, err)
+			return &types.Vote{ProposedBlockInfo: blockInfo, Signature: signedHash, GapNumber: 450}
+		}
+		content, err := json.Marshal(&types.VoteEquivocationContent{SmallerRoundVote: signVote(lower, lowerRound), LargerRoundVote: signVote(higher, higherRound), Signer: signer})
+		assert.Nil(t, err)
+		return &types.ForensicsReport{
+			Round:   lowerRound,
+			Signers: []common.Address{signer},
+			Proof:   &types.ForensicProof{Id: "equivocation", ForensicsType: "Vote", Content: string(content)},
+		}
+	}
+
+	// Block 905 of round 5 and its sibling of round 7 both extend the QC of
+	// block 904 of round 4. Voting for both is honest: the vote for block 905
+	// only locked the signer on the QC of round 3 carried by block 904.
+	numOfForks, forkRoundDifference := 1, 2
+	blockchain, _, currentBlock, signer, signFn, currentForkBlock := PrepareXDCTestBlockChainForV2Engine(t, 905, params.TestXDPoSMockChainConfig, &ForkedBlockOptions{numOfForkedBlocks: &numOfForks, forkedRoundDifference: &forkRoundDifference})
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	err := engine.EngineV2.VerifyForensicsReport(blockchain, report(t, signer, signFn, currentBlock, currentForkBlock, 5, 7))
+	assert.ErrorContains(t, err, "extends a QC not older")
+
+	// A fork from block 902 starting at round 6 extends the QC of round 2,
+	// voting for it after block 905 breaks the lock on the QC of round 3
+	numOfForks, forkRoundDifference = 3, 3
+	blockchain, _, currentBlock, signer, signFn, currentForkBlock = PrepareXDCTestBlockChainForV2Engine(t, 905, params.TestXDPoSMockChainConfig, &ForkedBlockOptions{numOfForkedBlocks: &numOfForks, forkedRoundDifference: &forkRoundDifference})
+	engine = blockchain.Engine().(*XDPoS.XDPoS)
+	forkBlock903 := blockchain.GetBlockByHash(blockchain.GetBlockByHash(currentForkBlock.ParentHash()).ParentHash())
+	assert.Equal(t, uint64(903), forkBlock903.NumberU64())
+	assert.Nil(t, engine.EngineV2.VerifyForensicsReport(blockchain, report(t, signer, signFn, currentBlock, forkBlock903, 5, 6)))
+}
+
+func TestForensicsReportRejectsNonConflictingQCs(t *testing.T) {
+	blockchain, _, currentBlock, _, _, _ := PrepareXDCTestBlockChainForV2Engine(t, 905, params.TestXDPoSMockChainConfig, nil)
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	forensics := engine.EngineV2.GetForensicsFaker()
+
+	extractQC := func(header *types.Header) types.QuorumCert {
+		var extra types.ExtraFields_v2
+		assert.Nil(t, utils.DecodeBytesExtraFields(header.Extra, &extra))
+		return *extra.QuorumCert
+	}
+	// The QCs of consecutive blocks of the canonical chain don't conflict
+	committedQCs := []types.QuorumCert{
+		extractQC(blockchain.GetHeaderByNumber(903)),
+		extractQC(blockchain.GetHeaderByNumber(904)),
+		extractQC(currentBlock.Header()),
+	}
+	assert.Nil(t, forensics.SendForensicProof(blockchain, engine.EngineV2, committedQCs[1], committedQCs[2], committedQCs))
+
+	api := engine.APIs(blockchain)[0].Service.(*XDPoS.API)
+	reports, err := api.GetForensicsReports(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, "QC", reports[0].Proof.ForensicsType)
+	err = engine.EngineV2.VerifyForensicsReport(blockchain, reports[0])
+	assert.ErrorContains(t, err, "diverging block")
+}
+
+func TestForensicsReportVerifiesCommittedQCs(t *testing.T) {
+	numOfForks, forkRoundDifference := 4, 1
+	blockchain, _, _, _, _, currentForkBlock := PrepareXDCTestBlockChainForV2Engine(t, 906, params.TestXDPoSMockChainConfig, &ForkedBlockOptions{numOfForkedBlocks: &numOfForks, forkedRoundDifference: &forkRoundDifference})
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	forensics := engine.EngineV2.GetForensicsFaker()
+
+	extractQC := func(header *types.Header) types.QuorumCert {
+		var extra types.ExtraFields_v2
+		assert.Nil(t, utils.DecodeBytesExtraFields(header.Extra, &extra))
+		return *extra.QuorumCert
+	}
+	// Blocks 904 to 906 carry the QCs of rounds 3 to 5 committing block 903,
+	// the fork from block 902 has a QC of round 4 for its own block 903
+	committedQCs := []types.QuorumCert{
+		extractQC(blockchain.GetHeaderByNumber(904)),
+		extractQC(blockchain.GetHeaderByNumber(905)),
+		extractQC(blockchain.GetHeaderByNumber(906)),
+	}
+	forkBlock904 := blockchain.GetHeaderByHash(blockchain.GetHeaderByHash(currentForkBlock.ParentHash()).ParentHash)
+	forkQC := extractQC(forkBlock904)
+	assert.Equal(t, types.Round(4), forkQC.ProposedBlockInfo.Round)
+	assert.Nil(t, forensics.SendForensicProof(blockchain, engine.EngineV2, committedQCs[1], forkQC, committedQCs))
+
+	api := engine.APIs(blockchain)[0].Service.(*XDPoS.API)
+	reports, err := api.GetForensicsReports(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(reports))
+	assert.NotEmpty(t, reports[0].Signers)
+	assert.Nil(t, engine.EngineV2.VerifyForensicsReport(blockchain, reports[0]))
+
+	// Without the committed QCs nobody can be blamed
+	var content types.ForensicsContent
+	assert.Nil(t, json.Unmarshal([]byte(reports[0].Proof.Content), &content))
+	content.CommittedQCs = nil
+	tampered, err := json.Marshal(&content)
+	assert.Nil(t, err)
+	report := *reports[0]
+	report.Proof = &types.ForensicProof{Id: report.Proof.Id, ForensicsType: report.Proof.ForensicsType, Content: string(tampered)}
+	assert.ErrorContains(t, engine.EngineV2.VerifyForensicsReport(blockchain, &report), "committed QCs")
+}
@@ -0,0 +1,50 @@
+package engine_v2_tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/metrics"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func counterValue(name string) int64 {
+	return metrics.GetOrRegisterCounter(name, nil).Snapshot().Count()
+}
+
+func TestMissedRoundsMetrics(t *testing.T) {
+	metrics.Enable()
+
+	blockchain, _, currentBlock, signer, signFn, _ := PrepareXDCTestBlockChainForV2Engine(t, 905, params.TestXDPoSMockChainConfig, nil)
+	engineV2 := blockchain.Engine().(*XDPoS.XDPoS).EngineV2
+
+	// Block 906 at round 8 on top of block 905 at round 5 skips rounds 6 and 7
+	block := CreateBlock(blockchain, params.TestXDPoSMockChainConfig, currentBlock, 906, 8, signer.Hex(), signer, signFn, nil, nil, "")
+	assert.Nil(t, blockchain.InsertBlock(block))
+
+	masternodes := engineV2.GetMasternodes(blockchain, block.Header())
+	leaders := make(map[string]int64)
+	for _, round := range []uint64{6, 7} {
+		name := "xdpos/v2/missedrounds/" + strings.ToLower(masternodes[round%params.TestXDPoSMockChainConfig.XDPoS.Epoch%uint64(len(masternodes))].Hex())
+		leaders[name] = counterValue(name)
+	}
+	total := counterValue("xdpos/v2/missedrounds")
+
+	// Rounds are accounted once, however often the block is processed
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, engineV2.ProposedBlockHandler(blockchain, block.Header()))
+	}
+	assert.Equal(t, total+2, counterValue("xdpos/v2/missedrounds"))
+
+	var missed int64
+	for name, before := range leaders {
+		missed += counterValue(name) - before
+	}
+	assert.Equal(t, int64(2), missed)
+
+	round, _, highestQC, _, _, _ := engineV2.GetPropertiesFaker()
+	assert.Equal(t, int64(round), metrics.GetOrRegisterGauge("xdpos/v2/round", nil).Snapshot().Value())
+	assert.Equal(t, int64(highestQC.ProposedBlockInfo.Round), metrics.GetOrRegisterGauge("xdpos/v2/qc/round", nil).Snapshot().Value())
+}
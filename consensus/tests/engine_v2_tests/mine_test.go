@@ -293,3 +293,62 @@ func TestUpdateMultipleMasterNodes(t *testing.T) {
 	assert.Equal(t, blockchain.Config().XDPoS.V2.Config(900).MaxMasternodes, len(header1800.Validators)/common.AddressLength)
 	assert.Equal(t, 0, len(header1800.Penalties)/common.AddressLength)
 }
+
+// test if protectors and observers are selected from the candidates left over after the masternodes, and recorded in the epoch switch block
+func TestPrepareRecordsEpochRoles(t *testing.T) {
+	config := params.TestXDPoSMockChainConfig
+	blockchain, _, currentBlock, signer, signFn := PrepareXDCTestBlockChainWith128Candidates(t, int(config.XDPoS.Epoch+config.XDPoS.Gap)-1, config)
+	adaptor := blockchain.Engine().(*XDPoS.XDPoS)
+	merkleRoot := "b345a8560bd51926803dd17677c9f0751193914a851a4ec13063d6bf50220b53"
+	parentBlock := CreateBlock(blockchain, config, currentBlock, 1350, 450, "0xaaa0000000000000000000000000000000001350", signer, signFn, nil, nil, merkleRoot)
+	err := blockchain.InsertBlock(parentBlock)
+	assert.Nil(t, err)
+	err = blockchain.UpdateM1()
+	assert.Nil(t, err)
+	for i := 1351; i < 1800; i++ {
+		blockCoinbase := fmt.Sprintf("0xaaa000000000000000000000000000000000%4d", i)
+		block := CreateBlock(blockchain, config, parentBlock, i, int64(i-900), blockCoinbase, signer, signFn, nil, nil, merkleRoot)
+		err = blockchain.InsertBlock(block)
+		assert.Nil(t, err)
+		parentBlock = block
+	}
+
+	backup := common.TIPEpochRoles
+	common.TIPEpochRoles = big.NewInt(0)
+	defer func() { common.TIPEpochRoles = backup }()
+
+	header1800 := &types.Header{
+		ParentHash: parentBlock.Hash(),
+		Number:     big.NewInt(int64(1800)),
+		GasLimit:   params.TargetGasLimit,
+		Time:       uint64(time.Now().Unix()),
+		Coinbase:   voterAddr,
+	}
+	adaptor.EngineV2.SetNewRoundFaker(blockchain, types.Round(900), false)
+	blockInfo := &types.BlockInfo{Hash: parentBlock.Hash(), Round: types.Round(900 - 1), Number: parentBlock.Number()}
+	quorumCert := &types.QuorumCert{ProposedBlockInfo: blockInfo, Signatures: []types.Signature{{1, 2, 3, 4, 5, 6, 7, 8}}, GapNumber: 1350}
+	adaptor.EngineV2.ProcessQCFaker(blockchain, quorumCert)
+	adaptor.EngineV2.AuthorizeFaker(voterAddr)
+	err = adaptor.Prepare(blockchain, header1800)
+	assert.Nil(t, err)
+
+	v2Config := blockchain.Config().XDPoS.V2.Config(900)
+	snap, err := adaptor.EngineV2.GetSnapshot(blockchain, header1800)
+	assert.Nil(t, err)
+	candidates := snap.NextEpochCandidates
+	masternodes := adaptor.EngineV2.GetMasternodesFromEpochSwitchHeader(header1800)
+	assert.Equal(t, candidates[:v2Config.MaxMasternodes], masternodes)
+
+	roles := adaptor.EngineV2.GetEpochRolesFromEpochSwitchHeader(header1800)
+	assert.NotNil(t, roles)
+	protectorsEnd := v2Config.MaxMasternodes + v2Config.MaxProtectorNodes
+	assert.Equal(t, candidates[v2Config.MaxMasternodes:protectorsEnd], roles.Protectors)
+	assert.Equal(t, candidates[protectorsEnd:protectorsEnd+v2Config.MaxObverserNodes], roles.Observers)
+
+	// the roles are not recorded before the upgrade
+	common.TIPEpochRoles = big.NewInt(9999999999)
+	header1800.Validators, header1800.Penalties = nil, nil
+	err = adaptor.Prepare(blockchain, header1800)
+	assert.Nil(t, err)
+	assert.Nil(t, adaptor.EngineV2.GetEpochRolesFromEpochSwitchHeader(header1800))
+}
@@ -0,0 +1,87 @@
+package engine_v2_tests
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/accounts"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func slashingTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, report *types.ForensicsReport) *types.Transaction {
+	data, err := json.Marshal(report)
+	assert.Nil(t, err)
+	tx := types.NewTransaction(nonce, common.SlashingAddrBinary, big.NewInt(0), 0, big.NewInt(0), data)
+	s := types.LatestSignerForChainID(big.NewInt(chainID))
+	h := s.Hash(tx)
+	sig, err := crypto.Sign(h[:], key)
+	assert.Nil(t, err)
+	signedTx, err := tx.WithSignature(s, sig)
+	assert.Nil(t, err)
+	return signedTx
+}
+
+func TestSlashingTransaction(t *testing.T) {
+	backup := common.TIPSlashing
+	common.TIPSlashing = big.NewInt(0)
+	defer func() { common.TIPSlashing = backup }()
+
+	var numOfForks = new(int)
+	*numOfForks = 1
+	blockchain, _, currentBlock, signer, signFn, currentForkBlock := PrepareXDCTestBlockChainForV2Engine(t, 901, params.TestXDPoSMockChainConfig, &ForkedBlockOptions{numOfForkedBlocks: numOfForks})
+
+	signVote := func(block *types.Block, round types.Round) *types.Vote {
+		blockInfo := &types.BlockInfo{Hash: block.Hash(), Round: round, Number: block.Number()}
+		signedHash, err := signFn(accounts.Account{Address: signer}, types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: blockInfo, GapNumber: 450}).Bytes())
+		assert.Nil(t, err)
+		return &types.Vote{ProposedBlockInfo: blockInfo, Signature: signedHash, GapNumber: 450}
+	}
+	content, err := json.Marshal(&types.VoteEquivocationContent{
+		SmallerRoundVote: signVote(currentBlock, 1),
+		LargerRoundVote:  signVote(currentForkBlock, 1),
+		Signer:           signer,
+	})
+	assert.Nil(t, err)
+	report := &types.ForensicsReport{
+		Round:   1,
+		Signers: []common.Address{signer},
+		Proof:   &types.ForensicProof{Id: "equivocation", ForensicsType: "Vote", Content: string(content)},
+	}
+	statedb, err := blockchain.State()
+	assert.Nil(t, err)
+
+	// A report indexed at the wrong round is rejected
+	tampered := *report
+	tampered.Round = 2
+	err = core.ValidateSlashingTransaction(blockchain, nil, statedb, slashingTx(t, acc1Key, 0, &tampered))
+	assert.True(t, errors.Is(err, core.ErrInvalidSlashingReport))
+
+	// Only masternodes may send slashing transactions, they don't pay gas
+	outsider, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	err = core.ValidateSlashingTransaction(blockchain, nil, statedb, slashingTx(t, outsider, 0, report))
+	assert.Equal(t, core.ErrUnauthorizedSlasher, err)
+
+	tx := slashingTx(t, acc1Key, 0, report)
+	assert.Nil(t, core.ValidateSlashingTransaction(blockchain, nil, statedb, tx))
+
+	var usedGas uint64
+	statedb.SetTxContext(tx.Hash(), 0)
+	receipt, gas, err, _ := core.ApplySlashingTransaction(blockchain.Config(), statedb, big.NewInt(902), common.Hash{}, tx, &usedGas)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), gas)
+	assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	assert.Equal(t, common.BytesToHash(signer.Bytes()), receipt.Logs[0].Topics[0])
+
+	// The same offence can't be slashed twice
+	err = core.ValidateSlashingTransaction(blockchain, nil, statedb, slashingTx(t, acc1Key, 1, report))
+	assert.Equal(t, core.ErrAlreadySlashed, err)
+}
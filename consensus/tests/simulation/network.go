@@ -0,0 +1,124 @@
+package simulation
+
+import (
+	"container/heap"
+	"math/rand"
+	"time"
+)
+
+// NetworkConfig describes the virtual network connecting the simulated nodes.
+type NetworkConfig struct {
+	Latency time.Duration // Base one-way delay of every message
+	Jitter  time.Duration // Maximum random delay added on top of the latency
+	Loss    float64       // Probability of a message being dropped, 0 to 1
+}
+
+// Partition cuts the network into isolated groups of nodes for a period of
+// the simulated time. Nodes not listed in any group are isolated from all.
+type Partition struct {
+	Start  time.Duration // Simulated time the partition begins
+	End    time.Duration // Simulated time the partition heals
+	Groups [][]int       // Indexes of the nodes able to reach each other
+}
+
+// separates reports whether the partition blocks the messages between the two
+// nodes at the given simulated time.
+func (p *Partition) separates(now time.Duration, a, b int) bool {
+	if now < p.Start || now >= p.End {
+		return false
+	}
+	for _, group := range p.Groups {
+		var hasA, hasB bool
+		for _, n := range group {
+			hasA = hasA || n == a
+			hasB = hasB || n == b
+		}
+		if hasA || hasB {
+			return !(hasA && hasB)
+		}
+	}
+	return true
+}
+
+// network decides the fate of every message sent between the nodes.
+type network struct {
+	config     NetworkConfig
+	partitions []Partition
+	rand       *rand.Rand
+}
+
+// delay returns how long the message from a to b takes to arrive, or false if
+// the message is lost or cut by a partition.
+func (n *network) delay(now time.Duration, a, b int) (time.Duration, bool) {
+	for i := range n.partitions {
+		if n.partitions[i].separates(now, a, b) {
+			return 0, false
+		}
+	}
+	if n.config.Loss > 0 && n.rand.Float64() < n.config.Loss {
+		return 0, false
+	}
+	delay := n.config.Latency
+	if n.config.Jitter > 0 {
+		delay += time.Duration(n.rand.Int63n(int64(n.config.Jitter) + 1))
+	}
+	return delay, true
+}
+
+// event is an action scheduled on the mock clock.
+type event struct {
+	at  time.Duration
+	seq uint64 // Insertion order, keeps simultaneous events deterministic
+	fn  func()
+}
+
+// eventQueue is a min-heap of events ordered by their simulated time.
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
+
+// clock is the mock clock of the simulation, advancing from event to event.
+type clock struct {
+	now    time.Duration
+	seq    uint64
+	events eventQueue
+}
+
+// schedule runs fn once the clock reaches the given simulated time.
+func (c *clock) schedule(at time.Duration, fn func()) {
+	c.seq++
+	heap.Push(&c.events, &event{at: at, seq: c.seq, fn: fn})
+}
+
+// after runs fn once the given duration has passed on the clock.
+func (c *clock) after(d time.Duration, fn func()) {
+	c.schedule(c.now+d, fn)
+}
+
+// step advances the clock to the next event and runs it, returning false if
+// there is no event left before the deadline.
+func (c *clock) step(deadline time.Duration) bool {
+	if len(c.events) == 0 || c.events[0].at > deadline {
+		return false
+	}
+	ev := heap.Pop(&c.events).(*event)
+	c.now = ev.at
+	ev.fn()
+	return true
+}
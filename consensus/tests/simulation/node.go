@@ -0,0 +1,264 @@
+package simulation
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/accounts"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/consensus/misc/eip1559"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/core/vm"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+)
+
+// node is a single masternode of the simulation, running its own chain and
+// XDPoS v2 engine.
+type node struct {
+	index  int
+	key    *ecdsa.PrivateKey
+	addr   common.Address
+	chain  *core.BlockChain
+	engine *XDPoS.XDPoS
+	sim    *Simulator
+
+	outMu  sync.Mutex
+	outbox []interface{} // Messages broadcast by the engine, waiting to be sent
+
+	seen      map[common.Hash]struct{}       // Messages already handled, not to be relayed again
+	orphans   map[common.Hash][]*types.Block // Blocks waiting for their parent, keyed by parent hash
+	requested map[common.Hash]struct{}       // Missing parents already requested from peers
+
+	round     types.Round      // Last round observed from the engine
+	timer     uint64           // Generation of the armed countdown, stale ones are ignored
+	committed *types.BlockInfo // Last commit observed from the engine
+}
+
+// newNode creates a masternode on top of a fresh chain of the given genesis.
+func newNode(sim *Simulator, index int, key *ecdsa.PrivateKey, genesis *core.Genesis) (*node, error) {
+	db := rawdb.NewMemoryDatabase()
+	genesis.MustCommit(db)
+
+	engine := XDPoS.NewFaker(db, genesis.Config)
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	n := &node{
+		index:     index,
+		key:       key,
+		addr:      crypto.PubkeyToAddress(key.PublicKey),
+		chain:     chain,
+		engine:    engine,
+		sim:       sim,
+		seen:      make(map[common.Hash]struct{}),
+		orphans:   make(map[common.Hash][]*types.Block),
+		requested: make(map[common.Hash]struct{}),
+	}
+	engine.Authorize(n.addr, n.sign)
+	engine.EngineV2.SetBroadcastFaker(n.enqueue)
+	engine.EngineV2.DisableCountdownFaker()
+	if err := engine.EngineV2.Initial(chain, chain.Genesis().Header()); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// sign is the signing function handed to the engine.
+func (n *node) sign(account accounts.Account, hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, n.key)
+}
+
+// enqueue collects a message broadcast by the engine.
+func (n *node) enqueue(msg interface{}) {
+	n.outMu.Lock()
+	defer n.outMu.Unlock()
+
+	n.outbox = append(n.outbox, msg)
+}
+
+// observe sends out the messages broadcast by the engine and reacts to the
+// changes of its round, certificates and commits. It must run after every
+// interaction with the engine.
+func (n *node) observe() {
+	n.outMu.Lock()
+	outbox := n.outbox
+	n.outbox = nil
+	n.outMu.Unlock()
+
+	for _, msg := range outbox {
+		switch msg.(type) {
+		case *types.Vote, *types.Timeout, *types.SyncInfo:
+			n.sim.broadcast(n, msg)
+		}
+	}
+	round, _, qc, tc, _, commit := n.engine.EngineV2.GetPropertiesFaker()
+	n.sim.certified(qc, tc)
+
+	if commit != nil && (n.committed == nil || commit.Hash != n.committed.Hash) {
+		n.sim.commit(n, commit)
+		n.committed = commit
+	}
+	if round != n.round {
+		n.round = round
+		n.armTimer(round, qc.ProposedBlockInfo.Round)
+		n.scheduleProposal(round, qc)
+	}
+}
+
+// armTimer restarts the countdown of the round, as the engine does with its
+// own timer on every new round.
+func (n *node) armTimer(round, highestQCRound types.Round) {
+	n.timer++
+	timer := n.timer
+	n.sim.clock.after(n.sim.timeout.GetTimeoutDuration(round, highestQCRound), func() {
+		if timer != n.timer {
+			return
+		}
+		n.sim.report.Timeouts++
+		n.engine.EngineV2.OnCountdownTimeout(n.sim.time(), n.chain)
+		n.observe()
+		if timer == n.timer {
+			_, _, qc, _, _, _ := n.engine.EngineV2.GetPropertiesFaker()
+			n.armTimer(n.round, qc.ProposedBlockInfo.Round)
+		}
+	})
+}
+
+// scheduleProposal tries to propose a block for the round once the mine
+// period since the parent block elapsed.
+func (n *node) scheduleProposal(round types.Round, qc *types.QuorumCert) {
+	parent := n.chain.GetHeaderByHash(qc.ProposedBlockInfo.Hash)
+	if parent == nil {
+		return
+	}
+	var wait time.Duration
+	if due := parent.Time + uint64(n.sim.config.V2.MinePeriod); due > n.sim.headerTime() {
+		wait = time.Duration(due-n.sim.headerTime()) * time.Second
+	}
+	n.sim.clock.after(wait, func() {
+		if n.round == round {
+			n.propose()
+		}
+	})
+}
+
+// propose seals a block on top of the highest QC if the node is the leader of
+// the current round, and sends it out to the peers.
+func (n *node) propose() {
+	_, _, qc, _, _, _ := n.engine.EngineV2.GetPropertiesFaker()
+	parent := n.chain.GetBlockByHash(qc.ProposedBlockInfo.Hash)
+	if parent == nil {
+		return
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Coinbase:   n.addr,
+	}
+	if err := n.engine.Prepare(n.chain, header); err != nil {
+		return // Not our turn, or already mined in this round
+	}
+	// Stamp the block by the mock clock rather than the wall clock
+	header.Time = parent.Time() + uint64(n.sim.config.V2.MinePeriod)
+	if now := n.sim.headerTime(); header.Time < now {
+		header.Time = now
+	}
+	if n.chain.Config().IsEIP1559(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(n.chain.Config(), header)
+	}
+	statedb, err := n.chain.StateAt(parent.Root())
+	if err != nil {
+		return
+	}
+	block, err := n.engine.Finalize(n.chain, header, statedb, statedb.Copy(), nil, nil, nil)
+	if err != nil {
+		return
+	}
+	if block, err = n.engine.Seal(n.chain, block, nil); err != nil {
+		return
+	}
+	n.sim.report.Blocks++
+	n.importBlock(n, block)
+}
+
+// importBlock inserts a block received from a peer, or sealed locally, and
+// lets the engine vote on it. Blocks of unknown parents are parked until the
+// parent arrives, which is requested from the sender.
+func (n *node) importBlock(from *node, block *types.Block) {
+	if n.chain.HasBlock(block.Hash(), block.NumberU64()) {
+		return
+	}
+	parent := block.ParentHash()
+	if !n.chain.HasBlock(parent, block.NumberU64()-1) {
+		n.orphans[parent] = append(n.orphans[parent], block)
+		if _, ok := n.requested[parent]; !ok {
+			n.requested[parent] = struct{}{}
+			if missing := from.chain.GetBlockByHash(parent); missing != nil {
+				n.sim.send(from, n, missing)
+			}
+		}
+		return
+	}
+	if err := n.chain.InsertBlock(block); err != nil {
+		n.sim.report.Rejected++
+		return
+	}
+	delete(n.requested, block.Hash())
+	n.engine.HandleProposedBlock(n.chain, block.Header())
+	n.sim.broadcast(n, block)
+	n.observe()
+
+	children := n.orphans[block.Hash()]
+	delete(n.orphans, block.Hash())
+	for _, child := range children {
+		n.importBlock(from, child)
+	}
+}
+
+// handle processes a consensus message received from a peer the way the BFT
+// handler does: verify it, relay it and hand it to the engine.
+func (n *node) handle(msg interface{}) {
+	var hash common.Hash
+	switch msg := msg.(type) {
+	case *types.Vote:
+		hash = msg.Hash()
+	case *types.Timeout:
+		hash = msg.Hash()
+	case *types.SyncInfo:
+		hash = msg.Hash()
+	}
+	if _, ok := n.seen[hash]; ok {
+		return
+	}
+	n.seen[hash] = struct{}{}
+
+	v2 := n.engine.EngineV2
+	switch msg := msg.(type) {
+	case *types.Vote:
+		if ok, err := v2.VerifyVoteMessage(n.chain, msg); err != nil || !ok {
+			return
+		}
+		n.sim.broadcast(n, msg)
+		v2.VoteHandler(n.chain, msg)
+	case *types.Timeout:
+		if ok, err := v2.VerifyTimeoutMessage(n.chain, msg); err != nil || !ok {
+			return
+		}
+		n.sim.broadcast(n, msg)
+		v2.TimeoutHandler(n.chain, msg)
+	case *types.SyncInfo:
+		if ok, err := v2.VerifySyncInfoMessage(n.chain, msg); err != nil || !ok {
+			return
+		}
+		n.sim.broadcast(n, msg)
+		v2.SyncInfoHandler(n.chain, msg)
+	}
+	n.observe()
+}
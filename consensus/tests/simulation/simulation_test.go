@@ -0,0 +1,123 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func testV2Config(certThreshold float64) *params.V2Config {
+	return &params.V2Config{
+		MaxMasternodes:       18,
+		CertThreshold:        certThreshold,
+		TimeoutSyncThreshold: 2,
+		TimeoutPeriod:        4,
+		MinePeriod:           2,
+		ExpTimeoutConfig:     params.ExpTimeoutConfig{Base: 1.0, MaxExponent: 0},
+	}
+}
+
+func TestHealthyNetwork(t *testing.T) {
+	report, err := Run(Config{
+		Nodes:    4,
+		V2:       testV2Config(0.667),
+		Duration: 2 * time.Minute,
+		Network:  NetworkConfig{Latency: 50 * time.Millisecond, Jitter: 50 * time.Millisecond},
+	})
+	assert.Nil(t, err)
+
+	// One block every mine period, committed three rounds later
+	assert.GreaterOrEqual(t, report.Blocks, uint64(58))
+	assert.GreaterOrEqual(t, report.Committed, uint64(55))
+	assert.Zero(t, report.TimeoutCerts)
+	assert.Zero(t, report.Timeouts)
+	assert.Empty(t, report.Violations)
+	assert.Empty(t, report.Stalls)
+}
+
+func TestDeterministicRuns(t *testing.T) {
+	config := Config{
+		Nodes:    4,
+		V2:       testV2Config(0.667),
+		Duration: 2 * time.Minute,
+		Seed:     7,
+		Network:  NetworkConfig{Latency: 50 * time.Millisecond, Jitter: time.Second, Loss: 0.2},
+	}
+	first, err := Run(config)
+	assert.Nil(t, err)
+	second, err := Run(config)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestLossyNetworkTimesOut(t *testing.T) {
+	report, err := Run(Config{
+		Nodes:    4,
+		V2:       testV2Config(0.667),
+		Duration: 5 * time.Minute,
+		Seed:     1,
+		Network:  NetworkConfig{Latency: 50 * time.Millisecond, Jitter: 100 * time.Millisecond, Loss: 0.3},
+	})
+	assert.Nil(t, err)
+
+	assert.NotZero(t, report.Dropped)
+	assert.NotZero(t, report.TimeoutCerts)
+	assert.Greater(t, report.TimeoutCertRate(), 0.1)
+	assert.NotEmpty(t, report.Stalls)
+	assert.Empty(t, report.Violations)
+}
+
+func TestPartitionStallsAndRecovers(t *testing.T) {
+	report, err := Run(Config{
+		Nodes:    4,
+		V2:       testV2Config(0.667),
+		Duration: 5 * time.Minute,
+		Network:  NetworkConfig{Latency: 50 * time.Millisecond},
+		Partitions: []Partition{{
+			Start:  time.Minute,
+			End:    2 * time.Minute,
+			Groups: [][]int{{0, 1}, {2, 3}},
+		}},
+	})
+	assert.Nil(t, err)
+
+	// Neither half holds a quorum, so the chain halts until the partition heals
+	assert.Len(t, report.Stalls, 1)
+	assert.True(t, report.Stalls[0].Start < time.Minute)
+	assert.True(t, report.Stalls[0].End > 2*time.Minute)
+	assert.Greater(t, report.Committed, uint64(100))
+	assert.Empty(t, report.Violations)
+}
+
+func TestLowThresholdBreaksSafety(t *testing.T) {
+	report, err := Run(Config{
+		Nodes:    6,
+		V2:       testV2Config(0.5),
+		Duration: 4 * time.Minute,
+		Network:  NetworkConfig{Latency: 50 * time.Millisecond},
+		Partitions: []Partition{{
+			Start:  time.Minute,
+			End:    3 * time.Minute,
+			Groups: [][]int{{0, 1, 2}, {3, 4, 5}},
+		}},
+	})
+	assert.Nil(t, err)
+
+	// With half of the votes enough for a certificate, both halves of the split
+	// keep finalising their own fork
+	assert.NotEmpty(t, report.Violations)
+	for _, v := range report.Violations {
+		assert.Len(t, v.Hashes, 2)
+		assert.NotEqual(t, v.Hashes[0], v.Hashes[1])
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := Run(Config{V2: testV2Config(0.667)})
+	assert.NotNil(t, err)
+
+	_, err = Run(Config{Nodes: 4})
+	assert.NotNil(t, err)
+}
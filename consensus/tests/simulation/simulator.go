@@ -0,0 +1,365 @@
+// Package simulation runs a set of XDPoS v2 masternodes on a virtual network
+// driven by a mock clock, so that consensus parameters can be evaluated under
+// latency, message loss and partitions in a deterministic and fast manner.
+//
+// Every node runs the real engine on top of its own in-memory chain. The
+// simulator stands in for the miner, the BFT handler and the p2p layer: it
+// proposes blocks on behalf of the round leaders, fires the round countdowns
+// and carries blocks, votes, timeouts and sync infos between the nodes.
+package simulation
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/countdown"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+const (
+	// epoch is the epoch length of the simulated chain, long enough for the
+	// masternode set to never change during a run.
+	epoch = 100000
+
+	// gap is the distance of the masternode snapshot to the epoch switch.
+	gap = 50000
+)
+
+// drainCheckpoints consumes the epoch switch notifications of the chains, which
+// nobody else listens to in a simulation.
+var drainCheckpoints sync.Once
+
+// Config is the setup of a simulation run.
+type Config struct {
+	Nodes      int              // Number of masternodes
+	V2         *params.V2Config // Consensus parameters under test
+	Duration   time.Duration    // Simulated time to run for
+	Seed       int64            // Seed of the network randomness
+	Network    NetworkConfig    // Latency and loss of the network
+	Partitions []Partition      // Network partitions over time
+
+	// StallThreshold is how long the network may go without committing a new
+	// block before it is reported as stalled. Defaults to three timeout periods.
+	StallThreshold time.Duration
+}
+
+// Violation is a safety failure: two nodes committed different blocks at the
+// same height.
+type Violation struct {
+	Number uint64        // Height of the conflicting commits
+	Hashes []common.Hash // Distinct committed block hashes, in commit order
+	Nodes  []int         // Node first committing each of the hashes
+	At     time.Duration // Simulated time the conflict was detected
+}
+
+// Stall is a liveness failure: a period without any new committed block.
+type Stall struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Report is the outcome of a simulation run.
+type Report struct {
+	Rounds       uint64 // Highest round reached by any node
+	Blocks       uint64 // Blocks proposed
+	Rejected     uint64 // Blocks refused by the chain of a node
+	Committed    uint64 // Highest block number committed by any node
+	QuorumCerts  uint64 // Distinct rounds certified by a QC
+	TimeoutCerts uint64 // Distinct rounds certified by a TC
+	Timeouts     uint64 // Round countdowns expired
+	Messages     uint64 // Messages delivered
+	Dropped      uint64 // Messages lost or cut by a partition
+
+	Violations []Violation
+	Stalls     []Stall
+}
+
+// TimeoutCertRate returns the share of the certified rounds ended by a timeout
+// certificate rather than a quorum certificate.
+func (r *Report) TimeoutCertRate() float64 {
+	if total := r.QuorumCerts + r.TimeoutCerts; total > 0 {
+		return float64(r.TimeoutCerts) / float64(total)
+	}
+	return 0
+}
+
+// Simulator drives a single simulation run.
+type Simulator struct {
+	config  Config
+	clock   clock
+	network network
+	timeout *countdown.ExpTimeoutDuration
+	genesis uint64 // Timestamp of the genesis block
+	nodes   []*node
+
+	qcRounds  map[types.Round]struct{}
+	tcRounds  map[types.Round]struct{}
+	commits   map[uint64]common.Hash   // Block committed at each height, first come
+	committer map[uint64]int           // Node first committing at each height
+	conflicts map[uint64]*Violation    // Violations reported at each height
+	last      map[int]*types.BlockInfo // Last block committed by each node
+	progress  time.Duration            // Simulated time of the last new commit
+	report    Report
+}
+
+// New creates a simulator of the given setup.
+func New(config Config) (*Simulator, error) {
+	if config.Nodes < 1 {
+		return nil, errors.New("no nodes to simulate")
+	}
+	if config.V2 == nil {
+		return nil, errors.New("missing v2 consensus config")
+	}
+	timeout, err := countdown.NewExpTimeoutDuration(time.Duration(config.V2.TimeoutPeriod)*time.Second, config.V2.ExpTimeoutConfig.Base, config.V2.ExpTimeoutConfig.MaxExponent)
+	if err != nil {
+		return nil, err
+	}
+	if config.StallThreshold == 0 {
+		config.StallThreshold = 3 * time.Duration(config.V2.TimeoutPeriod) * time.Second
+	}
+	drainCheckpoints.Do(func() {
+		go func() {
+			for range core.CheckpointCh {
+			}
+		}()
+	})
+	s := &Simulator{
+		config: config,
+		network: network{
+			config:     config.Network,
+			partitions: config.Partitions,
+			rand:       rand.New(rand.NewSource(config.Seed)),
+		},
+		timeout:   timeout,
+		qcRounds:  make(map[types.Round]struct{}),
+		tcRounds:  make(map[types.Round]struct{}),
+		commits:   make(map[uint64]common.Hash),
+		committer: make(map[uint64]int),
+		conflicts: make(map[uint64]*Violation),
+		last:      make(map[int]*types.BlockInfo),
+	}
+	// Start the chain far enough in the past for no simulated block to be
+	// stamped in the future of the wall clock, which the engine refuses.
+	s.genesis = uint64(time.Now().Add(-config.Duration - time.Hour).Unix())
+
+	keys := make([]*ecdsa.PrivateKey, config.Nodes)
+	for i := range keys {
+		keys[i] = newKey(i)
+	}
+	genesis := s.makeGenesis(keys)
+	for i, key := range keys {
+		n, err := newNode(s, i, key, genesis)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %w", i, err)
+		}
+		s.nodes = append(s.nodes, n)
+	}
+	return s, nil
+}
+
+// Run simulates the network for the configured duration and reports how the
+// consensus behaved.
+func Run(config Config) (*Report, error) {
+	s, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	return s.Run(), nil
+}
+
+// Run simulates the network for the configured duration and reports how the
+// consensus behaved.
+func (s *Simulator) Run() *Report {
+	for _, n := range s.nodes {
+		n.observe()
+	}
+	for s.clock.step(s.config.Duration) {
+	}
+	s.clock.now = s.config.Duration
+	s.stalled()
+
+	for _, n := range s.nodes {
+		if round := uint64(n.round); round > s.report.Rounds {
+			s.report.Rounds = round
+		}
+	}
+	s.report.QuorumCerts = uint64(len(s.qcRounds))
+	s.report.TimeoutCerts = uint64(len(s.tcRounds))
+	for _, v := range s.conflicts {
+		s.report.Violations = append(s.report.Violations, *v)
+	}
+	sort.Slice(s.report.Violations, func(i, j int) bool {
+		return s.report.Violations[i].Number < s.report.Violations[j].Number
+	})
+	report := s.report
+	return &report
+}
+
+// makeGenesis creates the genesis of the simulated chain, switching to the v2
+// engine right away with all the nodes as masternodes.
+func (s *Simulator) makeGenesis(keys []*ecdsa.PrivateKey) *core.Genesis {
+	v2 := &params.V2{
+		SwitchBlock:   big.NewInt(0),
+		CurrentConfig: s.config.V2,
+		AllConfigs:    map[uint64]*params.V2Config{0: s.config.V2},
+	}
+	v2.BuildConfigIndex()
+
+	xdpos := *params.TestXDPoSMockChainConfig.XDPoS
+	xdpos.Epoch, xdpos.Gap = epoch, gap
+	xdpos.Period = uint64(s.config.V2.MinePeriod)
+	xdpos.V2 = v2
+
+	config := *params.TestXDPoSMockChainConfig
+	config.XDPoS = &xdpos
+
+	extra := make([]byte, 32, 32+len(keys)*common.AddressLength+65)
+	for _, key := range keys {
+		extra = append(extra, crypto.PubkeyToAddress(key.PublicKey).Bytes()...)
+	}
+	extra = append(extra, make([]byte, 65)...)
+
+	return &core.Genesis{
+		Config:     &config,
+		Timestamp:  s.genesis,
+		ExtraData:  extra,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      core.GenesisAlloc{},
+	}
+}
+
+// time returns the wall clock time matching the simulated time.
+func (s *Simulator) time() time.Time {
+	return time.Unix(int64(s.genesis), 0).Add(s.clock.now)
+}
+
+// headerTime returns the block timestamp matching the simulated time.
+func (s *Simulator) headerTime() uint64 {
+	return s.genesis + uint64(s.clock.now/time.Second)
+}
+
+// broadcast sends a message to all the peers of a node.
+func (s *Simulator) broadcast(from *node, msg interface{}) {
+	for _, to := range s.nodes {
+		if to != from {
+			s.send(from, to, msg)
+		}
+	}
+}
+
+// send carries a message over the virtual network, unless it gets lost.
+func (s *Simulator) send(from, to *node, msg interface{}) {
+	delay, ok := s.network.delay(s.clock.now, from.index, to.index)
+	if !ok {
+		s.report.Dropped++
+		return
+	}
+	// Hand every node its own copy, the engine records the signer on them
+	switch m := msg.(type) {
+	case *types.Vote:
+		msg = &types.Vote{ProposedBlockInfo: m.ProposedBlockInfo, Signature: m.Signature, GapNumber: m.GapNumber}
+	case *types.Timeout:
+		msg = &types.Timeout{Round: m.Round, Signature: m.Signature, GapNumber: m.GapNumber}
+	}
+	s.clock.after(delay, func() {
+		s.report.Messages++
+		if block, ok := msg.(*types.Block); ok {
+			to.importBlock(from, block)
+		} else {
+			to.handle(msg)
+		}
+	})
+}
+
+// certified records the rounds certified by the highest certificates of a node.
+func (s *Simulator) certified(qc *types.QuorumCert, tc *types.TimeoutCert) {
+	if qc != nil && qc.ProposedBlockInfo.Number.Sign() > 0 {
+		s.qcRounds[qc.ProposedBlockInfo.Round] = struct{}{}
+	}
+	if tc != nil && tc.Round > 0 {
+		s.tcRounds[tc.Round] = struct{}{}
+	}
+}
+
+// commit records the blocks newly committed by a node, down to its previous
+// commit, and checks them against the commits of the other nodes.
+func (s *Simulator) commit(n *node, info *types.BlockInfo) {
+	var floor uint64
+	if last := s.last[n.index]; last != nil {
+		floor = last.Number.Uint64()
+	}
+	s.last[n.index] = info
+
+	for header := n.chain.GetHeaderByHash(info.Hash); header != nil && header.Number.Uint64() > floor; header = n.chain.GetHeaderByHash(header.ParentHash) {
+		number, hash := header.Number.Uint64(), header.Hash()
+
+		committed, ok := s.commits[number]
+		if !ok {
+			s.commits[number] = hash
+			s.committer[number] = n.index
+			continue
+		}
+		if committed == hash {
+			continue
+		}
+		v := s.conflicts[number]
+		if v == nil {
+			v = &Violation{
+				Number: number,
+				Hashes: []common.Hash{committed},
+				Nodes:  []int{s.committer[number]},
+				At:     s.clock.now,
+			}
+			s.conflicts[number] = v
+		}
+		if !containsHash(v.Hashes, hash) {
+			v.Hashes = append(v.Hashes, hash)
+			v.Nodes = append(v.Nodes, n.index)
+		}
+	}
+	if number := info.Number.Uint64(); number > s.report.Committed {
+		s.stalled()
+		s.report.Committed = number
+		s.progress = s.clock.now
+	}
+}
+
+// stalled reports a stall if the network went too long without a new commit.
+func (s *Simulator) stalled() {
+	if s.clock.now-s.progress > s.config.StallThreshold {
+		s.report.Stalls = append(s.report.Stalls, Stall{Start: s.progress, End: s.clock.now})
+	}
+}
+
+// containsHash reports whether the hash is in the list.
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// newKey derives the deterministic key of the node of the given index.
+func newKey(index int) *ecdsa.PrivateKey {
+	seed := make([]byte, 8)
+	binary.BigEndian.PutUint64(seed, uint64(index))
+	key, err := crypto.ToECDSA(crypto.Keccak256(seed))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
@@ -138,9 +138,7 @@ func (v *BlockValidator) ValidateTradingOrder(statedb *state.StateDB, XDCxStated
 			Rejects: newRejectedOrders,
 		}
 	}
-	if XDCXService.IsSDKNode() {
-		v.bc.AddMatchingResult(txMatchBatch.TxHash, tradingResult)
-	}
+	v.bc.AddMatchingResult(txMatchBatch.TxHash, tradingResult)
 	return nil
 }
 
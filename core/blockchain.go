@@ -21,8 +21,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"math/big"
 	"os"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -41,6 +43,7 @@ import (
 	contractValidator "github.com/XinFinOrg/XDPoSChain/contracts/validator/contract"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
 	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/state/snapshot"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/core/vm"
 	"github.com/XinFinOrg/XDPoSChain/crypto"
@@ -136,6 +139,7 @@ type CacheConfig struct {
 	TrieDirtyDisabled   bool          // Whether to disable trie write caching and GC altogether (archive node)
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory, 0 disables snapshots
 }
 
 type ResultProcessBlock struct {
@@ -188,6 +192,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache state.Database // State database to reuse between imports (contains state cache)
+	snaps      *snapshot.Tree // Snapshot tree for fast trie leaf access
 
 	bodyCache        *lru.Cache[common.Hash, *types.Body]         // Cache for the most recent block bodies
 	bodyRLPCache     *lru.Cache[common.Hash, rlp.RawValue]        // Cache for the most recent block bodies in RLP encoded format
@@ -291,6 +296,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	// Load any existing snapshot, regenerating it if loading failed
+	if bc.cacheConfig.SnapshotLimit > 0 {
+		bc.snaps = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, bc.CurrentBlock().Root())
+	}
 
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
@@ -532,7 +541,33 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64) error {
 	bc.futureBlocks.Purge()
 	bc.blocksHashCache.Purge()
 
-	return bc.loadLastState()
+	if err := bc.loadLastState(); err != nil {
+		return err
+	}
+	bc.ensureSnapshot(bc.CurrentBlock().Root())
+	return nil
+}
+
+// ensureSnapshot regenerates the state snapshot from the given head root if the
+// snapshot tree does not maintain its state anymore, which happens when the chain
+// is rewound beyond the persistent snapshot layer.
+func (bc *BlockChain) ensureSnapshot(root common.Hash) {
+	if bc.snaps != nil && bc.snaps.Snapshot(root) == nil {
+		log.Warn("Head state missing from snapshot, regenerating", "root", root)
+		bc.snaps.Rebuild(root)
+	}
+}
+
+// capSnapshot flattens the snapshot diff layers below the given canonical head
+// root into the persistent layer, keeping triesInMemory of them in memory.
+// Layers of side chains forking below the new persistent layer are dropped.
+func (bc *BlockChain) capSnapshot(root common.Hash) {
+	if bc.snaps == nil || bc.snaps.Snapshot(root) == nil {
+		return
+	}
+	if err := bc.snaps.Cap(root, triesInMemory); err != nil {
+		log.Warn("Failed to cap snapshot tree", "root", root, "layers", triesInMemory, "err", err)
+	}
 }
 
 // FastSyncCommitHead sets the current head block to the one defined by the hash
@@ -593,7 +628,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
 }
 
 // StateCache returns the caching database underpinning the blockchain instance.
@@ -1138,6 +1173,15 @@ func (bc *BlockChain) Stop() {
 	// returned.
 	bc.chainmu.Close()
 	bc.wg.Wait()
+
+	// Flatten the snapshot diff layers into the disk layer, so that the snapshot
+	// can be reused on the next startup
+	if bc.snaps != nil {
+		if err := bc.snaps.Cap(bc.CurrentBlock().Root(), 0); err != nil {
+			log.Error("Failed to persist state snapshot", "err", err)
+		}
+		bc.snaps.Release()
+	}
 	bc.saveData()
 	log.Info("Blockchain manager stopped")
 }
@@ -1354,12 +1398,18 @@ func (bc *BlockChain) writeBlockWithoutState(block *types.Block, td *big.Int) (e
 }
 
 // WriteBlockWithState writes the block and all associated state to the database.
+// The orders and trades of a mined block becoming the head are recorded from the
+// matching results the miner cached with AddMatchingResult.
 func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB, tradingState *tradingstate.TradingStateDB, lendingState *lendingstate.LendingStateDB) (status WriteStatus, err error) {
 	if !bc.chainmu.TryLock() {
 		return NonStatTy, errInsertionInterrupted
 	}
 	defer bc.chainmu.Unlock()
-	return bc.writeBlockWithState(block, receipts, state, tradingState, lendingState)
+	status, err = bc.writeBlockWithState(block, receipts, state, tradingState, lendingState)
+	if err == nil && status == CanonStatTy && bc.isExchangeBlock(block) {
+		bc.logExchangeData(block)
+	}
+	return status, err
 }
 
 // writeBlockWithState writes the block and all associated state to the database,
@@ -1552,6 +1602,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			if err := bc.reorg(currentBlock.Header(), block.Header()); err != nil {
 				return NonStatTy, err
 			}
+			// Side chain states are kept as diff layers, only a reorg below
+			// the persistent snapshot layer requires a regeneration
+			bc.ensureSnapshot(block.Root())
 		}
 		status = CanonStatTy
 	} else {
@@ -1562,6 +1615,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if status == CanonStatTy {
 		// WriteBlock has already been called, no need to write again
 		bc.writeHeadBlock(block, false)
+		bc.capSnapshot(block.Root())
 		// prepare set of masternodes for the next epoch
 		if bc.chainConfig.XDPoS != nil && ((block.NumberU64() % bc.chainConfig.XDPoS.Epoch) == (bc.chainConfig.XDPoS.Epoch - bc.chainConfig.XDPoS.Gap)) {
 			if err := bc.UpdateM1(); err != nil {
@@ -1733,7 +1787,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 			parent = bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
 		}
 		// Create a new statedb using the parent block and report an error if it fails.
-		statedb, err := state.New(parent.Root, bc.stateCache)
+		statedb, err := state.NewWithSnapshot(parent.Root, bc.stateCache, bc.snaps)
 		if err != nil {
 			return it.index, events, coalescedLogs, err
 		}
@@ -2087,7 +2141,7 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 	}
 	var parent = bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
 	// Create a new statedb using the parent block and report an error if it fails.
-	statedb, err := state.New(parent.Root, bc.stateCache)
+	statedb, err := state.NewWithSnapshot(parent.Root, bc.stateCache, bc.snaps)
 	if err != nil {
 		return nil, err
 	}
@@ -2389,6 +2443,9 @@ func (bc *BlockChain) reorg(oldHead, newHead *types.Header) error {
 		for _, tx := range block.Transactions() {
 			deletedTxs = append(deletedTxs, tx.Hash())
 		}
+		if bc.isExchangeBlock(block) {
+			bc.rollbackExchangeData(block)
+		}
 		// Collect deleted logs and emit them for new integrations
 		// if logs := bc.collectLogs(block, true); len(logs) > 0 {
 		// 	slices.Reverse(logs) // Emit revertals latest first, older then
@@ -2415,6 +2472,10 @@ func (bc *BlockChain) reorg(oldHead, newHead *types.Header) error {
 		}
 		// Update the head block
 		bc.writeHeadBlock(block, true)
+		// The new head itself is logged by the caller once it's written
+		if i > 0 && bc.isExchangeBlock(block) {
+			bc.logExchangeData(block)
+		}
 		// prepare set of masternodes for the next epoch
 		if bc.chainConfig.XDPoS != nil && ((block.NumberU64() % bc.chainConfig.XDPoS.Epoch) == (bc.chainConfig.XDPoS.Epoch - bc.chainConfig.XDPoS.Gap)) {
 			if err := bc.UpdateM1(); err != nil {
@@ -2751,13 +2812,21 @@ func (bc *BlockChain) UpdateM1() error {
 	return nil
 }
 
+// isExchangeBlock reports whether the block may carry XDCx matching data.
+func (bc *BlockChain) isExchangeBlock(block *types.Block) bool {
+	return bc.chainConfig.XDPoS != nil && bc.chainConfig.IsTIPXDCX(block.Number()) && block.NumberU64() > bc.chainConfig.XDPoS.Epoch
+}
+
+// logExchangeData records the orders and trades matched in the block into the
+// XDCx history database. Every node keeps this history, SDK nodes in their
+// add-on database and the others in the XDCx leveldb.
 func (bc *BlockChain) logExchangeData(block *types.Block) {
 	engine, ok := bc.Engine().(*XDPoS.XDPoS)
 	if !ok || engine == nil {
 		return
 	}
 	XDCXService := engine.GetXDCXService()
-	if XDCXService == nil || !XDCXService.IsSDKNode() {
+	if XDCXService == nil {
 		return
 	}
 	txMatchBatchData, err := ExtractTradingTransactions(block.Transactions())
@@ -2815,6 +2884,28 @@ func (bc *BlockChain) logExchangeData(block *types.Block) {
 	}
 }
 
+// rollbackExchangeData reverts the XDCx history written for the trading
+// transactions of a block dropped from the canonical chain.
+func (bc *BlockChain) rollbackExchangeData(block *types.Block) {
+	engine, ok := bc.Engine().(*XDPoS.XDPoS)
+	if !ok || engine == nil {
+		return
+	}
+	XDCXService := engine.GetXDCXService()
+	if XDCXService == nil {
+		return
+	}
+	txs := block.Transactions()
+	for i := len(txs) - 1; i >= 0; i-- {
+		if !txs[i].IsTradingTransaction() {
+			continue
+		}
+		if err := XDCXService.RollbackReorgTxMatch(txs[i].Hash()); err != nil {
+			log.Error("Failed to rollback XDCx data", "number", block.NumberU64(), "txhash", txs[i].Hash(), "err", err)
+		}
+	}
+}
+
 func (bc *BlockChain) logLendingData(block *types.Block) {
 	engine, ok := bc.Engine().(*XDPoS.XDPoS)
 	if !ok || engine == nil {
@@ -2869,8 +2960,8 @@ func (bc *BlockChain) logLendingData(block *types.Block) {
 		}
 	}
 
-	// update finalizedTrades
-	if block.Number().Uint64()%bc.chainConfig.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
+	// update finalizedTrades, settled liquidation auctions are finalized on any block
+	if block.Number().Uint64()%bc.chainConfig.XDPoS.Epoch == common.LiquidateLendingTradeBlock || bc.chainConfig.IsTIPXDCXLendingAuction(block.Number()) {
 		finalizedTx, err := ExtractLendingFinalizedTradeTransactions(block.Transactions())
 		if err != nil {
 			log.Crit("failed to extract finalizedTrades transaction", "err", err)
@@ -2896,6 +2987,36 @@ func (bc *BlockChain) AddMatchingResult(txHash common.Hash, matchingResults map[
 	}
 }
 
+// addActivationResult reports the trades and rejects of the stop orders activated
+// in a block along with the last order of the block's trading batch, as the miner
+// does. Activations of blocks without trading batch are not reported.
+func (bc *BlockChain) addActivationResult(txMatchBatches []tradingstate.TxMatchBatch, result tradingstate.MatchingResult) {
+	if len(txMatchBatches) == 0 || (len(result.Trades) == 0 && len(result.Rejects) == 0) {
+		return
+	}
+	txMatchBatch := txMatchBatches[len(txMatchBatches)-1]
+	if len(txMatchBatch.Data) == 0 {
+		return
+	}
+	order, err := txMatchBatch.Data[len(txMatchBatch.Data)-1].DecodeOrder()
+	if err != nil {
+		return
+	}
+	cacheKey := crypto.Keccak256Hash(txMatchBatch.TxHash.Bytes(), tradingstate.GetMatchingResultCacheKey(order).Bytes())
+	var (
+		trades  []map[string]string
+		rejects []*tradingstate.OrderItem
+	)
+	if cached, ok := bc.resultTrade.Get(cacheKey); ok {
+		trades, _ = cached.([]map[string]string)
+	}
+	if cached, ok := bc.rejectedOrders.Get(cacheKey); ok {
+		rejects, _ = cached.([]*tradingstate.OrderItem)
+	}
+	bc.resultTrade.Add(cacheKey, append(slices.Clone(trades), result.Trades...))
+	bc.rejectedOrders.Add(cacheKey, append(slices.Clone(rejects), result.Rejects...))
+}
+
 func (bc *BlockChain) AddLendingResult(txHash common.Hash, lendingResults map[common.Hash]lendingstate.MatchingResult) {
 	for hash, result := range lendingResults {
 		bc.resultLendingTrade.Add(crypto.Keccak256Hash(txHash.Bytes(), hash.Bytes()), result.Trades)
@@ -2928,7 +3049,8 @@ func (bc *BlockChain) AddFinalizedTrades(txHash common.Hash, trades map[common.H
 //  5. Handles epoch switch logic, including updating medium prices for trading services if the block is an epoch switch block.
 //  6. Validates trading and lending orders using the block's transactions and state.
 //  7. Processes liquidation data for lending trades if the block is a liquidation block.
-//  8. Verifies the integrity of the trading and lending state roots by comparing the computed roots with the expected roots.
+//  8. Activates the stop orders of the orderbooks whose last price crossed their trigger prices.
+//  9. Verifies the integrity of the trading and lending state roots by comparing the computed roots with the expected roots.
 func (bc *BlockChain) processTradingAndLendingStates(isValidBlockNumber bool, block *types.Block, parent *types.Header, statedb *state.StateDB) (*tradingstate.TradingStateDB, *lendingstate.LendingStateDB, error) {
 	if !isValidBlockNumber || bc.chainConfig.XDPoS == nil || block.NumberU64() <= bc.chainConfig.XDPoS.Epoch {
 		return nil, nil, nil
@@ -2968,7 +3090,7 @@ func (bc *BlockChain) processTradingAndLendingStates(isValidBlockNumber bool, bl
 	}
 
 	if isEpochSwithBlock {
-		if err := tradingService.UpdateMediumPriceBeforeEpoch(epochNumber, tradingState, statedb); err != nil {
+		if err := tradingService.UpdateMediumPriceBeforeEpoch(block.Header(), epochNumber, tradingState, statedb); err != nil {
 			return tradingState, lendingState, err
 		}
 	} else {
@@ -2994,19 +3116,27 @@ func (bc *BlockChain) processTradingAndLendingStates(isValidBlockNumber bool, bl
 				return tradingState, lendingState, err
 			}
 		}
+		// run liquidation auctions
+		finalizedTrades, _, _, err := lendingService.ProcessLiquidationAuctions(block.Header(), bc, statedb, tradingState, lendingState)
+		if err != nil {
+			return tradingState, lendingState, fmt.Errorf("failed to ProcessLiquidationAuctions. Err: %v", err)
+		}
 		// liquidate / finalize open lendingTrades
 		if block.Number().Uint64()%bc.chainConfig.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
-			finalizedTrades, _, _, _, _, err := lendingService.ProcessLiquidationData(block.Header(), bc, statedb, tradingState, lendingState)
+			liquidatedTrades, _, _, _, _, err := lendingService.ProcessLiquidationData(block.Header(), bc, statedb, tradingState, lendingState)
 			if err != nil {
 				return tradingState, lendingState, fmt.Errorf("failed to ProcessLiquidationData. Err: %v", err)
 			}
-			if tradingService.IsSDKNode() {
-				finalizedTx := lendingstate.FinalizedResult{}
-				if finalizedTx, err = ExtractLendingFinalizedTradeTransactions(block.Transactions()); err != nil {
-					return tradingState, lendingState, err
-				}
-				bc.AddFinalizedTrades(finalizedTx.TxHash, finalizedTrades)
+			maps.Copy(finalizedTrades, liquidatedTrades)
+		}
+		// activate the stop orders crossed by the trades of the block
+		bc.addActivationResult(txMatchBatchData, tradingService.ActivateStopOrders(block.Header(), author, bc, statedb, tradingState))
+		if tradingService.IsSDKNode() && len(finalizedTrades) > 0 {
+			finalizedTx := lendingstate.FinalizedResult{}
+			if finalizedTx, err = ExtractLendingFinalizedTradeTransactions(block.Transactions()); err != nil {
+				return tradingState, lendingState, err
 			}
+			bc.AddFinalizedTrades(finalizedTx.TxHash, finalizedTrades)
 		}
 	}
 
@@ -1955,3 +1955,107 @@ func TestDeleteCreateRevert(t *testing.T) {
 		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
 	}
 }
+
+// Tests that the state snapshot follows the chain head across block imports,
+// reorgs and rewinds, and is persisted on shutdown.
+func TestSnapshotFollowsHead(t *testing.T) {
+	engine := ethash.NewFaker()
+	gspec := &Genesis{
+		Config:  params.TestChainConfig,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(db)
+
+	shared, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 4, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+	original, _ := GenerateChain(params.TestChainConfig, shared[len(shared)-1], engine, db, 3, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{2}) })
+	competitor, _ := GenerateChain(params.TestChainConfig, shared[len(shared)-1], engine, db, 4, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{3}) })
+
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		SnapshotLimit:  1,
+
+		// The prefetcher outlives the imports, racing with the rewinds
+		TrieCleanNoPrefetch: true,
+	}, params.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// wait waits for the snapshot generation of the head state to finish
+	wait := func() {
+		t.Helper()
+		for i := 0; ; i++ {
+			it, err := chain.snaps.AccountIterator(chain.CurrentBlock().Root(), common.Hash{})
+			if err == nil {
+				it.Release()
+				return
+			}
+			if i == 500 {
+				t.Fatalf("snapshot generation timed out")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	wait()
+
+	// check asserts that the snapshot serves the head state
+	check := func(coinbase common.Address) {
+		t.Helper()
+		head := chain.CurrentBlock()
+		snap := chain.snaps.Snapshot(head.Root())
+		if snap == nil {
+			t.Fatalf("head %d state missing from snapshot", head.NumberU64())
+		}
+		statedb, _ := state.New(head.Root(), chain.stateCache)
+		account, err := snap.Account(crypto.Keccak256Hash(coinbase[:]))
+		if err != nil || account == nil || account.Balance.Cmp(statedb.GetBalance(coinbase)) != 0 {
+			t.Fatalf("head %d coinbase mismatch: have %v, want %v, err %v", head.NumberU64(), account, statedb.GetBalance(coinbase), err)
+		}
+	}
+	if _, err := chain.InsertChain(shared); err != nil {
+		t.Fatalf("failed to insert shared chain: %v", err)
+	}
+	if _, err := chain.InsertChain(original); err != nil {
+		t.Fatalf("failed to insert original chain: %v", err)
+	}
+	check(common.Address{2})
+
+	// The reorg and the rewind stay within the diff layers, they must neither
+	// regenerate the snapshot nor drop the layers of the old chain
+	diskRoot := chain.snaps.DiskRoot()
+	if _, err := chain.InsertChain(competitor); err != nil {
+		t.Fatalf("failed to insert competitor chain: %v", err)
+	}
+	if chain.CurrentBlock().Hash() != competitor[len(competitor)-1].Hash() {
+		t.Fatalf("competitor chain not canonical")
+	}
+	check(common.Address{3})
+	if chain.snaps.Snapshot(original[len(original)-1].Root()) == nil {
+		t.Fatalf("reorged chain state dropped from snapshot")
+	}
+	if chain.snaps.DiskRoot() != diskRoot {
+		t.Fatalf("snapshot regenerated on reorg")
+	}
+
+	if err := chain.SetHead(shared[len(shared)-1].NumberU64()); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	check(common.Address{1})
+	if chain.snaps.DiskRoot() != diskRoot {
+		t.Fatalf("snapshot regenerated on rewind")
+	}
+
+	// Check the head gets persisted on shutdown
+	wait()
+	chain.Stop()
+	if have, want := rawdb.ReadSnapshotRoot(diskdb), chain.CurrentBlock().Root(); have != want {
+		t.Fatalf("persisted snapshot root mismatch: have %x, want %x", have, want)
+	}
+}
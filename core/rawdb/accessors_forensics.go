@@ -0,0 +1,139 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/log"
+)
+
+// forensicsIDHash maps a forensic proof id, which has no fixed length, to the
+// hash used in the database keys.
+func forensicsIDHash(id string) common.Hash {
+	return crypto.Keccak256Hash([]byte(id))
+}
+
+func decodeForensicsReport(data []byte) *types.ForensicsReport {
+	report := new(types.ForensicsReport)
+	if err := json.Unmarshal(data, report); err != nil {
+		log.Error("Invalid forensics report JSON", "err", err)
+		return nil
+	}
+	return report
+}
+
+// ReadForensicsReport retrieves a forensics report by the round it was indexed
+// at and the id of its proof.
+func ReadForensicsReport(db ethdb.KeyValueReader, round uint64, id string) *types.ForensicsReport {
+	data, _ := db.Get(forensicsReportKey(round, forensicsIDHash(id)))
+	if len(data) == 0 {
+		return nil
+	}
+	return decodeForensicsReport(data)
+}
+
+// WriteForensicsReport stores a forensics report and indexes it by every signer
+// it blames. Storing the same proof twice overwrites the first copy.
+func WriteForensicsReport(db ethdb.KeyValueWriter, report *types.ForensicsReport) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Crit("Failed to encode forensics report", "err", err)
+	}
+	var (
+		round  = uint64(report.Round)
+		idHash = forensicsIDHash(report.Proof.Id)
+	)
+	if err := db.Put(forensicsReportKey(round, idHash), data); err != nil {
+		log.Crit("Failed to store forensics report", "err", err)
+	}
+	for _, signer := range report.Signers {
+		if err := db.Put(forensicsSignerKey(signer, round, idHash), nil); err != nil {
+			log.Crit("Failed to store forensics signer index", "err", err)
+		}
+	}
+}
+
+// ReadForensicsReports retrieves the forensics reports indexed within rounds
+// [from, to] in ascending round order. The first skip reports are left out and
+// at most limit are returned, a non positive limit returning all of them.
+func ReadForensicsReports(db ethdb.Iteratee, from, to uint64, skip, limit int) []*types.ForensicsReport {
+	it := db.NewIterator(forensicsReportPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var reports []*types.ForensicsReport
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(forensicsReportPrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(forensicsReportPrefix):]) > to {
+			break
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if report := decodeForensicsReport(it.Value()); report != nil {
+			reports = append(reports, report)
+		}
+		if limit > 0 && len(reports) >= limit {
+			break
+		}
+	}
+	return reports
+}
+
+// ReadSignerForensicsReports retrieves the forensics reports blaming a signer
+// indexed within rounds [from, to], with the same ordering and paging as
+// ReadForensicsReports.
+func ReadSignerForensicsReports(db ethdb.KeyValueStore, signer common.Address, from, to uint64, skip, limit int) []*types.ForensicsReport {
+	prefix := forensicsSignerKeyPrefix(signer)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var reports []*types.ForensicsReport
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+8+common.HashLength {
+			continue
+		}
+		round := binary.BigEndian.Uint64(key[len(prefix) : len(prefix)+8])
+		if round > to {
+			break
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		data, _ := db.Get(forensicsReportKey(round, common.BytesToHash(key[len(prefix)+8:])))
+		if len(data) == 0 {
+			continue
+		}
+		if report := decodeForensicsReport(data); report != nil {
+			reports = append(reports, report)
+		}
+		if limit > 0 && len(reports) >= limit {
+			break
+		}
+	}
+	return reports
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+)
+
+// Tests that forensics reports can be stored and paged through by round and by
+// blamed signer.
+func TestForensicsReportStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		signer1 = common.HexToAddress("0xaa")
+		signer2 = common.HexToAddress("0xbb")
+	)
+	newReport := func(round types.Round, signers ...common.Address) *types.ForensicsReport {
+		return &types.ForensicsReport{
+			Round:   round,
+			Signers: signers,
+			Proof:   &types.ForensicProof{Id: fmt.Sprintf("proof-%d-%d", round, len(signers)), ForensicsType: "QC", Content: "{}"},
+		}
+	}
+	if report := ReadForensicsReport(db, 10, "proof-10-1"); report != nil {
+		t.Fatalf("non existent report returned: %v", report)
+	}
+	WriteForensicsReport(db, newReport(10, signer1))
+	WriteForensicsReport(db, newReport(10, signer1, signer2))
+	WriteForensicsReport(db, newReport(20, signer2))
+	WriteForensicsReport(db, newReport(300, signer1))
+
+	if report := ReadForensicsReport(db, 10, "proof-10-2"); report == nil || len(report.Signers) != 2 || report.Proof.ForensicsType != "QC" {
+		t.Fatalf("report mismatch: %v", report)
+	}
+	if reports := ReadForensicsReports(db, 0, 100, 0, 0); len(reports) != 3 {
+		t.Fatalf("reports within rounds mismatch: have %d, want 3", len(reports))
+	}
+	if reports := ReadForensicsReports(db, 10, 300, 1, 2); len(reports) != 2 || reports[1].Round != 20 {
+		t.Fatalf("paged reports mismatch: %v", reports)
+	}
+	if reports := ReadSignerForensicsReports(db, signer1, 0, 1000, 0, 0); len(reports) != 3 || reports[2].Round != 300 {
+		t.Fatalf("signer reports mismatch: %v", reports)
+	}
+	if reports := ReadSignerForensicsReports(db, signer2, 11, 1000, 0, 1); len(reports) != 1 || reports[0].Round != 20 {
+		t.Fatalf("paged signer reports mismatch: %v", reports)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/log"
+)
+
+// rewardSignerFields are the fields of the epoch rewards listing the rewarded
+// masternodes, protectors and observers.
+var rewardSignerFields = []string{"signers", "signersProtector", "signersObserver"}
+
+// RewardEntry locates the rewards of an epoch switch block.
+type RewardEntry struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// RewardAccounts returns the nodes rewarded by the given json encoded epoch
+// rewards.
+func RewardAccounts(rewards []byte) ([]common.Address, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(rewards, &fields); err != nil {
+		return nil, err
+	}
+	var accounts []common.Address
+	for _, field := range rewardSignerFields {
+		if len(fields[field]) == 0 {
+			continue
+		}
+		var signers map[common.Address]json.RawMessage
+		if err := json.Unmarshal(fields[field], &signers); err != nil {
+			return nil, err
+		}
+		for account := range signers {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+// ReadRewards retrieves the json encoded rewards distributed by an epoch
+// switch block.
+func ReadRewards(db ethdb.KeyValueReader, number uint64, hash common.Hash) []byte {
+	data, _ := db.Get(rewardKey(number, hash))
+	return data
+}
+
+// HasRewards verifies the existence of the rewards of an epoch switch block.
+func HasRewards(db ethdb.KeyValueReader, number uint64, hash common.Hash) bool {
+	has, _ := db.Has(rewardKey(number, hash))
+	return has
+}
+
+// WriteRewards stores the json encoded rewards distributed by an epoch switch
+// block and indexes them by every rewarded node. An error is only returned if
+// the rewards can't be decoded. Rewards are kept when their block is reorged,
+// the block may become canonical again without being processed anew.
+func WriteRewards(db ethdb.KeyValueWriter, number uint64, hash common.Hash, rewards []byte) error {
+	accounts, err := RewardAccounts(rewards)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(rewardKey(number, hash), rewards); err != nil {
+		log.Crit("Failed to store epoch rewards", "err", err)
+	}
+	for _, account := range accounts {
+		if err := db.Put(accountRewardKey(account, number, hash), nil); err != nil {
+			log.Crit("Failed to store account reward index", "err", err)
+		}
+	}
+	return nil
+}
+
+// IterateAccountRewardEntries seeks to the first epoch switch block at or after
+// from that rewarded an account and walks the index in ascending block order up
+// to to, until the callback returns false. Only the index keys are read. Rewards
+// of reorged blocks are included, it's up to the caller to filter out non
+// canonical entries.
+func IterateAccountRewardEntries(db ethdb.Iteratee, account common.Address, from, to uint64, fn func(RewardEntry) bool) {
+	prefix := accountRewardKeyPrefix(account)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+8+common.HashLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix) : len(prefix)+8])
+		if number > to {
+			break
+		}
+		if !fn(RewardEntry{Number: number, Hash: common.BytesToHash(key[len(prefix)+8:])}) {
+			break
+		}
+	}
+}
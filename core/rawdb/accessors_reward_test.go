@@ -0,0 +1,101 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+)
+
+func testRewards(masternode, protector common.Address) []byte {
+	return []byte(fmt.Sprintf(`{"signers":{"%s":{"sign":3,"reward":100}},"rewards":{"%s":{"0x0000000000000000000000000000000000000001":90}},"signersProtector":{"%s":{"sign":1,"reward":10}}}`,
+		masternode.String0x(), masternode.String0x(), protector.String0x()))
+}
+
+// accountRewardEntries collects the reward entries of an account within [from, to].
+func accountRewardEntries(db ethdb.Iteratee, account common.Address, from, to uint64) []RewardEntry {
+	var entries []RewardEntry
+	IterateAccountRewardEntries(db, account, from, to, func(entry RewardEntry) bool {
+		entries = append(entries, entry)
+		return true
+	})
+	return entries
+}
+
+// Tests that epoch rewards can be stored and indexed by account, rewards of
+// reorged blocks included.
+func TestRewardStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		masternode = common.HexToAddress("0xaa")
+		protector  = common.HexToAddress("0xbb")
+		hash       = common.HexToHash("0x01")
+		reorged    = common.HexToHash("0x02")
+	)
+	if HasRewards(db, 900, hash) {
+		t.Fatalf("non existent rewards returned")
+	}
+	if err := WriteRewards(db, 900, hash, []byte("not json")); err == nil {
+		t.Fatalf("invalid rewards stored")
+	}
+	for _, entry := range []RewardEntry{{900, hash}, {1800, hash}, {1800, reorged}, {2700, hash}} {
+		if err := WriteRewards(db, entry.Number, entry.Hash, testRewards(masternode, protector)); err != nil {
+			t.Fatalf("failed to store rewards: %v", err)
+		}
+	}
+	if data := ReadRewards(db, 900, hash); !bytes.Equal(data, testRewards(masternode, protector)) {
+		t.Fatalf("rewards mismatch: have %s", data)
+	}
+	if entries := accountRewardEntries(db, masternode, 1000, 2700); len(entries) != 3 || entries[0].Number != 1800 || entries[2].Number != 2700 {
+		t.Fatalf("masternode reward entries mismatch: %v", entries)
+	}
+	if entries := accountRewardEntries(db, protector, 0, 1000); len(entries) != 1 || entries[0] != (RewardEntry{900, hash}) {
+		t.Fatalf("protector reward entries mismatch: %v", entries)
+	}
+	if entries := accountRewardEntries(db, common.HexToAddress("0x01"), 0, 2700); len(entries) != 0 {
+		t.Fatalf("holder indexed as a rewarded node: %v", entries)
+	}
+
+	if !HasRewards(db, 1800, reorged) {
+		t.Fatalf("rewards of the reorged block missing")
+	}
+}
+
+// Tests that iterating the account reward index seeks to the requested block
+// and stops as soon as the callback asks to.
+func TestIterateAccountRewardEntries(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	masternode := common.HexToAddress("0xaa")
+	for number := uint64(900); number <= 9000; number += 900 {
+		if err := WriteRewards(db, number, common.HexToHash("0x01"), testRewards(masternode, masternode)); err != nil {
+			t.Fatalf("failed to store rewards: %v", err)
+		}
+	}
+	var visited []uint64
+	IterateAccountRewardEntries(db, masternode, 2000, 9000, func(entry RewardEntry) bool {
+		visited = append(visited, entry.Number)
+		return len(visited) < 3
+	})
+	if len(visited) != 3 || visited[0] != 2700 || visited[2] != 4500 {
+		t.Fatalf("visited entries mismatch: %v", visited)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/metrics"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+)
+
+// getChunk returns a chunk of data of the given size, filled with the byte b.
+func getChunk(size int, b int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(b)
+	}
+	return data
+}
+
+// TestFreezerBasics test initializing a freezertable from scratch, writing to the table,
+// and reading it back.
+func TestFreezerBasics(t *testing.T) {
+	t.Parallel()
+	// set cutoff at 50 bytes
+	f, err := newCustomTable(t.TempDir(), "basics", metrics.NewMeter(), metrics.NewMeter(), 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// Write 15 bytes 255 times, results in 85 files
+	for x := 0; x < 255; x++ {
+		if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for y := 0; y < 255; y++ {
+		exp := getChunk(15, y)
+		got, err := f.Retrieve(uint64(y))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, exp) {
+			t.Fatalf("test %d, got \n%x != \n%x", y, got, exp)
+		}
+	}
+	// Check that we cannot read too far
+	if _, err := f.Retrieve(uint64(255)); err != errOutOfBounds {
+		t.Fatalf("expected out of bounds, got %v", err)
+	}
+}
+
+// TestFreezerBasicsClosing tests same as TestFreezerBasics, but also closes and
+// reopens the freezer between every operation, with and without compression.
+func TestFreezerBasicsClosing(t *testing.T) {
+	t.Parallel()
+	for _, noCompression := range []bool{true, false} {
+		var (
+			fname = fmt.Sprintf("basics-close-%v", noCompression)
+			dir   = t.TempDir()
+		)
+		f, err := newCustomTable(dir, fname, metrics.NewMeter(), metrics.NewMeter(), 50, noCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x := 0; x < 255; x++ {
+			if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+			if f, err = newCustomTable(dir, fname, metrics.NewMeter(), metrics.NewMeter(), 50, noCompression); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for y := 0; y < 255; y++ {
+			exp := getChunk(15, y)
+			got, err := f.Retrieve(uint64(y))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, exp) {
+				t.Fatalf("test %d, got \n%x != \n%x", y, got, exp)
+			}
+		}
+		f.Close()
+	}
+}
+
+// TestFreezerRepairDanglingHead tests that we can recover if index entries are removed
+func TestFreezerRepairDanglingHead(t *testing.T) {
+	t.Parallel()
+	var (
+		fname = "dangling-head"
+		dir   = t.TempDir()
+	)
+	{ // Fill a table and close it
+		f, err := newCustomTable(dir, fname, metrics.NewMeter(), metrics.NewMeter(), 50, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x := 0; x < 255; x++ {
+			f.Append(uint64(x), getChunk(15, x))
+		}
+		f.Close()
+	}
+	// Open the index and chop off a couple of bytes, simulating a crash mid-write
+	idxFile, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%s.ridx", fname)), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := idxFile.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idxFile.Truncate(stat.Size() - 4)
+	idxFile.Close()
+
+	// Now open it again, the last item should be dropped
+	f, err := newCustomTable(dir, fname, metrics.NewMeter(), metrics.NewMeter(), 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Retrieve(0xfe); err == nil {
+		t.Errorf("retrieved dangling item 0xfe")
+	}
+	if _, err := f.Retrieve(0xfd); err != nil {
+		t.Errorf("failed to retrieve item 0xfd: %v", err)
+	}
+}
+
+// TestFreezerTruncate tests that truncating a table discards the tail items and
+// that new items can be appended afterwards.
+func TestFreezerTruncate(t *testing.T) {
+	t.Parallel()
+	f, err := newCustomTable(t.TempDir(), "truncation", metrics.NewMeter(), metrics.NewMeter(), 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for x := 0; x < 30; x++ {
+		if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.truncate(10); err != nil {
+		t.Fatal(err)
+	}
+	if f.items != 10 {
+		t.Fatalf("expected %d items, got %d", 10, f.items)
+	}
+	if _, err := f.Retrieve(10); err != errOutOfBounds {
+		t.Fatalf("expected out of bounds, got %v", err)
+	}
+	if err := f.Append(10, getChunk(15, 0xaa)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Retrieve(10); err != nil || !bytes.Equal(got, getChunk(15, 0xaa)) {
+		t.Fatalf("retrieve after truncation mismatch: %x, %v", got, err)
+	}
+}
+
+// TestFreezerDatabase tests that block data moved into the freezer is served
+// transparently by the chain accessors.
+func TestFreezerDatabase(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	header := &types.Header{Number: big.NewInt(0), Extra: []byte("test header")}
+	hash := header.Hash()
+	headerRLP, _ := rlp.EncodeToBytes(header)
+	bodyRLP, _ := rlp.EncodeToBytes(&types.Body{})
+	receiptsRLP, _ := rlp.EncodeToBytes([]*types.ReceiptForStorage{})
+	tdRLP, _ := rlp.EncodeToBytes(big.NewInt(42))
+
+	if err := db.AppendAncient(0, hash.Bytes(), headerRLP, bodyRLP, receiptsRLP, tdRLP); err != nil {
+		t.Fatalf("failed to append ancient: %v", err)
+	}
+	if err := db.AppendAncient(5, hash.Bytes(), headerRLP, bodyRLP, receiptsRLP, tdRLP); err != errOutOrderInsertion {
+		t.Fatalf("out of order insertion error mismatch: have %v, want %v", err, errOutOrderInsertion)
+	}
+	if frozen, _ := db.Ancients(); frozen != 1 {
+		t.Fatalf("frozen items mismatch: have %d, want %d", frozen, 1)
+	}
+	if have := ReadCanonicalHash(db, 0); have != hash {
+		t.Fatalf("canonical hash mismatch: have %x, want %x", have, hash)
+	}
+	if have := ReadHeader(db, hash, 0); have == nil || have.Hash() != hash {
+		t.Fatalf("ancient header mismatch: have %v, want %v", have, header)
+	}
+	if !HasBody(db, hash, 0) || ReadBody(db, hash, 0) == nil {
+		t.Fatalf("ancient body missing")
+	}
+	if td := ReadTd(db, hash, 0); td == nil || td.Uint64() != 42 {
+		t.Fatalf("ancient td mismatch: have %v, want %v", td, 42)
+	}
+	if err := db.TruncateAncients(0); err != nil {
+		t.Fatalf("failed to truncate ancients: %v", err)
+	}
+	if have := ReadHeader(db, hash, 0); have != nil {
+		t.Fatalf("truncated header returned: %v", have)
+	}
+}
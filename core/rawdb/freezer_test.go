@@ -0,0 +1,220 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+// writeTestChain stores a canonical chain of n blocks with their receipts and
+// total difficulties, and marks the last one as the head.
+func writeTestChain(db ethdb.KeyValueWriter, n uint64) []*types.Header {
+	headers := make([]*types.Header, 0, n)
+	parent := common.Hash{}
+	for number := uint64(0); number < n; number++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent, Extra: []byte("freezer test")}
+		writeTestBlock(db, header)
+		WriteCanonicalHash(db, header.Hash(), number)
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	WriteHeadHeaderHash(db, parent)
+	WriteHeadBlockHash(db, parent)
+	return headers
+}
+
+// writeTestBlock stores a block with empty body and receipts.
+func writeTestBlock(db ethdb.KeyValueWriter, header *types.Header) {
+	WriteHeader(db, header)
+	WriteBody(db, header.Hash(), header.Number.Uint64(), &types.Body{})
+	WriteReceipts(db, header.Hash(), header.Number.Uint64(), types.Receipts{})
+	WriteTd(db, header.Hash(), header.Number.Uint64(), new(big.Int).Add(header.Number, common.Big1))
+}
+
+// Tests that the freeze cutoff follows the finalized block if it is canonical,
+// and the immutability threshold otherwise.
+func TestFreezeLimit(t *testing.T) {
+	head := uint64(params.ImmutabilityThreshold + 100)
+	tests := []struct {
+		name      string
+		head      uint64
+		finalized uint64
+		canonical bool
+		limit     uint64
+		ok        bool
+	}{
+		{"young chain", 100, 0, false, 0, false},
+		{"threshold", head, 0, false, 100, true},
+		{"finalized", 100, 60, true, 60, true},
+		{"finalized off the canonical chain", head, 60, false, 100, true},
+		{"finalized above the head", 50, 60, true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryDatabase()
+			if tt.finalized != 0 {
+				header := &types.Header{Number: new(big.Int).SetUint64(tt.finalized)}
+				WriteHeader(db, header)
+				WriteFinalizedBlockHash(db, header.Hash())
+				if tt.canonical {
+					WriteCanonicalHash(db, header.Hash(), tt.finalized)
+				} else {
+					WriteCanonicalHash(db, common.HexToHash("0x01"), tt.finalized)
+				}
+			}
+			limit, ok := freezeLimit(db, tt.head)
+			if limit != tt.limit || ok != tt.ok {
+				t.Errorf("freezeLimit() = %d, %v, want %d, %v", limit, ok, tt.limit, tt.ok)
+			}
+		})
+	}
+}
+
+// Tests that the background freezer moves the blocks below the cutoff it
+// computes into the ancient store, and wipes them, side blocks included, from
+// the key-value store.
+func TestFreezerFreeze(t *testing.T) {
+	var (
+		kvdb    = NewMemoryDatabase()
+		headers = writeTestChain(kvdb, 10)
+		side    = &types.Header{Number: big.NewInt(3), ParentHash: headers[2].Hash(), Extra: []byte("side")}
+	)
+	writeTestBlock(kvdb, side)
+	WriteFinalizedBlockHash(kvdb, headers[6].Hash())
+	limit, ok := freezeLimit(kvdb, 9)
+	if !ok || limit != 6 {
+		t.Fatalf("freezeLimit() = %d, %v, want 6, true", limit, ok)
+	}
+	db, err := NewDatabaseWithFreezer(kvdb, t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frdb := db.(*freezerdb)
+	defer frdb.AncientStore.Close()
+
+	// The block deletion follows the append, wait for the last frozen block to go
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if frozen, _ := db.Ancients(); frozen == limit {
+			if has, _ := kvdb.Has(headerHashKey(limit - 1)); !has {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			frozen, _ := db.Ancients()
+			t.Fatalf("freezer stalled at %d blocks, want %d", frozen, limit)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for number := uint64(0); number < 10; number++ {
+		hash := headers[number].Hash()
+		if have := ReadHeader(db, hash, number); have == nil || have.Hash() != hash {
+			t.Errorf("block %d: header not served", number)
+		}
+		if ReadTd(db, hash, number) == nil {
+			t.Errorf("block %d: td not served", number)
+		}
+		frozen := number < limit
+		if has, _ := db.HasAncient(freezerHashTable, number); has != frozen {
+			t.Errorf("block %d: frozen %v, want %v", number, has, frozen)
+		}
+		// the genesis block is always kept in the key-value store
+		if kept := HasHeader(kvdb, hash, number); kept != (number == 0 || !frozen) {
+			t.Errorf("block %d: kept in the key-value store %v, want %v", number, kept, !frozen)
+		}
+	}
+	if HasHeader(kvdb, side.Hash(), 3) {
+		t.Errorf("frozen side block kept in the key-value store")
+	}
+}
+
+// Tests that a freezer is only combined with a key-value store of the same chain
+// which continues where the freezer left off.
+func TestNewDatabaseWithFreezer(t *testing.T) {
+	var (
+		kvdb    = NewMemoryDatabase()
+		headers = writeTestChain(kvdb, 10)
+		dir     = t.TempDir()
+	)
+	// A read only database doesn't freeze anything
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 0 {
+		t.Fatalf("read only database froze %d blocks", frozen)
+	}
+	frdb := db.(*freezerdb).AncientStore.(*freezer)
+	for _, header := range headers[:4] {
+		number := header.Number.Uint64()
+		if err := frdb.AppendAncient(number, header.Hash().Bytes(), ReadHeaderRLP(kvdb, header.Hash(), number), ReadBodyRLP(kvdb, header.Hash(), number), ReadReceiptsRLP(kvdb, header.Hash(), number), ReadTdRLP(kvdb, header.Hash(), number)); err != nil {
+			t.Fatalf("failed to append block %d: %v", number, err)
+		}
+	}
+	frdb.Close()
+
+	tests := []struct {
+		name  string
+		setup func() ethdb.KeyValueStore
+		err   string
+	}{
+		{"same chain", func() ethdb.KeyValueStore { return kvdb }, ""},
+		{"other chain", func() ethdb.KeyValueStore {
+			other := NewMemoryDatabase()
+			writeTestChain(other, 1)
+			WriteCanonicalHash(other, common.HexToHash("0x01"), 0)
+			return other
+		}, "genesis mismatch"},
+		{"gap", func() ethdb.KeyValueStore {
+			gap := NewMemoryDatabase()
+			writeTestChain(gap, 10)
+			DeleteCanonicalHash(gap, 4)
+			return gap
+		}, "gap (#4)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewDatabaseWithFreezer(tt.setup(), dir, "", true)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("NewDatabaseWithFreezer() error = %v", err)
+				}
+				if frozen, _ := db.Ancients(); frozen != 4 {
+					t.Errorf("frozen = %d, want 4", frozen)
+				}
+				db.(*freezerdb).AncientStore.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("NewDatabaseWithFreezer() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	// An empty freezer is refused once blocks left the key-value store
+	DeleteCanonicalHash(kvdb, 1)
+	if _, err := NewDatabaseWithFreezer(kvdb, t.TempDir(), "", true); err == nil || !strings.Contains(err.Error(), "already extracted") {
+		t.Fatalf("NewDatabaseWithFreezer() error = %v, want the extracted segments refused", err)
+	}
+}
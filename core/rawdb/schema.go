@@ -39,9 +39,15 @@ var (
 	// headFastBlockKey tracks the latest known incomplete block's hash during fast sync.
 	headFastBlockKey = []byte("LastFast")
 
+	// headFinalizedBlockKey tracks the latest block committed by the XDPoS v2 consensus.
+	headFinalizedBlockKey = []byte("LastFinalized")
+
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// snapshotRootKey tracks the state root of the complete flat state snapshot.
+	snapshotRootKey = []byte("SnapshotRoot")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -55,12 +61,21 @@ var (
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 	codePrefix      = []byte("c") // codePrefix + code hash -> account code
 
+	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
+	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
+
 	// used by old db, now only used for conversion
 	oldReceiptsPrefix = []byte("receipts-")
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
+	rewardPrefix        = []byte("rewards-")        // rewardPrefix + num (uint64 big endian) + hash -> epoch rewards (json)
+	accountRewardPrefix = []byte("account-reward-") // accountRewardPrefix + address + num (uint64 big endian) + hash -> nil
+
+	forensicsReportPrefix = []byte("forensics-report-") // forensicsReportPrefix + round (uint64 big endian) + id hash -> forensics report (json)
+	forensicsSignerPrefix = []byte("forensics-signer-") // forensicsSignerPrefix + address + round (uint64 big endian) + id hash -> nil
+
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 
@@ -88,6 +103,16 @@ const (
 	freezerDifficultyTable = "diffs"
 )
 
+// freezerNoSnappy configures whether compression is disabled for the ancient-tables.
+// Hashes and difficulties don't compress well.
+var freezerNoSnappy = map[string]bool{
+	freezerHeaderTable:     false,
+	freezerHashTable:       true,
+	freezerBodiesTable:     false,
+	freezerReceiptTable:    false,
+	freezerDifficultyTable: true,
+}
+
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
 // fields.
 type LegacyTxLookupEntry struct {
@@ -172,6 +197,60 @@ func IsCodeKey(key []byte) (bool, []byte) {
 	return false, nil
 }
 
+// accountSnapshotKey = SnapshotAccountPrefix + hash
+func accountSnapshotKey(hash common.Hash) []byte {
+	return append(SnapshotAccountPrefix, hash.Bytes()...)
+}
+
+// storageSnapshotKey = SnapshotStoragePrefix + account hash + storage hash
+func storageSnapshotKey(accountHash, storageHash common.Hash) []byte {
+	return append(storageSnapshotsKey(accountHash), storageHash.Bytes()...)
+}
+
+// storageSnapshotsKey = SnapshotStoragePrefix + account hash
+func storageSnapshotsKey(accountHash common.Hash) []byte {
+	return append(SnapshotStoragePrefix, accountHash.Bytes()...)
+}
+
+// IsAccountSnapshotKey reports whether the given byte slice is the key of an
+// account snapshot entry, if so return the raw account hash as well.
+func IsAccountSnapshotKey(key []byte) (bool, []byte) {
+	if bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == common.HashLength+len(SnapshotAccountPrefix) {
+		return true, key[len(SnapshotAccountPrefix):]
+	}
+	return false, nil
+}
+
+// rewardKey = rewardPrefix + num (uint64 big endian) + hash
+func rewardKey(number uint64, hash common.Hash) []byte {
+	return append(append(rewardPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// accountRewardKeyPrefix = accountRewardPrefix + address
+func accountRewardKeyPrefix(account common.Address) []byte {
+	return append(accountRewardPrefix, account.Bytes()...)
+}
+
+// accountRewardKey = accountRewardPrefix + address + num (uint64 big endian) + hash
+func accountRewardKey(account common.Address, number uint64, hash common.Hash) []byte {
+	return append(append(accountRewardKeyPrefix(account), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// forensicsReportKey = forensicsReportPrefix + round (uint64 big endian) + id hash
+func forensicsReportKey(round uint64, idHash common.Hash) []byte {
+	return append(append(forensicsReportPrefix, encodeBlockNumber(round)...), idHash.Bytes()...)
+}
+
+// forensicsSignerKeyPrefix = forensicsSignerPrefix + address
+func forensicsSignerKeyPrefix(signer common.Address) []byte {
+	return append(forensicsSignerPrefix, signer.Bytes()...)
+}
+
+// forensicsSignerKey = forensicsSignerPrefix + address + round (uint64 big endian) + id hash
+func forensicsSignerKey(signer common.Address, round uint64, idHash common.Hash) []byte {
+	return append(append(forensicsSignerKeyPrefix(signer), encodeBlockNumber(round)...), idHash.Bytes()...)
+}
+
 // configKey = configPrefix + hash
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
@@ -0,0 +1,171 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+var (
+	// ErrInvalidSlashingReport is returned if the data of a slashing transaction
+	// is not a forensics report blaming at least one signer.
+	ErrInvalidSlashingReport = errors.New("invalid slashing report")
+
+	// ErrUnauthorizedSlasher is returned if a slashing transaction isn't sent
+	// by a masternode. Slashing transactions don't pay gas, only masternodes
+	// are allowed to send them.
+	ErrUnauthorizedSlasher = errors.New("slashing transaction not sent by a masternode")
+
+	// ErrAlreadySlashed is returned if a slashing transaction blames a signer
+	// already slashed for the same round.
+	ErrAlreadySlashed = errors.New("signer already slashed for this round")
+)
+
+// SlashingChain defines the chain access needed to verify the forensic proofs
+// carried by slashing transactions.
+type SlashingChain interface {
+	consensus.ChainReader
+
+	// Engine retrieves the chain's consensus engine.
+	Engine() consensus.Engine
+}
+
+// SlashingReport decodes the forensics report carried by a slashing transaction.
+func SlashingReport(tx *types.Transaction) (*types.ForensicsReport, error) {
+	report := new(types.ForensicsReport)
+	if err := json.Unmarshal(tx.Data(), report); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSlashingReport, err)
+	}
+	if report.Proof == nil || len(report.Signers) == 0 {
+		return nil, ErrInvalidSlashingReport
+	}
+	return report, nil
+}
+
+// slashedKey is the storage slot of the slashing address recording that a
+// signer was slashed for an offence at the given round.
+func slashedKey(signer common.Address, round types.Round) common.Hash {
+	return crypto.Keccak256Hash(signer.Bytes(), binary.BigEndian.AppendUint64(nil, uint64(round)))
+}
+
+// ValidateSlashingTransaction verifies the forensic proof carried by a slashing
+// transaction included in the block of the given header, or in the next block
+// if header is nil. The sender must be a masternode and none of the signers
+// blamed may have already been slashed for the same round.
+func ValidateSlashingTransaction(chain SlashingChain, header *types.Header, statedb *state.StateDB, tx *types.Transaction) error {
+	parent := chain.CurrentHeader()
+	if header != nil {
+		parent = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if parent == nil {
+			return consensus.ErrUnknownAncestor
+		}
+	}
+	blockNumber := new(big.Int).Add(parent.Number, common.Big1)
+	if !chain.Config().IsTIPSlashing(blockNumber) {
+		return nil
+	}
+	engine, ok := chain.Engine().(*XDPoS.XDPoS)
+	if !ok {
+		return ErrNotXDPoS
+	}
+	from, err := types.Sender(types.MakeSigner(chain.Config(), blockNumber), tx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(engine.GetMasternodes(chain, parent), from) {
+		return ErrUnauthorizedSlasher
+	}
+	report, err := SlashingReport(tx)
+	if err != nil {
+		return err
+	}
+	for _, signer := range report.Signers {
+		if statedb.GetState(common.SlashingAddrBinary, slashedKey(signer, report.Round)) != (common.Hash{}) {
+			return ErrAlreadySlashed
+		}
+	}
+	if err := engine.EngineV2.VerifyForensicsReport(chain, report); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSlashingReport, err)
+	}
+	return nil
+}
+
+// ApplySlashingTransaction records the signers blamed by an already validated
+// slashing transaction, they are penalised at the next epoch switch. Like
+// signing transactions, slashing transactions sent by masternodes don't
+// consume gas.
+func ApplySlashingTransaction(config *params.ChainConfig, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64) (*types.Receipt, uint64, error, bool) {
+	report, err := SlashingReport(tx)
+	if err != nil {
+		return nil, 0, err, false
+	}
+	from, err := types.Sender(types.MakeSigner(config, blockNumber), tx)
+	if err != nil {
+		return nil, 0, err, false
+	}
+	nonce := statedb.GetNonce(from)
+	if nonce < tx.Nonce() {
+		return nil, 0, ErrNonceTooHigh, false
+	} else if nonce > tx.Nonce() {
+		return nil, 0, ErrNonceTooLow, false
+	}
+	statedb.SetNonce(from, nonce+1)
+
+	// Keep the slashing address from being deleted as an empty account
+	if statedb.GetNonce(common.SlashingAddrBinary) == 0 {
+		statedb.SetNonce(common.SlashingAddrBinary, 1)
+	}
+	topics := make([]common.Hash, 0, len(report.Signers))
+	for _, signer := range report.Signers {
+		statedb.SetState(common.SlashingAddrBinary, slashedKey(signer, report.Round), common.BigToHash(common.Big1))
+		topics = append(topics, common.BytesToHash(signer.Bytes()))
+	}
+	// Update the state with pending changes
+	var root []byte
+	if config.IsByzantium(blockNumber) {
+		statedb.Finalise(true)
+	} else {
+		root = statedb.IntermediateRoot(config.IsEIP158(blockNumber)).Bytes()
+	}
+	receipt := types.NewReceipt(root, false, *usedGas)
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = 0
+	// Log the slashed signers, so that they can be filtered for
+	log := &types.Log{}
+	log.Address = common.SlashingAddrBinary
+	log.Topics = topics
+	log.BlockNumber = blockNumber.Uint64()
+	statedb.AddLog(log)
+	receipt.Logs = statedb.GetLogs(tx.Hash(), blockNumber.Uint64(), blockHash)
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	receipt.BlockHash = blockHash
+	receipt.BlockNumber = blockNumber
+	receipt.TransactionIndex = uint(statedb.TxIndex())
+	return receipt, 0, nil, false
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/core/state/snapshot"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
@@ -123,6 +124,51 @@ func (d iterativeDump) OnRoot(root common.Hash) {
 	}{root})
 }
 
+// dumpIterator steps over the accounts of a state in the order of their hashes.
+type dumpIterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Release()
+}
+
+// trieDumpIterator is a dumpIterator walking the account trie.
+type trieDumpIterator struct {
+	it *trie.Iterator
+}
+
+func (it *trieDumpIterator) Next() bool    { return it.it.Next() }
+func (it *trieDumpIterator) Key() []byte   { return it.it.Key }
+func (it *trieDumpIterator) Value() []byte { return it.it.Value }
+func (it *trieDumpIterator) Release()      {}
+
+// snapDumpIterator is a dumpIterator walking the flat state snapshot.
+type snapDumpIterator struct {
+	it snapshot.AccountIterator
+}
+
+func (it *snapDumpIterator) Next() bool { return it.it.Next() }
+func (it *snapDumpIterator) Key() []byte {
+	hash := it.it.Hash()
+	return hash[:]
+}
+func (it *snapDumpIterator) Value() []byte { return it.it.Account() }
+func (it *snapDumpIterator) Release()      { it.it.Release() }
+
+// accountIterator returns an iterator over the accounts of the state starting
+// at the given key, served by the flat snapshot if one is fully available for
+// the unmodified state, or by the account trie otherwise.
+func (s *StateDB) accountIterator(start []byte) dumpIterator {
+	if s.snap != nil && s.snap.Root() == s.trie.Hash() {
+		var seek common.Hash
+		copy(seek[:], start)
+		if it, err := s.snaps.AccountIterator(s.snap.Root(), seek); err == nil {
+			return &snapDumpIterator{it: it}
+		}
+	}
+	return &trieDumpIterator{it: trie.NewIterator(s.trie.NodeIterator(start))}
+}
+
 // DumpToCollector iterates the state according to the given options and inserts
 // the items into a collector for aggregation or serialization.
 func (s *StateDB) DumpToCollector(c DumpCollector, conf *DumpConfig) (nextKey []byte) {
@@ -139,10 +185,12 @@ func (s *StateDB) DumpToCollector(c DumpCollector, conf *DumpConfig) (nextKey []
 	log.Info("Trie dumping started", "root", s.trie.Hash())
 	c.OnRoot(s.trie.Hash())
 
-	it := trie.NewIterator(s.trie.NodeIterator(conf.Start))
+	it := s.accountIterator(conf.Start)
+	defer it.Release()
+
 	for it.Next() {
 		var data types.StateAccount
-		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+		if err := rlp.DecodeBytes(it.Value(), &data); err != nil {
 			panic(err)
 		}
 		account := DumpAccount{
@@ -150,10 +198,10 @@ func (s *StateDB) DumpToCollector(c DumpCollector, conf *DumpConfig) (nextKey []
 			Nonce:     data.Nonce,
 			Root:      data.Root[:],
 			CodeHash:  data.CodeHash,
-			SecureKey: it.Key,
+			SecureKey: it.Key(),
 		}
 		var (
-			addrBytes = s.trie.GetKey(it.Key)
+			addrBytes = s.trie.GetKey(it.Key())
 			addr      = common.BytesToAddress(addrBytes)
 			address   *common.Address
 		)
@@ -190,13 +238,13 @@ func (s *StateDB) DumpToCollector(c DumpCollector, conf *DumpConfig) (nextKey []
 		c.OnAccount(address, account)
 		accounts++
 		if time.Since(logged) > 8*time.Second {
-			log.Info("Trie dumping in progress", "at", it.Key, "accounts", accounts,
+			log.Info("Trie dumping in progress", "at", it.Key(), "accounts", accounts,
 				"elapsed", common.PrettyDuration(time.Since(start)))
 			logged = time.Now()
 		}
 		if conf.Max > 0 && accounts >= conf.Max {
 			if it.Next() {
-				nextKey = it.Key
+				nextKey = it.Key()
 			}
 			break
 		}
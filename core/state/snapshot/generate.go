@@ -0,0 +1,235 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// generatorLogInterval is the time between two progress reports of a running
+// snapshot generation.
+const generatorLogInterval = 8 * time.Second
+
+// generatorStats is a collection of statistics gathered by the snapshot generator
+// for logging purposes.
+type generatorStats struct {
+	start    time.Time // Timestamp when generation started
+	logged   time.Time // Timestamp when the progress was last reported
+	accounts uint64    // Number of accounts indexed
+	slots    uint64    // Number of storage slots indexed
+}
+
+// log creates an contextual log with the given message and the context pulled
+// from the internally maintained statistics.
+func (gs *generatorStats) log(msg string, root common.Hash, marker []byte) {
+	ctx := []interface{}{"root", root}
+	if len(marker) >= common.HashLength {
+		ctx = append(ctx, "at", common.BytesToHash(marker[:common.HashLength]))
+	}
+	ctx = append(ctx, "accounts", gs.accounts, "slots", gs.slots, "elapsed", common.PrettyDuration(time.Since(gs.start)))
+	log.Info(msg, ctx...)
+	gs.logged = time.Now()
+}
+
+// startGeneration starts a background goroutine generating the snapshot of the
+// layer from its current marker onwards. The state is pinned in the trie cache
+// right away, before the chain gets the chance to garbage collect it.
+func (dl *diskLayer) startGeneration() {
+	dl.triedb.Reference(dl.root, common.Hash{})
+
+	dl.genAbort = make(chan struct{})
+	dl.genDone = make(chan struct{})
+	go dl.generate(dl.genAbort, dl.genDone)
+}
+
+// stopGeneration aborts the running snapshot generator, if any, and waits for it
+// to terminate. It returns whether the snapshot was left incomplete.
+func (dl *diskLayer) stopGeneration() bool {
+	if dl.genAbort == nil {
+		return false
+	}
+	close(dl.genAbort)
+	<-dl.genDone
+	dl.genAbort, dl.genDone = nil, nil
+
+	return dl.generating()
+}
+
+// generate walks the state trie of the disk layer from the generation marker and
+// stores every account and storage slot into the flat snapshot, advancing the
+// marker along with each flushed batch. The snapshot is wiped first if nothing
+// was generated yet.
+func (dl *diskLayer) generate(abort chan struct{}, done chan struct{}) {
+	defer close(done)
+	defer dl.triedb.Dereference(dl.root)
+
+	dl.lock.RLock()
+	marker := dl.genMarker
+	dl.lock.RUnlock()
+
+	stats := &generatorStats{start: time.Now(), logged: time.Now()}
+	if len(marker) == 0 {
+		if !dl.wipe(abort) {
+			return
+		}
+	}
+	accTrie, err := trie.New(common.Hash{}, dl.root, dl.triedb)
+	if err != nil {
+		log.Error("Failed to open state trie for snapshot generation", "root", dl.root, "err", err)
+		return
+	}
+	var (
+		accMarker []byte
+		batch     = dl.diskdb.NewBatch()
+	)
+	if len(marker) > 0 {
+		accMarker = marker[:common.HashLength]
+	}
+	// flush writes out the batch and advances the marker, returning false if the
+	// generation was aborted meanwhile
+	flush := func(next []byte) bool {
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write state snapshot", "err", err)
+		}
+		batch.Reset()
+
+		dl.lock.Lock()
+		dl.genMarker = next
+		dl.lock.Unlock()
+
+		if time.Since(stats.logged) > generatorLogInterval {
+			stats.log("Generating state snapshot", dl.root, next)
+		}
+		select {
+		case <-abort:
+			stats.log("Aborted state snapshot generation", dl.root, next)
+			return false
+		default:
+			return true
+		}
+	}
+	accIt := trie.NewIterator(accTrie.NodeIterator(accMarker))
+	for accIt.Next() {
+		accountHash := common.BytesToHash(accIt.Key)
+
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			log.Crit("Invalid account encountered during snapshot generation", "hash", accountHash, "err", err)
+		}
+		rawdb.WriteAccountSnapshot(batch, accountHash, accIt.Value)
+		stats.accounts++
+		snapshotGeneratedAccountMeter.Mark(1)
+
+		if acc.Root != types.EmptyRootHash {
+			var storeMarker []byte
+			if accMarker != nil && bytes.Equal(accountHash[:], accMarker) && len(marker) > common.HashLength {
+				storeMarker = marker[common.HashLength:]
+			}
+			storeTrie, err := trie.New(accountHash, acc.Root, dl.triedb)
+			if err != nil {
+				log.Error("Failed to open storage trie for snapshot generation", "account", accountHash, "root", acc.Root, "err", err)
+				return
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(storeMarker))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(storeIt.Key), storeIt.Value)
+				stats.slots++
+				snapshotGeneratedStorageMeter.Mark(1)
+
+				if batch.ValueSize() > ethdb.IdealBatchSize {
+					if !flush(append(accountHash[:], storeIt.Key...)) {
+						return
+					}
+				}
+			}
+			if storeIt.Err != nil {
+				log.Error("Failed to iterate storage trie for snapshot generation", "account", accountHash, "root", acc.Root, "err", storeIt.Err)
+				return
+			}
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if !flush(append(accountHash[:], common.MaxHash[:]...)) {
+				return
+			}
+		}
+	}
+	if accIt.Err != nil {
+		log.Error("Failed to iterate state trie for snapshot generation", "root", dl.root, "err", accIt.Err)
+		return
+	}
+	// Snapshot fully generated, persist its root and stop the generator
+	rawdb.WriteSnapshotRoot(batch, dl.root)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write state snapshot", "err", err)
+	}
+	dl.lock.Lock()
+	dl.genMarker = nil
+	dl.lock.Unlock()
+
+	stats.log("Generated state snapshot", dl.root, nil)
+}
+
+// wipe deletes all the snapshot entries from the database, returning false if
+// the generation was aborted meanwhile.
+func (dl *diskLayer) wipe(abort chan struct{}) bool {
+	start := time.Now()
+	for _, prefix := range [][]byte{rawdb.SnapshotAccountPrefix, rawdb.SnapshotStoragePrefix} {
+		var (
+			batch = dl.diskdb.NewBatch()
+			it    = dl.diskdb.NewIterator(prefix, nil)
+			size  = len(prefix) + common.HashLength
+		)
+		if bytes.Equal(prefix, rawdb.SnapshotStoragePrefix) {
+			size += common.HashLength
+		}
+		for it.Next() {
+			// Other tables may share the single byte snapshot prefixes
+			if len(it.Key()) != size {
+				continue
+			}
+			batch.Delete(it.Key())
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					log.Crit("Failed to wipe state snapshot", "err", err)
+				}
+				batch.Reset()
+
+				select {
+				case <-abort:
+					it.Release()
+					return false
+				default:
+				}
+			}
+		}
+		it.Release()
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to wipe state snapshot", "err", err)
+		}
+	}
+	log.Debug("Wiped state snapshot", "elapsed", common.PrettyDuration(time.Since(start)))
+	return true
+}
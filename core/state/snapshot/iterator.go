@@ -0,0 +1,263 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+)
+
+// AccountIterator is an iterator to step over all the accounts in a snapshot,
+// which may or may not be composed of multiple layers.
+type AccountIterator interface {
+	// Next steps the iterator forward one element, returning false if exhausted,
+	// or an error if iteration failed for some reason (e.g. root being iterated
+	// becomes stale and garbage collected).
+	Next() bool
+
+	// Error returns any failure that occurred during iteration, which might have
+	// caused a premature iteration exit (e.g. snapshot stack becoming stale).
+	Error() error
+
+	// Hash returns the hash of the account the iterator is currently at.
+	Hash() common.Hash
+
+	// Account returns the RLP encoded slim account the iterator is currently at.
+	// An error will be returned if the iterator becomes invalid
+	Account() []byte
+
+	// Release releases associated resources. Release should always succeed and
+	// can be called multiple times without causing error.
+	Release()
+}
+
+// diffAccountIterator is an account iterator that steps over the accounts (both
+// live and deleted) contained within a single diff layer. Higher order iterators
+// will use the deleted accounts to skip deeper iterators.
+type diffAccountIterator struct {
+	layer   *diffLayer    // Live layer to retrieve values from
+	keys    []common.Hash // Keys left in the layer to iterate
+	curKey  common.Hash   // Current entry the iterator is at
+	curData []byte        // Account data of the current entry, nil if deleted
+	fail    error         // Any failures encountered (stale)
+}
+
+// AccountIterator creates an account iterator over a diff layer and all the
+// layers underneath it.
+func (dl *diffLayer) AccountIterator(seek common.Hash) AccountIterator {
+	return newLayeredAccountIterator(dl, seek)
+}
+
+// newDiffAccountIterator creates an iterator over the accounts changed or deleted
+// by a single diff layer, starting at the seek position.
+func newDiffAccountIterator(dl *diffLayer, seek common.Hash) *diffAccountIterator {
+	keys := make([]common.Hash, 0, len(dl.accountData)+len(dl.destructSet))
+	for hash := range dl.accountData {
+		keys = append(keys, hash)
+	}
+	for hash := range dl.destructSet {
+		if _, ok := dl.accountData[hash]; !ok {
+			keys = append(keys, hash)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+
+	index := sort.Search(len(keys), func(i int) bool { return bytes.Compare(seek[:], keys[i][:]) <= 0 })
+	return &diffAccountIterator{layer: dl, keys: keys[index:]}
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *diffAccountIterator) Next() bool {
+	if it.fail != nil || len(it.keys) == 0 {
+		return false
+	}
+	if it.layer.Stale() {
+		it.fail, it.keys = ErrSnapshotStale, nil
+		return false
+	}
+	it.curKey, it.keys = it.keys[0], it.keys[1:]
+	it.curData = it.layer.accountData[it.curKey]
+	return true
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *diffAccountIterator) Error() error {
+	return it.fail
+}
+
+// Hash returns the hash of the account the iterator is currently at.
+func (it *diffAccountIterator) Hash() common.Hash {
+	return it.curKey
+}
+
+// Account returns the RLP encoded account the iterator is currently at, or nil
+// if the account was deleted by the layer.
+func (it *diffAccountIterator) Account() []byte {
+	return it.curData
+}
+
+// Release is a noop for diff account iterators as there are no held resources.
+func (it *diffAccountIterator) Release() {}
+
+// diskAccountIterator is an account iterator that steps over the accounts of the
+// persistent disk layer.
+type diskAccountIterator struct {
+	layer *diskLayer
+	it    ethdb.Iterator
+	fail  error
+}
+
+// AccountIterator creates an account iterator over a disk layer.
+func (dl *diskLayer) AccountIterator(seek common.Hash) AccountIterator {
+	return &diskAccountIterator{
+		layer: dl,
+		it:    rawdb.IterateAccountSnapshots(dl.diskdb, seek),
+	}
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *diskAccountIterator) Next() bool {
+	if it.it == nil {
+		return false
+	}
+	if it.layer.Stale() {
+		it.fail = ErrSnapshotStale
+		it.Release()
+		return false
+	}
+	for {
+		if !it.it.Next() {
+			it.fail = it.it.Error()
+			it.Release()
+			return false
+		}
+		// Other tables may share the single byte snapshot prefix
+		if len(it.it.Key()) == len(rawdb.SnapshotAccountPrefix)+common.HashLength {
+			return true
+		}
+	}
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *diskAccountIterator) Error() error {
+	return it.fail
+}
+
+// Hash returns the hash of the account the iterator is currently at.
+func (it *diskAccountIterator) Hash() common.Hash {
+	return common.BytesToHash(it.it.Key()[len(rawdb.SnapshotAccountPrefix):])
+}
+
+// Account returns the RLP encoded account the iterator is currently at.
+func (it *diskAccountIterator) Account() []byte {
+	return it.it.Value()
+}
+
+// Release releases the database snapshot held during iteration.
+func (it *diskAccountIterator) Release() {
+	if it.it != nil {
+		it.it.Release()
+		it.it = nil
+	}
+}
+
+// layeredAccountIterator merges the accounts of a diff layer over the ones of its
+// parent layers, the diff taking precedence and its deletions hiding the parent's
+// entries. Only live accounts are returned.
+type layeredAccountIterator struct {
+	diff   *diffAccountIterator
+	parent AccountIterator
+
+	diffOk, parentOk bool // Whether the sub-iterators are positioned at an entry
+	started          bool
+
+	curKey  common.Hash
+	curData []byte
+	fail    error
+}
+
+// newLayeredAccountIterator creates an account iterator over a diff layer and
+// everything underneath it.
+func newLayeredAccountIterator(dl *diffLayer, seek common.Hash) AccountIterator {
+	return &layeredAccountIterator{
+		diff:   newDiffAccountIterator(dl, seek),
+		parent: dl.Parent().AccountIterator(seek),
+	}
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *layeredAccountIterator) Next() bool {
+	if !it.started {
+		it.diffOk, it.parentOk = it.diff.Next(), it.parent.Next()
+		it.started = true
+	}
+	for it.fail == nil {
+		if it.fail = it.diff.Error(); it.fail != nil {
+			break
+		}
+		if it.fail = it.parent.Error(); it.fail != nil {
+			break
+		}
+		switch {
+		case !it.diffOk && !it.parentOk:
+			return false
+
+		case !it.parentOk || (it.diffOk && bytes.Compare(it.diff.Hash().Bytes(), it.parent.Hash().Bytes()) <= 0):
+			// The diff entry comes first or shadows the parent one
+			if it.parentOk && it.diff.Hash() == it.parent.Hash() {
+				it.parentOk = it.parent.Next()
+			}
+			it.curKey, it.curData = it.diff.Hash(), it.diff.Account()
+			it.diffOk = it.diff.Next()
+			if it.curData == nil {
+				continue // deleted by the diff layer
+			}
+			return true
+
+		default:
+			it.curKey, it.curData = it.parent.Hash(), common.CopyBytes(it.parent.Account())
+			it.parentOk = it.parent.Next()
+			return true
+		}
+	}
+	return false
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *layeredAccountIterator) Error() error {
+	return it.fail
+}
+
+// Hash returns the hash of the account the iterator is currently at.
+func (it *layeredAccountIterator) Hash() common.Hash {
+	return it.curKey
+}
+
+// Account returns the RLP encoded account the iterator is currently at.
+func (it *layeredAccountIterator) Account() []byte {
+	return it.curData
+}
+
+// Release releases the resources held by the sub-iterators.
+func (it *layeredAccountIterator) Release() {
+	it.diff.Release()
+	it.parent.Release()
+}
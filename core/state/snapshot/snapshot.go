@@ -0,0 +1,391 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a journalled, dynamic state dump.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// ErrNotConstructed is returned if the callers want to iterate the snapshot
+	// while the generation is not finished yet.
+	ErrNotConstructed = errors.New("snapshot is not constructed")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the account associated with a particular hash in
+	// the snapshot, returning nil if the account does not exist.
+	Account(hash common.Hash) (*types.StateAccount, error)
+
+	// AccountRLP directly retrieves the account RLP associated with a particular
+	// hash in the snapshot, in the encoding of the account trie.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data associated with a particular hash,
+	// within a particular account, in the encoding of the storage trie.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports some
+// additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() snapshot
+
+	// Update creates a new layer on top of the existing snapshot diff tree with
+	// the specified data items.
+	Update(blockRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer
+
+	// Stale return whether this layer has become stale (was flattened across) or
+	// if it's still live.
+	Stale() bool
+
+	// AccountIterator creates an account iterator over an arbitrary layer.
+	AccountIterator(seek common.Hash) AccountIterator
+}
+
+// Tree is an Ethereum state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than the
+// disk layer, everything needs to be regenerated.
+//
+// The goal of a state snapshot is twofold: to allow direct access to account and
+// storage data to avoid expensive multi-level trie lookups; and to allow sorted,
+// cheap iteration of the account/storage tries for sync aid.
+type Tree struct {
+	diskdb ethdb.KeyValueStore      // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	cache  int                      // Megabytes permitted to use for read caches
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store (with a number of memory layers from a journal), ensuring that the head
+// of the snapshot matches the expected one.
+//
+// If the snapshot is missing or the disk layer is broken, the snapshot will be
+// reconstructed using both the existing data and the state trie. The generation
+// runs in the background, the snapshot only serving reads once done.
+func New(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash) *Tree {
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	if rawdb.ReadSnapshotRoot(diskdb) == root {
+		log.Info("Loaded state snapshot", "root", root)
+		snap.layers[root] = &diskLayer{
+			diskdb: diskdb,
+			triedb: triedb,
+			cache:  fastcache.New(cache * 1024 * 1024),
+			root:   root,
+		}
+		return snap
+	}
+	snap.Rebuild(root)
+	return snap
+}
+
+// Release stops the background generation of the snapshot, if still running.
+func (t *Tree) Release() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if disk := t.disklayer(); disk != nil {
+		disk.stopGeneration()
+	}
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if snap, ok := t.layers[blockRoot]; ok {
+		return snap
+	}
+	return nil
+}
+
+// DiskRoot returns the root of the persistent layer, or an empty hash if the
+// tree holds no layers.
+func (t *Tree) DiskRoot() common.Hash {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if disk := t.disklayer(); disk != nil {
+		return disk.Root()
+	}
+	return common.Hash{}
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for Clique networks where empty blocks
+	// don't modify the state (0 block subsidy).
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	// Generate a new snapshot on top of the parent
+	parent, ok := t.Snapshot(parentRoot).(snapshot)
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	snap := parent.Update(blockRoot, destructs, accounts, storage)
+
+	// Save the new snapshot for later
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.layers[snap.root] = snap
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards into the disk layer, and the layers not descending
+// from the new disk layer anymore are dropped.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	// Gather the diff layers from the head down to the disk layer
+	var path []*diffLayer
+	for layer := snap; ; layer = layer.Parent() {
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			break
+		}
+		path = append(path, diff)
+	}
+	if len(path) <= layers {
+		return nil
+	}
+	base := t.disklayer()
+	if base == nil {
+		return fmt.Errorf("snapshot [%#x] disk layer missing", root)
+	}
+	// Flatten the layers past the permitted ones, bottom first, pausing the
+	// generation of the disk layer meanwhile
+	generating := base.stopGeneration()
+	for i := len(path) - 1; i >= layers; i-- {
+		base = diffToDisk(base, path[i])
+	}
+	if layers > 0 {
+		path[layers-1].setParent(base)
+	}
+	if generating {
+		base.startGeneration()
+	}
+	// Drop the layers not building on top of the new disk layer
+	for root, layer := range t.layers {
+		for ; layer != nil; layer = layer.Parent() {
+			if _, ok := layer.(*diskLayer); ok {
+				break
+			}
+		}
+		if layer != base {
+			if diff, ok := t.layers[root].(*diffLayer); ok {
+				diff.markStale()
+			}
+			delete(t.layers, root)
+		}
+	}
+	t.layers[base.root] = base
+	return nil
+}
+
+// Rebuild wipes all available snapshot data from the persistent database and
+// discards all caches and diff layers. Afterwards, it starts a new snapshot
+// generator with the given root hash.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Stop the running generator and invalidate all the layers
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			layer.stopGeneration()
+			layer.markStale()
+		case *diffLayer:
+			layer.markStale()
+		}
+	}
+	log.Info("Rebuilding state snapshot", "root", root)
+	disk := &diskLayer{
+		diskdb:    t.diskdb,
+		triedb:    t.triedb,
+		cache:     fastcache.New(t.cache * 1024 * 1024),
+		root:      root,
+		genMarker: []byte{}, // Initialized but empty!
+	}
+	disk.startGeneration()
+	t.layers = map[common.Hash]snapshot{root: disk}
+}
+
+// AccountIterator creates a new account iterator for the specified root hash and
+// seeks to a starting account hash.
+func (t *Tree) AccountIterator(root common.Hash, seek common.Hash) (AccountIterator, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return nil, fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	if disk := t.disklayer(); disk == nil || disk.generating() {
+		return nil, ErrNotConstructed
+	}
+	return snap.AccountIterator(seek), nil
+}
+
+// disklayer is an internal helper function to return the disk layer.
+// The lock of snapTree is assumed to be held already.
+func (t *Tree) disklayer() *diskLayer {
+	for _, layer := range t.layers {
+		for ; layer != nil; layer = layer.Parent() {
+			if disk, ok := layer.(*diskLayer); ok {
+				return disk
+			}
+		}
+	}
+	return nil
+}
+
+// diffToDisk merges a bottom-most diff into the persistent disk layer underneath
+// it, returning the new disk layer. Only the data already covered by a running
+// generation is written, the rest being generated from the new root afterwards.
+func diffToDisk(base *diskLayer, bottom *diffLayer) *diskLayer {
+	batch := base.diskdb.NewBatch()
+	flush := func() {
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write state snapshot", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	// Mark the snapshot invalid until the merge completes, so that a crash
+	// midway leads to a regeneration
+	rawdb.DeleteSnapshotRoot(batch)
+
+	for hash := range bottom.destructSet {
+		if !base.accountCovered(hash) {
+			continue
+		}
+		rawdb.DeleteAccountSnapshot(batch, hash)
+		base.cache.Del(hash[:])
+
+		it := rawdb.IterateStorageSnapshots(base.diskdb, hash)
+		for it.Next() {
+			key := it.Key()
+			if len(key) != len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+				continue
+			}
+			batch.Delete(key)
+			base.cache.Del(key[len(rawdb.SnapshotStoragePrefix):])
+		}
+		it.Release()
+		flush()
+	}
+	for hash, data := range bottom.accountData {
+		if !base.accountCovered(hash) {
+			continue
+		}
+		rawdb.WriteAccountSnapshot(batch, hash, data)
+		base.cache.Set(hash[:], data)
+		flush()
+	}
+	for accountHash, storage := range bottom.storageData {
+		if !base.accountCovered(accountHash) {
+			continue
+		}
+		for storageHash, data := range storage {
+			if !base.storageCovered(accountHash, storageHash) {
+				continue
+			}
+			if len(data) > 0 {
+				rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, data)
+			} else {
+				rawdb.DeleteStorageSnapshot(batch, accountHash, storageHash)
+			}
+			base.cache.Set(append(accountHash[:], storageHash[:]...), data)
+		}
+		flush()
+	}
+	if base.genMarker == nil {
+		rawdb.WriteSnapshotRoot(batch, bottom.root)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write state snapshot", "err", err)
+	}
+	log.Debug("Flattened snapshot layer to disk", "root", bottom.root, "accounts", len(bottom.accountData), "destructs", len(bottom.destructSet))
+
+	res := &diskLayer{
+		diskdb:    base.diskdb,
+		triedb:    base.triedb,
+		cache:     base.cache,
+		root:      bottom.root,
+		genMarker: base.genMarker,
+	}
+	base.markStale()
+	bottom.markStale()
+	return res
+}
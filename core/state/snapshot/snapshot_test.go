@@ -0,0 +1,238 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// testAccount generates the RLP encoding of an account with the given balance
+// and storage root.
+func testAccount(balance int64, root common.Hash) []byte {
+	data, _ := rlp.EncodeToBytes(&types.StateAccount{
+		Balance:  big.NewInt(balance),
+		Root:     root,
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	})
+	return data
+}
+
+// makeTestState creates a state of n accounts, each of them but the first
+// holding a storage slot, and returns its root.
+func makeTestState(t *testing.T, db ethdb.Database, n int) (*trie.Database, common.Hash) {
+	var (
+		triedb = trie.NewDatabase(db)
+		nodes  = trie.NewMergedNodeSet()
+		accTr  = trie.NewEmpty(triedb)
+	)
+	for i := 1; i <= n; i++ {
+		accountHash := common.Hash{byte(i)}
+		storageRoot := types.EmptyRootHash
+		if i > 1 {
+			storeTr, _ := trie.New(accountHash, common.Hash{}, triedb)
+			storeTr.Update(common.Hash{byte(i)}.Bytes(), []byte{byte(i)})
+			root, set, err := storeTr.Commit(false)
+			if err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+			if err := nodes.Merge(set); err != nil {
+				t.Fatalf("failed to merge storage nodes: %v", err)
+			}
+			storageRoot = root
+		}
+		accTr.Update(accountHash.Bytes(), testAccount(int64(i), storageRoot))
+	}
+	root, set, err := accTr.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	if err := nodes.Merge(set); err != nil {
+		t.Fatalf("failed to merge account nodes: %v", err)
+	}
+	if err := triedb.Update(nodes); err != nil {
+		t.Fatalf("failed to update trie database: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie database: %v", err)
+	}
+	return triedb, root
+}
+
+// waitGeneration waits until the snapshot tree is done generating its disk layer.
+func waitGeneration(t *testing.T, snaps *Tree) {
+	for i := 0; i < 500; i++ {
+		snaps.lock.RLock()
+		disk := snaps.disklayer()
+		snaps.lock.RUnlock()
+		if !disk.generating() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("snapshot generation timed out")
+}
+
+// Tests that a snapshot is generated from the state trie and reloaded on restart.
+func TestGenerateSnapshot(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	// Leave an unrelated entry sharing the account prefix around
+	db.Put([]byte("account-unrelated"), []byte{1})
+
+	triedb, root := makeTestState(t, db, 10)
+	snaps := New(db, triedb, 1, root)
+	waitGeneration(t, snaps)
+
+	if have := rawdb.ReadSnapshotRoot(db); have != root {
+		t.Fatalf("snapshot root mismatch: have %x, want %x", have, root)
+	}
+	snap := snaps.Snapshot(root)
+	for i := 1; i <= 10; i++ {
+		account, err := snap.Account(common.Hash{byte(i)})
+		if err != nil || account == nil || account.Balance.Int64() != int64(i) {
+			t.Fatalf("account %d: have %v, err %v", i, account, err)
+		}
+		slot, err := snap.Storage(common.Hash{byte(i)}, common.Hash{byte(i)})
+		if i > 1 && (err != nil || !bytes.Equal(slot, []byte{byte(i)})) {
+			t.Fatalf("slot %d: have %x, err %v", i, slot, err)
+		}
+	}
+	if data, _ := db.Get([]byte("account-unrelated")); len(data) == 0 {
+		t.Fatalf("unrelated entry wiped")
+	}
+	// A restarted tree must load the persisted snapshot without generating
+	snaps = New(db, triedb, 1, root)
+	if snaps.disklayer().generating() {
+		t.Fatalf("persisted snapshot regenerated")
+	}
+}
+
+// Tests that diff layers shadow their parents and are flattened into the disk
+// layer when capped.
+func TestDiffLayersCap(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	triedb, root := makeTestState(t, db, 3)
+	snaps := New(db, triedb, 1, root)
+	waitGeneration(t, snaps)
+
+	var (
+		root1 = common.Hash{0xa1}
+		root2 = common.Hash{0xa2}
+		acc1  = common.Hash{1}
+		acc2  = common.Hash{2}
+		acc9  = common.Hash{9}
+	)
+	// Layer 1 modifies an account and creates a new one
+	err := snaps.Update(root1, root, nil, map[common.Hash][]byte{
+		acc1: testAccount(100, types.EmptyRootHash),
+		acc9: testAccount(9, types.EmptyRootHash),
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to add layer 1: %v", err)
+	}
+	// Layer 2 destructs an account with storage
+	err = snaps.Update(root2, root1, map[common.Hash]struct{}{acc2: {}}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to add layer 2: %v", err)
+	}
+	if err := snaps.Update(common.Hash{0xff}, common.Hash{0xee}, nil, nil, nil); err == nil {
+		t.Fatalf("layer without parent accepted")
+	}
+	check := func(snap Snapshot) {
+		t.Helper()
+		if account, _ := snap.Account(acc1); account == nil || account.Balance.Int64() != 100 {
+			t.Fatalf("modified account: have %v", account)
+		}
+		if account, _ := snap.Account(acc9); account == nil {
+			t.Fatalf("created account missing")
+		}
+		if account, _ := snap.Account(acc2); account != nil {
+			t.Fatalf("destructed account present: %v", account)
+		}
+		if slot, _ := snap.Storage(acc2, common.Hash{2}); len(slot) != 0 {
+			t.Fatalf("destructed slot present: %x", slot)
+		}
+	}
+	check(snaps.Snapshot(root2))
+
+	// The parent layer must be unaffected by the child's changes
+	if account, _ := snaps.Snapshot(root1).Account(acc2); account == nil {
+		t.Fatalf("account missing from the parent layer")
+	}
+	// Flatten everything into the disk and check the stale layers are dropped
+	old := snaps.Snapshot(root1)
+	if err := snaps.Cap(root2, 0); err != nil {
+		t.Fatalf("failed to cap: %v", err)
+	}
+	if _, err := old.Account(acc1); err != ErrSnapshotStale {
+		t.Fatalf("flattened layer not stale: %v", err)
+	}
+	if snaps.Snapshot(root) != nil || snaps.Snapshot(root1) != nil {
+		t.Fatalf("flattened layers retained")
+	}
+	if have := rawdb.ReadSnapshotRoot(db); have != root2 {
+		t.Fatalf("snapshot root mismatch: have %x, want %x", have, root2)
+	}
+	check(snaps.Snapshot(root2))
+	if _, ok := snaps.Snapshot(root2).(*diskLayer); !ok {
+		t.Fatalf("capped layer not on disk")
+	}
+}
+
+// Tests that the account iterator merges the layers in order, hiding the
+// destructed accounts.
+func TestAccountIterator(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	triedb, root := makeTestState(t, db, 5)
+	snaps := New(db, triedb, 1, root)
+	waitGeneration(t, snaps)
+
+	root1, root2 := common.Hash{0xa1}, common.Hash{0xa2}
+	snaps.Update(root1, root, map[common.Hash]struct{}{{3}: {}}, map[common.Hash][]byte{
+		{7}: testAccount(7, types.EmptyRootHash),
+	}, nil)
+	snaps.Update(root2, root1, map[common.Hash]struct{}{{7}: {}}, map[common.Hash][]byte{
+		{3}: testAccount(33, types.EmptyRootHash),
+		{6}: testAccount(6, types.EmptyRootHash),
+	}, nil)
+
+	it, err := snaps.AccountIterator(root2, common.Hash{2})
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Release()
+
+	var have []byte
+	for it.Next() {
+		have = append(have, it.Hash()[0])
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if want := []byte{2, 3, 4, 5, 6}; !bytes.Equal(have, want) {
+		t.Fatalf("iterated accounts mismatch: have %v, want %v", have, want)
+	}
+}
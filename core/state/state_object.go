@@ -194,19 +194,31 @@ func (s *stateObject) GetCommittedState(db Database, key common.Hash) common.Has
 	if _, destructed := s.db.stateObjectsDestruct[s.address]; destructed {
 		return common.Hash{}
 	}
-	// Track the amount of time wasted on reading the storage trie
-	start := time.Now()
-	// Otherwise load the value from the database
-	tr, err := s.getTrie(db)
-	if err != nil {
-		s.setError(err)
-		return common.Hash{}
-	}
-	enc, err := tr.TryGet(key.Bytes())
-	s.db.StorageReads += time.Since(start)
-	if err != nil {
-		s.setError(err)
-		return common.Hash{}
+	// If no live objects are available, attempt to use snapshots
+	var (
+		enc []byte
+		err error
+	)
+	if s.db.snap != nil {
+		start := time.Now()
+		enc, err = s.db.snap.Storage(s.addrHash, crypto.Keccak256Hash(key.Bytes()))
+		s.db.SnapshotStorageReads += time.Since(start)
+	}
+	// If the snapshot is unavailable or reading from it fails, load from the database
+	if s.db.snap == nil || err != nil {
+		// Track the amount of time wasted on reading the storage trie
+		start := time.Now()
+		tr, err := s.getTrie(db)
+		if err != nil {
+			s.setError(err)
+			return common.Hash{}
+		}
+		enc, err = tr.TryGet(key.Bytes())
+		s.db.StorageReads += time.Since(start)
+		if err != nil {
+			s.setError(err)
+			return common.Hash{}
+		}
 	}
 	var value common.Hash
 	if len(enc) > 0 {
@@ -267,6 +279,14 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 		s.setError(err)
 		return nil, err
 	}
+	// If state snapshotting is active, cache the data til commit
+	var storage map[common.Hash][]byte
+	if s.db.snap != nil {
+		if storage = s.db.snapStorage[s.addrHash]; storage == nil {
+			storage = make(map[common.Hash][]byte)
+			s.db.snapStorage[s.addrHash] = storage
+		}
+	}
 	// Insert all the pending updates into the trie
 	for key, value := range s.pendingStorage {
 		// Skip noop changes, persist actual changes
@@ -275,6 +295,7 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 		}
 		s.originStorage[key] = value
 
+		var v []byte
 		if (value == common.Hash{}) {
 			if err := tr.TryDelete(key[:]); err != nil {
 				s.setError(err)
@@ -283,13 +304,16 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 			s.db.StorageDeleted += 1
 		} else {
 			// Encoding []byte cannot fail, ok to ignore the error.
-			v, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
+			v, _ = rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
 			if err := tr.TryUpdate(key[:], v); err != nil {
 				s.setError(err)
 				return nil, err
 			}
 			s.db.StorageUpdated += 1
 		}
+		if storage != nil {
+			storage[crypto.Keccak256Hash(key[:])] = v // v will be nil if value is 0x00
+		}
 	}
 	if len(s.pendingStorage) > 0 {
 		s.pendingStorage = make(Storage)
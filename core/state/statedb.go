@@ -18,6 +18,7 @@
 package state
 
 import (
+	"errors"
 	"fmt"
 	"maps"
 	"math/big"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state/snapshot"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/crypto"
 	"github.com/XinFinOrg/XDPoSChain/log"
@@ -39,6 +41,17 @@ type revision struct {
 	journalIndex int
 }
 
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
 // StateDB structs within the ethereum protocol are used to store anything
 // within the merkle trie. StateDBs take care of caching and storing
 // nested states. It's the general query interface to retrieve:
@@ -48,6 +61,12 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	snaps         *snapshot.Tree
+	snap          snapshot.Snapshot
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects         map[common.Address]*stateObject
 	stateObjectsPending  map[common.Address]struct{} // State objects finalized but not yet written to the trie
@@ -95,6 +114,10 @@ type StateDB struct {
 	StorageUpdates time.Duration
 	StorageCommits time.Duration
 
+	SnapshotAccountReads time.Duration
+	SnapshotStorageReads time.Duration
+	SnapshotCommits      time.Duration
+
 	AccountUpdated int
 	StorageUpdated int
 	AccountDeleted int
@@ -130,6 +153,35 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 	}, nil
 }
 
+// NewWithSnapshot creates a new state from a given trie, serving its reads from
+// the flat snapshot of the root if available, and recording the changes made so
+// that Commit can push them into the snapshot tree.
+func NewWithSnapshot(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
+	sdb, err := New(root, db)
+	if err != nil {
+		return nil, err
+	}
+	if snaps != nil {
+		sdb.snaps = snaps
+		sdb.resetSnapshot(root)
+	}
+	return sdb, nil
+}
+
+// resetSnapshot attaches the state to the snapshot of the given root, if the
+// snapshot tree maintains one, dropping any changes recorded so far.
+func (s *StateDB) resetSnapshot(root common.Hash) {
+	s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
+	if s.snaps == nil {
+		return
+	}
+	if s.snap = s.snaps.Snapshot(root); s.snap != nil {
+		s.snapDestructs = make(map[common.Hash]struct{})
+		s.snapAccounts = make(map[common.Hash][]byte)
+		s.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
+}
+
 // setError remembers the first non-nil error it is called with.
 func (s *StateDB) setError(err error) {
 	if s.dbErr == nil {
@@ -150,6 +202,7 @@ func (s *StateDB) Reset(root common.Hash) error {
 		return err
 	}
 	s.trie = tr
+	s.resetSnapshot(root)
 	s.stateObjects = make(map[common.Address]*stateObject)
 	s.stateObjectsPending = make(map[common.Address]struct{})
 	s.stateObjectsDirty = make(map[common.Address]struct{})
@@ -353,6 +406,32 @@ func (s *StateDB) StorageTrie(addr common.Address) (Trie, error) {
 	return cpy.getTrie(s.db)
 }
 
+// GetProof returns the Merkle proof for a given account.
+func (s *StateDB) GetProof(addr common.Address) ([][]byte, error) {
+	return s.GetProofByHash(crypto.Keccak256Hash(addr.Bytes()))
+}
+
+// GetProofByHash returns the Merkle proof for a given account.
+func (s *StateDB) GetProofByHash(addrHash common.Hash) ([][]byte, error) {
+	var proof proofList
+	err := s.trie.Prove(addrHash[:], 0, &proof)
+	return proof, err
+}
+
+// GetStorageProof returns the Merkle proof for given storage slot.
+func (s *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte, error) {
+	trie, err := s.StorageTrie(a)
+	if err != nil {
+		return nil, err
+	}
+	if trie == nil {
+		return nil, errors.New("storage trie for requested address does not exist")
+	}
+	var proof proofList
+	err = trie.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return proof, err
+}
+
 func (s *StateDB) HasSelfDestructed(addr common.Address) bool {
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
@@ -495,6 +574,14 @@ func (s *StateDB) updateStateObject(obj *stateObject) {
 	if err := s.trie.TryUpdateAccount(addr[:], &obj.data); err != nil {
 		s.setError(fmt.Errorf("updateStateObject (%x) error: %v", addr[:], err))
 	}
+	// If state snapshotting is active, cache the data til commit
+	if s.snap != nil {
+		data, err := rlp.EncodeToBytes(&obj.data)
+		if err != nil {
+			panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
+		}
+		s.snapAccounts[obj.addrHash] = data
+	}
 }
 
 // deleteStateObject removes the given object from the state trie.
@@ -507,6 +594,18 @@ func (s *StateDB) deleteStateObject(obj *stateObject) {
 	if err := s.trie.TryDelete(addr[:]); err != nil {
 		s.setError(fmt.Errorf("deleteStateObject (%x) error: %v", addr[:], err))
 	}
+	// If state snapshotting is active, also mark the destruction there
+	if s.snap != nil {
+		s.snapDestruct(obj.addrHash)
+	}
+}
+
+// snapDestruct records the destruction of an account for the snapshot, dropping
+// any of its changes recorded earlier.
+func (s *StateDB) snapDestruct(addrHash common.Hash) {
+	s.snapDestructs[addrHash] = struct{}{}
+	delete(s.snapAccounts, addrHash)
+	delete(s.snapStorage, addrHash)
 }
 
 // DeleteAddress removes the address from the state trie.
@@ -537,16 +636,32 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
 	}
-	// Load the object from the database
-	start := time.Now()
-	data, err := s.trie.TryGetAccount(addr.Bytes())
-	s.AccountReads += time.Since(start)
-	if err != nil {
-		s.setError(fmt.Errorf("getDeleteStateObject (%x) error: %w", addr.Bytes(), err))
-		return nil
+	// If no live objects are available, attempt to use snapshots
+	var data *types.StateAccount
+	if s.snap != nil {
+		start := time.Now()
+		acc, err := s.snap.Account(crypto.Keccak256Hash(addr.Bytes()))
+		s.SnapshotAccountReads += time.Since(start)
+		if err == nil {
+			if acc == nil {
+				return nil
+			}
+			data = acc
+		}
 	}
+	// If snapshot unavailable or reading from it failed, load from the database
 	if data == nil {
-		return nil
+		start := time.Now()
+		var err error
+		data, err = s.trie.TryGetAccount(addr.Bytes())
+		s.AccountReads += time.Since(start)
+		if err != nil {
+			s.setError(fmt.Errorf("getDeleteStateObject (%x) error: %w", addr.Bytes(), err))
+			return nil
+		}
+		if data == nil {
+			return nil
+		}
 	}
 	// Insert into the live set
 	obj := newObject(s, addr, *data)
@@ -658,6 +773,16 @@ func (s *StateDB) Copy() *StateDB {
 		preimages:            maps.Clone(s.preimages),
 		journal:              newJournal(),
 	}
+	// Copy the snapshot tracking, the recorded changes being immutable
+	if s.snap != nil {
+		state.snaps, state.snap = s.snaps, s.snap
+		state.snapDestructs = maps.Clone(s.snapDestructs)
+		state.snapAccounts = maps.Clone(s.snapAccounts)
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(s.snapStorage))
+		for addrHash, storage := range s.snapStorage {
+			state.snapStorage[addrHash] = maps.Clone(storage)
+		}
+	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range s.journal.dirties {
 		// As documented [here](https://github.com/XinFinOrg/XDPoSChain/pull/16485#issuecomment-380438527),
@@ -764,6 +889,11 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			// set indefinitely).
 			s.stateObjectsDestruct[obj.address] = struct{}{}
 		} else {
+			// A resurrected account drops the storage recorded for the
+			// previous incarnation from the snapshot too
+			if _, destructed := s.stateObjectsDestruct[obj.address]; destructed && obj.created && s.snap != nil {
+				s.snapDestruct(obj.addrHash)
+			}
 			obj.finalise()
 		}
 		obj.created = false
@@ -902,6 +1032,20 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 	if err := s.db.TrieDB().Update(nodes); err != nil {
 		return common.Hash{}, err
 	}
+	// If snapshotting is enabled, update the snapshot tree with this new version
+	if s.snap != nil {
+		start := time.Now()
+		// Only update if there's a state transition (skip empty blocks)
+		if parent := s.snap.Root(); parent != root {
+			// The layer is only flattened once its block becomes canonical, so
+			// that side chain states stay available across reorgs
+			if err := s.snaps.Update(root, parent, s.snapDestructs, s.snapAccounts, s.snapStorage); err != nil {
+				log.Warn("Failed to update snapshot tree", "from", parent, "to", root, "err", err)
+			}
+		}
+		s.SnapshotCommits += time.Since(start)
+		s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
+	}
 	return root, err
 }
 
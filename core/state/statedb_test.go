@@ -28,10 +28,15 @@ import (
 	"sync"
 	"testing"
 	"testing/quick"
+	"time"
 
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state/snapshot"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -912,3 +917,114 @@ func TestCopyCopyCommitCopy(t *testing.T) {
 		t.Fatalf("third copy committed storage slot mismatch: have %x, want %x", val, sval)
 	}
 }
+
+// TestStateDBProof tests that account and storage proofs generated by the state
+// database verify against the state root.
+func TestStateDBProof(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+
+	addr := common.BytesToAddress([]byte("proof"))
+	slot := common.HexToHash("0x01")
+	state.SetBalance(addr, big.NewInt(42))
+	state.SetState(addr, slot, common.HexToHash("0x2a"))
+
+	root, _ := state.Commit(false)
+	state, _ = New(root, state.Database())
+
+	accountProof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	proofDb := rawdb.NewMemoryDatabase()
+	for _, node := range accountProof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	blob, err := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), proofDb)
+	if err != nil || len(blob) == 0 {
+		t.Fatalf("account proof verification failed: %v", err)
+	}
+	storageProof, err := state.GetStorageProof(addr, slot)
+	if err != nil {
+		t.Fatalf("failed to prove storage slot: %v", err)
+	}
+	proofDb = rawdb.NewMemoryDatabase()
+	for _, node := range storageProof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	if _, err := trie.VerifyProof(state.GetStorageRoot(addr), crypto.Keccak256(slot.Bytes()), proofDb); err != nil {
+		t.Fatalf("storage proof verification failed: %v", err)
+	}
+	if _, err := state.GetStorageProof(common.BytesToAddress([]byte("missing")), slot); err == nil {
+		t.Fatalf("expected error for missing storage trie")
+	}
+}
+
+// Tests that the changes of a committed state are pushed into the snapshot tree,
+// and that states backed by the snapshot read and dump the same as the trie.
+func TestSnapshotCommit(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		sdb   = NewDatabase(db)
+		addrA = common.Address{0xa}
+		addrB = common.Address{0xb}
+		addrC = common.Address{0xc}
+		slot  = common.Hash{0x1}
+	)
+	state, _ := New(types.EmptyRootHash, sdb)
+	state.SetBalance(addrA, big.NewInt(1))
+	state.SetNonce(addrB, 1)
+	state.SetState(addrB, slot, common.Hash{0x11})
+	state.SetNonce(addrC, 1)
+	root, _ := state.Commit(false)
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	snaps := snapshot.New(db, sdb.TrieDB(), 1, root)
+	for i := 0; ; i++ {
+		it, err := snaps.AccountIterator(root, common.Hash{})
+		if err == nil {
+			it.Release()
+			break
+		}
+		if i == 500 {
+			t.Fatalf("snapshot generation timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Modify the state on top of the snapshot and commit it
+	state, _ = NewWithSnapshot(root, sdb, snaps)
+	state.SetBalance(addrA, big.NewInt(2))
+	state.SetState(addrB, slot, common.Hash{0x22})
+	state.SelfDestruct(addrC)
+	root, _ = state.Commit(true)
+
+	snap := snaps.Snapshot(root)
+	if snap == nil {
+		t.Fatalf("committed state missing from the snapshot tree")
+	}
+	if account, _ := snap.Account(crypto.Keccak256Hash(addrA[:])); account == nil || account.Balance.Int64() != 2 {
+		t.Fatalf("account A: have %v", account)
+	}
+	if account, _ := snap.Account(crypto.Keccak256Hash(addrC[:])); account != nil {
+		t.Fatalf("destructed account C present: %v", account)
+	}
+	enc, _ := snap.Storage(crypto.Keccak256Hash(addrB[:]), crypto.Keccak256Hash(slot[:]))
+	if want, _ := rlp.EncodeToBytes(common.Hash{0x22}.Bytes()); !bytes.Equal(enc, want) {
+		t.Fatalf("slot of B: have %x, want %x", enc, want)
+	}
+	// Reads and dumps served by the snapshot must match the trie
+	snapState, _ := NewWithSnapshot(root, sdb, snaps)
+	trieState, _ := New(root, sdb)
+	if have := snapState.GetBalance(addrA); have.Int64() != 2 {
+		t.Fatalf("balance of A: have %v", have)
+	}
+	if have := snapState.GetState(addrB, slot); have != (common.Hash{0x22}) {
+		t.Fatalf("slot of B: have %x", have)
+	}
+	if snapState.Exist(addrC) {
+		t.Fatalf("destructed account C exists")
+	}
+	if have, want := snapState.RawDump(nil), trieState.RawDump(nil); !reflect.DeepEqual(have, want) {
+		t.Fatalf("dump mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+}
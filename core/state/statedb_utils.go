@@ -90,15 +90,12 @@ var (
 )
 
 func GetCandidates(statedb *StateDB) []common.Address {
-	slot := slotValidatorMapping["candidates"]
-	slotHash := common.BigToHash(new(big.Int).SetUint64(slot))
-	arrLength := statedb.GetState(common.MasternodeVotingSMCBinary, slotHash)
+	arrLength := statedb.GetState(common.MasternodeVotingSMCBinary, GetCandidatesLengthLoc())
 	count := arrLength.Big().Uint64()
 	rets := make([]common.Address, 0, count)
 
 	for i := uint64(0); i < count; i++ {
-		key := GetLocDynamicArrAtElement(slotHash, i, 1)
-		ret := statedb.GetState(common.MasternodeVotingSMCBinary, key)
+		ret := statedb.GetState(common.MasternodeVotingSMCBinary, GetCandidateLoc(i))
 		if !ret.IsZero() {
 			rets = append(rets, common.HexToAddress(ret.Hex()))
 		}
@@ -117,12 +114,29 @@ func GetCandidateOwner(statedb *StateDB, candidate common.Address) common.Addres
 }
 
 func GetCandidateCap(statedb *StateDB, candidate common.Address) *big.Int {
-	slot := slotValidatorMapping["validatorsState"]
+	ret := statedb.GetState(common.MasternodeVotingSMCBinary, GetCandidateCapLoc(candidate))
+	return ret.Big()
+}
+
+// GetCandidatesLengthLoc returns the storage location of the length of the
+// candidates array of the validator contract.
+func GetCandidatesLengthLoc() common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(slotValidatorMapping["candidates"]))
+}
+
+// GetCandidateLoc returns the storage location of the candidate at the given
+// index of the candidates array of the validator contract.
+func GetCandidateLoc(index uint64) common.Hash {
+	return GetLocDynamicArrAtElement(GetCandidatesLengthLoc(), index, 1)
+}
+
+// GetCandidateCapLoc returns the storage location of the cap of a candidate
+// of the validator contract.
+func GetCandidateCapLoc(candidate common.Address) common.Hash {
 	// validatorsState[_candidate].cap;
-	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
+	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slotValidatorMapping["validatorsState"])
 	locCandidateCap := locValidatorsState.Add(locValidatorsState, new(big.Int).SetUint64(uint64(1)))
-	ret := statedb.GetState(common.MasternodeVotingSMCBinary, common.BigToHash(locCandidateCap))
-	return ret.Big()
+	return common.BigToHash(locCandidateCap)
 }
 
 func GetVoters(statedb *StateDB, candidate common.Address) []common.Address {
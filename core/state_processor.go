@@ -117,6 +117,12 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, tra
 				return nil, nil, 0, err
 			}
 		}
+		// verify the forensic proof of slashing transactions
+		if tx.IsSlashingTransaction() {
+			if err := ValidateSlashingTransaction(p.bc, header, statedb, tx); err != nil {
+				return nil, nil, 0, err
+			}
+		}
 		statedb.SetTxContext(tx.Hash(), i)
 		receipt, gas, err, tokenFeeUsed := applyTransaction(p.config, balanceFee, gp, statedb, coinbaseOwner, blockNumber, header.BaseFee, blockHash, tx, usedGas, vmenv)
 		if err != nil {
@@ -197,6 +203,12 @@ func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, stated
 				return nil, nil, 0, err
 			}
 		}
+		// verify the forensic proof of slashing transactions
+		if tx.IsSlashingTransaction() {
+			if err := ValidateSlashingTransaction(p.bc, header, statedb, tx); err != nil {
+				return nil, nil, 0, err
+			}
+		}
 		statedb.SetTxContext(tx.Hash(), i)
 		receipt, gas, err, tokenFeeUsed := applyTransaction(p.config, balanceFee, gp, statedb, coinbaseOwner, blockNumber, header.BaseFee, blockHash, tx, usedGas, vmenv)
 		if err != nil {
@@ -226,6 +238,9 @@ func applyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*
 		if *to == common.BlockSignersBinary && config.IsTIPSigning(blockNumber) {
 			return ApplySignTransaction(config, statedb, blockNumber, blockHash, tx, usedGas)
 		}
+		if *to == common.SlashingAddrBinary && config.IsTIPSlashing(blockNumber) {
+			return ApplySlashingTransaction(config, statedb, blockNumber, blockHash, tx, usedGas)
+		}
 		if *to == common.TradingStateAddrBinary && config.IsTIPXDCXReceiver(blockNumber) {
 			return ApplyEmptyTransaction(config, statedb, blockNumber, blockHash, tx, usedGas)
 		}
@@ -67,7 +67,7 @@ type LendingPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
-	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+	Lifetime time.Duration // Maximum amount of time non-local transactions are kept in the pool
 }
 
 // blockChain_XDCx add order state
@@ -108,6 +108,26 @@ func (config *LendingPoolConfig) sanitize() LendingPoolConfig {
 		log.Warn("Sanitizing invalid LendingPool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.AccountSlots < 1 {
+		log.Warn("Sanitizing invalid LendingPool account slots", "provided", conf.AccountSlots, "updated", DefaultLendingPoolConfig.AccountSlots)
+		conf.AccountSlots = DefaultLendingPoolConfig.AccountSlots
+	}
+	if conf.GlobalSlots < 1 {
+		log.Warn("Sanitizing invalid LendingPool global slots", "provided", conf.GlobalSlots, "updated", DefaultLendingPoolConfig.GlobalSlots)
+		conf.GlobalSlots = DefaultLendingPoolConfig.GlobalSlots
+	}
+	if conf.AccountQueue < 1 {
+		log.Warn("Sanitizing invalid LendingPool account queue", "provided", conf.AccountQueue, "updated", DefaultLendingPoolConfig.AccountQueue)
+		conf.AccountQueue = DefaultLendingPoolConfig.AccountQueue
+	}
+	if conf.GlobalQueue < 1 {
+		log.Warn("Sanitizing invalid LendingPool global queue", "provided", conf.GlobalQueue, "updated", DefaultLendingPoolConfig.GlobalQueue)
+		conf.GlobalQueue = DefaultLendingPoolConfig.GlobalQueue
+	}
+	if conf.Lifetime < 1 {
+		log.Warn("Sanitizing invalid LendingPool lifetime", "provided", conf.Lifetime, "updated", DefaultLendingPoolConfig.Lifetime)
+		conf.Lifetime = DefaultLendingPoolConfig.Lifetime
+	}
 	return conf
 }
 
@@ -140,6 +160,7 @@ type LendingPool struct {
 	pending   map[common.Address]*lendingtxList         // All currently processable transactions
 	queue     map[common.Address]*lendingtxList         // Queued but non-processable transactions
 	beats     map[common.Address]time.Time              // Last heartbeat from each known account
+	seen      map[common.Hash]time.Time                 // Arrival time of each transaction for lifetime expiry
 	all       map[common.Hash]*types.LendingTransaction // All transactions to allow lookups
 	wg        sync.WaitGroup                            // for shutdown sync
 	homestead bool
@@ -148,9 +169,9 @@ type LendingPool struct {
 
 // NewLendingPool creates a new transaction pool to gather, sort and filter inbound
 // transactions from the network.
-func NewLendingPool(chainconfig *params.ChainConfig, chain blockChainLending) *LendingPool {
+func NewLendingPool(config LendingPoolConfig, chainconfig *params.ChainConfig, chain blockChainLending) *LendingPool {
 	// Sanitize the input to ensure no vulnerable gas prices are set
-	config := (&DefaultLendingPoolConfig).sanitize()
+	config = (&config).sanitize()
 	log.Debug("NewLendingPool start...", "current block", chain.CurrentBlock().Header().Number)
 	// Create the transaction pool with its initial settings
 	pool := &LendingPool{
@@ -161,6 +182,7 @@ func NewLendingPool(chainconfig *params.ChainConfig, chain blockChainLending) *L
 		pending:     make(map[common.Address]*lendingtxList),
 		queue:       make(map[common.Address]*lendingtxList),
 		beats:       make(map[common.Address]time.Time),
+		seen:        make(map[common.Hash]time.Time),
 		all:         make(map[common.Hash]*types.LendingTransaction),
 		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
 	}
@@ -239,21 +261,10 @@ func (pool *LendingPool) loop() {
 				prevPending, prevQueued = pending, queued
 			}
 
-			// Handle inactive account transaction eviction
+			// Handle expired transaction eviction
 		case <-evict.C:
 			pool.mu.Lock()
-			for addr := range pool.queue {
-				// Skip local transactions from the eviction mechanism
-				if pool.locals.contains(addr) {
-					continue
-				}
-				// Any non-locals old enough should be removed
-				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
-						pool.removeTx(tx.Hash())
-					}
-				}
-			}
+			pool.expire(time.Now())
 			pool.mu.Unlock()
 
 			// Handle local transaction journal rotation
@@ -651,14 +662,30 @@ func (pool *LendingPool) validateTx(tx *types.LendingTransaction, local bool) er
 	return nil
 }
 
+// amendsLending reports whether tx may replace old, the pooled lending transaction
+// of the same account and nonce. An amendment may change the quantity, interest,
+// type or status of the lending, but it has to keep the lending and trade IDs,
+// relayer, lending token, term and side. As a cancellation carries the ID of a
+// lending already in the lending book, a pooled new lending (still without ID)
+// can't be amended into one.
+func amendsLending(old, tx *types.LendingTransaction) bool {
+	return old.LendingId() == tx.LendingId() &&
+		old.LendingTradeId() == tx.LendingTradeId() &&
+		old.RelayerAddress() == tx.RelayerAddress() &&
+		old.LendingToken() == tx.LendingToken() &&
+		old.Term() == tx.Term() &&
+		old.Side() == tx.Side()
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
-// later pending promotion and execution. If the transaction is a replacement for
+// later pending promotion and execution. If the transaction is an amendment of
 // an already pending or queued one, it overwrites the previous and returns this
-// so outer code doesn't uselessly call promote.
+// so outer code doesn't uselessly call promote. Any other transaction reusing a
+// pooled nonce is rejected.
 //
 // If a newly added transaction is marked as local, its sending account will be
 // whitelisted, preventing any associated transaction from being dropped out of
-// the pool due to pricing constraints.
+// the pool due to pricing constraints or age.
 func (pool *LendingPool) add(tx *types.LendingTransaction, local bool) (bool, error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
@@ -675,8 +702,14 @@ func (pool *LendingPool) add(tx *types.LendingTransaction, local bool) (bool, er
 	}
 	from, _ := types.LendingSender(pool.signer, tx) // already validated
 
-	// If the transaction pool is full, discard underpriced transactions
-	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+	// An lending already holding the nonce may only be replaced by its amendment
+	old := pool.nonceHolder(from, tx.Nonce())
+	if old != nil && !amendsLending(old, tx) {
+		log.Debug("Discarding mismatched lending replacement", "hash", hash, "nonce", tx.Nonce(), "old", old.Hash())
+		return false, ErrAmendMismatch
+	}
+	// If the transaction pool is full, make room by evicting a lending of the sender
+	if old == nil && uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue && !pool.evictQueued(from, tx.Nonce()) {
 		log.Debug("Add lending transaction to pool full", "hash", hash, "nonce", tx.Nonce())
 		return false, ErrPoolOverflow
 	}
@@ -692,6 +725,7 @@ func (pool *LendingPool) add(tx *types.LendingTransaction, local bool) (bool, er
 			pendingReplaceMeter.Mark(1)
 		}
 		pool.all[tx.Hash()] = tx
+		pool.seen[hash] = time.Now()
 		pool.journalTx(from, tx)
 
 		log.Debug("Lending Pooled new executable transaction", "hash", hash, "useraddress", tx.UserAddress(), "nonce", tx.Nonce(), "status", tx.Status(), "lendingid", tx.LendingId())
@@ -703,6 +737,7 @@ func (pool *LendingPool) add(tx *types.LendingTransaction, local bool) (bool, er
 	if err != nil {
 		return false, err
 	}
+	pool.seen[hash] = time.Now()
 	// Mark local addresses and journal local transactions
 	if local {
 		pool.locals.add(from)
@@ -939,6 +974,69 @@ func (pool *LendingPool) removeTx(hash common.Hash) {
 	}
 }
 
+// nonceHolder retrieves the pending or queued transaction of an account holding
+// the given nonce, or nil if the nonce is free.
+func (pool *LendingPool) nonceHolder(addr common.Address, nonce uint64) *types.LendingTransaction {
+	if list := pool.pending[addr]; list != nil {
+		if tx := list.txs.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := pool.queue[addr]; list != nil {
+		return list.txs.Get(nonce)
+	}
+	return nil
+}
+
+// expire drops the non-local transactions which have been in the pool for longer
+// than the configured lifetime, and forgets the arrival time of the ones that
+// left the pool in the mean time.
+func (pool *LendingPool) expire(now time.Time) {
+	for hash, seen := range pool.seen {
+		tx := pool.all[hash]
+		if tx == nil {
+			delete(pool.seen, hash)
+			continue
+		}
+		if now.Sub(seen) <= pool.config.Lifetime || pool.locals.containsTx(tx) {
+			continue
+		}
+		log.Debug("Evicting expired lending transaction", "hash", hash, "nonce", tx.Nonce(), "lendingid", tx.LendingId())
+		pool.removeTx(hash)
+		delete(pool.seen, hash)
+	}
+}
+
+// evictQueued makes room for a transaction of the given account and nonce by
+// dropping the queued one of its own with the highest nonce, so that a full pool
+// never evicts the lendings of other accounts, nor the pending ones the sender's
+// later lendings depend on. Nothing is evicted from local accounts, or if the
+// sender queues nothing above the given nonce. It returns whether anything was
+// evicted.
+func (pool *LendingPool) evictQueued(from common.Address, nonce uint64) bool {
+	if pool.locals.contains(from) {
+		return false
+	}
+	list := pool.queue[from]
+	if list == nil {
+		return false
+	}
+	var highest *types.LendingTransaction
+	for _, tx := range list.txs.items {
+		if tx.Nonce() > nonce && (highest == nil || tx.Nonce() > highest.Nonce()) {
+			highest = tx
+		}
+	}
+	if highest == nil {
+		return false
+	}
+	hash := highest.Hash()
+	log.Debug("Evicting queued lending transaction", "hash", hash, "from", from, "nonce", highest.Nonce())
+	pool.removeTx(hash)
+	delete(pool.seen, hash)
+	return true
+}
+
 // promoteExecutables moves transactions that have become processable from the
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
@@ -282,3 +282,82 @@ func TestRecallLending(t *testing.T) {
 	testSendLending(key, nonce, USDAddress, common.XDCNativeAddressBinary, new(big.Int).Mul(_1E8, big.NewInt(1000)), interestRate, lendingstate.Borrowing, lendingstate.LendingStatusNew, true, 0, 0, common.Hash{}, "")
 	time.Sleep(2 * time.Second)
 }
+
+func signedLending(t *testing.T, nonce, interest, term uint64, side, status string, lendingID uint64) *types.LendingTransaction {
+	key, _ := crypto.HexToECDSA("65ec4d4dfbcac594a14c36baa462d6f73cd86134840f6cf7b80a1e1cd33473e2")
+	tx := types.NewLendingTransaction(nonce, big.NewInt(1), interest, term, common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e"), crypto.PubkeyToAddress(key.PublicKey), USDAddress, BTCAddress, false, status, side, LendingTypeLimit, common.Hash{}, lendingID, 0, "")
+	signed, err := types.LendingSignTx(tx, types.LendingTxSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign lending: %v", err)
+	}
+	return signed
+}
+
+func TestLendingAmendment(t *testing.T) {
+	old := signedLending(t, 5, 100, 86400, lendingstate.Investing, lendingstate.LendingStatusNew, 7)
+
+	tests := []struct {
+		tx   *types.LendingTransaction
+		want bool
+	}{
+		{signedLending(t, 5, 120, 86400, lendingstate.Investing, lendingstate.LendingStatusNew, 7), true},
+		{signedLending(t, 5, 100, 86400, lendingstate.Investing, lendingstate.LendingStatusCancelled, 7), true},
+		{signedLending(t, 5, 100, 86400, lendingstate.Investing, lendingstate.LendingStatusNew, 8), false},
+		{signedLending(t, 5, 100, 3600, lendingstate.Investing, lendingstate.LendingStatusNew, 7), false},
+		{signedLending(t, 5, 100, 86400, lendingstate.Borrowing, lendingstate.LendingStatusNew, 7), false},
+	}
+	for i, tt := range tests {
+		if have := amendsLending(old, tt.tx); have != tt.want {
+			t.Errorf("test %d: amendment mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// A pooled new lending has no ID yet, so no cancellation can amend it
+	fresh := signedLending(t, 5, 100, 86400, lendingstate.Investing, lendingstate.LendingStatusNew, 0)
+	if amendsLending(fresh, signedLending(t, 5, 100, 86400, lendingstate.Investing, lendingstate.LendingStatusCancelled, 7)) {
+		t.Errorf("cancellation amended a new lending")
+	}
+}
+
+func TestLendingPoolExpiry(t *testing.T) {
+	pool := &LendingPool{
+		config:  DefaultLendingPoolConfig,
+		signer:  types.LendingTxSigner{},
+		pending: make(map[common.Address]*lendingtxList),
+		queue:   make(map[common.Address]*lendingtxList),
+		beats:   make(map[common.Address]time.Time),
+		seen:    make(map[common.Hash]time.Time),
+		all:     make(map[common.Hash]*types.LendingTransaction),
+	}
+	pool.locals = newLendingAccountSet(pool.signer)
+
+	var (
+		now   = time.Now()
+		stale = signedLending(t, 3, 100, 86400, lendingstate.Investing, lendingstate.LendingStatusNew, 0)
+		fresh = signedLending(t, 4, 100, 86400, lendingstate.Investing, lendingstate.LendingStatusNew, 0)
+	)
+	for _, tx := range []*types.LendingTransaction{stale, fresh} {
+		if _, err := pool.enqueueTx(tx.Hash(), tx); err != nil {
+			t.Fatalf("failed to enqueue lending: %v", err)
+		}
+	}
+	pool.seen[stale.Hash()] = now.Add(-pool.config.Lifetime - time.Second)
+	pool.seen[fresh.Hash()] = now.Add(-time.Minute)
+
+	pool.expire(now)
+	if pool.all[stale.Hash()] != nil {
+		t.Errorf("stale lending not expired")
+	}
+	if pool.all[fresh.Hash()] == nil {
+		t.Errorf("fresh lending expired")
+	}
+	from, _ := types.LendingSender(pool.signer, fresh)
+	if pool.evictQueued(common.Address{}, 0) {
+		t.Errorf("lending evicted for an account without any")
+	}
+	if pool.evictQueued(from, 4) {
+		t.Errorf("lending evicted for a higher nonce")
+	}
+	if !pool.evictQueued(from, 3) || len(pool.all) != 0 {
+		t.Errorf("queued lending not evicted")
+	}
+}
@@ -47,6 +47,7 @@ var (
 	ErrInvalidOrderUserAddress = errors.New("invalid order user address")
 	ErrInvalidOrderQuantity    = errors.New("invalid order quantity")
 	ErrInvalidOrderPrice       = errors.New("invalid order price")
+	ErrInvalidOrderTrigger     = errors.New("invalid order trigger price")
 	ErrInvalidOrderHash        = errors.New("invalid order hash")
 	ErrInvalidCancelledOrder   = errors.New("invalid cancel orderid")
 )
@@ -54,6 +55,11 @@ var (
 var (
 	OrderTypeLimit    = "LO"
 	OrderTypeMarket   = "MO"
+	OrderTypeIOC      = "IOC"
+	OrderTypeFOK      = "FOK"
+	OrderTypePostOnly = "PO"
+	OrderTypeStopMO   = "SMO"
+	OrderTypeStopLO   = "SLO"
 	OrderStatusNew    = "NEW"
 	OrderStatusCancle = "CANCELLED"
 	OrderSideBid      = "BUY"
@@ -63,6 +69,7 @@ var (
 var (
 	ErrPendingNonceTooLow = errors.New("pending nonce too low")
 	ErrPoolOverflow       = errors.New("exceed pool size")
+	ErrAmendMismatch      = errors.New("replacement does not amend the pooled order")
 )
 
 // OrderPoolConfig are the configuration parameters of the order transaction pool.
@@ -76,7 +83,7 @@ type OrderPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
-	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+	Lifetime time.Duration // Maximum amount of time non-local transactions are kept in the pool
 }
 
 // blockChain_XDCx add order state
@@ -117,6 +124,26 @@ func (config *OrderPoolConfig) sanitize() OrderPoolConfig {
 		log.Warn("Sanitizing invalid OrderPool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.AccountSlots < 1 {
+		log.Warn("Sanitizing invalid OrderPool account slots", "provided", conf.AccountSlots, "updated", DefaultOrderPoolConfig.AccountSlots)
+		conf.AccountSlots = DefaultOrderPoolConfig.AccountSlots
+	}
+	if conf.GlobalSlots < 1 {
+		log.Warn("Sanitizing invalid OrderPool global slots", "provided", conf.GlobalSlots, "updated", DefaultOrderPoolConfig.GlobalSlots)
+		conf.GlobalSlots = DefaultOrderPoolConfig.GlobalSlots
+	}
+	if conf.AccountQueue < 1 {
+		log.Warn("Sanitizing invalid OrderPool account queue", "provided", conf.AccountQueue, "updated", DefaultOrderPoolConfig.AccountQueue)
+		conf.AccountQueue = DefaultOrderPoolConfig.AccountQueue
+	}
+	if conf.GlobalQueue < 1 {
+		log.Warn("Sanitizing invalid OrderPool global queue", "provided", conf.GlobalQueue, "updated", DefaultOrderPoolConfig.GlobalQueue)
+		conf.GlobalQueue = DefaultOrderPoolConfig.GlobalQueue
+	}
+	if conf.Lifetime < 1 {
+		log.Warn("Sanitizing invalid OrderPool lifetime", "provided", conf.Lifetime, "updated", DefaultOrderPoolConfig.Lifetime)
+		conf.Lifetime = DefaultOrderPoolConfig.Lifetime
+	}
 	return conf
 }
 
@@ -149,6 +176,7 @@ type OrderPool struct {
 	pending   map[common.Address]*ordertxList         // All currently processable transactions
 	queue     map[common.Address]*ordertxList         // Queued but non-processable transactions
 	beats     map[common.Address]time.Time            // Last heartbeat from each known account
+	seen      map[common.Hash]time.Time               // Arrival time of each transaction for lifetime expiry
 	all       map[common.Hash]*types.OrderTransaction // All transactions to allow lookups
 	wg        sync.WaitGroup                          // for shutdown sync
 	homestead bool
@@ -157,9 +185,9 @@ type OrderPool struct {
 
 // NewOrderPool creates a new transaction pool to gather, sort and filter inbound
 // transactions from the network.
-func NewOrderPool(chainconfig *params.ChainConfig, chain blockChainXDCx) *OrderPool {
+func NewOrderPool(config OrderPoolConfig, chainconfig *params.ChainConfig, chain blockChainXDCx) *OrderPool {
 	// Sanitize the input to ensure no vulnerable gas prices are set
-	config := (&DefaultOrderPoolConfig).sanitize()
+	config = (&config).sanitize()
 	log.Debug("NewOrderPool start...", "current block", chain.CurrentBlock().Header().Number)
 	// Create the transaction pool with its initial settings
 	pool := &OrderPool{
@@ -170,6 +198,7 @@ func NewOrderPool(chainconfig *params.ChainConfig, chain blockChainXDCx) *OrderP
 		pending:     make(map[common.Address]*ordertxList),
 		queue:       make(map[common.Address]*ordertxList),
 		beats:       make(map[common.Address]time.Time),
+		seen:        make(map[common.Hash]time.Time),
 		all:         make(map[common.Hash]*types.OrderTransaction),
 		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
 	}
@@ -245,21 +274,10 @@ func (pool *OrderPool) loop() {
 
 			log.Debug("Order pool status report", "executable", pending, "queued", queued)
 
-			// Handle inactive account transaction eviction
+			// Handle expired transaction eviction
 		case <-evict.C:
 			pool.mu.Lock()
-			for addr := range pool.queue {
-				// Skip local transactions from the eviction mechanism
-				if pool.locals.contains(addr) {
-					continue
-				}
-				// Any non-locals old enough should be removed
-				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
-						pool.removeTx(tx.Hash())
-					}
-				}
-			}
+			pool.expire(time.Now())
 			pool.mu.Unlock()
 
 			// Handle local transaction journal rotation
@@ -446,23 +464,42 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 		if quantity == nil || quantity.Sign() <= 0 {
 			return ErrInvalidOrderQuantity
 		}
-		if orderType != OrderTypeMarket {
+		if orderType != OrderTypeMarket && orderType != OrderTypeStopMO {
 			if price == nil || price.Sign() <= 0 {
 				return ErrInvalidOrderPrice
 			}
 		}
+		if tx.IsStopOrder() {
+			if tx.TriggerPrice() == nil || tx.TriggerPrice().Sign() <= 0 {
+				return ErrInvalidOrderTrigger
+			}
+		}
 
 		if orderSide != OrderSideAsk && orderSide != OrderSideBid {
 			return ErrInvalidOrderSide
 		}
-		if orderType != OrderTypeLimit && orderType != OrderTypeMarket {
+		switch orderType {
+		case OrderTypeLimit, OrderTypeMarket:
+		case OrderTypeIOC, OrderTypeFOK, OrderTypePostOnly:
+			// orders with a time in force are accepted from the next block on
+			next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+			if !pool.chainconfig.IsTIPXDCXOrderTypes(next) {
+				return ErrInvalidOrderType
+			}
+		case OrderTypeStopMO, OrderTypeStopLO:
+			// stop orders are accepted from the next block on
+			next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+			if !pool.chainconfig.IsTIPXDCXStopOrders(next) {
+				return ErrInvalidOrderType
+			}
+		default:
 			return ErrInvalidOrderType
 		}
 		if err := tradingstate.VerifyPair(cloneStateDb, tx.ExchangeAddress(), tx.BaseToken(), tx.QuoteToken()); err != nil {
 			return err
 		}
 
-		if orderType == OrderTypeLimit {
+		if orderType != OrderTypeMarket && orderType != OrderTypeStopMO {
 			XDPoSEngine, ok := pool.chain.Engine().(*XDPoS.XDPoS)
 			if !ok {
 				return core.ErrNotXDPoS
@@ -560,14 +597,28 @@ func (pool *OrderPool) validateTx(tx *types.OrderTransaction, local bool) error
 	return nil
 }
 
+// amendsOrder reports whether tx may replace old, the pooled order transaction of
+// the same account and nonce. An amendment may change the price, quantity, type
+// or status of the order, but it has to keep the order ID, exchange, pair and
+// side. As a cancellation carries the ID of an order already in the orderbook, a
+// pooled new order (still without ID) can't be amended into one.
+func amendsOrder(old, tx *types.OrderTransaction) bool {
+	return old.OrderID() == tx.OrderID() &&
+		old.ExchangeAddress() == tx.ExchangeAddress() &&
+		old.BaseToken() == tx.BaseToken() &&
+		old.QuoteToken() == tx.QuoteToken() &&
+		old.Side() == tx.Side()
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
-// later pending promotion and execution. If the transaction is a replacement for
+// later pending promotion and execution. If the transaction is an amendment of
 // an already pending or queued one, it overwrites the previous and returns this
-// so outer code doesn't uselessly call promote.
+// so outer code doesn't uselessly call promote. Any other transaction reusing a
+// pooled nonce is rejected.
 //
 // If a newly added transaction is marked as local, its sending account will be
 // whitelisted, preventing any associated transaction from being dropped out of
-// the pool due to pricing constraints.
+// the pool due to pricing constraints or age.
 func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
@@ -584,8 +635,14 @@ func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error)
 	}
 	from, _ := types.OrderSender(pool.signer, tx) // already validated
 
-	// If the transaction pool is full, discard underpriced transactions
-	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+	// An order already holding the nonce may only be replaced by its amendment
+	old := pool.nonceHolder(from, tx.Nonce())
+	if old != nil && !amendsOrder(old, tx) {
+		log.Debug("Discarding mismatched order replacement", "hash", hash, "nonce", tx.Nonce(), "old", old.Hash())
+		return false, ErrAmendMismatch
+	}
+	// If the transaction pool is full, make room by evicting an order of the sender
+	if old == nil && uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue && !pool.evictQueued(from, tx.Nonce()) {
 		log.Debug("Add order transaction to pool full", "hash", hash, "nonce", tx.Nonce())
 		return false, ErrPoolOverflow
 	}
@@ -601,6 +658,7 @@ func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error)
 			pendingReplaceMeter.Mark(1)
 		}
 		pool.all[tx.Hash()] = tx
+		pool.seen[hash] = time.Now()
 		pool.journalTx(from, tx)
 
 		log.Debug("Pooled new executable transaction", "hash", hash, "useraddress", tx.UserAddress().Hex(), "nonce", tx.Nonce(), "status", tx.Status(), "orderid", tx.OrderID())
@@ -613,6 +671,7 @@ func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error)
 	if err != nil {
 		return false, err
 	}
+	pool.seen[hash] = time.Now()
 	// Mark local addresses and journal local transactions
 	if local {
 		pool.locals.add(from)
@@ -855,6 +914,69 @@ func (pool *OrderPool) removeTx(hash common.Hash) {
 	}
 }
 
+// nonceHolder retrieves the pending or queued transaction of an account holding
+// the given nonce, or nil if the nonce is free.
+func (pool *OrderPool) nonceHolder(addr common.Address, nonce uint64) *types.OrderTransaction {
+	if list := pool.pending[addr]; list != nil {
+		if tx := list.txs.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := pool.queue[addr]; list != nil {
+		return list.txs.Get(nonce)
+	}
+	return nil
+}
+
+// expire drops the non-local transactions which have been in the pool for longer
+// than the configured lifetime, and forgets the arrival time of the ones that
+// left the pool in the mean time.
+func (pool *OrderPool) expire(now time.Time) {
+	for hash, seen := range pool.seen {
+		tx := pool.all[hash]
+		if tx == nil {
+			delete(pool.seen, hash)
+			continue
+		}
+		if now.Sub(seen) <= pool.config.Lifetime || pool.locals.containsTx(tx) {
+			continue
+		}
+		log.Debug("Evicting expired order transaction", "hash", hash, "nonce", tx.Nonce(), "orderid", tx.OrderID())
+		pool.removeTx(hash)
+		delete(pool.seen, hash)
+	}
+}
+
+// evictQueued makes room for a transaction of the given account and nonce by
+// dropping the queued one of its own with the highest nonce, so that a full pool
+// never evicts the orders of other accounts, nor the pending ones the sender's
+// later orders depend on. Nothing is evicted from local accounts, or if the
+// sender queues nothing above the given nonce. It returns whether anything was
+// evicted.
+func (pool *OrderPool) evictQueued(from common.Address, nonce uint64) bool {
+	if pool.locals.contains(from) {
+		return false
+	}
+	list := pool.queue[from]
+	if list == nil {
+		return false
+	}
+	var highest *types.OrderTransaction
+	for _, tx := range list.txs.items {
+		if tx.Nonce() > nonce && (highest == nil || tx.Nonce() > highest.Nonce()) {
+			highest = tx
+		}
+	}
+	if highest == nil {
+		return false
+	}
+	hash := highest.Hash()
+	log.Debug("Evicting queued order transaction", "hash", hash, "from", from, "nonce", highest.Nonce())
+	pool.removeTx(hash)
+	delete(pool.seen, hash)
+	return true
+}
+
 // promoteExecutables moves transactions that have become processable from the
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
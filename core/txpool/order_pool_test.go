@@ -2,6 +2,7 @@ package txpool
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"log"
 	"math/big"
 	"strconv"
@@ -308,3 +309,145 @@ func TestCancelOrder(t *testing.T) {
 	time.Sleep(5 * time.Second)
 	//testSendOrder(t, new(big.Int).SetUint64(48), new(big.Int).SetUint64(15), "SELL", "NEW", 0)
 }
+
+// newTestOrderPool creates an order pool without a backing chain, enough to
+// exercise the queue bookkeeping.
+func newTestOrderPool(config OrderPoolConfig) *OrderPool {
+	pool := &OrderPool{
+		config:  config,
+		signer:  types.OrderTxSigner{},
+		pending: make(map[common.Address]*ordertxList),
+		queue:   make(map[common.Address]*ordertxList),
+		beats:   make(map[common.Address]time.Time),
+		seen:    make(map[common.Hash]time.Time),
+		all:     make(map[common.Hash]*types.OrderTransaction),
+	}
+	pool.locals = newOrderAccountSet(pool.signer)
+	return pool
+}
+
+func signedOrder(t *testing.T, nonce uint64, price int64, side, status string, orderID uint64) *types.OrderTransaction {
+	key, _ := crypto.HexToECDSA("65ec4d4dfbcac594a14c36baa462d6f73cd86134840f6cf7b80a1e1cd33473e2")
+	return signedOrderBy(t, key, nonce, price, side, status, orderID)
+}
+
+func signedOrderBy(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, price int64, side, status string, orderID uint64) *types.OrderTransaction {
+	tx := types.NewOrderTransaction(nonce, big.NewInt(1), big.NewInt(price), common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e"), crypto.PubkeyToAddress(key.PublicKey), common.XDCNativeAddressBinary, BTCAddress, status, side, OrderTypeLimit, common.Hash{}, orderID)
+	signed, err := types.OrderSignTx(tx, types.OrderTxSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign order: %v", err)
+	}
+	return signed
+}
+
+func TestOrderAmendment(t *testing.T) {
+	old := signedOrder(t, 5, 100, OrderSideBid, OrderStatusNew, 7)
+
+	tests := []struct {
+		tx   *types.OrderTransaction
+		want bool
+	}{
+		{signedOrder(t, 5, 120, OrderSideBid, OrderStatusNew, 7), true},     // price amendment
+		{signedOrder(t, 5, 100, OrderSideBid, OrderStatusCancle, 7), true},  // cancellation
+		{signedOrder(t, 5, 100, OrderSideBid, OrderStatusNew, 8), false},    // other order
+		{signedOrder(t, 5, 100, OrderSideAsk, OrderStatusNew, 7), false},    // flipped side
+		{signedOrder(t, 5, 100, OrderSideBid, OrderStatusCancle, 0), false}, // lost order ID
+	}
+	for i, tt := range tests {
+		if have := amendsOrder(old, tt.tx); have != tt.want {
+			t.Errorf("test %d: amendment mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// A pooled new order has no ID yet, so no cancellation can amend it
+	fresh := signedOrder(t, 5, 100, OrderSideBid, OrderStatusNew, 0)
+	if amendsOrder(fresh, signedOrder(t, 5, 100, OrderSideBid, OrderStatusCancle, 7)) {
+		t.Errorf("cancellation amended a new order")
+	}
+}
+
+func TestOrderPoolExpiry(t *testing.T) {
+	pool := newTestOrderPool(DefaultOrderPoolConfig)
+
+	var (
+		now   = time.Now()
+		stale = signedOrder(t, 3, 100, OrderSideBid, OrderStatusNew, 0)
+		fresh = signedOrder(t, 4, 100, OrderSideBid, OrderStatusNew, 0)
+	)
+	for _, tx := range []*types.OrderTransaction{stale, fresh} {
+		if _, err := pool.enqueueTx(tx.Hash(), tx); err != nil {
+			t.Fatalf("failed to enqueue order: %v", err)
+		}
+	}
+	pool.seen[stale.Hash()] = now.Add(-pool.config.Lifetime - time.Second)
+	pool.seen[fresh.Hash()] = now
+
+	pool.expire(now)
+	if pool.all[stale.Hash()] != nil || pool.seen[stale.Hash()] != (time.Time{}) {
+		t.Errorf("stale order not expired")
+	}
+	if pool.all[fresh.Hash()] == nil {
+		t.Errorf("fresh order expired")
+	}
+	// Local orders are exempt from the lifetime
+	from, _ := types.OrderSender(pool.signer, fresh)
+	pool.locals.add(from)
+	pool.seen[fresh.Hash()] = now.Add(-pool.config.Lifetime - time.Second)
+	pool.expire(now)
+	if pool.all[fresh.Hash()] == nil {
+		t.Errorf("local order expired")
+	}
+}
+
+func TestOrderPoolEvictQueued(t *testing.T) {
+	pool := newTestOrderPool(DefaultOrderPoolConfig)
+
+	other, _ := crypto.GenerateKey()
+	txs := []*types.OrderTransaction{
+		signedOrder(t, 3, 100, OrderSideBid, OrderStatusNew, 0),
+		signedOrder(t, 5, 100, OrderSideBid, OrderStatusNew, 0),
+		signedOrder(t, 6, 100, OrderSideBid, OrderStatusNew, 0),
+		signedOrderBy(t, other, 1, 100, OrderSideBid, OrderStatusNew, 0),
+	}
+	from, _ := types.OrderSender(pool.signer, txs[0])
+
+	// The lowest nonce is pending and, however old, has to survive any eviction
+	pool.pending[from] = newOrderTxList(true)
+	pool.pending[from].Add(txs[0])
+	pool.all[txs[0].Hash()] = txs[0]
+	pool.seen[txs[0].Hash()] = time.Now().Add(-time.Hour)
+	for _, tx := range txs[1:] {
+		pool.enqueueTx(tx.Hash(), tx)
+		pool.seen[tx.Hash()] = time.Now()
+	}
+	if !pool.evictQueued(from, 4) {
+		t.Fatalf("nothing evicted")
+	}
+	if pool.all[txs[2].Hash()] != nil {
+		t.Errorf("highest queued order not evicted")
+	}
+	if pool.all[txs[0].Hash()] == nil || pool.pending[from].Len() != 1 {
+		t.Errorf("pending order evicted")
+	}
+	if len(pool.all) != 3 {
+		t.Errorf("pool size mismatch: have %d, want 3", len(pool.all))
+	}
+	// Nothing queued above the incoming nonce leaves no room for it
+	if pool.evictQueued(from, 5) {
+		t.Errorf("order evicted for a higher nonce")
+	}
+	// An account only makes room by evicting its own orders
+	if !pool.evictQueued(crypto.PubkeyToAddress(other.PublicKey), 0) {
+		t.Fatalf("nothing evicted")
+	}
+	if pool.all[txs[3].Hash()] != nil || pool.all[txs[1].Hash()] == nil {
+		t.Errorf("order of another account evicted")
+	}
+	if pool.evictQueued(crypto.PubkeyToAddress(other.PublicKey), 0) {
+		t.Errorf("order evicted for an account without any")
+	}
+	// Nothing is evicted from local accounts
+	pool.locals.add(from)
+	if pool.evictQueued(from, 4) {
+		t.Errorf("local order evicted")
+	}
+}
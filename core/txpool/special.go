@@ -0,0 +1,92 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/log"
+)
+
+// isSpecialLaneTx reports whether a transaction belongs to the special lane:
+// a block signing or randomization transaction sent by a current signer.
+func (pool *TxPool) isSpecialLaneTx(from common.Address, tx *types.Transaction) bool {
+	return tx.IsSpecialTransaction() && pool.IsSigner != nil && pool.IsSigner(from)
+}
+
+// checkSpecialLane ensures the special lane has room for one more transaction
+// of the given signer. The local signers are only bound by the global limit.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) checkSpecialLane(from common.Address, local bool) error {
+	total, account := pool.all.SpecialCount(from)
+	if uint64(total) >= pool.config.SpecialSlots {
+		return ErrSpecialLaneFull
+	}
+	if !local && uint64(account) >= pool.config.SpecialAccountSlots {
+		return ErrSpecialLaneFull
+	}
+	return nil
+}
+
+// evictStaleSpecials drops the signing transactions of the blocks which are too
+// old to be included by the miner anymore, handing their nonces back to the
+// signers for newer signatures.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) evictStaleSpecials(head *types.Header) {
+	if head == nil || pool.chainconfig.XDPoS == nil {
+		return
+	}
+	window := new(big.Int).SetUint64(pool.chainconfig.XDPoS.Epoch * 2)
+	oldest := new(big.Int).Add(head.Number, common.Big1)
+	if oldest.Sub(oldest, window).Sign() <= 0 {
+		return
+	}
+	var stale []common.Hash
+	pool.all.RangeSpecial(func(hash common.Hash, tx *types.Transaction) bool {
+		if tx.IsSigningTransaction() && new(big.Int).SetBytes(tx.Data()[4:36]).Cmp(oldest) <= 0 {
+			stale = append(stale, hash)
+		}
+		return true
+	})
+	for _, hash := range stale {
+		log.Trace("Evicting stale special transaction", "hash", hash)
+		pool.removeTx(hash, false)
+	}
+	specialEvictionMeter.Mark(int64(len(stale)))
+}
+
+// SpecialContent retrieves the transactions of the special lane, grouped by
+// signer and sorted by nonce.
+func (pool *TxPool) SpecialContent() map[common.Address]types.Transactions {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	content := make(map[common.Address]types.Transactions)
+	pool.all.RangeSpecial(func(hash common.Hash, tx *types.Transaction) bool {
+		from, _ := types.Sender(pool.signer, tx) // already validated
+		content[from] = append(content[from], tx)
+		return true
+	})
+	for _, txs := range content {
+		sort.Sort(types.TxByNonce(txs))
+	}
+	return content
+}
@@ -95,6 +95,10 @@ var (
 
 	ErrDuplicateSpecialTransaction = errors.New("duplicate a special transaction")
 
+	// ErrSpecialLaneFull is returned if a signer's special transaction exceeds the
+	// limits of the special transaction lane.
+	ErrSpecialLaneFull = errors.New("special transaction lane is full")
+
 	ErrMinDeploySMC = errors.New("smart contract creation cost is under allowance")
 )
 
@@ -139,6 +143,11 @@ var (
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
 
 	reheapTimer = metrics.NewRegisteredTimer("txpool/reheap", nil)
+
+	// Metrics for the special transaction lane
+	specialGauge         = metrics.NewRegisteredGauge("txpool/special", nil)
+	specialOverflowMeter = metrics.NewRegisteredMeter("txpool/special/overflow", nil) // Rejected due to the lane limits
+	specialEvictionMeter = metrics.NewRegisteredMeter("txpool/special/eviction", nil) // Dropped due to signing a stale block
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -188,6 +197,9 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	SpecialAccountSlots uint64 // Maximum number of special transactions permitted per signer
+	SpecialSlots        uint64 // Maximum number of special transactions for all signers
 }
 
 // DefaultConfig contains the default configurations for the transaction
@@ -205,6 +217,9 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	SpecialAccountSlots: 32,
+	SpecialSlots:        4096,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -243,6 +258,14 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.SpecialAccountSlots < 1 {
+		log.Warn("Sanitizing invalid txpool special account slots", "provided", conf.SpecialAccountSlots, "updated", DefaultConfig.SpecialAccountSlots)
+		conf.SpecialAccountSlots = DefaultConfig.SpecialAccountSlots
+	}
+	if conf.SpecialSlots < 1 {
+		log.Warn("Sanitizing invalid txpool special slots", "provided", conf.SpecialSlots, "updated", DefaultConfig.SpecialSlots)
+		conf.SpecialSlots = DefaultConfig.SpecialSlots
+	}
 	return conf
 }
 
@@ -747,6 +770,13 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		copyState := pool.currentState.Copy()
 		return core.ValidateXDCXApplyTransaction(pool.chain, nil, copyState, common.BytesToAddress(tx.Data()[4:]))
 	}
+
+	// verify the forensic proof of slashing transactions
+	if tx.IsSlashingTransaction() {
+		if chain, ok := pool.chain.(core.SlashingChain); ok {
+			return core.ValidateSlashingTransaction(chain, nil, pool.currentState, tx)
+		}
+	}
 	return nil
 }
 
@@ -776,11 +806,22 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		return false, err
 	}
 	from, _ := types.Sender(pool.signer, tx) // already validated
-	if tx.IsSpecialTransaction() && pool.IsSigner != nil && pool.IsSigner(from) && pool.pendingNonces.get(from) == tx.Nonce() {
-		return pool.promoteSpecialTx(from, tx, isLocal)
+
+	// The special transactions of the signers go into a lane of their own, with
+	// its own limits and apart from the slot accounting of the other ones
+	special := pool.isSpecialLaneTx(from, tx)
+	if special {
+		if err := pool.checkSpecialLane(from, isLocal); err != nil {
+			log.Trace("Discarding overflown special transaction", "hash", hash, "from", from)
+			specialOverflowMeter.Mark(1)
+			return false, err
+		}
+		if pool.pendingNonces.get(from) == tx.Nonce() {
+			return pool.promoteSpecialTx(from, tx, isLocal)
+		}
 	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
+	if !special && uint64(pool.all.Slots()-pool.all.SpecialSlots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
 		if !isLocal && pool.priced.Underpriced(tx) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
@@ -799,7 +840,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		// New transaction is better than our worse ones, make room for it.
 		// If it's a local transaction, forcibly discard all available transactions.
 		// Otherwise if we can't make enough room for new one, abort the operation.
-		drop, success := pool.priced.Discard(pool.all.Slots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
+		drop, success := pool.priced.Discard(pool.all.Slots()-pool.all.SpecialSlots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
 
 		// Special case, we still can't make the room for the new remote one.
 		if !isLocal && !success {
@@ -831,7 +872,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pendingReplaceMeter.Mark(1)
 		}
 		pool.all.Add(tx, isLocal)
-		pool.priced.Put(tx, isLocal)
+		if special {
+			pool.all.MarkSpecial(hash, from)
+		} else {
+			pool.priced.Put(tx, isLocal)
+		}
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -845,6 +890,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	if err != nil {
 		return false, err
 	}
+	if special {
+		// Keep the lane out of the price based eviction
+		pool.all.MarkSpecial(hash, from)
+		pool.priced.Removed(1)
+	}
 	// Mark local addresses and journal local transactions
 	if local && !pool.locals.contains(from) {
 		log.Info("Setting new local account", "address", from)
@@ -980,6 +1030,7 @@ func (pool *TxPool) promoteSpecialTx(addr common.Address, tx *types.Transaction,
 	if pool.all.Get(tx.Hash()) == nil {
 		pool.all.Add(tx, isLocal)
 	}
+	pool.all.MarkSpecial(tx.Hash(), addr)
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.beats[addr] = time.Now()
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
@@ -1325,6 +1376,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
 		pool.demoteUnexecutables()
+		pool.evictStaleSpecials(reset.newHead)
 		if reset.newHead != nil && pool.chainconfig.IsEIP1559(new(big.Int).Add(reset.newHead.Number, big.NewInt(1))) {
 			pendingBaseFee := eip1559.CalcBaseFee(pool.chainconfig, reset.newHead)
 			pool.priced.SetBaseFee(pendingBaseFee)
@@ -1532,9 +1584,14 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 // pending limit. The algorithm tries to reduce transaction counts by an approximately
 // equal number for all for accounts with many pending transactions.
 func (pool *TxPool) truncatePending() {
+	// The special transactions are limited by their own lane
+	ordinary := func(addr common.Address, txs *list) int {
+		_, specials := pool.all.SpecialCount(addr)
+		return txs.Len() - specials
+	}
 	pending := uint64(0)
-	for _, list := range pool.pending {
-		pending += uint64(list.Len())
+	for addr, list := range pool.pending {
+		pending += uint64(ordinary(addr, list))
 	}
 	if pending <= pool.config.GlobalSlots {
 		return
@@ -1545,8 +1602,8 @@ func (pool *TxPool) truncatePending() {
 	spammers := prque.New[int64, common.Address](nil)
 	for addr, list := range pool.pending {
 		// Only evict transactions from high rollers
-		if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
-			spammers.Push(addr, int64(list.Len()))
+		if count := ordinary(addr, list); !pool.locals.contains(addr) && uint64(count) > pool.config.AccountSlots {
+			spammers.Push(addr, int64(count))
 		}
 	}
 	// Gradually drop transactions from offenders
@@ -1826,13 +1883,21 @@ type lookup struct {
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
+
+	special        map[common.Hash]*types.Transaction // Transactions of the special lane, neither local nor remote
+	specialSenders map[common.Hash]common.Address     // Signers of the special lane transactions
+	specialCounts  map[common.Address]int             // Number of special lane transactions of each signer
+	specialSlots   int
 }
 
 // newLookup returns a new lookup structure.
 func newLookup() *lookup {
 	return &lookup{
-		locals:  make(map[common.Hash]*types.Transaction),
-		remotes: make(map[common.Hash]*types.Transaction),
+		locals:         make(map[common.Hash]*types.Transaction),
+		remotes:        make(map[common.Hash]*types.Transaction),
+		special:        make(map[common.Hash]*types.Transaction),
+		specialSenders: make(map[common.Hash]common.Address),
+		specialCounts:  make(map[common.Address]int),
 	}
 }
 
@@ -1867,7 +1932,10 @@ func (t *lookup) Get(hash common.Hash) *types.Transaction {
 	if tx := t.locals[hash]; tx != nil {
 		return tx
 	}
-	return t.remotes[hash]
+	if tx := t.remotes[hash]; tx != nil {
+		return tx
+	}
+	return t.special[hash]
 }
 
 // GetLocal returns a transaction if it exists in the lookup, or nil if not found.
@@ -1891,7 +1959,7 @@ func (t *lookup) Count() int {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
-	return len(t.locals) + len(t.remotes)
+	return len(t.locals) + len(t.remotes) + len(t.special)
 }
 
 // LocalCount returns the current number of local transactions in the lookup.
@@ -1942,6 +2010,18 @@ func (t *lookup) Remove(hash common.Hash) {
 	if !ok {
 		tx, ok = t.remotes[hash]
 	}
+	if !ok {
+		if tx, ok = t.special[hash]; ok {
+			from := t.specialSenders[hash]
+			if t.specialCounts[from]--; t.specialCounts[from] == 0 {
+				delete(t.specialCounts, from)
+			}
+			t.specialSlots -= numSlots(tx)
+			delete(t.special, hash)
+			delete(t.specialSenders, hash)
+			specialGauge.Update(int64(len(t.special)))
+		}
+	}
 	if !ok {
 		log.Error("No transaction found to be deleted", "hash", hash)
 		return
@@ -1953,6 +2033,59 @@ func (t *lookup) Remove(hash common.Hash) {
 	delete(t.remotes, hash)
 }
 
+// MarkSpecial moves a transaction of the given signer into the special lane, out
+// of the local and remote sets and so out of reach of the price based eviction.
+func (t *lookup) MarkSpecial(hash common.Hash, from common.Address) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tx, ok := t.locals[hash]
+	if !ok {
+		tx, ok = t.remotes[hash]
+	}
+	if !ok {
+		return
+	}
+	delete(t.locals, hash)
+	delete(t.remotes, hash)
+
+	t.special[hash] = tx
+	t.specialSenders[hash] = from
+	t.specialCounts[from]++
+	t.specialSlots += numSlots(tx)
+	specialGauge.Update(int64(len(t.special)))
+}
+
+// RangeSpecial calls f on each transaction of the special lane. The callback
+// passed should return the indicator whether the iteration needs to be continued.
+func (t *lookup) RangeSpecial(f func(hash common.Hash, tx *types.Transaction) bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for key, value := range t.special {
+		if !f(key, value) {
+			return
+		}
+	}
+}
+
+// SpecialCount returns the number of transactions of the special lane, in total
+// and of the given signer.
+func (t *lookup) SpecialCount(from common.Address) (total int, account int) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return len(t.special), t.specialCounts[from]
+}
+
+// SpecialSlots returns the current number of slots used by the special lane.
+func (t *lookup) SpecialSlots() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.specialSlots
+}
+
 // RemoteToLocals migrates the transactions belongs to the given locals to locals
 // set. The assumption is held the locals set is thread-safe to be used.
 func (t *lookup) RemoteToLocals(locals *accountSet) int {
@@ -2599,3 +2599,146 @@ func BenchmarkMultiAccountBatchInsert(b *testing.B) {
 		pool.AddRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// signingTransaction creates a block signing transaction for the given block.
+func signingTransaction(nonce uint64, number uint64, key *ecdsa.PrivateKey) *types.Transaction {
+	data := common.Hex2Bytes(common.HexSignMethod)
+	data = append(data, common.LeftPadBytes(new(big.Int).SetUint64(number).Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(common.Hash{}.Bytes(), 32)...)
+	tx, _ := types.SignTx(types.NewTransaction(nonce, common.BlockSignersBinary, big.NewInt(0), 200000, big.NewInt(0), data), types.HomesteadSigner{}, key)
+	return tx
+}
+
+// setupSpecialPool creates a pool whose special lane is bounded by the given
+// limits, treating every sender as a signer.
+func setupSpecialPool(chainConfig *params.ChainConfig, accountSlots, slots uint64) *TxPool {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.SpecialAccountSlots = accountSlots
+	config.SpecialSlots = slots
+
+	pool := NewTxPool(config, chainConfig, blockchain)
+	pool.IsSigner = func(common.Address) bool { return true }
+	<-pool.initDoneCh
+	return pool
+}
+
+// Tests that the special transactions of the signers are bounded by the limits
+// of their own lane.
+func TestSpecialLaneLimits(t *testing.T) {
+	t.Parallel()
+
+	pool := setupSpecialPool(params.TestChainConfig, 2, 3)
+	defer pool.Stop()
+
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+
+	// The per signer limit applies to each signer on its own
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.addRemoteSync(signingTransaction(i, 1, key1)); err != nil {
+			t.Fatalf("failed to add special transaction %d: %v", i, err)
+		}
+	}
+	if err := pool.addRemoteSync(signingTransaction(2, 1, key1)); err != ErrSpecialLaneFull {
+		t.Fatalf("account overflow error mismatch: have %v, want %v", err, ErrSpecialLaneFull)
+	}
+	if err := pool.addRemoteSync(signingTransaction(0, 1, key2)); err != nil {
+		t.Fatalf("failed to add special transaction of second signer: %v", err)
+	}
+	// The global limit applies to all signers together
+	if err := pool.addRemoteSync(signingTransaction(1, 1, key2)); err != ErrSpecialLaneFull {
+		t.Fatalf("global overflow error mismatch: have %v, want %v", err, ErrSpecialLaneFull)
+	}
+	content := pool.SpecialContent()
+	if len(content) != 2 {
+		t.Fatalf("special content signers mismatch: have %d, want 2", len(content))
+	}
+	if txs := content[crypto.PubkeyToAddress(key1.PublicKey)]; len(txs) != 2 || txs[0].Nonce() != 0 || txs[1].Nonce() != 1 {
+		t.Errorf("special content of first signer mismatch: %v", txs)
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Errorf("pending transactions mismatch: have %d, want 3", pending)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the special lane stays out of the slot accounting of the ordinary
+// transactions, so a full pool neither rejects nor evicts signing transactions.
+func TestSpecialLaneSlotAccounting(t *testing.T) {
+	t.Parallel()
+
+	pool := setupSpecialPool(params.TestChainConfig, 4, 4)
+	pool.config.GlobalSlots = 2
+	pool.config.GlobalQueue = 0
+	pool.config.AccountSlots = 2
+	defer pool.Stop()
+
+	signer, _ := crypto.GenerateKey()
+	spammer, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(spammer.PublicKey), big.NewInt(params.Ether))
+	price := common.GetMinGasPrice(nil)
+
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.addRemoteSync(signingTransaction(i, 1, signer)); err != nil {
+			t.Fatalf("failed to add special transaction %d: %v", i, err)
+		}
+	}
+	if slots := pool.all.SpecialSlots(); slots != 2 {
+		t.Fatalf("special slots mismatch: have %d, want 2", slots)
+	}
+	// The ordinary transactions still have all the configured slots
+	pool.IsSigner = func(addr common.Address) bool { return addr != crypto.PubkeyToAddress(spammer.PublicKey) }
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.addRemoteSync(pricedTransaction(i, 100000, new(big.Int).Add(price, big.NewInt(int64(i+5))), spammer)); err != nil {
+			t.Fatalf("failed to add ordinary transaction %d: %v", i, err)
+		}
+	}
+	// The pool is full of ordinary transactions, despite the special ones
+	if err := pool.addRemoteSync(pricedTransaction(2, 100000, price, spammer)); err != ErrUnderpriced {
+		t.Fatalf("overflow error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if content := pool.SpecialContent(); len(content[crypto.PubkeyToAddress(signer.PublicKey)]) != 2 {
+		t.Errorf("special transactions evicted: %v", content)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the signing transactions of blocks too old to be mined are evicted
+// from the special lane, handing their nonces back to the signer.
+func TestSpecialLaneStaleEviction(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.XDPoS = &params.XDPoSConfig{Epoch: 10}
+
+	pool := setupSpecialPool(&config, 8, 8)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	for i, number := range []uint64{80, 85, 95} {
+		if err := pool.addRemoteSync(signingTransaction(uint64(i), number, key)); err != nil {
+			t.Fatalf("failed to add special transaction %d: %v", i, err)
+		}
+	}
+	// At head 100 the miner only takes the signatures of blocks above 81
+	pool.mu.Lock()
+	pool.evictStaleSpecials(&types.Header{Number: big.NewInt(100)})
+	pool.mu.Unlock()
+
+	if slots := pool.all.SpecialSlots(); slots != 2 {
+		t.Fatalf("special slots mismatch: have %d, want 2", slots)
+	}
+	if nonce := pool.Nonce(crypto.PubkeyToAddress(key.PublicKey)); nonce != 0 {
+		t.Errorf("signer nonce mismatch: have %d, want 0", nonce)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 2 {
+		t.Errorf("pool stats mismatch: have %d pending and %d queued, want 0 and 2", pending, queued)
+	}
+}
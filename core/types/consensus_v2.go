@@ -119,6 +119,14 @@ type TimeoutCert struct {
 type ExtraFields_v2 struct {
 	Round      Round
 	QuorumCert *QuorumCert
+	Roles      *EpochRoles `rlp:"optional"` // Only set in epoch switch blocks since TIPEpochRoles
+}
+
+// The protector and observer nodes of an epoch, selected from the candidates
+// left over after the masternodes and penalties at the epoch switch
+type EpochRoles struct {
+	Protectors []common.Address
+	Observers  []common.Address
 }
 
 // Encode XDPoS 2.0 extra fields into bytes
@@ -136,6 +144,8 @@ type EpochSwitchInfo struct {
 	Standbynodes               []common.Address
 	Masternodes                []common.Address
 	MasternodesLen             int
+	Protectors                 []common.Address
+	Observers                  []common.Address
 	EpochSwitchBlockInfo       *BlockInfo
 	EpochSwitchParentBlockInfo *BlockInfo
 }
@@ -14,6 +14,7 @@ type ForensicsContent struct {
 	AcrossEpoch          bool           `json:"acrossEpoch"`
 	SmallerRoundInfo     *ForensicsInfo `json:"smallerRoundInfo"`
 	LargerRoundInfo      *ForensicsInfo `json:"largerRoundInfo"`
+	CommittedQCs         []QuorumCert   `json:"committedQCs,omitempty"` // QCs of three consecutive rounds committing the block of the first one
 }
 
 type VoteEquivocationContent struct {
@@ -31,3 +32,11 @@ type ForensicProof struct {
 type ForensicsEvent struct {
 	ForensicsProof *ForensicProof
 }
+
+// ForensicsReport is a forensic proof as persisted by the node, indexed by the
+// lowest round involved and by the signers it blames.
+type ForensicsReport struct {
+	Round   Round            `json:"round"`
+	Signers []common.Address `json:"signers"`
+	Proof   *ForensicProof   `json:"proof"`
+}
@@ -114,11 +114,14 @@ func (ordersign OrderTxSigner) OrderCreateHash(tx *OrderTransaction) common.Hash
 	sha.Write(tx.BaseToken().Bytes())
 	sha.Write(tx.QuoteToken().Bytes())
 	sha.Write(common.BigToHash(tx.Quantity()).Bytes())
-	if tx.IsLoTypeOrder() {
+	if tx.IsPricedOrder() {
 		if tx.Price() != nil {
 			sha.Write(common.BigToHash(tx.Price()).Bytes())
 		}
 	}
+	if tx.IsStopOrder() && tx.TriggerPrice() != nil {
+		sha.Write(common.BigToHash(tx.TriggerPrice()).Bytes())
+	}
 	sha.Write(common.BigToHash(tx.EncodedSide()).Bytes())
 	sha.Write([]byte(tx.Status()))
 	sha.Write([]byte(tx.Type()))
@@ -41,6 +41,11 @@ const (
 	OrderStatusCancelled     = "CANCELLED"
 	OrderTypeMo              = "MO"
 	OrderTypeLo              = "LO"
+	OrderTypeIoc             = "IOC" // Immediate or cancel
+	OrderTypeFok             = "FOK" // Fill or kill
+	OrderTypePo              = "PO"  // Post only
+	OrderTypeSmo             = "SMO" // Stop market
+	OrderTypeSlo             = "SLO" // Stop limit
 )
 
 // OrderTransaction order transaction
@@ -71,6 +76,9 @@ type ordertxdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash common.Hash `json:"hash"`
+
+	// Price at which a stop order is activated
+	TriggerPrice *big.Int `json:"triggerPrice,omitempty" rlp:"optional"`
 }
 
 // IsCancelledOrder check if tx is cancelled transaction
@@ -88,6 +96,20 @@ func (tx *OrderTransaction) IsLoTypeOrder() bool {
 	return tx.Type() == OrderTypeLo
 }
 
+// IsPricedOrder check if tx type is an order with a limit price: LO, IOC, FOK or PO
+func (tx *OrderTransaction) IsPricedOrder() bool {
+	switch tx.Type() {
+	case OrderTypeLo, OrderTypeIoc, OrderTypeFok, OrderTypePo, OrderTypeSlo:
+		return true
+	}
+	return false
+}
+
+// IsStopOrder check if tx type is an order waiting for a trigger price: SMO or SLO
+func (tx *OrderTransaction) IsStopOrder() bool {
+	return tx.Type() == OrderTypeSmo || tx.Type() == OrderTypeSlo
+}
+
 // EncodeRLP implements rlp.Encoder
 func (tx *OrderTransaction) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, &tx.data)
@@ -118,6 +140,7 @@ func (tx *OrderTransaction) Type() string                    { return tx.data.Ty
 func (tx *OrderTransaction) Signature() (V, R, S *big.Int)   { return tx.data.V, tx.data.R, tx.data.S }
 func (tx *OrderTransaction) OrderHash() common.Hash          { return tx.data.Hash }
 func (tx *OrderTransaction) OrderID() uint64                 { return tx.data.OrderID }
+func (tx *OrderTransaction) TriggerPrice() *big.Int          { return tx.data.TriggerPrice }
 func (tx *OrderTransaction) EncodedSide() *big.Int {
 	if tx.Side() == "BUY" {
 		return big.NewInt(0)
@@ -127,6 +150,15 @@ func (tx *OrderTransaction) EncodedSide() *big.Int {
 }
 func (tx *OrderTransaction) SetOrderHash(h common.Hash) { tx.data.Hash = h }
 
+// SetTriggerPrice sets the activation price of a stop order
+func (tx *OrderTransaction) SetTriggerPrice(price *big.Int) {
+	if price == nil || price.Sign() == 0 {
+		tx.data.TriggerPrice = nil
+		return
+	}
+	tx.data.TriggerPrice = new(big.Int).Set(price)
+}
+
 // From get transaction from
 func (tx *OrderTransaction) From() *common.Address {
 	if tx.data.V != nil {
@@ -505,6 +505,11 @@ func (tx *Transaction) IsLendingFinalizedTradeTransaction() bool {
 	return to != nil && *to == common.XDCXLendingFinalizedTradeAddressBinary
 }
 
+func (tx *Transaction) IsSlashingTransaction() bool {
+	to := tx.To()
+	return to != nil && *to == common.SlashingAddrBinary
+}
+
 func (tx *Transaction) IsSkipNonceTransaction() bool {
 	to := tx.To()
 	return to != nil && skipNonceDestinationAddress[*to]
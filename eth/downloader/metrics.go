@@ -41,5 +41,8 @@ var (
 	stateInMeter   = metrics.NewRegisteredMeter("eth/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("eth/downloader/states/drop", nil)
 
+	rangeInMeter   = metrics.NewRegisteredMeter("eth/downloader/ranges/in", nil)
+	rangeDropMeter = metrics.NewRegisteredMeter("eth/downloader/ranges/drop", nil)
+
 	throttleCounter = metrics.NewRegisteredCounter("eth/downloader/throttle", nil)
 )
@@ -90,6 +90,14 @@ type Peer interface {
 	RequestNodeData([]common.Hash) error
 }
 
+// RangePeer encapsulates the methods required to retrieve the state by trie
+// ranges from a remote peer speaking xdsnap/1.
+type RangePeer interface {
+	RequestAccountRange(root common.Hash, origin common.Hash, limit common.Hash, bytes uint64) error
+	RequestStorageRanges(space TrieSpace, roots []common.Hash, origin common.Hash, bytes uint64) error
+	RequestByteCodes(hashes []common.Hash, bytes uint64) error
+}
+
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
 type lightPeerWrapper struct {
 	peer LightPeer
@@ -224,6 +232,58 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// FetchAccountRange sends an account range retrieval request to the remote peer.
+func (p *peerConnection) FetchAccountRange(root common.Hash, origin common.Hash, limit common.Hash, bytes uint64) error {
+	peer, ok := p.peer.(RangePeer)
+	if !ok {
+		panic(fmt.Sprintf("account range fetch [xdsnap/1] requested on eth/%d", p.version))
+	}
+	// Short circuit if the peer is already fetching
+	if !atomic.CompareAndSwapInt32(&p.stateIdle, 0, 1) {
+		return errAlreadyFetching
+	}
+	p.stateStarted = time.Now()
+
+	go peer.RequestAccountRange(root, origin, limit, bytes)
+
+	return nil
+}
+
+// FetchStorageRanges sends a storage or XDCx trie ranges retrieval request to
+// the remote peer.
+func (p *peerConnection) FetchStorageRanges(space TrieSpace, roots []common.Hash, origin common.Hash, bytes uint64) error {
+	peer, ok := p.peer.(RangePeer)
+	if !ok {
+		panic(fmt.Sprintf("storage ranges fetch [xdsnap/1] requested on eth/%d", p.version))
+	}
+	// Short circuit if the peer is already fetching
+	if !atomic.CompareAndSwapInt32(&p.stateIdle, 0, 1) {
+		return errAlreadyFetching
+	}
+	p.stateStarted = time.Now()
+
+	go peer.RequestStorageRanges(space, roots, origin, bytes)
+
+	return nil
+}
+
+// FetchByteCodes sends a contract code retrieval request to the remote peer.
+func (p *peerConnection) FetchByteCodes(hashes []common.Hash, bytes uint64) error {
+	peer, ok := p.peer.(RangePeer)
+	if !ok {
+		panic(fmt.Sprintf("byte code fetch [xdsnap/1] requested on eth/%d", p.version))
+	}
+	// Short circuit if the peer is already fetching
+	if !atomic.CompareAndSwapInt32(&p.stateIdle, 0, 1) {
+		return errAlreadyFetching
+	}
+	p.stateStarted = time.Now()
+
+	go peer.RequestByteCodes(hashes, bytes)
+
+	return nil
+}
+
 // SetHeadersIdle sets the peer to idle, allowing it to execute new header retrieval
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
@@ -505,6 +565,21 @@ func (ps *peerSet) ReceiptIdlePeers() ([]*peerConnection, int) {
 	return ps.idlePeers(63, 101, idle, throughput)
 }
 
+// RangeIdlePeers retrieves a flat list of all the currently state-idle peers
+// able to serve trie ranges, ordered by their reputation.
+func (ps *peerSet) RangeIdlePeers() ([]*peerConnection, int) {
+	idle := func(p *peerConnection) bool {
+		_, ok := p.peer.(RangePeer)
+		return ok && atomic.LoadInt32(&p.stateIdle) == 0
+	}
+	throughput := func(p *peerConnection) float64 {
+		p.lock.RLock()
+		defer p.lock.RUnlock()
+		return p.stateThroughput
+	}
+	return ps.idlePeers(101, 101, idle, throughput)
+}
+
 // NodeDataIdlePeers retrieves a flat list of all the currently node-data-idle
 // peers within the active peer set, ordered by their reputation.
 func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
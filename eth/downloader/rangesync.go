@@ -0,0 +1,680 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/XDCxlending/lendingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/ethdb/memorydb"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+var (
+	accountRangeChunks = 16         // Number of chunks of the account trie retrieved concurrently
+	MaxRangeBytes      = 512 * 1024 // Soft limit on the size of trie range and code responses
+	MaxRangeTries      = 128        // Maximum number of tries to request the ranges of at once
+	MaxCodeFetch       = 64         // Maximum number of contract codes to request at once
+	rangeLogInterval   = 8 * time.Second
+)
+
+var (
+	errRangeUnavailable = errors.New("trie range unavailable")
+	errInvalidRange     = errors.New("invalid trie range")
+)
+
+// TrieSpace identifies the database the tries of a range request are served from.
+type TrieSpace uint8
+
+const (
+	StateSpace TrieSpace = iota // Account and storage tries of the chain state
+	XDCxSpace                   // Trading and lending state tries of XDCx
+)
+
+// XDCxRootsFn retrieves the roots of the XDCx trading and lending states
+// committed by a block.
+type XDCxRootsFn func(block *types.Block) (trading common.Hash, lending common.Hash)
+
+// SetXDCxState sets the database the XDCx trading and lending states are
+// retrieved into during snap sync, along with the method finding their roots.
+func (d *Downloader) SetXDCxState(db ethdb.Database, roots XDCxRootsFn) {
+	d.xdcxDB = db
+	d.xdcxRoots = roots
+}
+
+// accountTask is a chunk of the account trie to retrieve.
+type accountTask struct {
+	next common.Hash     // Next account to retrieve
+	last common.Hash     // Last account of the chunk
+	trie *trie.StackTrie // Rebuilds the nodes of the chunk
+	busy bool            // Whether a request for the chunk is in flight
+	done bool            // Whether the whole chunk was retrieved
+}
+
+// rangeTask is a storage or XDCx trie to retrieve in full.
+type rangeTask struct {
+	space   TrieSpace
+	root    common.Hash
+	next    common.Hash                             // Next key to retrieve
+	trie    *trie.StackTrie                         // Rebuilds the nodes of the trie
+	resolve func(leaf []byte) ([]*rangeTask, error) // Finds the tries nested in a leaf, nil if there are none
+}
+
+// rangeKey identifies a trie scheduled for retrieval.
+type rangeKey struct {
+	space TrieSpace
+	root  common.Hash
+}
+
+// rangeReq is an in-flight trie range or contract code request.
+type rangeReq struct {
+	peer    *peerConnection
+	account *accountTask  // Account chunk requested, or
+	tries   []*rangeTask  // tries requested, or
+	codes   []common.Hash // contract codes requested
+	timer   *time.Timer   // Timer to fire when the request times out
+}
+
+// rangeSync retrieves the state of a block by verified ranges of trie leaves,
+// rebuilding the trie nodes locally. The storage and XDCx tries are retrieved in
+// full, one after the other, while the account trie is split in chunks retrieved
+// concurrently. The nodes spanning the chunks are left to the trie node sync
+// healing the state afterwards.
+type rangeSync struct {
+	d    *Downloader
+	root common.Hash
+
+	accounts  []*accountTask
+	tries     []*rangeTask
+	codes     map[common.Hash]struct{}
+	scheduled map[rangeKey]struct{} // Tries and codes already scheduled, to retrieve them once
+
+	active    map[string]*rangeReq // Currently in-flight requests
+	unusable  map[string]struct{}  // Peers unable to serve the state, or serving invalid ranges
+	stateDB   ethdb.Batch
+	xdcxDB    ethdb.Batch
+	xdcxStore ethdb.Database
+
+	accountSynced uint64
+	leafSynced    uint64
+	codeSynced    uint64
+	logTime       time.Time
+
+	deliver chan dataPack  // Trie ranges forwarded by the state fetcher
+	timeout chan *rangeReq // Timed out requests
+	done    chan struct{}  // Closed once the retrieval terminates
+}
+
+// newRangeSync creates the range retrieval of the given state root, including
+// the XDCx trading and lending states when their roots are known.
+func newRangeSync(d *Downloader, root common.Hash, trading, lending common.Hash) *rangeSync {
+	r := &rangeSync{
+		d:         d,
+		root:      root,
+		codes:     make(map[common.Hash]struct{}),
+		scheduled: make(map[rangeKey]struct{}),
+		active:    make(map[string]*rangeReq),
+		unusable:  make(map[string]struct{}),
+		stateDB:   d.stateDB.NewBatch(),
+		deliver:   make(chan dataPack),
+		timeout:   make(chan *rangeReq),
+		done:      make(chan struct{}),
+	}
+	// Split the account trie in evenly sized chunks
+	step := new(big.Int).Div(new(big.Int).Lsh(common.Big1, 256), big.NewInt(int64(accountRangeChunks)))
+	for i := 0; i < accountRangeChunks; i++ {
+		next := new(big.Int).Mul(step, big.NewInt(int64(i)))
+		last := new(big.Int).Sub(new(big.Int).Add(next, step), common.Big1)
+		r.accounts = append(r.accounts, &accountTask{
+			next: common.BigToHash(next),
+			last: common.BigToHash(last),
+			trie: trie.NewStackTrie(r.stateDB),
+		})
+	}
+	if d.xdcxDB != nil {
+		r.xdcxStore = d.xdcxDB
+		r.xdcxDB = d.xdcxDB.NewBatch()
+		if trading != (common.Hash{}) && trading != tradingstate.EmptyRoot {
+			r.schedule(r.tradingTask(trading, tradingstate.SyncExchangeTrie))
+		}
+		if lending != (common.Hash{}) && lending != lendingstate.EmptyRoot {
+			r.schedule(r.lendingTask(lending, lendingstate.SyncLendingBookTrie))
+		}
+	}
+	return r
+}
+
+// storageTask creates the retrieval task of a contract storage trie.
+func (r *rangeSync) storageTask(root common.Hash) *rangeTask {
+	return &rangeTask{space: StateSpace, root: root, trie: trie.NewStackTrie(r.stateDB)}
+}
+
+// tradingTask creates the retrieval task of a trie of the XDCx trading state.
+func (r *rangeSync) tradingTask(root common.Hash, kind tradingstate.SyncTrieKind) *rangeTask {
+	task := &rangeTask{space: XDCxSpace, root: root, trie: trie.NewStackTrie(r.xdcxDB)}
+	if kind != tradingstate.SyncLeafTrie {
+		task.resolve = func(leaf []byte) ([]*rangeTask, error) {
+			children, err := tradingstate.SyncChildren(kind, leaf)
+			if err != nil {
+				return nil, err
+			}
+			tasks := make([]*rangeTask, 0, len(children))
+			for _, child := range children {
+				tasks = append(tasks, r.tradingTask(child.Root, child.Kind))
+			}
+			return tasks, nil
+		}
+	}
+	return task
+}
+
+// lendingTask creates the retrieval task of a trie of the XDCx lending state.
+func (r *rangeSync) lendingTask(root common.Hash, kind lendingstate.SyncTrieKind) *rangeTask {
+	task := &rangeTask{space: XDCxSpace, root: root, trie: trie.NewStackTrie(r.xdcxDB)}
+	if kind != lendingstate.SyncLeafTrie {
+		task.resolve = func(leaf []byte) ([]*rangeTask, error) {
+			children, err := lendingstate.SyncChildren(kind, leaf)
+			if err != nil {
+				return nil, err
+			}
+			tasks := make([]*rangeTask, 0, len(children))
+			for _, child := range children {
+				tasks = append(tasks, r.lendingTask(child.Root, child.Kind))
+			}
+			return tasks, nil
+		}
+	}
+	return task
+}
+
+// schedule queues a trie for retrieval, unless it was already scheduled or is
+// known locally. Tries with nested tries are retrieved even if known, as their
+// nested tries may have been left incomplete by an earlier sync.
+func (r *rangeSync) schedule(task *rangeTask) {
+	key := rangeKey{task.space, task.root}
+	if _, ok := r.scheduled[key]; ok {
+		return
+	}
+	r.scheduled[key] = struct{}{}
+
+	db := ethdb.KeyValueReader(r.d.stateDB)
+	if task.space == XDCxSpace {
+		db = r.xdcxStore
+	}
+	if task.resolve == nil && rawdb.HasTrieNode(db, task.root) {
+		return
+	}
+	r.tries = append(r.tries, task)
+}
+
+// scheduleCode queues a contract code for retrieval, unless already known.
+func (r *rangeSync) scheduleCode(hash common.Hash) {
+	key := rangeKey{StateSpace, hash}
+	if _, ok := r.scheduled[key]; ok {
+		return
+	}
+	r.scheduled[key] = struct{}{}
+
+	if !rawdb.HasCode(r.d.stateDB, hash) {
+		r.codes[hash] = struct{}{}
+	}
+}
+
+// finished returns whether every trie and contract code was retrieved.
+func (r *rangeSync) finished() bool {
+	if len(r.tries) > 0 || len(r.codes) > 0 || len(r.active) > 0 {
+		return false
+	}
+	for _, task := range r.accounts {
+		if !task.done {
+			return false
+		}
+	}
+	return true
+}
+
+// run retrieves the state ranges until done or canceled.
+func (r *rangeSync) run(cancel chan struct{}) error {
+	defer close(r.done)
+
+	log.Debug("State range sync starting", "root", r.root)
+
+	newPeer := make(chan *peerConnection, 1024)
+	peerSub := r.d.peers.SubscribeNewPeers(newPeer)
+	defer peerSub.Unsubscribe()
+
+	peerDrop := make(chan *peerConnection, 1024)
+	dropSub := r.d.peers.SubscribePeerDrops(peerDrop)
+	defer dropSub.Unsubscribe()
+
+	defer r.spindown(peerDrop)
+
+	for !r.finished() {
+		r.assignTasks()
+
+		select {
+		case <-newPeer:
+			// New peer arrived, try to assign it download tasks
+
+		case <-cancel:
+			return errCancelStateFetch
+
+		case <-r.d.cancelCh:
+			return errCanceled
+
+		case p := <-peerDrop:
+			if req := r.active[p.id]; req != nil {
+				req.timer.Stop()
+				delete(r.active, p.id)
+				r.revert(req)
+			}
+
+		case req := <-r.timeout:
+			// Ignore the stale timeout of an already delivered request
+			if r.active[req.peer.id] != req {
+				continue
+			}
+			delete(r.active, req.peer.id)
+			r.revert(req)
+			req.peer.SetNodeDataIdle(0, time.Now())
+
+		case pack := <-r.deliver:
+			req := r.active[pack.PeerId()]
+			if req == nil {
+				log.Debug("Unrequested trie range", "peer", pack.PeerId(), "len", pack.Items())
+				continue
+			}
+			req.timer.Stop()
+			delete(r.active, pack.PeerId())
+
+			err := r.process(req, pack.(*rangePack))
+			switch {
+			case err == nil:
+				req.peer.SetNodeDataIdle(pack.Items(), time.Now())
+
+			case errors.Is(err, errRangeUnavailable), errors.Is(err, errInvalidRange):
+				req.peer.log.Debug("Peer unable to serve state ranges", "root", r.root, "err", err)
+				r.unusable[req.peer.id] = struct{}{}
+				r.revert(req)
+				req.peer.SetNodeDataIdle(0, time.Now())
+
+			default:
+				return err
+			}
+		}
+		if err := r.commit(false); err != nil {
+			return err
+		}
+	}
+	log.Info("State ranges retrieved, healing", "root", r.root, "accounts", r.accountSynced, "leaves", r.leafSynced, "codes", r.codeSynced)
+	return r.commit(true)
+}
+
+// spindown waits for the in-flight requests to be delivered or time out, so
+// that their peers are idle once the retrieval terminates.
+func (r *rangeSync) spindown(peerDrop chan *peerConnection) {
+	for len(r.active) > 0 {
+		var req *rangeReq
+		select {
+		case pack := <-r.deliver:
+			req = r.active[pack.PeerId()]
+		case p := <-peerDrop:
+			req = r.active[p.id]
+		case req = <-r.timeout:
+			if r.active[req.peer.id] != req {
+				req = nil
+			}
+		}
+		if req == nil {
+			continue
+		}
+		req.timer.Stop()
+		delete(r.active, req.peer.id)
+		req.peer.SetNodeDataIdle(0, time.Now())
+	}
+}
+
+// assignTasks assigns the queued codes, tries and account chunks to the idle
+// peers able to serve them.
+func (r *rangeSync) assignTasks() {
+	peers, _ := r.d.peers.RangeIdlePeers()
+	for _, p := range peers {
+		if _, ok := r.unusable[p.id]; ok {
+			continue
+		}
+		var (
+			req = &rangeReq{peer: p}
+			err error
+		)
+		switch {
+		case len(r.codes) > 0:
+			for hash := range r.codes {
+				req.codes = append(req.codes, hash)
+				delete(r.codes, hash)
+				if len(req.codes) == MaxCodeFetch {
+					break
+				}
+			}
+			err = p.FetchByteCodes(req.codes, uint64(MaxRangeBytes))
+
+		case len(r.tries) > 0:
+			req.tries = r.popTries()
+			roots := make([]common.Hash, len(req.tries))
+			for i, task := range req.tries {
+				roots[i] = task.root
+			}
+			err = p.FetchStorageRanges(req.tries[0].space, roots, req.tries[0].next, uint64(MaxRangeBytes))
+
+		default:
+			for _, task := range r.accounts {
+				if !task.done && !task.busy {
+					req.account = task
+					break
+				}
+			}
+			if req.account == nil {
+				return
+			}
+			req.account.busy = true
+			err = p.FetchAccountRange(r.root, req.account.next, req.account.last, uint64(MaxRangeBytes))
+		}
+		if err != nil {
+			r.revert(req)
+			continue
+		}
+		req.timer = time.AfterFunc(r.d.requestTTL(), func() {
+			select {
+			case r.timeout <- req:
+			case <-r.done:
+			}
+		})
+		r.active[p.id] = req
+	}
+}
+
+// popTries dequeues the tries of a storage ranges request. A partially retrieved
+// trie is requested alone, as the origin only applies to the first trie.
+func (r *rangeSync) popTries() []*rangeTask {
+	first := r.tries[0]
+	if first.next != (common.Hash{}) {
+		r.tries = r.tries[1:]
+		return []*rangeTask{first}
+	}
+	var (
+		tasks []*rangeTask
+		left  []*rangeTask
+	)
+	for _, task := range r.tries {
+		if len(tasks) < MaxRangeTries && task.space == first.space && task.next == (common.Hash{}) {
+			tasks = append(tasks, task)
+		} else {
+			left = append(left, task)
+		}
+	}
+	r.tries = left
+	return tasks
+}
+
+// revert puts the tasks of a failed request back into the queues.
+func (r *rangeSync) revert(req *rangeReq) {
+	if req.account != nil {
+		req.account.busy = false
+	}
+	r.tries = append(r.tries, req.tries...)
+	for _, hash := range req.codes {
+		r.codes[hash] = struct{}{}
+	}
+}
+
+// process verifies a delivered response and integrates it into the state.
+func (r *rangeSync) process(req *rangeReq, pack *rangePack) error {
+	switch {
+	case req.account != nil:
+		return r.processAccounts(req, pack)
+	case len(req.tries) > 0:
+		return r.processTries(req, pack)
+	default:
+		return r.processCodes(req, pack)
+	}
+}
+
+// processAccounts integrates a range of accounts into its chunk, scheduling the
+// storage tries and codes of the accounts.
+func (r *rangeSync) processAccounts(req *rangeReq, pack *rangePack) error {
+	task := req.account
+	if len(pack.keys) == 0 || (len(pack.keys[0]) == 0 && len(pack.proof) == 0) {
+		return errRangeUnavailable
+	}
+	if len(pack.keys) != 1 || len(pack.values) != 1 {
+		return fmt.Errorf("%w: %d account ranges", errInvalidRange, len(pack.keys))
+	}
+	keys, values := pack.keys[0], pack.values[0]
+	cont, err := verifyRange(r.root, task.next, keys, values, pack.proof)
+	if err != nil {
+		return err
+	}
+	accounts := make([]types.StateAccount, len(keys))
+	for i := range keys {
+		if err := rlp.DecodeBytes(values[i], &accounts[i]); err != nil {
+			return fmt.Errorf("%w: invalid account %x: %v", errInvalidRange, keys[i], err)
+		}
+	}
+	// Accounts past the chunk, if any, are retrieved with the next chunk
+	task.busy = false
+	for i, key := range keys {
+		if key.Big().Cmp(task.last.Big()) > 0 {
+			cont = false
+			break
+		}
+		task.trie.Update(key[:], values[i])
+		if accounts[i].Root != types.EmptyRootHash {
+			r.schedule(r.storageTask(accounts[i].Root))
+		}
+		if hash := common.BytesToHash(accounts[i].CodeHash); hash != types.EmptyCodeHash {
+			r.scheduleCode(hash)
+		}
+		r.accountSynced++
+	}
+	r.updateStats(len(keys))
+
+	if cont && keys[len(keys)-1] != task.last {
+		task.next = incHash(keys[len(keys)-1])
+		return nil
+	}
+	task.done = true
+	if _, err := task.trie.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// processTries integrates the ranges of a batch of tries, scheduling their
+// nested tries and queueing back the partially retrieved ones.
+func (r *rangeSync) processTries(req *rangeReq, pack *rangePack) error {
+	if len(pack.keys) == 0 {
+		return errRangeUnavailable
+	}
+	if len(pack.keys) > len(req.tries) || len(pack.values) != len(pack.keys) {
+		return fmt.Errorf("%w: %d ranges for %d tries", errInvalidRange, len(pack.keys), len(req.tries))
+	}
+	// Verify every range before integrating any of them
+	conts := make([]bool, len(pack.keys))
+	for i, keys := range pack.keys {
+		var (
+			origin common.Hash
+			proof  [][]byte
+		)
+		if i == 0 {
+			origin = req.tries[0].next
+		}
+		if i == len(pack.keys)-1 {
+			proof = pack.proof
+		} else if len(keys) == 0 {
+			return fmt.Errorf("%w: empty trie %x", errInvalidRange, req.tries[i].root)
+		}
+		cont, err := verifyRange(req.tries[i].root, origin, keys, pack.values[i], proof)
+		if err != nil {
+			return err
+		}
+		conts[i] = cont
+	}
+	for i, keys := range pack.keys {
+		task := req.tries[i]
+		for j, key := range keys {
+			task.trie.Update(key[:], pack.values[i][j])
+			if task.resolve == nil {
+				continue
+			}
+			children, err := task.resolve(pack.values[i][j])
+			if err != nil {
+				return fmt.Errorf("%w: invalid leaf %x of trie %x: %v", errInvalidRange, key, task.root, err)
+			}
+			for _, child := range children {
+				r.schedule(child)
+			}
+		}
+		r.leafSynced += uint64(len(keys))
+		r.updateStats(len(keys))
+
+		if conts[i] {
+			task.next = incHash(keys[len(keys)-1])
+			r.tries = append(r.tries, task)
+			continue
+		}
+		root, err := task.trie.Commit()
+		if err != nil {
+			return err
+		}
+		if root != task.root {
+			return fmt.Errorf("trie %x rebuilt with root %x", task.root, root)
+		}
+	}
+	// Queue back the tries not served
+	r.tries = append(r.tries, req.tries[len(pack.keys):]...)
+	return nil
+}
+
+// processCodes stores the delivered contract codes.
+func (r *rangeSync) processCodes(req *rangeReq, pack *rangePack) error {
+	if len(pack.codes) == 0 {
+		return errRangeUnavailable
+	}
+	requested := make(map[common.Hash]struct{}, len(req.codes))
+	for _, hash := range req.codes {
+		requested[hash] = struct{}{}
+	}
+	for _, code := range pack.codes {
+		hash := crypto.Keccak256Hash(code)
+		if _, ok := requested[hash]; !ok {
+			return fmt.Errorf("%w: unrequested code %x", errInvalidRange, hash)
+		}
+		delete(requested, hash)
+		rawdb.WriteCode(r.stateDB, hash, code)
+	}
+	r.codeSynced += uint64(len(pack.codes))
+	r.updateStats(len(pack.codes))
+
+	// Queue back the codes not served
+	for hash := range requested {
+		r.codes[hash] = struct{}{}
+	}
+	return nil
+}
+
+// commit writes the batched trie nodes and codes to disk.
+func (r *rangeSync) commit(force bool) error {
+	for _, batch := range []ethdb.Batch{r.stateDB, r.xdcxDB} {
+		if batch == nil || (!force && batch.ValueSize() < ethdb.IdealBatchSize) {
+			continue
+		}
+		if err := batch.Write(); err != nil {
+			return fmt.Errorf("write DB error: %v", err)
+		}
+		batch.Reset()
+	}
+	return nil
+}
+
+// updateStats bumps the state sync progress counters and occasionally displays
+// a log message for the user to see.
+func (r *rangeSync) updateStats(processed int) {
+	r.d.syncStatsLock.Lock()
+	r.d.syncStatsState.processed += uint64(processed)
+	r.d.syncStatsLock.Unlock()
+
+	if time.Since(r.logTime) > rangeLogInterval {
+		r.logTime = time.Now()
+		log.Info("Imported new state ranges", "accounts", r.accountSynced, "leaves", r.leafSynced, "codes", r.codeSynced, "pending", len(r.tries)+len(r.codes))
+	}
+}
+
+// verifyRange checks a range of trie leaves starting at origin against the trie
+// root, returning whether more leaves follow it. Without a proof, the range must
+// hold the whole trie.
+func verifyRange(root, origin common.Hash, keys []common.Hash, values [][]byte, proof [][]byte) (bool, error) {
+	if len(keys) != len(values) {
+		return false, fmt.Errorf("%w: %d keys, %d values", errInvalidRange, len(keys), len(values))
+	}
+	keyBytes := make([][]byte, len(keys))
+	for i := range keys {
+		keyBytes[i] = keys[i].Bytes()
+	}
+	var (
+		nodes ethdb.KeyValueReader
+		last  []byte
+	)
+	if len(proof) == 0 {
+		if origin != (common.Hash{}) {
+			return false, fmt.Errorf("%w: missing proof", errInvalidRange)
+		}
+	} else {
+		db := memorydb.New()
+		for _, node := range proof {
+			db.Put(crypto.Keccak256(node), node)
+		}
+		nodes = db
+		if len(keys) > 0 {
+			last = keyBytes[len(keys)-1]
+		}
+	}
+	cont, err := trie.VerifyRangeProof(root, origin.Bytes(), last, keyBytes, values, nodes)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", errInvalidRange, err)
+	}
+	return cont, nil
+}
+
+// incHash returns the hash following h.
+func incHash(h common.Hash) common.Hash {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/ethdb/memorydb"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// rangeTestLeaves is the number of leaves the tester peers serve per range, low
+// enough for every response to be cut short and proven.
+const rangeTestLeaves = 1
+
+// serveRange gathers a range of leaves of one of the peer's tries, along with
+// the proof of its edges if needed.
+func (dlp *downloadTesterPeer) serveRange(root, origin, limit common.Hash) ([]common.Hash, [][]byte, [][]byte) {
+	dlp.dl.lock.RLock()
+	defer dlp.dl.lock.RUnlock()
+
+	tr, err := trie.New(common.Hash{}, root, trie.NewDatabase(dlp.dl.peerDb))
+	if err != nil {
+		return nil, nil, nil
+	}
+	var (
+		keys   []common.Hash
+		values [][]byte
+		more   bool
+	)
+	it := trie.NewIterator(tr.NodeIterator(origin[:]))
+	for it.Next() {
+		key := common.BytesToHash(it.Key)
+		keys = append(keys, key)
+		values = append(values, common.CopyBytes(it.Value))
+		if len(keys) == rangeTestLeaves || key.Big().Cmp(limit.Big()) >= 0 {
+			more = true
+			break
+		}
+	}
+	if origin == (common.Hash{}) && !more {
+		return keys, values, nil
+	}
+	db := memorydb.New()
+	tr.Prove(origin[:], 0, db)
+	if len(keys) > 0 {
+		tr.Prove(keys[len(keys)-1][:], 0, db)
+	}
+	var proof [][]byte
+	pit := db.NewIterator(nil, nil)
+	defer pit.Release()
+	for pit.Next() {
+		proof = append(proof, common.CopyBytes(pit.Value()))
+	}
+	return keys, values, proof
+}
+
+// RequestAccountRange constructs a range of the peer's account trie and delivers
+// it to the tester.
+func (dlp *downloadTesterPeer) RequestAccountRange(root common.Hash, origin common.Hash, limit common.Hash, bytes uint64) error {
+	keys, values, proof := dlp.serveRange(root, origin, limit)
+	go dlp.dl.downloader.DeliverAccountRange(dlp.id, keys, values, proof)
+	return nil
+}
+
+// RequestStorageRanges constructs the ranges of a batch of the peer's tries and
+// delivers them to the tester.
+func (dlp *downloadTesterPeer) RequestStorageRanges(space TrieSpace, roots []common.Hash, origin common.Hash, bytes uint64) error {
+	var (
+		keys   [][]common.Hash
+		values [][][]byte
+		proof  [][]byte
+	)
+	for i, root := range roots {
+		var start common.Hash
+		if i == 0 {
+			start = origin
+		}
+		k, v, p := dlp.serveRange(root, start, common.MaxHash)
+		keys, values = append(keys, k), append(values, v)
+		if p != nil {
+			proof = p
+			break
+		}
+	}
+	go dlp.dl.downloader.DeliverStorageRanges(dlp.id, keys, values, proof)
+	return nil
+}
+
+// RequestByteCodes gathers the requested contract codes of the peer and delivers
+// them to the tester.
+func (dlp *downloadTesterPeer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	dlp.dl.lock.RLock()
+	defer dlp.dl.lock.RUnlock()
+
+	var codes [][]byte
+	for _, hash := range hashes {
+		if code := rawdb.ReadCode(dlp.dl.peerDb, hash); len(code) > 0 {
+			codes = append(codes, code)
+		}
+	}
+	go dlp.dl.downloader.DeliverByteCodes(dlp.id, codes)
+	return nil
+}
+
+// Tests that snap sync retrieves the pivot state by trie ranges from xdsnap
+// peers and heals it into a complete state.
+func TestSnapSynchronisation(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("peer", 101, chain)
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chain.len())
+
+	// Every node of the pivot state must have been retrieved
+	pivot := chain.headerm[chain.chain[chain.len()-1-fsMinFullBlocks]]
+	tr, err := trie.New(common.Hash{}, pivot.Root, trie.NewDatabase(tester.stateDb))
+	if err != nil {
+		t.Fatalf("pivot state missing: %v", err)
+	}
+	it := tr.NodeIterator(nil)
+	for it.Next(true) {
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("pivot state incomplete: %v", err)
+	}
+}
+
+// Tests that trie ranges are verified against the requested root and origin.
+func TestVerifyRange(t *testing.T) {
+	db := trie.NewDatabase(rawdb.NewMemoryDatabase())
+	tr := trie.NewEmpty(db)
+	var keys []common.Hash
+	for i := byte(1); i <= 10; i++ {
+		key := common.Hash{i}
+		keys = append(keys, key)
+		tr.Update(key[:], []byte{i})
+	}
+	root := tr.Hash()
+
+	values := func(keys []common.Hash) [][]byte {
+		vals := make([][]byte, len(keys))
+		for i, key := range keys {
+			vals[i] = []byte{key[0]}
+		}
+		return vals
+	}
+	prove := func(origin common.Hash, keys []common.Hash) [][]byte {
+		db := memorydb.New()
+		tr.Prove(origin[:], 0, db)
+		tr.Prove(keys[len(keys)-1][:], 0, db)
+		var proof [][]byte
+		it := db.NewIterator(nil, nil)
+		defer it.Release()
+		for it.Next() {
+			proof = append(proof, common.CopyBytes(it.Value()))
+		}
+		return proof
+	}
+	// The whole trie needs no proof
+	if cont, err := verifyRange(root, common.Hash{}, keys, values(keys), nil); err != nil || cont {
+		t.Fatalf("whole trie: cont %v, err %v", cont, err)
+	}
+	// A proven range in the middle of the trie is followed by more leaves
+	if cont, err := verifyRange(root, keys[2], keys[2:5], values(keys[2:5]), prove(keys[2], keys[2:5])); err != nil || !cont {
+		t.Fatalf("middle range: cont %v, err %v", cont, err)
+	}
+	// A range missing a leaf must be rejected
+	gapped := append(append([]common.Hash{}, keys[2:4]...), keys[5])
+	if _, err := verifyRange(root, keys[2], gapped, values(gapped), prove(keys[2], gapped)); err == nil {
+		t.Fatalf("gapped range accepted")
+	}
+	// A partial range without a proof must be rejected
+	if _, err := verifyRange(root, keys[2], keys[2:], values(keys[2:]), nil); err == nil {
+		t.Fatalf("unproven range accepted")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
 	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/ethdb"
 	"github.com/XinFinOrg/XDPoSChain/log"
 	"github.com/XinFinOrg/XDPoSChain/trie"
@@ -62,6 +63,30 @@ type stateSyncStats struct {
 // syncState starts downloading state with the given root hash.
 func (d *Downloader) syncState(root common.Hash) *stateSync {
 	s := newStateSync(d, root)
+	if d.getMode() == SnapSync {
+		s.ranges = newRangeSync(d, root, common.Hash{}, common.Hash{})
+	}
+	return d.startStateSync(s)
+}
+
+// syncPivotState starts downloading the state of the pivot block. In snap sync,
+// the XDCx trading and lending states committed by the block are retrieved too.
+func (d *Downloader) syncPivotState(pivot *fetchResult) *stateSync {
+	if d.getMode() != SnapSync {
+		return d.syncState(pivot.Header.Root)
+	}
+	var trading, lending common.Hash
+	if d.xdcxDB != nil && d.xdcxRoots != nil {
+		block := types.NewBlockWithHeader(pivot.Header).WithBody(pivot.Transactions, pivot.Uncles)
+		trading, lending = d.xdcxRoots(block)
+	}
+	s := newStateSync(d, pivot.Header.Root)
+	s.ranges = newRangeSync(d, pivot.Header.Root, trading, lending)
+	return d.startStateSync(s)
+}
+
+// startStateSync hands a state sync over to the state fetcher.
+func (d *Downloader) startStateSync(s *stateSync) *stateSync {
 	select {
 	case d.stateSyncStart <- s:
 		// If we tell the statesync to restart with a new root, we also need
@@ -86,6 +111,8 @@ func (d *Downloader) stateFetcher() {
 			}
 		case <-d.stateCh:
 			// Ignore state responses while no sync is running.
+		case <-d.rangeCh:
+			// Ignore trie range responses while no sync is running.
 		case <-d.quitCh:
 			return
 		}
@@ -154,6 +181,16 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			finished = append(finished, req)
 			delete(active, pack.PeerId())
 
+		// Forward trie ranges to the range retrieval, if still running:
+		case pack := <-d.rangeCh:
+			if s.ranges == nil {
+				continue
+			}
+			select {
+			case s.ranges.deliver <- pack:
+			case <-s.ranges.done:
+			}
+
 			// Handle dropped peer connections:
 		case p := <-peerDrop:
 			// Skip if no request is currently pending
@@ -225,6 +262,8 @@ func (d *Downloader) spindownStateSync(active map[string]*stateReq, finished []*
 		case pack := <-d.stateCh:
 			req = active[pack.PeerId()]
 			reason = "delivered"
+		// Drop trie ranges, tracked by the range retrieval itself:
+		case <-d.rangeCh:
 		// Handle dropped peer connections:
 		case p := <-peerDrop:
 			req = active[p.id]
@@ -256,6 +295,7 @@ type stateSync struct {
 
 	sched  *trie.Sync // State trie sync scheduler defining the tasks
 	keccak hash.Hash  // Keccak256 hasher to verify deliveries with
+	ranges *rangeSync // Trie range retrieval preceding the trie node sync (snap sync only)
 
 	trieTasks map[string]*trieTask      // Set of trie node tasks currently queued for retrieval, indexed by path
 	codeTasks map[common.Hash]*codeTask // Set of byte code tasks currently queued for retrieval, indexed by hash
@@ -310,7 +350,16 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 // it finishes, and finally notifying any goroutines waiting for the loop to
 // finish.
 func (s *stateSync) run() {
-	s.err = s.loop()
+	close(s.started)
+	if s.ranges != nil {
+		// Retrieve the bulk of the state by ranges, then heal the trie nodes spanning
+		// the account chunks and any trie left incomplete from the root again
+		s.err = s.ranges.run(s.cancel)
+		s.sched = state.NewStateSync(s.root, s.d.stateDB, nil)
+	}
+	if s.err == nil {
+		s.err = s.loop()
+	}
 	close(s.done)
 }
 
@@ -333,7 +382,6 @@ func (s *stateSync) Cancel() error {
 // pushed here async. The reason is to decouple processing from data receipt
 // and timeouts.
 func (s *stateSync) loop() (err error) {
-	close(s.started)
 	// Listen for new peer events to assign tasks to them
 	newPeer := make(chan *peerConnection, 1024)
 	peerSub := s.d.peers.SubscribeNewPeers(newPeer)
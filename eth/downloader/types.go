@@ -19,6 +19,7 @@ package downloader
 import (
 	"fmt"
 
+	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 )
 
@@ -77,3 +78,22 @@ type statePack struct {
 func (p *statePack) PeerId() string { return p.peerId }
 func (p *statePack) Items() int     { return len(p.states) }
 func (p *statePack) Stats() string  { return fmt.Sprintf("%d", len(p.states)) }
+
+// rangePack is a batch of trie ranges or contract codes returned by a peer.
+type rangePack struct {
+	peerId string
+	keys   [][]common.Hash // Keys of the leaves of every trie range
+	values [][][]byte      // Values of the leaves of every trie range
+	proof  [][]byte        // Nodes proving the edges of the last range
+	codes  [][]byte        // Contract codes
+}
+
+func (p *rangePack) PeerId() string { return p.peerId }
+func (p *rangePack) Items() int {
+	items := len(p.codes)
+	for _, keys := range p.keys {
+		items += len(keys)
+	}
+	return items
+}
+func (p *rangePack) Stats() string { return fmt.Sprintf("%d:%d", len(p.keys), p.Items()) }
@@ -52,10 +52,13 @@ var Defaults = Config{
 	FilterLogCacheSize: 32,
 	GasPrice:           big.NewInt(0.25 * params.Shannon),
 
-	TxPool:      txpool.DefaultConfig,
-	RPCGasCap:   50000000,
-	GPO:         FullNodeGPO,
-	RPCTxFeeCap: 1, // 1 ether
+	TxPool:        txpool.DefaultConfig,
+	OrderPool:     txpool.DefaultOrderPoolConfig,
+	LendingPool:   txpool.DefaultLendingPoolConfig,
+	RPCGasCap:     50000000,
+	RPCEVMTimeout: 5 * time.Second,
+	GPO:           FullNodeGPO,
+	RPCTxFeeCap:   1, // 1 ether
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -field-override configMarshaling -formats toml -out gen_config.go
@@ -78,13 +81,19 @@ type Config struct {
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Light sync options
+	LightServer     string      `toml:",omitempty"` // RPC endpoint of the full node followed in light sync mode
+	LightCheckpoint common.Hash `toml:",omitempty"` // Trusted epoch switch header the light chain starts from
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string
 	TrieCleanCache     int
 	TrieDirtyCache     int
 	TrieTimeout        time.Duration
+	SnapshotCache      int
 	Preimages          bool
 
 	// This is the number of blocks for which logs will be cached in the filter system.
@@ -97,7 +106,9 @@ type Config struct {
 	GasPrice     *big.Int
 
 	// Transaction pool options
-	TxPool txpool.Config
+	TxPool      txpool.Config
+	OrderPool   txpool.OrderPoolConfig
+	LendingPool txpool.LendingPoolConfig
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
@@ -108,6 +119,9 @@ type Config struct {
 	// RPCGasCap is the global gas cap for eth-call variants.
 	RPCGasCap uint64
 
+	// RPCEVMTimeout is the global timeout for eth-call.
+	RPCEVMTimeout time.Duration
+
 	// RPCTxFeeCap is the global transaction fee(price * gaslimit) cap for
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
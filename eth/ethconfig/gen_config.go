@@ -23,14 +23,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		LightServ               int         `toml:",omitempty"`
+		LightPeers              int         `toml:",omitempty"`
+		LightServer             string      `toml:",omitempty"`
+		LightCheckpoint         common.Hash `toml:",omitempty"`
+		SkipBcVersionCheck      bool        `toml:"-"`
+		DatabaseHandles         int         `toml:"-"`
 		DatabaseCache           int
+		DatabaseFreezer         string
 		TrieCleanCache          int
 		TrieDirtyCache          int
 		TrieTimeout             time.Duration
+		SnapshotCache           int
 		Preimages               bool
 		FilterLogCacheSize      int
 		Etherbase               common.Address `toml:",omitempty"`
@@ -38,9 +42,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
 		TxPool                  txpool.Config
+		OrderPool               txpool.OrderPoolConfig
+		LendingPool             txpool.LendingPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		RPCGasCap               uint64
+		RPCEVMTimeout           time.Duration
 		RPCTxFeeCap             float64
 	}
 	var enc Config
@@ -50,12 +57,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPruning = c.NoPruning
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.LightServer = c.LightServer
+	enc.LightCheckpoint = c.LightCheckpoint
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.DatabaseFreezer = c.DatabaseFreezer
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
 	enc.FilterLogCacheSize = c.FilterLogCacheSize
 	enc.Etherbase = c.Etherbase
@@ -63,9 +74,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
 	enc.TxPool = c.TxPool
+	enc.OrderPool = c.OrderPool
+	enc.LendingPool = c.LendingPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
 	return &enc, nil
 }
@@ -77,14 +91,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		LightServ               *int         `toml:",omitempty"`
+		LightPeers              *int         `toml:",omitempty"`
+		LightServer             *string      `toml:",omitempty"`
+		LightCheckpoint         *common.Hash `toml:",omitempty"`
+		SkipBcVersionCheck      *bool        `toml:"-"`
+		DatabaseHandles         *int         `toml:"-"`
 		DatabaseCache           *int
+		DatabaseFreezer         *string
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
 		TrieTimeout             *time.Duration
+		SnapshotCache           *int
 		Preimages               *bool
 		FilterLogCacheSize      *int
 		Etherbase               *common.Address `toml:",omitempty"`
@@ -92,9 +110,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
 		TxPool                  *txpool.Config
+		OrderPool               *txpool.OrderPoolConfig
+		LendingPool             *txpool.LendingPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		RPCGasCap               *uint64
+		RPCEVMTimeout           *time.Duration
 		RPCTxFeeCap             *float64
 	}
 	var dec Config
@@ -119,6 +140,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightServer != nil {
+		c.LightServer = *dec.LightServer
+	}
+	if dec.LightCheckpoint != nil {
+		c.LightCheckpoint = *dec.LightCheckpoint
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -128,6 +155,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.DatabaseFreezer != nil {
+		c.DatabaseFreezer = *dec.DatabaseFreezer
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -137,6 +167,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.SnapshotCache != nil {
+		c.SnapshotCache = *dec.SnapshotCache
+	}
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
@@ -158,6 +191,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.OrderPool != nil {
+		c.OrderPool = *dec.OrderPool
+	}
+	if dec.LendingPool != nil {
+		c.LendingPool = *dec.LendingPool
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -167,6 +206,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCGasCap != nil {
 		c.RPCGasCap = *dec.RPCGasCap
 	}
+	if dec.RPCEVMTimeout != nil {
+		c.RPCEVMTimeout = *dec.RPCEVMTimeout
+	}
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
@@ -42,6 +42,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/p2p/discover"
 	"github.com/XinFinOrg/XDPoSChain/params"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
+	"github.com/XinFinOrg/XDPoSChain/trie"
 )
 
 const (
@@ -69,6 +70,7 @@ type ProtocolManager struct {
 	networkId uint64
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync  uint32 // Flag whether fast sync should retrieve the state by trie ranges from xdsnap peers
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
@@ -83,6 +85,8 @@ type ProtocolManager struct {
 	peers      *peerSet
 	bft        *bft.Bfter
 
+	xdcxTrieDBs []*trie.Database // Trie databases the XDCx trading and lending state ranges are served from
+
 	SubProtocols []p2p.Protocol
 
 	eventMux      *event.TypeMux
@@ -113,14 +117,15 @@ type ProtocolManager struct {
 	knownTimeouts  *lru.Cache[common.Hash, struct{}]
 }
 
-// NewProtocolManagerEx add order pool to protocol
-func NewProtocolManagerEx(config *params.ChainConfig, mode downloader.SyncMode, networkID uint64, mux *event.TypeMux, txpool txPool, orderpool orderPool, lendingpool lendingPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb ethdb.Database) (*ProtocolManager, error) {
+// NewProtocolManagerEx add order pool and XDCx state to protocol
+func NewProtocolManagerEx(config *params.ChainConfig, mode downloader.SyncMode, networkID uint64, mux *event.TypeMux, txpool txPool, orderpool orderPool, lendingpool lendingPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb ethdb.Database, xdcxdb ethdb.Database) (*ProtocolManager, error) {
 	protocol, err := NewProtocolManager(config, mode, networkID, mux, txpool, engine, blockchain, chaindb)
 	if err != nil {
 		return nil, err
 	}
 	protocol.addOrderPoolProtocol(orderpool)
 	protocol.addLendingPoolProtocol(lendingpool)
+	protocol.addXDCxState(xdcxdb, engine)
 	return protocol, nil
 }
 
@@ -151,18 +156,21 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		lendingTxSub:   nil,
 	}
 	// Figure out whether to allow fast sync or not
-	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
+	if (mode == downloader.FastSync || mode == downloader.SnapSync) && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
 		mode = downloader.FullSync
 	}
-	if mode == downloader.FastSync {
+	if mode == downloader.FastSync || mode == downloader.SnapSync {
 		manager.fastSync = uint32(1)
 	}
+	if mode == downloader.SnapSync {
+		manager.snapSync = uint32(1)
+	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
-		if mode == downloader.FastSync && version < eth63 {
+		if (mode == downloader.FastSync || mode == downloader.SnapSync) && version < eth63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -704,6 +712,60 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver receipts", "err", err)
 		}
 
+	case p.version >= xdsnap1 && msg.Code == GetAccountRangeMsg:
+		// Decode the account range query and serve it
+		var req getAccountRangeData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p.SendAccountRange(pm.serveAccountRange(&req))
+
+	case p.version >= xdsnap1 && msg.Code == AccountRangeMsg:
+		// A range of accounts arrived to one of our previous requests
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverAccountRange(p.id, res.Keys, res.Values, res.Proof); err != nil {
+			log.Debug("Failed to deliver account range", "err", err)
+		}
+
+	case p.version >= xdsnap1 && msg.Code == GetStorageRangesMsg:
+		// Decode the trie ranges query and serve it
+		var req getStorageRangesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p.SendStorageRanges(pm.serveStorageRanges(&req))
+
+	case p.version >= xdsnap1 && msg.Code == StorageRangesMsg:
+		// The ranges of a batch of tries arrived to one of our previous requests
+		var res storageRangesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverStorageRanges(p.id, res.Keys, res.Values, res.Proof); err != nil {
+			log.Debug("Failed to deliver trie ranges", "err", err)
+		}
+
+	case p.version >= xdsnap1 && msg.Code == GetByteCodesMsg:
+		// Decode the contract code query and serve it
+		var req getByteCodesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p.SendByteCodes(pm.serveByteCodes(&req))
+
+	case p.version >= xdsnap1 && msg.Code == ByteCodesMsg:
+		// A batch of contract codes arrived to one of our previous requests
+		var codes [][]byte
+		if err := msg.Decode(&codes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverByteCodes(p.id, codes); err != nil {
+			log.Debug("Failed to deliver byte codes", "err", err)
+		}
+
 	case msg.Code == NewBlockHashesMsg:
 		var announces newBlockHashesData
 		if err := msg.Decode(&announces); err != nil {
@@ -0,0 +1,222 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/eth/downloader"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/ethdb/memorydb"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// lastHash is the largest trie key, used as the limit of the trie ranges served
+// without one.
+var lastHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+// addXDCxState enables serving and snap syncing the XDCx trading and lending
+// states kept in the given database.
+func (pm *ProtocolManager) addXDCxState(xdcxdb ethdb.Database, engine consensus.Engine) {
+	if xdcxdb == nil {
+		return
+	}
+	c, ok := engine.(*XDPoS.XDPoS)
+	if !ok || c.GetXDCXService == nil || c.GetLendingService == nil {
+		return
+	}
+	// Recently committed XDCx tries may still live in the services' trie caches
+	if trading := c.GetXDCXService(); trading != nil && trading.GetStateCache() != nil {
+		pm.xdcxTrieDBs = append(pm.xdcxTrieDBs, trading.GetStateCache().TrieDB())
+	}
+	if lending := c.GetLendingService(); lending != nil && lending.GetStateCache() != nil {
+		pm.xdcxTrieDBs = append(pm.xdcxTrieDBs, lending.GetStateCache().TrieDB())
+	}
+	pm.xdcxTrieDBs = append(pm.xdcxTrieDBs, trie.NewDatabase(xdcxdb))
+
+	pm.downloader.SetXDCxState(xdcxdb, func(block *types.Block) (common.Hash, common.Hash) {
+		trading, lending := c.GetXDCXService(), c.GetLendingService()
+		if !pm.chainconfig.IsTIPXDCX(block.Number()) || trading == nil || lending == nil {
+			return common.Hash{}, common.Hash{}
+		}
+		author, err := c.Author(block.Header())
+		if err != nil {
+			log.Warn("Failed to find the author of the pivot block", "number", block.Number(), "err", err)
+			return common.Hash{}, common.Hash{}
+		}
+		tradingRoot, _ := trading.GetTradingStateRoot(block, author)
+		lendingRoot, _ := lending.GetLendingStateRoot(block, author)
+		return tradingRoot, lendingRoot
+	})
+}
+
+// openRangeTrie opens a trie of the given space to serve its leaves, returning
+// nil if the trie is not available.
+func (pm *ProtocolManager) openRangeTrie(space downloader.TrieSpace, root common.Hash) *trie.Trie {
+	var dbs []*trie.Database
+	switch space {
+	case downloader.StateSpace:
+		dbs = []*trie.Database{pm.blockchain.StateCache().TrieDB()}
+	case downloader.XDCxSpace:
+		dbs = pm.xdcxTrieDBs
+	}
+	for _, db := range dbs {
+		if tr, err := trie.New(common.Hash{}, root, db); err == nil {
+			return tr
+		}
+	}
+	return nil
+}
+
+// serveAccountRange gathers the accounts of the requested range, along with the
+// proof of its edges. An unavailable state is answered with an empty range.
+func (pm *ProtocolManager) serveAccountRange(req *getAccountRangeData) *accountRangeData {
+	tr := pm.openRangeTrie(downloader.StateSpace, req.Root)
+	if tr == nil {
+		return &accountRangeData{}
+	}
+	keys, values, _, more, err := trieRange(tr, req.Origin, req.Limit, responseBytes(req.Bytes))
+	if err != nil {
+		log.Debug("Failed to gather account range", "root", req.Root, "origin", req.Origin, "err", err)
+		return &accountRangeData{}
+	}
+	res := &accountRangeData{Keys: keys, Values: values}
+	if req.Origin != (common.Hash{}) || more {
+		if res.Proof, err = proveRange(tr, req.Origin, keys); err != nil {
+			log.Debug("Failed to prove account range", "root", req.Root, "origin", req.Origin, "err", err)
+			return &accountRangeData{}
+		}
+	}
+	return res
+}
+
+// serveStorageRanges gathers the leaves of the requested tries until the byte
+// limit is reached, proving the edges of the last range if incomplete. Tries are
+// served in order up to the first one unavailable.
+func (pm *ProtocolManager) serveStorageRanges(req *getStorageRangesData) *storageRangesData {
+	var (
+		res    = &storageRangesData{}
+		budget = responseBytes(req.Bytes)
+		bytes  int
+	)
+	for i, root := range req.Roots {
+		if bytes >= budget || i >= downloader.MaxRangeTries {
+			break
+		}
+		tr := pm.openRangeTrie(req.Space, root)
+		if tr == nil {
+			break
+		}
+		var origin common.Hash
+		if i == 0 {
+			origin = req.Origin
+		}
+		keys, values, size, more, err := trieRange(tr, origin, lastHash, budget-bytes)
+		if err != nil {
+			log.Debug("Failed to gather trie range", "space", req.Space, "root", root, "err", err)
+			break
+		}
+		if origin != (common.Hash{}) || more {
+			proof, err := proveRange(tr, origin, keys)
+			if err != nil {
+				log.Debug("Failed to prove trie range", "space", req.Space, "root", root, "err", err)
+				break
+			}
+			res.Keys = append(res.Keys, keys)
+			res.Values = append(res.Values, values)
+			res.Proof = proof
+			break
+		}
+		res.Keys = append(res.Keys, keys)
+		res.Values = append(res.Values, values)
+		bytes += size
+	}
+	return res
+}
+
+// serveByteCodes gathers the requested contract codes until the byte limit is
+// reached, skipping the unknown ones.
+func (pm *ProtocolManager) serveByteCodes(req *getByteCodesData) [][]byte {
+	var (
+		codes  [][]byte
+		budget = responseBytes(req.Bytes)
+		bytes  int
+	)
+	for _, hash := range req.Hashes {
+		if bytes >= budget || len(codes) >= downloader.MaxCodeFetch {
+			break
+		}
+		if code, err := pm.blockchain.ContractCodeWithPrefix(hash); err == nil && len(code) > 0 {
+			codes = append(codes, code)
+			bytes += len(code)
+		}
+	}
+	return codes
+}
+
+// trieRange gathers the leaves of a trie starting at origin, stopping at the
+// first leaf at or past limit, or once the byte budget is spent. It returns
+// whether the range was cut short of the end of the trie.
+func trieRange(tr *trie.Trie, origin, limit common.Hash, budget int) ([]common.Hash, [][]byte, int, bool, error) {
+	var (
+		keys   []common.Hash
+		values [][]byte
+		bytes  int
+	)
+	it := trie.NewIterator(tr.NodeIterator(origin[:]))
+	for it.Next() {
+		key := common.BytesToHash(it.Key)
+		keys = append(keys, key)
+		values = append(values, common.CopyBytes(it.Value))
+		bytes += common.HashLength + len(it.Value)
+
+		if key.Big().Cmp(limit.Big()) >= 0 || bytes >= budget {
+			return keys, values, bytes, true, nil
+		}
+	}
+	return keys, values, bytes, false, it.Err
+}
+
+// proveRange creates the proof of the edges of a trie range.
+func proveRange(tr *trie.Trie, origin common.Hash, keys []common.Hash) ([][]byte, error) {
+	db := memorydb.New()
+	if err := tr.Prove(origin[:], 0, db); err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		if err := tr.Prove(keys[len(keys)-1][:], 0, db); err != nil {
+			return nil, err
+		}
+	}
+	var proof [][]byte
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		proof = append(proof, common.CopyBytes(it.Value()))
+	}
+	return proof, nil
+}
+
+// responseBytes caps the byte limit requested by a remote peer.
+func responseBytes(requested uint64) int {
+	if requested == 0 || requested > softResponseLimit {
+		return softResponseLimit
+	}
+	return int(requested)
+}
@@ -2,6 +2,7 @@ package hooks
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -241,6 +242,16 @@ func AttachConsensusV2Hooks(adaptor *XDPoS.XDPoS, bc *core.BlockChain, chainConf
 			}
 		}
 
+		// penalise the candidates slashed with forensic proofs during the epoch
+		if chain.Config().IsTIPSlashing(number) {
+			slashed, err := slashedCandidates(chain, number, listBlockHash, candidates, penalties)
+			if err != nil {
+				log.Error("[HookPenalty] Fail to collect slashed masternodes", "err", err)
+				return nil, err
+			}
+			penalties = append(penalties, slashed...)
+		}
+
 		for i, p := range penalties {
 			log.Info("[HookPenalty] Final penalty list", "index", i, "addr", p)
 		}
@@ -409,8 +420,13 @@ func GetSigningTxCount(c *XDPoS.XDPoS, chain consensus.ChainReader, header *type
 			if epochCount == rewardEpochCount {
 				startBlockNumber = h.Number.Uint64() + 1
 				nodesToKeep[MasterNodeBeneficiary] = c.GetMasternodesFromCheckpointHeader(h)
-				// in reward upgrade, add protector and observer nodes
-				if chain.Config().IsTIPUpgradeReward(header.Number) {
+				// in reward upgrade, add protector and observer nodes. use the ones recorded in the
+				// epoch switch block, or select them the same way from the current candidates if
+				// it was sealed before TIPEpochRoles
+				if roles := c.EngineV2.GetEpochRolesFromEpochSwitchHeader(h); roles != nil && chain.Config().IsTIPUpgradeReward(header.Number) {
+					nodesToKeep[ProtectorNodeBeneficiary] = roles.Protectors
+					nodesToKeep[ObserverNodeBeneficiary] = roles.Observers
+				} else if chain.Config().IsTIPUpgradeReward(header.Number) {
 					candidates := state.GetCandidates(parentState)
 					var ms []utils.Masternode
 					for _, candidate := range candidates {
@@ -423,30 +439,14 @@ func GetSigningTxCount(c *XDPoS.XDPoS, chain consensus.ChainReader, header *type
 					sort.Slice(ms, func(i, j int) bool {
 						return ms[i].Stake.Cmp(ms[j].Stake) >= 0
 					})
-					// find penalty and filter them out
-					penalties := common.ExtractAddressFromBytes(h.Penalties)
-					filterMap := make(map[common.Address]struct{})
-					for _, addr := range penalties {
-						filterMap[addr] = struct{}{}
-					}
-					for _, addr := range nodesToKeep[MasterNodeBeneficiary] {
-						filterMap[addr] = struct{}{}
-					}
-					// find top candidates
-					protector := []common.Address{}
-					observer := []common.Address{}
+					sorted := make([]common.Address, 0, len(ms))
 					for _, node := range ms {
-						if _, ok := filterMap[node.Address]; ok {
-							continue
-						}
-						if len(protector) < currentConfig.MaxProtectorNodes {
-							protector = append(protector, node.Address)
-						} else if len(observer) < currentConfig.MaxObverserNodes {
-							observer = append(observer, node.Address)
-						}
+						sorted = append(sorted, node.Address)
 					}
-					nodesToKeep[ProtectorNodeBeneficiary] = protector
-					nodesToKeep[ObserverNodeBeneficiary] = observer
+					penalties := common.ExtractAddressFromBytes(h.Penalties)
+					roles := utils.SelectEpochRoles(sorted, nodesToKeep[MasterNodeBeneficiary], penalties, currentConfig.MaxProtectorNodes, currentConfig.MaxObverserNodes)
+					nodesToKeep[ProtectorNodeBeneficiary] = roles.Protectors
+					nodesToKeep[ObserverNodeBeneficiary] = roles.Observers
 				}
 				break
 			}
@@ -560,3 +560,45 @@ func CalculateRewardForSignerFixed(chainReward *big.Int, signers map[common.Addr
 
 	return resultSigners, nil
 }
+
+// slashedCandidates returns the candidates, not penalised yet, blamed by the
+// slashing transactions of the given blocks. listBlockHash[i] is the hash of
+// block number-1-i, down to the previous epoch switch block.
+func slashedCandidates(chain consensus.ChainReader, number *big.Int, listBlockHash []common.Hash, candidates []common.Address, penalties []common.Address) ([]common.Address, error) {
+	remaining := make(map[common.Address]bool, len(candidates))
+	for _, candidate := range candidates {
+		remaining[candidate] = true
+	}
+	for _, p := range penalties {
+		delete(remaining, p)
+	}
+	var slashed []common.Address
+	for i, hash := range listBlockHash {
+		blockNumber := number.Uint64() - uint64(i) - 1
+		if !chain.Config().IsTIPSlashing(new(big.Int).SetUint64(blockNumber)) {
+			break
+		}
+		block := chain.GetBlock(hash, blockNumber)
+		if block == nil {
+			return nil, fmt.Errorf("block %d (%x) not found", blockNumber, hash)
+		}
+		for _, tx := range block.Transactions() {
+			if !tx.IsSlashingTransaction() {
+				continue
+			}
+			// Slashing transactions were verified when the block was imported
+			report, err := core.SlashingReport(tx)
+			if err != nil {
+				continue
+			}
+			for _, signer := range report.Signers {
+				if remaining[signer] {
+					log.Info("[HookPenalty] Find a node slashed with a forensic proof", "addr", signer.Hex(), "round", report.Round, "tx", tx.Hash())
+					slashed = append(slashed, signer)
+					delete(remaining, signer)
+				}
+			}
+		}
+	}
+	return slashed, nil
+}
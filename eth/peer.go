@@ -25,6 +25,7 @@ import (
 
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/eth/downloader"
 	"github.com/XinFinOrg/XDPoSChain/p2p"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
 	mapset "github.com/deckarep/golang-set/v2"
@@ -307,6 +308,33 @@ func (p *peer) SendNodeData(data [][]byte) error {
 	}
 }
 
+// SendAccountRange sends a range of accounts to a remote peer.
+func (p *peer) SendAccountRange(data *accountRangeData) error {
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, AccountRangeMsg, data)
+	} else {
+		return p2p.Send(p.rw, AccountRangeMsg, data)
+	}
+}
+
+// SendStorageRanges sends the ranges of a batch of tries to a remote peer.
+func (p *peer) SendStorageRanges(data *storageRangesData) error {
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, StorageRangesMsg, data)
+	} else {
+		return p2p.Send(p.rw, StorageRangesMsg, data)
+	}
+}
+
+// SendByteCodes sends a batch of contract codes to a remote peer.
+func (p *peer) SendByteCodes(codes [][]byte) error {
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, ByteCodesMsg, codes)
+	} else {
+		return p2p.Send(p.rw, ByteCodesMsg, codes)
+	}
+}
+
 // SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
 // ones requested from an already RLP encoded format.
 func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
@@ -427,6 +455,41 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	}
 }
 
+// RequestAccountRange fetches a range of accounts of the state trie with the
+// given root from a remote node.
+func (p *peer) RequestAccountRange(root common.Hash, origin common.Hash, limit common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching range of accounts", "root", root, "origin", origin, "limit", limit, "bytes", common.StorageSize(bytes))
+	req := &getAccountRangeData{Root: root, Origin: origin, Limit: limit, Bytes: bytes}
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, GetAccountRangeMsg, req)
+	} else {
+		return p2p.Send(p.rw, GetAccountRangeMsg, req)
+	}
+}
+
+// RequestStorageRanges fetches the leaves of a batch of storage or XDCx tries
+// from a remote node, starting at origin in the first trie.
+func (p *peer) RequestStorageRanges(space downloader.TrieSpace, roots []common.Hash, origin common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching ranges of trie leaves", "space", space, "count", len(roots), "origin", origin, "bytes", common.StorageSize(bytes))
+	req := &getStorageRangesData{Space: space, Roots: roots, Origin: origin, Bytes: bytes}
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, GetStorageRangesMsg, req)
+	} else {
+		return p2p.Send(p.rw, GetStorageRangesMsg, req)
+	}
+}
+
+// RequestByteCodes fetches a batch of contract codes from a remote node.
+func (p *peer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching batch of byte codes", "count", len(hashes), "bytes", common.StorageSize(bytes))
+	req := &getByteCodesData{Hashes: hashes, Bytes: bytes}
+	if p.pairRw != nil {
+		return p2p.Send(p.pairRw, GetByteCodesMsg, req)
+	} else {
+		return p2p.Send(p.rw, GetByteCodesMsg, req)
+	}
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
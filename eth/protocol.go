@@ -24,25 +24,27 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/common"
 	"github.com/XinFinOrg/XDPoSChain/core"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/eth/downloader"
 	"github.com/XinFinOrg/XDPoSChain/event"
 	"github.com/XinFinOrg/XDPoSChain/rlp"
 )
 
 // Constants to match up protocol versions and messages
 const (
-	eth62  = 62
-	eth63  = 63
-	xdpos2 = 100
+	eth62   = 62
+	eth63   = 63
+	xdpos2  = 100
+	xdsnap1 = 101
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{xdpos2, eth63, eth62}
+var ProtocolVersions = []uint{xdsnap1, xdpos2, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{227, 17, 8}
+var ProtocolLengths = []uint64{227, 227, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -65,6 +67,14 @@ const (
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
 
+	// Protocol messages belonging to xdsnap/101
+	GetAccountRangeMsg  = 0x11
+	AccountRangeMsg     = 0x12
+	GetStorageRangesMsg = 0x13
+	StorageRangesMsg    = 0x14
+	GetByteCodesMsg     = 0x15
+	ByteCodesMsg        = 0x16
+
 	// Protocol messages belonging to xdpos2/100
 	VoteMsg     = 0xe0
 	TimeoutMsg  = 0xe1
@@ -206,6 +216,44 @@ type newBlockData struct {
 	TD    *big.Int
 }
 
+// getAccountRangeData represents an account range query.
+type getAccountRangeData struct {
+	Root   common.Hash // Root of the account trie to serve
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the last account to retrieve
+	Bytes  uint64      // Soft limit at which to stop returning data
+}
+
+// accountRangeData is the network packet for account range distribution.
+type accountRangeData struct {
+	Keys   []common.Hash // Hashes of the accounts in the range, ascending
+	Values [][]byte      // RLP encoded accounts in the range
+	Proof  [][]byte      // Trie nodes proving the range edges
+}
+
+// getStorageRangesData represents a query of the leaves of a batch of tries,
+// either contract storage tries or XDCx trading and lending state tries.
+type getStorageRangesData struct {
+	Space  downloader.TrieSpace // Database the tries are served from
+	Roots  []common.Hash        // Roots of the tries to serve
+	Origin common.Hash          // Key of the first leaf to retrieve from the first trie
+	Bytes  uint64               // Soft limit at which to stop returning data
+}
+
+// storageRangesData is the network packet for trie ranges distribution. Only
+// the last range may be incomplete and carry a proof.
+type storageRangesData struct {
+	Keys   [][]common.Hash // Keys of the leaves of each trie, ascending
+	Values [][][]byte      // Values of the leaves of each trie
+	Proof  [][]byte        // Trie nodes proving the edges of the last range
+}
+
+// getByteCodesData represents a contract code query.
+type getByteCodesData struct {
+	Hashes []common.Hash // Code hashes to retrieve the codes of
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
 // blockBody represents the data content of a single block.
 type blockBody struct {
 	Transactions []*types.Transaction // Transactions contained within a block
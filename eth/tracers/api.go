@@ -36,6 +36,7 @@ import (
 	"github.com/XinFinOrg/XDPoSChain/core/state"
 	"github.com/XinFinOrg/XDPoSChain/core/types"
 	"github.com/XinFinOrg/XDPoSChain/core/vm"
+	"github.com/XinFinOrg/XDPoSChain/eth/tracers/balance"
 	"github.com/XinFinOrg/XDPoSChain/eth/tracers/logger"
 	"github.com/XinFinOrg/XDPoSChain/ethdb"
 	"github.com/XinFinOrg/XDPoSChain/internal/ethapi"
@@ -117,6 +118,34 @@ func (context *chainContext) GetHeader(hash common.Hash, number uint64) *types.H
 	return header
 }
 
+func (context *chainContext) Config() *params.ChainConfig {
+	return context.api.backend.ChainConfig()
+}
+
+func (context *chainContext) CurrentHeader() *types.Header {
+	header, _ := context.api.backend.HeaderByNumber(context.ctx, rpc.LatestBlockNumber)
+	return header
+}
+
+func (context *chainContext) GetHeaderByNumber(number uint64) *types.Header {
+	header, _ := context.api.backend.HeaderByNumber(context.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (context *chainContext) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, _ := context.api.backend.HeaderByHash(context.ctx, hash)
+	return header
+}
+
+func (context *chainContext) GetBlock(hash common.Hash, number uint64) *types.Block {
+	block, err := context.api.backend.BlockByNumber(context.ctx, rpc.BlockNumber(number))
+	if err == nil && block != nil && block.Hash() == hash {
+		return block
+	}
+	block, _ = context.api.backend.BlockByHash(context.ctx, hash)
+	return block
+}
+
 // chainContext represents the context reader which is used by the evm for reading
 // the necessary chain context.
 func (api *API) chainContext(ctx context.Context) core.ChainContext {
@@ -174,6 +203,10 @@ type TraceConfig struct {
 	// Config specific to given tracer. Note struct logger
 	// config are historically embedded in main object.
 	TracerConfig json.RawMessage
+	// BalanceChanges traces blocks with the balanceChangesTracer, appending
+	// the balance changes made outside of the transactions (XDCx settlements
+	// and rewards) as a last result without transaction.
+	BalanceChanges bool
 }
 
 // TraceCallConfig is the config for traceCall API. It holds one more
@@ -547,6 +580,12 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if err != nil {
 		return nil, err
 	}
+	var parentState *state.StateDB
+	if config != nil && config.BalanceChanges {
+		tracer := balanceChangesTracer
+		config = &TraceConfig{Tracer: &tracer, Timeout: config.Timeout, Reexec: config.Reexec, BalanceChanges: true}
+		parentState = statedb.Copy()
+	}
 	// Execute all the transaction contained within the block concurrently
 	var (
 		signer  = types.MakeSigner(api.backend.ChainConfig(), block.Number())
@@ -629,6 +668,13 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if failed != nil {
 		return nil, failed
 	}
+	if parentState != nil {
+		changes, err := api.blockBalanceChanges(ctx, block, parent, statedb, parentState)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &txTraceResult{Result: changes})
+	}
 	return results, nil
 }
 
@@ -762,9 +808,13 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	// Call SetTxContext to clear out the statedb access list
 	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
 	owner := common.Address{}
-	if _, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()), owner); err != nil {
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()), owner)
+	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
+	if tracer, ok := tracer.(BalanceChangeTracer); ok {
+		balance.CaptureGasFee(tracer, api.backend.ChainConfig(), message, result.UsedGas, vmctx, statedb)
+	}
 	return tracer.GetResult()
 }
 
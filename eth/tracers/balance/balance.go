@@ -0,0 +1,207 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package balance collects the balance changes of executed messages, both the
+// value transfers made by the EVM and the fees XDC settles outside of it. It's
+// shared by the balanceChangesTracer and eth_simulateV1.
+package balance
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/vm"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+// Reason is the cause of a balance change.
+type Reason string
+
+const (
+	Transfer         Reason = "transfer"         // Value moved by a call, create or selfdestruct
+	Gas              Reason = "gas"              // Gas bought by the sender or paid to the block's fee recipient
+	TRC21Fee         Reason = "trc21Fee"         // Gas sponsored by a TRC21 token, paid by the issuer contract
+	MasternodeReward Reason = "masternodeReward" // Reward share of a masternode owner
+	VoterReward      Reason = "voterReward"      // Reward share of a masternode voter
+	FoundationReward Reason = "foundationReward" // Reward share of the foundation wallet
+	XDCxSettlement   Reason = "xdcxSettlement"   // Settlement of XDCx trades and lending
+)
+
+// Change is a single change of an account balance along with its cause.
+type Change struct {
+	Address common.Address
+	Delta   *big.Int
+	Reason  Reason
+}
+
+type changeJSON struct {
+	Address common.Address `json:"address"`
+	Delta   string         `json:"delta"`
+	Reason  Reason         `json:"reason"`
+}
+
+// MarshalJSON encodes the change with its delta as a signed hex number.
+func (c Change) MarshalJSON() ([]byte, error) {
+	return json.Marshal(changeJSON{
+		Address: c.Address,
+		Delta:   hexutil.EncodeBig(c.Delta),
+		Reason:  c.Reason,
+	})
+}
+
+// UnmarshalJSON decodes a change encoded by MarshalJSON.
+func (c *Change) UnmarshalJSON(input []byte) error {
+	var dec changeJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	delta, err := hexutil.DecodeBig(strings.TrimPrefix(dec.Delta, "-"))
+	if err != nil {
+		return fmt.Errorf("invalid balance delta %q: %w", dec.Delta, err)
+	}
+	if strings.HasPrefix(dec.Delta, "-") {
+		delta.Neg(delta)
+	}
+	c.Address, c.Delta, c.Reason = dec.Address, delta, dec.Reason
+	return nil
+}
+
+// Tracer is a vm.EVMLogger collecting the value transfers of the call frames
+// which succeeded, along with the balance changes reported to it by the caller
+// for what's settled outside of the EVM.
+type Tracer struct {
+	frames  [][]Change // Transfers of the call frames still executing
+	changes []Change
+}
+
+// NewTracer creates a tracer with no balance change recorded yet.
+func NewTracer() *Tracer {
+	return &Tracer{changes: []Change{}}
+}
+
+// Changes returns the balance changes recorded so far, in execution order.
+func (t *Tracer) Changes() []Change {
+	return t.changes
+}
+
+// transfer returns the balance changes of moving value between two accounts.
+func transfer(from, to common.Address, value *big.Int) []Change {
+	if value == nil || value.Sign() == 0 || from == to {
+		return nil
+	}
+	return []Change{
+		{Address: from, Delta: new(big.Int).Neg(value), Reason: Transfer},
+		{Address: to, Delta: new(big.Int).Set(value), Reason: Transfer},
+	}
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *Tracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = [][]Change{transfer(from, to, value)}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *Tracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	if err == nil && len(t.frames) > 0 {
+		t.changes = append(t.changes, t.frames[0]...)
+	}
+	t.frames = nil
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *Tracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *Tracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *Tracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	// Delegated calls move no value, even if they report the one of their caller
+	if typ == vm.DELEGATECALL || typ == vm.STATICCALL {
+		value = nil
+	}
+	t.frames = append(t.frames, transfer(from, to, value))
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *Tracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	size := len(t.frames)
+	if size <= 1 {
+		return
+	}
+	frame := t.frames[size-1]
+	t.frames = t.frames[:size-1]
+
+	// A failed frame reverts its transfers and the ones of its subcalls
+	if err == nil {
+		t.frames[size-2] = append(t.frames[size-2], frame...)
+	}
+}
+
+func (*Tracer) CaptureTxStart(gasLimit uint64) {}
+
+func (*Tracer) CaptureTxEnd(restGas uint64) {}
+
+// CaptureBalanceChange records a balance change made outside of the EVM.
+func (t *Tracer) CaptureBalanceChange(addr common.Address, delta *big.Int, reason Reason) {
+	if delta.Sign() == 0 {
+		return
+	}
+	t.changes = append(t.changes, Change{Address: addr, Delta: new(big.Int).Set(delta), Reason: reason})
+}
+
+// ChangeRecorder is implemented by the tracers accounting for the balance
+// changes made outside of the EVM.
+type ChangeRecorder interface {
+	CaptureBalanceChange(addr common.Address, delta *big.Int, reason Reason)
+}
+
+// CaptureGasFee reports the fee of an executed message to a recorder, the way
+// the state transition and the block processor settle it: the sender buys the
+// gas unless a TRC21 token sponsors it, and the fee is paid to the owner of
+// the block signer.
+func CaptureGasFee(recorder ChangeRecorder, config *params.ChainConfig, msg core.Message, usedGas uint64, vmctx vm.BlockContext, statedb *state.StateDB) {
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(usedGas), msg.GasPrice())
+	if msg.BalanceTokenFee() == nil {
+		recorder.CaptureBalanceChange(msg.From(), new(big.Int).Neg(fee), Gas)
+	} else {
+		tokenFee := common.GetGasFee(vmctx.BlockNumber.Uint64(), usedGas)
+		recorder.CaptureBalanceChange(common.TRC21IssuerSMC, tokenFee.Neg(tokenFee), TRC21Fee)
+	}
+	if vmctx.BlockNumber.Cmp(common.TIPTRC21Fee) > 0 {
+		if owner := statedb.GetOwner(vmctx.Coinbase); owner != (common.Address{}) {
+			recorder.CaptureBalanceChange(owner, fee, Gas)
+		}
+		return
+	}
+	effectiveTip := msg.GasPrice()
+	if config.IsEIP1559(vmctx.BlockNumber) {
+		effectiveTip = new(big.Int).Sub(msg.GasFeeCap(), vmctx.BaseFee)
+		if effectiveTip.Cmp(msg.GasTipCap()) > 0 {
+			effectiveTip = msg.GasTipCap()
+		}
+	}
+	recorder.CaptureBalanceChange(vmctx.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(usedGas), effectiveTip), Gas)
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"sort"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/XDCxlending/lendingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/eth/tracers/balance"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+// balanceChangesTracer is the name of the native tracer reporting balance changes.
+const balanceChangesTracer = "balanceChangesTracer"
+
+// BalanceChangeReason is the cause of a balance change.
+type BalanceChangeReason = balance.Reason
+
+const (
+	BalanceChangeTransfer         = balance.Transfer
+	BalanceChangeGas              = balance.Gas
+	BalanceChangeTRC21Fee         = balance.TRC21Fee
+	BalanceChangeMasternodeReward = balance.MasternodeReward
+	BalanceChangeVoterReward      = balance.VoterReward
+	BalanceChangeFoundationReward = balance.FoundationReward
+	BalanceChangeXDCxSettlement   = balance.XDCxSettlement
+)
+
+// BalanceChange is a single change of an account balance along with its cause.
+type BalanceChange = balance.Change
+
+// BalanceChangeTracer is a tracer which also accounts for the balance changes
+// XDC applies outside of the EVM.
+type BalanceChangeTracer interface {
+	Tracer
+	balance.ChangeRecorder
+}
+
+// blockBalanceChanges returns the balance changes a block applies outside of
+// its transactions: the XDCx settlements made before them and the rewards
+// credited after them. The statedb is the state after the transactions, the
+// parentState the one before.
+func (api *API) blockBalanceChanges(ctx context.Context, block *types.Block, parent *types.Block, statedb *state.StateDB, parentState *state.StateDB) ([]BalanceChange, error) {
+	engine, ok := api.backend.Engine().(*XDPoS.XDPoS)
+	if !ok {
+		return []BalanceChange{}, nil
+	}
+	changes, err := api.settlementBalanceChanges(ctx, engine, block, parent, parentState)
+	if err != nil {
+		return nil, err
+	}
+	rewards, err := engine.ApplyRewards(&chainContext{api: api, ctx: ctx}, types.CopyHeader(block.Header()), statedb, parentState)
+	if err != nil {
+		return nil, err
+	}
+	if rewards == nil {
+		return changes, nil
+	}
+	rewardChanges, err := rewardBalanceChanges(api.backend.ChainConfig(), rewards, parentState)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, rewardChanges...), nil
+}
+
+// rewardBalanceChanges splits the rewards recorded by the reward hook into the
+// shares of the masternode owners, their voters and the foundation wallet.
+func rewardBalanceChanges(config *params.ChainConfig, rewards map[string]interface{}, parentState *state.StateDB) ([]BalanceChange, error) {
+	// The recorded rewards are the ones served by eth_getRewardByHash, decode
+	// them the same way: role -> signer -> holder -> amount
+	blob, err := json.Marshal(rewards)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]map[common.Address]json.RawMessage
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		return nil, err
+	}
+	var (
+		changes    = []BalanceChange{}
+		foundation = config.XDPoS.FoudationWalletAddr
+	)
+	for _, role := range []struct{ signers, rewards string }{
+		{"signers", "rewards"},
+		{"signersProtector", "rewardsProtector"},
+		{"signersObserver", "rewardsObserver"},
+	} {
+		for _, signer := range sortedAddresses(decoded[role.rewards]) {
+			var holders map[common.Address]*big.Int
+			if err := json.Unmarshal(decoded[role.rewards][signer], &holders); err != nil {
+				return nil, err
+			}
+			var signerLog struct {
+				Reward *big.Int `json:"reward"`
+			}
+			if blob, ok := decoded[role.signers][signer]; ok {
+				if err := json.Unmarshal(blob, &signerLog); err != nil {
+					return nil, err
+				}
+			}
+			if signerLog.Reward == nil {
+				signerLog.Reward = new(big.Int)
+			}
+			// Split the reward of the signer with the percentages of contracts.GetRewardBalancesRate,
+			// the owner and the foundation may vote for the masternode too
+			var (
+				owner           = state.GetCandidateOwner(parentState, signer)
+				masternodeShare = new(big.Int).Div(new(big.Int).Mul(signerLog.Reward, big.NewInt(common.RewardMasterPercent)), big.NewInt(100))
+				foundationShare = new(big.Int).Div(new(big.Int).Mul(signerLog.Reward, big.NewInt(common.RewardFoundationPercent)), big.NewInt(100))
+			)
+			for _, holder := range sortedAddresses(holders) {
+				remaining := new(big.Int).Set(holders[holder])
+				if holder == owner {
+					share := bigMin(masternodeShare, remaining)
+					changes = appendBalanceChange(changes, holder, share, BalanceChangeMasternodeReward)
+					remaining.Sub(remaining, share)
+				}
+				if holder == foundation {
+					share := bigMin(foundationShare, remaining)
+					changes = appendBalanceChange(changes, holder, share, BalanceChangeFoundationReward)
+					remaining.Sub(remaining, share)
+				}
+				changes = appendBalanceChange(changes, holder, remaining, BalanceChangeVoterReward)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// settlementBalanceChanges replays the XDCx trading and lending settlements of
+// the block on a copy of the parent state, the same way the block importer does
+// before running the transactions, and returns the balance changes of the
+// traders and relayer owners involved.
+func (api *API) settlementBalanceChanges(ctx context.Context, engine *XDPoS.XDPoS, block *types.Block, parent *types.Block, parentState *state.StateDB) ([]BalanceChange, error) {
+	config := api.backend.ChainConfig()
+	if !config.IsTIPXDCXReceiver(block.Number()) || block.NumberU64() <= config.XDPoS.Epoch {
+		return []BalanceChange{}, nil
+	}
+	tradingService, lendingService := engine.GetXDCXService(), engine.GetLendingService()
+	if tradingService == nil || lendingService == nil {
+		return []BalanceChange{}, nil
+	}
+	if isEpochSwitch, _, err := engine.IsEpochSwitch(block.Header()); err != nil || isEpochSwitch {
+		// Epoch switch blocks only update the medium prices
+		return []BalanceChange{}, err
+	}
+	author, err := engine.Author(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	parentAuthor, err := engine.Author(parent.Header())
+	if err != nil {
+		return nil, err
+	}
+	tradingState, err := tradingService.GetTradingState(parent, parentAuthor)
+	if err != nil {
+		return nil, err
+	}
+	lendingState, err := lendingService.GetLendingState(parent, parentAuthor)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		chain    = &chainContext{api: api, ctx: ctx}
+		header   = block.Header()
+		statedb  = parentState.Copy()
+		involved = make(map[common.Address]struct{})
+		relayers = make(map[common.Address]struct{})
+	)
+	tradingBatches, err := core.ExtractTradingTransactions(block.Transactions())
+	if err != nil {
+		return nil, err
+	}
+	addTrades := func(trades []map[string]string) {
+		for _, trade := range trades {
+			involved[common.HexToAddress(trade[tradingstate.TradeMaker])] = struct{}{}
+			relayers[common.HexToAddress(trade[tradingstate.TradeMakerExchange])] = struct{}{}
+		}
+	}
+	for _, batch := range tradingBatches {
+		for _, match := range batch.Data {
+			order, err := match.DecodeOrder()
+			if err != nil {
+				continue // skipped by the block importer too
+			}
+			trades, _, err := tradingService.ApplyOrder(header, author, chain, statedb, tradingState, tradingstate.GetTradingOrderBookHash(order.BaseToken, order.QuoteToken), order)
+			if err != nil {
+				return nil, err
+			}
+			involved[order.UserAddress], relayers[order.ExchangeAddress] = struct{}{}, struct{}{}
+			addTrades(trades)
+		}
+	}
+	addLendingTrades := func(trades []*lendingstate.LendingTrade) {
+		for _, trade := range trades {
+			involved[trade.Borrower], involved[trade.Investor] = struct{}{}, struct{}{}
+			relayers[trade.BorrowingRelayer], relayers[trade.InvestingRelayer] = struct{}{}, struct{}{}
+		}
+	}
+	lendingBatches, err := core.ExtractLendingTransactions(block.Transactions())
+	if err != nil {
+		return nil, err
+	}
+	for _, batch := range lendingBatches {
+		for _, item := range batch.Data {
+			trades, _, err := lendingService.ApplyOrder(header, author, chain, statedb, lendingState, tradingState, lendingstate.GetLendingOrderBookHash(item.LendingToken, item.Term), item)
+			if err != nil {
+				return nil, err
+			}
+			involved[item.UserAddress], relayers[item.Relayer] = struct{}{}, struct{}{}
+			addLendingTrades(trades)
+		}
+	}
+	settled, _, fills, err := lendingService.ProcessLiquidationAuctions(header, chain, statedb, tradingState, lendingState)
+	if err != nil {
+		return nil, err
+	}
+	for _, trade := range settled {
+		addLendingTrades([]*lendingstate.LendingTrade{trade})
+	}
+	for _, fill := range fills {
+		involved[fill.Maker] = struct{}{}
+	}
+	if block.NumberU64()%config.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
+		updated, liquidated, autoRepaid, autoToppedUp, autoRecalled, err := lendingService.ProcessLiquidationData(header, chain, statedb, tradingState, lendingState)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range updated {
+			addLendingTrades([]*lendingstate.LendingTrade{trade})
+		}
+		addLendingTrades(liquidated)
+		addLendingTrades(autoRepaid)
+		addLendingTrades(autoToppedUp)
+		addLendingTrades(autoRecalled)
+	}
+	addTrades(tradingService.ActivateStopOrders(header, author, chain, statedb, tradingState).Trades)
+	// Relayers collect their fees in the balance of their owners
+	for relayer := range relayers {
+		involved[tradingstate.GetRelayerOwner(relayer, parentState)] = struct{}{}
+	}
+	changes := []BalanceChange{}
+	for _, addr := range sortedAddresses(involved) {
+		delta := new(big.Int).Sub(statedb.GetBalance(addr), parentState.GetBalance(addr))
+		changes = appendBalanceChange(changes, addr, delta, BalanceChangeXDCxSettlement)
+	}
+	return changes, nil
+}
+
+// appendBalanceChange appends a change to the list, unless it's empty.
+func appendBalanceChange(changes []BalanceChange, addr common.Address, delta *big.Int, reason BalanceChangeReason) []BalanceChange {
+	if delta.Sign() == 0 {
+		return changes
+	}
+	return append(changes, BalanceChange{Address: addr, Delta: new(big.Int).Set(delta), Reason: reason})
+}
+
+// sortedAddresses returns the keys of an address keyed map in ascending order.
+func sortedAddresses[V any](m map[common.Address]V) []common.Address {
+	addrs := make([]common.Address, 0, len(m))
+	for addr := range m {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
+func bigMin(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+// Tests that balance changes encode their deltas as signed hex numbers.
+func TestBalanceChangeJSON(t *testing.T) {
+	changes := []BalanceChange{
+		{Address: common.Address{1}, Delta: big.NewInt(-255), Reason: BalanceChangeGas},
+		{Address: common.Address{2}, Delta: big.NewInt(255), Reason: BalanceChangeTRC21Fee},
+	}
+	blob, err := json.Marshal(changes)
+	if err != nil {
+		t.Fatalf("failed to encode changes: %v", err)
+	}
+	want := `[{"address":"0x0100000000000000000000000000000000000000","delta":"-0xff","reason":"gas"},` +
+		`{"address":"0x0200000000000000000000000000000000000000","delta":"0xff","reason":"trc21Fee"}]`
+	if string(blob) != want {
+		t.Fatalf("encoding mismatch:\nhave %s\nwant %s", blob, want)
+	}
+	var decoded []BalanceChange
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode changes: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, changes) {
+		t.Fatalf("decoded changes mismatch: have %v, want %v", decoded, changes)
+	}
+}
+
+// Tests that the rewards of a signer are split into the shares of its owner,
+// its voters and the foundation, even if the owner and the foundation vote.
+func TestRewardBalanceChanges(t *testing.T) {
+	var (
+		signer     = common.HexToAddress("0x1000")
+		owner      = common.HexToAddress("0x2000")
+		voter      = common.HexToAddress("0x3000")
+		foundation = common.HexToAddress("0x4000")
+		config     = &params.ChainConfig{XDPoS: &params.XDPoSConfig{FoudationWalletAddr: foundation}}
+	)
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	ownerSlot := state.GetLocMappingAtKey(signer.Hash(), 1) // validatorsState[signer].owner
+	statedb.SetState(common.MasternodeVotingSMCBinary, common.BigToHash(ownerSlot), common.BytesToHash(owner.Bytes()))
+
+	rewards := map[string]interface{}{
+		"signers": map[common.Address]interface{}{
+			signer: map[string]interface{}{"sign": 3, "reward": big.NewInt(1000)},
+		},
+		"rewards": map[common.Address]interface{}{
+			signer: map[common.Address]*big.Int{
+				owner:      big.NewInt(900 + 7),
+				voter:      big.NewInt(11),
+				foundation: big.NewInt(100 + 5),
+			},
+		},
+	}
+	have, err := rewardBalanceChanges(config, rewards, statedb)
+	if err != nil {
+		t.Fatalf("failed to split rewards: %v", err)
+	}
+	want := []BalanceChange{
+		{Address: owner, Delta: big.NewInt(900), Reason: BalanceChangeMasternodeReward},
+		{Address: owner, Delta: big.NewInt(7), Reason: BalanceChangeVoterReward},
+		{Address: voter, Delta: big.NewInt(11), Reason: BalanceChangeVoterReward},
+		{Address: foundation, Delta: big.NewInt(100), Reason: BalanceChangeFoundationReward},
+		{Address: foundation, Delta: big.NewInt(5), Reason: BalanceChangeVoterReward},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("reward changes mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/core/vm"
+	"github.com/XinFinOrg/XDPoSChain/eth/tracers"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/XinFinOrg/XDPoSChain/tests"
+)
+
+// callWithValue returns the code calling the given address with some value,
+// discarding the result of the call.
+func callWithValue(to common.Address, value byte) []byte {
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), value, byte(vm.PUSH20),
+	}
+	code = append(code, to.Bytes()...)
+	return append(code, byte(vm.PUSH2), 0xff, 0xff, byte(vm.CALL), byte(vm.POP))
+}
+
+// Tests that the balanceChanges tracer reports the value transfers of a
+// transaction, leaving out the ones of the reverted call frames.
+func TestBalanceChangesTracer(t *testing.T) {
+	var (
+		origin   = common.HexToAddress("0x1000")
+		caller   = common.HexToAddress("0x2000")
+		receiver = common.HexToAddress("0x3000")
+		reverter = common.HexToAddress("0x4000")
+	)
+	code := append(callWithValue(receiver, 1), callWithValue(reverter, 2)...)
+	alloc := types.GenesisAlloc{
+		origin:   {Balance: big.NewInt(params.Ether)},
+		caller:   {Code: append(code, byte(vm.STOP))},
+		receiver: {Balance: big.NewInt(0)},
+		reverter: {Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)}},
+	}
+	var (
+		statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), alloc)
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: big.NewInt(1),
+			Difficulty:  big.NewInt(1),
+			GasLimit:    10000000,
+		}
+		msg = types.NewMessage(origin, &caller, 0, big.NewInt(10), 1000000, big.NewInt(1), big.NewInt(1), big.NewInt(1), nil, nil, false, nil, nil)
+	)
+	tracer, err := tracers.New("balanceChangesTracer", new(tracers.Context), nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, core.NewEVMTxContext(msg), statedb, nil, params.AllEthashProtocolChanges, vm.Config{Tracer: tracer})
+	if _, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()), common.Address{}); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	// Fees are settled outside of the EVM, reported by the tracing API
+	tracer.(tracers.BalanceChangeTracer).CaptureBalanceChange(origin, big.NewInt(-21000), tracers.BalanceChangeGas)
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var have []tracers.BalanceChange
+	if err := json.Unmarshal(res, &have); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	want := []tracers.BalanceChange{
+		{Address: origin, Delta: big.NewInt(-10), Reason: tracers.BalanceChangeTransfer},
+		{Address: caller, Delta: big.NewInt(10), Reason: tracers.BalanceChangeTransfer},
+		{Address: caller, Delta: big.NewInt(-1), Reason: tracers.BalanceChangeTransfer},
+		{Address: receiver, Delta: big.NewInt(1), Reason: tracers.BalanceChangeTransfer},
+		{Address: origin, Delta: big.NewInt(-21000), Reason: tracers.BalanceChangeGas},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("balance changes mismatch:\nhave %s\nwant %+v", res, want)
+	}
+}
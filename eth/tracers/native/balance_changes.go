@@ -0,0 +1,90 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/core/vm"
+	"github.com/XinFinOrg/XDPoSChain/eth/tracers"
+	"github.com/XinFinOrg/XDPoSChain/eth/tracers/balance"
+)
+
+func init() {
+	register("balanceChangesTracer", newBalanceChangesTracer)
+}
+
+// balanceChangesTracer reports every balance change of a transaction with its
+// reason. Value transfers are collected from the EVM, keeping only the ones of
+// the call frames which succeeded, while the gas and TRC21 fees settled outside
+// of the EVM are reported by the tracing API.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "balanceChangesTracer"})
+//	[
+//	  {address: "0x71562b71999873db5b286df957af199ec94617f7", delta: "-0x1", reason: "transfer"},
+//	  {address: "0x2a65aca4d5fc5b5c859090a6c34d164135398226", delta: "0x1", reason: "transfer"},
+//	  {address: "0x71562b71999873db5b286df957af199ec94617f7", delta: "-0x5208", reason: "gas"},
+//	  {address: "0x487f2a9a0c2b8c1c9f6ad2c6e1a5f0f3c1d4a3b2", delta: "0x5208", reason: "gas"}
+//	]
+type balanceChangesTracer struct {
+	*balance.Tracer
+	env       *vm.EVM
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newBalanceChangesTracer returns a native go tracer which reports the balance
+// changes of a tx, and implements vm.EVMLogger.
+func newBalanceChangesTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &balanceChangesTracer{Tracer: balance.NewTracer()}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *balanceChangesTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.Tracer.CaptureStart(env, from, to, create, input, gas, value)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *balanceChangesTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.env.Cancel()
+		return
+	}
+	t.Tracer.CaptureEnter(typ, from, to, input, gas, value)
+}
+
+// GetResult returns the json-encoded list of balance changes, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *balanceChangesTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.Changes())
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *balanceChangesTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
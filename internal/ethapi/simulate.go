@@ -0,0 +1,378 @@
+// Copyright (c) 2018 XDPoSChain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/consensus/misc/eip1559"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/core/vm"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/eth/tracers/balance"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+)
+
+// maxSimulateBlocks is the maximum number of blocks a single eth_simulateV1
+// request may simulate.
+const maxSimulateBlocks = 256
+
+var (
+	errSimulateNoBlocks      = errors.New("empty input")
+	errSimulateTooManyBlocks = fmt.Errorf("too many blocks, at most %d", maxSimulateBlocks)
+	errSimulateGasExhausted  = errors.New("gas cap of the request exhausted")
+)
+
+// simBlock is a batch of calls to be simulated sequentially in one block,
+// on top of the given block and state overrides.
+type simBlock struct {
+	BlockOverrides *BlockOverrides
+	StateOverrides *StateOverride
+	Calls          []TransactionArgs
+}
+
+// simOpts are the inputs to eth_simulateV1.
+type simOpts struct {
+	BlockStateCalls []simBlock
+	BalanceChanges  bool // Report the balance changes of every call
+}
+
+// simCallError is the failure of a call which was executed but did not
+// succeed, e.g. a revert or an out of gas.
+type simCallError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    string `json:"data,omitempty"`
+}
+
+// simCallResult is the outcome of a single simulated call.
+type simCallResult struct {
+	ReturnValue    hexutil.Bytes    `json:"returnData"`
+	Logs           []*types.Log     `json:"logs"`
+	GasUsed        hexutil.Uint64   `json:"gasUsed"`
+	Status         hexutil.Uint64   `json:"status"`
+	Error          *simCallError    `json:"error,omitempty"`
+	BalanceChanges []balance.Change `json:"balanceChanges,omitempty"`
+}
+
+// simBlockResult is the outcome of a simulated block.
+type simBlockResult struct {
+	Number     hexutil.Uint64  `json:"number"`
+	Hash       common.Hash     `json:"hash"`
+	ParentHash common.Hash     `json:"parentHash"`
+	StateRoot  common.Hash     `json:"stateRoot"`
+	Timestamp  hexutil.Uint64  `json:"timestamp"`
+	GasLimit   hexutil.Uint64  `json:"gasLimit"`
+	GasUsed    hexutil.Uint64  `json:"gasUsed"`
+	Miner      common.Address  `json:"miner"`
+	BaseFee    *hexutil.Big    `json:"baseFeePerGas,omitempty"`
+	Calls      []simCallResult `json:"calls"`
+}
+
+// SimulateV1 executes ordered bundles of calls on top of the given block, each
+// bundle in its own simulated block. The state changes of a call are visible
+// to the calls after it, and nothing is persisted. The calls pay their gas as
+// transactions do, including the fees sponsored by TRC21 tokens. The RPC gas
+// cap and EVM timeout bound the request as a whole, not each of its calls.
+func (s *BlockChainAPI) SimulateV1(ctx context.Context, opts simOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]*simBlockResult, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errSimulateNoBlocks
+	}
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, errSimulateTooManyBlocks
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	statedb, base, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return nil, errors.New("nil header in SimulateV1")
+	}
+	// Setup context so it may be cancelled when the timeout is reached
+	var cancel context.CancelFunc
+	timeout := s.b.RPCEVMTimeout()
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	sim := &simulator{
+		b:       s.b,
+		config:  s.b.ChainConfig(),
+		state:   statedb,
+		headers: []*types.Header{base},
+		chain:   &simChainContext{ctx: ctx, b: s.b},
+		budget:  s.b.RPCGasCap(),
+		timeout: timeout,
+		tracing: opts.BalanceChanges,
+	}
+	if sim.budget == 0 {
+		sim.budget = math.MaxUint64
+	}
+	if sim.coinbase, err = s.b.Engine().Author(base); err != nil {
+		sim.coinbase = base.Coinbase
+	}
+	if XDCx := s.b.XDCxService(); XDCx != nil {
+		block, err := s.b.BlockByNumberOrHash(ctx, *blockNrOrHash)
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			if sim.XDCxState, err = XDCx.GetTradingState(block, sim.coinbase); err != nil {
+				return nil, err
+			}
+		}
+	}
+	results := make([]*simBlockResult, 0, len(opts.BlockStateCalls))
+	for i, block := range opts.BlockStateCalls {
+		result, err := sim.processBlock(ctx, &block)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// simulator holds the state shared by the blocks of an eth_simulateV1 request.
+type simulator struct {
+	b         Backend
+	config    *params.ChainConfig
+	state     *state.StateDB
+	XDCxState *tradingstate.TradingStateDB
+	headers   []*types.Header // Base header followed by the simulated ones
+	chain     *simChainContext
+	coinbase  common.Address
+	budget    uint64 // Gas left to the calls of all the remaining blocks
+	timeout   time.Duration
+	tracing   bool
+}
+
+// makeHeader derives the header of the next simulated block from the last one
+// and the block overrides.
+func (sim *simulator) makeHeader(overrides *BlockOverrides) (*types.Header, error) {
+	parent := sim.headers[len(sim.headers)-1]
+	header := overrides.MakeHeader(&types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   sim.coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	})
+	if header.Number.Cmp(parent.Number) <= 0 {
+		return nil, fmt.Errorf("block number %d not above parent %d", header.Number, parent.Number)
+	}
+	if header.Time <= parent.Time {
+		return nil, fmt.Errorf("block timestamp %d not above parent %d", header.Time, parent.Time)
+	}
+	header.BaseFee = eip1559.CalcBaseFee(sim.config, header)
+	return header, nil
+}
+
+// getHashFn resolves block hashes from the simulated blocks first, falling
+// back to the canonical ancestors of the base block.
+func (sim *simulator) getHashFn() vm.GetHashFunc {
+	chainHash := core.GetHashFn(sim.headers[0], sim.chain)
+	return func(n uint64) common.Hash {
+		for i := len(sim.headers) - 1; i >= 0; i-- {
+			if sim.headers[i].Number.Uint64() == n {
+				return sim.headers[i].Hash()
+			}
+		}
+		return chainHash(n)
+	}
+}
+
+// processBlock applies the overrides and executes the calls of a simulated
+// block, settling the TRC21 fees at the end as the state processor does.
+func (sim *simulator) processBlock(ctx context.Context, block *simBlock) (*simBlockResult, error) {
+	header, err := sim.makeHeader(block.BlockOverrides)
+	if err != nil {
+		return nil, err
+	}
+	if err := block.StateOverrides.Apply(sim.state); err != nil {
+		return nil, err
+	}
+	blockCtx := core.NewEVMBlockContext(header, sim.chain, &header.Coinbase)
+	blockCtx.GetHash = sim.getHashFn()
+	block.BlockOverrides.Apply(&blockCtx)
+
+	var (
+		number        = header.Number.Uint64()
+		owner         = sim.state.GetOwner(header.Coinbase)
+		feeCapacity   = state.GetTRC21FeeCapacityFromState(sim.state)
+		balanceUpdate = make(map[common.Address]*big.Int)
+		totalFeeUsed  = new(big.Int)
+		gp            = new(core.GasPool).AddGas(header.GasLimit)
+		calls         = make([]simCallResult, 0, len(block.Calls))
+		txHashes      = make([]common.Hash, 0, len(block.Calls))
+	)
+	for i, args := range block.Calls {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", sim.timeout)
+		}
+		if sim.budget == 0 {
+			return nil, fmt.Errorf("call %d: %w", i, errSimulateGasExhausted)
+		}
+		gasGiven := args.Gas != nil
+		if !gasGiven {
+			remaining := hexutil.Uint64(min(gp.Gas(), sim.budget))
+			args.Gas = &remaining
+		}
+		msg, err := args.ToMessage(sim.b, header.Number, sim.budget, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		// Calls to a token holding a fee capacity are paid by the token issuer
+		var capacity *big.Int
+		if to := msg.To(); to != nil {
+			capacity = feeCapacity[*to]
+		}
+		if capacity != nil {
+			gas := msg.Gas()
+			if price := common.GetGasPrice(header.Number); !gasGiven && price.Sign() > 0 {
+				// Without an explicit limit, spend at most what the token can pay
+				if affordable := new(big.Int).Div(capacity, price); affordable.IsUint64() && affordable.Uint64() < gas {
+					gas = affordable.Uint64()
+				}
+			}
+			msg = types.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), gas, msg.GasPrice(), msg.GasFeeCap(), msg.GasTipCap(), msg.Data(), msg.AccessList(), true, capacity, header.Number)
+		}
+		txHash := crypto.Keccak256Hash(header.Number.Bytes(), new(big.Int).SetUint64(uint64(i)).Bytes())
+		sim.state.SetTxContext(txHash, i)
+
+		var tracer *balance.Tracer
+		vmConfig := vm.Config{NoBaseFee: true}
+		if sim.tracing {
+			tracer = balance.NewTracer()
+			vmConfig.Tracer = tracer
+		}
+		evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), sim.state, sim.XDCxState, sim.config, vmConfig)
+		stop := context.AfterFunc(ctx, evm.Cancel)
+		result, err := core.ApplyMessage(evm, msg, gp, owner)
+		stop()
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", sim.timeout)
+		}
+		sim.budget -= result.UsedGas
+		if capacity != nil {
+			if result.Failed() {
+				state.PayFeeWithTRC21TxFail(sim.state, msg.From(), *msg.To())
+			}
+			fee := common.GetGasFee(number, result.UsedGas)
+			feeCapacity[*msg.To()] = new(big.Int).Sub(capacity, fee)
+			balanceUpdate[*msg.To()] = feeCapacity[*msg.To()]
+			totalFeeUsed.Add(totalFeeUsed, fee)
+		}
+		sim.state.Finalise(true)
+
+		logs := sim.state.GetLogs(txHash, number, common.Hash{})
+		if logs == nil {
+			logs = []*types.Log{}
+		}
+		call := simCallResult{
+			ReturnValue: result.Return(),
+			Logs:        logs,
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+			Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if result.Failed() {
+			call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if errors.Is(result.Err, vm.ErrExecutionReverted) {
+				revertErr := newRevertError(result)
+				call.Error = &simCallError{Message: revertErr.Error(), Code: revertErr.ErrorCode(), Data: revertErr.reason}
+			} else {
+				call.Error = &simCallError{Message: result.Err.Error(), Code: -32015}
+			}
+		}
+		if tracer != nil {
+			balance.CaptureGasFee(tracer, sim.config, msg, result.UsedGas, evm.Context, sim.state)
+			call.BalanceChanges = tracer.Changes()
+		}
+		calls = append(calls, call)
+		txHashes = append(txHashes, txHash)
+	}
+	state.UpdateTRC21Fee(sim.state, balanceUpdate, totalFeeUsed)
+
+	header.GasUsed = header.GasLimit - gp.Gas()
+	header.Root = sim.state.IntermediateRoot(sim.config.IsEIP158(header.Number))
+	sim.headers = append(sim.headers, header)
+
+	hash := header.Hash()
+	for i := range calls {
+		for _, l := range calls[i].Logs {
+			l.BlockHash = hash
+			l.TxHash = txHashes[i]
+		}
+	}
+	result := &simBlockResult{
+		Number:     hexutil.Uint64(number),
+		Hash:       hash,
+		ParentHash: header.ParentHash,
+		StateRoot:  header.Root,
+		Timestamp:  hexutil.Uint64(header.Time),
+		GasLimit:   hexutil.Uint64(header.GasLimit),
+		GasUsed:    hexutil.Uint64(header.GasUsed),
+		Miner:      header.Coinbase,
+		Calls:      calls,
+	}
+	if header.BaseFee != nil {
+		result.BaseFee = (*hexutil.Big)(header.BaseFee)
+	}
+	return result, nil
+}
+
+// simChainContext resolves the canonical headers below the simulated blocks.
+type simChainContext struct {
+	ctx context.Context
+	b   Backend
+}
+
+func (c *simChainContext) Engine() consensus.Engine {
+	return c.b.Engine()
+}
+
+func (c *simChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, err := c.b.HeaderByHash(c.ctx, hash)
+	if err != nil || header == nil || header.Number.Uint64() != number {
+		log.Debug("Failed to resolve simulated block ancestor", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	return header
+}
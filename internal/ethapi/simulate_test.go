@@ -0,0 +1,173 @@
+package ethapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/consensus/ethash"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+)
+
+// simBackend serves a fixed state on top of the mock backend.
+type simBackend struct {
+	*backendMock
+	state  *state.StateDB
+	gasCap uint64
+}
+
+func (b *simBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.state, b.current, nil
+}
+
+func (b *simBackend) Engine() consensus.Engine { return ethash.NewFaker() }
+
+func (b *simBackend) RPCGasCap() uint64 { return b.gasCap }
+
+func TestSimulateV1(t *testing.T) {
+	var (
+		sender   = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		receiver = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		logger   = common.HexToAddress("0x1000000000000000000000000000000000000003")
+		reverter = common.HexToAddress("0x1000000000000000000000000000000000000004")
+	)
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	b := &simBackend{backendMock: newBackendMock(), state: statedb}
+	api := NewBlockChainAPI(b, nil)
+
+	var (
+		balance   = (*hexutil.Big)(big.NewInt(1000))
+		value     = (*hexutil.Big)(big.NewInt(7))
+		logCode   = hexutil.Bytes(common.FromHex("0x60006000a000")) // LOG0(0, 0)
+		revertErr = hexutil.Bytes(common.FromHex("0x60006000fd"))   // REVERT(0, 0)
+		time      = (*hexutil.Big)(big.NewInt(600))
+	)
+	opts := simOpts{
+		BalanceChanges: true,
+		BlockStateCalls: []simBlock{
+			{
+				StateOverrides: &StateOverride{
+					sender:   OverrideAccount{Balance: &balance},
+					logger:   OverrideAccount{Code: &logCode},
+					reverter: OverrideAccount{Code: &revertErr},
+				},
+				Calls: []TransactionArgs{
+					{From: &sender, To: &receiver, Value: value},
+					{From: &sender, To: &logger},
+					{From: &sender, To: &reverter, Value: value},
+				},
+			},
+			{
+				BlockOverrides: &BlockOverrides{Time: time},
+				Calls: []TransactionArgs{
+					{From: &sender, To: &receiver, Value: value},
+				},
+			},
+		},
+	}
+	results, err := api.SimulateV1(context.Background(), opts, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("block count mismatch: have %d, want 2", len(results))
+	}
+	first, second := results[0], results[1]
+	if first.Number != 1101 || second.Number != 1102 {
+		t.Errorf("block numbers mismatch: have %d and %d, want 1101 and 1102", first.Number, second.Number)
+	}
+	if second.ParentHash != first.Hash {
+		t.Errorf("parent hash mismatch: have %x, want %x", second.ParentHash, first.Hash)
+	}
+	if first.Timestamp != 556 || second.Timestamp != 600 {
+		t.Errorf("timestamps mismatch: have %d and %d, want 556 and 600", first.Timestamp, second.Timestamp)
+	}
+	if len(first.Calls) != 3 || len(second.Calls) != 1 {
+		t.Fatalf("call count mismatch: have %d and %d, want 3 and 1", len(first.Calls), len(second.Calls))
+	}
+	// The plain transfer succeeds and reports its value moving
+	transfer := first.Calls[0]
+	if transfer.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) || transfer.Error != nil {
+		t.Errorf("transfer failed: status %d, error %v", transfer.Status, transfer.Error)
+	}
+	have, _ := json.Marshal(transfer.BalanceChanges)
+	want := `[{"address":"0x1000000000000000000000000000000000000001","delta":"-0x7","reason":"transfer"},{"address":"0x1000000000000000000000000000000000000002","delta":"0x7","reason":"transfer"}]`
+	if string(have) != want {
+		t.Errorf("balance changes mismatch:\nhave %s\nwant %s", have, want)
+	}
+	// The logging call reports its log, attached to the simulated block
+	if logs := first.Calls[1].Logs; len(logs) != 1 || logs[0].Address != logger || logs[0].BlockHash != first.Hash || logs[0].BlockNumber != 1101 {
+		t.Errorf("logs mismatch: %v", logs)
+	}
+	// The reverting call fails without moving any value
+	reverted := first.Calls[2]
+	if reverted.Status != hexutil.Uint64(types.ReceiptStatusFailed) || reverted.Error == nil || reverted.Error.Code != 3 {
+		t.Errorf("revert not reported: status %d, error %v", reverted.Status, reverted.Error)
+	}
+	if len(reverted.BalanceChanges) != 0 {
+		t.Errorf("reverted call moved value: %v", reverted.BalanceChanges)
+	}
+	// The state of the first block carries over to the second one
+	if bal := statedb.GetBalance(receiver); bal.Uint64() != 14 {
+		t.Errorf("receiver balance mismatch: have %d, want 14", bal)
+	}
+	if bal := statedb.GetBalance(sender); bal.Uint64() != 986 {
+		t.Errorf("sender balance mismatch: have %d, want 986", bal)
+	}
+}
+
+func TestSimulateV1InvalidBlocks(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	api := NewBlockChainAPI(&simBackend{backendMock: newBackendMock(), state: statedb}, nil)
+
+	if _, err := api.SimulateV1(context.Background(), simOpts{}, nil); err != errSimulateNoBlocks {
+		t.Errorf("empty input error mismatch: have %v, want %v", err, errSimulateNoBlocks)
+	}
+	past := (*hexutil.Big)(big.NewInt(1000))
+	opts := simOpts{BlockStateCalls: []simBlock{{BlockOverrides: &BlockOverrides{Number: past}}}}
+	if _, err := api.SimulateV1(context.Background(), opts, nil); err == nil {
+		t.Error("expected error for a block number below the base block")
+	}
+}
+
+// Tests that the RPC gas cap is shared by the calls of all the simulated blocks
+// instead of being granted to each of them.
+func TestSimulateV1GasCap(t *testing.T) {
+	var (
+		sender   = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		receiver = common.HexToAddress("0x1000000000000000000000000000000000000002")
+	)
+	simulate := func(gasCap uint64, blocks int) ([]*simBlockResult, error) {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()))
+		api := NewBlockChainAPI(&simBackend{backendMock: newBackendMock(), state: statedb, gasCap: gasCap}, nil)
+
+		opts := simOpts{}
+		for i := 0; i < blocks; i++ {
+			opts.BlockStateCalls = append(opts.BlockStateCalls, simBlock{Calls: []TransactionArgs{{From: &sender, To: &receiver}}})
+		}
+		return api.SimulateV1(context.Background(), opts, nil)
+	}
+	results, err := simulate(42000, 2)
+	if err != nil {
+		t.Fatalf("simulation within the gas cap failed: %v", err)
+	}
+	if used := results[0].GasUsed + results[1].GasUsed; used != 42000 {
+		t.Errorf("gas used mismatch: have %d, want 42000", used)
+	}
+	// Once the cap is spent, further calls are refused outright
+	if _, err := simulate(42000, 3); !errors.Is(err, errSimulateGasExhausted) {
+		t.Errorf("exhausted cap error mismatch: have %v, want %v", err, errSimulateGasExhausted)
+	}
+	// A call only gets what's left of the cap, which may not be enough
+	if _, err := simulate(50000, 3); err == nil || errors.Is(err, errSimulateGasExhausted) {
+		t.Errorf("expected intrinsic gas error for the call exceeding the gas cap, have %v", err)
+	}
+}
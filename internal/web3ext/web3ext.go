@@ -178,6 +178,24 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getRewardHistory',
+			call: 'XDPoS_getRewardHistory',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getForensicsReports',
+			call: 'XDPoS_getForensicsReports',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getFinalityProof',
+			call: 'XDPoS_getFinalityProof',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -536,6 +554,12 @@ web3._extend({
 			call: 'eth_getTransactionAndReceiptProof',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -583,6 +607,12 @@ web3._extend({
 			call: 'eth_getCurrentTotalMinted',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'simulateV1',
+			call: 'eth_simulateV1',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter],
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -889,6 +919,34 @@ web3._extend({
             call: 'XDCx_getLendingTradeById',
             params: 3
 		}),
+		new web3._extend.Method({
+            name: 'getOrderByHash',
+            call: 'XDCx_getOrderByHash',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'getOrdersByUser',
+            call: 'XDCx_getOrdersByUser',
+            params: 3,
+            inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+            name: 'getTradesByPair',
+            call: 'XDCx_getTradesByPair',
+            params: 5,
+            inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal, null]
+		}),
+		new web3._extend.Method({
+            name: 'getOrderHistory',
+            call: 'XDCx_getOrderHistory',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'getCandles',
+            call: 'XDCx_getCandles',
+            params: 4,
+            inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
 	]
 });
 `
@@ -1046,6 +1104,10 @@ web3._extend({
 			name: 'content',
 			getter: 'txpool_content'
 		}),
+		new web3._extend.Property({
+			name: 'specialContent',
+			getter: 'txpool_specialContent'
+		}),
 		new web3._extend.Property({
 			name: 'inspect',
 			getter: 'txpool_inspect'
@@ -0,0 +1,469 @@
+package light
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx"
+	"github.com/XinFinOrg/XDPoSChain/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDPoSChain/XDCxlending"
+	"github.com/XinFinOrg/XDPoSChain/accounts"
+	"github.com/XinFinOrg/XDPoSChain/accounts/abi/bind"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/common/math"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/bloombits"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/core/vm"
+	"github.com/XinFinOrg/XDPoSChain/eth/downloader"
+	"github.com/XinFinOrg/XDPoSChain/ethclient"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/event"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+)
+
+var (
+	errNotSupported   = errors.New("not supported in light mode")
+	errHeaderNotFound = errors.New("header not found")
+)
+
+// LightAPIBackend implements ethapi.Backend for light clients. Headers come
+// from the certified light chain, while bodies, receipts and state are
+// retrieved from the server and checked against them.
+type LightAPIBackend struct {
+	allowUnprotectedTxs bool
+	light               *LightClient
+}
+
+func (b *LightAPIBackend) ChainConfig() *params.ChainConfig {
+	return b.light.chainConfig
+}
+
+func (b *LightAPIBackend) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(b.light.chain.CurrentHeader())
+}
+
+func (b *LightAPIBackend) CurrentHeader() *types.Header {
+	return b.light.chain.CurrentHeader()
+}
+
+func (b *LightAPIBackend) SetHead(number uint64) {
+	b.light.chain.SetHead(number)
+}
+
+func (b *LightAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	// Pending and latest both resolve to the highest certified header
+	if blockNr == rpc.PendingBlockNumber || blockNr == rpc.LatestBlockNumber {
+		return b.light.chain.CurrentHeader(), nil
+	}
+	if blockNr == rpc.CommittedBlockNumber {
+		if header := b.light.chain.CommittedHeader(); header != nil {
+			return header, nil
+		}
+		return nil, errHeaderNotFound
+	}
+	header := b.light.chain.GetHeaderByNumber(uint64(blockNr))
+	if header == nil {
+		return nil, errors.New("header for number not found")
+	}
+	return header, nil
+}
+
+func (b *LightAPIBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.HeaderByNumber(ctx, blockNr)
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := b.light.chain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, errors.New("header for hash not found")
+		}
+		if blockNrOrHash.RequireCanonical && !b.isCanonical(header) {
+			return nil, errors.New("hash is not currently canonical")
+		}
+		return header, nil
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
+func (b *LightAPIBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.light.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *LightAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return b.blockOf(ctx, header)
+}
+
+func (b *LightAPIBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	header := b.light.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil
+	}
+	return b.blockOf(ctx, header)
+}
+
+func (b *LightAPIBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return b.blockOf(ctx, header)
+}
+
+func (b *LightAPIBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	return b.BlockByHash(ctx, blockHash)
+}
+
+// blockOf assembles the block of a certified header with its verified body.
+func (b *LightAPIBackend) blockOf(ctx context.Context, header *types.Header) (*types.Block, error) {
+	body, err := retrieveBody(ctx, b.light.client, b.light.chainDb, header)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, nil), nil
+}
+
+// GetBody returns body of a block. It does not resolve special block numbers.
+func (b *LightAPIBackend) GetBody(ctx context.Context, hash common.Hash, number rpc.BlockNumber) (*types.Body, error) {
+	if number < 0 || hash == (common.Hash{}) {
+		return nil, errors.New("invalid arguments; expect hash and no special block numbers")
+	}
+	header := b.light.chain.GetHeader(hash, uint64(number))
+	if header == nil {
+		return nil, errHeaderNotFound
+	}
+	return retrieveBody(ctx, b.light.client, b.light.chainDb, header)
+}
+
+func (b *LightAPIBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
+	return nil, nil
+}
+
+func (b *LightAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header == nil {
+		return nil, nil, errHeaderNotFound
+	}
+	statedb, err := state.New(header.Root, b.light.stateCache)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, header, nil
+}
+
+func (b *LightAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb, err := state.New(header.Root, b.light.stateCache)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, header, nil
+}
+
+func (b *LightAPIBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	header := b.light.chain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, nil
+	}
+	return retrieveReceipts(ctx, b.light.client, b.light.chainDb, b.light.chainConfig, header)
+}
+
+func (b *LightAPIBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	header := b.light.chain.GetHeader(hash, number)
+	if header == nil {
+		return nil, errHeaderNotFound
+	}
+	receipts, err := retrieveReceipts(ctx, b.light.client, b.light.chainDb, b.light.chainConfig, header)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([][]*types.Log, len(receipts))
+	for i, receipt := range receipts {
+		logs[i] = receipt.Logs
+	}
+	return logs, nil
+}
+
+// GetTd returns nil, the total difficulty is unknown without the headers
+// before the checkpoint.
+func (b *LightAPIBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
+	return nil
+}
+
+func (b *LightAPIBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, XDCxState *tradingstate.TradingStateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	if vmConfig == nil {
+		vmConfig = new(vm.Config)
+	}
+	state.SetBalance(msg.From(), math.MaxBig256)
+	txContext := core.NewEVMTxContext(msg)
+	context := core.NewEVMBlockContext(header, b.light.chain, nil)
+	return vm.NewEVM(context, txContext, state, XDCxState, b.light.chainConfig, *vmConfig), state.Error, nil
+}
+
+func (b *LightAPIBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.light.chain.SubscribeChainEvent(ch)
+}
+
+func (b *LightAPIBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.light.chain.SubscribeChainHeadEvent(ch)
+}
+
+func (b *LightAPIBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (b *LightAPIBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (b *LightAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (b *LightAPIBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// SendTx relays the transaction to the server, light clients have no pool.
+func (b *LightAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	return ethclient.NewClient(b.light.client).SendTransaction(ctx, signedTx)
+}
+
+func (b *LightAPIBackend) SendOrderTx(ctx context.Context, signedTx *types.OrderTransaction) error {
+	return errNotSupported
+}
+
+func (b *LightAPIBackend) SendLendingTx(ctx context.Context, signedTx *types.LendingTransaction) error {
+	return errNotSupported
+}
+
+func (b *LightAPIBackend) GetPoolTransactions() (types.Transactions, error) {
+	return nil, nil
+}
+
+func (b *LightAPIBackend) GetPoolTransaction(txHash common.Hash) *types.Transaction {
+	return nil
+}
+
+// GetPoolNonce returns the pending nonce known by the server. It is not
+// proven, but only serves to build new transactions of the account.
+func (b *LightAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	var nonce hexutil.Uint64
+	err := b.light.client.CallContext(ctx, &nonce, "eth_getTransactionCount", addr, "pending")
+	return uint64(nonce), err
+}
+
+func (b *LightAPIBackend) Stats() (pending int, queued int) {
+	return 0, 0
+}
+
+func (b *LightAPIBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return make(map[common.Address]types.Transactions), make(map[common.Address]types.Transactions)
+}
+
+func (b *LightAPIBackend) TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	return nil, nil
+}
+
+func (b *LightAPIBackend) TxPoolSpecialContent() map[common.Address]types.Transactions {
+	return make(map[common.Address]types.Transactions)
+}
+
+func (b *LightAPIBackend) OrderTxPoolContent() (map[common.Address]types.OrderTransactions, map[common.Address]types.OrderTransactions) {
+	return make(map[common.Address]types.OrderTransactions), make(map[common.Address]types.OrderTransactions)
+}
+
+func (b *LightAPIBackend) OrderStats() (pending int, queued int) {
+	return 0, 0
+}
+
+func (b *LightAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Downloader returns nil, light clients follow the server over RPC.
+func (b *LightAPIBackend) Downloader() *downloader.Downloader {
+	return nil
+}
+
+func (b *LightAPIBackend) ProtocolVersion() int {
+	return 0
+}
+
+func (b *LightAPIBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return ethclient.NewClient(b.light.client).SuggestGasTipCap(ctx)
+}
+
+func (b *LightAPIBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	return nil, nil, nil, nil, errNotSupported
+}
+
+func (b *LightAPIBackend) BlobBaseFee(ctx context.Context) *big.Int {
+	return new(big.Int)
+}
+
+func (b *LightAPIBackend) ChainDb() ethdb.Database {
+	return b.light.chainDb
+}
+
+func (b *LightAPIBackend) AccountManager() *accounts.Manager {
+	return b.light.accountManager
+}
+
+func (b *LightAPIBackend) UnprotectedAllowed() bool {
+	return b.allowUnprotectedTxs
+}
+
+func (b *LightAPIBackend) RPCGasCap() uint64 {
+	return b.light.config.RPCGasCap
+}
+
+func (b *LightAPIBackend) RPCEVMTimeout() time.Duration {
+	return b.light.config.RPCEVMTimeout
+}
+
+func (b *LightAPIBackend) RPCTxFeeCap() float64 {
+	return b.light.config.RPCTxFeeCap
+}
+
+// BloomStatus reports no indexed section, log filters scan the headers.
+func (b *LightAPIBackend) BloomStatus() (uint64, uint64) {
+	return params.BloomBitsBlocks, 0
+}
+
+func (b *LightAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+}
+
+func (b *LightAPIBackend) Engine() consensus.Engine {
+	return b.light.engine
+}
+
+func (b *LightAPIBackend) GetIPCClient() (bind.ContractBackend, error) {
+	return ethclient.NewClient(b.light.client), nil
+}
+
+func (b *LightAPIBackend) GetRewardByHash(hash common.Hash) map[string]map[string]map[string]*big.Int {
+	return make(map[string]map[string]map[string]*big.Int)
+}
+
+func (b *LightAPIBackend) GetVotersRewards(masternodeAddr common.Address) map[common.Address]*big.Int {
+	return nil
+}
+
+func (b *LightAPIBackend) GetVotersCap(checkpoint *big.Int, masterAddr common.Address, voters []common.Address) map[common.Address]*big.Int {
+	header := b.light.chain.GetHeaderByNumber(checkpoint.Uint64())
+	if header == nil {
+		return nil
+	}
+	statedb, err := state.New(header.Root, b.light.stateCache)
+	if err != nil {
+		return nil
+	}
+	votersCap := make(map[common.Address]*big.Int)
+	for _, voter := range voters {
+		votersCap[voter] = state.GetVoterCap(statedb, masterAddr, voter)
+	}
+	return votersCap
+}
+
+// GetEpochDuration returns the duration of the last complete epoch, or the
+// nominal one if the light chain does not reach back that far.
+func (b *LightAPIBackend) GetEpochDuration() *big.Int {
+	config := b.light.chainConfig.XDPoS
+	number := b.light.chain.CurrentHeader().Number.Uint64()
+	last := number - number%config.Epoch
+	if last >= config.Epoch {
+		current, previous := b.light.chain.GetHeaderByNumber(last), b.light.chain.GetHeaderByNumber(last-config.Epoch)
+		if current != nil && previous != nil {
+			return new(big.Int).SetUint64(current.Time - previous.Time)
+		}
+	}
+	return new(big.Int).SetUint64(config.Epoch * config.Period)
+}
+
+func (b *LightAPIBackend) GetMasternodesCap(checkpoint uint64) map[common.Address]*big.Int {
+	header := b.light.chain.GetHeaderByNumber(checkpoint)
+	if header == nil {
+		return nil
+	}
+	statedb, err := state.New(header.Root, b.light.stateCache)
+	if err != nil {
+		return nil
+	}
+	masternodesCap := make(map[common.Address]*big.Int)
+	for _, candidate := range state.GetCandidates(statedb) {
+		masternodesCap[candidate] = state.GetCandidateCap(statedb, candidate)
+	}
+	return masternodesCap
+}
+
+func (b *LightAPIBackend) GetBlocksHashCache(blockNr uint64) []common.Hash {
+	if header := b.light.chain.GetHeaderByNumber(blockNr); header != nil {
+		return []common.Hash{header.Hash()}
+	}
+	return nil
+}
+
+// AreTwoBlockSamePath reports whether both blocks are on the light chain, which
+// only holds the canonical headers.
+func (b *LightAPIBackend) AreTwoBlockSamePath(newBlock common.Hash, oldBlock common.Hash) bool {
+	newHeader, oldHeader := b.light.chain.GetHeaderByHash(newBlock), b.light.chain.GetHeaderByHash(oldBlock)
+	if newHeader == nil || oldHeader == nil {
+		return false
+	}
+	return b.isCanonical(newHeader) && b.isCanonical(oldHeader)
+}
+
+// isCanonical reports whether the header is still on the light chain.
+func (b *LightAPIBackend) isCanonical(header *types.Header) bool {
+	canonical := b.light.chain.GetHeaderByNumber(header.Number.Uint64())
+	return canonical != nil && canonical.Hash() == header.Hash()
+}
+
+func (b *LightAPIBackend) GetOrderNonce(address common.Hash) (uint64, error) {
+	return 0, errNotSupported
+}
+
+func (b *LightAPIBackend) XDCxService() *XDCx.XDCX {
+	return b.light.XDCX
+}
+
+func (b *LightAPIBackend) LendingService() *XDCxlending.Lending {
+	return b.light.Lending
+}
+
+// GetPeer returns the number of peers, the server being the only one.
+func (b *LightAPIBackend) GetPeer() int {
+	return 1
+}
@@ -0,0 +1,222 @@
+// Package light implements a light client of the XDPoS v2 chain.
+//
+// The client follows the headers of a full node over RPC from a trusted epoch
+// switch checkpoint. Every header is accepted only once a QC carried by its
+// child proves it was certified by the masternodes of its epoch, and the
+// masternodes are handed over at each epoch switch header. Bodies, receipts
+// and state are retrieved from the full node on demand and checked against
+// the certified headers before being served through a light ethapi.Backend.
+package light
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/XinFinOrg/XDPoSChain/XDCx"
+	"github.com/XinFinOrg/XDPoSChain/XDCxlending"
+	"github.com/XinFinOrg/XDPoSChain/accounts"
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/eth/ethconfig"
+	"github.com/XinFinOrg/XDPoSChain/eth/filters"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/internal/ethapi"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/node"
+	"github.com/XinFinOrg/XDPoSChain/p2p"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+)
+
+// checkpointKey tracks the trusted checkpoint the stored light chain starts
+// from, so that restarts need no checkpoint flag.
+var checkpointKey = []byte("LightCheckpoint")
+
+// LightClient implements the XDC light client service.
+type LightClient struct {
+	config         *ethconfig.Config
+	chainConfig    *params.ChainConfig
+	chainDb        ethdb.Database // Proof-backed chain database
+	stateCache     state.Database
+	accountManager *accounts.Manager
+	engine         consensus.Engine
+	networkId      uint64
+	p2pServer      *p2p.Server
+
+	client *rpc.Client
+	chain  *LightChain
+	syncer *syncer
+
+	XDCX    *XDCx.XDCX
+	Lending *XDCxlending.Lending
+
+	ApiBackend *LightAPIBackend
+}
+
+// New creates a light client following the full node configured as server.
+func New(stack *node.Node, config *ethconfig.Config, XDCXServ *XDCx.XDCX, lendingServ *XDCxlending.Lending) (*LightClient, error) {
+	if config.LightServer == "" {
+		return nil, errors.New("light sync requires the RPC endpoint of a full node")
+	}
+	db, err := stack.OpenDatabase("lightchaindata", config.DatabaseCache, config.DatabaseHandles, "eth/db/lightchaindata/", false)
+	if err != nil {
+		return nil, err
+	}
+	chainConfig, _, genesisErr := core.SetupGenesisBlock(db, config.Genesis)
+	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
+		db.Close()
+		return nil, genesisErr
+	}
+	networkID := config.NetworkId
+	if networkID == 0 {
+		networkID = chainConfig.ChainId.Uint64()
+	}
+	common.CopyConstants(networkID)
+
+	client, err := rpc.Dial(config.LightServer)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to light server: %w", err)
+	}
+	lc := &LightClient{
+		config:         config,
+		chainConfig:    chainConfig,
+		chainDb:        newOdrDatabase(db, client),
+		accountManager: stack.AccountManager(),
+		networkId:      networkID,
+		p2pServer:      stack.Server(),
+		client:         client,
+		XDCX:           XDCXServ,
+		Lending:        lendingServ,
+	}
+	lc.stateCache = state.NewDatabase(lc.chainDb)
+	lc.engine = XDPoS.New(chainConfig, lc.chainDb)
+
+	checkpoint, err := lc.loadCheckpoint(config.LightCheckpoint)
+	if err != nil {
+		lc.close()
+		return nil, err
+	}
+	if lc.chain, err = NewLightChain(lc.chainDb, chainConfig, lc.engine, checkpoint, newCandidateChecker(lc.chainDb, chainConfig)); err != nil {
+		lc.close()
+		return nil, err
+	}
+	lc.syncer = newSyncer(lc.chain, client)
+	lc.ApiBackend = &LightAPIBackend{stack.Config().AllowUnprotectedTxs, lc}
+
+	stack.RegisterAPIs(lc.APIs())
+	stack.RegisterLifecycle(lc)
+	return lc, nil
+}
+
+// loadCheckpoint returns the trusted checkpoint header, retrieving it from the
+// server and checking it against the configured hash on first start.
+func (lc *LightClient) loadCheckpoint(hash common.Hash) (*types.Header, error) {
+	if stored, _ := lc.chainDb.Get(checkpointKey); len(stored) == common.HashLength {
+		if hash != (common.Hash{}) && hash != common.BytesToHash(stored) {
+			log.Warn("Ignoring checkpoint of an existing light chain", "configured", hash, "stored", common.BytesToHash(stored))
+		}
+		hash = common.BytesToHash(stored)
+		if number := rawdb.ReadHeaderNumber(lc.chainDb, hash); number != nil {
+			if header := rawdb.ReadHeader(lc.chainDb, hash, *number); header != nil {
+				return header, nil
+			}
+		}
+	}
+	if hash == (common.Hash{}) {
+		return nil, errNoCheckpoint
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var header *types.Header
+	if err := lc.client.CallContext(ctx, &header, "eth_getBlockByHash", hash, false); err != nil {
+		return nil, err
+	}
+	if header == nil || header.Hash() != hash {
+		return nil, errCheckpointMissing
+	}
+	if err := lc.chainDb.Put(checkpointKey, hash.Bytes()); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// APIs returns the collection of RPC services the light client offers.
+func (lc *LightClient) APIs() []rpc.API {
+	return append(ethapi.GetAPIs(lc.ApiBackend, lc.chain), []rpc.API{
+		{
+			Namespace: "eth",
+			Service:   filters.NewFilterAPI(filters.NewFilterSystem(lc.ApiBackend, filters.Config{LogCacheSize: lc.config.FilterLogCacheSize}), true),
+		}, {
+			Namespace: "net",
+			Service:   ethapi.NewNetAPI(lc.p2pServer, lc.networkId),
+		}, {
+			Namespace: "light",
+			Service:   NewLightAPI(lc),
+		},
+	}...)
+}
+
+// Chain returns the certified header chain.
+func (lc *LightClient) Chain() *LightChain { return lc.chain }
+
+// Start implements node.Lifecycle, starting the header sync.
+func (lc *LightClient) Start() error {
+	lc.syncer.wg.Add(1)
+	go lc.syncer.run()
+
+	log.Info("Started light client", "server", lc.config.LightServer, "checkpoint", lc.chain.Checkpoint())
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the header sync.
+func (lc *LightClient) Stop() error {
+	lc.syncer.stop()
+	lc.chain.Stop()
+	lc.close()
+
+	log.Info("Light client stopped")
+	return nil
+}
+
+func (lc *LightClient) close() {
+	lc.client.Close()
+	lc.chainDb.Close()
+}
+
+// LightAPI provides the status of the light client.
+type LightAPI struct {
+	lc *LightClient
+}
+
+// NewLightAPI creates a new light client API.
+func NewLightAPI(lc *LightClient) *LightAPI {
+	return &LightAPI{lc}
+}
+
+// Status returns the progress of the header sync and the committee the light
+// client currently verifies QCs against.
+func (api *LightAPI) Status() map[string]interface{} {
+	chain := api.lc.chain
+	head := chain.CurrentHeader()
+	status := map[string]interface{}{
+		"checkpoint":    chain.Checkpoint(),
+		"startingBlock": hexutil.Uint64(api.lc.syncer.start),
+		"currentBlock":  hexutil.Uint64(head.Number.Uint64()),
+		"currentHash":   head.Hash(),
+		"highestBlock":  hexutil.Uint64(api.lc.syncer.highest.Load()),
+		"masternodes":   chain.Masternodes(),
+	}
+	if committed := chain.CommittedHeader(); committed != nil {
+		status["committedBlock"] = hexutil.Uint64(committed.Number.Uint64())
+	}
+	return status
+}
@@ -0,0 +1,420 @@
+package light
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDPoSChain/core"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/event"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/params"
+)
+
+var (
+	errNoCheckpoint      = errors.New("no trusted checkpoint")
+	errNotEpochSwitch    = errors.New("checkpoint is not an epoch switch header")
+	errNonContiguous     = errors.New("non contiguous headers")
+	errMissingCertifier  = errors.New("no header carrying the QC of the last header")
+	errCheckpointMissing = errors.New("checkpoint header not found")
+	errMissingGap        = errors.New("gap block of the epoch not found")
+	errUnlinkedAncestor  = errors.New("ancestor does not lead to the checkpoint")
+	errCommitteeMismatch = errors.New("masternodes do not match the expected committee")
+)
+
+// CommitteeChecker validates the committee newly recorded by an epoch switch
+// header against the state of the gap block of the previous epoch.
+type CommitteeChecker func(gap, header *types.Header, committee *utils.Committee) error
+
+// LightChain is a chain of headers following the XDPoS v2 consensus from a
+// trusted checkpoint. A header is only added to the chain once it is certified
+// by a QC of its committee, carried by its child header.
+//
+// The masternodes of the first epoch are trusted along with the checkpoint.
+// Later committees are taken from the epoch switch headers, which extend the
+// certified chain, and are checked by the CommitteeChecker if any against
+// their gap block. As the gap block of an epoch may precede the checkpoint, the
+// headers down to the lowest such gap block are stored as well, authenticated
+// by the parent hashes leading to the checkpoint.
+type LightChain struct {
+	config  *params.ChainConfig
+	db      ethdb.Database
+	engine  consensus.Engine
+	checker CommitteeChecker
+
+	checkpoint common.Hash // Trusted epoch switch header the chain starts from
+
+	mu        sync.RWMutex
+	head      *types.Header
+	committee *utils.Committee // Committee of the epoch of the head
+
+	chainFeed     event.Feed
+	chainHeadFeed event.Feed
+	scope         event.SubscriptionScope
+}
+
+// NewLightChain opens the header chain stored in the database, or starts it
+// from the given checkpoint header if the database is empty.
+func NewLightChain(db ethdb.Database, config *params.ChainConfig, engine consensus.Engine, checkpoint *types.Header, checker CommitteeChecker) (*LightChain, error) {
+	if config.XDPoS == nil || config.XDPoS.V2 == nil || config.XDPoS.V2.SwitchBlock == nil {
+		return nil, errors.New("light client requires an XDPoS v2 chain")
+	}
+	if checkpoint == nil {
+		return nil, errNoCheckpoint
+	}
+	lc := &LightChain{
+		config:     config,
+		db:         db,
+		engine:     engine,
+		checker:    checker,
+		checkpoint: checkpoint.Hash(),
+	}
+	if ok, err := utils.IsEpochSwitchHeader(config.XDPoS, checkpoint); err != nil || !ok {
+		return nil, errNotEpochSwitch
+	}
+	if rawdb.ReadHeader(db, lc.checkpoint, checkpoint.Number.Uint64()) == nil {
+		rawdb.WriteHeader(db, checkpoint)
+		rawdb.WriteCanonicalHash(db, lc.checkpoint, checkpoint.Number.Uint64())
+	}
+	head := checkpoint
+	if hash := rawdb.ReadHeadHeaderHash(db); hash != (common.Hash{}) {
+		if number := rawdb.ReadHeaderNumber(db, hash); number != nil && *number > checkpoint.Number.Uint64() {
+			if stored := rawdb.ReadHeader(db, hash, *number); stored != nil {
+				head = stored
+			}
+		}
+	}
+	if err := lc.setHead(head); err != nil {
+		return nil, err
+	}
+	log.Info("Loaded light chain", "number", head.Number, "hash", head.Hash(), "checkpoint", checkpoint.Number)
+	return lc, nil
+}
+
+// setHead makes the given stored header the head of the chain, loading the
+// committee of its epoch from the latest epoch switch header.
+func (lc *LightChain) setHead(head *types.Header) error {
+	header := head
+	for {
+		ok, err := utils.IsEpochSwitchHeader(lc.config.XDPoS, header)
+		if err != nil {
+			return err
+		}
+		if ok || header.Hash() == lc.checkpoint {
+			break
+		}
+		if header = lc.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return errCheckpointMissing
+		}
+	}
+	committee, err := utils.NewCommittee(lc.config.XDPoS, header)
+	if err != nil {
+		return err
+	}
+	lc.mu.Lock()
+	lc.head, lc.committee = head, committee
+	lc.mu.Unlock()
+
+	rawdb.WriteHeadHeaderHash(lc.db, head.Hash())
+	return nil
+}
+
+// SetHead rewinds the chain to the given number, never beyond the checkpoint.
+// Certified headers are not final until committed by the chain of three QCs,
+// so the tip may have to be dropped when the network moves to another fork.
+func (lc *LightChain) SetHead(number uint64) error {
+	head := lc.CurrentHeader()
+	if number >= head.Number.Uint64() {
+		return nil
+	}
+	checkpoint := lc.GetHeaderByHash(lc.checkpoint)
+	if number < checkpoint.Number.Uint64() {
+		number = checkpoint.Number.Uint64()
+	}
+	header := lc.GetHeaderByNumber(number)
+	if header == nil {
+		return errCheckpointMissing
+	}
+	batch := lc.db.NewBatch()
+	for n := head.Number.Uint64(); n > number; n-- {
+		rawdb.DeleteCanonicalHash(batch, n)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Warn("Rewound light chain", "from", head.Number, "to", number)
+	return lc.setHead(header)
+}
+
+// InsertHeaders verifies and appends a contiguous batch of headers on top of
+// the head. The last header only serves as the carrier of the QC certifying
+// the one before it and is not inserted. It returns the number of headers
+// inserted, which is less than len(headers)-1 on error.
+func (lc *LightChain) InsertHeaders(headers []*types.Header) (int, error) {
+	if len(headers) < 2 {
+		return 0, errMissingCertifier
+	}
+	for i := 1; i < len(headers); i++ {
+		if headers[i].ParentHash != headers[i-1].Hash() || headers[i].Number.Uint64() != headers[i-1].Number.Uint64()+1 {
+			return 0, errNonContiguous
+		}
+	}
+	for i := 0; i < len(headers)-1; i++ {
+		if err := lc.insertHeader(headers[i], headers[i+1]); err != nil {
+			return i, fmt.Errorf("header %d: %w", headers[i].Number.Uint64(), err)
+		}
+	}
+	return len(headers) - 1, nil
+}
+
+// insertHeader verifies a header with the QC carried by its child and appends
+// it to the chain.
+func (lc *LightChain) insertHeader(header, child *types.Header) error {
+	config := lc.config.XDPoS
+
+	lc.mu.RLock()
+	head, current := lc.head, lc.committee
+	lc.mu.RUnlock()
+
+	if header.ParentHash != head.Hash() || header.Number.Uint64() != head.Number.Uint64()+1 {
+		return consensus.ErrUnknownAncestor
+	}
+	qc, round, err := utils.DecodeHeaderExtra(config, header)
+	if err != nil {
+		return err
+	}
+	// The QC of a v2 header certifies its parent
+	if qc.ProposedBlockInfo.Hash != head.Hash() {
+		return utils.ErrQCBlockMismatch
+	}
+	if round <= qc.ProposedBlockInfo.Round {
+		return utils.ErrRoundInvalid
+	}
+	switched, err := utils.IsEpochSwitchHeader(config, header)
+	if err != nil {
+		return err
+	}
+	if switched {
+		previous := current
+		if current, err = utils.NewCommittee(config, header); err != nil {
+			return err
+		}
+		if err := lc.checkCommittee(previous, header, current); err != nil {
+			return err
+		}
+	}
+	certificate, _, err := utils.DecodeHeaderExtra(config, child)
+	if err != nil {
+		return err
+	}
+	info := certificate.ProposedBlockInfo
+	if info.Hash != header.Hash() || info.Number.Cmp(header.Number) != 0 || info.Round != round {
+		return utils.ErrQCBlockMismatch
+	}
+	if err := current.VerifyQC(config, certificate); err != nil {
+		return err
+	}
+	batch := lc.db.NewBatch()
+	rawdb.WriteHeader(batch, header)
+	rawdb.WriteCanonicalHash(batch, header.Hash(), header.Number.Uint64())
+	rawdb.WriteHeadHeaderHash(batch, header.Hash())
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	lc.mu.Lock()
+	lc.head, lc.committee = header, current
+	lc.mu.Unlock()
+
+	if switched {
+		log.Info("Light chain entered new epoch", "number", header.Number, "round", round, "masternodes", len(current.Masternodes))
+	}
+	block := types.NewBlockWithHeader(header)
+	lc.chainFeed.Send(core.ChainEvent{Block: block, Hash: block.Hash()})
+	lc.chainHeadFeed.Send(core.ChainHeadEvent{Block: block})
+	return nil
+}
+
+// MissingAncestors returns the range of headers before the checkpoint which
+// still have to be inserted by InsertAncestors, down to the lowest gap block a
+// committee following the checkpoint may refer to.
+func (lc *LightChain) MissingAncestors() (first, last uint64, ok bool) {
+	config := lc.config.XDPoS
+	checkpoint := lc.GetHeaderByHash(lc.checkpoint)
+	if checkpoint == nil {
+		return 0, 0, false
+	}
+	first = (&utils.Committee{Number: checkpoint.Number.Uint64()}).GapNumber(config)
+	for n := checkpoint.Number.Uint64(); n > first; n-- {
+		if lc.GetHeaderByNumber(n-1) == nil {
+			return first, n - 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// InsertAncestors stores a contiguous batch of headers preceding the lowest
+// stored ancestor of the checkpoint. The headers are not certified by a QC but
+// authenticated by their hash, which has to match the parent hash of the header
+// following them. It returns the number of headers inserted, counting from the
+// last one.
+func (lc *LightChain) InsertAncestors(headers []*types.Header) (int, error) {
+	for i := 1; i < len(headers); i++ {
+		if headers[i].ParentHash != headers[i-1].Hash() || headers[i].Number.Uint64() != headers[i-1].Number.Uint64()+1 {
+			return 0, errNonContiguous
+		}
+	}
+	checkpoint := lc.GetHeaderByHash(lc.checkpoint)
+	if checkpoint == nil {
+		return 0, errCheckpointMissing
+	}
+	for i := len(headers) - 1; i >= 0; i-- {
+		header := headers[i]
+		number := header.Number.Uint64()
+		if number >= checkpoint.Number.Uint64() {
+			return len(headers) - 1 - i, errUnlinkedAncestor
+		}
+		child := lc.GetHeaderByNumber(number + 1)
+		if child == nil || child.ParentHash != header.Hash() {
+			return len(headers) - 1 - i, errUnlinkedAncestor
+		}
+		batch := lc.db.NewBatch()
+		rawdb.WriteHeader(batch, header)
+		rawdb.WriteCanonicalHash(batch, header.Hash(), number)
+		if err := batch.Write(); err != nil {
+			return len(headers) - 1 - i, err
+		}
+	}
+	return len(headers), nil
+}
+
+// checkCommittee verifies the committee opened by an epoch switch header. The
+// first v2 epoch keeps the masternodes of the last v1 block, capped the same
+// way as the engine does. Later committees are checked by the CommitteeChecker
+// against the state of their gap block, which must be part of the chain or
+// among the ancestors of the checkpoint.
+func (lc *LightChain) checkCommittee(previous *utils.Committee, header *types.Header, committee *utils.Committee) error {
+	config := lc.config.XDPoS
+	if header.Number.Uint64() == config.V2.SwitchBlock.Uint64()+1 {
+		expected := utils.SelectMasternodes(previous.Masternodes, nil, config.V2.Config(uint64(committee.Round)).MaxMasternodes)
+		if !utils.CompareSignersLists(expected, committee.Masternodes) {
+			return errCommitteeMismatch
+		}
+		return nil
+	}
+	if lc.checker == nil {
+		return nil
+	}
+	number := committee.GapNumber(config)
+	gap := lc.GetHeaderByNumber(number)
+	if gap == nil {
+		return fmt.Errorf("%w: %d", errMissingGap, number)
+	}
+	return lc.checker(gap, header, committee)
+}
+
+// Config retrieves the chain's chain configuration.
+func (lc *LightChain) Config() *params.ChainConfig { return lc.config }
+
+// Engine retrieves the consensus engine, only used to read the block authors.
+func (lc *LightChain) Engine() consensus.Engine { return lc.engine }
+
+// CurrentHeader retrieves the highest certified header.
+func (lc *LightChain) CurrentHeader() *types.Header {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	return lc.head
+}
+
+// CommittedHeader returns the highest header committed by the chain of three
+// certified headers of consecutive rounds starting with it.
+func (lc *LightChain) CommittedHeader() *types.Header {
+	config := lc.config.XDPoS
+	header := lc.CurrentHeader()
+	for i := uint64(0); i < config.Epoch; i++ {
+		parent := lc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if parent == nil {
+			return nil
+		}
+		grandparent := lc.GetHeader(parent.ParentHash, parent.Number.Uint64()-1)
+		if grandparent == nil {
+			return nil
+		}
+		rounds := make([]types.Round, 3)
+		for j, h := range []*types.Header{header, parent, grandparent} {
+			round, err := utils.HeaderRound(config, h)
+			if err != nil {
+				return nil
+			}
+			rounds[j] = round
+		}
+		if rounds[0] == rounds[1]+1 && rounds[1] == rounds[2]+1 {
+			return grandparent
+		}
+		header = parent
+	}
+	return nil
+}
+
+// Checkpoint returns the hash of the trusted header the chain starts from.
+func (lc *LightChain) Checkpoint() common.Hash { return lc.checkpoint }
+
+// Masternodes returns the committee of the epoch of the head.
+func (lc *LightChain) Masternodes() []common.Address {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	return append([]common.Address(nil), lc.committee.Masternodes...)
+}
+
+// GetHeader retrieves a certified header by hash and number.
+func (lc *LightChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return rawdb.ReadHeader(lc.db, hash, number)
+}
+
+// GetHeaderByHash retrieves a certified header by hash.
+func (lc *LightChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	number := rawdb.ReadHeaderNumber(lc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return lc.GetHeader(hash, *number)
+}
+
+// GetHeaderByNumber retrieves a certified header of the canonical chain by
+// number.
+func (lc *LightChain) GetHeaderByNumber(number uint64) *types.Header {
+	hash := rawdb.ReadCanonicalHash(lc.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return lc.GetHeader(hash, number)
+}
+
+// GetBlock returns nil, the light chain does not store block bodies.
+func (lc *LightChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return nil
+}
+
+// SubscribeChainEvent registers a subscription of ChainEvent, sent for every
+// newly certified header.
+func (lc *LightChain) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return lc.scope.Track(lc.chainFeed.Subscribe(ch))
+}
+
+// SubscribeChainHeadEvent registers a subscription of ChainHeadEvent, sent for
+// every newly certified header.
+func (lc *LightChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return lc.scope.Track(lc.chainHeadFeed.Subscribe(ch))
+}
+
+// Stop closes the subscriptions of the chain.
+func (lc *LightChain) Stop() {
+	lc.scope.Close()
+}
@@ -0,0 +1,269 @@
+package light
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testEpoch       = 10
+	testGap         = 5
+	testSwitchBlock = 10
+)
+
+func newTestConfig() *params.ChainConfig {
+	v2 := &params.V2{
+		SwitchBlock: big.NewInt(testSwitchBlock),
+		AllConfigs: map[uint64]*params.V2Config{
+			0: {CertThreshold: 0.667, MaxMasternodes: 18},
+		},
+	}
+	v2.BuildConfigIndex()
+	return &params.ChainConfig{
+		ChainId: big.NewInt(1337),
+		XDPoS: &params.XDPoSConfig{
+			Epoch: testEpoch,
+			Gap:   testGap,
+			V2:    v2,
+		},
+	}
+}
+
+// testChain generates the headers of a v2 chain starting with the last v1
+// block, all certified by the same masternodes.
+type testChain struct {
+	keys    []*ecdsa.PrivateKey
+	signers []common.Address
+	headers []*types.Header
+}
+
+func newTestChain(t *testing.T, masternodes int, length int) *testChain {
+	tc := new(testChain)
+	for i := 0; i < masternodes; i++ {
+		key, _ := crypto.GenerateKey()
+		tc.keys = append(tc.keys, key)
+		tc.signers = append(tc.signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	extra := make([]byte, utils.ExtraVanity)
+	for _, signer := range tc.signers {
+		extra = append(extra, signer[:]...)
+	}
+	extra = append(extra, make([]byte, utils.ExtraSeal)...)
+	tc.headers = []*types.Header{{Number: big.NewInt(testSwitchBlock), Extra: extra}}
+
+	for i := 1; i < length; i++ {
+		parent := tc.headers[i-1]
+		tc.headers = append(tc.headers, tc.child(t, parent, types.Round(i), tc.qc(parent, types.Round(i-1), len(tc.keys))))
+	}
+	return tc
+}
+
+// qc builds the QC of a header signed by the first signers masternodes.
+func (tc *testChain) qc(header *types.Header, round types.Round, signers int) *types.QuorumCert {
+	start := header.Number.Uint64() - header.Number.Uint64()%testEpoch
+	qc := &types.QuorumCert{
+		ProposedBlockInfo: &types.BlockInfo{Hash: header.Hash(), Round: round, Number: header.Number},
+		GapNumber:         start - testGap,
+	}
+	signHash := types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: qc.ProposedBlockInfo, GapNumber: qc.GapNumber})
+	for _, key := range tc.keys[:signers] {
+		sig, _ := crypto.Sign(signHash.Bytes(), key)
+		qc.Signatures = append(qc.Signatures, sig)
+	}
+	return qc
+}
+
+// child builds the header of the given round on top of parent, carrying qc.
+func (tc *testChain) child(t *testing.T, parent *types.Header, round types.Round, qc *types.QuorumCert) *types.Header {
+	extra, err := (&types.ExtraFields_v2{Round: round, QuorumCert: qc}).EncodeToBytes()
+	assert.Nil(t, err)
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Extra:      extra,
+	}
+	if qc.ProposedBlockInfo.Number.Uint64() == testSwitchBlock || qc.ProposedBlockInfo.Round < round-round%testEpoch {
+		for _, signer := range tc.signers {
+			header.Validators = append(header.Validators, signer[:]...)
+		}
+	}
+	return header
+}
+
+func TestLightChainInsertHeaders(t *testing.T) {
+	config := newTestConfig()
+	tc := newTestChain(t, 4, 25)
+
+	chain, err := NewLightChain(rawdb.NewMemoryDatabase(), config, nil, tc.headers[0], nil)
+	assert.Nil(t, err)
+
+	n, err := chain.InsertHeaders(tc.headers[1:])
+	assert.Nil(t, err)
+	assert.Equal(t, 23, n)
+	assert.Equal(t, tc.headers[23].Hash(), chain.CurrentHeader().Hash())
+	assert.Equal(t, tc.signers, chain.Masternodes())
+	assert.Equal(t, tc.headers[21].Hash(), chain.CommittedHeader().Hash())
+
+	// The last header waits for the QC of its child
+	assert.Nil(t, chain.GetHeaderByNumber(tc.headers[24].Number.Uint64()))
+}
+
+func TestLightChainRejectsWeakQC(t *testing.T) {
+	config := newTestConfig()
+	tc := newTestChain(t, 4, 4)
+
+	chain, err := NewLightChain(rawdb.NewMemoryDatabase(), config, nil, tc.headers[0], nil)
+	assert.Nil(t, err)
+	_, err = chain.InsertHeaders(tc.headers[1:3])
+	assert.Nil(t, err)
+
+	// Two signatures out of four masternodes do not reach the threshold
+	weak := tc.child(t, tc.headers[2], 3, tc.qc(tc.headers[2], 2, 2))
+	_, err = chain.InsertHeaders([]*types.Header{tc.headers[2], weak})
+	assert.True(t, errors.Is(err, utils.ErrInvalidQCSignatures))
+
+	// Signatures of other keys are not accepted either
+	other := newTestChain(t, 4, 1)
+	forged := tc.child(t, tc.headers[2], 3, other.qc(tc.headers[2], 2, 4))
+	_, err = chain.InsertHeaders([]*types.Header{tc.headers[2], forged})
+	assert.True(t, errors.Is(err, utils.ErrUnknownQCSigner))
+
+	// A QC for another block does not certify the header
+	wrong := tc.child(t, tc.headers[2], 3, tc.qc(tc.headers[1], 2, 4))
+	_, err = chain.InsertHeaders([]*types.Header{tc.headers[2], wrong})
+	assert.True(t, errors.Is(err, utils.ErrQCBlockMismatch))
+
+	assert.Equal(t, tc.headers[1].Hash(), chain.CurrentHeader().Hash())
+}
+
+func TestLightChainCommitteeChecker(t *testing.T) {
+	config := newTestConfig()
+	tc := newTestChain(t, 4, 12)
+
+	reject := errors.New("rejected")
+	var gaps []uint64
+	checker := func(gap, header *types.Header, committee *utils.Committee) error {
+		gaps = append(gaps, gap.Number.Uint64())
+		return reject
+	}
+	chain, err := NewLightChain(rawdb.NewMemoryDatabase(), config, nil, tc.headers[0], checker)
+	assert.Nil(t, err)
+
+	// The first v2 epoch keeps the masternodes of the checkpoint
+	n, err := chain.InsertHeaders(tc.headers[1:])
+	assert.True(t, errors.Is(err, reject))
+	assert.Equal(t, 9, n)
+	assert.Equal(t, []uint64{15}, gaps)
+	assert.Equal(t, tc.headers[9].Hash(), chain.CurrentHeader().Hash())
+}
+
+func TestLightChainFirstEpochCommittee(t *testing.T) {
+	config := newTestConfig()
+	tc := newTestChain(t, 4, 3)
+
+	chain, err := NewLightChain(rawdb.NewMemoryDatabase(), config, nil, tc.headers[0], nil)
+	assert.Nil(t, err)
+
+	// The first v2 block may not record other masternodes than the checkpoint
+	forged := tc.child(t, tc.headers[0], 1, tc.qc(tc.headers[0], 0, 0))
+	forged.Validators = append(forged.Validators, common.Address{1}.Bytes()...)
+	certifier := tc.child(t, forged, 2, tc.qc(forged, 1, 4))
+	_, err = chain.InsertHeaders([]*types.Header{forged, certifier})
+	assert.True(t, errors.Is(err, errCommitteeMismatch))
+	assert.Equal(t, tc.headers[0].Hash(), chain.CurrentHeader().Hash())
+}
+
+func TestLightChainMissingGap(t *testing.T) {
+	config := newTestConfig()
+	tc := newTestChain(t, 4, 22)
+
+	// Start from the epoch switch at block 20, whose next epoch has its gap
+	// block before the checkpoint
+	checkpoint := tc.headers[10]
+	assert.Equal(t, uint64(20), checkpoint.Number.Uint64())
+	var gaps []common.Hash
+	checker := func(gap, header *types.Header, committee *utils.Committee) error {
+		gaps = append(gaps, gap.Hash())
+		return nil
+	}
+	chain, err := NewLightChain(rawdb.NewMemoryDatabase(), config, nil, checkpoint, checker)
+	assert.Nil(t, err)
+
+	// Rounds 11 to 19 are skipped, so block 21 opens the epoch of round 20
+	early := tc.child(t, checkpoint, 20, tc.qc(checkpoint, 10, 4))
+	certifier := tc.child(t, early, 21, tc.qc(early, 20, 4))
+	_, err = chain.InsertHeaders([]*types.Header{early, certifier})
+	assert.True(t, errors.Is(err, errMissingGap))
+	assert.Equal(t, checkpoint.Hash(), chain.CurrentHeader().Hash())
+
+	first, last, ok := chain.MissingAncestors()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(15), first)
+	assert.Equal(t, uint64(19), last)
+
+	// Ancestors not leading to the checkpoint are rejected
+	forged := *tc.headers[9]
+	forged.Time = 1
+	_, err = chain.InsertAncestors([]*types.Header{tc.headers[8], &forged})
+	assert.Equal(t, errUnlinkedAncestor, err)
+	_, err = chain.InsertAncestors(tc.headers[5:9])
+	assert.Equal(t, errUnlinkedAncestor, err)
+
+	// The authenticated ancestors provide the gap block
+	n, err := chain.InsertAncestors(tc.headers[5:10])
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	_, _, ok = chain.MissingAncestors()
+	assert.False(t, ok)
+
+	_, err = chain.InsertHeaders([]*types.Header{early, certifier})
+	assert.Nil(t, err)
+	assert.Equal(t, early.Hash(), chain.CurrentHeader().Hash())
+	assert.Equal(t, []common.Hash{tc.headers[5].Hash()}, gaps)
+}
+
+func TestLightChainSetHead(t *testing.T) {
+	config := newTestConfig()
+	tc := newTestChain(t, 4, 15)
+	db := rawdb.NewMemoryDatabase()
+
+	chain, err := NewLightChain(db, config, nil, tc.headers[0], nil)
+	assert.Nil(t, err)
+	_, err = chain.InsertHeaders(tc.headers[1:])
+	assert.Nil(t, err)
+
+	assert.Nil(t, chain.SetHead(tc.headers[12].Number.Uint64()))
+	assert.Equal(t, tc.headers[12].Hash(), chain.CurrentHeader().Hash())
+	assert.Nil(t, chain.GetHeaderByNumber(tc.headers[13].Number.Uint64()))
+
+	// The chain resumes from the stored head
+	chain, err = NewLightChain(db, config, nil, tc.headers[0], nil)
+	assert.Nil(t, err)
+	assert.Equal(t, tc.headers[12].Hash(), chain.CurrentHeader().Hash())
+	assert.Equal(t, tc.signers, chain.Masternodes())
+
+	// Never rewinds beyond the checkpoint
+	assert.Nil(t, chain.SetHead(0))
+	assert.Equal(t, tc.headers[0].Hash(), chain.CurrentHeader().Hash())
+}
+
+func TestNewLightChainCheckpoint(t *testing.T) {
+	config := newTestConfig()
+	tc := newTestChain(t, 4, 3)
+
+	_, err := NewLightChain(rawdb.NewMemoryDatabase(), config, nil, nil, nil)
+	assert.Equal(t, errNoCheckpoint, err)
+
+	_, err = NewLightChain(rawdb.NewMemoryDatabase(), config, nil, tc.headers[2], nil)
+	assert.Equal(t, errNotEpochSwitch, err)
+}
@@ -0,0 +1,169 @@
+package light
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/ethdb"
+	"github.com/XinFinOrg/XDPoSChain/params"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+	"github.com/XinFinOrg/XDPoSChain/trie"
+)
+
+// requestTimeout is the time allowed to a single request to the server.
+const requestTimeout = 10 * time.Second
+
+var (
+	errInvalidProof    = errors.New("retrieved data does not match its hash")
+	errInvalidBody     = errors.New("retrieved transactions do not match the header")
+	errInvalidReceipts = errors.New("retrieved receipts do not match the header")
+)
+
+// odrDatabase is a chain database retrieving the missing trie nodes and
+// contract codes from the server on demand. Both are keyed by their hash, so
+// every retrieved value is checked against its key before being stored, which
+// makes any state read from a certified root as trustworthy as the root.
+type odrDatabase struct {
+	ethdb.Database
+	client *rpc.Client
+}
+
+// newOdrDatabase wraps a local database with on demand retrieval.
+func newOdrDatabase(db ethdb.Database, client *rpc.Client) *odrDatabase {
+	return &odrDatabase{Database: db, client: client}
+}
+
+// hashOfKey returns the hash a value stored under the key must have, if the
+// key is the key of a trie node or of a contract code.
+func hashOfKey(key []byte) (common.Hash, bool) {
+	if len(key) == common.HashLength {
+		return common.BytesToHash(key), true
+	}
+	if ok, hash := rawdb.IsCodeKey(key); ok {
+		return common.BytesToHash(hash), true
+	}
+	return common.Hash{}, false
+}
+
+// Get retrieves the value of the key from the local database, falling back to
+// the server for trie nodes and contract codes.
+func (db *odrDatabase) Get(key []byte) ([]byte, error) {
+	value, err := db.Database.Get(key)
+	if err == nil {
+		return value, nil
+	}
+	hash, ok := hashOfKey(key)
+	if !ok {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var blob hexutil.Bytes
+	if err := db.client.CallContext(ctx, &blob, "debug_dbGet", hexutil.Encode(key)); err != nil {
+		return nil, fmt.Errorf("failed to retrieve %x: %w", key, err)
+	}
+	if crypto.Keccak256Hash(blob) != hash {
+		return nil, errInvalidProof
+	}
+	if err := db.Database.Put(key, blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Has reports whether the key is available locally or from the server.
+func (db *odrDatabase) Has(key []byte) (bool, error) {
+	if ok, err := db.Database.Has(key); err == nil && ok {
+		return true, nil
+	}
+	if _, ok := hashOfKey(key); !ok {
+		return false, nil
+	}
+	_, err := db.Get(key)
+	return err == nil, nil
+}
+
+// retrieveBody fetches the transactions of a certified header from the server
+// and checks them against the transaction root of the header.
+func retrieveBody(ctx context.Context, client *rpc.Client, db ethdb.Database, header *types.Header) (*types.Body, error) {
+	hash, number := header.Hash(), header.Number.Uint64()
+	if body := rawdb.ReadBody(db, hash, number); body != nil {
+		return body, nil
+	}
+	var raw struct {
+		Transactions []*types.Transaction `json:"transactions"`
+	}
+	if err := client.CallContext(ctx, &raw, "eth_getBlockByHash", hash, true); err != nil {
+		return nil, err
+	}
+	if types.DeriveSha(types.Transactions(raw.Transactions), trie.NewStackTrie(nil)) != header.TxHash {
+		return nil, errInvalidBody
+	}
+	body := &types.Body{Transactions: raw.Transactions}
+	rawdb.WriteBody(db, hash, number, body)
+	return body, nil
+}
+
+// retrieveReceipts fetches the receipts of a certified header from the server
+// and checks them against the receipt root of the header. The derived fields
+// are recomputed locally rather than taken from the server.
+func retrieveReceipts(ctx context.Context, client *rpc.Client, db ethdb.Database, config *params.ChainConfig, header *types.Header) (types.Receipts, error) {
+	hash, number := header.Hash(), header.Number.Uint64()
+	body, err := retrieveBody(ctx, client, db, header)
+	if err != nil {
+		return nil, err
+	}
+	if receipts := rawdb.ReadReceipts(db, hash, number, config); receipts != nil {
+		return receipts, nil
+	}
+	var receipts types.Receipts
+	if err := client.CallContext(ctx, &receipts, "eth_getBlockReceipts", hash); err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(body.Transactions) || types.DeriveSha(receipts, trie.NewStackTrie(nil)) != header.ReceiptHash {
+		return nil, errInvalidReceipts
+	}
+	if err := receipts.DeriveFields(config, hash, number, header.BaseFee, body.Transactions); err != nil {
+		return nil, err
+	}
+	rawdb.WriteReceipts(db, hash, number, receipts)
+	return receipts, nil
+}
+
+// newCandidateChecker returns a CommitteeChecker recomputing the masternodes
+// of a new epoch from the state of its gap block, read through the proof-backed
+// database: the candidates of the validator contract in descending stake
+// order, without the penalties of the epoch switch header, capped at the
+// maximum number of masternodes of its round.
+func newCandidateChecker(db ethdb.Database, config *params.ChainConfig) CommitteeChecker {
+	states := state.NewDatabase(db)
+	return func(gap, header *types.Header, committee *utils.Committee) error {
+		statedb, err := state.New(gap.Root, states)
+		if err != nil {
+			return err
+		}
+		var ms []utils.Masternode
+		for _, candidate := range state.GetCandidates(statedb) {
+			if !candidate.IsZero() {
+				ms = append(ms, utils.Masternode{Address: candidate, Stake: state.GetCandidateCap(statedb, candidate)})
+			}
+		}
+		candidates := utils.SortCandidates(ms)
+		penalties := common.ExtractAddressFromBytes(header.Penalties)
+		expected := utils.SelectMasternodes(candidates, penalties, config.XDPoS.V2.Config(uint64(committee.Round)).MaxMasternodes)
+		if !utils.CompareSignersLists(expected, committee.Masternodes) {
+			return fmt.Errorf("%w at gap block %d", errCommitteeMismatch, gap.Number.Uint64())
+		}
+		return nil
+	}
+}
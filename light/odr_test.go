@@ -0,0 +1,120 @@
+package light
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDPoSChain/common"
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDPoSChain/core/rawdb"
+	"github.com/XinFinOrg/XDPoSChain/core/state"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/crypto"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDebugAPI serves debug_dbGet from a fixed set of values.
+type testDebugAPI struct {
+	values map[string][]byte
+	served int
+}
+
+func (api *testDebugAPI) DbGet(key string) (hexutil.Bytes, error) {
+	api.served++
+	value, ok := api.values[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return value, nil
+}
+
+func newTestOdrDatabase(t *testing.T, api *testDebugAPI) *odrDatabase {
+	server := rpc.NewServer()
+	assert.Nil(t, server.RegisterName("debug", api))
+	t.Cleanup(server.Stop)
+
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return newOdrDatabase(rawdb.NewMemoryDatabase(), client)
+}
+
+func TestOdrDatabaseGet(t *testing.T) {
+	node := []byte("trie node")
+	code := []byte("contract code")
+	nodeKey := crypto.Keccak256(node)
+	codeKey := append([]byte("c"), crypto.Keccak256(code)...)
+	forgedKey := crypto.Keccak256([]byte("other node"))
+
+	api := &testDebugAPI{values: map[string][]byte{
+		hexutil.Encode(nodeKey):   node,
+		hexutil.Encode(codeKey):   code,
+		hexutil.Encode(forgedKey): []byte("forged node"),
+	}}
+	db := newTestOdrDatabase(t, api)
+
+	value, err := db.Get(nodeKey)
+	assert.Nil(t, err)
+	assert.Equal(t, node, value)
+
+	value, err = db.Get(codeKey)
+	assert.Nil(t, err)
+	assert.Equal(t, code, value)
+
+	// Retrieved values are stored locally
+	value, err = db.Get(nodeKey)
+	assert.Nil(t, err)
+	assert.Equal(t, node, value)
+	assert.Equal(t, 2, api.served)
+
+	// Values not matching their key are rejected
+	_, err = db.Get(forgedKey)
+	assert.Equal(t, errInvalidProof, err)
+	ok, _ := db.Has(forgedKey)
+	assert.False(t, ok)
+
+	// Other keys are never retrieved
+	_, err = db.Get([]byte("LastHeader"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 4, api.served)
+}
+
+// writeCandidates stores the candidates and their stakes in the validator
+// contract storage and returns the resulting state root.
+func writeCandidates(t *testing.T, db state.Database, candidates []common.Address, stakes []int64) common.Hash {
+	statedb, err := state.New(types.EmptyRootHash, db)
+	assert.Nil(t, err)
+	contract := common.MasternodeVotingSMCBinary
+	statedb.SetState(contract, state.GetCandidatesLengthLoc(), common.BigToHash(big.NewInt(int64(len(candidates)))))
+	for i, candidate := range candidates {
+		statedb.SetState(contract, state.GetCandidateLoc(uint64(i)), common.BytesToHash(candidate[:]))
+		statedb.SetState(contract, state.GetCandidateCapLoc(candidate), common.BigToHash(big.NewInt(stakes[i])))
+	}
+	root, err := statedb.Commit(false)
+	assert.Nil(t, err)
+	assert.Nil(t, db.TrieDB().Commit(root, false))
+	return root
+}
+
+func TestCandidateChecker(t *testing.T) {
+	config := newTestConfig()
+	config.XDPoS.V2.AllConfigs[0].MaxMasternodes = 2
+
+	db := rawdb.NewMemoryDatabase()
+	a, b, c := common.Address{1}, common.Address{2}, common.Address{3}
+	gap := &types.Header{Number: big.NewInt(15), Root: writeCandidates(t, state.NewDatabase(db), []common.Address{a, b, c}, []int64{1, 3, 2})}
+	checker := newCandidateChecker(db, config)
+
+	// The top candidates by stake
+	header := &types.Header{Number: big.NewInt(20)}
+	assert.Nil(t, checker(gap, header, &utils.Committee{Masternodes: []common.Address{b, c}}))
+	assert.True(t, errors.Is(checker(gap, header, &utils.Committee{Masternodes: []common.Address{a, b}}), errCommitteeMismatch))
+	assert.True(t, errors.Is(checker(gap, header, &utils.Committee{Masternodes: []common.Address{b}}), errCommitteeMismatch))
+
+	// Without the penalized candidates
+	header.Penalties = c.Bytes()
+	assert.Nil(t, checker(gap, header, &utils.Committee{Masternodes: []common.Address{b, a}}))
+	assert.True(t, errors.Is(checker(gap, header, &utils.Committee{Masternodes: []common.Address{b, c}}), errCommitteeMismatch))
+}
@@ -0,0 +1,206 @@
+package light
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/XinFinOrg/XDPoSChain/common/hexutil"
+	"github.com/XinFinOrg/XDPoSChain/core/types"
+	"github.com/XinFinOrg/XDPoSChain/log"
+	"github.com/XinFinOrg/XDPoSChain/rpc"
+)
+
+const (
+	// syncInterval is the time between two polls of the server head.
+	syncInterval = 2 * time.Second
+
+	// maxHeaderFetch is the number of headers requested in a single batch.
+	maxHeaderFetch = 128
+
+	// maxRewind is the number of certified headers dropped at most when the
+	// server is on another fork. Deeper headers are committed by a chain of
+	// three QCs and cannot be reverted.
+	maxRewind = 3
+)
+
+var (
+	errForkTooDeep      = errors.New("server fork is deeper than the commit window")
+	errMissingAncestors = errors.New("server misses ancestors of the checkpoint")
+)
+
+// syncer keeps the light chain in sync with the head of the server.
+type syncer struct {
+	chain  *LightChain
+	client *rpc.Client
+
+	highest atomic.Uint64 // Highest header number announced by the server
+	start   uint64        // Head number the sync started from
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newSyncer(chain *LightChain, client *rpc.Client) *syncer {
+	return &syncer{
+		chain:  chain,
+		client: client,
+		start:  chain.CurrentHeader().Number.Uint64(),
+		quit:   make(chan struct{}),
+	}
+}
+
+// run polls the server for new headers until stopped.
+func (s *syncer) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sync(); err != nil {
+			log.Warn("Light sync failed", "err", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *syncer) stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// sync fetches and inserts the headers between the local head and the head
+// of the server. The server head itself is left out until a child carrying
+// its QC arrives.
+func (s *syncer) sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var number hexutil.Uint64
+	if err := s.client.CallContext(ctx, &number, "eth_blockNumber"); err != nil {
+		return err
+	}
+	s.highest.Store(uint64(number))
+
+	if err := s.syncAncestors(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-s.quit:
+			return nil
+		default:
+		}
+		head := s.chain.CurrentHeader().Number.Uint64()
+		if head >= uint64(number) {
+			return nil
+		}
+		count := uint64(number) - head
+		if count > maxHeaderFetch {
+			count = maxHeaderFetch
+		}
+		headers, err := s.fetchHeaders(head+1, count)
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			return nil
+		}
+		// The server moved to another fork, drop the uncommitted tip
+		if headers[0].ParentHash != s.chain.CurrentHeader().Hash() {
+			if err := s.rewind(); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := s.chain.InsertHeaders(headers); err != nil {
+			return err
+		}
+		if len(headers) < 2 {
+			return nil
+		}
+	}
+}
+
+// syncAncestors fetches the headers before the checkpoint holding the gap
+// blocks the committees following it are checked against.
+func (s *syncer) syncAncestors() error {
+	for {
+		first, last, ok := s.chain.MissingAncestors()
+		if !ok {
+			return nil
+		}
+		from := first
+		if last-first >= maxHeaderFetch {
+			from = last + 1 - maxHeaderFetch
+		}
+		headers, err := s.fetchHeaders(from, last-from+1)
+		if err != nil {
+			return err
+		}
+		if uint64(len(headers)) != last-from+1 {
+			return errMissingAncestors
+		}
+		if _, err := s.chain.InsertAncestors(headers); err != nil {
+			return err
+		}
+	}
+}
+
+// rewind drops the tip of the chain down to the last header shared with the
+// server.
+func (s *syncer) rewind() error {
+	head := s.chain.CurrentHeader()
+	for depth := uint64(1); depth <= maxRewind && depth <= head.Number.Uint64(); depth++ {
+		number := head.Number.Uint64() - depth
+		local := s.chain.GetHeaderByNumber(number)
+		if local == nil {
+			break
+		}
+		remote, err := s.fetchHeaders(number, 1)
+		if err != nil {
+			return err
+		}
+		if len(remote) == 1 && remote[0].Hash() == local.Hash() {
+			return s.chain.SetHead(number)
+		}
+	}
+	return errForkTooDeep
+}
+
+// fetchHeaders retrieves a batch of consecutive headers from the server,
+// stopping at the first one the server does not have.
+func (s *syncer) fetchHeaders(from, count uint64) ([]*types.Header, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	headers := make([]*types.Header, count)
+	batch := make([]rpc.BatchElem, count)
+	for i := range batch {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeBig(new(big.Int).SetUint64(from + uint64(i))), false},
+			Result: &headers[i],
+		}
+	}
+	if err := s.client.BatchCallContext(ctx, batch); err != nil {
+		return nil, err
+	}
+	for i := range batch {
+		if batch[i].Error != nil {
+			return nil, batch[i].Error
+		}
+		if headers[i] == nil {
+			return headers[:i], nil
+		}
+	}
+	return headers, nil
+}
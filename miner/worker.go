@@ -21,6 +21,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"maps"
 	"math/big"
 	"sync"
 	"sync/atomic"
@@ -60,6 +61,10 @@ const (
 	chainSideChanSize = 10
 
 	txMatchGasLimit = 40000000
+
+	// specialTxGasPercent is the share of the block gas limit reserved for the
+	// signing and randomization transactions of the signers.
+	specialTxGasPercent = 25
 )
 
 // Agent can register themself with the worker
@@ -741,7 +746,7 @@ func (w *worker) commitNewWork() {
 				}
 
 				if isEpochSwitchBlock {
-					err := XDCX.UpdateMediumPriceBeforeEpoch(epochNumber, work.tradingState, work.state)
+					err := XDCX.UpdateMediumPriceBeforeEpoch(header, epochNumber, work.tradingState, work.state)
 					if err != nil {
 						log.Error("Fail when update medium price last epoch", "error", err)
 						return
@@ -758,6 +763,11 @@ func (w *worker) commitNewWork() {
 					lendingOrderPending, _ := w.eth.LendingPool().Pending()
 					lendingInput, lendingMatchingResults = XDCXLending.ProcessOrderPending(header, w.coinbase, w.chain, lendingOrderPending, work.state, work.lendingState, work.tradingState)
 					log.Debug("lending transaction matches found", "lendingInput", len(lendingInput), "lendingMatchingResults", len(lendingMatchingResults))
+					auctionTrades, auctionLiquidatedTrades, _, err := XDCXLending.ProcessLiquidationAuctions(header, w.chain, work.state, work.tradingState, work.lendingState)
+					if err != nil {
+						log.Error("Fail when process lending liquidation auctions", "error", err)
+						return
+					}
 					if header.Number.Uint64()%w.config.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
 						updatedTrades, liquidatedTrades, autoRepayTrades, autoTopUpTrades, autoRecallTrades, err = XDCXLending.ProcessLiquidationData(header, w.chain, work.state, work.tradingState, work.lendingState)
 						if err != nil {
@@ -765,6 +775,26 @@ func (w *worker) commitNewWork() {
 							return
 						}
 					}
+					// activate the stop orders crossed by the trades of the block, they are
+					// reported along with the last order of the block
+					activated := XDCX.ActivateStopOrders(header, w.coinbase, w.chain, work.state, work.tradingState)
+					if len(tradingTxMatches) > 0 && (len(activated.Trades) > 0 || len(activated.Rejects) > 0) {
+						if order, err := tradingTxMatches[len(tradingTxMatches)-1].DecodeOrder(); err == nil {
+							key := tradingstate.GetMatchingResultCacheKey(order)
+							result := tradingMatchingResults[key]
+							result.Trades = append(result.Trades, activated.Trades...)
+							result.Rejects = append(result.Rejects, activated.Rejects...)
+							tradingMatchingResults[key] = result
+						}
+					}
+					// settled auctions are reported with the other liquidated trades
+					if len(auctionTrades) > 0 {
+						if updatedTrades == nil {
+							updatedTrades = map[common.Hash]*lendingstate.LendingTrade{}
+						}
+						maps.Copy(updatedTrades, auctionTrades)
+						liquidatedTrades = append(liquidatedTrades, auctionLiquidatedTrades...)
+					}
 				}
 
 				if len(tradingTxMatches) > 0 {
@@ -786,9 +816,7 @@ func (w *worker) commitNewWork() {
 						return
 					} else {
 						tradingTransaction = txM
-						if XDCX.IsSDKNode() {
-							w.chain.AddMatchingResult(tradingTransaction.Hash(), tradingMatchingResults)
-						}
+						w.chain.AddMatchingResult(tradingTransaction.Hash(), tradingMatchingResults)
 						// force adding trading, lending transaction to this block
 						if tradingTransaction != nil {
 							specialTxs = append(specialTxs, tradingTransaction)
@@ -884,12 +912,18 @@ func (w *worker) commitNewWork() {
 
 func (w *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Address]*big.Int, txs *types.TransactionsByPriceAndNonce, specialTxs types.Transactions, bc *core.BlockChain, coinbase common.Address, pendingLogsFeed *event.Feed) {
 	gp := new(core.GasPool).AddGas(w.header.GasLimit)
+	// The signing and randomization transactions of the signers run first in a
+	// lane of their own, guaranteed its share of the block but unable to take more.
+	// They are applied for free, so the lane is charged their gas limit instead
+	// and its usage is taken out of the block before ordinary txs are packed.
+	laneGp := new(core.GasPool).AddGas(w.header.GasLimit * specialTxGasPercent / 100)
 	balanceUpdated := map[common.Address]*big.Int{}
 	totalFeeUsed := big.NewInt(0)
 	var coalescedLogs []*types.Log
 	// first priority for special Txs
 	for _, tx := range specialTxs {
 		to := tx.To()
+		lane := tx.IsSpecialTransaction()
 		//HF number for black-list
 		if (w.header.Number.Uint64() >= common.BlackListHFNumber) && !common.IsTestnet {
 			from := tx.From()
@@ -924,6 +958,10 @@ func (w *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Addr
 			}
 		}
 
+		if lane && laneGp.Gas() < tx.Gas() {
+			log.Trace("Not enough reserved gas for special transaction", "hash", tx.Hash(), "lane", laneGp)
+			continue
+		}
 		if gp.Gas() < params.TxGas && tx.Gas() > 0 {
 			log.Trace("Not enough gas for further transactions", "gp", gp)
 			break
@@ -960,6 +998,9 @@ func (w *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Addr
 			continue
 		}
 		logs, tokenFeeUsed, gas, err := w.commitTransaction(balanceFee, tx, bc, coinbase, gp)
+		if err == nil && lane {
+			laneGp.SubGas(tx.Gas())
+		}
 		switch err {
 		case core.ErrNonceTooLow:
 			// New head notification data race between the transaction pool and miner, shift
@@ -985,6 +1026,8 @@ func (w *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Addr
 			totalFeeUsed = totalFeeUsed.Add(totalFeeUsed, fee)
 		}
 	}
+	reserved := min(w.header.GasLimit*specialTxGasPercent/100-laneGp.Gas(), gp.Gas())
+	gp.SubGas(reserved)
 	for {
 		// If we don't have enough gas for any further transactions then we're done
 		if gp.Gas() < params.TxGas {
@@ -1038,6 +1081,14 @@ func (w *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Addr
 				continue
 			}
 		}
+		// verify the forensic proof of slashing transactions
+		if tx.IsSlashingTransaction() {
+			if err := core.ValidateSlashingTransaction(bc, w.header, w.state, tx); err != nil {
+				log.Debug("Slashing: invalid report", "hash", tx.Hash(), "err", err)
+				txs.Pop()
+				continue
+			}
+		}
 
 		// Error may be ignored here. The error has already been checked
 		// during transaction acceptance is the transaction pool.
@@ -694,6 +694,11 @@ func (c *ChainConfig) Description() string {
 	banner += fmt.Sprintf("  - Cancun:                      %-8v\n", cancunBlock)
 	banner += fmt.Sprintf("  - TIPUpgradeReward:            %-8v\n", common.TIPUpgradeReward)
 	banner += fmt.Sprintf("  - TIPEpochHalving:             %-8v\n", common.TIPEpochHalving)
+	banner += fmt.Sprintf("  - TIPSlashing:                 %-8v\n", common.TIPSlashing)
+	banner += fmt.Sprintf("  - TIPXDCXOrderTypes:           %-8v\n", common.TIPXDCXOrderTypes)
+	banner += fmt.Sprintf("  - TIPXDCXStopOrders:           %-8v\n", common.TIPXDCXStopOrders)
+	banner += fmt.Sprintf("  - TIPXDCXLendingAuction:       %-8v\n", common.TIPXDCXLendingAuction)
+	banner += fmt.Sprintf("  - TIPEpochRoles:               %-8v\n", common.TIPEpochRoles)
 	banner += fmt.Sprintf("  - Engine:                      %v", engine)
 	return banner
 }
@@ -826,6 +831,38 @@ func (c *ChainConfig) IsTIPEpochHalving(num *big.Int) bool {
 	return isForked(common.TIPEpochHalving, num)
 }
 
+// IsTIPSlashing returns whether num is either equal to the slashing fork block
+// or greater, from which on masternodes can be penalised with forensic proofs.
+func (c *ChainConfig) IsTIPSlashing(num *big.Int) bool {
+	return isForked(common.TIPSlashing, num)
+}
+
+// IsTIPXDCXOrderTypes returns whether num is either equal to the fork block
+// activating the IOC, FOK and post-only XDCx order types or greater.
+func (c *ChainConfig) IsTIPXDCXOrderTypes(num *big.Int) bool {
+	return isForked(common.TIPXDCXOrderTypes, num)
+}
+
+// IsTIPXDCXStopOrders returns whether num is either equal to the fork block
+// activating the stop-market and stop-limit XDCx order types or greater.
+func (c *ChainConfig) IsTIPXDCXStopOrders(num *big.Int) bool {
+	return isForked(common.TIPXDCXStopOrders, num)
+}
+
+// IsTIPXDCXLendingAuction returns whether num is either equal to the fork block
+// or greater, after which undercollateralized lending trades are liquidated
+// through a Dutch auction instead of seizing the collateral outright.
+func (c *ChainConfig) IsTIPXDCXLendingAuction(num *big.Int) bool {
+	return isForked(common.TIPXDCXLendingAuction, num)
+}
+
+// IsTIPEpochRoles returns whether num is either equal to the fork block or
+// greater, from which on the XDPoS v2 epoch switch blocks record the protector
+// and observer nodes of their epoch.
+func (c *ChainConfig) IsTIPEpochRoles(num *big.Int) bool {
+	return isForked(common.TIPEpochRoles, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -27,4 +27,9 @@ const (
 	// BloomConfirms is the number of confirmation blocks before a bloom section is
 	// considered probably final and its rotated bits are calculated.
 	BloomConfirms = 256
+
+	// ImmutabilityThreshold is the number of blocks after which a chain segment is
+	// considered immutable (i.e. soft finality). It is used by the chain freezer
+	// as a fallback for blocks which were not finalized by XDPoS v2 consensus.
+	ImmutabilityThreshold = 90000
 )